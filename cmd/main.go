@@ -1,17 +1,53 @@
 package main
 
 import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/certificate"
 	"backend-election/internal/pkg/config"
 	"backend-election/internal/pkg/database"
+	"backend-election/internal/pkg/elgamal"
+	"backend-election/internal/pkg/ledger"
+	"backend-election/internal/pkg/logger"
 	"backend-election/internal/pkg/migration"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/route"
+	"backend-election/internal/usecase"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
+	// verifying a proof is done offline, by anyone, without the config of the node
+	if len(os.Args) >= 2 && os.Args[1] == "verify-proof" {
+		verifyProof(os.Args[2:])
+		return
+	}
+
+	// a certificate is verified offline too, by journalists and observers
+	if len(os.Args) >= 2 && os.Args[1] == "verify-certificate" {
+		verifyCertificate(os.Args[2:])
+		return
+	}
+
+	// a trustee decrypts with its share on its own machine, the share never reaches the node
+	if len(os.Args) >= 2 && os.Args[1] == "trustee-decrypt" {
+		trusteeDecrypt(os.Args[2:])
+		return
+	}
+
 	if _, ok := os.LookupEnv("APP_NAME"); !ok {
 		if err := config.Setup(".env"); err != nil {
 			fmt.Println("failed to setup config", err)
@@ -19,6 +55,12 @@ func main() {
 		}
 	}
 
+	// generating a key pair needs no database
+	if len(os.Args) >= 2 && os.Args[1] == "peer-keygen" {
+		peerKeygen()
+		return
+	}
+
 	db, err := database.NewDatabase()
 	if err != nil {
 		fmt.Println("Could not connect to database", err)
@@ -34,8 +76,12 @@ func main() {
 	switch os.Args[1] {
 	case "migrate":
 		migrate(db.Conn)
+	case "audit-verify":
+		auditVerify(db.Conn)
+	case "sync-access":
+		syncAccess(db.Conn)
 	default:
-		fmt.Println("Unknown command. Available commands: migrate")
+		fmt.Println("Unknown command. Available commands: migrate, audit-verify, sync-access, peer-keygen, verify-proof, verify-certificate, trustee-decrypt")
 	}
 }
 
@@ -48,3 +94,226 @@ func migrate(db *sql.DB) {
 	}
 	fmt.Println("Finish migration...")
 }
+
+// auditVerify walks the hash chain of the audit log from the first record and reports the first broken link
+func auditVerify(db *sql.DB) {
+	const pageSize = 1000
+	auditRepo := repository.AuditRepository{Db: db, Log: logger.New()}
+	verifier := audit.NewVerifier()
+	for {
+		entries, err := auditRepo.Entries(context.Background(), verifier.Checked()+1, pageSize)
+		if err != nil {
+			fmt.Println("Could not read audit log: ", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if err := verifier.Check(entry); err != nil {
+				fmt.Println("Audit log is broken at", err)
+				os.Exit(1)
+			}
+		}
+		if len(entries) < pageSize {
+			break
+		}
+	}
+
+	fmt.Println("Audit log is valid")
+	fmt.Println("records:", verifier.Checked())
+}
+
+// syncAccess upserts the access of the private routes and lists the access that matches no route any more
+func syncAccess(db *sql.DB) {
+	log := logger.New()
+	// permissions cached by the running nodes are dropped when redis is reachable, they expire on their own otherwise
+	cache, err := redis.NewCache(context.Background(), os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PASSWORD"), 24*time.Hour)
+	if err != nil {
+		fmt.Println("Could not connect to redis, cached permissions expire on their own:", err)
+	} else {
+		defer cache.Close()
+	}
+
+	sync, _, err := usecase.AccessUC{Log: log, DB: db, Cache: cache}.Sync(context.Background(), route.Access())
+	if err != nil {
+		fmt.Println("Could not sync access: ", err)
+		os.Exit(1)
+	}
+
+	for _, access := range sync.Added {
+		fmt.Printf("added     %s (%s)\n", access.Path, access.Name)
+	}
+	for _, access := range sync.Renamed {
+		fmt.Printf("renamed   %s (%s)\n", access.Path, access.Name)
+	}
+	for _, access := range sync.Orphaned {
+		fmt.Printf("orphaned  %s (%s)\n", access.Path, access.Name)
+	}
+	fmt.Printf("Access synced: %d added, %d renamed, %d orphaned\n", len(sync.Added), len(sync.Renamed), len(sync.Orphaned))
+}
+
+// peerKeygen prints a new Ed25519 key pair for a node. The public key is registered as a peer on the other nodes.
+// A certifier key is generated the same way, its public key is published for verify-certificate.
+func peerKeygen() {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Println("Could not generate key pair: ", err)
+		os.Exit(1)
+	}
+	fmt.Println("public key :", base64.StdEncoding.EncodeToString(publicKey))
+	fmt.Println("private key:", hex.EncodeToString(privateKey.Seed()))
+}
+
+// verifyProof checks a proof saved from GET /verify/{receipt} against its root, and against the receipt code when given.
+// The root should be compared with the root published by the nodes (GET /ledger/root) at the same tree size.
+func verifyProof(args []string) {
+	if len(args) < 1 {
+		fmt.Println("No proof file. try with: go run cmd/main.go verify-proof proof.json [receipt]")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Println("Could not read proof: ", err)
+		os.Exit(1)
+	}
+
+	var proof ledger.Proof
+	if err := json.Unmarshal(data, &proof); err != nil {
+		fmt.Println("Could not decode proof: ", err)
+		os.Exit(1)
+	}
+	if proof.Height == 0 {
+		fmt.Println("The ballot is not in the ledger yet, request the proof again later")
+		os.Exit(1)
+	}
+
+	if err := proof.Verify(); err != nil {
+		fmt.Println("Proof is invalid: ", err)
+		os.Exit(1)
+	}
+	if len(args) >= 2 {
+		if err := proof.VerifyReceipt(args[1]); err != nil {
+			fmt.Println("Proof is invalid: ", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("Proof is valid")
+	fmt.Println("height   :", proof.Height)
+	fmt.Println("tree size:", proof.TreeSize)
+	fmt.Println("root     :", hex.EncodeToString(proof.Root))
+}
+
+// verifyCertificate checks a bundle saved from GET /elections/{id}/certificate. The public keys of the certifiers,
+// base64 encoded as published by the election authority, may follow the file, each of them must have signed it.
+func verifyCertificate(args []string) {
+	if len(args) < 1 {
+		fmt.Println("No certificate file. try with: go run cmd/main.go verify-certificate certificate.json [public keys]")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Println("Could not read certificate: ", err)
+		os.Exit(1)
+	}
+
+	var bundle dto.CertificateResponse
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		fmt.Println("Could not decode certificate: ", err)
+		os.Exit(1)
+	}
+	signatures, err := bundle.ToSignatures()
+	if err != nil {
+		fmt.Println("Certificate is invalid: ", err)
+		os.Exit(1)
+	}
+
+	var trusted []ed25519.PublicKey
+	for _, arg := range args[1:] {
+		key, err := base64.StdEncoding.DecodeString(arg)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			fmt.Println("Invalid public key: ", arg)
+			os.Exit(1)
+		}
+		trusted = append(trusted, key)
+	}
+
+	if err := certificate.Verify(bundle.Document, bundle.SHA256, signatures, trusted); err != nil {
+		fmt.Println("Certificate is invalid: ", err)
+		os.Exit(1)
+	}
+
+	var document dto.CertificateDocument
+	if err := json.Unmarshal(bundle.Document, &document); err != nil {
+		fmt.Println("Could not decode certificate document: ", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Certificate is valid")
+	fmt.Println("election    :", document.Election.ID, document.Election.Name)
+	fmt.Println("certified at:", document.CertifiedAt)
+	fmt.Println("sha256      :", bundle.SHA256)
+	for _, signature := range signatures {
+		fmt.Println("signed by   :", base64.StdEncoding.EncodeToString(signature.PublicKey))
+	}
+	if len(trusted) == 0 {
+		fmt.Println("Compare the signers with the certifier keys published by the election authority")
+	}
+}
+
+// trusteeDecrypt prints the decryption request of a trustee for the encrypted tally of GET /elections/{id}/encrypted-tally.
+// The share file is the response of POST /elections/{id}/trustees/share, fetched by the trustee.
+func trusteeDecrypt(args []string) {
+	if len(args) < 2 {
+		fmt.Println("No share or tally file. try with: go run cmd/main.go trustee-decrypt share.json tally.json")
+		os.Exit(1)
+	}
+
+	var shareFile dto.TrusteeShareResponse
+	var tally dto.EncryptedTallyResponse
+	for i, v := range []interface{}{&shareFile, &tally} {
+		data, err := os.ReadFile(args[i])
+		if err != nil {
+			fmt.Println("Could not read file: ", err)
+			os.Exit(1)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			fmt.Println("Could not decode file: ", err)
+			os.Exit(1)
+		}
+	}
+
+	value, ok := new(big.Int).SetString(shareFile.Share, 16)
+	verificationKey, _ := new(big.Int).SetString(shareFile.VerificationKey, 16)
+	if !ok || verificationKey == nil || elgamal.Element(value).Cmp(verificationKey) != 0 {
+		fmt.Println("Share does not match its verification key")
+		os.Exit(1)
+	}
+	share := elgamal.Share{Index: shareFile.Index, Value: value}
+
+	candidateIDs := make([]int64, 0, len(tally.Candidates))
+	shares := make([]elgamal.DecryptionShare, 0, len(tally.Candidates))
+	for _, candidate := range tally.Candidates {
+		ciphertext, err := candidate.Ciphertext.ToEntity()
+		if err != nil {
+			fmt.Println("Invalid tally: ", err)
+			os.Exit(1)
+		}
+		decryptionShare, err := elgamal.PartialDecrypt(share, ciphertext)
+		if err != nil {
+			fmt.Println("Could not decrypt the tally: ", err)
+			os.Exit(1)
+		}
+		candidateIDs = append(candidateIDs, candidate.CandidateID)
+		shares = append(shares, decryptionShare)
+	}
+
+	var request dto.DecryptionRequest
+	request.FromEntity(candidateIDs, shares)
+	data, err := json.MarshalIndent(request, "", "  ")
+	if err != nil {
+		fmt.Println("Could not encode decryption: ", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
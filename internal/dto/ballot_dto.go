@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/ledger"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// CastBallotRequest holds the choices of a plaintext election, or the encrypted ballot of an encrypted election,
+// with the credential that entitles the anonymous voter to cast it
+type CastBallotRequest struct {
+	Credential BallotCredential `json:"credential"`
+	Choices    []int64          `json:"choices"`
+	Encrypted  *EncryptedBallot `json:"encrypted,omitempty"`
+}
+
+func (b *CastBallotRequest) Validate() error {
+	if err := b.Credential.Validate(); err != nil {
+		return err
+	}
+
+	if b.Encrypted != nil {
+		if len(b.Choices) > 0 {
+			return errors.New("encrypted ballot can not have choices")
+		}
+		if len(b.Encrypted.Ciphertexts) == 0 {
+			return errors.New("encrypted ciphertexts is required")
+		}
+		return nil
+	}
+
+	if len(b.Choices) == 0 {
+		return errors.New("choices is required")
+	}
+
+	seen := make(map[int64]bool, len(b.Choices))
+	for _, choice := range b.Choices {
+		if seen[choice] {
+			return errors.New("choices can not contain the same candidate twice")
+		}
+		seen[choice] = true
+	}
+
+	return nil
+}
+
+func (b *CastBallotRequest) ToEntity(electionID int64) model.Ballot {
+	return model.Ballot{
+		ElectionID: electionID,
+		Choices:    b.Choices,
+	}
+}
+
+type CastBallotResponse struct {
+	ElectionID int64  `json:"election_id"`
+	Message    string `json:"message"`
+	// Receipt lets the voter check the ballot is in the ledger with GET /verify/{receipt}, it is only shown once
+	Receipt string `json:"receipt"`
+}
+
+// ReceiptVerificationResponse is the inclusion proof of the ballot of a receipt. Status is pending while the ballot
+// waits for its batch, the proof fields are then empty. Hashes are hex encoded.
+type ReceiptVerificationResponse struct {
+	ElectionID int64           `json:"election_id"`
+	Status     string          `json:"status"`
+	Height     int64           `json:"height,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+	PrevHash   string          `json:"prev_hash,omitempty"`
+	TreeSize   int64           `json:"tree_size,omitempty"`
+	Root       string          `json:"root,omitempty"`
+	AuditPath  []string        `json:"audit_path,omitempty"`
+}
+
+func (d *ReceiptVerificationResponse) FromEntity(ballot model.Ballot, proof ledger.Proof) {
+	d.ElectionID = ballot.ElectionID
+	d.Status = "pending"
+	if proof.Height == 0 {
+		return
+	}
+
+	d.Status = "included"
+	d.Height = proof.Height
+	d.Payload = proof.Payload
+	d.PrevHash = hex.EncodeToString(proof.PrevHash)
+	d.TreeSize = proof.TreeSize
+	d.Root = hex.EncodeToString(proof.Root)
+	d.AuditPath = make([]string, 0, len(proof.AuditPath))
+	for _, hash := range proof.AuditPath {
+		d.AuditPath = append(d.AuditPath, hex.EncodeToString(hash))
+	}
+}
@@ -0,0 +1,103 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+)
+
+type AddCandidateRequest struct {
+	BallotNumber    int    `json:"ballot_number"`
+	Name            string `json:"name"`
+	RunningMateName string `json:"running_mate_name"`
+	Party           string `json:"party"`
+}
+
+func (c *AddCandidateRequest) Validate() error {
+	if c.BallotNumber <= 0 {
+		return errors.New("ballot_number must be greater than 0")
+	}
+
+	if len(c.Name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(c.Name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if len(c.RunningMateName) > 128 {
+		return errors.New("running_mate_name maximal 128 character")
+	}
+
+	if len(c.Party) > 128 {
+		return errors.New("party maximal 128 character")
+	}
+
+	return nil
+}
+
+func (c *AddCandidateRequest) ToEntity(electionID int64) model.Candidate {
+	return model.Candidate{
+		ElectionID:      electionID,
+		BallotNumber:    c.BallotNumber,
+		Name:            c.Name,
+		RunningMateName: c.RunningMateName,
+		Party:           c.Party,
+	}
+}
+
+type UpdateCandidateRequest struct {
+	ID              int64  `json:"id"`
+	BallotNumber    int    `json:"ballot_number"`
+	Name            string `json:"name"`
+	RunningMateName string `json:"running_mate_name"`
+	Party           string `json:"party"`
+}
+
+func (c *UpdateCandidateRequest) Validate(id int64) error {
+	if id != c.ID {
+		return errors.New("id not match with candidate id")
+	}
+
+	add := AddCandidateRequest{BallotNumber: c.BallotNumber, Name: c.Name, RunningMateName: c.RunningMateName, Party: c.Party}
+	return add.Validate()
+}
+
+func (c *UpdateCandidateRequest) ToEntity(electionID int64) model.Candidate {
+	return model.Candidate{
+		ID:              c.ID,
+		ElectionID:      electionID,
+		BallotNumber:    c.BallotNumber,
+		Name:            c.Name,
+		RunningMateName: c.RunningMateName,
+		Party:           c.Party,
+	}
+}
+
+type CandidateResponse struct {
+	ID              int64  `json:"id"`
+	ElectionID      int64  `json:"election_id"`
+	BallotNumber    int    `json:"ballot_number"`
+	Name            string `json:"name"`
+	RunningMateName string `json:"running_mate_name,omitempty"`
+	Party           string `json:"party,omitempty"`
+}
+
+func (c *CandidateResponse) FromEntity(candidate model.Candidate) {
+	c.ID = candidate.ID
+	c.ElectionID = candidate.ElectionID
+	c.BallotNumber = candidate.BallotNumber
+	c.Name = candidate.Name
+	c.RunningMateName = candidate.RunningMateName
+	c.Party = candidate.Party
+}
+
+func (c *CandidateResponse) ListFromEntity(candidates []model.Candidate) []CandidateResponse {
+	var list []CandidateResponse = make([]CandidateResponse, 0)
+	for _, candidate := range candidates {
+		var candidateResponse CandidateResponse
+		candidateResponse.FromEntity(candidate)
+		list = append(list, candidateResponse)
+	}
+	return list
+}
@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/certificate"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// CertificateVersion is the version of the certificate document format
+const CertificateVersion = 1
+
+// CertificateDocument is the results document of a certified election. The certifiers sign its canonical JSON form.
+// Seats is only set for an election with districts.
+type CertificateDocument struct {
+	Version     int                     `json:"version"`
+	Election    CertificateElection     `json:"election"`
+	CertifiedAt string                  `json:"certified_at"`
+	Results     ElectionResultResponse  `json:"results"`
+	Tally       TallySnapshotResponse   `json:"tally"`
+	Seats       *SeatAllocationResponse `json:"seats,omitempty"`
+}
+
+type CertificateElection struct {
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	CountingMethod string  `json:"counting_method"`
+	Seats          int     `json:"seats"`
+	SeatMethod     string  `json:"seat_method"`
+	Threshold      float64 `json:"threshold"`
+}
+
+func (d *CertificateElection) FromEntity(election model.Election) {
+	d.ID = election.ID
+	d.Name = election.Name
+	d.CountingMethod = election.CountingMethod
+	d.Seats = election.Seats
+	d.SeatMethod = election.SeatMethod
+	d.Threshold = election.Threshold
+}
+
+// CertificateResponse is the signed export bundle of a certified election, verified offline with verify-certificate
+type CertificateResponse struct {
+	ElectionID int64 `json:"election_id"`
+	// Document is the CertificateDocument the certifiers signed
+	Document json.RawMessage `json:"document" swaggertype:"object"`
+	// SHA256 is the hex encoded checksum of the canonical document
+	SHA256     string                         `json:"sha256"`
+	Signatures []CertificateSignatureResponse `json:"signatures"`
+}
+
+type CertificateSignatureResponse struct {
+	// PublicKey is the base64 encoded Ed25519 public key of the certifier
+	PublicKey string `json:"public_key"`
+	// Signature is the base64 encoded Ed25519 signature over the canonical document
+	Signature string `json:"signature"`
+}
+
+func (d *CertificateResponse) FromEntity(cert model.Certificate) {
+	d.ElectionID = cert.ElectionID
+	d.Document = json.RawMessage(cert.Document)
+	d.SHA256 = cert.SHA256
+	d.Signatures = make([]CertificateSignatureResponse, 0, len(cert.Signatures))
+	for _, signature := range cert.Signatures {
+		d.Signatures = append(d.Signatures, CertificateSignatureResponse{
+			PublicKey: base64.StdEncoding.EncodeToString(signature.PublicKey),
+			Signature: base64.StdEncoding.EncodeToString(signature.Signature),
+		})
+	}
+}
+
+// ToSignatures decodes the signatures of the bundle for certificate.Verify
+func (d *CertificateResponse) ToSignatures() ([]certificate.Signature, error) {
+	signatures := make([]certificate.Signature, 0, len(d.Signatures))
+	for _, item := range d.Signatures {
+		publicKey, err := base64.StdEncoding.DecodeString(item.PublicKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, errors.New("public_key must be a base64 encoded ed25519 public key")
+		}
+		signature, err := base64.StdEncoding.DecodeString(item.Signature)
+		if err != nil {
+			return nil, errors.New("signature must be base64 encoded")
+		}
+		signatures = append(signatures, certificate.Signature{PublicKey: publicKey, Signature: signature})
+	}
+	return signatures, nil
+}
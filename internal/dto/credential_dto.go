@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/blindsig"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// CredentialKeyResponse is the public key that signs the voting credentials of the election, base64 encoded
+// PKIX DER. The voter blinds the token with it and verifies the signature before casting the ballot.
+type CredentialKeyResponse struct {
+	ElectionID int64  `json:"election_id"`
+	PublicKey  string `json:"public_key"`
+}
+
+func (k *CredentialKeyResponse) FromEntity(electionID int64, publicKey *rsa.PublicKey) error {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	k.ElectionID = electionID
+	k.PublicKey = base64.StdEncoding.EncodeToString(der)
+	return nil
+}
+
+// CredentialRequest holds the token of the voter blinded with the credential key of the election, hex encoded
+type CredentialRequest struct {
+	Blinded string `json:"blinded"`
+}
+
+func (c *CredentialRequest) Validate() error {
+	if len(c.Blinded) == 0 {
+		return errors.New("blinded is required")
+	}
+	if _, err := hex.DecodeString(c.Blinded); err != nil {
+		return errors.New("blinded must be hex encoded")
+	}
+	return nil
+}
+
+func (c *CredentialRequest) ToEntity(electionID int64) model.Credential {
+	blinded, _ := hex.DecodeString(c.Blinded)
+	return model.Credential{
+		ElectionID: electionID,
+		Blinded:    blinded,
+	}
+}
+
+// CredentialResponse holds the signature of the blinded token, hex encoded. The voter unblinds it into the
+// signature of the token.
+type CredentialResponse struct {
+	ElectionID     int64  `json:"election_id"`
+	BlindSignature string `json:"blind_signature"`
+}
+
+// BallotCredential is the unblinded credential a ballot is cast with, hex encoded. It does not identify the voter.
+type BallotCredential struct {
+	Token     string `json:"token"`
+	Signature string `json:"signature"`
+}
+
+func (c *BallotCredential) Validate() error {
+	token, err := hex.DecodeString(c.Token)
+	if err != nil || len(token) != blindsig.TokenSize {
+		return errors.New("credential token must be 32 hex encoded bytes")
+	}
+	if len(c.Signature) == 0 {
+		return errors.New("credential signature is required")
+	}
+	if _, err := hex.DecodeString(c.Signature); err != nil {
+		return errors.New("credential signature must be hex encoded")
+	}
+	return nil
+}
+
+// ToEntity returns the token and its signature
+func (c *BallotCredential) ToEntity() ([]byte, []byte) {
+	token, _ := hex.DecodeString(c.Token)
+	signature, _ := hex.DecodeString(c.Signature)
+	return token, signature
+}
+
+func (c *BallotCredential) FromEntity(token []byte, signature []byte) {
+	c.Token = hex.EncodeToString(token)
+	c.Signature = hex.EncodeToString(signature)
+}
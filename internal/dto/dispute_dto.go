@@ -0,0 +1,183 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+)
+
+// DisputeRequest files a dispute against the tally form of a polling station or the recapitulation of a region,
+// exactly one of polling_station_id and region_id is given. Filer is the party or candidate filing the dispute.
+type DisputeRequest struct {
+	PollingStationID int64  `json:"polling_station_id"`
+	RegionID         int64  `json:"region_id"`
+	Filer            string `json:"filer"`
+	Reason           string `json:"reason"`
+}
+
+func (d *DisputeRequest) Validate() error {
+	if (d.PollingStationID > 0) == (d.RegionID > 0) {
+		return errors.New("either polling_station_id or region_id is required")
+	}
+
+	if d.PollingStationID < 0 || d.RegionID < 0 {
+		return errors.New("polling_station_id and region_id can not be negative")
+	}
+
+	if len(d.Filer) == 0 {
+		return errors.New("filer is required")
+	}
+	if len(d.Filer) > 255 {
+		return errors.New("filer is too long")
+	}
+
+	if len(d.Reason) == 0 {
+		return errors.New("reason is required")
+	}
+
+	return nil
+}
+
+func (d *DisputeRequest) ToEntity(electionID int64) model.Dispute {
+	return model.Dispute{
+		ElectionID:       electionID,
+		PollingStationID: d.PollingStationID,
+		RegionID:         d.RegionID,
+		Filer:            d.Filer,
+		Reason:           d.Reason,
+	}
+}
+
+type DisputeResponse struct {
+	ID               int64  `json:"id"`
+	ElectionID       int64  `json:"election_id"`
+	PollingStationID int64  `json:"polling_station_id,omitempty"`
+	RegionID         int64  `json:"region_id,omitempty"`
+	Filer            string `json:"filer"`
+	Reason           string `json:"reason"`
+	Status           string `json:"status"`
+	Resolution       string `json:"resolution,omitempty"`
+	FiledAt          string `json:"filed_at"`
+	FiledBy          int64  `json:"filed_by"`
+	ResolvedAt       string `json:"resolved_at,omitempty"`
+	ResolvedBy       int64  `json:"resolved_by,omitempty"`
+}
+
+func (d *DisputeResponse) FromEntity(dispute model.Dispute) {
+	d.ID = dispute.ID
+	d.ElectionID = dispute.ElectionID
+	d.PollingStationID = dispute.PollingStationID
+	d.RegionID = dispute.RegionID
+	d.Filer = dispute.Filer
+	d.Reason = dispute.Reason
+	d.Status = dispute.Status
+	d.Resolution = dispute.Resolution
+	d.FiledAt = dispute.FiledAt
+	d.FiledBy = dispute.FiledBy
+	d.ResolvedAt = dispute.ResolvedAt
+	d.ResolvedBy = dispute.ResolvedBy
+}
+
+func (d *DisputeResponse) ListFromEntity(disputes []model.Dispute) []DisputeResponse {
+	var list []DisputeResponse = make([]DisputeResponse, 0)
+	for _, dispute := range disputes {
+		var disputeResponse DisputeResponse
+		disputeResponse.FromEntity(dispute)
+		list = append(list, disputeResponse)
+	}
+	return list
+}
+
+// DisputeTransitionRequest moves a dispute along filed, under_review, recount_ordered and resolved.
+// A note is required to order a recount and to resolve, the note of the resolution is the resolution of the dispute.
+type DisputeTransitionRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+func (d *DisputeTransitionRequest) Validate() error {
+	switch d.Status {
+	case model.DisputeStatusUnderReview:
+	case model.DisputeStatusRecountOrdered, model.DisputeStatusResolved:
+		if len(d.Note) == 0 {
+			return errors.New("note is required to order a recount or resolve")
+		}
+	default:
+		return errors.New("status must be under_review, recount_ordered or resolved")
+	}
+	return nil
+}
+
+type DisputeTransitionResponse struct {
+	ID         int64  `json:"id"`
+	DisputeID  int64  `json:"dispute_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Note       string `json:"note"`
+	UserID     int64  `json:"user_id"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func (d *DisputeTransitionResponse) FromEntity(transition model.DisputeTransition) {
+	d.ID = transition.ID
+	d.DisputeID = transition.DisputeID
+	d.FromStatus = transition.FromStatus
+	d.ToStatus = transition.ToStatus
+	d.Note = transition.Note
+	d.UserID = transition.UserID
+	d.CreatedAt = transition.CreatedAt
+}
+
+func (d *DisputeTransitionResponse) ListFromEntity(transitions []model.DisputeTransition) []DisputeTransitionResponse {
+	var list []DisputeTransitionResponse = make([]DisputeTransitionResponse, 0)
+	for _, transition := range transitions {
+		var transitionResponse DisputeTransitionResponse
+		transitionResponse.FromEntity(transition)
+		list = append(list, transitionResponse)
+	}
+	return list
+}
+
+type DisputeEvidenceResponse struct {
+	ID          int64  `json:"id"`
+	DisputeID   int64  `json:"dispute_id"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Filename    string `json:"filename"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   int64  `json:"created_by"`
+}
+
+func (d *DisputeEvidenceResponse) FromEntity(evidence model.DisputeEvidence) {
+	d.ID = evidence.ID
+	d.DisputeID = evidence.DisputeID
+	d.SHA256 = evidence.SHA256
+	d.ContentType = evidence.ContentType
+	d.Size = evidence.Size
+	d.Filename = evidence.Filename
+	d.CreatedAt = evidence.CreatedAt
+	d.CreatedBy = evidence.CreatedBy
+}
+
+func (d *DisputeEvidenceResponse) ListFromEntity(evidences []model.DisputeEvidence) []DisputeEvidenceResponse {
+	var list []DisputeEvidenceResponse = make([]DisputeEvidenceResponse, 0)
+	for _, evidence := range evidences {
+		var evidenceResponse DisputeEvidenceResponse
+		evidenceResponse.FromEntity(evidence)
+		list = append(list, evidenceResponse)
+	}
+	return list
+}
+
+// DisputeDetailResponse is a dispute with the history of its status and the evidences attached to it
+type DisputeDetailResponse struct {
+	DisputeResponse
+	Transitions []DisputeTransitionResponse `json:"transitions"`
+	Evidences   []DisputeEvidenceResponse   `json:"evidences"`
+}
+
+func (d *DisputeDetailResponse) FromEntity(dispute model.Dispute, transitions []model.DisputeTransition, evidences []model.DisputeEvidence) {
+	d.DisputeResponse.FromEntity(dispute)
+	d.Transitions = (&DisputeTransitionResponse{}).ListFromEntity(transitions)
+	d.Evidences = (&DisputeEvidenceResponse{}).ListFromEntity(evidences)
+}
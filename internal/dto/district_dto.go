@@ -0,0 +1,188 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+)
+
+type AddDistrictRequest struct {
+	Name  string `json:"name"`
+	Seats int    `json:"seats"`
+}
+
+func (d *AddDistrictRequest) Validate() error {
+	if len(d.Name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(d.Name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if d.Seats <= 0 {
+		return errors.New("seats must be greater than 0")
+	}
+
+	return nil
+}
+
+func (d *AddDistrictRequest) ToEntity(electionID int64) model.District {
+	return model.District{
+		ElectionID: electionID,
+		Name:       d.Name,
+		Seats:      d.Seats,
+	}
+}
+
+type UpdateDistrictRequest struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Seats int    `json:"seats"`
+}
+
+func (d *UpdateDistrictRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with district id")
+	}
+
+	add := AddDistrictRequest{Name: d.Name, Seats: d.Seats}
+	return add.Validate()
+}
+
+func (d *UpdateDistrictRequest) ToEntity(electionID int64) model.District {
+	return model.District{
+		ID:         d.ID,
+		ElectionID: electionID,
+		Name:       d.Name,
+		Seats:      d.Seats,
+	}
+}
+
+type DistrictResponse struct {
+	ID         int64  `json:"id"`
+	ElectionID int64  `json:"election_id"`
+	Name       string `json:"name"`
+	Seats      int    `json:"seats"`
+}
+
+func (d *DistrictResponse) FromEntity(district model.District) {
+	d.ID = district.ID
+	d.ElectionID = district.ElectionID
+	d.Name = district.Name
+	d.Seats = district.Seats
+}
+
+func (d *DistrictResponse) ListFromEntity(districts []model.District) []DistrictResponse {
+	var list []DistrictResponse = make([]DistrictResponse, 0)
+	for _, district := range districts {
+		var districtResponse DistrictResponse
+		districtResponse.FromEntity(district)
+		list = append(list, districtResponse)
+	}
+	return list
+}
+
+type PartyVotesRequest struct {
+	Party string `json:"party"`
+	Votes int64  `json:"votes"`
+}
+
+// DistrictVotesRequest holds the vote totals of every party in a district. It replaces the votes entered before.
+type DistrictVotesRequest struct {
+	Votes []PartyVotesRequest `json:"votes"`
+}
+
+func (d *DistrictVotesRequest) Validate() error {
+	if len(d.Votes) == 0 {
+		return errors.New("votes is required")
+	}
+
+	if len(d.Votes) > 100 {
+		return errors.New("votes maximal 100 parties")
+	}
+
+	seen := make(map[string]bool, len(d.Votes))
+	for _, partyVotes := range d.Votes {
+		if len(partyVotes.Party) == 0 {
+			return errors.New("party is required")
+		}
+
+		if len(partyVotes.Party) > 128 {
+			return errors.New("party maximal 128 character")
+		}
+
+		if partyVotes.Votes < 0 {
+			return errors.New("votes can not be negative")
+		}
+
+		if seen[partyVotes.Party] {
+			return errors.New("votes can not contain the same party twice")
+		}
+		seen[partyVotes.Party] = true
+	}
+
+	return nil
+}
+
+func (d *DistrictVotesRequest) ToEntity(districtID int64) []model.PartyVotes {
+	list := make([]model.PartyVotes, 0, len(d.Votes))
+	for _, partyVotes := range d.Votes {
+		list = append(list, model.PartyVotes{DistrictID: districtID, Party: partyVotes.Party, Votes: partyVotes.Votes})
+	}
+	return list
+}
+
+type PartySeatsResponse struct {
+	Party    string `json:"party"`
+	Votes    int64  `json:"votes"`
+	Seats    int    `json:"seats"`
+	Eligible bool   `json:"eligible"`
+}
+
+type DistrictSeatsResponse struct {
+	DistrictID  int64                `json:"district_id"`
+	Name        string               `json:"name"`
+	Seats       int                  `json:"seats"`
+	Unallocated int                  `json:"unallocated"`
+	TieBreak    bool                 `json:"tie_break"`
+	Parties     []PartySeatsResponse `json:"parties"`
+}
+
+// SeatAllocationResponse holds the seats won by every party, nationally and per district.
+// Parties below the threshold are listed with eligible set to false.
+type SeatAllocationResponse struct {
+	ElectionID int64                   `json:"election_id"`
+	SeatMethod string                  `json:"seat_method"`
+	Threshold  float64                 `json:"threshold"`
+	Votes      int64                   `json:"votes"`
+	Parties    []PartySeatsResponse    `json:"parties"`
+	Districts  []DistrictSeatsResponse `json:"districts"`
+}
+
+func partySeatsFromEntity(parties []model.PartySeats) []PartySeatsResponse {
+	list := make([]PartySeatsResponse, 0, len(parties))
+	for _, party := range parties {
+		list = append(list, PartySeatsResponse{Party: party.Party, Votes: party.Votes, Seats: party.Seats, Eligible: party.Eligible})
+	}
+	return list
+}
+
+func (s *SeatAllocationResponse) FromEntity(electionID int64, allocation model.SeatAllocation) {
+	s.ElectionID = electionID
+	s.SeatMethod = allocation.Method
+	s.Threshold = allocation.Threshold
+	s.Votes = allocation.Votes
+	s.Parties = partySeatsFromEntity(allocation.Parties)
+
+	s.Districts = make([]DistrictSeatsResponse, 0, len(allocation.Districts))
+	for _, district := range allocation.Districts {
+		s.Districts = append(s.Districts, DistrictSeatsResponse{
+			DistrictID:  district.DistrictID,
+			Name:        district.Name,
+			Seats:       district.Seats,
+			Unallocated: district.Unallocated,
+			TieBreak:    district.TieBreak,
+			Parties:     partySeatsFromEntity(district.Parties),
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"time"
+)
+
+// electionZones are the Indonesian time zones, fixed offsets since none of them observes daylight saving time
+var electionZones = map[string]*time.Location{
+	model.TimezoneWIB:  time.FixedZone(model.TimezoneWIB, 7*60*60),
+	model.TimezoneWITA: time.FixedZone(model.TimezoneWITA, 8*60*60),
+	model.TimezoneWIT:  time.FixedZone(model.TimezoneWIT, 9*60*60),
+}
+
+// localTimeLayouts are the accepted layouts of a voting window time without an offset, read in the election timezone
+var localTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// validateCounting checks the counting method and the number of seats, filling the defaults when they are empty
+func validateCounting(method *string, seats *int) error {
+	if len(*method) == 0 {
+		*method = model.CountingMethodPlurality
+	}
+
+	if *seats == 0 {
+		*seats = 1
+	}
+
+	if *seats < 0 {
+		return errors.New("seats must be positive")
+	}
+
+	switch *method {
+	case model.CountingMethodPlurality, model.CountingMethodApproval, model.CountingMethodSTV:
+	case model.CountingMethodIRV:
+		if *seats != 1 {
+			return errors.New("irv elects exactly one seat")
+		}
+	default:
+		return errors.New("counting_method is unknown")
+	}
+
+	return nil
+}
+
+// validateSeatAllocation checks the party-list seat allocation settings, filling the default method when it is empty
+func validateSeatAllocation(method *string, threshold float64) error {
+	if len(*method) == 0 {
+		*method = model.SeatMethodSainteLague
+	}
+
+	if *method != model.SeatMethodSainteLague && *method != model.SeatMethodDHondt {
+		return errors.New("seat_method is unknown")
+	}
+
+	if threshold < 0 || threshold > 100 {
+		return errors.New("threshold must be a percentage between 0 and 100")
+	}
+
+	return nil
+}
+
+// validateSchedule checks the voting window, filling the default timezone when it is empty. The times are read in
+// the timezone unless they carry an offset, and are converted to RFC 3339 UTC.
+func validateSchedule(opensAt *string, closesAt *string, timezone *string) error {
+	if len(*timezone) == 0 {
+		*timezone = model.TimezoneWIB
+	}
+
+	location, ok := electionZones[*timezone]
+	if !ok {
+		return errors.New("timezone must be WIB, WITA or WIT")
+	}
+
+	var window [2]time.Time
+	for i, value := range []*string{opensAt, closesAt} {
+		if len(*value) == 0 {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, *value)
+		for _, layout := range localTimeLayouts {
+			if err == nil {
+				break
+			}
+			t, err = time.ParseInLocation(layout, *value, location)
+		}
+		if err != nil {
+			return errors.New("opens_at and closes_at must be formatted as 2006-01-02T15:04")
+		}
+
+		window[i] = t
+		*value = t.UTC().Format(time.RFC3339)
+	}
+
+	if !window[0].IsZero() && !window[1].IsZero() && !window[1].After(window[0]) {
+		return errors.New("closes_at must be after opens_at")
+	}
+
+	return nil
+}
+
+// localTime formats an RFC 3339 UTC time of the voting window in the election timezone
+func localTime(value string, timezone string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	location, ok := electionZones[timezone]
+	if err != nil || !ok {
+		return value
+	}
+	return t.In(location).Format(time.RFC3339)
+}
+
+type ElectionCreateRequest struct {
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	CountingMethod string  `json:"counting_method"`
+	Seats          int     `json:"seats"`
+	SeatMethod     string  `json:"seat_method"`
+	Threshold      float64 `json:"threshold"`
+	// OpensAt and ClosesAt are the voting window, the scheduler opens and closes the election at these times.
+	// They are local times in the timezone (2006-01-02T15:04), leave them empty to open or close the election by hand.
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+	// Timezone is WIB, WITA or WIT, WIB when empty
+	Timezone string `json:"timezone"`
+}
+
+func (e *ElectionCreateRequest) Validate() error {
+	if len(e.Name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(e.Name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if err := validateCounting(&e.CountingMethod, &e.Seats); err != nil {
+		return err
+	}
+
+	if err := validateSchedule(&e.OpensAt, &e.ClosesAt, &e.Timezone); err != nil {
+		return err
+	}
+
+	return validateSeatAllocation(&e.SeatMethod, e.Threshold)
+}
+
+func (e *ElectionCreateRequest) ToEntity() model.Election {
+	return model.Election{
+		Name:           e.Name,
+		Description:    e.Description,
+		CountingMethod: e.CountingMethod,
+		Seats:          e.Seats,
+		SeatMethod:     e.SeatMethod,
+		Threshold:      e.Threshold,
+		OpensAt:        e.OpensAt,
+		ClosesAt:       e.ClosesAt,
+		Timezone:       e.Timezone,
+	}
+}
+
+type ElectionUpdateRequest struct {
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	CountingMethod string  `json:"counting_method"`
+	Seats          int     `json:"seats"`
+	SeatMethod     string  `json:"seat_method"`
+	Threshold      float64 `json:"threshold"`
+	// OpensAt and ClosesAt are the voting window, the scheduler opens and closes the election at these times.
+	// They are local times in the timezone (2006-01-02T15:04), leave them empty to open or close the election by hand.
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+	// Timezone is WIB, WITA or WIT, WIB when empty
+	Timezone string `json:"timezone"`
+}
+
+func (e *ElectionUpdateRequest) Validate(id int64) error {
+	if id != e.ID {
+		return errors.New("id not match with election id")
+	}
+
+	if len(e.Name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(e.Name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if err := validateCounting(&e.CountingMethod, &e.Seats); err != nil {
+		return err
+	}
+
+	if err := validateSchedule(&e.OpensAt, &e.ClosesAt, &e.Timezone); err != nil {
+		return err
+	}
+
+	return validateSeatAllocation(&e.SeatMethod, e.Threshold)
+}
+
+func (e *ElectionUpdateRequest) ToEntity() model.Election {
+	return model.Election{
+		ID:             e.ID,
+		Name:           e.Name,
+		Description:    e.Description,
+		CountingMethod: e.CountingMethod,
+		Seats:          e.Seats,
+		SeatMethod:     e.SeatMethod,
+		Threshold:      e.Threshold,
+		OpensAt:        e.OpensAt,
+		ClosesAt:       e.ClosesAt,
+		Timezone:       e.Timezone,
+	}
+}
+
+type ElectionTransitionRequest struct {
+	Status string `json:"status"`
+}
+
+func (e *ElectionTransitionRequest) Validate() error {
+	if len(e.Status) == 0 {
+		return errors.New("status is required")
+	}
+
+	switch e.Status {
+	case model.ElectionStatusDraft,
+		model.ElectionStatusScheduled,
+		model.ElectionStatusOpen,
+		model.ElectionStatusClosed,
+		model.ElectionStatusTallied,
+		model.ElectionStatusCertified:
+	default:
+		return errors.New("status is unknown")
+	}
+
+	return nil
+}
+
+type ElectionResponse struct {
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Status         string  `json:"status"`
+	CountingMethod string  `json:"counting_method"`
+	Seats          int     `json:"seats"`
+	SeatMethod     string  `json:"seat_method"`
+	Threshold      float64 `json:"threshold"`
+	// PublicKey is the hex encoded ElGamal key the ballots are encrypted under, empty for a plaintext election
+	PublicKey        string `json:"public_key,omitempty"`
+	TrusteeThreshold int    `json:"trustee_threshold,omitempty"`
+	// OpensAt and ClosesAt are the voting window in RFC 3339 with the offset of the timezone
+	OpensAt  string `json:"opens_at,omitempty"`
+	ClosesAt string `json:"closes_at,omitempty"`
+	Timezone string `json:"timezone"`
+}
+
+func (e *ElectionResponse) FromEntity(election model.Election) {
+	e.ID = election.ID
+	e.Name = election.Name
+	e.Description = election.Description
+	e.Status = election.Status
+	e.CountingMethod = election.CountingMethod
+	e.Seats = election.Seats
+	e.SeatMethod = election.SeatMethod
+	e.Threshold = election.Threshold
+	if len(election.PublicKey) > 0 {
+		e.PublicKey = encodeBytes(election.PublicKey)
+		e.TrusteeThreshold = election.TrusteeThreshold
+	}
+	if len(election.OpensAt) > 0 {
+		e.OpensAt = localTime(election.OpensAt, election.Timezone)
+	}
+	if len(election.ClosesAt) > 0 {
+		e.ClosesAt = localTime(election.ClosesAt, election.Timezone)
+	}
+	e.Timezone = election.Timezone
+}
+
+func (e *ElectionResponse) ListFromEntity(elections []model.Election) []ElectionResponse {
+	var list []ElectionResponse = make([]ElectionResponse, 0)
+	for _, election := range elections {
+		var electionResponse ElectionResponse
+		electionResponse.FromEntity(election)
+		list = append(list, electionResponse)
+	}
+	return list
+}
+
+type ElectionTransitionResponse struct {
+	ID         int64  `json:"id"`
+	ElectionID int64  `json:"election_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	// UserID is 0 for a transition made by the scheduler
+	UserID    int64  `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (e *ElectionTransitionResponse) FromEntity(transition model.ElectionTransition) {
+	e.ID = transition.ID
+	e.ElectionID = transition.ElectionID
+	e.FromStatus = transition.FromStatus
+	e.ToStatus = transition.ToStatus
+	e.UserID = transition.UserID
+	e.CreatedAt = transition.CreatedAt
+}
+
+func (e *ElectionTransitionResponse) ListFromEntity(transitions []model.ElectionTransition) []ElectionTransitionResponse {
+	var list []ElectionTransitionResponse = make([]ElectionTransitionResponse, 0)
+	for _, transition := range transitions {
+		var transitionResponse ElectionTransitionResponse
+		transitionResponse.FromEntity(transition)
+		list = append(list, transitionResponse)
+	}
+	return list
+}
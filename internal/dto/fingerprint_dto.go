@@ -0,0 +1,118 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/biometric"
+	"encoding/base64"
+	"errors"
+)
+
+// maxTemplateSize bounds the decoded template, a view of 255 minutiae with extended data fits well within it
+const maxTemplateSize = 64 << 10
+
+// FingerprintRequest holds a finger minutiae record read by the scanner
+type FingerprintRequest struct {
+	// Format is iso19794-2 or ansi378
+	Format string `json:"format"`
+	// Template is the base64 encoded minutiae record
+	Template string `json:"template"`
+}
+
+func (d *FingerprintRequest) Validate() error {
+	if !biometric.ValidFormat(biometric.Format(d.Format)) {
+		return biometric.ErrUnknownFormat
+	}
+
+	if len(d.Template) == 0 {
+		return errors.New("template is required")
+	}
+	if base64.StdEncoding.DecodedLen(len(d.Template)) > maxTemplateSize {
+		return errors.New("template is too large")
+	}
+
+	if _, err := d.ToTemplate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Decode returns the minutiae record
+func (d *FingerprintRequest) Decode() ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(d.Template)
+	if err != nil {
+		return nil, errors.New("template must be base64 encoded")
+	}
+	return data, nil
+}
+
+// ToTemplate decodes and parses the minutiae record
+func (d *FingerprintRequest) ToTemplate() (biometric.Template, error) {
+	data, err := d.Decode()
+	if err != nil {
+		return biometric.Template{}, err
+	}
+	return biometric.Parse(biometric.Format(d.Format), data)
+}
+
+// FingerprintEnrollRequest enrolls the fingerprint of a voter.
+// Set AllowDuplicate to enroll a fingerprint that matches the fingerprint of another voter.
+type FingerprintEnrollRequest struct {
+	FingerprintRequest
+	AllowDuplicate bool `json:"allow_duplicate"`
+}
+
+// FingerprintResponse describes an enrolled fingerprint, the template itself is never returned
+type FingerprintResponse struct {
+	VoterID        int64  `json:"voter_id"`
+	Format         string `json:"format"`
+	FingerPosition int    `json:"finger_position"`
+	MinutiaeCount  int    `json:"minutiae_count"`
+	Quality        int    `json:"quality"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at,omitempty"`
+}
+
+func (d *FingerprintResponse) FromEntity(fingerprint model.VoterFingerprint) {
+	d.VoterID = fingerprint.VoterID
+	d.Format = fingerprint.Format
+	d.FingerPosition = fingerprint.FingerPosition
+	d.MinutiaeCount = fingerprint.MinutiaeCount
+	d.Quality = fingerprint.Quality
+	d.CreatedAt = fingerprint.CreatedAt
+	d.UpdatedAt = fingerprint.UpdatedAt
+}
+
+type FingerprintMatchResponse struct {
+	VoterID int64 `json:"voter_id"`
+	Score   int   `json:"score"`
+}
+
+func (d *FingerprintMatchResponse) FromEntity(match model.FingerprintMatch) {
+	d.VoterID = match.VoterID
+	d.Score = match.Score
+}
+
+func (d *FingerprintMatchResponse) ListFromEntity(matches []model.FingerprintMatch) []FingerprintMatchResponse {
+	var list []FingerprintMatchResponse = make([]FingerprintMatchResponse, 0)
+	for _, match := range matches {
+		var matchResponse FingerprintMatchResponse
+		matchResponse.FromEntity(match)
+		list = append(list, matchResponse)
+	}
+	return list
+}
+
+// FingerprintVerifyResponse is the result of a 1:1 verification, Matched is true when Score reaches Threshold
+type FingerprintVerifyResponse struct {
+	VoterID   int64 `json:"voter_id"`
+	Score     int   `json:"score"`
+	Threshold int   `json:"threshold"`
+	Matched   bool  `json:"matched"`
+}
+
+// FingerprintDuplicateResponse lists the voters whose enrolled fingerprint matches the fingerprint being enrolled
+type FingerprintDuplicateResponse struct {
+	Message string                     `json:"message"`
+	Matches []FingerprintMatchResponse `json:"matches"`
+}
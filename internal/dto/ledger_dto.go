@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/ledger"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// LedgerHeadResponse is the last entry of a ledger, hashes are hex encoded
+type LedgerHeadResponse struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+	Root   string `json:"root"`
+}
+
+func (d *LedgerHeadResponse) FromEntity(head ledger.Head) {
+	d.Height = head.Height
+	d.Hash = hex.EncodeToString(head.Hash)
+	d.Root = hex.EncodeToString(head.Root)
+}
+
+type LedgerEntryResponse struct {
+	Height   int64           `json:"height"`
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+	Root     string          `json:"root"`
+}
+
+func (d *LedgerEntryResponse) FromEntity(entry ledger.Entry) {
+	d.Height = entry.Height
+	d.Payload = entry.Payload
+	d.PrevHash = hex.EncodeToString(entry.PrevHash)
+	d.Hash = hex.EncodeToString(entry.Hash)
+	d.Root = hex.EncodeToString(entry.Root)
+}
+
+func (d *LedgerEntryResponse) ListFromEntity(entries []ledger.Entry) []LedgerEntryResponse {
+	var list []LedgerEntryResponse = make([]LedgerEntryResponse, 0)
+	for _, entry := range entries {
+		var entryResponse LedgerEntryResponse
+		entryResponse.FromEntity(entry)
+		list = append(list, entryResponse)
+	}
+	return list
+}
+
+// LedgerReplicaResponse is the head of the replica of the ledger of the node with the base64 encoded public key
+type LedgerReplicaResponse struct {
+	PublicKey string `json:"public_key"`
+	LedgerHeadResponse
+}
+
+func (d *LedgerReplicaResponse) ListFromEntity(replicas []model.LedgerReplica) []LedgerReplicaResponse {
+	var list []LedgerReplicaResponse = make([]LedgerReplicaResponse, 0)
+	for _, replica := range replicas {
+		var replicaResponse LedgerReplicaResponse
+		replicaResponse.PublicKey = base64.StdEncoding.EncodeToString(replica.PublicKey)
+		replicaResponse.Height = replica.Height
+		replicaResponse.Hash = hex.EncodeToString(replica.Hash)
+		replicaResponse.Root = hex.EncodeToString(replica.Root)
+		list = append(list, replicaResponse)
+	}
+	return list
+}
+
+// LedgerSyncResponse is the outcome of replicating the ledger of a peer
+type LedgerSyncResponse struct {
+	PeerID int64  `json:"peer_id"`
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (d *LedgerSyncResponse) ListFromEntity(syncs []model.LedgerSync) []LedgerSyncResponse {
+	var list []LedgerSyncResponse = make([]LedgerSyncResponse, 0)
+	for _, sync := range syncs {
+		list = append(list, LedgerSyncResponse{
+			PeerID: sync.PeerID,
+			Height: sync.Height,
+			Hash:   hex.EncodeToString(sync.Hash),
+			Error:  sync.Error,
+		})
+	}
+	return list
+}
+
+type LedgerDivergenceResponse struct {
+	ID           int64  `json:"id"`
+	OriginPeerID int64  `json:"origin_peer_id"`
+	ReportedBy   int64  `json:"reported_by"`
+	Height       int64  `json:"height"`
+	LocalHash    string `json:"local_hash"`
+	RemoteHash   string `json:"remote_hash"`
+	Reason       string `json:"reason"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func (d *LedgerDivergenceResponse) FromEntity(divergence model.LedgerDivergence) {
+	d.ID = divergence.ID
+	d.OriginPeerID = divergence.OriginPeerID
+	d.ReportedBy = divergence.ReportedBy
+	d.Height = divergence.Height
+	d.LocalHash = hex.EncodeToString(divergence.LocalHash)
+	d.RemoteHash = hex.EncodeToString(divergence.RemoteHash)
+	d.Reason = divergence.Reason
+	d.CreatedAt = divergence.CreatedAt
+}
+
+func (d *LedgerDivergenceResponse) ListFromEntity(divergences []model.LedgerDivergence) []LedgerDivergenceResponse {
+	var list []LedgerDivergenceResponse = make([]LedgerDivergenceResponse, 0)
+	for _, divergence := range divergences {
+		var divergenceResponse LedgerDivergenceResponse
+		divergenceResponse.FromEntity(divergence)
+		list = append(list, divergenceResponse)
+	}
+	return list
+}
@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/url"
+)
+
+type AddPeerRequest struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	// PublicKey is the base64 encoded 32 byte Ed25519 public key of the peer
+	PublicKey string `json:"public_key"`
+	// RemoteID is the id this node is registered under at the peer, needed to replicate the ledger of the peer
+	RemoteID int64 `json:"remote_id,omitempty"`
+}
+
+func (d *AddPeerRequest) Validate() error {
+	if err := validatePeer(d.Name, d.Endpoint, d.RemoteID); err != nil {
+		return err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(d.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("public_key must be a base64 encoded ed25519 public key")
+	}
+
+	return nil
+}
+
+func (d *AddPeerRequest) ToEntity() model.Peer {
+	key, _ := base64.StdEncoding.DecodeString(d.PublicKey)
+	return model.Peer{
+		Name:      d.Name,
+		Endpoint:  d.Endpoint,
+		PublicKey: key,
+		RemoteID:  d.RemoteID,
+	}
+}
+
+type UpdatePeerRequest struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	RemoteID int64  `json:"remote_id,omitempty"`
+}
+
+func (d *UpdatePeerRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with peer id")
+	}
+
+	return validatePeer(d.Name, d.Endpoint, d.RemoteID)
+}
+
+func (d *UpdatePeerRequest) ToEntity() model.Peer {
+	return model.Peer{
+		ID:       d.ID,
+		Name:     d.Name,
+		Endpoint: d.Endpoint,
+		RemoteID: d.RemoteID,
+	}
+}
+
+func validatePeer(name string, endpoint string, remoteID int64) error {
+	if len(name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if len(endpoint) > 255 {
+		return errors.New("endpoint maximal 255 character")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.New("endpoint must be an http or https url")
+	}
+
+	if remoteID < 0 {
+		return errors.New("remote_id must be a valid id")
+	}
+
+	return nil
+}
+
+// PeerStatusRequest approves (active), suspends or revokes a peer
+type PeerStatusRequest struct {
+	Status string `json:"status"`
+}
+
+func (d *PeerStatusRequest) Validate() error {
+	switch d.Status {
+	case model.PeerStatusActive, model.PeerStatusSuspended, model.PeerStatusRevoked:
+	default:
+		return errors.New("status must be active, suspended or revoked")
+	}
+
+	return nil
+}
+
+type PeerResponse struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Endpoint  string `json:"endpoint"`
+	PublicKey string `json:"public_key"`
+	RemoteID  int64  `json:"remote_id,omitempty"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+func (d *PeerResponse) FromEntity(peer model.Peer) {
+	d.ID = peer.ID
+	d.Name = peer.Name
+	d.Endpoint = peer.Endpoint
+	d.PublicKey = base64.StdEncoding.EncodeToString(peer.PublicKey)
+	d.RemoteID = peer.RemoteID
+	d.Status = peer.Status
+	d.CreatedAt = peer.CreatedAt
+	d.UpdatedAt = peer.UpdatedAt
+}
+
+func (d *PeerResponse) ListFromEntity(peers []model.Peer) []PeerResponse {
+	var list []PeerResponse = make([]PeerResponse, 0)
+	for _, peer := range peers {
+		var peerResponse PeerResponse
+		peerResponse.FromEntity(peer)
+		list = append(list, peerResponse)
+	}
+	return list
+}
@@ -0,0 +1,287 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"fmt"
+)
+
+type TallyLineRequest struct {
+	CandidateID int64 `json:"candidate_id"`
+	Votes       int64 `json:"votes"`
+}
+
+type TallyLineResponse struct {
+	CandidateID int64 `json:"candidate_id"`
+	Votes       int64 `json:"votes"`
+}
+
+// TallyFormRequest holds the votes per candidate written on the tally form (C1) of a polling station,
+// also used for the totals declared by a region recapitulation
+type TallyFormRequest struct {
+	Lines        []TallyLineRequest `json:"lines"`
+	InvalidVotes int64              `json:"invalid_votes"`
+	Note         string             `json:"note"`
+}
+
+func (d *TallyFormRequest) Validate() error {
+	if len(d.Lines) == 0 {
+		return errors.New("lines is required")
+	}
+
+	seen := make(map[int64]bool, len(d.Lines))
+	for _, line := range d.Lines {
+		if line.CandidateID <= 0 {
+			return errors.New("candidate_id is required")
+		}
+		if seen[line.CandidateID] {
+			return fmt.Errorf("candidate %d is listed more than once", line.CandidateID)
+		}
+		seen[line.CandidateID] = true
+
+		if line.Votes < 0 {
+			return errors.New("votes can not be negative")
+		}
+	}
+
+	if d.InvalidVotes < 0 {
+		return errors.New("invalid_votes can not be negative")
+	}
+
+	return nil
+}
+
+func (d *TallyFormRequest) toLines() []model.TallyLine {
+	var lines []model.TallyLine = make([]model.TallyLine, 0, len(d.Lines))
+	for _, line := range d.Lines {
+		lines = append(lines, model.TallyLine{CandidateID: line.CandidateID, Votes: line.Votes})
+	}
+	return lines
+}
+
+func (d *TallyFormRequest) ToTallyForm(electionID int64, pollingStationID int64) model.TallyForm {
+	return model.TallyForm{
+		ElectionID:       electionID,
+		PollingStationID: pollingStationID,
+		Lines:            d.toLines(),
+		InvalidVotes:     d.InvalidVotes,
+		Note:             d.Note,
+	}
+}
+
+func (d *TallyFormRequest) ToRecapitulation(electionID int64, regionID int64) model.Recapitulation {
+	return model.Recapitulation{
+		ElectionID:   electionID,
+		RegionID:     regionID,
+		Lines:        d.toLines(),
+		InvalidVotes: d.InvalidVotes,
+		Note:         d.Note,
+	}
+}
+
+type ReviewRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+func (d *ReviewRequest) Validate() error {
+	if d.Status != model.ReviewStatusApproved && d.Status != model.ReviewStatusRejected {
+		return errors.New("status must be approved or rejected")
+	}
+
+	if d.Status == model.ReviewStatusRejected && len(d.Note) == 0 {
+		return errors.New("note is required to reject")
+	}
+
+	return nil
+}
+
+func linesFromEntity(lines []model.TallyLine) []TallyLineResponse {
+	var list []TallyLineResponse = make([]TallyLineResponse, 0, len(lines))
+	for _, line := range lines {
+		list = append(list, TallyLineResponse{CandidateID: line.CandidateID, Votes: line.Votes})
+	}
+	return list
+}
+
+type TallyFormResponse struct {
+	ID               int64               `json:"id"`
+	ElectionID       int64               `json:"election_id"`
+	PollingStationID int64               `json:"polling_station_id"`
+	Status           string              `json:"status"`
+	Lines            []TallyLineResponse `json:"lines,omitempty"`
+	InvalidVotes     int64               `json:"invalid_votes"`
+	Note             string              `json:"note"`
+	SubmittedAt      string              `json:"submitted_at"`
+	SubmittedBy      int64               `json:"submitted_by"`
+	ReviewedAt       string              `json:"reviewed_at"`
+	ReviewedBy       int64               `json:"reviewed_by"`
+}
+
+func (d *TallyFormResponse) FromEntity(form model.TallyForm) {
+	d.ID = form.ID
+	d.ElectionID = form.ElectionID
+	d.PollingStationID = form.PollingStationID
+	d.Status = form.Status
+	if form.Lines != nil {
+		d.Lines = linesFromEntity(form.Lines)
+	}
+	d.InvalidVotes = form.InvalidVotes
+	d.Note = form.Note
+	d.SubmittedAt = form.SubmittedAt
+	d.SubmittedBy = form.SubmittedBy
+	d.ReviewedAt = form.ReviewedAt
+	d.ReviewedBy = form.ReviewedBy
+}
+
+func (d *TallyFormResponse) ListFromEntity(forms []model.TallyForm) []TallyFormResponse {
+	var list []TallyFormResponse = make([]TallyFormResponse, 0)
+	for _, form := range forms {
+		var formResponse TallyFormResponse
+		formResponse.FromEntity(form)
+		list = append(list, formResponse)
+	}
+	return list
+}
+
+type RecapitulationResponse struct {
+	ID           int64               `json:"id"`
+	ElectionID   int64               `json:"election_id"`
+	RegionID     int64               `json:"region_id"`
+	Status       string              `json:"status"`
+	Revision     int                 `json:"revision"`
+	Lines        []TallyLineResponse `json:"lines,omitempty"`
+	InvalidVotes int64               `json:"invalid_votes"`
+	Note         string              `json:"note"`
+	SubmittedAt  string              `json:"submitted_at"`
+	SubmittedBy  int64               `json:"submitted_by"`
+	ReviewedAt   string              `json:"reviewed_at"`
+	ReviewedBy   int64               `json:"reviewed_by"`
+}
+
+func (d *RecapitulationResponse) FromEntity(recapitulation model.Recapitulation) {
+	d.ID = recapitulation.ID
+	d.ElectionID = recapitulation.ElectionID
+	d.RegionID = recapitulation.RegionID
+	d.Status = recapitulation.Status
+	d.Revision = recapitulation.Revision
+	if recapitulation.Lines != nil {
+		d.Lines = linesFromEntity(recapitulation.Lines)
+	}
+	d.InvalidVotes = recapitulation.InvalidVotes
+	d.Note = recapitulation.Note
+	d.SubmittedAt = recapitulation.SubmittedAt
+	d.SubmittedBy = recapitulation.SubmittedBy
+	d.ReviewedAt = recapitulation.ReviewedAt
+	d.ReviewedBy = recapitulation.ReviewedBy
+}
+
+func (d *RecapitulationResponse) ListFromEntity(recapitulations []model.Recapitulation) []RecapitulationResponse {
+	var list []RecapitulationResponse = make([]RecapitulationResponse, 0)
+	for _, recapitulation := range recapitulations {
+		var recapitulationResponse RecapitulationResponse
+		recapitulationResponse.FromEntity(recapitulation)
+		list = append(list, recapitulationResponse)
+	}
+	return list
+}
+
+// DiscrepancyResponse is a submitted total that differs from the computed sum. candidate_id 0 stands for the invalid votes.
+type DiscrepancyResponse struct {
+	Revision       int   `json:"revision"`
+	CandidateID    int64 `json:"candidate_id"`
+	SubmittedVotes int64 `json:"submitted_votes"`
+	ComputedVotes  int64 `json:"computed_votes"`
+}
+
+func (d *DiscrepancyResponse) ListFromEntity(discrepancies []model.Discrepancy) []DiscrepancyResponse {
+	var list []DiscrepancyResponse = make([]DiscrepancyResponse, 0)
+	for _, discrepancy := range discrepancies {
+		list = append(list, DiscrepancyResponse{
+			Revision:       discrepancy.Revision,
+			CandidateID:    discrepancy.CandidateID,
+			SubmittedVotes: discrepancy.SubmittedVotes,
+			ComputedVotes:  discrepancy.ComputedVotes,
+		})
+	}
+	return list
+}
+
+// RecapitulationSumResponse is the computed sum of the approved polling stations or child regions
+type RecapitulationSumResponse struct {
+	Lines            []TallyLineResponse `json:"lines"`
+	InvalidVotes     int64               `json:"invalid_votes"`
+	Children         int                 `json:"children"`
+	ApprovedChildren int                 `json:"approved_children"`
+}
+
+// RecapitulationReportResponse holds the recapitulation of a region next to the computed sum of its children.
+// recapitulation is omitted while nothing is submitted for the region.
+type RecapitulationReportResponse struct {
+	Region         RegionResponse            `json:"region"`
+	Recapitulation *RecapitulationResponse   `json:"recapitulation,omitempty"`
+	Computed       RecapitulationSumResponse `json:"computed"`
+	Discrepancies  []DiscrepancyResponse     `json:"discrepancies"`
+}
+
+func (d *RecapitulationReportResponse) FromEntity(report model.RecapitulationReport) {
+	d.Region.FromEntity(report.Region)
+	if report.Recapitulation.ID > 0 {
+		d.Recapitulation = &RecapitulationResponse{}
+		d.Recapitulation.FromEntity(report.Recapitulation)
+	}
+	d.Computed = RecapitulationSumResponse{
+		Lines:            linesFromEntity(report.Sum.Lines),
+		InvalidVotes:     report.Sum.InvalidVotes,
+		Children:         report.Sum.Children,
+		ApprovedChildren: report.Sum.ApprovedChildren,
+	}
+
+	var discrepancyResponse DiscrepancyResponse
+	d.Discrepancies = discrepancyResponse.ListFromEntity(report.Discrepancies)
+}
+
+// RecapitulationSubmitResponse is the stored recapitulation with the discrepancies found in this revision
+type RecapitulationSubmitResponse struct {
+	Recapitulation RecapitulationResponse `json:"recapitulation"`
+	Discrepancies  []DiscrepancyResponse  `json:"discrepancies"`
+}
+
+func (d *RecapitulationSubmitResponse) FromEntity(recapitulation model.Recapitulation, discrepancies []model.Discrepancy) {
+	d.Recapitulation.FromEntity(recapitulation)
+
+	var discrepancyResponse DiscrepancyResponse
+	d.Discrepancies = discrepancyResponse.ListFromEntity(discrepancies)
+}
+
+type TallyFormScanResponse struct {
+	ID          int64  `json:"id"`
+	TallyFormID int64  `json:"tally_form_id"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Filename    string `json:"filename"`
+	CreatedAt   string `json:"created_at"`
+	CreatedBy   int64  `json:"created_by"`
+}
+
+func (d *TallyFormScanResponse) FromEntity(scan model.TallyFormScan) {
+	d.ID = scan.ID
+	d.TallyFormID = scan.TallyFormID
+	d.SHA256 = scan.SHA256
+	d.ContentType = scan.ContentType
+	d.Size = scan.Size
+	d.Filename = scan.Filename
+	d.CreatedAt = scan.CreatedAt
+	d.CreatedBy = scan.CreatedBy
+}
+
+func (d *TallyFormScanResponse) ListFromEntity(scans []model.TallyFormScan) []TallyFormScanResponse {
+	var list []TallyFormScanResponse = make([]TallyFormScanResponse, 0)
+	for _, scan := range scans {
+		var scanResponse TallyFormScanResponse
+		scanResponse.FromEntity(scan)
+		list = append(list, scanResponse)
+	}
+	return list
+}
@@ -0,0 +1,192 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+)
+
+type AddRegionRequest struct {
+	ParentID int64  `json:"parent_id"`
+	Level    string `json:"level"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+}
+
+func (d *AddRegionRequest) Validate() error {
+	isLevel := false
+	for _, level := range model.RegionLevels {
+		if d.Level == level {
+			isLevel = true
+		}
+	}
+	if !isLevel {
+		return errors.New("level must be national, province, regency, subdistrict or village")
+	}
+
+	if d.Level == model.RegionLevelNational && d.ParentID != 0 {
+		return errors.New("national region can not have a parent")
+	}
+
+	if d.Level != model.RegionLevelNational && d.ParentID <= 0 {
+		return errors.New("parent_id is required")
+	}
+
+	return validateRegion(d.Code, d.Name)
+}
+
+func (d *AddRegionRequest) ToEntity() model.Region {
+	return model.Region{
+		ParentID: d.ParentID,
+		Level:    d.Level,
+		Code:     d.Code,
+		Name:     d.Name,
+	}
+}
+
+type UpdateRegionRequest struct {
+	ID   int64  `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func (d *UpdateRegionRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with region id")
+	}
+
+	return validateRegion(d.Code, d.Name)
+}
+
+func (d *UpdateRegionRequest) ToEntity() model.Region {
+	return model.Region{
+		ID:   d.ID,
+		Code: d.Code,
+		Name: d.Name,
+	}
+}
+
+func validateRegion(code string, name string) error {
+	if len(code) == 0 {
+		return errors.New("code is required")
+	}
+
+	if len(code) > 16 {
+		return errors.New("code maximal 16 character")
+	}
+
+	if len(name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	return nil
+}
+
+type RegionResponse struct {
+	ID       int64  `json:"id"`
+	ParentID int64  `json:"parent_id"`
+	Level    string `json:"level"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+}
+
+func (d *RegionResponse) FromEntity(region model.Region) {
+	d.ID = region.ID
+	d.ParentID = region.ParentID
+	d.Level = region.Level
+	d.Code = region.Code
+	d.Name = region.Name
+}
+
+func (d *RegionResponse) ListFromEntity(regions []model.Region) []RegionResponse {
+	var list []RegionResponse = make([]RegionResponse, 0)
+	for _, region := range regions {
+		var regionResponse RegionResponse
+		regionResponse.FromEntity(region)
+		list = append(list, regionResponse)
+	}
+	return list
+}
+
+type AddPollingStationRequest struct {
+	VillageID int64  `json:"village_id"`
+	Number    string `json:"number"`
+	Address   string `json:"address"`
+}
+
+func (d *AddPollingStationRequest) Validate() error {
+	if d.VillageID <= 0 {
+		return errors.New("village_id is required")
+	}
+
+	return validatePollingStation(d.Number)
+}
+
+func (d *AddPollingStationRequest) ToEntity() model.PollingStation {
+	return model.PollingStation{
+		VillageID: d.VillageID,
+		Number:    d.Number,
+		Address:   d.Address,
+	}
+}
+
+type UpdatePollingStationRequest struct {
+	ID      int64  `json:"id"`
+	Number  string `json:"number"`
+	Address string `json:"address"`
+}
+
+func (d *UpdatePollingStationRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with polling station id")
+	}
+
+	return validatePollingStation(d.Number)
+}
+
+func (d *UpdatePollingStationRequest) ToEntity() model.PollingStation {
+	return model.PollingStation{
+		ID:      d.ID,
+		Number:  d.Number,
+		Address: d.Address,
+	}
+}
+
+func validatePollingStation(number string) error {
+	if len(number) == 0 {
+		return errors.New("number is required")
+	}
+
+	if len(number) > 8 {
+		return errors.New("number maximal 8 character")
+	}
+
+	return nil
+}
+
+type PollingStationResponse struct {
+	ID        int64  `json:"id"`
+	VillageID int64  `json:"village_id"`
+	Number    string `json:"number"`
+	Address   string `json:"address"`
+}
+
+func (d *PollingStationResponse) FromEntity(pollingStation model.PollingStation) {
+	d.ID = pollingStation.ID
+	d.VillageID = pollingStation.VillageID
+	d.Number = pollingStation.Number
+	d.Address = pollingStation.Address
+}
+
+func (d *PollingStationResponse) ListFromEntity(pollingStations []model.PollingStation) []PollingStationResponse {
+	var list []PollingStationResponse = make([]PollingStationResponse, 0)
+	for _, pollingStation := range pollingStations {
+		var pollingStationResponse PollingStationResponse
+		pollingStationResponse.FromEntity(pollingStation)
+		list = append(list, pollingStationResponse)
+	}
+	return list
+}
@@ -0,0 +1,114 @@
+package dto
+
+import "backend-election/internal/model"
+
+type CandidateVotesResponse struct {
+	CandidateID  int64   `json:"candidate_id"`
+	BallotNumber int     `json:"ballot_number"`
+	Name         string  `json:"name"`
+	Votes        float64 `json:"votes"`
+}
+
+type ResultRoundResponse struct {
+	Round      int                      `json:"round"`
+	Votes      []CandidateVotesResponse `json:"votes"`
+	Exhausted  float64                  `json:"exhausted"`
+	Elected    []int64                  `json:"elected"`
+	Eliminated []int64                  `json:"eliminated"`
+	TieBreak   bool                     `json:"tie_break"`
+}
+
+// ElectionResultResponse holds the elected candidates and the breakdown of every counting round.
+// Single round methods (plurality and approval) return one round.
+type ElectionResultResponse struct {
+	ElectionID     int64                 `json:"election_id"`
+	CountingMethod string                `json:"counting_method"`
+	Seats          int                   `json:"seats"`
+	Ballots        int                   `json:"ballots"`
+	Invalid        int                   `json:"invalid"`
+	Quota          float64               `json:"quota,omitempty"`
+	Elected        []CandidateResponse   `json:"elected"`
+	Rounds         []ResultRoundResponse `json:"rounds"`
+}
+
+func (e *ElectionResultResponse) FromEntity(electionID int64, result model.TallyResult, candidates []model.Candidate) {
+	byID := make(map[int64]model.Candidate, len(candidates))
+	for _, candidate := range candidates {
+		byID[candidate.ID] = candidate
+	}
+
+	e.ElectionID = electionID
+	e.CountingMethod = result.Method
+	e.Seats = result.Seats
+	e.Ballots = result.Ballots
+	e.Invalid = result.Invalid
+	e.Quota = result.Quota
+
+	e.Elected = make([]CandidateResponse, 0, len(result.Elected))
+	for _, id := range result.Elected {
+		var candidateResponse CandidateResponse
+		candidateResponse.FromEntity(byID[id])
+		e.Elected = append(e.Elected, candidateResponse)
+	}
+
+	e.Rounds = make([]ResultRoundResponse, 0, len(result.Rounds))
+	for _, round := range result.Rounds {
+		roundResponse := ResultRoundResponse{
+			Round:      round.Round,
+			Votes:      make([]CandidateVotesResponse, 0, len(round.Votes)),
+			Exhausted:  round.Exhausted,
+			Elected:    append(make([]int64, 0), round.Elected...),
+			Eliminated: append(make([]int64, 0), round.Eliminated...),
+			TieBreak:   round.TieBreak,
+		}
+		for _, votes := range round.Votes {
+			roundResponse.Votes = append(roundResponse.Votes, CandidateVotesResponse{
+				CandidateID:  votes.CandidateID,
+				BallotNumber: byID[votes.CandidateID].BallotNumber,
+				Name:         byID[votes.CandidateID].Name,
+				Votes:        votes.Votes,
+			})
+		}
+		e.Rounds = append(e.Rounds, roundResponse)
+	}
+}
+
+// TallySnapshotResponse is the sum of the approved tally forms of an election. Updates with a version up to
+// the version of the snapshot are already included in it.
+type TallySnapshotResponse struct {
+	ElectionID    int64               `json:"election_id"`
+	Version       int64               `json:"version"`
+	ApprovedForms int                 `json:"approved_forms"`
+	Lines         []TallyLineResponse `json:"lines"`
+	InvalidVotes  int64               `json:"invalid_votes"`
+}
+
+func (d *TallySnapshotResponse) FromEntity(snapshot model.TallySnapshot) {
+	d.ElectionID = snapshot.ElectionID
+	d.Version = snapshot.Version
+	d.ApprovedForms = snapshot.ApprovedForms
+	d.Lines = linesFromEntity(snapshot.Lines)
+	d.InvalidVotes = snapshot.InvalidVotes
+}
+
+// TallyUpdateResponse holds the votes of one approved tally form, to be added to the snapshot.
+// A reopened update holds the negated votes of a form taken back for a recount.
+type TallyUpdateResponse struct {
+	ElectionID       int64               `json:"election_id"`
+	Version          int64               `json:"version"`
+	PollingStationID int64               `json:"polling_station_id"`
+	VillageID        int64               `json:"village_id"`
+	Lines            []TallyLineResponse `json:"lines"`
+	InvalidVotes     int64               `json:"invalid_votes"`
+	Reopened         bool                `json:"reopened"`
+}
+
+func (d *TallyUpdateResponse) FromEntity(update model.TallyUpdate) {
+	d.ElectionID = update.ElectionID
+	d.Version = update.Version
+	d.PollingStationID = update.PollingStationID
+	d.VillageID = update.VillageID
+	d.Lines = linesFromEntity(update.Lines)
+	d.InvalidVotes = update.InvalidVotes
+	d.Reopened = update.Reopened
+}
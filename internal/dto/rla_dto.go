@@ -0,0 +1,186 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"fmt"
+)
+
+// RLARequest starts the risk-limiting audit of a tallied election. Seed is the number rolled with dice in the
+// public seed ceremony, at least 20 decimal digits, every draw of the audit is computed from it.
+type RLARequest struct {
+	AuditType string  `json:"audit_type"`
+	RiskLimit float64 `json:"risk_limit"`
+	MaxDraws  int     `json:"max_draws"`
+	Seed      string  `json:"seed"`
+}
+
+func (d *RLARequest) Validate() error {
+	if d.AuditType != model.RLATypePolling && d.AuditType != model.RLATypeComparison {
+		return errors.New("audit_type must be polling or comparison")
+	}
+
+	if d.RiskLimit <= 0 || d.RiskLimit >= 1 {
+		return errors.New("risk_limit must be between 0 and 1")
+	}
+
+	if d.MaxDraws <= 0 {
+		return errors.New("max_draws must be positive")
+	}
+
+	if len(d.Seed) < 20 || len(d.Seed) > 100 {
+		return errors.New("seed must have 20 to 100 digits")
+	}
+	for _, c := range d.Seed {
+		if c < '0' || c > '9' {
+			return errors.New("seed must only have digits")
+		}
+	}
+
+	return nil
+}
+
+func (d *RLARequest) ToEntity(electionID int64) model.RLA {
+	return model.RLA{
+		ElectionID: electionID,
+		Type:       d.AuditType,
+		RiskLimit:  d.RiskLimit,
+		MaxDraws:   d.MaxDraws,
+		Seed:       d.Seed,
+	}
+}
+
+type RLAResponse struct {
+	ElectionID int64   `json:"election_id"`
+	AuditType  string  `json:"audit_type"`
+	RiskLimit  float64 `json:"risk_limit"`
+	MaxDraws   int     `json:"max_draws"`
+	Seed       string  `json:"seed"`
+	CreatedAt  string  `json:"created_at"`
+	CreatedBy  int64   `json:"created_by"`
+}
+
+func (d *RLAResponse) FromEntity(audit model.RLA) {
+	d.ElectionID = audit.ElectionID
+	d.AuditType = audit.Type
+	d.RiskLimit = audit.RiskLimit
+	d.MaxDraws = audit.MaxDraws
+	d.Seed = audit.Seed
+	d.CreatedAt = audit.CreatedAt
+	d.CreatedBy = audit.CreatedBy
+}
+
+type RLAPairResponse struct {
+	Winner  int64   `json:"winner"`
+	Loser   int64   `json:"loser"`
+	Measure float64 `json:"measure"`
+}
+
+// RLAReportResponse is the state of the audit. Status is in_progress, passed or escalated, an escalated audit
+// calls for a full hand count. Interpreted is the number of draws the risk measure is computed from.
+type RLAReportResponse struct {
+	RLAResponse
+	Status      string            `json:"status"`
+	Ballots     int64             `json:"ballots"`
+	Batches     int               `json:"batches"`
+	Winners     []int64           `json:"winners"`
+	Draws       int               `json:"draws"`
+	Interpreted int               `json:"interpreted"`
+	RiskMeasure float64           `json:"risk_measure"`
+	Pairs       []RLAPairResponse `json:"pairs"`
+}
+
+func (d *RLAReportResponse) FromEntity(report model.RLAReport) {
+	d.RLAResponse.FromEntity(report.RLA)
+	d.Status = report.Status
+	d.Ballots = report.Ballots
+	d.Batches = report.Batches
+	d.Winners = report.Winners
+	d.Draws = report.Draws
+	d.Interpreted = report.Interpreted
+	d.RiskMeasure = report.RiskMeasure
+	d.Pairs = make([]RLAPairResponse, 0, len(report.Pairs))
+	for _, pair := range report.Pairs {
+		d.Pairs = append(d.Pairs, RLAPairResponse{Winner: pair.Winner, Loser: pair.Loser, Measure: pair.Measure})
+	}
+}
+
+// RLADrawRequest asks the next draws of the audit
+type RLADrawRequest struct {
+	Count int `json:"count"`
+}
+
+func (d *RLADrawRequest) Validate() error {
+	if d.Count <= 0 || d.Count > 1000 {
+		return errors.New("count must be between 1 and 1000")
+	}
+	return nil
+}
+
+type RLADrawResponse struct {
+	Index            int    `json:"index"`
+	PollingStationID int64  `json:"polling_station_id"`
+	BallotPosition   int    `json:"ballot_position"`
+	CreatedAt        string `json:"created_at"`
+}
+
+func (d *RLADrawResponse) FromEntity(draw model.RLADraw) {
+	d.Index = draw.Index
+	d.PollingStationID = draw.PollingStationID
+	d.BallotPosition = draw.BallotPosition
+	d.CreatedAt = draw.CreatedAt
+}
+
+// RLAInterpretationRequest is what the auditors read from a drawn ballot, or counted by hand in a drawn batch.
+// A ballot holds one vote in lines or one invalid vote. BallotPosition is 0 for a batch.
+type RLAInterpretationRequest struct {
+	PollingStationID int64              `json:"polling_station_id"`
+	BallotPosition   int                `json:"ballot_position"`
+	Lines            []TallyLineRequest `json:"lines"`
+	InvalidVotes     int64              `json:"invalid_votes"`
+}
+
+func (d *RLAInterpretationRequest) Validate() error {
+	if d.PollingStationID <= 0 {
+		return errors.New("polling_station_id is required")
+	}
+
+	if d.BallotPosition < 0 {
+		return errors.New("ballot_position can not be negative")
+	}
+
+	seen := make(map[int64]bool, len(d.Lines))
+	for _, line := range d.Lines {
+		if line.CandidateID <= 0 {
+			return errors.New("candidate_id is required")
+		}
+		if seen[line.CandidateID] {
+			return fmt.Errorf("candidate %d is listed more than once", line.CandidateID)
+		}
+		seen[line.CandidateID] = true
+
+		if line.Votes < 0 {
+			return errors.New("votes can not be negative")
+		}
+	}
+
+	if d.InvalidVotes < 0 {
+		return errors.New("invalid_votes can not be negative")
+	}
+
+	return nil
+}
+
+func (d *RLAInterpretationRequest) ToEntity(electionID int64) model.RLAInterpretation {
+	interpretation := model.RLAInterpretation{
+		ElectionID:       electionID,
+		PollingStationID: d.PollingStationID,
+		BallotPosition:   d.BallotPosition,
+		Lines:            make([]model.TallyLine, 0, len(d.Lines)),
+		InvalidVotes:     d.InvalidVotes,
+	}
+	for _, line := range d.Lines {
+		interpretation.Lines = append(interpretation.Lines, model.TallyLine{CandidateID: line.CandidateID, Votes: line.Votes})
+	}
+	return interpretation
+}
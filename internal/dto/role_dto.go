@@ -0,0 +1,265 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"strings"
+)
+
+// AddRoleRequest adds a role. election_id and region_id scope the access of the role to the routes of the election
+// and to the region with the regions below it, leave them out for a role that holds its access everywhere.
+type AddRoleRequest struct {
+	Name       string `json:"name"`
+	ElectionID int64  `json:"election_id"`
+	RegionID   int64  `json:"region_id"`
+}
+
+func (d *AddRoleRequest) Validate() error {
+	return validateRole(d.Name, d.ElectionID, d.RegionID)
+}
+
+func (d *AddRoleRequest) ToEntity() model.Role {
+	return model.Role{Name: d.Name, ElectionID: d.ElectionID, RegionID: d.RegionID}
+}
+
+type UpdateRoleRequest struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	ElectionID int64  `json:"election_id"`
+	RegionID   int64  `json:"region_id"`
+}
+
+func (d *UpdateRoleRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with role id")
+	}
+
+	return validateRole(d.Name, d.ElectionID, d.RegionID)
+}
+
+func (d *UpdateRoleRequest) ToEntity() model.Role {
+	return model.Role{ID: d.ID, Name: d.Name, ElectionID: d.ElectionID, RegionID: d.RegionID}
+}
+
+func validateRole(name string, electionID int64, regionID int64) error {
+	if len(name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(name) > 45 {
+		return errors.New("name maximal 45 character")
+	}
+
+	if electionID < 0 {
+		return errors.New("election_id must be positive")
+	}
+
+	if regionID < 0 {
+		return errors.New("region_id must be positive")
+	}
+
+	return nil
+}
+
+type RoleResponse struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	IsSuperuser bool   `json:"is_superuser"`
+	ElectionID  int64  `json:"election_id,omitempty"`
+	RegionID    int64  `json:"region_id,omitempty"`
+}
+
+func (d *RoleResponse) FromEntity(role model.Role) {
+	d.ID = role.ID
+	d.Name = role.Name
+	d.IsSuperuser = role.IsSuperuser
+	d.ElectionID = role.ElectionID
+	d.RegionID = role.RegionID
+}
+
+func (d *RoleResponse) ListFromEntity(roles []model.Role) []RoleResponse {
+	var list []RoleResponse = make([]RoleResponse, 0)
+	for _, role := range roles {
+		var roleResponse RoleResponse
+		roleResponse.FromEntity(role)
+		list = append(list, roleResponse)
+	}
+	return list
+}
+
+// AssignRoleRequest gives a role to a user
+type AssignRoleRequest struct {
+	RoleID int64 `json:"role_id"`
+}
+
+func (d *AssignRoleRequest) Validate() error {
+	if d.RoleID <= 0 {
+		return errors.New("role_id is required")
+	}
+
+	return nil
+}
+
+type AddAccessRequest struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func (d *AddAccessRequest) Validate() error {
+	return validateAccess(d.Name, d.Path)
+}
+
+func (d *AddAccessRequest) ToEntity() model.Access {
+	return model.Access{Name: d.Name, Path: d.Path}
+}
+
+type UpdateAccessRequest struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func (d *UpdateAccessRequest) Validate(id int64) error {
+	if id != d.ID {
+		return errors.New("id not match with access id")
+	}
+
+	return validateAccess(d.Name, d.Path)
+}
+
+func (d *UpdateAccessRequest) ToEntity() model.Access {
+	return model.Access{ID: d.ID, Name: d.Name, Path: d.Path}
+}
+
+// validateAccess checks the name and the path of an access
+func validateAccess(name string, path string) error {
+	if len(name) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	return validateAccessPath(path)
+}
+
+// validateAccessPath checks the path is an upper case method and a route pattern separated by a space
+func validateAccessPath(path string) error {
+	if len(path) == 0 {
+		return errors.New("path is required")
+	}
+
+	if len(path) > 128 {
+		return errors.New("path maximal 128 character")
+	}
+
+	method, route, found := strings.Cut(path, " ")
+	if !found || len(method) == 0 || strings.ToUpper(method) != method || !strings.HasPrefix(route, "/") || strings.ContainsAny(route, " \t") {
+		return errors.New("path must be a method and a route, like GET /users/:id")
+	}
+
+	return nil
+}
+
+type AccessResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func (d *AccessResponse) FromEntity(access model.Access) {
+	d.ID = access.ID
+	d.Name = access.Name
+	d.Path = access.Path
+}
+
+func (d *AccessResponse) ListFromEntity(access []model.Access) []AccessResponse {
+	var list []AccessResponse = make([]AccessResponse, 0)
+	for _, a := range access {
+		var accessResponse AccessResponse
+		accessResponse.FromEntity(a)
+		list = append(list, accessResponse)
+	}
+	return list
+}
+
+// GrantAccessRequest grants an access to a role
+type GrantAccessRequest struct {
+	AccessID int64 `json:"access_id"`
+}
+
+func (d *GrantAccessRequest) Validate() error {
+	if d.AccessID <= 0 {
+		return errors.New("access_id is required")
+	}
+
+	return nil
+}
+
+// AddParentRequest makes a role inherit the access of a parent role
+type AddParentRequest struct {
+	ParentID int64 `json:"parent_id"`
+}
+
+func (d *AddParentRequest) Validate(roleID int64) error {
+	if d.ParentID <= 0 {
+		return errors.New("parent_id is required")
+	}
+
+	if d.ParentID == roleID {
+		return errors.New("a role can not inherit itself")
+	}
+
+	return nil
+}
+
+// ExplainAccessRequest asks why a user has or lacks an access path, within an election or a region when they are given
+type ExplainAccessRequest struct {
+	Path       string `json:"path"`
+	ElectionID int64  `json:"election_id"`
+	RegionID   int64  `json:"region_id"`
+}
+
+func (d *ExplainAccessRequest) Validate() error {
+	if d.ElectionID < 0 {
+		return errors.New("election_id must be positive")
+	}
+
+	if d.RegionID < 0 {
+		return errors.New("region_id must be positive")
+	}
+
+	return validateAccessPath(d.Path)
+}
+
+type AccessGrantResponse struct {
+	// Roles is the chain from the role the user holds to the role granted the access
+	Roles      []RoleResponse `json:"roles"`
+	ElectionID int64          `json:"election_id,omitempty"`
+	RegionID   int64          `json:"region_id,omitempty"`
+}
+
+type AccessExplanationResponse struct {
+	UserID  int64                 `json:"user_id"`
+	Path    string                `json:"path"`
+	Allowed bool                  `json:"allowed"`
+	Reason  string                `json:"reason"`
+	Grants  []AccessGrantResponse `json:"grants"`
+}
+
+func (d *AccessExplanationResponse) FromEntity(explanation model.AccessExplanation) {
+	d.UserID = explanation.UserID
+	d.Path = explanation.Path
+	d.Allowed = explanation.Allowed
+	d.Reason = explanation.Reason
+	d.Grants = make([]AccessGrantResponse, 0)
+	for _, grant := range explanation.Grants {
+		var roleResponse RoleResponse
+		d.Grants = append(d.Grants, AccessGrantResponse{
+			Roles:      roleResponse.ListFromEntity(grant.Roles),
+			ElectionID: grant.ElectionID,
+			RegionID:   grant.RegionID,
+		})
+	}
+}
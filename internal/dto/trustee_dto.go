@@ -0,0 +1,314 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/elgamal"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// maxTrustees bounds the number of trustees of an election
+const maxTrustees = 100
+
+// ErrInvalidNumber is returned for a group element or exponent that is not a hex encoded number
+var ErrInvalidNumber = errors.New("must be a hex encoded number")
+
+// encodeNumber hex encodes a group element or exponent
+func encodeNumber(x *big.Int) string {
+	return x.Text(16)
+}
+
+func encodeBytes(b []byte) string {
+	return encodeNumber(new(big.Int).SetBytes(b))
+}
+
+// decodeNumber reads a hex encoded group element or exponent, field names the number in the error
+func decodeNumber(field string, s string) (*big.Int, error) {
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok || x.Sign() < 0 {
+		return nil, fmt.Errorf("%s %w", field, ErrInvalidNumber)
+	}
+	return x, nil
+}
+
+func decodeNumbers(field string, list []string) ([]*big.Int, error) {
+	numbers := make([]*big.Int, 0, len(list))
+	for _, s := range list {
+		x, err := decodeNumber(field, s)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, x)
+	}
+	return numbers, nil
+}
+
+// Ciphertext is an exponential ElGamal ciphertext, A = g^r and B = g^m h^r hex encoded
+type Ciphertext struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+func (d *Ciphertext) FromEntity(c elgamal.Ciphertext) {
+	d.A = encodeNumber(c.A)
+	d.B = encodeNumber(c.B)
+}
+
+func (d Ciphertext) ToEntity() (elgamal.Ciphertext, error) {
+	var c elgamal.Ciphertext
+	var err error
+	if c.A, err = decodeNumber("ciphertext a", d.A); err != nil {
+		return c, err
+	}
+	c.B, err = decodeNumber("ciphertext b", d.B)
+	return c, err
+}
+
+// DisjunctiveProof proves that a ciphertext encrypts one of a list of values, with a challenge and a response per value
+type DisjunctiveProof struct {
+	Challenges []string `json:"challenges"`
+	Responses  []string `json:"responses"`
+}
+
+func (d *DisjunctiveProof) FromEntity(proof elgamal.DisjunctiveProof) {
+	d.Challenges = make([]string, 0, len(proof.Challenges))
+	for _, challenge := range proof.Challenges {
+		d.Challenges = append(d.Challenges, encodeNumber(challenge))
+	}
+	d.Responses = make([]string, 0, len(proof.Responses))
+	for _, response := range proof.Responses {
+		d.Responses = append(d.Responses, encodeNumber(response))
+	}
+}
+
+func (d DisjunctiveProof) ToEntity() (elgamal.DisjunctiveProof, error) {
+	var proof elgamal.DisjunctiveProof
+	var err error
+	if proof.Challenges, err = decodeNumbers("proof challenge", d.Challenges); err != nil {
+		return proof, err
+	}
+	proof.Responses, err = decodeNumbers("proof response", d.Responses)
+	return proof, err
+}
+
+// EncryptedBallot is a ballot of an encrypted election: a ciphertext of 0 or 1 for every candidate in ballot order,
+// the proof that each encrypts 0 or 1 and the proof that their sum is a total allowed by the counting method
+type EncryptedBallot struct {
+	Ciphertexts []Ciphertext       `json:"ciphertexts"`
+	Proofs      []DisjunctiveProof `json:"proofs"`
+	TotalProof  DisjunctiveProof   `json:"total_proof"`
+}
+
+func (d *EncryptedBallot) FromEntity(ballot elgamal.Ballot) {
+	d.Ciphertexts = make([]Ciphertext, len(ballot.Ciphertexts))
+	for i, c := range ballot.Ciphertexts {
+		d.Ciphertexts[i].FromEntity(c)
+	}
+	d.Proofs = make([]DisjunctiveProof, len(ballot.Proofs))
+	for i, proof := range ballot.Proofs {
+		d.Proofs[i].FromEntity(proof)
+	}
+	d.TotalProof.FromEntity(ballot.TotalProof)
+}
+
+func (d EncryptedBallot) ToEntity() (elgamal.Ballot, error) {
+	ballot := elgamal.Ballot{
+		Ciphertexts: make([]elgamal.Ciphertext, 0, len(d.Ciphertexts)),
+		Proofs:      make([]elgamal.DisjunctiveProof, 0, len(d.Proofs)),
+	}
+	for _, c := range d.Ciphertexts {
+		ciphertext, err := c.ToEntity()
+		if err != nil {
+			return ballot, err
+		}
+		ballot.Ciphertexts = append(ballot.Ciphertexts, ciphertext)
+	}
+	for _, p := range d.Proofs {
+		proof, err := p.ToEntity()
+		if err != nil {
+			return ballot, err
+		}
+		ballot.Proofs = append(ballot.Proofs, proof)
+	}
+
+	var err error
+	ballot.TotalProof, err = d.TotalProof.ToEntity()
+	return ballot, err
+}
+
+type TrusteeSetupRequest struct {
+	Threshold int     `json:"threshold"`
+	UserIDs   []int64 `json:"user_ids"`
+}
+
+func (t *TrusteeSetupRequest) Validate() error {
+	if len(t.UserIDs) == 0 {
+		return errors.New("user_ids is required")
+	}
+
+	if len(t.UserIDs) > maxTrustees {
+		return fmt.Errorf("user_ids maximal %d trustees", maxTrustees)
+	}
+
+	seen := make(map[int64]bool, len(t.UserIDs))
+	for _, userID := range t.UserIDs {
+		if userID <= 0 {
+			return errors.New("user_ids must be valid ids")
+		}
+		if seen[userID] {
+			return errors.New("user_ids can not contain the same user twice")
+		}
+		seen[userID] = true
+	}
+
+	if t.Threshold < 1 || t.Threshold > len(t.UserIDs) {
+		return errors.New("threshold must be between 1 and the number of trustees")
+	}
+
+	return nil
+}
+
+// TrusteeShareResponse holds the key share of the trustee asking for it. It is only shown once, keep it private.
+type TrusteeShareResponse struct {
+	ElectionID      int64  `json:"election_id"`
+	Index           int    `json:"index"`
+	Share           string `json:"share"`
+	VerificationKey string `json:"verification_key"`
+}
+
+func (d *TrusteeShareResponse) FromEntity(trustee model.Trustee) {
+	d.ElectionID = trustee.ElectionID
+	d.Index = trustee.Index
+	d.Share = encodeBytes(trustee.Share)
+	d.VerificationKey = encodeBytes(trustee.VerificationKey)
+}
+
+type TrusteeResponse struct {
+	Index           int    `json:"index"`
+	UserID          int64  `json:"user_id"`
+	VerificationKey string `json:"verification_key"`
+	Decrypted       bool   `json:"decrypted"`
+}
+
+type TrusteeListResponse struct {
+	ElectionID int64             `json:"election_id"`
+	PublicKey  string            `json:"public_key"`
+	Threshold  int               `json:"threshold"`
+	Decrypted  int               `json:"decrypted"`
+	Trustees   []TrusteeResponse `json:"trustees"`
+}
+
+func (d *TrusteeListResponse) FromEntity(election model.Election, trustees []model.Trustee) {
+	d.ElectionID = election.ID
+	d.PublicKey = encodeBytes(election.PublicKey)
+	d.Threshold = election.TrusteeThreshold
+	d.Trustees = make([]TrusteeResponse, 0, len(trustees))
+	for _, trustee := range trustees {
+		if trustee.Decrypted {
+			d.Decrypted++
+		}
+		d.Trustees = append(d.Trustees, TrusteeResponse{
+			Index:           trustee.Index,
+			UserID:          trustee.UserID,
+			VerificationKey: encodeBytes(trustee.VerificationKey),
+			Decrypted:       trustee.Decrypted,
+		})
+	}
+}
+
+type EncryptedTotalResponse struct {
+	CandidateID int64      `json:"candidate_id"`
+	Ciphertext  Ciphertext `json:"ciphertext"`
+}
+
+// EncryptedTallyResponse is the product of the ciphertexts of all ballots for every candidate in ballot order,
+// the encryption of the votes of the candidate. Trustees decrypt it with their share.
+type EncryptedTallyResponse struct {
+	ElectionID int64                    `json:"election_id"`
+	PublicKey  string                   `json:"public_key"`
+	Ballots    int                      `json:"ballots"`
+	Candidates []EncryptedTotalResponse `json:"candidates"`
+}
+
+func (d *EncryptedTallyResponse) FromEntity(election model.Election, candidates []model.Candidate, totals []elgamal.Ciphertext, ballots int) {
+	d.ElectionID = election.ID
+	d.PublicKey = encodeBytes(election.PublicKey)
+	d.Ballots = ballots
+	d.Candidates = make([]EncryptedTotalResponse, len(candidates))
+	for i, candidate := range candidates {
+		d.Candidates[i].CandidateID = candidate.ID
+		d.Candidates[i].Ciphertext.FromEntity(totals[i])
+	}
+}
+
+type ChaumPedersenProof struct {
+	Challenge string `json:"challenge"`
+	Response  string `json:"response"`
+}
+
+// DecryptionShare is the partial decryption A^share of the encrypted votes of a candidate by one trustee
+type DecryptionShare struct {
+	CandidateID int64              `json:"candidate_id"`
+	Factor      string             `json:"factor"`
+	Proof       ChaumPedersenProof `json:"proof"`
+}
+
+type DecryptionRequest struct {
+	Shares []DecryptionShare `json:"shares"`
+}
+
+func (d *DecryptionRequest) Validate() error {
+	if len(d.Shares) == 0 {
+		return errors.New("shares is required")
+	}
+
+	seen := make(map[int64]bool, len(d.Shares))
+	for _, share := range d.Shares {
+		if seen[share.CandidateID] {
+			return errors.New("shares can not contain the same candidate twice")
+		}
+		seen[share.CandidateID] = true
+	}
+
+	return nil
+}
+
+// FromEntity fills the request with the decryption shares a trustee computed for the candidates of the tally
+func (d *DecryptionRequest) FromEntity(candidateIDs []int64, shares []elgamal.DecryptionShare) {
+	d.Shares = make([]DecryptionShare, len(shares))
+	for i, share := range shares {
+		d.Shares[i] = DecryptionShare{
+			CandidateID: candidateIDs[i],
+			Factor:      encodeNumber(share.Factor),
+			Proof:       ChaumPedersenProof{Challenge: encodeNumber(share.Proof.Challenge), Response: encodeNumber(share.Proof.Response)},
+		}
+	}
+}
+
+// ToEntity returns the decryption shares keyed by candidate
+func (d *DecryptionRequest) ToEntity() (map[int64]elgamal.DecryptionShare, error) {
+	shares := make(map[int64]elgamal.DecryptionShare, len(d.Shares))
+	for _, s := range d.Shares {
+		var share elgamal.DecryptionShare
+		var err error
+		if share.Factor, err = decodeNumber("factor", s.Factor); err != nil {
+			return shares, err
+		}
+		if share.Proof.Challenge, err = decodeNumber("proof challenge", s.Proof.Challenge); err != nil {
+			return shares, err
+		}
+		if share.Proof.Response, err = decodeNumber("proof response", s.Proof.Response); err != nil {
+			return shares, err
+		}
+		shares[s.CandidateID] = share
+	}
+	return shares, nil
+}
+
+type DecryptionResponse struct {
+	ElectionID   int64 `json:"election_id"`
+	TrusteeIndex int   `json:"trustee_index"`
+	Decrypted    int   `json:"decrypted"`
+	Threshold    int   `json:"threshold"`
+}
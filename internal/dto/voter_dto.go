@@ -0,0 +1,271 @@
+package dto
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	nikPattern      = regexp.MustCompile(`^[0-9]{16}$`)
+	nonLetterRegexp = regexp.MustCompile(`[^A-Z ]+`)
+	spacesRegexp    = regexp.MustCompile(`\s+`)
+)
+
+// ValidateNIK checks the 16 digit national ID (Nomor Induk Kependudukan).
+// The layout is PPKKCC DDMMYY SSSS: province, regency and subdistrict code, birth date
+// (day is added with 40 for women) and a serial number.
+func ValidateNIK(nik string) error {
+	if !nikPattern.MatchString(nik) {
+		return errors.New("nik must be 16 digits")
+	}
+
+	province, _ := strconv.Atoi(nik[0:2])
+	if province < 11 || province > 94 {
+		return errors.New("nik has an unknown province code")
+	}
+
+	if nik[2:4] == "00" || nik[4:6] == "00" {
+		return errors.New("nik has an invalid region code")
+	}
+
+	if _, _, err := nikBirthDate(nik); err != nil {
+		return err
+	}
+
+	if nik[12:16] == "0000" {
+		return errors.New("nik has an invalid serial number")
+	}
+
+	return nil
+}
+
+// nikBirthDate returns day, month and two digit year encoded in the NIK and the gender derived from the day
+func nikBirthDate(nik string) (string, string, error) {
+	day, _ := strconv.Atoi(nik[6:8])
+	month, _ := strconv.Atoi(nik[8:10])
+	year, _ := strconv.Atoi(nik[10:12])
+
+	gender := "L"
+	if day > 40 {
+		day -= 40
+		gender = "P"
+	}
+
+	// 2000 is a leap year, so 29 February is accepted here and checked against the full birth date later
+	if _, err := time.Parse("2006-01-02", "2000-"+twoDigits(month)+"-"+twoDigits(day)); err != nil {
+		return "", "", errors.New("nik has an invalid birth date")
+	}
+
+	return twoDigits(day) + "-" + twoDigits(month) + "-" + twoDigits(year), gender, nil
+}
+
+func twoDigits(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// NormalizeName converts a name into the form used for deduplication: upper case letters separated by single spaces
+func NormalizeName(name string) string {
+	normalized := nonLetterRegexp.ReplaceAllString(strings.ToUpper(name), " ")
+	return strings.TrimSpace(spacesRegexp.ReplaceAllString(normalized, " "))
+}
+
+// MaskNIK hides the birth date and most of the region code of a NIK
+func MaskNIK(nik string) string {
+	if len(nik) != 16 {
+		return strings.Repeat("*", len(nik))
+	}
+	return nik[0:4] + strings.Repeat("*", 10) + nik[14:16]
+}
+
+type VoterCreateRequest struct {
+	NIK            string `json:"nik"`
+	Name           string `json:"name"`
+	BirthPlace     string `json:"birth_place"`
+	BirthDate      string `json:"birth_date"`
+	Gender         string `json:"gender"`
+	Address        string `json:"address"`
+	UserID         int64  `json:"user_id"`
+	AllowDuplicate bool   `json:"allow_duplicate"`
+}
+
+func (v *VoterCreateRequest) Validate() error {
+	if len(v.NIK) == 0 {
+		return errors.New("nik is required")
+	}
+
+	if err := ValidateNIK(v.NIK); err != nil {
+		return err
+	}
+
+	if len(NormalizeName(v.Name)) == 0 {
+		return errors.New("name is required")
+	}
+
+	if len(v.Name) > 128 {
+		return errors.New("name maximal 128 character")
+	}
+
+	if v.UserID < 0 {
+		return errors.New("user_id is invalid")
+	}
+
+	if len(v.BirthPlace) > 64 {
+		return errors.New("birth_place maximal 64 character")
+	}
+
+	if len(v.BirthDate) == 0 {
+		return errors.New("birth_date is required")
+	}
+
+	birthDate, err := time.Parse("2006-01-02", v.BirthDate)
+	if err != nil {
+		return errors.New("birth_date must be formatted as YYYY-MM-DD")
+	}
+
+	if birthDate.After(time.Now()) {
+		return errors.New("birth_date can not be in the future")
+	}
+
+	nikDate, gender, _ := nikBirthDate(v.NIK)
+	if nikDate != birthDate.Format("02-01-06") {
+		return errors.New("birth_date does not match nik")
+	}
+
+	if len(v.Gender) == 0 {
+		v.Gender = gender
+	}
+
+	if v.Gender != "L" && v.Gender != "P" {
+		return errors.New("gender must be L or P")
+	}
+
+	if v.Gender != gender {
+		return errors.New("gender does not match nik")
+	}
+
+	return nil
+}
+
+func (v *VoterCreateRequest) ToEntity() model.Voter {
+	return model.Voter{
+		NIK:            v.NIK,
+		Name:           strings.TrimSpace(v.Name),
+		NormalizedName: NormalizeName(v.Name),
+		BirthPlace:     v.BirthPlace,
+		BirthDate:      v.BirthDate,
+		Gender:         v.Gender,
+		Address:        v.Address,
+		UserID:         v.UserID,
+	}
+}
+
+type VoterUpdateRequest struct {
+	ID         int64  `json:"id"`
+	NIK        string `json:"nik"`
+	Name       string `json:"name"`
+	BirthPlace string `json:"birth_place"`
+	BirthDate  string `json:"birth_date"`
+	Gender     string `json:"gender"`
+	Address    string `json:"address"`
+	UserID     int64  `json:"user_id"`
+}
+
+func (v *VoterUpdateRequest) Validate(id int64) error {
+	if id != v.ID {
+		return errors.New("id not match with voter id")
+	}
+
+	create := VoterCreateRequest{NIK: v.NIK, Name: v.Name, BirthPlace: v.BirthPlace, BirthDate: v.BirthDate, Gender: v.Gender, Address: v.Address, UserID: v.UserID}
+	if err := create.Validate(); err != nil {
+		return err
+	}
+	v.Gender = create.Gender
+
+	return nil
+}
+
+func (v *VoterUpdateRequest) ToEntity() model.Voter {
+	return model.Voter{
+		ID:             v.ID,
+		NIK:            v.NIK,
+		Name:           strings.TrimSpace(v.Name),
+		NormalizedName: NormalizeName(v.Name),
+		BirthPlace:     v.BirthPlace,
+		BirthDate:      v.BirthDate,
+		Gender:         v.Gender,
+		Address:        v.Address,
+		UserID:         v.UserID,
+	}
+}
+
+type ElectionVoterRequest struct {
+	VoterIDs []int64 `json:"voter_ids"`
+}
+
+func (e *ElectionVoterRequest) Validate() error {
+	if len(e.VoterIDs) == 0 {
+		return errors.New("voter_ids is required")
+	}
+
+	if len(e.VoterIDs) > 1000 {
+		return errors.New("voter_ids maximal 1000 items")
+	}
+
+	return nil
+}
+
+type ElectionVoterResponse struct {
+	ElectionID int64 `json:"election_id"`
+	Registered int64 `json:"registered"`
+}
+
+// VoterResponse holds the voter data. Personal data is only filled for callers
+// holding the PII /voters access, otherwise the NIK is masked and the rest is left out.
+type VoterResponse struct {
+	ID         int64  `json:"id"`
+	NIK        string `json:"nik"`
+	Name       string `json:"name"`
+	Gender     string `json:"gender"`
+	BirthPlace string `json:"birth_place,omitempty"`
+	BirthDate  string `json:"birth_date,omitempty"`
+	Address    string `json:"address,omitempty"`
+	UserID     int64  `json:"user_id,omitempty"`
+}
+
+func (v *VoterResponse) FromEntity(voter model.Voter, withPII bool) {
+	v.ID = voter.ID
+	v.Name = voter.Name
+	v.Gender = voter.Gender
+	if !withPII {
+		v.NIK = MaskNIK(voter.NIK)
+		return
+	}
+
+	v.NIK = voter.NIK
+	v.BirthPlace = voter.BirthPlace
+	v.BirthDate = voter.BirthDate
+	v.Address = voter.Address
+	v.UserID = voter.UserID
+}
+
+func (v *VoterResponse) ListFromEntity(voters []model.Voter, withPII bool) []VoterResponse {
+	var list []VoterResponse = make([]VoterResponse, 0)
+	for _, voter := range voters {
+		var voterResponse VoterResponse
+		voterResponse.FromEntity(voter, withPII)
+		list = append(list, voterResponse)
+	}
+	return list
+}
+
+type VoterDuplicateResponse struct {
+	Message            string          `json:"message"`
+	PossibleDuplicates []VoterResponse `json:"possible_duplicates"`
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Accesses handler manages the access paths the roles can be granted
+type Accesses struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Access
+// @Description List the access paths ordered by path
+// @Tags Access
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.AccessResponse
+// @Router /access [get]
+func (h *Accesses) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var accessRepo = repository.AccessRepository{Log: h.Log, Db: h.DB}
+	access, err := accessRepo.List(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var accessResponse dto.AccessResponse
+	response := accessResponse.ListFromEntity(access)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Access By ID
+// @Description Get Access By ID
+// @Tags Access
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Access ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.AccessResponse
+// @Failure 404 {string} string
+// @Router /access/{id} [get]
+func (h *Accesses) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var accessRepo = repository.AccessRepository{Log: h.Log, Db: h.DB}
+	accessRepo.AccessEntity = model.Access{ID: id}
+	if err := accessRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var response dto.AccessResponse
+	response.FromEntity(accessRepo.AccessEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Add Access
+// @Description Add an access path. The path is an upper case method and a route pattern, like GET /users/:id.
+// @Tags Access
+// @Accept  json
+// @Produce  json
+// @Param request body dto.AddAccessRequest true "Access to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.AccessResponse
+// @Failure 409 {string} string
+// @Router /access [post]
+func (h *Accesses) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var accessRequest dto.AddAccessRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&accessRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := accessRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var accessRepo = repository.AccessRepository{Log: h.Log, Db: h.DB}
+	accessRepo.AccessEntity = accessRequest.ToEntity()
+	if err := accessRepo.Save(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var response dto.AccessResponse
+	response.FromEntity(accessRepo.AccessEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Access
+// @Description Update the name and the path of an access. The roles granted the access are granted the new path.
+// @Tags Access
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Access ID"
+// @Param request body dto.UpdateAccessRequest true "Access to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.AccessResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /access/{id} [put]
+func (h *Accesses) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var accessRequest dto.UpdateAccessRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&accessRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := accessRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var accessRepo = repository.AccessRepository{Log: h.Log, Db: h.DB}
+	accessRepo.AccessEntity = model.Access{ID: id}
+	if err := accessRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	var before dto.AccessResponse
+	before.FromEntity(accessRepo.AccessEntity)
+	audit.Before(ctx, before)
+
+	accessRepo.AccessEntity = accessRequest.ToEntity()
+	if err := accessRepo.Update(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var response dto.AccessResponse
+	response.FromEntity(accessRepo.AccessEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.invalidate(ctx)
+}
+
+// @Security Bearer
+// @Summary Delete Access By ID
+// @Description Delete an access and revoke it from every role
+// @Tags Access
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Access ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Router /access/{id} [delete]
+func (h *Accesses) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var accessRepo = repository.AccessRepository{Log: h.Log, Db: h.DB}
+	accessRepo.AccessEntity = model.Access{ID: id}
+	if err := accessRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	var before dto.AccessResponse
+	before.FromEntity(accessRepo.AccessEntity)
+	audit.Before(ctx, before)
+
+	if err := accessRepo.Delete(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.invalidate(ctx)
+}
+
+// invalidate drops the cached permissions of every user, an access may be granted to any role
+func (h *Accesses) invalidate(ctx context.Context) {
+	authUC := usecase.AuthUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	if err := authUC.InvalidatePermissions(ctx); err != nil {
+		h.Log.Error(err)
+	}
+}
+
+func (h *Accesses) writeError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "Access not found", http.StatusNotFound)
+	case repository.ErrAccessTaken:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Ballots handler
+type Ballots struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Summary Cast Ballot
+// @Description Cast a ballot anonymously with a voting credential, the unblinded token and signature of POST /elections/{id}/credentials.
+// @Description The request carries no bearer token, so the ballot is not linked to the voter. Each credential casts one ballot.
+// @Description Choices are candidate IDs in order of preference.
+// @Description The response holds the receipt code of the ballot, it is not stored and can not be shown again.
+// @Tags Ballots
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param ballot body dto.CastBallotRequest true "Ballot to cast"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Success 201 {object} dto.CastBallotResponse
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/ballots [post]
+func (h *Ballots) Cast(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var ballotRequest dto.CastBallotRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&ballotRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := ballotRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ballotUC = usecase.BallotUC{Log: h.Log, DB: h.DB}
+	receipt, statusCode, err := ballotUC.Cast(ctx, electionID, ballotRequest)
+	if err != nil {
+		switch statusCode {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid input: "+err.Error(), statusCode)
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusForbidden, http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	httpres.SetMarshal(ctx, w, http.StatusCreated, dto.CastBallotResponse{ElectionID: electionID, Message: "ballot is cast", Receipt: receipt}, "")
+}
+
+// @Summary Verify Receipt
+// @Description Merkle inclusion proof of the ballot of a receipt against the published root of the ballot ledger (GET /ledger/root).
+// @Description The ledger entry holds the receipt hash and a salted commitment to the ballot, never the choices, so the proof does not show the vote.
+// @Description Verify it offline with: go run cmd/main.go verify-proof proof.json RECEIPT
+// @Tags Ballots
+// @Accept  json
+// @Produce  json
+// @Param receipt path string true "Receipt code"
+// @Success 200 {object} dto.ReceiptVerificationResponse
+// @Failure 404 {string} string
+// @Router /verify/{receipt} [get]
+func (h *Ballots) Verify(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var ballotUC = usecase.BallotUC{Log: h.Log, DB: h.DB}
+	ballot, proof, statusCode, err := ballotUC.VerifyReceipt(ctx, ps.ByName("receipt"))
+	if err != nil {
+		if statusCode == http.StatusNotFound {
+			http.Error(w, "Receipt not found", statusCode)
+		} else {
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.ReceiptVerificationResponse
+	response.FromEntity(ballot, proof)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Candidates handler
+type Candidates struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Candidates
+// @Description List Candidates of an election ordered by ballot number
+// @Tags Candidates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.CandidateResponse
+// @Router /elections/{id}/candidates [get]
+func (h *Candidates) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = model.Candidate{ElectionID: electionID}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var candidatesResponse dto.CandidateResponse
+	response := candidatesResponse.ListFromEntity(candidates)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Candidate By ID
+// @Description Get Candidate By ID
+// @Tags Candidates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param candidate_id path int true "Candidate ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.CandidateResponse
+// @Router /elections/{id}/candidates/{candidate_id} [get]
+func (h *Candidates) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("candidate_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid candidate_id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("candidates.%d", id)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = model.Candidate{ID: id, ElectionID: electionID}
+	err = candidateRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.CandidateResponse
+	response.FromEntity(candidateRepo.CandidateEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Add Candidate
+// @Description Register a Candidate. Only allowed while the election is in draft state.
+// @Tags Candidates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param request body dto.AddCandidateRequest true "Candidate to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.CandidateResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/candidates [post]
+func (h *Candidates) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var candidateRequest dto.AddCandidateRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&candidateRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := candidateRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) {
+		return
+	}
+
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = candidateRequest.ToEntity(electionID)
+	if err := candidateRepo.Save(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.CandidateResponse
+	response.FromEntity(candidateRepo.CandidateEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Candidate
+// @Description Update Candidate. Only allowed while the election is in draft state.
+// @Tags Candidates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param candidate_id path int true "Candidate ID"
+// @Param request body dto.UpdateCandidateRequest true "Candidate to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.CandidateResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/candidates/{candidate_id} [put]
+func (h *Candidates) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("candidate_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid candidate_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var candidateRequest dto.UpdateCandidateRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&candidateRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := candidateRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) || !h.isCandidateExist(ctx, w, electionID, id) {
+		return
+	}
+
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = candidateRequest.ToEntity(electionID)
+	if err := candidateRepo.Update(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.CandidateResponse
+	response.FromEntity(candidateRepo.CandidateEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.Cache.Del(ctx, fmt.Sprintf("candidates.%d", id))
+}
+
+// @Security Bearer
+// @Summary Delete Candidate By ID
+// @Description Delete Candidate By ID. Only allowed while the election is in draft state.
+// @Tags Candidates
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param candidate_id path int true "Candidate ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/candidates/{candidate_id} [delete]
+func (h *Candidates) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("candidate_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid candidate_id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) || !h.isCandidateExist(ctx, w, electionID, id) {
+		return
+	}
+
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = model.Candidate{ID: id, ElectionID: electionID}
+	if err := candidateRepo.Delete(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Cache.Del(ctx, fmt.Sprintf("candidates.%d", id))
+}
+
+// isElectionEditable writes the error response and returns false when the election does not exist or has left draft state
+func (h *Candidates) isElectionEditable(ctx context.Context, w http.ResponseWriter, electionID int64) bool {
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: electionID}
+	err := electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	if electionRepo.ElectionEntity.Status != model.ElectionStatusDraft {
+		http.Error(w, "Candidates are locked: "+repository.ErrElectionLocked.Error(), http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// isCandidateExist writes the error response and returns false when the candidate does not exist in the election
+func (h *Candidates) isCandidateExist(ctx context.Context, w http.ResponseWriter, electionID int64, id int64) bool {
+	var candidateRepo = repository.CandidateRepository{Log: h.Log, Db: h.DB}
+	candidateRepo.CandidateEntity = model.Candidate{ID: id, ElectionID: electionID}
+	err := candidateRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Candidate not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	var before dto.CandidateResponse
+	before.FromEntity(candidateRepo.CandidateEntity)
+	audit.Before(ctx, before)
+	return true
+}
+
+func (h *Candidates) writeSaveError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrElectionLocked:
+		http.Error(w, "Candidates are locked: "+err.Error(), http.StatusConflict)
+	case repository.ErrBallotNumberTaken:
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
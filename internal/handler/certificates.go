@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Certificates handler for the signed results of certified elections
+type Certificates struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Summary Get Election Certificate
+// @Description Signed export bundle of a certified election: the results document, its sha256 checksum and the detached Ed25519
+// @Description signatures of the certifier keys over its canonical JSON form (keys sorted, no whitespace, numbers as written).
+// @Description Save the bundle and check it offline with: go run cmd/main.go verify-certificate certificate.json [public keys]
+// @Tags Results
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Success 200 {object} dto.CertificateResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/certificate [get]
+func (h *Certificates) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("certificates.%d", electionID)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var certificateUC = usecase.CertificateUC{Log: h.Log, DB: h.DB}
+	cert, statusCode, err := certificateUC.Get(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Certificate not found", statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	// a certified election is final, its certificate never changes
+	var response dto.CertificateResponse
+	response.FromEntity(cert)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Credentials handler for the blindly signed voting credentials
+type Credentials struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Summary Get Credential Key
+// @Description RSA public key that blindly signs the voting credentials of the election. The voter picks a random 32 byte token,
+// @Description blinds its full domain hash with this key, has it signed with POST /elections/{id}/credentials and unblinds the signature.
+// @Description The key is made when the election opens, before that the election is not open yet.
+// @Tags Credentials
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Success 200 {object} dto.CredentialKeyResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/credential-key [get]
+func (h *Credentials) Key(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var credentialUC = usecase.CredentialUC{Log: h.Log, DB: h.DB}
+	publicKey, statusCode, err := credentialUC.Key(ctx, electionID)
+	if err != nil {
+		switch {
+		case err == usecase.ErrNoCredentialKey:
+			http.Error(w, "Credential key not found", statusCode)
+		case statusCode == http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case statusCode == http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.CredentialKeyResponse
+	if err := response.FromEntity(electionID, publicKey); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Issue Voting Credential
+// @Description Blindly sign the credential of the authenticated voter. Every eligible voter gets one credential per open election.
+// @Description Asking again with the same blinded token returns its signature again, another blinded token is refused.
+// @Description The server never sees the token itself, so the ballot cast with it can not be traced back to the voter.
+// @Tags Credentials
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param credential body dto.CredentialRequest true "Blinded token"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.CredentialResponse
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/credentials [post]
+func (h *Credentials) Issue(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var credentialRequest dto.CredentialRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&credentialRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := credentialRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var credentialUC = usecase.CredentialUC{Log: h.Log, DB: h.DB}
+	blindSignature, statusCode, err := credentialUC.Issue(ctx, credentialRequest.ToEntity(electionID))
+	if err != nil {
+		switch statusCode {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid input: "+err.Error(), statusCode)
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusForbidden, http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	httpres.SetMarshal(ctx, w, http.StatusCreated, dto.CredentialResponse{ElectionID: electionID, BlindSignature: hex.EncodeToString(blindSignature)}, "")
+}
@@ -0,0 +1,434 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Disputes handler for the disputes filed against tally forms and recapitulations
+type Disputes struct {
+	Log     *logger.Logger
+	DB      *sql.DB
+	Cache   *redis.Cache
+	Storage storage.Storage
+}
+
+// @Security Bearer
+// @Summary File Dispute
+// @Description File a dispute against the tally form of a polling station or the recapitulation of a region, while the election is closed or tallied
+// @Tags Disputes
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param dispute body dto.DisputeRequest true "Dispute to file"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.DisputeResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/disputes [post]
+func (h *Disputes) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var disputeRequest dto.DisputeRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&disputeRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := disputeRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var disputeUC = usecase.DisputeUC{Log: h.Log, DB: h.DB, Cache: h.Cache, Storage: h.Storage}
+	dispute, statusCode, err := disputeUC.File(ctx, disputeRequest.ToEntity(electionID))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Election not found")
+		return
+	}
+
+	var response dto.DisputeResponse
+	response.FromEntity(dispute)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary List Disputes
+// @Description List the disputes of the election in filing order
+// @Tags Disputes
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param status query string false "filed, under_review, recount_ordered or resolved"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.DisputeResponse
+// @Router /elections/{id}/disputes [get]
+func (h *Disputes) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var disputeRepo = repository.DisputeRepository{Log: h.Log, Db: h.DB}
+	disputeRepo.DisputeEntity = model.Dispute{ElectionID: electionID}
+	disputes, err := disputeRepo.List(ctx, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var disputesResponse dto.DisputeResponse
+	response := disputesResponse.ListFromEntity(disputes)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Dispute
+// @Description Get the dispute with the history of its status and the evidences attached to it
+// @Tags Disputes
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param dispute_id path int true "Dispute ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.DisputeDetailResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/disputes/{dispute_id} [get]
+func (h *Disputes) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, disputeID, ok := h.ids(w, ps)
+	if !ok {
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var disputeUC = usecase.DisputeUC{Log: h.Log, DB: h.DB, Cache: h.Cache, Storage: h.Storage}
+	dispute, transitions, evidences, statusCode, err := disputeUC.Get(ctx, electionID, disputeID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Dispute not found")
+		return
+	}
+
+	var response dto.DisputeDetailResponse
+	response.FromEntity(dispute, transitions, evidences)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Move Dispute
+// @Description Move the dispute along its workflow: filed to under_review, under_review to recount_ordered, and any open dispute to resolved.
+// @Description Ordering a recount reopens the tally form or recapitulation under dispute so it is submitted and reviewed again, and drops the cached
+// @Description recapitulations above it. The election must be closed. A dispute with a recount is resolved once the recounted submission is approved.
+// @Tags Disputes
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param dispute_id path int true "Dispute ID"
+// @Param transition body dto.DisputeTransitionRequest true "Status to move to"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.DisputeTransitionResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/disputes/{dispute_id}/transitions [post]
+func (h *Disputes) Transition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, disputeID, ok := h.ids(w, ps)
+	if !ok {
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var transitionRequest dto.DisputeTransitionRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&transitionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := transitionRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var disputeUC = usecase.DisputeUC{Log: h.Log, DB: h.DB, Cache: h.Cache, Storage: h.Storage}
+	_, transition, statusCode, err := disputeUC.Transition(ctx, electionID, disputeID, transitionRequest.Status, transitionRequest.Note)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Dispute not found")
+		return
+	}
+
+	var response dto.DisputeTransitionResponse
+	response.FromEntity(transition)
+	audit.Before(ctx, map[string]string{"status": transition.FromStatus})
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Upload Dispute Evidence
+// @Description Attach a photo or document (jpeg, png, webp or pdf) to an open dispute. The content type is sniffed from the file. Files are stored by their sha256 checksum, uploading the same file again returns the existing evidence with status 200.
+// @Tags Disputes
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param dispute_id path int true "Dispute ID"
+// @Param file formData file true "Evidence"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.DisputeEvidenceResponse
+// @Success 201 {object} dto.DisputeEvidenceResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 413 {string} string
+// @Failure 415 {string} string
+// @Router /elections/{id}/disputes/{dispute_id}/evidences [post]
+func (h *Disputes) UploadEvidence(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, disputeID, ok := h.ids(w, ps)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, storage.MaxSize()+multipartOverhead)
+	defer r.Body.Close()
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: please supply a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Invalid input: file is required", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			h.Log.Error(err)
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(w, storage.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Invalid input: please supply a multipart/form-data body", http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		filename := filepath.Base(part.FileName())
+		if filename == "." || filename == string(filepath.Separator) || len(filename) > 255 {
+			filename = ""
+		}
+
+		var httpres = httpresponse.Response{Cache: h.Cache}
+		var disputeUC = usecase.DisputeUC{Log: h.Log, DB: h.DB, Cache: h.Cache, Storage: h.Storage}
+		evidence, created, statusCode, err := disputeUC.UploadEvidence(ctx, electionID, disputeID, filename, part)
+		part.Close()
+		if err != nil {
+			h.writeError(w, statusCode, err, "Dispute not found")
+			return
+		}
+
+		var response dto.DisputeEvidenceResponse
+		response.FromEntity(evidence)
+		if created {
+			audit.After(ctx, response)
+			httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+		} else {
+			httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+		}
+		return
+	}
+}
+
+// @Security Bearer
+// @Summary Download Dispute Evidence
+// @Description Download an evidence attached to the dispute by its sha256 checksum
+// @Tags Disputes
+// @Produce  octet-stream
+// @Param id path int true "Election ID"
+// @Param dispute_id path int true "Dispute ID"
+// @Param sha256 path string true "SHA-256 checksum of the evidence"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {file} file
+// @Failure 404 {string} string
+// @Router /elections/{id}/disputes/{dispute_id}/evidences/{sha256} [get]
+func (h *Disputes) DownloadEvidence(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, disputeID, ok := h.ids(w, ps)
+	if !ok {
+		return
+	}
+
+	var disputeUC = usecase.DisputeUC{Log: h.Log, DB: h.DB, Cache: h.Cache, Storage: h.Storage}
+	evidence, content, statusCode, err := disputeUC.OpenEvidence(ctx, electionID, disputeID, ps.ByName("sha256"))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Evidence not found")
+		return
+	}
+	defer content.Close()
+
+	// the content of a checksum never changes
+	w.Header().Set("Content-Type", evidence.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(evidence.Size, 10))
+	w.Header().Set("ETag", `"`+evidence.SHA256+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		h.Log.Error(err)
+	}
+}
+
+// ids parses the election and dispute ids of the path, writing the error response when one is invalid
+func (h *Disputes) ids(w http.ResponseWriter, ps httprouter.Params) (int64, int64, bool) {
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	disputeID, err := strconv.ParseInt(ps.ByName("dispute_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid dispute_id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return electionID, disputeID, true
+}
+
+func (h *Disputes) writeError(w http.ResponseWriter, statusCode int, err error, notFound string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		http.Error(w, "Invalid input: "+err.Error(), statusCode)
+	case http.StatusNotFound:
+		http.Error(w, notFound, statusCode)
+	case http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		http.Error(w, err.Error(), statusCode)
+	default:
+		http.Error(w, "Internal Server Error", statusCode)
+	}
+}
@@ -0,0 +1,423 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Districts handler
+type Districts struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Districts
+// @Description List the electoral districts of an election with their seat quota
+// @Tags Districts
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.DistrictResponse
+// @Router /elections/{id}/districts [get]
+func (h *Districts) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = model.District{ElectionID: electionID}
+	districts, err := districtRepo.List(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var districtsResponse dto.DistrictResponse
+	response := districtsResponse.ListFromEntity(districts)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Add District
+// @Description Add an electoral district with its seat quota. Only allowed while the election is in draft state.
+// @Tags Districts
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param request body dto.AddDistrictRequest true "District to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.DistrictResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/districts [post]
+func (h *Districts) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var districtRequest dto.AddDistrictRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&districtRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := districtRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) {
+		return
+	}
+
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = districtRequest.ToEntity(electionID)
+	if err := districtRepo.Save(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.DistrictResponse
+	response.FromEntity(districtRepo.DistrictEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update District
+// @Description Update District. Only allowed while the election is in draft state.
+// @Tags Districts
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param district_id path int true "District ID"
+// @Param request body dto.UpdateDistrictRequest true "District to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.DistrictResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/districts/{district_id} [put]
+func (h *Districts) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("district_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid district_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var districtRequest dto.UpdateDistrictRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&districtRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := districtRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) || !h.isDistrictExist(ctx, w, electionID, id) {
+		return
+	}
+
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = districtRequest.ToEntity(electionID)
+	if err := districtRepo.Update(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.DistrictResponse
+	response.FromEntity(districtRepo.DistrictEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Delete District By ID
+// @Description Delete District By ID. Only allowed while the election is in draft state.
+// @Tags Districts
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param district_id path int true "District ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/districts/{district_id} [delete]
+func (h *Districts) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("district_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid district_id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.isElectionEditable(ctx, w, electionID) || !h.isDistrictExist(ctx, w, electionID, id) {
+		return
+	}
+
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = model.District{ID: id, ElectionID: electionID}
+	if err := districtRepo.Delete(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Security Bearer
+// @Summary Enter District Party Votes
+// @Description Replace the vote totals of every party in the district. Only allowed while the election is closed.
+// @Tags Districts
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param district_id path int true "District ID"
+// @Param request body dto.DistrictVotesRequest true "Party votes of the district"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/districts/{district_id}/votes [put]
+func (h *Districts) SaveVotes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("district_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid district_id", http.StatusBadRequest)
+		return
+	}
+
+	var votesRequest dto.DistrictVotesRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&votesRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := votesRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = model.District{ID: id, ElectionID: electionID}
+	if err := h.auditVotes(ctx, districtRepo); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = districtRepo.SaveVotes(ctx, votesRequest.ToEntity(id))
+	if err == sql.ErrNoRows {
+		http.Error(w, "District not found", http.StatusNotFound)
+		return
+	} else if err == repository.ErrVotesLocked {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	audit.After(ctx, votesRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isElectionEditable writes the error response and returns false when the election does not exist or has left draft state
+func (h *Districts) isElectionEditable(ctx context.Context, w http.ResponseWriter, electionID int64) bool {
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: electionID}
+	err := electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	if electionRepo.ElectionEntity.Status != model.ElectionStatusDraft {
+		http.Error(w, "Districts are locked: "+repository.ErrElectionLocked.Error(), http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// isDistrictExist writes the error response and returns false when the district does not exist in the election
+func (h *Districts) isDistrictExist(ctx context.Context, w http.ResponseWriter, electionID int64, id int64) bool {
+	var districtRepo = repository.DistrictRepository{Log: h.Log, Db: h.DB}
+	districtRepo.DistrictEntity = model.District{ID: id, ElectionID: electionID}
+	err := districtRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "District not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	var before dto.DistrictResponse
+	before.FromEntity(districtRepo.DistrictEntity)
+	audit.Before(ctx, before)
+	return true
+}
+
+// auditVotes records the party votes of the district for the audit log as they were before they are replaced
+func (h *Districts) auditVotes(ctx context.Context, districtRepo repository.DistrictRepository) error {
+	votes, err := districtRepo.ListVotes(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := dto.DistrictVotesRequest{Votes: make([]dto.PartyVotesRequest, 0)}
+	for _, partyVotes := range votes {
+		if partyVotes.DistrictID == districtRepo.DistrictEntity.ID {
+			before.Votes = append(before.Votes, dto.PartyVotesRequest{Party: partyVotes.Party, Votes: partyVotes.Votes})
+		}
+	}
+	audit.Before(ctx, before)
+	return nil
+}
+
+func (h *Districts) writeSaveError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrElectionLocked:
+		http.Error(w, "Districts are locked: "+err.Error(), http.StatusConflict)
+	case repository.ErrDistrictNameTaken:
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
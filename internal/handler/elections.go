@@ -0,0 +1,447 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Elections handler
+type Elections struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+	// CertifierKeys sign the certificate of an election when it is certified
+	CertifierKeys []ed25519.PrivateKey
+}
+
+// @Security Bearer
+// @Summary List Elections
+// @Description List Elections
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param search query string false "Search by name"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.ElectionResponse
+// @Router /elections [get]
+func (h *Elections) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	elections, err := electionRepo.List(ctx, r.URL.Query().Get("search"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var electionsResponse dto.ElectionResponse
+	response := electionsResponse.ListFromEntity(elections)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Election By ID
+// @Description Get Election By ID
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.ElectionResponse
+// @Router /elections/{id} [get]
+func (h *Elections) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("elections.%d", id)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: id}
+	err = electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.ElectionResponse
+	response.FromEntity(electionRepo.ElectionEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Create Election
+// @Description Create Election in draft state
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param election body dto.ElectionCreateRequest true "Election to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.ElectionResponse
+// @Router /elections [post]
+func (h *Elections) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var electionRequest dto.ElectionCreateRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&electionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := electionRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = electionRequest.ToEntity()
+	if err := electionRepo.Save(ctx); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.ElectionResponse
+	response.FromEntity(electionRepo.ElectionEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Election
+// @Description Update Election. Only elections in draft state can be updated.
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param election body dto.ElectionUpdateRequest true "Election to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.ElectionResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id} [put]
+func (h *Elections) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var electionRequest dto.ElectionUpdateRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&electionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := electionRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: id}
+	err = electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var before dto.ElectionResponse
+	before.FromEntity(electionRepo.ElectionEntity)
+	audit.Before(ctx, before)
+
+	electionRepo.ElectionEntity = electionRequest.ToEntity()
+	err = electionRepo.Update(ctx)
+	if err == repository.ErrElectionStatusChanged {
+		http.Error(w, "Election can only be updated in draft state", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.ElectionResponse
+	response.FromEntity(electionRepo.ElectionEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.Cache.Del(ctx, fmt.Sprintf("elections.%d", id))
+}
+
+// @Security Bearer
+// @Summary Delete Election By ID
+// @Description Delete Election By ID. Only elections in draft state can be deleted.
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id} [delete]
+func (h *Elections) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: id}
+	err = electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var before dto.ElectionResponse
+	before.FromEntity(electionRepo.ElectionEntity)
+	audit.Before(ctx, before)
+
+	err = electionRepo.Delete(ctx)
+	if err == repository.ErrElectionStatusChanged {
+		http.Error(w, "Election can only be deleted in draft state", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Cache.Del(ctx, fmt.Sprintf("elections.%d", id))
+}
+
+// @Security Bearer
+// @Summary Transition Election
+// @Description Move an election to the next state: draft, scheduled, open, closed, tallied, certified.
+// @Description Certifying an election signs its results document with the certifier keys, published at GET /elections/{id}/certificate.
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param transition body dto.ElectionTransitionRequest true "Target status"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.ElectionTransitionResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 503 {string} string
+// @Router /elections/{id}/transitions [post]
+func (h *Elections) Transition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var transitionRequest dto.ElectionTransitionRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&transitionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := transitionRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var electionUC = usecase.ElectionUC{Log: h.Log, DB: h.DB, CertifierKeys: h.CertifierKeys}
+	transition, statusCode, err := electionUC.Transition(ctx, id, transitionRequest.Status)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, "Invalid transition: "+err.Error(), statusCode)
+		case http.StatusServiceUnavailable:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.ElectionTransitionResponse
+	response.FromEntity(transition)
+	audit.Before(ctx, map[string]string{"status": transition.FromStatus})
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.Cache.Del(ctx, fmt.Sprintf("elections.%d", id))
+}
+
+// @Security Bearer
+// @Summary List Election Transitions
+// @Description List the recorded state transitions of an election
+// @Tags Elections
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.ElectionTransitionResponse
+// @Router /elections/{id}/transitions [get]
+func (h *Elections) ListTransitions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: id}
+	transitions, err := electionRepo.ListTransitions(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var transitionResponse dto.ElectionTransitionResponse
+	response := transitionResponse.ListFromEntity(transitions)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/biometric"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Fingerprints handler for the biometric enrollment and matching of voters. Responses are never cached.
+type Fingerprints struct {
+	Log       *logger.Logger
+	DB        *sql.DB
+	Cache     *redis.Cache
+	Biometric *biometric.Engine
+}
+
+// @Security Bearer
+// @Summary Get Voter Fingerprint
+// @Description Get the fingerprint enrolled for a voter. The template itself is never returned.
+// @Tags Fingerprints
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.FingerprintResponse
+// @Failure 404 {string} string
+// @Router /voters/{id}/fingerprint [get]
+func (h *Fingerprints) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var fingerprintRepo = repository.VoterFingerprintRepository{Log: h.Log, Db: h.DB}
+	fingerprintRepo.VoterFingerprintEntity = model.VoterFingerprint{VoterID: id}
+	err = fingerprintRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Fingerprint not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.FingerprintResponse
+	response.FromEntity(fingerprintRepo.VoterFingerprintEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Enroll Voter Fingerprint
+// @Description Enroll the fingerprint of a voter from an ISO/IEC 19794-2 or ANSI-378 minutiae record, replacing the fingerprint enrolled before.
+// @Description The record is stored encrypted. When the fingerprint matches the fingerprint of other voters they are reported with status 409,
+// @Description set allow_duplicate to enroll it anyway.
+// @Tags Fingerprints
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param fingerprint body dto.FingerprintEnrollRequest true "Fingerprint to enroll"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.FingerprintResponse
+// @Failure 404 {string} string
+// @Failure 409 {object} dto.FingerprintDuplicateResponse
+// @Router /voters/{id}/fingerprint [put]
+func (h *Fingerprints) Enroll(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var fingerprintRequest dto.FingerprintEnrollRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&fingerprintRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := fingerprintRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var fingerprintRepo = repository.VoterFingerprintRepository{Log: h.Log, Db: h.DB}
+	fingerprintRepo.VoterFingerprintEntity = model.VoterFingerprint{VoterID: id}
+	err = fingerprintRepo.Find(ctx)
+	if err == nil {
+		var before dto.FingerprintResponse
+		before.FromEntity(fingerprintRepo.VoterFingerprintEntity)
+		audit.Before(ctx, before)
+	} else if err != sql.ErrNoRows {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var fingerprintUC = usecase.FingerprintUC{Log: h.Log, DB: h.DB, Biometric: h.Biometric}
+	fingerprint, matches, statusCode, err := fingerprintUC.Enroll(ctx, id, fingerprintRequest)
+	if err == usecase.ErrFingerprintDuplicate {
+		var matchResponse dto.FingerprintMatchResponse
+		httpres.SetMarshal(ctx, w, statusCode, dto.FingerprintDuplicateResponse{
+			Message: "fingerprint matches other voters, resend with allow_duplicate to enroll anyway",
+			Matches: matchResponse.ListFromEntity(matches),
+		}, "")
+		return
+	} else if err != nil {
+		h.writeError(w, statusCode, err, "Voter not found")
+		return
+	}
+
+	var response dto.FingerprintResponse
+	response.FromEntity(fingerprint)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Delete Voter Fingerprint
+// @Description Erase the fingerprint enrolled for a voter
+// @Tags Fingerprints
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Router /voters/{id}/fingerprint [delete]
+func (h *Fingerprints) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var fingerprintRepo = repository.VoterFingerprintRepository{Log: h.Log, Db: h.DB}
+	fingerprintRepo.VoterFingerprintEntity = model.VoterFingerprint{VoterID: id}
+	err = fingerprintRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Fingerprint not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var before dto.FingerprintResponse
+	before.FromEntity(fingerprintRepo.VoterFingerprintEntity)
+	audit.Before(ctx, before)
+
+	err = fingerprintRepo.Delete(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Fingerprint not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Security Bearer
+// @Summary Verify Voter Fingerprint
+// @Description Compare the fingerprint read at voter check-in with the fingerprint enrolled for the voter (1:1).
+// @Description matched is true when the score reaches the configured threshold.
+// @Tags Fingerprints
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param fingerprint body dto.FingerprintRequest true "Fingerprint read by the scanner"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.FingerprintVerifyResponse
+// @Failure 404 {string} string
+// @Router /voters/{id}/fingerprint/verify [post]
+func (h *Fingerprints) Verify(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var fingerprintRequest dto.FingerprintRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&fingerprintRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := fingerprintRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var fingerprintUC = usecase.FingerprintUC{Log: h.Log, DB: h.DB, Biometric: h.Biometric}
+	match, statusCode, err := fingerprintUC.Verify(ctx, id, fingerprintRequest)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Fingerprint not found")
+		return
+	}
+
+	httpres.SetMarshal(ctx, w, http.StatusOK, dto.FingerprintVerifyResponse{
+		VoterID:   match.VoterID,
+		Score:     match.Score,
+		Threshold: h.Biometric.Matcher.Threshold,
+		Matched:   h.Biometric.Matcher.Match(match.Score),
+	}, "")
+}
+
+// @Security Bearer
+// @Summary Identify Fingerprint
+// @Description Search a fingerprint among the fingerprints of all voters (1:N) to find duplicate registrations.
+// @Description Voters scoring at least the configured threshold are returned, best match first.
+// @Tags Fingerprints
+// @Accept  json
+// @Produce  json
+// @Param fingerprint body dto.FingerprintRequest true "Fingerprint read by the scanner"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.FingerprintMatchResponse
+// @Router /fingerprints/identify [post]
+func (h *Fingerprints) Identify(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var fingerprintRequest dto.FingerprintRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&fingerprintRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := fingerprintRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var fingerprintUC = usecase.FingerprintUC{Log: h.Log, DB: h.DB, Biometric: h.Biometric}
+	matches, statusCode, err := fingerprintUC.Identify(ctx, fingerprintRequest)
+	if err != nil {
+		h.writeError(w, statusCode, err, "")
+		return
+	}
+
+	var matchResponse dto.FingerprintMatchResponse
+	httpres.SetMarshal(ctx, w, http.StatusOK, matchResponse.ListFromEntity(matches), "")
+}
+
+func (h *Fingerprints) writeError(w http.ResponseWriter, statusCode int, err error, notFound string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		http.Error(w, "Invalid input: "+err.Error(), statusCode)
+	case http.StatusNotFound:
+		http.Error(w, notFound, statusCode)
+	default:
+		http.Error(w, "Internal Server Error", statusCode)
+	}
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxLedgerEntries is the largest page of ledger entries
+const maxLedgerEntries = 1000
+
+// Ledgers handler for the ballot ledger and its replication between peers
+type Ledgers struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+	Key   ed25519.PrivateKey
+}
+
+// @Summary Ledger Head
+// @Description The height, the hash and the Merkle root of the last entry of the ballot ledger of this node. Signed by an active peer.
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Param X-Peer-ID header int true "Peer ID"
+// @Param X-Peer-Timestamp header int true "Unix timestamp"
+// @Param X-Peer-Signature header string true "Request signature"
+// @Success 200 {object} dto.LedgerHeadResponse
+// @Failure 401 {string} string
+// @Router /ledger/head [get]
+func (h *Ledgers) Head(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var ledgerRepo = repository.LedgerRepository{Log: h.Log, Db: h.DB, OriginPeerID: model.LedgerOriginLocal}
+	head, err := ledgerRepo.Head(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.LedgerHeadResponse
+	response.FromEntity(head)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Summary Ledger Root
+// @Description The published root of the ballot ledger of this node: the height, the hash and the Merkle root of its last entry.
+// @Description Receipt proofs of GET /verify/{receipt} lead to this root.
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dto.LedgerHeadResponse
+// @Router /ledger/root [get]
+func (h *Ledgers) Root(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.Head(w, r, ps)
+}
+
+// @Summary Ledger Entries
+// @Description Entries of the ballot ledger of this node from a height on, a peer catches up its replica with it. Signed by an active peer.
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Param from query int false "First height, 1 by default"
+// @Param limit query int false "Maximal number of entries, 100 by default and 1000 at most"
+// @Param X-Peer-ID header int true "Peer ID"
+// @Param X-Peer-Timestamp header int true "Unix timestamp"
+// @Param X-Peer-Signature header string true "Request signature"
+// @Success 200 {array} dto.LedgerEntryResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Router /ledger/entries [get]
+func (h *Ledgers) Entries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	from, limit := int64(1), 100
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = strconv.ParseInt(value, 10, 64); err != nil || from < 1 {
+			http.Error(w, "Invalid input: from must be a height from 1", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxLedgerEntries {
+			http.Error(w, "Invalid input: limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var ledgerRepo = repository.LedgerRepository{Log: h.Log, Db: h.DB, OriginPeerID: model.LedgerOriginLocal}
+	entries, err := ledgerRepo.Entries(ctx, from, limit)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var entriesResponse dto.LedgerEntryResponse
+	response := entriesResponse.ListFromEntity(entries)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Summary Ledger Replicas
+// @Description Heads of the replicas this node keeps of the ledgers of its peers, so peers can cross verify them. Signed by an active peer.
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Param X-Peer-ID header int true "Peer ID"
+// @Param X-Peer-Timestamp header int true "Unix timestamp"
+// @Param X-Peer-Signature header string true "Request signature"
+// @Success 200 {array} dto.LedgerReplicaResponse
+// @Failure 401 {string} string
+// @Router /ledger/replicas [get]
+func (h *Ledgers) Replicas(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var ledgerRepo = repository.LedgerRepository{Log: h.Log, Db: h.DB}
+	replicas, err := ledgerRepo.ListReplicas(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var replicasResponse dto.LedgerReplicaResponse
+	response := replicasResponse.ListFromEntity(replicas)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Sync Ledgers
+// @Description Replicate the ledgers of all active peers from the height of their replica and cross verify the replicas they keep. Divergences are recorded.
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.LedgerSyncResponse
+// @Failure 503 {string} string
+// @Router /ledger/sync [post]
+func (h *Ledgers) Sync(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	syncs, statusCode, err := usecase.LedgerUC{Log: h.Log, DB: h.DB, Key: h.Key}.Sync(ctx)
+	if statusCode == http.StatusServiceUnavailable {
+		http.Error(w, "Peer key is not configured", statusCode)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", statusCode)
+		return
+	}
+
+	var syncsResponse dto.LedgerSyncResponse
+	response := syncsResponse.ListFromEntity(syncs)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary List Ledger Divergences
+// @Description Ledgers found to differ from their replica or from the replica of another peer, latest first
+// @Tags Ledger
+// @Accept  json
+// @Produce  json
+// @Param peer_id query int false "Origin peer of the ledger, all ledgers when omitted"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.LedgerDivergenceResponse
+// @Failure 400 {string} string
+// @Router /ledger/divergences [get]
+func (h *Ledgers) Divergences(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var peerID int64
+	if value := r.URL.Query().Get("peer_id"); value != "" {
+		var err error
+		if peerID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "please supply a valid peer_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var divergenceRepo = repository.LedgerDivergenceRepository{Log: h.Log, Db: h.DB}
+	divergences, err := divergenceRepo.List(ctx, peerID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var divergencesResponse dto.LedgerDivergenceResponse
+	response := divergencesResponse.ListFromEntity(divergences)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
@@ -0,0 +1,384 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Peers handler for the registry of the other nodes of the election network
+type Peers struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Peers
+// @Description List the registered peer nodes, filtered by status
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param status query string false "pending, active, suspended or revoked"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.PeerResponse
+// @Router /peers [get]
+func (h *Peers) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peers, err := peerRepo.List(ctx, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var peersResponse dto.PeerResponse
+	response := peersResponse.ListFromEntity(peers)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Peer By ID
+// @Description Get Peer By ID
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Peer ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.PeerResponse
+// @Failure 404 {string} string
+// @Router /peers/{id} [get]
+func (h *Peers) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peerRepo.PeerEntity = model.Peer{ID: id}
+	err = peerRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.PeerResponse
+	response.FromEntity(peerRepo.PeerEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Register Peer
+// @Description Register a peer node with its endpoint and Ed25519 public key. The peer stays pending until it is approved.
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param request body dto.AddPeerRequest true "Peer to register"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.PeerResponse
+// @Failure 409 {string} string
+// @Router /peers [post]
+func (h *Peers) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var peerRequest dto.AddPeerRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&peerRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := peerRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peerRepo.PeerEntity = peerRequest.ToEntity()
+	err = peerRepo.Save(ctx)
+	if err == repository.ErrPublicKeyTaken {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.PeerResponse
+	response.FromEntity(peerRepo.PeerEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Peer
+// @Description Update the name and the endpoint of a peer. The public key can not be changed, register a new peer instead.
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Peer ID"
+// @Param request body dto.UpdatePeerRequest true "Peer to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.PeerResponse
+// @Failure 404 {string} string
+// @Router /peers/{id} [put]
+func (h *Peers) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var peerRequest dto.UpdatePeerRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&peerRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := peerRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isPeerExist(ctx, w, id) {
+		return
+	}
+
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peerRepo.PeerEntity = peerRequest.ToEntity()
+	err = peerRepo.Update(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.PeerResponse
+	response.FromEntity(peerRepo.PeerEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Change Peer Status
+// @Description Approve (active), suspend, reinstate (active) or revoke a peer. Only active peers may call this node. Revoking is final.
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Peer ID"
+// @Param request body dto.PeerStatusRequest true "New status"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.PeerResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /peers/{id}/status [put]
+func (h *Peers) SetStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var statusRequest dto.PeerStatusRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&statusRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := statusRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isPeerExist(ctx, w, id) {
+		return
+	}
+
+	var peerUC = usecase.PeerUC{Log: h.Log, DB: h.DB}
+	peer, statusCode, err := peerUC.SetStatus(ctx, id, statusRequest.Status)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Peer not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, "Invalid transition: "+err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.PeerResponse
+	response.FromEntity(peer)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Summary Peer Handshake
+// @Description Returns the calling peer as registered on this node. The request must be signed by an active peer:
+// @Description X-Peer-Signature is the base64 Ed25519 signature of the method, the request URI, the hex sha256 of the body
+// @Description and X-Peer-Timestamp (unix seconds), joined by newlines. A signature is accepted once.
+// @Tags Peers
+// @Accept  json
+// @Produce  json
+// @Param X-Peer-ID header int true "Peer ID"
+// @Param X-Peer-Timestamp header int true "Unix timestamp"
+// @Param X-Peer-Signature header string true "Request signature"
+// @Success 200 {object} dto.PeerResponse
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Router /peer [get]
+func (h *Peers) Handshake(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peerRepo.PeerEntity = model.Peer{ID: ctx.Value(myctx.Key("peer_id")).(int64)}
+	if err := peerRepo.Find(ctx); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.PeerResponse
+	response.FromEntity(peerRepo.PeerEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// isPeerExist writes the error response and returns false when the peer does not exist
+func (h *Peers) isPeerExist(ctx context.Context, w http.ResponseWriter, id int64) bool {
+	var peerRepo = repository.PeerRepository{Log: h.Log, Db: h.DB}
+	peerRepo.PeerEntity = model.Peer{ID: id}
+	err := peerRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	var before dto.PeerResponse
+	before.FromEntity(peerRepo.PeerEntity)
+	audit.Before(ctx, before)
+	return true
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PollingStations handler
+type PollingStations struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Polling Stations
+// @Description List the polling stations (TPS) ordered by village and number, filtered by village
+// @Tags PollingStations
+// @Accept  json
+// @Produce  json
+// @Param village_id query int false "Village ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.PollingStationResponse
+// @Router /polling-stations [get]
+func (h *PollingStations) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var villageID int64
+	if value := r.URL.Query().Get("village_id"); len(value) > 0 {
+		var err error
+		if villageID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid village_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var pollingStationRepo = repository.PollingStationRepository{Log: h.Log, Db: h.DB}
+	pollingStations, err := pollingStationRepo.List(ctx, villageID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var pollingStationsResponse dto.PollingStationResponse
+	response := pollingStationsResponse.ListFromEntity(pollingStations)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Polling Station By ID
+// @Description Get Polling Station By ID
+// @Tags PollingStations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Polling Station ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.PollingStationResponse
+// @Router /polling-stations/{id} [get]
+func (h *PollingStations) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("polling-stations.%d", id)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var pollingStationRepo = repository.PollingStationRepository{Log: h.Log, Db: h.DB}
+	pollingStationRepo.PollingStationEntity = model.PollingStation{ID: id}
+	err = pollingStationRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Polling station not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.PollingStationResponse
+	response.FromEntity(pollingStationRepo.PollingStationEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Add Polling Station
+// @Description Add a polling station (TPS) to a village
+// @Tags PollingStations
+// @Accept  json
+// @Produce  json
+// @Param request body dto.AddPollingStationRequest true "Polling station to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.PollingStationResponse
+// @Failure 409 {string} string
+// @Router /polling-stations [post]
+func (h *PollingStations) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var pollingStationRequest dto.AddPollingStationRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&pollingStationRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := pollingStationRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regionRepo.RegionEntity = model.Region{ID: pollingStationRequest.VillageID}
+	err = regionRepo.Find(ctx)
+	if err == sql.ErrNoRows || (err == nil && regionRepo.RegionEntity.Level != model.RegionLevelVillage) {
+		http.Error(w, "Invalid input: village not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var pollingStationRepo = repository.PollingStationRepository{Log: h.Log, Db: h.DB}
+	pollingStationRepo.PollingStationEntity = pollingStationRequest.ToEntity()
+	if err := pollingStationRepo.Save(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.PollingStationResponse
+	response.FromEntity(pollingStationRepo.PollingStationEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+
+	// a new polling station changes the number of children in the recapitulations above it
+	h.Cache.DeleteByPrefix(ctx, "recapitulations.")
+}
+
+// @Security Bearer
+// @Summary Update Polling Station
+// @Description Update the number and the address of a polling station. The village can not be changed.
+// @Tags PollingStations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Polling Station ID"
+// @Param request body dto.UpdatePollingStationRequest true "Polling station to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.PollingStationResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /polling-stations/{id} [put]
+func (h *PollingStations) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var pollingStationRequest dto.UpdatePollingStationRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&pollingStationRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := pollingStationRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var pollingStationRepo = repository.PollingStationRepository{Log: h.Log, Db: h.DB}
+	pollingStationRepo.PollingStationEntity = model.PollingStation{ID: id}
+	err = pollingStationRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Polling station not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var before dto.PollingStationResponse
+	before.FromEntity(pollingStationRepo.PollingStationEntity)
+	audit.Before(ctx, before)
+
+	pollingStationRepo.PollingStationEntity = pollingStationRequest.ToEntity()
+	if err := pollingStationRepo.Update(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.PollingStationResponse
+	response.FromEntity(pollingStationRepo.PollingStationEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.Cache.Del(ctx, fmt.Sprintf("polling-stations.%d", id))
+}
+
+// @Security Bearer
+// @Summary Delete Polling Station By ID
+// @Description Delete Polling Station By ID
+// @Tags PollingStations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Polling Station ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Router /polling-stations/{id} [delete]
+func (h *PollingStations) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var pollingStationRepo = repository.PollingStationRepository{Log: h.Log, Db: h.DB}
+	pollingStationRepo.PollingStationEntity = model.PollingStation{ID: id}
+	err = pollingStationRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Polling station not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var before dto.PollingStationResponse
+	before.FromEntity(pollingStationRepo.PollingStationEntity)
+	audit.Before(ctx, before)
+
+	if err := pollingStationRepo.Delete(ctx); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Cache.Del(ctx, fmt.Sprintf("polling-stations.%d", id))
+	h.Cache.DeleteByPrefix(ctx, "recapitulations.")
+}
+
+func (h *PollingStations) writeSaveError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "Polling station not found", http.StatusNotFound)
+	case repository.ErrPollingStationNumberTaken:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,601 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Recapitulations handler, covering the tally forms of the polling stations and the recapitulation of every region above them
+type Recapitulations struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Tally Forms
+// @Description List the tally forms (C1) of an election without their lines, filtered by village and status
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param village_id query int false "Village ID"
+// @Param status query string false "submitted, approved, rejected or reopened"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.TallyFormResponse
+// @Router /elections/{id}/tally-forms [get]
+func (h *Recapitulations) ListTallyForms(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var villageID int64
+	if value := r.URL.Query().Get("village_id"); len(value) > 0 {
+		if villageID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid village_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var tallyFormRepo = repository.TallyFormRepository{Log: h.Log, Db: h.DB}
+	tallyFormRepo.TallyFormEntity = model.TallyForm{ElectionID: electionID}
+	forms, err := tallyFormRepo.List(ctx, villageID, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var formsResponse dto.TallyFormResponse
+	response := formsResponse.ListFromEntity(forms)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Tally Form
+// @Description Get the tally form (C1) of a polling station with the votes of every candidate
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TallyFormResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id} [get]
+func (h *Recapitulations) GetTallyForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var tallyFormRepo = repository.TallyFormRepository{Log: h.Log, Db: h.DB}
+	tallyFormRepo.TallyFormEntity = model.TallyForm{ElectionID: electionID, PollingStationID: pollingStationID}
+	err = tallyFormRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tally form not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.TallyFormResponse
+	response.FromEntity(tallyFormRepo.TallyFormEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Submit Tally Form
+// @Description Submit the tally form (C1) of a polling station. A rejected or submitted form is replaced, an approved form can not be changed. Only allowed while the election is closed.
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param request body dto.TallyFormRequest true "Votes of the polling station"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TallyFormResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id} [put]
+func (h *Recapitulations) SubmitTallyForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var formRequest dto.TallyFormRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&formRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := formRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.auditTallyForm(ctx, electionID, pollingStationID); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var recapitulationUC = usecase.RecapitulationUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	form, statusCode, err := recapitulationUC.SubmitTallyForm(ctx, formRequest.ToTallyForm(electionID, pollingStationID))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Polling station not found")
+		return
+	}
+
+	var response dto.TallyFormResponse
+	response.FromEntity(form)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Review Tally Form
+// @Description Approve or reject a submitted tally form. A rejection needs a note. Only allowed while the election is closed.
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param request body dto.ReviewRequest true "Review of the tally form"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TallyFormResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id}/status [put]
+func (h *Recapitulations) ReviewTallyForm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var reviewRequest dto.ReviewRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&reviewRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := reviewRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.auditTallyForm(ctx, electionID, pollingStationID); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var recapitulationUC = usecase.RecapitulationUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	form, statusCode, err := recapitulationUC.ReviewTallyForm(ctx, electionID, pollingStationID, reviewRequest.Status, reviewRequest.Note)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Tally form not found")
+		return
+	}
+
+	var response dto.TallyFormResponse
+	response.FromEntity(form)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary List Recapitulations
+// @Description List the recapitulations of an election without their lines, filtered by parent region and status
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param parent_id query int false "Parent region ID"
+// @Param status query string false "submitted, approved, rejected or reopened"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RecapitulationResponse
+// @Router /elections/{id}/recapitulations [get]
+func (h *Recapitulations) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var parentID int64
+	if value := r.URL.Query().Get("parent_id"); len(value) > 0 {
+		if parentID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid parent_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var recapitulationRepo = repository.RecapitulationRepository{Log: h.Log, Db: h.DB}
+	recapitulationRepo.RecapitulationEntity = model.Recapitulation{ElectionID: electionID}
+	recapitulations, err := recapitulationRepo.List(ctx, parentID, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var recapitulationsResponse dto.RecapitulationResponse
+	response := recapitulationsResponse.ListFromEntity(recapitulations)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Recapitulation
+// @Description Get the recapitulation of a region next to the computed sum of its approved children (tally forms for a village, child regions above it) and the discrepancies recorded for every revision
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param region_id path int true "Region ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RecapitulationReportResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/recapitulations/{region_id} [get]
+func (h *Recapitulations) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	regionID, err := strconv.ParseInt(ps.ByName("region_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid region_id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("recapitulations.%d.%d", electionID, regionID)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var recapitulationUC = usecase.RecapitulationUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	report, statusCode, err := recapitulationUC.Report(ctx, electionID, regionID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Region not found")
+		return
+	}
+
+	var response dto.RecapitulationReportResponse
+	response.FromEntity(report)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Submit Recapitulation
+// @Description Submit the totals of a region. Every polling station (for a village) or child region has to be approved first. Totals that differ from the computed sum are recorded as discrepancies of the new revision. Only allowed while the election is closed.
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param region_id path int true "Region ID"
+// @Param request body dto.TallyFormRequest true "Totals of the region"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RecapitulationSubmitResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/recapitulations/{region_id} [put]
+func (h *Recapitulations) Submit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	regionID, err := strconv.ParseInt(ps.ByName("region_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid region_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var recapitulationRequest dto.TallyFormRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&recapitulationRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := recapitulationRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.auditRecapitulation(ctx, electionID, regionID)
+	var recapitulationUC = usecase.RecapitulationUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	recapitulation, discrepancies, statusCode, err := recapitulationUC.Submit(ctx, recapitulationRequest.ToRecapitulation(electionID, regionID))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Region not found")
+		return
+	}
+
+	var response dto.RecapitulationSubmitResponse
+	response.FromEntity(recapitulation, discrepancies)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Review Recapitulation
+// @Description Approve or reject the submitted recapitulation of a region. A rejection needs a note. Only allowed while the election is closed.
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param region_id path int true "Region ID"
+// @Param request body dto.ReviewRequest true "Review of the recapitulation"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RecapitulationResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/recapitulations/{region_id}/status [put]
+func (h *Recapitulations) Review(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	regionID, err := strconv.ParseInt(ps.ByName("region_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid region_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var reviewRequest dto.ReviewRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&reviewRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := reviewRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.auditRecapitulation(ctx, electionID, regionID)
+	var recapitulationUC = usecase.RecapitulationUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	recapitulation, statusCode, err := recapitulationUC.Review(ctx, electionID, regionID, reviewRequest.Status, reviewRequest.Note)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Recapitulation not found")
+		return
+	}
+
+	var response dto.RecapitulationResponse
+	response.FromEntity(recapitulation)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+func (h *Recapitulations) writeError(w http.ResponseWriter, statusCode int, err error, notFound string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		http.Error(w, "Invalid input: "+err.Error(), statusCode)
+	case http.StatusNotFound:
+		http.Error(w, notFound, statusCode)
+	case http.StatusConflict:
+		http.Error(w, err.Error(), statusCode)
+	default:
+		http.Error(w, "Internal Server Error", statusCode)
+	}
+}
+
+// auditTallyForm records the tally form as it was before the request for the audit log, with the status a review
+// moves it from. A first submission has none, any other failure to read the form fails the request.
+func (h *Recapitulations) auditTallyForm(ctx context.Context, electionID int64, pollingStationID int64) error {
+	tallyFormRepo := repository.TallyFormRepository{Log: h.Log, Db: h.DB, TallyFormEntity: model.TallyForm{ElectionID: electionID, PollingStationID: pollingStationID}}
+	err := tallyFormRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var before dto.TallyFormResponse
+	before.FromEntity(tallyFormRepo.TallyFormEntity)
+	audit.Before(ctx, before)
+	return nil
+}
+
+// auditRecapitulation records the recapitulation as it was before the request for the audit log
+func (h *Recapitulations) auditRecapitulation(ctx context.Context, electionID int64, regionID int64) {
+	recapitulationRepo := repository.RecapitulationRepository{Log: h.Log, Db: h.DB, RecapitulationEntity: model.Recapitulation{ElectionID: electionID, RegionID: regionID}}
+	if err := recapitulationRepo.Find(ctx); err == nil {
+		var before dto.RecapitulationResponse
+		before.FromEntity(recapitulationRepo.RecapitulationEntity)
+		audit.Before(ctx, before)
+	}
+}
@@ -0,0 +1,355 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Regions handler
+type Regions struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Regions
+// @Description List the administrative regions ordered by code, filtered by parent and level
+// @Tags Regions
+// @Accept  json
+// @Produce  json
+// @Param parent_id query int false "Parent region ID"
+// @Param level query string false "national, province, regency, subdistrict or village"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RegionResponse
+// @Router /regions [get]
+func (h *Regions) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var parentID int64
+	if value := r.URL.Query().Get("parent_id"); len(value) > 0 {
+		var err error
+		if parentID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid parent_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regions, err := regionRepo.List(ctx, parentID, r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var regionsResponse dto.RegionResponse
+	response := regionsResponse.ListFromEntity(regions)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Region By ID
+// @Description Get Region By ID
+// @Tags Regions
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Region ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RegionResponse
+// @Router /regions/{id} [get]
+func (h *Regions) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("regions.%d", id)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regionRepo.RegionEntity = model.Region{ID: id}
+	err = regionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Region not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.RegionResponse
+	response.FromEntity(regionRepo.RegionEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Add Region
+// @Description Add an administrative region. A national region has no parent, every other level sits directly below the level above it (national, province, regency, subdistrict, village).
+// @Tags Regions
+// @Accept  json
+// @Produce  json
+// @Param request body dto.AddRegionRequest true "Region to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.RegionResponse
+// @Failure 409 {string} string
+// @Router /regions [post]
+func (h *Regions) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var regionRequest dto.AddRegionRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&regionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := regionRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if regionRequest.ParentID > 0 {
+		var parentRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+		parentRepo.RegionEntity = model.Region{ID: regionRequest.ParentID}
+		err = parentRepo.Find(ctx)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Invalid input: parent region not found", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !usecase.IsChildLevel(parentRepo.RegionEntity.Level, regionRequest.Level) {
+			http.Error(w, fmt.Sprintf("Invalid input: a %s can not be placed below a %s", regionRequest.Level, parentRepo.RegionEntity.Level), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regionRepo.RegionEntity = regionRequest.ToEntity()
+	if err := regionRepo.Save(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.RegionResponse
+	response.FromEntity(regionRepo.RegionEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Region
+// @Description Update the code and the name of a region. The parent and the level can not be changed.
+// @Tags Regions
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Region ID"
+// @Param request body dto.UpdateRegionRequest true "Region to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RegionResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /regions/{id} [put]
+func (h *Regions) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var regionRequest dto.UpdateRegionRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&regionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := regionRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regionRepo.RegionEntity = model.Region{ID: id}
+	err = regionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Region not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var before dto.RegionResponse
+	before.FromEntity(regionRepo.RegionEntity)
+	audit.Before(ctx, before)
+
+	regionRepo.RegionEntity = regionRequest.ToEntity()
+	if err := regionRepo.Update(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	var response dto.RegionResponse
+	response.FromEntity(regionRepo.RegionEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+	h.Cache.Del(ctx, fmt.Sprintf("regions.%d", id))
+}
+
+// @Security Bearer
+// @Summary Delete Region By ID
+// @Description Delete Region By ID. Regions that still have child regions or polling stations can not be deleted.
+// @Tags Regions
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Region ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /regions/{id} [delete]
+func (h *Regions) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var regionRepo = repository.RegionRepository{Log: h.Log, Db: h.DB}
+	regionRepo.RegionEntity = model.Region{ID: id}
+	err = regionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Region not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var before dto.RegionResponse
+	before.FromEntity(regionRepo.RegionEntity)
+	audit.Before(ctx, before)
+
+	if err := regionRepo.Delete(ctx); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Cache.Del(ctx, fmt.Sprintf("regions.%d", id))
+}
+
+func (h *Regions) writeSaveError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "Region not found", http.StatusNotFound)
+	case repository.ErrRegionCodeTaken, repository.ErrRegionInUse:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/stream"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Results handler
+type Results struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+	Hub   *stream.Hub
+}
+
+// @Security Bearer
+// @Summary Get Election Results
+// @Description Count the ballots with the counting method of the election. Multi round methods (irv and stv) return the breakdown of every round. Results are available once the election is closed.
+// @Tags Results
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.ElectionResultResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/results [get]
+func (h *Results) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	httpres := httpresponse.Response{Cache: h.Cache}
+	key := fmt.Sprintf("results.%d", electionID)
+	if cacheValue, isExist := h.Cache.Get(ctx, key); isExist {
+		httpres.Set(w, http.StatusOK, cacheValue)
+		return
+	}
+
+	var resultUC = usecase.ResultUC{Log: h.Log, DB: h.DB}
+	result, candidates, statusCode, err := resultUC.Count(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.ElectionResultResponse
+	response.FromEntity(electionID, result, candidates)
+
+	// ballots are only accepted while the election is open, so the results never change once they are available
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, key)
+}
+
+// @Security Bearer
+// @Summary Get Seat Allocation
+// @Description Allocate the seats of every district among the parties with the seat method of the election (sainte_lague or dhondt). Parties below the national threshold get no seats. Ties go to the party with more votes in the district, then to the party name in alphabetical order, and are flagged with tie_break.
+// @Tags Results
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.SeatAllocationResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/seats [get]
+func (h *Results) Seats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var resultUC = usecase.ResultUC{Log: h.Log, DB: h.DB}
+	allocation, statusCode, err := resultUC.Seats(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	// party votes can still be corrected while the election is closed, so the allocation is not cached
+	var response dto.SeatAllocationResponse
+	response.FromEntity(electionID, allocation)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
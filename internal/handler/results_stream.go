@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/usecase"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// streamHeartbeat keeps idle streams alive through proxies
+	streamHeartbeat = 15 * time.Second
+	// streamWriteTimeout replaces the WriteTimeout of the server for every event, a client that stops reading is dropped
+	streamWriteTimeout = 10 * time.Second
+)
+
+// streamEvent is one message of the results stream
+type streamEvent struct {
+	Name string
+	ID   int64
+	Data []byte
+}
+
+// @Security Bearer
+// @Summary Stream Election Results
+// @Description Push the tally as it is counted. The stream starts with a snapshot event holding the sum of the approved tally forms, followed by a tally event for every tally form approved afterwards. A recount ordered for a tally form sends a tally event marked reopened, its negated votes take the form back out of the sum. Versions go up by one with every approval, a gap means an update was lost and the stream should be reopened. A reconnect event asks the client to reopen the stream, for example while the server shuts down. Served as Server-Sent Events, or as WebSocket messages of the form {"event":..., "data":...} when the request asks for a WebSocket upgrade.
+// @Tags Results
+// @Produce  text/event-stream
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TallyUpdateResponse
+// @Failure 404 {string} string
+// @Failure 503 {string} string
+// @Router /elections/{id}/results/stream [get]
+func (h *Results) Stream(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	if h.Hub == nil {
+		http.Error(w, "Results stream is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// listen before the snapshot is read, so no approval falls between the two
+	updates, stop := h.Hub.Listen(usecase.ResultsChannel(electionID))
+	defer stop()
+
+	var resultUC = usecase.ResultUC{Log: h.Log, DB: h.DB}
+	snapshot, statusCode, err := resultUC.Snapshot(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var snapshotResponse dto.TallySnapshotResponse
+	snapshotResponse.FromEntity(snapshot)
+	data, err := sonic.Marshal(snapshotResponse)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	first := streamEvent{Name: "snapshot", ID: snapshot.Version, Data: data}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.streamWebSocket(w, r, first, updates)
+		return
+	}
+	h.streamEvents(w, r, first, updates)
+}
+
+// streamEvents writes the stream as Server-Sent Events
+func (h *Results) streamEvents(w http.ResponseWriter, r *http.Request, first streamEvent, updates <-chan string) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	h.relay(r.Context(), first, updates, func(event streamEvent) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			return err
+		}
+
+		var err error
+		switch event.Name {
+		case "ping":
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		default:
+			_, err = fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", event.Name, event.ID, event.Data)
+		}
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	})
+}
+
+// streamWebSocket writes the stream as WebSocket text messages
+func (h *Results) streamWebSocket(w http.ResponseWriter, r *http.Request, first streamEvent, updates <-chan string) {
+	server := websocket.Server{
+		// the stream is authenticated with the bearer token, not with cookies, so any origin may connect
+		Handshake: func(config *websocket.Config, r *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			// the connection is hijacked with the deadlines of the server still set
+			ws.SetDeadline(time.Time{})
+
+			// reading handles the close and ping frames of the client
+			go func() {
+				defer cancel()
+				var message string
+				for websocket.Message.Receive(ws, &message) == nil {
+				}
+			}()
+
+			h.relay(ctx, first, updates, func(event streamEvent) error {
+				if err := ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+					return err
+				}
+
+				data := event.Data
+				if data == nil {
+					data = []byte("null")
+				}
+				return websocket.Message.Send(ws, fmt.Sprintf(`{"event":%q,"id":%d,"data":%s}`, event.Name, event.ID, data))
+			})
+		},
+	}
+
+	server.ServeHTTP(unwrapHijacker(w), r)
+}
+
+// relay sends the first event, then every update newer than it, until the client goes away or the listener is closed
+func (h *Results) relay(ctx context.Context, first streamEvent, updates <-chan string, send func(streamEvent) error) {
+	if err := send(first); err != nil {
+		h.Log.Error(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := send(streamEvent{Name: "ping"}); err != nil {
+				return
+			}
+		case payload, ok := <-updates:
+			if !ok {
+				send(streamEvent{Name: "reconnect", ID: first.ID})
+				return
+			}
+
+			var update dto.TallyUpdateResponse
+			if err := sonic.UnmarshalString(payload, &update); err != nil {
+				h.Log.Error(err)
+				continue
+			}
+			// already counted in the snapshot
+			if update.Version <= first.ID {
+				continue
+			}
+
+			if err := send(streamEvent{Name: "tally", ID: update.Version, Data: []byte(payload)}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// unwrapHijacker returns the first writer of the middleware chain that can be hijacked
+func unwrapHijacker(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		if _, ok := w.(http.Hijacker); ok {
+			return w
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = unwrapper.Unwrap()
+	}
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RLAs handler for the risk-limiting audits of the paper-backed results
+type RLAs struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary Start Risk-Limiting Audit
+// @Description Start the risk-limiting audit of a tallied plurality election, against the outcome of its approved tally forms.
+// @Description A polling audit draws single ballots and measures the risk with BRAVO, a comparison audit draws whole TPS batches
+// @Description with probability proportional to their error bound and measures the risk with the Kaplan-Markov bound.
+// @Description The seed comes from the public seed ceremony, an election has one audit and its seed never changes.
+// @Tags RLA
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param rla body dto.RLARequest true "Audit to start"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.RLAResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/rla [post]
+func (h *RLAs) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rlaRequest dto.RLARequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&rlaRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := rlaRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rlaUC = usecase.RLAUC{Log: h.Log, DB: h.DB}
+	rla, statusCode, err := rlaUC.Create(ctx, rlaRequest.ToEntity(electionID))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Election not found")
+		return
+	}
+
+	var response dto.RLAResponse
+	response.FromEntity(rla)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Summary Get Risk-Limiting Audit
+// @Description State of the risk-limiting audit of the election: the public seed, the risk measure of every reported winner
+// @Description against every reported loser, and the decision. The measure is computed from the draws interpreted in a row
+// @Description from the first draw. The audit passes once the measure is at most the risk limit, and escalates to a full hand count
+// @Description when max_draws are interpreted without meeting it.
+// @Tags RLA
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Success 200 {object} dto.RLAReportResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/rla [get]
+func (h *RLAs) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rlaUC = usecase.RLAUC{Log: h.Log, DB: h.DB}
+	report, statusCode, err := rlaUC.Report(ctx, electionID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Audit not found")
+		return
+	}
+
+	var response dto.RLAReportResponse
+	response.FromEntity(report)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Summary List Risk-Limiting Audit Draws
+// @Description Every draw of the audit in draw order. Draw k of a polling audit is the SHA-256 of the seed, a comma and k, mod N over the
+// @Description ballot manifest, the approved tally forms in polling station order with the votes and invalid votes stacked per form.
+// @Description Anyone with the seed can repeat the draws.
+// @Tags RLA
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Success 200 {array} dto.RLADrawResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/rla/draws [get]
+func (h *RLAs) ListDraws(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rlaUC = usecase.RLAUC{Log: h.Log, DB: h.DB}
+	draws, statusCode, err := rlaUC.Draws(ctx, electionID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Audit not found")
+		return
+	}
+
+	var response []dto.RLADrawResponse = make([]dto.RLADrawResponse, 0, len(draws))
+	for _, draw := range draws {
+		var drawResponse dto.RLADrawResponse
+		drawResponse.FromEntity(draw)
+		response = append(response, drawResponse)
+	}
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Draw Risk-Limiting Audit Sample
+// @Description Draw the next ballots or batches of the audit, with replacement. No more than max_draws are made in total,
+// @Description and no draw is made once the audit passed or escalated.
+// @Tags RLA
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param draws body dto.RLADrawRequest true "Number of draws"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {array} dto.RLADrawResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/rla/draws [post]
+func (h *RLAs) Draw(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var drawRequest dto.RLADrawRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&drawRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := drawRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rlaUC = usecase.RLAUC{Log: h.Log, DB: h.DB}
+	draws, statusCode, err := rlaUC.Draw(ctx, electionID, drawRequest.Count)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Audit not found")
+		return
+	}
+
+	var response []dto.RLADrawResponse = make([]dto.RLADrawResponse, 0, len(draws))
+	for _, draw := range draws {
+		var drawResponse dto.RLADrawResponse
+		drawResponse.FromEntity(draw)
+		response = append(response, drawResponse)
+	}
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Record Risk-Limiting Audit Interpretation
+// @Description Record what the auditors read from a drawn ballot, one vote in lines or one invalid vote, or what they counted by hand
+// @Description in a drawn batch of a comparison audit, with ballot_position 0. An interpretation is recorded once and counts for every
+// @Description draw of the same ballot or batch.
+// @Tags RLA
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param interpretation body dto.RLAInterpretationRequest true "Interpretation"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.RLAReportResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/rla/interpretations [post]
+func (h *RLAs) Interpret(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var interpretationRequest dto.RLAInterpretationRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&interpretationRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := interpretationRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rlaUC = usecase.RLAUC{Log: h.Log, DB: h.DB}
+	statusCode, err := rlaUC.Interpret(ctx, interpretationRequest.ToEntity(electionID))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Audit not found")
+		return
+	}
+	audit.After(ctx, interpretationRequest)
+
+	report, statusCode, err := rlaUC.Report(ctx, electionID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Audit not found")
+		return
+	}
+
+	var response dto.RLAReportResponse
+	response.FromEntity(report)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+func (h *RLAs) writeError(w http.ResponseWriter, statusCode int, err error, notFound string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		http.Error(w, "Invalid input: "+err.Error(), statusCode)
+	case http.StatusNotFound:
+		http.Error(w, notFound, statusCode)
+	case http.StatusConflict:
+		http.Error(w, err.Error(), statusCode)
+	default:
+		http.Error(w, "Internal Server Error", statusCode)
+	}
+}
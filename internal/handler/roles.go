@@ -0,0 +1,980 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Roles handler manages the roles, the access granted to them, the roles they inherit and the roles of the users
+type Roles struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Roles
+// @Description List the roles ordered by name
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Router /roles [get]
+func (h *Roles) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var roleRepo = repository.RoleRepository{Log: h.Log, Db: h.DB}
+	roles, err := roleRepo.List(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var rolesResponse dto.RoleResponse
+	response := rolesResponse.ListFromEntity(roles)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Role By ID
+// @Description Get Role By ID
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RoleResponse
+// @Failure 404 {string} string
+// @Router /roles/{id} [get]
+func (h *Roles) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var response dto.RoleResponse
+	response.FromEntity(roleRepo.RoleEntity)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Add Role
+// @Description Add a role. New roles hold no access and are never superuser roles. A role scoped to an election or a region only grants its access on the routes of the election, and of the region and the regions below it.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param request body dto.AddRoleRequest true "Role to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.RoleResponse
+// @Failure 409 {string} string
+// @Router /roles [post]
+func (h *Roles) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var roleRequest dto.AddRoleRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&roleRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var roleRepo = repository.RoleRepository{Log: h.Log, Db: h.DB}
+	roleRepo.RoleEntity = roleRequest.ToEntity()
+	if err := roleRepo.Save(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var response dto.RoleResponse
+	response.FromEntity(roleRepo.RoleEntity)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Update Role
+// @Description Rename a role and change its scope. A superuser role can not be scoped.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param request body dto.UpdateRoleRequest true "Role to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.RoleResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /roles/{id} [put]
+func (h *Roles) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var roleRequest dto.UpdateRoleRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&roleRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRequest.Validate(roleRepo.RoleEntity.ID); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	var before dto.RoleResponse
+	before.FromEntity(roleRepo.RoleEntity)
+	audit.Before(ctx, before)
+
+	roleRepo.RoleEntity = roleRequest.ToEntity()
+	if err := roleRepo.Update(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var response dto.RoleResponse
+	response.FromEntity(roleRepo.RoleEntity)
+	audit.After(ctx, response)
+
+	// the scope is part of the cached permissions of the holders
+	if before.ElectionID != response.ElectionID || before.RegionID != response.RegionID {
+		holders, err := roleRepo.Holders(ctx)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		h.invalidate(ctx, holders...)
+	}
+
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Delete Role By ID
+// @Description Delete a role, its access grants, its parents, the roles inheriting it and its users. A superuser role can not be deleted when no other active user would be left holding a superuser role.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /roles/{id} [delete]
+func (h *Roles) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	var before dto.RoleResponse
+	before.FromEntity(roleRepo.RoleEntity)
+	audit.Before(ctx, before)
+
+	holders, err := roleRepo.Holders(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := roleRepo.Delete(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.invalidate(ctx, holders...)
+}
+
+// @Security Bearer
+// @Summary List Role Access
+// @Description List the access granted to a role ordered by path
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.AccessResponse
+// @Failure 404 {string} string
+// @Router /roles/{id}/access [get]
+func (h *Roles) ListAccess(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	access, err := roleRepo.ListAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var accessResponse dto.AccessResponse
+	response := accessResponse.ListFromEntity(access)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Grant Role Access
+// @Description Grant an access to a role. The users holding the role get the access right away.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param request body dto.GrantAccessRequest true "Access to grant"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.AccessResponse
+// @Failure 404 {string} string
+// @Router /roles/{id}/access [post]
+func (h *Roles) Grant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	var grantRequest dto.GrantAccessRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&grantRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := grantRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := roleRepo.Grant(ctx, grantRequest.AccessID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	audit.After(ctx, grantRequest)
+
+	h.writeAccess(ctx, w, roleRepo)
+}
+
+// @Security Bearer
+// @Summary Revoke Role Access
+// @Description Revoke an access from a role. Access can not be revoked from a superuser role.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param access_id path int true "Access ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.AccessResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /roles/{id}/access/{access_id} [delete]
+func (h *Roles) Revoke(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	accessID, err := strconv.ParseInt(ps.ByName("access_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid access_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	audit.Before(ctx, dto.GrantAccessRequest{AccessID: accessID})
+	if err := roleRepo.Revoke(ctx, accessID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Access is not granted to the role", http.StatusNotFound)
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeAccess(ctx, w, roleRepo)
+}
+
+// @Security Bearer
+// @Summary List Role Parents
+// @Description List the roles a role inherits directly ordered by name
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Router /roles/{id}/parents [get]
+func (h *Roles) ListParents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	parents, err := roleRepo.ListParents(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rolesResponse dto.RoleResponse
+	response := rolesResponse.ListFromEntity(parents)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Add Role Parent
+// @Description Make a role inherit the access of a parent role, like a provincial admin inheriting the access of a regency admin. The inherited access is narrowed to the scope of both roles, a parent scoped to another election or region grants nothing. A parent that already inherits the role, directly or through its own parents, is refused.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param request body dto.AddParentRequest true "Parent role to inherit"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /roles/{id}/parents [post]
+func (h *Roles) AddParent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	var parentRequest dto.AddParentRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&parentRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := parentRequest.Validate(roleRepo.RoleEntity.ID); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := roleRepo.Inherit(ctx, parentRequest.ParentID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	audit.After(ctx, parentRequest)
+
+	h.writeParents(ctx, w, roleRepo)
+}
+
+// @Security Bearer
+// @Summary Remove Role Parent
+// @Description Stop a role inheriting the access of a parent role
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Role ID"
+// @Param parent_id path int true "Parent Role ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Router /roles/{id}/parents/{parent_id} [delete]
+func (h *Roles) RemoveParent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	roleRepo, ok := h.find(w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+	parentID, err := strconv.ParseInt(ps.ByName("parent_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid parent_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := roleRepo.Find(ctx); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	audit.Before(ctx, dto.AddParentRequest{ParentID: parentID})
+	if err := roleRepo.Disinherit(ctx, parentID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Role does not inherit the parent", http.StatusNotFound)
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeParents(ctx, w, roleRepo)
+}
+
+// @Security Bearer
+// @Summary List User Roles
+// @Description List the roles held by a user ordered by name
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Router /users/{id}/roles [get]
+func (h *Roles) ListUserRoles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	userID, ok := h.findUser(ctx, w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	h.writeUserRoles(ctx, w, userID)
+}
+
+// @Security Bearer
+// @Summary Assign User Role
+// @Description Give a role to a user. The user gets the access of the role right away.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body dto.AssignRoleRequest true "Role to assign"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Router /users/{id}/roles [post]
+func (h *Roles) Assign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	userID, ok := h.findUser(ctx, w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	var assignRequest dto.AssignRoleRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&assignRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := assignRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var roleRepo = repository.RoleRepository{Log: h.Log, Db: h.DB}
+	roleRepo.RoleEntity = model.Role{ID: assignRequest.RoleID}
+	err = roleRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invalid input: role not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := roleRepo.Assign(ctx, userID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	audit.After(ctx, assignRequest)
+	h.invalidate(ctx, userID)
+
+	h.writeUserRoles(ctx, w, userID)
+}
+
+// @Security Bearer
+// @Summary Unassign User Role
+// @Description Take a role from a user. The last active user holding a superuser role keeps it.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param role_id path int true "Role ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /users/{id}/roles/{role_id} [delete]
+func (h *Roles) Unassign(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	userID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+	roleRepo, ok := h.find(w, ps.ByName("role_id"))
+	if !ok {
+		return
+	}
+
+	audit.Before(ctx, dto.AssignRoleRequest{RoleID: roleRepo.RoleEntity.ID})
+	if err := roleRepo.Unassign(ctx, userID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "User does not hold the role", http.StatusNotFound)
+			return
+		}
+		h.writeError(w, err)
+		return
+	}
+	h.invalidate(ctx, userID)
+
+	h.writeUserRoles(ctx, w, userID)
+}
+
+// @Security Bearer
+// @Summary Explain User Access
+// @Description Tell why a user has or lacks an access path, for support to debug the permissions of a user. Every grant lists the chain from the role the user holds to the role granted the access. A user holding only scoped grants of the path is explained within the election or the region given, without one the request is refused naming the scopes of the grants.
+// @Tags Roles
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param path query string true "Access path, like GET /users/:id"
+// @Param election_id query int false "Election to explain scoped grants in"
+// @Param region_id query int false "Region to explain scoped grants in"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.AccessExplanationResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /users/{id}/access/explain [get]
+func (h *Roles) ExplainAccess(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	userID, ok := h.findUser(ctx, w, ps.ByName("id"))
+	if !ok {
+		return
+	}
+
+	explainRequest := dto.ExplainAccessRequest{Path: r.URL.Query().Get("path")}
+	if value := r.URL.Query().Get("election_id"); len(value) > 0 {
+		var err error
+		if explainRequest.ElectionID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid election_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("region_id"); len(value) > 0 {
+		var err error
+		if explainRequest.RegionID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			h.Log.Error(err)
+			http.Error(w, "please supply a valid region_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := explainRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	authUC := usecase.AuthUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	explanation, statusCode, err := authUC.Explain(ctx, userID, explainRequest.Path, explainRequest.ElectionID, explainRequest.RegionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid input: "+err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var response dto.AccessExplanationResponse
+	response.FromEntity(explanation)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// find parses the role id, writing 400 when it is not valid
+func (h *Roles) find(w http.ResponseWriter, value string) (repository.RoleRepository, bool) {
+	var roleRepo = repository.RoleRepository{Log: h.Log, Db: h.DB}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return roleRepo, false
+	}
+
+	roleRepo.RoleEntity = model.Role{ID: id}
+	return roleRepo, true
+}
+
+// findUser parses the user id and checks the user exists
+func (h *Roles) findUser(ctx context.Context, w http.ResponseWriter, value string) (int64, bool) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return 0, false
+	}
+
+	var userRepo = repository.UserRepository{Log: h.Log, Db: h.DB}
+	userRepo.UserEntity = model.User{ID: id}
+	err = userRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return 0, false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// writeAccess drops the cached permissions of the holders of the role and writes the access of the role
+func (h *Roles) writeAccess(ctx context.Context, w http.ResponseWriter, roleRepo repository.RoleRepository) {
+	holders, err := roleRepo.Holders(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	h.invalidate(ctx, holders...)
+
+	access, err := roleRepo.ListAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var accessResponse dto.AccessResponse
+	response := accessResponse.ListFromEntity(access)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// writeParents drops the cached permissions of the holders of the role and of its heirs and writes the parents of the role
+func (h *Roles) writeParents(ctx context.Context, w http.ResponseWriter, roleRepo repository.RoleRepository) {
+	holders, err := roleRepo.Holders(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	h.invalidate(ctx, holders...)
+
+	parents, err := roleRepo.ListParents(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rolesResponse dto.RoleResponse
+	response := rolesResponse.ListFromEntity(parents)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+func (h *Roles) writeUserRoles(ctx context.Context, w http.ResponseWriter, userID int64) {
+	var roleRepo = repository.RoleRepository{Log: h.Log, Db: h.DB}
+	roles, err := roleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var rolesResponse dto.RoleResponse
+	response := rolesResponse.ListFromEntity(roles)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// invalidate drops the cached permissions of the users, a role without users changes nobody's permissions
+func (h *Roles) invalidate(ctx context.Context, userIDs ...int64) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	authUC := usecase.AuthUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	if err := authUC.InvalidatePermissions(ctx, userIDs...); err != nil {
+		h.Log.Error(err)
+	}
+}
+
+func (h *Roles) writeError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "Role not found", http.StatusNotFound)
+	case repository.ErrUserNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case repository.ErrAccessNotFound, repository.ErrScopeNotFound, repository.ErrParentNotFound:
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+	case repository.ErrRoleNameTaken, repository.ErrLastSuperuser, repository.ErrSuperuserAccess, repository.ErrSuperuserScope, repository.ErrRoleCycle:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
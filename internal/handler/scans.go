@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// multipartOverhead is the room left for the multipart headers and boundaries on top of the file size limit
+const multipartOverhead = 64 << 10
+
+// Scans handler for the photos and scans of signed tally sheets
+type Scans struct {
+	Log     *logger.Logger
+	DB      *sql.DB
+	Cache   *redis.Cache
+	Storage storage.Storage
+}
+
+// @Security Bearer
+// @Summary List Tally Form Scans
+// @Description List the photos and scans attached to the tally form of a polling station
+// @Tags Recapitulations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.TallyFormScanResponse
+// @Failure 404 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id}/scans [get]
+func (h *Scans) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var scanUC = usecase.ScanUC{Log: h.Log, DB: h.DB, Storage: h.Storage}
+	scans, statusCode, err := scanUC.List(ctx, electionID, pollingStationID)
+	if err != nil {
+		h.writeError(w, statusCode, err, "Tally form not found")
+		return
+	}
+
+	var scansResponse dto.TallyFormScanResponse
+	response := scansResponse.ListFromEntity(scans)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Upload Tally Form Scan
+// @Description Attach a photo or scan (jpeg, png, webp or pdf) of the signed tally sheet to the tally form of a polling station. The content type is sniffed from the file. Files are stored by their sha256 checksum, uploading the same file again returns the existing scan with status 200. Scans of an approved tally form can not be added.
+// @Tags Recapitulations
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param file formData file true "Photo or scan of the signed tally sheet"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TallyFormScanResponse
+// @Success 201 {object} dto.TallyFormScanResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Failure 413 {string} string
+// @Failure 415 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id}/scans [post]
+func (h *Scans) Upload(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, storage.MaxSize()+multipartOverhead)
+	defer r.Body.Close()
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: please supply a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Invalid input: file is required", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			h.Log.Error(err)
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(w, storage.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Invalid input: please supply a multipart/form-data body", http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		filename := filepath.Base(part.FileName())
+		if filename == "." || filename == string(filepath.Separator) || len(filename) > 255 {
+			filename = ""
+		}
+
+		var httpres = httpresponse.Response{Cache: h.Cache}
+		var scanUC = usecase.ScanUC{Log: h.Log, DB: h.DB, Storage: h.Storage}
+		scan, created, statusCode, err := scanUC.Upload(ctx, electionID, pollingStationID, filename, part)
+		part.Close()
+		if err != nil {
+			h.writeError(w, statusCode, err, "Tally form not found")
+			return
+		}
+
+		var response dto.TallyFormScanResponse
+		response.FromEntity(scan)
+		if created {
+			audit.After(ctx, response)
+			httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+		} else {
+			httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+		}
+		return
+	}
+}
+
+// @Security Bearer
+// @Summary Download Tally Form Scan
+// @Description Download a photo or scan attached to the tally form of a polling station by its sha256 checksum
+// @Tags Recapitulations
+// @Produce  octet-stream
+// @Param id path int true "Election ID"
+// @Param polling_station_id path int true "Polling Station ID"
+// @Param sha256 path string true "SHA-256 checksum of the scan"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {file} file
+// @Failure 404 {string} string
+// @Router /elections/{id}/tally-forms/{polling_station_id}/scans/{sha256} [get]
+func (h *Scans) Download(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	pollingStationID, err := strconv.ParseInt(ps.ByName("polling_station_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid polling_station_id", http.StatusBadRequest)
+		return
+	}
+
+	var scanUC = usecase.ScanUC{Log: h.Log, DB: h.DB, Storage: h.Storage}
+	scan, content, statusCode, err := scanUC.Open(ctx, electionID, pollingStationID, ps.ByName("sha256"))
+	if err != nil {
+		h.writeError(w, statusCode, err, "Scan not found")
+		return
+	}
+	defer content.Close()
+
+	// the content of a checksum never changes
+	w.Header().Set("Content-Type", scan.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(scan.Size, 10))
+	w.Header().Set("ETag", `"`+scan.SHA256+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		h.Log.Error(err)
+	}
+}
+
+func (h *Scans) writeError(w http.ResponseWriter, statusCode int, err error, notFound string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		http.Error(w, "Invalid input: "+err.Error(), statusCode)
+	case http.StatusNotFound:
+		http.Error(w, notFound, statusCode)
+	case http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		http.Error(w, err.Error(), statusCode)
+	default:
+		http.Error(w, "Internal Server Error", statusCode)
+	}
+}
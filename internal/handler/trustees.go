@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Trustees handler for the key ceremony and the threshold decryption of encrypted elections
+type Trustees struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary Setup Election Trustees
+// @Description Encrypt the ballots of the election. A new ElGamal key is created and split among the trustees, threshold of them decrypt the tally together.
+// @Description The response holds no key share, every trustee fetches their own share once from POST /elections/{id}/trustees/share.
+// @Description Only plurality and approval elections in draft state can be encrypted, once.
+// @Tags Trustees
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param trustees body dto.TrusteeSetupRequest true "Trustees to setup"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.TrusteeListResponse
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/trustees [post]
+func (h *Trustees) Setup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var trusteeRequest dto.TrusteeSetupRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&trusteeRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := trusteeRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var trusteeUC = usecase.TrusteeUC{Log: h.Log, DB: h.DB}
+	election, trustees, statusCode, err := trusteeUC.Setup(ctx, electionID, trusteeRequest)
+	if err != nil {
+		switch statusCode {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid input: "+err.Error(), statusCode)
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.TrusteeListResponse
+	response.FromEntity(election, trustees)
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
+
+// @Security Bearer
+// @Summary Fetch Trustee Share
+// @Description Hand the authenticated trustee their key share of the election. The share is erased once fetched and can not be fetched again, keep it private.
+// @Tags Trustees
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TrusteeShareResponse
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/trustees/share [post]
+func (h *Trustees) Share(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var trusteeUC = usecase.TrusteeUC{Log: h.Log, DB: h.DB}
+	trustee, statusCode, err := trusteeUC.Share(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusForbidden, http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.TrusteeShareResponse
+	response.FromEntity(trustee)
+	// the share must not be kept by the idempotency cache nor by any proxy
+	w.Header().Set("Cache-Control", "no-store")
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary List Election Trustees
+// @Description List the trustees of an encrypted election with their verification keys and whether they decrypted the tally
+// @Tags Trustees
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.TrusteeListResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/trustees [get]
+func (h *Trustees) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var trusteeUC = usecase.TrusteeUC{Log: h.Log, DB: h.DB}
+	election, trustees, statusCode, err := trusteeUC.List(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.TrusteeListResponse
+	response.FromEntity(election, trustees)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Encrypted Tally
+// @Description The encrypted votes of every candidate of a closed encrypted election: the product of the ciphertexts of all ballots.
+// @Description A trustee computes its decryption share offline with: go run cmd/main.go trustee-decrypt share.json tally.json
+// @Tags Trustees
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.EncryptedTallyResponse
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/encrypted-tally [get]
+func (h *Trustees) EncryptedTally(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var trusteeUC = usecase.TrusteeUC{Log: h.Log, DB: h.DB}
+	election, candidates, totals, ballots, statusCode, err := trusteeUC.EncryptedTally(ctx, electionID)
+	if err != nil {
+		switch statusCode {
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	var response dto.EncryptedTallyResponse
+	response.FromEntity(election, candidates, totals, ballots)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Submit Tally Decryption
+// @Description Submit the decryption share of the authenticated trustee for the encrypted votes of every candidate, each with its Chaum-Pedersen proof.
+// @Description The results of the election are revealed once threshold trustees submitted a valid decryption.
+// @Tags Trustees
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param decryption body dto.DecryptionRequest true "Decryption shares"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.DecryptionResponse
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /elections/{id}/decryptions [post]
+func (h *Trustees) Decrypt(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var decryptionRequest dto.DecryptionRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&decryptionRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := decryptionRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var trusteeUC = usecase.TrusteeUC{Log: h.Log, DB: h.DB}
+	// the trustees are recorded as they were before, Decrypt answers the election not found or not set up
+	if election, trustees, _, err := trusteeUC.List(ctx, electionID); err == nil {
+		var before dto.TrusteeListResponse
+		before.FromEntity(election, trustees)
+		audit.Before(ctx, before)
+	}
+
+	election, trustee, decrypted, statusCode, err := trusteeUC.Decrypt(ctx, electionID, decryptionRequest)
+	if err != nil {
+		switch statusCode {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid input: "+err.Error(), statusCode)
+		case http.StatusNotFound:
+			http.Error(w, "Election not found", statusCode)
+		case http.StatusForbidden, http.StatusConflict:
+			http.Error(w, err.Error(), statusCode)
+		default:
+			http.Error(w, "Internal Server Error", statusCode)
+		}
+		return
+	}
+
+	response := dto.DecryptionResponse{ElectionID: election.ID, TrusteeIndex: trustee.Index, Decrypted: decrypted, Threshold: election.TrusteeThreshold}
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
+}
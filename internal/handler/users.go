@@ -3,10 +3,12 @@ package handler
 import (
 	"backend-election/internal/dto"
 	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
 	"backend-election/internal/pkg/httpresponse"
 	"backend-election/internal/pkg/logger"
 	"backend-election/internal/pkg/redis"
 	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
 	"context"
 	"database/sql"
 	"fmt"
@@ -173,6 +175,7 @@ func (h *Users) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	}
 	var response dto.UserResponse
 	response.FromEntity(userRepo.UserEntity)
+	audit.After(ctx, response)
 	httpres.SetMarshal(ctx, w, http.StatusCreated, response, "")
 }
 
@@ -187,6 +190,7 @@ func (h *Users) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 // @Param Idempotency-Key header string true "Idempotency-Key"
 // @Param Authorization header string true "Bearer token"
 // @Success 200 {object} dto.UserResponse
+// @Failure 404 {string} string
 // @Router /users/{id} [put]
 func (h *Users) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var ctx = r.Context()
@@ -228,6 +232,19 @@ func (h *Users) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 	}
 
 	var userRepo = repository.UserRepository{Log: h.Log, Db: h.DB}
+	userRepo.UserEntity = model.User{ID: int64(id)}
+	err = userRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var before dto.UserResponse
+	before.FromEntity(userRepo.UserEntity)
+	audit.Before(ctx, before)
+
 	userRepo.UserEntity = userRequest.ToEntity()
 	if err := userRepo.Update(ctx); err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -236,13 +253,14 @@ func (h *Users) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 
 	var response dto.UserResponse
 	response.FromEntity(userRepo.UserEntity)
+	audit.After(ctx, response)
 	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
 	h.Cache.Del(ctx, fmt.Sprintf("users.%d", id))
 }
 
 // @Security Bearer
 // @Summary Delete User By ID
-// @Description Delete User By ID
+// @Description Delete User By ID. The last active user holding a superuser role can not be deleted.
 // @Tags Users
 // @Accept  json
 // @Produce  json
@@ -250,6 +268,8 @@ func (h *Users) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 // @Param Idempotency-Key header string true "Idempotency-Key"
 // @Param Authorization header string true "Bearer token"
 // @Success 204
+// @Failure 404 {string} string
+// @Failure 409 {string} string
 // @Router /users/{id} [delete]
 func (h *Users) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var ctx = r.Context()
@@ -276,11 +296,31 @@ func (h *Users) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 
 	var userRepo = repository.UserRepository{Log: h.Log, Db: h.DB}
 	userRepo.UserEntity = model.User{ID: int64(id)}
-	if err := userRepo.Delete(ctx); err != nil {
+	err = userRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var before dto.UserResponse
+	before.FromEntity(userRepo.UserEntity)
+	audit.Before(ctx, before)
+
+	err = userRepo.Delete(ctx)
+	if err == repository.ErrLastSuperuser {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 	h.Cache.Del(ctx, fmt.Sprintf("users.%d", id))
+	authUC := usecase.AuthUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	if err := authUC.InvalidatePermissions(ctx, int64(id)); err != nil {
+		h.Log.Error(err)
+	}
 }
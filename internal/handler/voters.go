@@ -0,0 +1,636 @@
+package handler
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/httpresponse"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/julienschmidt/httprouter"
+)
+
+// VoterPIIAccess is the access path required to see voter personal data
+const VoterPIIAccess = "PII /voters"
+
+// Voters handler. Voter responses are never cached because they may hold personal data.
+type Voters struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// @Security Bearer
+// @Summary List Voters
+// @Description List Voters. Personal data is masked unless the caller holds the PII /voters access.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param search query string false "Search by name"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.VoterResponse
+// @Router /voters [get]
+func (h *Voters) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voters, err := voterRepo.List(ctx, dto.NormalizeName(r.URL.Query().Get("search")))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var votersResponse dto.VoterResponse
+	response := votersResponse.ListFromEntity(voters, withPII)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Get Voter By ID
+// @Description Get Voter By ID. Personal data is masked unless the caller holds the PII /voters access.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.VoterResponse
+// @Router /voters/{id} [get]
+func (h *Voters) GetById(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = model.Voter{ID: id}
+	err = voterRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.VoterResponse
+	response.FromEntity(voterRepo.VoterEntity, withPII)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Create Voter
+// @Description Register a Voter. Possible duplicates (same NIK, or same normalized name and birth date) are reported with status 409.
+// @Description Set allow_duplicate to register a voter that only shares name and birth date with another voter.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param voter body dto.VoterCreateRequest true "Voter to add"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 201 {object} dto.VoterResponse
+// @Failure 409 {object} dto.VoterDuplicateResponse
+// @Router /voters [post]
+func (h *Voters) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRequest dto.VoterCreateRequest
+	defer r.Body.Close()
+	err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&voterRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := voterRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = voterRequest.ToEntity()
+	duplicates, err := voterRepo.PossibleDuplicates(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var voterResponse dto.VoterResponse
+	for _, duplicate := range duplicates {
+		if duplicate.NIK == voterRepo.VoterEntity.NIK {
+			httpres.SetMarshal(ctx, w, http.StatusConflict, dto.VoterDuplicateResponse{
+				Message:            repository.ErrNIKTaken.Error(),
+				PossibleDuplicates: voterResponse.ListFromEntity(duplicates, withPII),
+			}, "")
+			return
+		}
+	}
+
+	if len(duplicates) > 0 && !voterRequest.AllowDuplicate {
+		httpres.SetMarshal(ctx, w, http.StatusConflict, dto.VoterDuplicateResponse{
+			Message:            "possible duplicate voters found, resend with allow_duplicate to register anyway",
+			PossibleDuplicates: voterResponse.ListFromEntity(duplicates, withPII),
+		}, "")
+		return
+	}
+
+	err = voterRepo.Save(ctx)
+	if err == repository.ErrNIKTaken || err == repository.ErrUserTaken {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	audit.After(ctx, h.snapshot(voterRepo.VoterEntity))
+	voterResponse.FromEntity(voterRepo.VoterEntity, withPII)
+	httpres.SetMarshal(ctx, w, http.StatusCreated, voterResponse, "")
+}
+
+// @Security Bearer
+// @Summary Update Voter
+// @Description Update Voter
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param voter body dto.VoterUpdateRequest true "Voter to update"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.VoterResponse
+// @Failure 404 {string} string
+// @Router /voters/{id} [put]
+func (h *Voters) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRequest dto.VoterUpdateRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&voterRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := voterRequest.Validate(id); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = model.Voter{ID: id}
+	err = voterRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	audit.Before(ctx, h.snapshot(voterRepo.VoterEntity))
+
+	voterRepo.VoterEntity = voterRequest.ToEntity()
+	err = voterRepo.Update(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voter not found", http.StatusNotFound)
+		return
+	} else if err == repository.ErrNIKTaken || err == repository.ErrUserTaken {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var response dto.VoterResponse
+	response.FromEntity(voterRepo.VoterEntity, withPII)
+	audit.After(ctx, h.snapshot(voterRepo.VoterEntity))
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Delete Voter By ID
+// @Description Delete Voter By ID
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 404 {string} string
+// @Router /voters/{id} [delete]
+func (h *Voters) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = model.Voter{ID: id}
+	err = voterRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	audit.Before(ctx, h.snapshot(voterRepo.VoterEntity))
+
+	if err := voterRepo.Delete(ctx); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Security Bearer
+// @Summary List Possible Duplicates of a Voter
+// @Description List voters sharing the NIK, or sharing both the normalized name and the birth date with the voter
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Voter ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.VoterResponse
+// @Router /voters/{id}/duplicates [get]
+func (h *Voters) Duplicates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = model.Voter{ID: id}
+	err = voterRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Voter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	duplicates, err := voterRepo.PossibleDuplicates(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var votersResponse dto.VoterResponse
+	response := votersResponse.ListFromEntity(duplicates, withPII)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary List Eligible Voters of an Election
+// @Description List Eligible Voters of an Election. Personal data is masked unless the caller holds the PII /voters access.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {array} dto.VoterResponse
+// @Router /elections/{id}/voters [get]
+func (h *Voters) ListEligible(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	withPII, err := h.hasPIIAccess(ctx)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voters, err := voterRepo.ListEligible(ctx, electionID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var votersResponse dto.VoterResponse
+	response := votersResponse.ListFromEntity(voters, withPII)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Register Eligible Voters of an Election
+// @Description Make voters eligible in an election. Only allowed before the election is open.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param voters body dto.ElectionVoterRequest true "Voters to register"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dto.ElectionVoterResponse
+// @Failure 409 {string} string
+// @Router /elections/{id}/voters [post]
+func (h *Voters) RegisterEligible(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	var httpres = httpresponse.Response{Cache: h.Cache}
+	var eligibleRequest dto.ElectionVoterRequest
+	defer r.Body.Close()
+	err = sonic.ConfigDefault.NewDecoder(r.Body).Decode(&eligibleRequest)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if err := eligibleRequest.Validate(); err != nil {
+		h.Log.Error(err)
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.isRegistrationOpen(ctx, w, electionID) {
+		return
+	}
+
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	registered, err := voterRepo.RegisterEligible(ctx, electionID, eligibleRequest.VoterIDs)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.ElectionVoterResponse{ElectionID: electionID, Registered: registered}
+	audit.After(ctx, response)
+	httpres.SetMarshal(ctx, w, http.StatusOK, response, "")
+}
+
+// @Security Bearer
+// @Summary Unregister Eligible Voter of an Election
+// @Description Remove a voter from an election. Only allowed before the election is open.
+// @Tags Voters
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Election ID"
+// @Param voter_id path int true "Voter ID"
+// @Param Idempotency-Key header string true "Idempotency-Key"
+// @Param Authorization header string true "Bearer token"
+// @Success 204
+// @Failure 409 {string} string
+// @Router /elections/{id}/voters/{voter_id} [delete]
+func (h *Voters) UnregisterEligible(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = r.Context()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		h.Log.Error(context.Canceled)
+		http.Error(w, "Request is canceled", http.StatusExpectationFailed)
+		return
+	case context.DeadlineExceeded:
+		h.Log.Error(context.DeadlineExceeded)
+		http.Error(w, "Deadline is exceeded", http.StatusExpectationFailed)
+		return
+	default:
+	}
+
+	electionID, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid id", http.StatusBadRequest)
+		return
+	}
+
+	voterID, err := strconv.ParseInt(ps.ByName("voter_id"), 10, 64)
+	if err != nil {
+		h.Log.Error(err)
+		http.Error(w, "please supply a valid voter_id", http.StatusBadRequest)
+		return
+	}
+
+	if !h.isRegistrationOpen(ctx, w, electionID) {
+		return
+	}
+
+	audit.Before(ctx, dto.ElectionVoterRequest{VoterIDs: []int64{voterID}})
+	var voterRepo = repository.VoterRepository{Log: h.Log, Db: h.DB}
+	voterRepo.VoterEntity = model.Voter{ID: voterID}
+	if err := voterRepo.UnregisterEligible(ctx, electionID); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isRegistrationOpen writes the error response and returns false when the election does not exist or is already open
+func (h *Voters) isRegistrationOpen(ctx context.Context, w http.ResponseWriter, electionID int64) bool {
+	var electionRepo = repository.ElectionRepository{Log: h.Log, Db: h.DB}
+	electionRepo.ElectionEntity = model.Election{ID: electionID}
+	err := electionRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Election not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	status := electionRepo.ElectionEntity.Status
+	if status != model.ElectionStatusDraft && status != model.ElectionStatusScheduled {
+		http.Error(w, "Voter registration is closed for election in "+status+" state", http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// snapshot is the voter as recorded in the audit log, with the personal data masked whatever the access of the caller
+func (h *Voters) snapshot(voter model.Voter) dto.VoterResponse {
+	var response dto.VoterResponse
+	response.FromEntity(voter, false)
+	return response
+}
+
+func (h *Voters) hasPIIAccess(ctx context.Context) (bool, error) {
+	userID, ok := ctx.Value(myctx.Key("user_id")).(int64)
+	if !ok {
+		return false, nil
+	}
+	authUC := usecase.AuthUC{Log: h.Log, DB: h.DB, Cache: h.Cache}
+	return authUC.HasAuth(ctx, userID, VoterPIIAccess)
+}
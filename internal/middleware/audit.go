@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Audit writes a record of every mutating request to the audit log once the handler is done.
+// It runs after Authorization, which puts the route path in the context.
+func (m *Middleware) Audit(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r, ps)
+			return
+		}
+
+		ctx, snapshot := audit.WithSnapshot(r.Context())
+		rw := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rw, r.WithContext(ctx), ps)
+
+		record := audit.Record{
+			Method:       r.Method,
+			URL:          r.URL.Path,
+			StatusCode:   rw.statusCode,
+			ClientIP:     r.RemoteAddr,
+			ForwardedFor: r.Header.Get("X-Forwarded-For"),
+			Before:       snapshot.Before,
+			After:        snapshot.After,
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			record.ClientIP = host
+		}
+		if actorID, ok := ctx.Value(myctx.Key("user_id")).(int64); ok {
+			record.ActorID = actorID
+		}
+		if path, ok := ctx.Value(myctx.Key("path")).(string); ok {
+			record.Path = path
+		}
+
+		// the change is done, so the record is written even when the client went away meanwhile
+		auditRepo := repository.AuditRepository{Db: m.DB, Log: m.Log}
+		if _, err := auditRepo.Append(context.WithoutCancel(ctx), record); err != nil {
+			m.spool(record, err)
+		}
+	})
+}
+
+// spool keeps a record the audit log refused until AuditUC appends it. The change it records is done already,
+// so a record that can not be spooled either is logged whole rather than dropped.
+func (m *Middleware) spool(record audit.Record, err error) {
+	m.Log.Error(fmt.Errorf("audit log refused the record of %s %s, it is spooled: %w", record.Method, record.URL, err))
+	if m.Spool != nil {
+		err := m.Spool.Push(record)
+		if err == nil {
+			return
+		}
+		m.Log.Error(err)
+	}
+
+	payload, _ := json.Marshal(record)
+	m.Log.Error(fmt.Errorf("audit record is lost: %s", payload))
+}
+
+// statusRecorder keeps the status code written by the handler
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rw *statusRecorder) WriteHeader(statusCode int) {
+	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, to flush and to change deadlines
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
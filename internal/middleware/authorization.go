@@ -3,33 +3,43 @@ package middleware
 
 import (
 	"backend-election/internal/pkg/myctx"
-	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
 	"context"
-	"database/sql"
 	"net/http"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// Authorization lets the request through when one of the roles of the authenticated user grants the route.
+// A role scoped to an election or a region only grants the route when its params point into the scope.
+// It runs after Authentication, which puts the user id in the context.
 func (m *Middleware) Authorization(next httprouter.Handle) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		userID, ok := r.Context().Value(myctx.Key("user_id")).(int64)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		path := r.URL.Path
+		params := make(map[string]string, len(ps))
 		for _, param := range ps {
 			path = strings.Replace(path, "/"+ps.ByName(param.Key), "/:"+param.Key, 1)
+			params[param.Key] = param.Value
 		}
 		ctx := context.WithValue(r.Context(), myctx.Key("path"), path)
 		r = r.WithContext(ctx)
 
-		authRepository := repository.AuthRepository{Db: m.DB, Log: m.Log}
-		hasAuth, err := authRepository.HasAuth(r.Context(), r.Method+" "+path)
-		if err != nil && err != sql.ErrNoRows {
+		authUC := usecase.AuthUC{Log: m.Log, DB: m.DB, Cache: m.Cache}
+		hasAuth, err := authUC.Authorize(r.Context(), userID, r.Method+" "+path, params)
+		if err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
 		if !hasAuth {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
 
@@ -33,6 +33,11 @@ func (m *Middleware) Idempotency(next httprouter.Handle) httprouter.Handle {
 		rw := &responseRecorder{ResponseWriter: w, body: new(bytes.Buffer)}
 		next(rw, r, ps)
 
+		// secrets like a key share are marked no-store, replaying the key must not hand them to someone else
+		if rw.Header().Get("Cache-Control") == "no-store" {
+			return
+		}
+
 		m.Cache.SetTTL(10 * time.Minute)
 		m.Cache.Add(ctx, idempotencyKey, rw.body.String())
 		m.Cache.ResetTTL()
@@ -55,3 +60,8 @@ func (rw *responseRecorder) Write(b []byte) (int, error) {
 	rw.body.Write(b)
 	return rw.ResponseWriter.Write(b)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, to flush and to change deadlines
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"backend-election/internal/pkg/audit"
 	"backend-election/internal/pkg/logger"
 	"backend-election/internal/pkg/redis"
 	"database/sql"
@@ -12,6 +13,8 @@ type Middleware struct {
 	Log   *logger.Logger
 	DB    *sql.DB
 	Cache *redis.Cache
+	// Spool keeps the audit records the audit log refused, for AuditUC to append later
+	Spool *audit.Spool
 }
 
 func (m *Middleware) WrapMiddleware(mw []func(httprouter.Handle) httprouter.Handle, handler httprouter.Handle) httprouter.Handle {
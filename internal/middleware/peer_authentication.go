@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/pkg/peer"
+	"backend-election/internal/repository"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxPeerBody is the largest body a peer may send, the body is read at once to check its signature
+const maxPeerBody = 10 << 20
+
+// PeerAuthentication accepts requests signed by an active peer, as an alternative to the JWT of Authentication for
+// calls between nodes. A signature is accepted once, a replayed request is refused while its timestamp is still valid.
+func (m *Middleware) PeerAuthentication(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		peerID, err := peer.ID(r)
+		if err != nil {
+			http.Error(w, "Peer signature missing", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPeerBody))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			m.Log.Error(err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		peerRepo := repository.PeerRepository{Log: m.Log, Db: m.DB, PeerEntity: model.Peer{ID: peerID}}
+		if err := peerRepo.Find(r.Context()); err == sql.ErrNoRows {
+			http.Error(w, "Unknown peer", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := peer.Verify(r, body, ed25519.PublicKey(peerRepo.PeerEntity.PublicKey), time.Now()); err != nil {
+			m.Log.Error(err)
+			http.Error(w, "Invalid peer signature", http.StatusUnauthorized)
+			return
+		}
+
+		// checked after the signature, so the status of a peer is only disclosed to the peer itself
+		if peerRepo.PeerEntity.Status != model.PeerStatusActive {
+			http.Error(w, "Peer is not active", http.StatusForbidden)
+			return
+		}
+
+		signature, _ := base64.StdEncoding.DecodeString(r.Header.Get(peer.HeaderSignature))
+		isNew, err := m.Cache.SetNX(r.Context(), "peer-signatures."+base64.RawURLEncoding.EncodeToString(signature), 1, 2*peer.MaxSkew)
+		if err != nil {
+			m.Log.Error(err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !isNew {
+			http.Error(w, "Replayed request", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), myctx.Key("peer_id"), peerID)
+		r = r.WithContext(ctx)
+
+		next(w, r, ps)
+	})
+}
@@ -0,0 +1,15 @@
+package model
+
+// Ballot is the anonymous content of a cast vote. It holds no reference to the voter.
+type Ballot struct {
+	ID         int64
+	ElectionID int64
+	Choices    []int64
+	// Ciphertexts is the ballot of an encrypted election, the A and B of the ciphertext of every candidate in
+	// ballot order. Choices is then empty.
+	Ciphertexts [][]byte
+	// ReceiptHash is the SHA-256 of the receipt code of the voter
+	ReceiptHash []byte
+	// LedgerHeight is the entry of the ballot in the ledger, 0 while it waits for its batch
+	LedgerHeight int64
+}
@@ -0,0 +1,16 @@
+package model
+
+type Candidate struct {
+	ID              int64
+	ElectionID      int64
+	BallotNumber    int
+	Name            string
+	RunningMateName string
+	Party           string
+	CreatedAt       string
+	CreatedBy       int64
+	UpdatedAt       string
+	UpdatedBy       int64
+	DeletedAt       string
+	DeletedBy       int64
+}
@@ -0,0 +1,18 @@
+package model
+
+// Certificate is the signed results document of a certified election. Document is the canonical JSON
+// the certifiers signed and SHA256 its hex encoded checksum.
+type Certificate struct {
+	ElectionID  int64
+	Document    []byte
+	SHA256      string
+	Signatures  []CertificateSignature
+	CertifiedAt string
+	CertifiedBy int64
+}
+
+// CertificateSignature is the detached Ed25519 signature of a certifier over the certificate document
+type CertificateSignature struct {
+	PublicKey []byte
+	Signature []byte
+}
@@ -0,0 +1,16 @@
+package model
+
+// CredentialKey is the RSA key that blindly signs the voting credentials of an election, PKCS #1 DER encoded
+type CredentialKey struct {
+	ElectionID int64
+	PrivateKey []byte
+	CreatedAt  string
+}
+
+// Credential is the blinded message a voter had signed for an election. The signed token itself is never seen
+// by the server before the ballot is cast with it.
+type Credential struct {
+	ElectionID int64
+	VoterID    int64
+	Blinded    []byte
+}
@@ -0,0 +1,47 @@
+package model
+
+const (
+	DisputeStatusFiled          = "filed"
+	DisputeStatusUnderReview    = "under_review"
+	DisputeStatusRecountOrdered = "recount_ordered"
+	DisputeStatusResolved       = "resolved"
+)
+
+// Dispute is an objection filed by a party against the tally form of a polling station or the recapitulation of
+// a region. Exactly one of PollingStationID and RegionID is set.
+type Dispute struct {
+	ID               int64
+	ElectionID       int64
+	PollingStationID int64
+	RegionID         int64
+	Filer            string
+	Reason           string
+	Status           string
+	Resolution       string
+	FiledAt          string
+	FiledBy          int64
+	ResolvedAt       string
+	ResolvedBy       int64
+}
+
+type DisputeTransition struct {
+	ID         int64
+	DisputeID  int64
+	FromStatus string
+	ToStatus   string
+	Note       string
+	UserID     int64
+	CreatedAt  string
+}
+
+// DisputeEvidence is a file attached to a dispute, stored under the SHA-256 checksum of its content
+type DisputeEvidence struct {
+	ID          int64
+	DisputeID   int64
+	SHA256      string
+	ContentType string
+	Size        int64
+	Filename    string
+	CreatedAt   string
+	CreatedBy   int64
+}
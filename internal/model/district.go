@@ -0,0 +1,20 @@
+package model
+
+type District struct {
+	ID         int64
+	ElectionID int64
+	Name       string
+	Seats      int
+	CreatedAt  string
+	CreatedBy  int64
+	UpdatedAt  string
+	UpdatedBy  int64
+	DeletedAt  string
+	DeletedBy  int64
+}
+
+type PartyVotes struct {
+	DistrictID int64
+	Party      string
+	Votes      int64
+}
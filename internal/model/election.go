@@ -0,0 +1,52 @@
+package model
+
+const (
+	ElectionStatusDraft     = "draft"
+	ElectionStatusScheduled = "scheduled"
+	ElectionStatusOpen      = "open"
+	ElectionStatusClosed    = "closed"
+	ElectionStatusTallied   = "tallied"
+	ElectionStatusCertified = "certified"
+)
+
+// Time zones of Indonesia the voting window of an election is set in
+const (
+	TimezoneWIB  = "WIB"
+	TimezoneWITA = "WITA"
+	TimezoneWIT  = "WIT"
+)
+
+type Election struct {
+	ID             int64
+	Name           string
+	Description    string
+	Status         string
+	CountingMethod string
+	Seats          int
+	SeatMethod     string
+	Threshold      float64
+	// PublicKey is the ElGamal key of an encrypted election, empty when the ballots are stored in plaintext
+	PublicKey []byte
+	// TrusteeThreshold is how many trustees decrypt the tally of an encrypted election together
+	TrusteeThreshold int
+	// OpensAt and ClosesAt are the voting window in RFC 3339 UTC, empty when the election is opened or closed by hand
+	OpensAt   string
+	ClosesAt  string
+	Timezone  string
+	CreatedAt string
+	CreatedBy int64
+	UpdatedAt string
+	UpdatedBy int64
+	DeletedAt string
+	DeletedBy int64
+}
+
+type ElectionTransition struct {
+	ID         int64
+	ElectionID int64
+	FromStatus string
+	ToStatus   string
+	// UserID is 0 for a transition made by the scheduler
+	UserID    int64
+	CreatedAt string
+}
@@ -0,0 +1,22 @@
+package model
+
+// VoterFingerprint is the enrolled fingerprint minutiae template of a voter.
+// Template is sealed, it is only opened to be matched and never leaves the service.
+type VoterFingerprint struct {
+	VoterID        int64
+	Format         string
+	FingerPosition int
+	MinutiaeCount  int
+	Quality        int
+	Template       []byte
+	CreatedAt      string
+	CreatedBy      int64
+	UpdatedAt      string
+	UpdatedBy      int64
+}
+
+// FingerprintMatch is the score of a fingerprint compared with the enrolled fingerprint of a voter
+type FingerprintMatch struct {
+	VoterID int64
+	Score   int
+}
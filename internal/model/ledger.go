@@ -0,0 +1,34 @@
+package model
+
+// LedgerOriginLocal is the origin of the ledger of this node, the replica of the ledger of a peer has the id of the peer
+const LedgerOriginLocal int64 = 0
+
+// LedgerDivergence is a ledger found to differ from its replica or from the replica of another peer.
+// OriginPeerID is the ledger that diverged and ReportedBy the peer the differing history was read from.
+type LedgerDivergence struct {
+	ID           int64
+	OriginPeerID int64
+	ReportedBy   int64
+	Height       int64
+	LocalHash    []byte
+	RemoteHash   []byte
+	Reason       string
+	CreatedAt    string
+}
+
+// LedgerReplica is the head of the replica this node keeps of the ledger of a peer
+type LedgerReplica struct {
+	PeerID    int64
+	PublicKey []byte
+	Height    int64
+	Hash      []byte
+	Root      []byte
+}
+
+// LedgerSync is the outcome of replicating the ledger of a peer, Error is empty when it succeeded
+type LedgerSync struct {
+	PeerID int64
+	Height int64
+	Hash   []byte
+	Error  string
+}
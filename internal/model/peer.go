@@ -0,0 +1,23 @@
+package model
+
+const (
+	PeerStatusPending   = "pending"
+	PeerStatusActive    = "active"
+	PeerStatusSuspended = "suspended"
+	PeerStatusRevoked   = "revoked"
+)
+
+// Peer is another node of the election network, identified by its Ed25519 public key
+type Peer struct {
+	ID        int64
+	Name      string
+	Endpoint  string
+	PublicKey []byte
+	// RemoteID is the id this node is registered under at the peer, 0 when it is not known yet
+	RemoteID  int64
+	Status    string
+	CreatedAt string
+	CreatedBy int64
+	UpdatedAt string
+	UpdatedBy int64
+}
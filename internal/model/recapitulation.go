@@ -0,0 +1,102 @@
+package model
+
+const (
+	ReviewStatusSubmitted = "submitted"
+	ReviewStatusApproved  = "approved"
+	ReviewStatusRejected  = "rejected"
+	ReviewStatusReopened  = "reopened"
+)
+
+type TallyLine struct {
+	CandidateID int64
+	Votes       int64
+}
+
+type TallyForm struct {
+	ID               int64
+	ElectionID       int64
+	PollingStationID int64
+	Status           string
+	Lines            []TallyLine
+	InvalidVotes     int64
+	Note             string
+	SubmittedAt      string
+	SubmittedBy      int64
+	ReviewedAt       string
+	ReviewedBy       int64
+}
+
+type Recapitulation struct {
+	ID           int64
+	ElectionID   int64
+	RegionID     int64
+	Status       string
+	Revision     int
+	Lines        []TallyLine
+	InvalidVotes int64
+	Note         string
+	SubmittedAt  string
+	SubmittedBy  int64
+	ReviewedAt   string
+	ReviewedBy   int64
+}
+
+// Discrepancy records a submitted total that differs from the sum of the children. CandidateID is 0 for the invalid votes.
+type Discrepancy struct {
+	ID               int64
+	RecapitulationID int64
+	Revision         int
+	CandidateID      int64
+	SubmittedVotes   int64
+	ComputedVotes    int64
+	CreatedAt        string
+}
+
+// RecapitulationSum is the computed sum of the approved children of a region
+type RecapitulationSum struct {
+	Lines            []TallyLine
+	InvalidVotes     int64
+	Children         int
+	ApprovedChildren int
+}
+
+// RecapitulationReport is the state of the recapitulation of a region. Recapitulation.ID is 0 while nothing is submitted.
+type RecapitulationReport struct {
+	Region         Region
+	Recapitulation Recapitulation
+	Sum            RecapitulationSum
+	Discrepancies  []Discrepancy
+}
+
+// TallyFormScan is a photo or scan of the signed tally sheet, stored under the SHA-256 checksum of its content
+type TallyFormScan struct {
+	ID          int64
+	TallyFormID int64
+	SHA256      string
+	ContentType string
+	Size        int64
+	Filename    string
+	CreatedAt   string
+	CreatedBy   int64
+}
+
+// TallySnapshot is the sum of the approved tally forms of an election at a tally version
+type TallySnapshot struct {
+	ElectionID    int64
+	Version       int64
+	ApprovedForms int
+	Lines         []TallyLine
+	InvalidVotes  int64
+}
+
+// TallyUpdate is the approval of one tally form, the version is the tally version of the election after the approval.
+// A reopened update is a recount taking the form back out of the sum, its votes are negated.
+type TallyUpdate struct {
+	ElectionID       int64
+	Version          int64
+	PollingStationID int64
+	VillageID        int64
+	Lines            []TallyLine
+	InvalidVotes     int64
+	Reopened         bool
+}
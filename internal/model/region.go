@@ -0,0 +1,45 @@
+package model
+
+const (
+	RegionLevelNational    = "national"
+	RegionLevelProvince    = "province"
+	RegionLevelRegency     = "regency"
+	RegionLevelSubdistrict = "subdistrict"
+	RegionLevelVillage     = "village"
+)
+
+// RegionLevels lists the administrative levels from the top down. Polling stations sit below the village level.
+var RegionLevels = []string{
+	RegionLevelNational,
+	RegionLevelProvince,
+	RegionLevelRegency,
+	RegionLevelSubdistrict,
+	RegionLevelVillage,
+}
+
+type Region struct {
+	ID        int64
+	ParentID  int64
+	Level     string
+	Code      string
+	Name      string
+	CreatedAt string
+	CreatedBy int64
+	UpdatedAt string
+	UpdatedBy int64
+	DeletedAt string
+	DeletedBy int64
+}
+
+type PollingStation struct {
+	ID        int64
+	VillageID int64
+	Number    string
+	Address   string
+	CreatedAt string
+	CreatedBy int64
+	UpdatedAt string
+	UpdatedBy int64
+	DeletedAt string
+	DeletedBy int64
+}
@@ -0,0 +1,65 @@
+package model
+
+const (
+	RLATypePolling    = "polling"
+	RLATypeComparison = "comparison"
+
+	RLAStatusInProgress = "in_progress"
+	RLAStatusPassed     = "passed"
+	RLAStatusEscalated  = "escalated"
+)
+
+// RLA is the risk-limiting audit of the approved tally forms of an election. Seed is the public seed of the seed
+// ceremony, every draw is repeated from it. MaxDraws is the number of draws after which the audit escalates to a
+// full hand count.
+type RLA struct {
+	ElectionID int64
+	Type       string
+	RiskLimit  float64
+	MaxDraws   int
+	Seed       string
+	CreatedAt  string
+	CreatedBy  int64
+}
+
+// RLADraw is a draw of the audit. BallotPosition is the position of the drawn ballot in the TPS batch from 1,
+// it is 0 when the whole batch is drawn.
+type RLADraw struct {
+	ElectionID       int64
+	Index            int
+	PollingStationID int64
+	BallotPosition   int
+	CreatedAt        string
+}
+
+// RLAInterpretation is what the auditors read from a drawn ballot, or counted by hand in a drawn batch
+type RLAInterpretation struct {
+	ElectionID       int64
+	PollingStationID int64
+	BallotPosition   int
+	Lines            []TallyLine
+	InvalidVotes     int64
+	CreatedAt        string
+	CreatedBy        int64
+}
+
+// RLAPair is the risk measure of a reported winner against a reported loser
+type RLAPair struct {
+	Winner  int64
+	Loser   int64
+	Measure float64
+}
+
+// RLAReport is the state of the audit. The measure is computed from the longest run of draws from the first one
+// that are all interpreted, Interpreted is the length of that run.
+type RLAReport struct {
+	RLA         RLA
+	Status      string
+	Ballots     int64
+	Batches     int
+	Winners     []int64
+	Draws       int
+	Interpreted int
+	RiskMeasure float64
+	Pairs       []RLAPair
+}
@@ -0,0 +1,48 @@
+package model
+
+type Role struct {
+	ID   int64
+	Name string
+	// IsSuperuser marks the roles that at least one active user must keep holding
+	IsSuperuser bool
+	// ElectionID and RegionID scope the access of the role to an election and to a region subtree, 0 is unscoped
+	ElectionID int64
+	RegionID   int64
+}
+
+type Access struct {
+	ID   int64
+	Name string
+	// Path is the method and the route pattern the access grants, like GET /users/:id
+	Path string
+}
+
+// AccessSync reports the changes made to the access table by a sync with the routes
+type AccessSync struct {
+	// Added lists the access of the new routes, granted to every superuser role
+	Added []Access
+	// Renamed lists the access whose name changed in the routes
+	Renamed []Access
+	// Orphaned lists the access no route declares any more, they are kept until deleted through the API
+	Orphaned []Access
+}
+
+// AccessExplanation tells why a user has or lacks an access path
+type AccessExplanation struct {
+	UserID int64
+	Path   string
+	// Allowed reports whether the user has the access path, within the election and the region asked when the user
+	// only holds scoped grants of the path
+	Allowed bool
+	Reason  string
+	Grants  []AccessGrant
+}
+
+// AccessGrant is one way a user is granted an access path
+type AccessGrant struct {
+	// Roles is the chain from the role the user holds to the role granted the access, through the parents it inherits
+	Roles []Role
+	// ElectionID and RegionID are the scope the grant is inherited with, the narrower of the scopes along the chain
+	ElectionID int64
+	RegionID   int64
+}
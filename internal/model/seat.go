@@ -0,0 +1,30 @@
+package model
+
+const (
+	SeatMethodSainteLague = "sainte_lague"
+	SeatMethodDHondt      = "dhondt"
+)
+
+type PartySeats struct {
+	Party    string
+	Votes    int64
+	Seats    int
+	Eligible bool
+}
+
+type DistrictSeats struct {
+	DistrictID  int64
+	Name        string
+	Seats       int
+	Unallocated int
+	Parties     []PartySeats
+	TieBreak    bool
+}
+
+type SeatAllocation struct {
+	Method    string
+	Threshold float64
+	Votes     int64
+	Parties   []PartySeats
+	Districts []DistrictSeats
+}
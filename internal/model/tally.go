@@ -0,0 +1,32 @@
+package model
+
+const (
+	CountingMethodPlurality = "plurality"
+	CountingMethodApproval  = "approval"
+	CountingMethodIRV       = "irv"
+	CountingMethodSTV       = "stv"
+)
+
+type CandidateVotes struct {
+	CandidateID int64
+	Votes       float64
+}
+
+type TallyRound struct {
+	Round      int
+	Votes      []CandidateVotes
+	Exhausted  float64
+	Elected    []int64
+	Eliminated []int64
+	TieBreak   bool
+}
+
+type TallyResult struct {
+	Method  string
+	Seats   int
+	Ballots int
+	Invalid int
+	Quota   float64
+	Rounds  []TallyRound
+	Elected []int64
+}
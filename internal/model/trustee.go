@@ -0,0 +1,26 @@
+package model
+
+// Trustee holds a share of the key of an encrypted election. Index is the point of the share, starting at 1.
+type Trustee struct {
+	ElectionID      int64
+	Index           int
+	UserID          int64
+	VerificationKey []byte
+	// Share is the key share waiting for the trustee to fetch it, it is only read when the trustee fetches it
+	Share []byte
+	// Decrypted reports whether the trustee submitted its decryption of the tally
+	Decrypted bool
+	CreatedAt string
+	CreatedBy int64
+}
+
+// TallyDecryption is the decryption share of a trustee for the encrypted tally of every candidate in ballot order,
+// with the challenge and response of the Chaum-Pedersen proof of each
+type TallyDecryption struct {
+	ElectionID   int64
+	TrusteeIndex int
+	Factors      [][]byte
+	Challenges   [][]byte
+	Responses    [][]byte
+	CreatedAt    string
+}
@@ -0,0 +1,19 @@
+package model
+
+type Voter struct {
+	ID             int64
+	NIK            string
+	Name           string
+	NormalizedName string
+	BirthPlace     string
+	BirthDate      string
+	Gender         string
+	Address        string
+	UserID         int64
+	CreatedAt      string
+	CreatedBy      int64
+	UpdatedAt      string
+	UpdatedBy      int64
+	DeletedAt      string
+	DeletedBy      int64
+}
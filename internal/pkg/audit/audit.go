@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"backend-election/internal/pkg/myctx"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// HashSize is the size of the hash chaining the records
+const HashSize = sha256.Size
+
+var (
+	// ErrBrokenLink is returned when a record does not point to the hash of the record before it
+	ErrBrokenLink = errors.New("record does not link to the previous record")
+	// ErrInvalidHash is returned when the hash of a record does not match its content
+	ErrInvalidHash = errors.New("record hash does not match its content")
+	// ErrGap is returned when a record is missing from the sequence
+	ErrGap = errors.New("record is missing from the sequence")
+)
+
+// Record is what happened in one mutating request. Path is the route with its parameters (/users/:id)
+// and URL the path that was requested. Before and After are snapshots of the changed resource, when the handler
+// provides them. ClientIP is the address of the connection, ForwardedFor is what the client claims in X-Forwarded-For.
+type Record struct {
+	Seq          int64           `json:"seq"`
+	ActorID      int64           `json:"actor_id"`
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	URL          string          `json:"url"`
+	StatusCode   int             `json:"status_code"`
+	ClientIP     string          `json:"client_ip"`
+	ForwardedFor string          `json:"forwarded_for,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+// Entry is a stored record. Payload is the JSON of the record exactly as it was hashed,
+// Hash = SHA-256(PrevHash || Payload) chains every record to all records before it.
+type Entry struct {
+	Seq      int64
+	Payload  []byte
+	PrevHash []byte
+	Hash     []byte
+}
+
+// Genesis is the previous hash of the first record
+func Genesis() []byte {
+	return make([]byte, HashSize)
+}
+
+// Chain returns the entry of the record following the record with hash prev
+func Chain(prev []byte, record Record) (Entry, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Seq: record.Seq, Payload: payload, PrevHash: prev, Hash: hash(prev, payload)}, nil
+}
+
+func hash(prev []byte, payload []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// Verifier walks the chain from the first record. Check the entries in sequence order.
+type Verifier struct {
+	seq  int64
+	hash []byte
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{hash: Genesis()}
+}
+
+// Checked returns the number of records checked so far
+func (v *Verifier) Checked() int64 {
+	return v.seq
+}
+
+// Check verifies that the entry is the next record of the chain, the error names the first broken link
+func (v *Verifier) Check(entry Entry) error {
+	if entry.Seq != v.seq+1 {
+		return fmt.Errorf("record %d: %w, expected record %d", entry.Seq, ErrGap, v.seq+1)
+	}
+	if !bytes.Equal(entry.PrevHash, v.hash) {
+		return fmt.Errorf("record %d: %w", entry.Seq, ErrBrokenLink)
+	}
+	if !bytes.Equal(entry.Hash, hash(entry.PrevHash, entry.Payload)) {
+		return fmt.Errorf("record %d: %w", entry.Seq, ErrInvalidHash)
+	}
+
+	var record Record
+	if err := json.Unmarshal(entry.Payload, &record); err != nil || record.Seq != entry.Seq {
+		return fmt.Errorf("record %d: %w", entry.Seq, ErrInvalidHash)
+	}
+
+	v.seq = entry.Seq
+	v.hash = entry.Hash
+	return nil
+}
+
+// Snapshot holds the state of the resource changed by the request
+type Snapshot struct {
+	Before json.RawMessage
+	After  json.RawMessage
+}
+
+// WithSnapshot returns a context the handler records its snapshots in
+func WithSnapshot(ctx context.Context) (context.Context, *Snapshot) {
+	snapshot := &Snapshot{}
+	return context.WithValue(ctx, myctx.Key("audit"), snapshot), snapshot
+}
+
+// Before records the state of the resource before the change. Pass the response DTO of the resource,
+// so secrets like password hashes never reach the audit log.
+func Before(ctx context.Context, v interface{}) {
+	if snapshot, ok := ctx.Value(myctx.Key("audit")).(*Snapshot); ok {
+		snapshot.Before, _ = json.Marshal(v)
+	}
+}
+
+// After records the state of the resource after the change
+func After(ctx context.Context, v interface{}) {
+	if snapshot, ok := ctx.Value(myctx.Key("audit")).(*Snapshot); ok {
+		snapshot.After, _ = json.Marshal(v)
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func chain(t *testing.T, n int) []Entry {
+	entries := make([]Entry, 0, n)
+	prev := Genesis()
+	for i := 1; i <= n; i++ {
+		entry, err := Chain(prev, Record{Seq: int64(i), ActorID: 425071490427828, Method: "PUT", Path: "/users/:id", StatusCode: 200})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+		prev = entry.Hash
+	}
+	return entries
+}
+
+func verify(entries []Entry) error {
+	verifier := NewVerifier()
+	for _, entry := range entries {
+		if err := verifier.Check(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestChain(t *testing.T) {
+	if err := verify(chain(t, 5)); err != nil {
+		t.Fatalf("valid chain is rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]Entry) []Entry
+		want   error
+	}{
+		{"Payload", func(e []Entry) []Entry {
+			e[2].Payload = []byte(`{"seq":3,"actor_id":1,"method":"PUT","path":"/users/:id","status_code":200}`)
+			return e
+		}, ErrInvalidHash},
+		{"Removed", func(e []Entry) []Entry { return append(e[:2], e[3:]...) }, ErrGap},
+		{"Relinked", func(e []Entry) []Entry {
+			e[3].PrevHash = e[1].Hash
+			return e
+		}, ErrBrokenLink},
+		{"Rehashed", func(e []Entry) []Entry {
+			// a record rewritten with a valid hash breaks the link of the record after it
+			rewritten, _ := Chain(e[1].Hash, Record{Seq: 3, ActorID: 1})
+			e[2] = rewritten
+			return e
+		}, ErrBrokenLink},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.tamper(chain(t, 5))); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	// without the middleware the snapshots go nowhere
+	Before(context.Background(), map[string]string{"name": "Asep"})
+
+	ctx, snapshot := WithSnapshot(context.Background())
+	Before(ctx, map[string]string{"name": "Asep"})
+	After(ctx, map[string]string{"name": "Asep Sunandar"})
+	if string(snapshot.Before) != `{"name":"Asep"}` || string(snapshot.After) != `{"name":"Asep Sunandar"}` {
+		t.Errorf("unexpected snapshot %s -> %s", snapshot.Before, snapshot.After)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	spool := &Spool{Path: filepath.Join(t.TempDir(), "audit", "spool.jsonl")}
+	for _, url := range []string{"/users/1", "/users/2", "/users/3"} {
+		if err := spool.Push(Record{Method: "PUT", Path: "/users/:id", URL: url, StatusCode: 200}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the audit log is down after the first record, the others stay spooled
+	var urls []string
+	down := errors.New("audit log is down")
+	added, err := spool.Drain(func(record Record) error {
+		if len(urls) == 1 {
+			return down
+		}
+		urls = append(urls, record.URL)
+		return nil
+	})
+	if added != 1 || err != down {
+		t.Fatalf("got %d added, err %v", added, err)
+	}
+
+	// a record torn by a crash is dropped, the records around it are added
+	f, err := os.OpenFile(spool.Path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"method":"PU` + "\n")
+	f.Close()
+	if err := spool.Push(Record{Method: "DELETE", Path: "/users/:id", URL: "/users/4", StatusCode: 204}); err != nil {
+		t.Fatal(err)
+	}
+	added, err = spool.Drain(func(record Record) error {
+		urls = append(urls, record.URL)
+		return nil
+	})
+	if added != 3 || err == nil {
+		t.Fatalf("got %d added, err %v", added, err)
+	}
+	if want := []string{"/users/1", "/users/2", "/users/3", "/users/4"}; !slices.Equal(urls, want) {
+		t.Fatalf("added %v, want %v", urls, want)
+	}
+
+	if _, err := os.Stat(spool.Path); !os.IsNotExist(err) {
+		t.Fatalf("drained spool is kept: %v", err)
+	}
+	if added, err := spool.Drain(func(Record) error { return down }); added != 0 || err != nil {
+		t.Fatalf("empty spool got %d added, err %v", added, err)
+	}
+}
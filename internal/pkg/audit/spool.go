@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Spool keeps the records that could not be appended to the audit log in a file, one JSON record per line,
+// until Drain appends them. The file is synced on every push, so a spooled record survives a restart.
+type Spool struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Push adds the record at the end of the spool
+func (s *Spool) Push(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Drain hands the spooled records to add in the order they were pushed, and returns how many it added.
+// It stops at the first record add fails on, that record and the ones after it stay in the spool. A line that
+// is not a record, torn by a crash while it was pushed, is dropped and its error returned once the rest is added.
+func (s *Spool) Drain(add func(Record) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var added, done int
+	var drainErr error
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			done++
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			drainErr = err
+			done++
+			continue
+		}
+		if err := add(record); err != nil {
+			drainErr = err
+			break
+		}
+		added++
+		done++
+	}
+
+	if done == len(lines) {
+		if err := os.Remove(s.Path); err != nil {
+			return added, err
+		}
+		return added, drainErr
+	}
+
+	// keep the records not added yet, replacing the file at once so a crash keeps either spool whole
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, bytes.Join(lines[done:], nil), 0o600); err != nil {
+		return added, err
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return added, err
+	}
+	return added, drainErr
+}
@@ -0,0 +1,91 @@
+package biometric
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+)
+
+// sealVersion prefixes every sealed template so the cipher can be changed later
+const sealVersion = 1
+
+var (
+	// ErrInvalidKey is returned when BIOMETRIC_KEY is not a hex encoded 32 byte key
+	ErrInvalidKey = errors.New("biometric key must be 32 bytes, hex encoded")
+	// ErrSealed is returned when a sealed template can not be opened
+	ErrSealed = errors.New("sealed template can not be opened")
+)
+
+// Engine seals enrolled templates with AES-256-GCM and matches them.
+// A sealed template is bound to its voter, it can not be opened as the template of another voter.
+type Engine struct {
+	Matcher Matcher
+	aead    cipher.AEAD
+}
+
+// New creates the engine configured with BIOMETRIC_KEY and BIOMETRIC_MATCH_THRESHOLD
+func New() (*Engine, error) {
+	threshold, err := strconv.Atoi(os.Getenv("BIOMETRIC_MATCH_THRESHOLD"))
+	if err != nil || threshold <= 0 || threshold > 100 {
+		threshold = DefaultThreshold
+	}
+
+	key, err := hex.DecodeString(os.Getenv("BIOMETRIC_KEY"))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return NewEngine(key, threshold)
+}
+
+// NewEngine creates the engine with a 32 byte key and the match threshold
+func NewEngine(key []byte, threshold int) (*Engine, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{Matcher: Matcher{Threshold: threshold}, aead: aead}, nil
+}
+
+// Seal encrypts the template of the voter. The result is the version, the nonce and the ciphertext.
+func (e *Engine) Seal(template []byte, voterID int64) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append([]byte{sealVersion}, nonce...)
+	return e.aead.Seal(sealed, nonce, template, voterData(voterID)), nil
+}
+
+// Open decrypts a template sealed for the voter
+func (e *Engine) Open(sealed []byte, voterID int64) ([]byte, error) {
+	size := e.aead.NonceSize()
+	if len(sealed) < 1+size || sealed[0] != sealVersion {
+		return nil, ErrSealed
+	}
+
+	template, err := e.aead.Open(nil, sealed[1:1+size], sealed[1+size:], voterData(voterID))
+	if err != nil {
+		return nil, ErrSealed
+	}
+	return template, nil
+}
+
+// voterData is the additional authenticated data binding a sealed template to the voter
+func voterData(voterID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(voterID))
+}
@@ -0,0 +1,147 @@
+package biometric
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// finger returns count random minutiae spread over a 15 x 20 mm fingertip
+func finger(rng *rand.Rand, count int) Template {
+	template := Template{FingerPosition: 2, Quality: 80}
+	for i := 0; i < count; i++ {
+		template.Minutiae = append(template.Minutiae, Minutia{
+			X:       2 + rng.Float64()*15,
+			Y:       -2 - rng.Float64()*20,
+			Angle:   rng.Float64() * 2 * math.Pi,
+			Type:    MinutiaType(1 + rng.Intn(2)),
+			Quality: 60,
+		})
+	}
+	return template
+}
+
+// impression is another reading of the same finger: rotated, moved, with positional noise,
+// some minutiae missed and some spurious ones found
+func impression(rng *rand.Rand, template Template, rotation float64) Template {
+	sin, cos := math.Sincos(rotation)
+	result := Template{FingerPosition: template.FingerPosition, Quality: template.Quality}
+	for i, m := range template.Minutiae {
+		if i%5 == 0 {
+			continue
+		}
+		x, y := m.X-10, m.Y+12
+		result.Minutiae = append(result.Minutiae, Minutia{
+			X:       10 + x*cos - y*sin + 1.5 + rng.NormFloat64()*0.1,
+			Y:       -12 + x*sin + y*cos - 0.8 + rng.NormFloat64()*0.1,
+			Angle:   m.Angle + rotation + rng.NormFloat64()*0.03,
+			Type:    m.Type,
+			Quality: m.Quality,
+		})
+	}
+	result.Minutiae = append(result.Minutiae, finger(rng, 5).Minutiae...)
+	return result
+}
+
+func TestParse(t *testing.T) {
+	template := finger(rand.New(rand.NewSource(1)), 20)
+
+	for _, format := range []Format{FormatISO19794, FormatANSI378} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := Encode(format, template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := Parse(format, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.FingerPosition != 2 || parsed.Quality != 80 || len(parsed.Minutiae) != 20 {
+				t.Fatalf("unexpected template %+v", parsed)
+			}
+
+			// one unit of the angle, 2 degrees for ansi378
+			angleUnit := 2 * math.Pi / 180
+			for i, m := range parsed.Minutiae {
+				want := template.Minutiae[i]
+				if math.Abs(m.X-want.X) > 0.05 || math.Abs(m.Y-want.Y) > 0.05 || math.Abs(angleDiff(m.Angle, want.Angle)) > angleUnit || m.Type != want.Type {
+					t.Fatalf("minutia %d = %+v, want %+v", i, m, want)
+				}
+			}
+
+			if _, err := Parse(format, data[:len(data)-10]); err == nil {
+				t.Error("a truncated record should be rejected")
+			}
+		})
+	}
+
+	iso, _ := Encode(FormatISO19794, template)
+	if _, err := Parse(FormatANSI378, iso); err == nil {
+		t.Error("an iso19794-2 record should not parse as ansi378")
+	}
+	if _, err := Parse("wsq", iso); err != ErrUnknownFormat {
+		t.Errorf("err = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestScore(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	matcher := Matcher{Threshold: DefaultThreshold}
+	enrolled := finger(rng, 40)
+
+	for _, rotation := range []float64{0, 0.3, -0.5} {
+		probe := impression(rng, enrolled, rotation)
+		if score := matcher.Score(probe, enrolled); !matcher.Match(score) {
+			t.Errorf("same finger rotated %.1f rad scored %d", rotation, score)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		if score := matcher.Score(finger(rng, 40), enrolled); matcher.Match(score) {
+			t.Errorf("another finger scored %d", score)
+		}
+	}
+
+	if score := matcher.Score(enrolled, enrolled); score != 100 {
+		t.Errorf("identical templates scored %d", score)
+	}
+	if score := matcher.Score(Template{}, enrolled); score != 0 {
+		t.Errorf("an empty template scored %d", score)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	engine, err := NewEngine(bytes.Repeat([]byte{7}, 32), DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := []byte("FMR\x00 20\x00 template")
+
+	sealed, err := engine.Seal(template, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, template) {
+		t.Fatal("sealed template holds the plain template")
+	}
+
+	opened, err := engine.Open(sealed, 42)
+	if err != nil || !bytes.Equal(opened, template) {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	if _, err := engine.Open(sealed, 43); err != ErrSealed {
+		t.Errorf("template of voter 42 opened for voter 43: %v", err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := engine.Open(sealed, 42); err != ErrSealed {
+		t.Errorf("tampered template opened: %v", err)
+	}
+
+	if _, err := NewEngine([]byte("short"), DefaultThreshold); err != ErrInvalidKey {
+		t.Errorf("err = %v, want %v", err, ErrInvalidKey)
+	}
+}
@@ -0,0 +1,172 @@
+package biometric
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultThreshold is the score from which two templates are taken to be of the same finger
+	DefaultThreshold = 40
+	// MinMinutiae is the number of minutiae an enrolled template needs to be matched reliably
+	MinMinutiae = 12
+
+	// distanceTolerance is how far apart in millimetres two aligned minutiae may be, about 12 pixels at 500 dpi
+	distanceTolerance = 0.6
+	// angleTolerance is how much the directions of two aligned minutiae may differ
+	angleTolerance = math.Pi / 12
+	// neighbours is the number of nearest minutiae describing the surroundings of a minutia
+	neighbours = 5
+	// alignments is the number of the most similar minutia pairs tried as the reference of an alignment
+	alignments = 12
+)
+
+// Matcher compares minutiae templates. Score is from 0 to 100 and a score of at least Threshold is a match.
+type Matcher struct {
+	Threshold int
+}
+
+// Match reports whether the score reaches the threshold
+func (m Matcher) Match(score int) bool {
+	return score >= m.Threshold
+}
+
+// neighbour is a nearby minutia seen from a minutia: its distance, the direction towards it and its direction,
+// both angles relative to the direction of the minutia so they do not change when the finger is rotated or moved
+type neighbour struct {
+	distance  float64
+	bearing   float64
+	direction float64
+}
+
+// Score compares the probe with the gallery template.
+// Minutiae with similar surroundings are paired, then each of the most similar pairs is used to rotate and move
+// the probe onto the gallery, and the alignment pairing up the most minutiae wins.
+// The score is the number of paired minutiae squared over the product of both minutiae counts, as a percentage.
+func (m Matcher) Score(probe Template, gallery Template) int {
+	p, g := probe.Minutiae, gallery.Minutiae
+	if len(p) == 0 || len(g) == 0 {
+		return 0
+	}
+
+	pLocal, gLocal := describe(p), describe(g)
+
+	type pair struct {
+		i, j       int
+		similarity int
+	}
+	var pairs []pair
+	for i := range p {
+		for j := range g {
+			if similarity := compareNeighbours(pLocal[i], gLocal[j]); similarity >= 2 {
+				pairs = append(pairs, pair{i, j, similarity})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].similarity > pairs[b].similarity })
+	if len(pairs) > alignments {
+		pairs = pairs[:alignments]
+	}
+
+	best := 0
+	for _, pr := range pairs {
+		if paired := align(p, g, p[pr.i], g[pr.j]); paired > best {
+			best = paired
+		}
+	}
+
+	score := 100 * best * best / (len(p) * len(g))
+	if score > 100 {
+		score = 100
+	}
+	return score
+}
+
+// describe returns the nearest neighbours of every minutia, closest first
+func describe(minutiae []Minutia) [][]neighbour {
+	local := make([][]neighbour, len(minutiae))
+	for i, a := range minutiae {
+		list := make([]neighbour, 0, len(minutiae)-1)
+		for j, b := range minutiae {
+			if i == j {
+				continue
+			}
+			dx, dy := b.X-a.X, b.Y-a.Y
+			list = append(list, neighbour{
+				distance:  math.Hypot(dx, dy),
+				bearing:   angleDiff(math.Atan2(dy, dx), a.Angle),
+				direction: angleDiff(b.Angle, a.Angle),
+			})
+		}
+		sort.Slice(list, func(x, y int) bool { return list[x].distance < list[y].distance })
+		if len(list) > neighbours {
+			list = list[:neighbours]
+		}
+		local[i] = list
+	}
+	return local
+}
+
+// compareNeighbours counts the neighbours of a that have a counterpart among the neighbours of b.
+// Every neighbour of b is used once, a missing or spurious minutia only costs the pair it belongs to.
+func compareNeighbours(a []neighbour, b []neighbour) int {
+	used := make([]bool, len(b))
+	count := 0
+	for _, na := range a {
+		for k, nb := range b {
+			if used[k] {
+				continue
+			}
+			if math.Abs(na.distance-nb.distance) <= distanceTolerance &&
+				math.Abs(angleDiff(na.bearing, nb.bearing)) <= angleTolerance &&
+				math.Abs(angleDiff(na.direction, nb.direction)) <= angleTolerance {
+				used[k] = true
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// align rotates and moves the probe so reference p lies on reference g and counts the minutiae that pair up.
+// Each gallery minutia pairs with the closest probe minutia within tolerance, at most once.
+func align(probe []Minutia, gallery []Minutia, p Minutia, g Minutia) int {
+	rotation := angleDiff(g.Angle, p.Angle)
+	sin, cos := math.Sincos(rotation)
+
+	used := make([]bool, len(gallery))
+	paired := 0
+	for _, a := range probe {
+		dx, dy := a.X-p.X, a.Y-p.Y
+		x := g.X + dx*cos - dy*sin
+		y := g.Y + dx*sin + dy*cos
+		angle := a.Angle + rotation
+
+		closest, distance := -1, distanceTolerance
+		for k, b := range gallery {
+			if used[k] || math.Abs(angleDiff(angle, b.Angle)) > angleTolerance {
+				continue
+			}
+			if d := math.Hypot(b.X-x, b.Y-y); d <= distance {
+				closest, distance = k, d
+			}
+		}
+		if closest >= 0 {
+			used[closest] = true
+			paired++
+		}
+	}
+	return paired
+}
+
+// angleDiff returns a - b normalized to (-π, π]
+func angleDiff(a float64, b float64) float64 {
+	d := math.Mod(a-b, 2*math.Pi)
+	if d > math.Pi {
+		d -= 2 * math.Pi
+	} else if d <= -math.Pi {
+		d += 2 * math.Pi
+	}
+	return d
+}
@@ -0,0 +1,205 @@
+package biometric
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Format is the standard a minutiae template is encoded with
+type Format string
+
+const (
+	// FormatISO19794 is ISO/IEC 19794-2:2005 finger minutiae record format
+	FormatISO19794 Format = "iso19794-2"
+	// FormatANSI378 is ANSI INCITS 378-2004 finger minutiae format
+	FormatANSI378 Format = "ansi378"
+)
+
+// MinutiaType is the kind of ridge feature of a minutia
+type MinutiaType int
+
+const (
+	MinutiaOther       MinutiaType = 0
+	MinutiaRidgeEnding MinutiaType = 1
+	MinutiaBifurcation MinutiaType = 2
+)
+
+// defaultResolution is 500 dpi in pixels per centimetre, used when a record does not state its resolution
+const defaultResolution = 197
+
+var (
+	// ErrUnknownFormat is returned for a format other than iso19794-2 or ansi378
+	ErrUnknownFormat = errors.New("template format must be iso19794-2 or ansi378")
+	// ErrInvalidTemplate is returned when a record does not follow the format it claims
+	ErrInvalidTemplate = errors.New("template is not a valid finger minutiae record")
+)
+
+// Minutia is a ridge ending or bifurcation. The position is in millimetres with the y axis pointing up,
+// so the direction is an ordinary counterclockwise angle in radians and records of different resolutions compare.
+type Minutia struct {
+	X       float64
+	Y       float64
+	Angle   float64
+	Type    MinutiaType
+	Quality int
+}
+
+// Template is the first finger view of a minutiae record
+type Template struct {
+	Format         Format
+	FingerPosition int
+	Quality        int
+	Minutiae       []Minutia
+}
+
+// ValidFormat reports whether the format is supported
+func ValidFormat(format Format) bool {
+	return format == FormatISO19794 || format == FormatANSI378
+}
+
+// Parse decodes a finger minutiae record. Both formats share the finger view layout and differ in the header
+// and in the unit of the minutia angle. Views after the first one are ignored.
+func Parse(format Format, data []byte) (Template, error) {
+	var template = Template{Format: format}
+
+	if len(data) < 8 || string(data[0:4]) != "FMR\x00" || string(data[4:8]) != " 20\x00" {
+		return template, ErrInvalidTemplate
+	}
+
+	var (
+		offset     int
+		views      int
+		resolution [2]int
+		angleUnit  float64
+	)
+
+	switch format {
+	case FormatISO19794:
+		// format id, version, 4 byte record length, capture equipment, image size, resolution, view count, reserved
+		if len(data) < 24 || int(binary.BigEndian.Uint32(data[8:12])) != len(data) {
+			return template, ErrInvalidTemplate
+		}
+		resolution = [2]int{int(binary.BigEndian.Uint16(data[18:20])), int(binary.BigEndian.Uint16(data[20:22]))}
+		views = int(data[22])
+		offset = 24
+		angleUnit = 2 * math.Pi / 256
+	case FormatANSI378:
+		// the record length takes 2 bytes, or 6 bytes when the first 2 are zero
+		if len(data) < 26 {
+			return template, ErrInvalidTemplate
+		}
+		offset = 10
+		length := int(binary.BigEndian.Uint16(data[8:10]))
+		if length == 0 {
+			if len(data) < 30 {
+				return template, ErrInvalidTemplate
+			}
+			length = int(binary.BigEndian.Uint32(data[10:14]))
+			offset = 14
+		}
+		if length != len(data) {
+			return template, ErrInvalidTemplate
+		}
+		// CBEFF product identifier, capture equipment and image size precede the resolution
+		offset += 10
+		resolution = [2]int{int(binary.BigEndian.Uint16(data[offset : offset+2])), int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))}
+		views = int(data[offset+4])
+		offset += 6
+		angleUnit = 2 * math.Pi / 180
+	default:
+		return template, ErrUnknownFormat
+	}
+
+	if views == 0 || len(data) < offset+4 {
+		return template, ErrInvalidTemplate
+	}
+	for i := range resolution {
+		if resolution[i] == 0 {
+			resolution[i] = defaultResolution
+		}
+	}
+
+	template.FingerPosition = int(data[offset])
+	template.Quality = int(data[offset+2])
+	count := int(data[offset+3])
+	offset += 4
+
+	if len(data) < offset+count*6 {
+		return template, fmt.Errorf("%w: %d minutiae do not fit in the record", ErrInvalidTemplate, count)
+	}
+
+	template.Minutiae = make([]Minutia, 0, count)
+	for i := 0; i < count; i++ {
+		m := data[offset : offset+6]
+		x := int(binary.BigEndian.Uint16(m[0:2]) & 0x3fff)
+		y := int(binary.BigEndian.Uint16(m[2:4]) & 0x3fff)
+		template.Minutiae = append(template.Minutiae, Minutia{
+			X:       float64(x) * 10 / float64(resolution[0]),
+			Y:       -float64(y) * 10 / float64(resolution[1]),
+			Angle:   float64(m[4]) * angleUnit,
+			Type:    MinutiaType(m[0] >> 6),
+			Quality: int(m[5]),
+		})
+		offset += 6
+	}
+
+	return template, nil
+}
+
+// Encode writes the template as a single view record of the format at 500 dpi, the inverse of Parse
+func Encode(format Format, template Template) ([]byte, error) {
+	var header []byte
+	switch format {
+	case FormatISO19794:
+		header = make([]byte, 24)
+		binary.BigEndian.PutUint16(header[18:20], defaultResolution)
+		binary.BigEndian.PutUint16(header[20:22], defaultResolution)
+		header[22] = 1
+	case FormatANSI378:
+		header = make([]byte, 26)
+		binary.BigEndian.PutUint16(header[20:22], defaultResolution)
+		binary.BigEndian.PutUint16(header[22:24], defaultResolution)
+		header[24] = 1
+	default:
+		return nil, ErrUnknownFormat
+	}
+	copy(header[0:8], "FMR\x00 20\x00")
+
+	if len(template.Minutiae) > 255 {
+		return nil, fmt.Errorf("%w: a view holds at most 255 minutiae", ErrInvalidTemplate)
+	}
+
+	data := append(header, byte(template.FingerPosition), 0, byte(template.Quality), byte(len(template.Minutiae)))
+	for _, m := range template.Minutiae {
+		x := int(math.Round(m.X * defaultResolution / 10))
+		y := int(math.Round(-m.Y * defaultResolution / 10))
+		if x < 0 || x > 0x3fff || y < 0 || y > 0x3fff {
+			return nil, fmt.Errorf("%w: minutia outside of the image", ErrInvalidTemplate)
+		}
+
+		units := 256.0
+		if format == FormatANSI378 {
+			units = 180
+		}
+		direction := math.Mod(m.Angle, 2*math.Pi)
+		if direction < 0 {
+			direction += 2 * math.Pi
+		}
+		angle := int(math.Round(direction/(2*math.Pi)*units)) % int(units)
+
+		data = binary.BigEndian.AppendUint16(data, uint16(m.Type)<<14|uint16(x))
+		data = binary.BigEndian.AppendUint16(data, uint16(y))
+		data = append(data, byte(angle), byte(m.Quality))
+	}
+	// no extended data
+	data = append(data, 0, 0)
+
+	if format == FormatISO19794 {
+		binary.BigEndian.PutUint32(data[8:12], uint32(len(data)))
+	} else {
+		binary.BigEndian.PutUint16(data[8:10], uint16(len(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,161 @@
+package blindsig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// KeyBits is the size of the modulus of a signing key
+const KeyBits = 2048
+
+// TokenSize is the size in bytes of a credential token
+const TokenSize = 32
+
+// domain separates the digest of a token from other uses of SHA-256 over the same bytes
+const domain = "backend-election credential v1"
+
+var (
+	// ErrInvalidToken is returned for a token that is not TokenSize bytes long
+	ErrInvalidToken = errors.New("token is invalid")
+	// ErrInvalidMessage is returned for a blinded message that is not a number between 0 and the modulus
+	ErrInvalidMessage = errors.New("blinded message is invalid")
+	// ErrInvalidSignature is returned when a signature does not verify
+	ErrInvalidSignature = errors.New("signature is invalid")
+)
+
+// GenerateKey returns a new signing key. A key signs credentials of one election only.
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, KeyBits)
+}
+
+// NewToken returns a random credential token. The voter keeps it secret until the ballot is cast.
+func NewToken() ([]byte, error) {
+	token := make([]byte, TokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Blind hides the digest of the token from the signer: blinded = H(token) * r^e mod n.
+// The unblinder is r^-1 mod n, the voter keeps it to unblind the signature.
+func Blind(pub *rsa.PublicKey, token []byte) ([]byte, *big.Int, error) {
+	if len(token) != TokenSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	r, unblinder, err := blindingFactor(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blinded := new(big.Int).Exp(r, big.NewInt(int64(pub.E)), pub.N)
+	blinded.Mul(blinded, digest(pub, token))
+	blinded.Mod(blinded, pub.N)
+	return encode(pub, blinded), unblinder, nil
+}
+
+// Sign signs the blinded message: blinded^d mod n. The signer learns nothing about the token.
+// The signature is checked before it is returned, so a faulty computation never leaks the key.
+func Sign(priv *rsa.PrivateKey, blinded []byte) ([]byte, error) {
+	x := new(big.Int).SetBytes(blinded)
+	if x.Sign() == 0 || x.Cmp(priv.N) >= 0 {
+		return nil, ErrInvalidMessage
+	}
+
+	// the message is blinded once more so the time of the exponentiation does not depend on it
+	b, bInverse, err := blindingFactor(&priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	e := big.NewInt(int64(priv.E))
+	y := new(big.Int).Exp(b, e, priv.N)
+	y.Mul(y, x).Mod(y, priv.N)
+
+	s := new(big.Int).Exp(y, priv.D, priv.N)
+	s.Mul(s, bInverse).Mod(s, priv.N)
+
+	if new(big.Int).Exp(s, e, priv.N).Cmp(x) != 0 {
+		return nil, ErrInvalidSignature
+	}
+	return encode(&priv.PublicKey, s), nil
+}
+
+// Unblind removes the blinding factor from the signature of the signer and returns the signature of the token
+func Unblind(pub *rsa.PublicKey, token []byte, blindSignature []byte, unblinder *big.Int) ([]byte, error) {
+	s := new(big.Int).SetBytes(blindSignature)
+	if s.Cmp(pub.N) >= 0 {
+		return nil, ErrInvalidSignature
+	}
+	s.Mul(s, unblinder).Mod(s, pub.N)
+
+	signature := encode(pub, s)
+	if err := Verify(pub, token, signature); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// Verify checks that signature^e mod n equals the digest of the token
+func Verify(pub *rsa.PublicKey, token []byte, signature []byte) error {
+	if len(token) != TokenSize {
+		return ErrInvalidToken
+	}
+
+	s := new(big.Int).SetBytes(signature)
+	if s.Sign() == 0 || s.Cmp(pub.N) >= 0 {
+		return ErrInvalidSignature
+	}
+	if new(big.Int).Exp(s, big.NewInt(int64(pub.E)), pub.N).Cmp(digest(pub, token)) != 0 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// digest is the full domain hash of the token: SHA-256 in counter mode over the modulus and the token,
+// cut to one bit less than the modulus so it is always smaller than n
+func digest(pub *rsa.PublicKey, token []byte) *big.Int {
+	bits := pub.N.BitLen() - 1
+	size := (bits + 7) / 8
+
+	out := make([]byte, 0, size+sha256.Size)
+	counter := make([]byte, 4)
+	for i := uint32(0); len(out) < size; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write([]byte(domain))
+		h.Write(pub.N.Bytes())
+		h.Write(token)
+		h.Write(counter)
+		out = h.Sum(out)
+	}
+
+	m := new(big.Int).SetBytes(out[:size])
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return m.Mod(m, mask)
+}
+
+// blindingFactor returns a random r invertible mod n and its inverse
+func blindingFactor(pub *rsa.PublicKey) (*big.Int, *big.Int, error) {
+	for {
+		r, err := rand.Int(rand.Reader, pub.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.Sign() == 0 {
+			continue
+		}
+		if inverse := new(big.Int).ModInverse(r, pub.N); inverse != nil {
+			return r, inverse, nil
+		}
+	}
+}
+
+// encode writes x in the size of the modulus
+func encode(pub *rsa.PublicKey, x *big.Int) []byte {
+	return x.FillBytes(make([]byte, (pub.N.BitLen()+7)/8))
+}
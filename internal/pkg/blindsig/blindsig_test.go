@@ -0,0 +1,83 @@
+package blindsig
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestBlindSignature(t *testing.T) {
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &priv.PublicKey
+
+	token, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blinded, unblinder, err := Blind(pub, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(blinded).Cmp(digest(pub, token)) == 0 {
+		t.Fatal("blinded message is the digest of the token")
+	}
+
+	// blinding the same token twice gives unrelated messages, so the signer can not link them
+	again, _, err := Blind(pub, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(blinded, again) {
+		t.Error("blinding the same token twice gives the same message")
+	}
+
+	blindSignature, err := Sign(priv, blinded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := Unblind(pub, token, blindSignature, unblinder)
+	if err != nil {
+		t.Fatalf("unblinded signature does not verify: %v", err)
+	}
+	if err := Verify(pub, token, signature); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(signature, blindSignature) {
+		t.Error("signature of the token is the signature of the blinded message")
+	}
+
+	t.Run("Forgery", func(t *testing.T) {
+		other, _ := NewToken()
+		if err := Verify(pub, other, signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("signature verifies for another token: %v", err)
+		}
+
+		tampered := append([]byte(nil), signature...)
+		tampered[len(tampered)-1] ^= 1
+		if err := Verify(pub, token, tampered); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("tampered signature verifies: %v", err)
+		}
+
+		otherKey, _ := GenerateKey()
+		if err := Verify(&otherKey.PublicKey, token, signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("signature verifies under another key: %v", err)
+		}
+
+		if err := Verify(pub, token[:TokenSize-1], signature); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("short token is accepted: %v", err)
+		}
+	})
+
+	t.Run("InvalidMessage", func(t *testing.T) {
+		for _, message := range [][]byte{{0}, pub.N.Bytes()} {
+			if _, err := Sign(priv, message); !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("signer accepts %x: %v", message, err)
+			}
+		}
+	})
+}
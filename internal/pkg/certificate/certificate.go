@@ -0,0 +1,124 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrNoKeys is returned when CERTIFIER_PRIVATE_KEYS is not set, elections can not be certified then
+	ErrNoKeys = errors.New("CERTIFIER_PRIVATE_KEYS is not set")
+	// ErrChecksum is returned when the checksum of the bundle does not match its document
+	ErrChecksum = errors.New("certificate checksum does not match the document")
+	// ErrNoSignatures is returned when the bundle carries no signature
+	ErrNoSignatures = errors.New("certificate is not signed")
+	// ErrInvalidSignature is returned when a signature does not verify with its public key
+	ErrInvalidSignature = errors.New("certificate signature is invalid")
+	// ErrMissingSigner is returned when a trusted certifier did not sign the document
+	ErrMissingSigner = errors.New("certificate is not signed by a trusted certifier")
+)
+
+// Signature is the detached Ed25519 signature of a certifier over the canonical document
+type Signature struct {
+	PublicKey ed25519.PublicKey
+	Signature []byte
+}
+
+// PrivateKeys reads the keys of the certifiers from CERTIFIER_PRIVATE_KEYS, a comma separated list of the hex seeds
+// printed by peer-keygen
+func PrivateKeys() ([]ed25519.PrivateKey, error) {
+	value := os.Getenv("CERTIFIER_PRIVATE_KEYS")
+	if strings.TrimSpace(value) == "" {
+		return nil, ErrNoKeys
+	}
+
+	var keys []ed25519.PrivateKey
+	for _, item := range strings.Split(value, ",") {
+		seed, err := hex.DecodeString(strings.TrimSpace(item))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("CERTIFIER_PRIVATE_KEYS must be a comma separated list of hex encoded ed25519 seeds")
+		}
+		keys = append(keys, ed25519.NewKeyFromSeed(seed))
+	}
+	return keys, nil
+}
+
+// Canonical returns the canonical form of a JSON document, the form that is signed: object keys sorted,
+// no insignificant whitespace, no HTML escaping and numbers as written. The signatures so survive any
+// reformatting of the published bundle.
+func Canonical(document []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("certificate document has trailing data")
+	}
+
+	// maps are encoded with sorted keys, json.Number as written
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Checksum is the hex encoded SHA-256 checksum of the canonical document
+func Checksum(canonical []byte) string {
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign signs the canonical document with every key
+func Sign(canonical []byte, keys []ed25519.PrivateKey) []Signature {
+	signatures := make([]Signature, 0, len(keys))
+	for _, key := range keys {
+		signatures = append(signatures, Signature{
+			PublicKey: key.Public().(ed25519.PublicKey),
+			Signature: ed25519.Sign(key, canonical),
+		})
+	}
+	return signatures
+}
+
+// Verify checks the checksum and every signature of the document. When trusted keys are given, each of them
+// must have signed the document as well.
+func Verify(document []byte, checksum string, signatures []Signature, trusted []ed25519.PublicKey) error {
+	canonical, err := Canonical(document)
+	if err != nil {
+		return err
+	}
+	if Checksum(canonical) != checksum {
+		return ErrChecksum
+	}
+	if len(signatures) == 0 {
+		return ErrNoSignatures
+	}
+
+	signed := make(map[string]bool, len(signatures))
+	for _, signature := range signatures {
+		if len(signature.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(signature.PublicKey, canonical, signature.Signature) {
+			return fmt.Errorf("%w: %s", ErrInvalidSignature, base64.StdEncoding.EncodeToString(signature.PublicKey))
+		}
+		signed[string(signature.PublicKey)] = true
+	}
+
+	for _, key := range trusted {
+		if !signed[string(key)] {
+			return fmt.Errorf("%w: %s", ErrMissingSigner, base64.StdEncoding.EncodeToString(key))
+		}
+	}
+	return nil
+}
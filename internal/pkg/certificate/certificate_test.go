@@ -0,0 +1,60 @@
+package certificate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	canonical, err := Canonical([]byte(`{ "b": [1, 2.50, {"z": null, "a": "<x>"}], "a": 1e3 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":1e3,"b":[1,2.50,{"a":"<x>","z":null}]}`; string(canonical) != want {
+		t.Errorf("got %s, want %s", canonical, want)
+	}
+
+	again, _ := Canonical(canonical)
+	if string(again) != string(canonical) {
+		t.Errorf("canonical form is not stable: %s", again)
+	}
+
+	if _, err := Canonical([]byte(`{"a":1} {"b":2}`)); err == nil {
+		t.Error("trailing data is accepted")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var keys []ed25519.PrivateKey
+	for i := 0; i < 2; i++ {
+		_, key, _ := ed25519.GenerateKey(rand.Reader)
+		keys = append(keys, key)
+	}
+	_, outsider, _ := ed25519.GenerateKey(rand.Reader)
+
+	canonical, _ := Canonical([]byte(`{"election":{"id":1},"results":{"elected":[7]}}`))
+	checksum := Checksum(canonical)
+	signatures := Sign(canonical, keys)
+
+	// the signatures hold for any formatting of the document
+	formatted := []byte("{\n  \"results\": {\"elected\": [7]},\n  \"election\": {\"id\": 1}\n}")
+	if err := Verify(formatted, checksum, signatures, []ed25519.PublicKey{keys[1].Public().(ed25519.PublicKey)}); err != nil {
+		t.Fatalf("valid certificate: %v", err)
+	}
+
+	tampered := []byte(`{"election":{"id":1},"results":{"elected":[8]}}`)
+	if err := Verify(tampered, checksum, signatures, nil); err != ErrChecksum {
+		t.Errorf("tampered document: %v", err)
+	}
+	if err := Verify(tampered, Checksum(tampered), signatures, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered document with its checksum: %v", err)
+	}
+	if err := Verify(canonical, checksum, nil, nil); err != ErrNoSignatures {
+		t.Errorf("unsigned document: %v", err)
+	}
+	if err := Verify(canonical, checksum, signatures, []ed25519.PublicKey{outsider.Public().(ed25519.PublicKey)}); !errors.Is(err, ErrMissingSigner) {
+		t.Errorf("document without the trusted signer: %v", err)
+	}
+}
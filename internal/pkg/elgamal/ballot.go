@@ -0,0 +1,69 @@
+package elgamal
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Ballot is an encrypted ballot with one ciphertext per candidate in ballot order. Every ciphertext encrypts 0 or 1,
+// and the sum of the ciphertexts encrypts one of the totals allowed by the counting method, so a ballot can not give
+// a candidate more than one vote or choose more candidates than allowed.
+type Ballot struct {
+	Ciphertexts []Ciphertext
+	Proofs      []DisjunctiveProof
+	TotalProof  DisjunctiveProof
+}
+
+// selectionValues are the plaintexts a ciphertext of a ballot may hold
+var selectionValues = []int64{0, 1}
+
+// EncryptBallot encrypts the selections of a voter, true for every chosen candidate, under the public key h
+func EncryptBallot(h *big.Int, selections []bool, totals []int64) (Ballot, error) {
+	ballot := Ballot{Ciphertexts: make([]Ciphertext, 0, len(selections)), Proofs: make([]DisjunctiveProof, 0, len(selections))}
+	sum, randomness, total := Zero(), new(big.Int), int64(0)
+	for _, selected := range selections {
+		var m int64
+		if selected {
+			m = 1
+		}
+
+		c, r, err := Encrypt(h, m)
+		if err != nil {
+			return ballot, err
+		}
+		proof, err := ProveOneOf(h, c, r, m, selectionValues)
+		if err != nil {
+			return ballot, err
+		}
+
+		ballot.Ciphertexts = append(ballot.Ciphertexts, c)
+		ballot.Proofs = append(ballot.Proofs, proof)
+		sum = sum.Add(c)
+		randomness.Add(randomness, r)
+		total += m
+	}
+
+	var err error
+	ballot.TotalProof, err = ProveOneOf(h, sum, randomness.Mod(randomness, q), total, totals)
+	return ballot, err
+}
+
+// Verify checks that the ballot has a ciphertext for each of the candidates and that its proofs hold
+func (b Ballot) Verify(h *big.Int, candidates int, totals []int64) error {
+	if len(b.Ciphertexts) != candidates || len(b.Proofs) != candidates {
+		return fmt.Errorf("%w: ballot must have %d ciphertexts", ErrInvalidProof, candidates)
+	}
+
+	sum := Zero()
+	for i, c := range b.Ciphertexts {
+		if err := b.Proofs[i].Verify(h, c, selectionValues); err != nil {
+			return fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+		sum = sum.Add(c)
+	}
+
+	if err := b.TotalProof.Verify(h, sum, totals); err != nil {
+		return fmt.Errorf("total: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package elgamal
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// modp2048 is the 2048-bit MODP group of RFC 3526 (group 14). The modulus is a safe prime and the generator 2 is a
+// quadratic residue, so it generates the subgroup of prime order q = (p - 1) / 2.
+const modp2048 = `
+	FFFFFFFF FFFFFFFF C90FDAA2 2168C234 C4C6628B 80DC1CD1 29024E08 8A67CC74 020BBEA6 3B139B22 514A0879 8E3404DD
+	EF9519B3 CD3A431B 302B0A6D F25F1437 4FE1356D 6D51C245 E485B576 625E7EC6 F44C42E9 A637ED6B 0BFF5CB6 F406B7ED
+	EE386BFB 5A899FA5 AE9F2411 7C4B1FE6 49286651 ECE45B3D C2007CB8 A163BF05 98DA4836 1C55D39A 69163FA8 FD24CF5F
+	83655D23 DCA3AD96 1C62F356 208552BB 9ED52907 7096966D 670C354E 4ABC9804 F1746C08 CA18217C 32905E46 2E36CE3B
+	E39E772C 180E8603 9B2783A2 EC07A28F B5C55DF0 6F4C52C9 DE2BCBF6 95581718 3995497C EA956AE5 15D22618 98FA0510
+	15728E5A 8AACAA68 FFFFFFFF FFFFFFFF`
+
+// ElementSize is the size in bytes of a group element
+const ElementSize = 256
+
+var (
+	p, _ = new(big.Int).SetString(strings.Join(strings.Fields(modp2048), ""), 16)
+	q    = new(big.Int).Rsh(p, 1)
+	g    = big.NewInt(2)
+	one  = big.NewInt(1)
+)
+
+var (
+	// ErrInvalidElement is returned for a value that is not an element of the group
+	ErrInvalidElement = errors.New("value is not an element of the group")
+	// ErrInvalidProof is returned when a zero-knowledge proof does not verify
+	ErrInvalidProof = errors.New("proof is invalid")
+	// ErrOutOfRange is returned when a decrypted value is not in the searched range
+	ErrOutOfRange = errors.New("plaintext is out of range")
+)
+
+// Ciphertext is the exponential ElGamal encryption of m under the public key h: A = g^r and B = g^m h^r.
+// Multiplying two ciphertexts encrypts the sum of their plaintexts, so votes are added up without decrypting them.
+type Ciphertext struct {
+	A *big.Int
+	B *big.Int
+}
+
+// Zero is the encryption of 0 without randomness, the start of a sum
+func Zero() Ciphertext {
+	return Ciphertext{A: big.NewInt(1), B: big.NewInt(1)}
+}
+
+// Encrypt encrypts m under the public key h and returns the randomness r, which proofs about the ciphertext need
+func Encrypt(h *big.Int, m int64) (Ciphertext, *big.Int, error) {
+	r, err := randomExponent()
+	if err != nil {
+		return Ciphertext{}, nil, err
+	}
+	return Ciphertext{A: exp(g, r), B: mul(exp(g, big.NewInt(m)), exp(h, r))}, r, nil
+}
+
+// Add returns the encryption of the sum of the plaintexts of c and other
+func (c Ciphertext) Add(other Ciphertext) Ciphertext {
+	return Ciphertext{A: mul(c.A, other.A), B: mul(c.B, other.B)}
+}
+
+// Validate checks that both parts of the ciphertext are elements of the group
+func (c Ciphertext) Validate() error {
+	if err := ValidateElement(c.A); err != nil {
+		return err
+	}
+	return ValidateElement(c.B)
+}
+
+// ValidateElement checks that x is an element of the subgroup of order q
+func ValidateElement(x *big.Int) error {
+	if x == nil || x.Sign() <= 0 || x.Cmp(p) >= 0 || exp(x, q).Cmp(one) != 0 {
+		return ErrInvalidElement
+	}
+	return nil
+}
+
+// Element returns g^x, the public counterpart of the secret exponent x
+func Element(x *big.Int) *big.Int {
+	return exp(g, x)
+}
+
+func randomExponent() (*big.Int, error) {
+	return rand.Int(rand.Reader, q)
+}
+
+func exp(x *big.Int, e *big.Int) *big.Int {
+	return new(big.Int).Exp(x, e, p)
+}
+
+func mul(x *big.Int, y *big.Int) *big.Int {
+	z := new(big.Int).Mul(x, y)
+	return z.Mod(z, p)
+}
+
+// divide returns x / y^e for an element y of the group
+func divide(x *big.Int, y *big.Int, e *big.Int) *big.Int {
+	return mul(x, exp(y, new(big.Int).Sub(q, new(big.Int).Mod(e, q))))
+}
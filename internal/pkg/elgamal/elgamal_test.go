@@ -0,0 +1,146 @@
+package elgamal
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	if p.BitLen() != 2048 || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		t.Fatal("modulus is not a 2048-bit safe prime")
+	}
+	if err := ValidateElement(g); err != nil {
+		t.Fatal("generator is not in the subgroup of order q")
+	}
+	if err := ValidateElement(new(big.Int).Sub(p, one)); err == nil {
+		t.Error("p - 1 is accepted as an element")
+	}
+}
+
+func TestThresholdTally(t *testing.T) {
+	h, shares, verificationKeys, err := Deal(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// plurality over three candidates, every ballot chooses exactly one
+	votes := []int{0, 2, 2, 1, 2, 0, 2}
+	totals := []int64{1}
+	sums := []Ciphertext{Zero(), Zero(), Zero()}
+	for _, vote := range votes {
+		selections := make([]bool, len(sums))
+		selections[vote] = true
+		ballot, err := EncryptBallot(h, selections, totals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ballot.Verify(h, len(sums), totals); err != nil {
+			t.Fatalf("valid ballot is rejected: %v", err)
+		}
+		for i, c := range ballot.Ciphertexts {
+			sums[i] = sums[i].Add(c)
+		}
+	}
+
+	want := []int64{2, 1, 4}
+	for _, trustees := range [][]int{{1, 2, 3}, {2, 4, 5}, {1, 2, 3, 4, 5}} {
+		for i, sum := range sums {
+			factors := map[int]*big.Int{}
+			for _, index := range trustees {
+				share, err := PartialDecrypt(shares[index-1], sum)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := share.Verify(verificationKeys[index-1], sum); err != nil {
+					t.Fatalf("decryption share of trustee %d is rejected: %v", index, err)
+				}
+				factors[index] = share.Factor
+			}
+
+			got, err := Combine(sum, factors, int64(len(votes)))
+			if err != nil || got != want[i] {
+				t.Errorf("trustees %v decrypt candidate %d to %d (%v), want %d", trustees, i, got, err, want[i])
+			}
+		}
+	}
+
+	// fewer trustees than the threshold learn nothing
+	factors := map[int]*big.Int{}
+	for _, index := range []int{1, 2} {
+		share, _ := PartialDecrypt(shares[index-1], sums[2])
+		factors[index] = share.Factor
+	}
+	if got, err := Combine(sums[2], factors, int64(len(votes))); err == nil && got == want[2] {
+		t.Error("two trustees decrypted a tally with a threshold of three")
+	}
+}
+
+func TestForgery(t *testing.T) {
+	h, shares, verificationKeys, err := Deal(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := []int64{1}
+
+	t.Run("Double Vote", func(t *testing.T) {
+		c, r, _ := Encrypt(h, 2)
+		proof, err := ProveOneOf(h, c, r, 2, []int64{0, 1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := proof.Verify(h, c, selectionValues); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("encryption of 2 passes as a selection: %v", err)
+		}
+		if _, err := ProveOneOf(h, c, r, 2, selectionValues); err == nil {
+			t.Error("a proof is made for a value outside the list")
+		}
+	})
+
+	t.Run("Two Choices", func(t *testing.T) {
+		ballot, err := EncryptBallot(h, []bool{true, true, false}, []int64{2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ballot.Verify(h, 3, totals); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("plurality ballot with two choices is accepted: %v", err)
+		}
+	})
+
+	t.Run("Swapped Ciphertext", func(t *testing.T) {
+		ballot, _ := EncryptBallot(h, []bool{false, true}, totals)
+		other, _ := EncryptBallot(h, []bool{true, false}, totals)
+		ballot.Ciphertexts[0] = other.Ciphertexts[0]
+		if err := ballot.Verify(h, 2, totals); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("ballot with a replaced ciphertext is accepted: %v", err)
+		}
+	})
+
+	t.Run("Other Key", func(t *testing.T) {
+		ballot, _ := EncryptBallot(h, []bool{false, true}, totals)
+		other, _, _, _ := Deal(1, 1)
+		if err := ballot.Verify(other, 2, totals); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("ballot is accepted under another key: %v", err)
+		}
+	})
+
+	t.Run("Wrong Share", func(t *testing.T) {
+		c, _, _ := Encrypt(h, 1)
+		share, _ := PartialDecrypt(shares[0], c)
+		if err := share.Verify(verificationKeys[1], c); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("decryption share is accepted for another trustee: %v", err)
+		}
+		share.Factor = mul(share.Factor, g)
+		if err := share.Verify(verificationKeys[0], c); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("altered decryption share is accepted: %v", err)
+		}
+	})
+
+	t.Run("Invalid Element", func(t *testing.T) {
+		c, _, _ := Encrypt(h, 1)
+		c.A = new(big.Int).Sub(p, one)
+		if _, err := PartialDecrypt(shares[0], c); !errors.Is(err, ErrInvalidElement) {
+			t.Errorf("ciphertext outside the group is decrypted: %v", err)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package elgamal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// Proof is a non-interactive Chaum-Pedersen proof that log_g(X) = log_Y(Z), without revealing the logarithm
+type Proof struct {
+	Challenge *big.Int
+	Response  *big.Int
+}
+
+// ProveEqual proves that X = g^s and Z = Y^s
+func ProveEqual(s *big.Int, x *big.Int, y *big.Int, z *big.Int) (Proof, error) {
+	w, err := randomExponent()
+	if err != nil {
+		return Proof{}, err
+	}
+
+	c := challenge(x, y, z, exp(g, w), exp(y, w))
+	r := new(big.Int).Mul(c, s)
+	r.Add(r, w).Mod(r, q)
+	return Proof{Challenge: c, Response: r}, nil
+}
+
+// Verify checks the proof that log_g(X) = log_Y(Z)
+func (pr Proof) Verify(x *big.Int, y *big.Int, z *big.Int) error {
+	for _, element := range []*big.Int{x, y, z} {
+		if err := ValidateElement(element); err != nil {
+			return err
+		}
+	}
+	if !exponent(pr.Challenge) || !exponent(pr.Response) {
+		return ErrInvalidProof
+	}
+
+	t1 := divide(exp(g, pr.Response), x, pr.Challenge)
+	t2 := divide(exp(y, pr.Response), z, pr.Challenge)
+	if challenge(x, y, z, t1, t2).Cmp(pr.Challenge) != 0 {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// DisjunctiveProof is a Cramer-Damgard-Schoenmakers proof that a ciphertext encrypts one of a list of values,
+// without revealing which one. It holds one simulated Chaum-Pedersen proof per value but the known one, and the
+// challenges add up to the challenge of the whole proof.
+type DisjunctiveProof struct {
+	Challenges []*big.Int
+	Responses  []*big.Int
+}
+
+// ProveOneOf proves that c, the encryption of m with the randomness r under the public key h, encrypts one of values
+func ProveOneOf(h *big.Int, c Ciphertext, r *big.Int, m int64, values []int64) (DisjunctiveProof, error) {
+	proof := DisjunctiveProof{Challenges: make([]*big.Int, len(values)), Responses: make([]*big.Int, len(values))}
+	commitments := make([]*big.Int, 0, 2*len(values))
+	known := -1
+	var w *big.Int
+	for i, value := range values {
+		if value == m && known < 0 {
+			var err error
+			if w, err = randomExponent(); err != nil {
+				return proof, err
+			}
+			known = i
+			commitments = append(commitments, exp(g, w), exp(h, w))
+			continue
+		}
+
+		var err error
+		if proof.Challenges[i], err = randomExponent(); err != nil {
+			return proof, err
+		}
+		if proof.Responses[i], err = randomExponent(); err != nil {
+			return proof, err
+		}
+		t1, t2 := disjunctiveCommitments(h, c, value, proof.Challenges[i], proof.Responses[i])
+		commitments = append(commitments, t1, t2)
+	}
+	if known < 0 {
+		return proof, fmt.Errorf("%d is not one of the values %v", m, values)
+	}
+
+	sum := disjunctiveChallenge(h, c, values, commitments)
+	for i, challenge := range proof.Challenges {
+		if i != known {
+			sum.Sub(sum, challenge)
+		}
+	}
+	proof.Challenges[known] = sum.Mod(sum, q)
+	proof.Responses[known] = new(big.Int).Mul(proof.Challenges[known], r)
+	proof.Responses[known].Add(proof.Responses[known], w).Mod(proof.Responses[known], q)
+	return proof, nil
+}
+
+// Verify checks the proof that c encrypts one of values under the public key h
+func (pr DisjunctiveProof) Verify(h *big.Int, c Ciphertext, values []int64) error {
+	if err := ValidateElement(h); err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if len(values) == 0 || len(pr.Challenges) != len(values) || len(pr.Responses) != len(values) {
+		return ErrInvalidProof
+	}
+
+	sum := new(big.Int)
+	commitments := make([]*big.Int, 0, 2*len(values))
+	for i, value := range values {
+		if !exponent(pr.Challenges[i]) || !exponent(pr.Responses[i]) {
+			return ErrInvalidProof
+		}
+		t1, t2 := disjunctiveCommitments(h, c, value, pr.Challenges[i], pr.Responses[i])
+		commitments = append(commitments, t1, t2)
+		sum.Add(sum, pr.Challenges[i])
+	}
+
+	if sum.Mod(sum, q).Cmp(disjunctiveChallenge(h, c, values, commitments)) != 0 {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// disjunctiveCommitments recomputes the commitments of the proof that A = g^r and B / g^value = h^r
+func disjunctiveCommitments(h *big.Int, c Ciphertext, value int64, challenge *big.Int, response *big.Int) (*big.Int, *big.Int) {
+	z := divide(c.B, g, big.NewInt(value))
+	return divide(exp(g, response), c.A, challenge), divide(exp(h, response), z, challenge)
+}
+
+func disjunctiveChallenge(h *big.Int, c Ciphertext, values []int64, commitments []*big.Int) *big.Int {
+	inputs := []*big.Int{h, c.A, c.B}
+	for _, value := range values {
+		inputs = append(inputs, big.NewInt(value))
+	}
+	return challenge(append(inputs, commitments...)...)
+}
+
+// challenge is the Fiat-Shamir challenge: the SHA-256 of the generator and the inputs, each padded to ElementSize
+func challenge(inputs ...*big.Int) *big.Int {
+	hash := sha256.New()
+	buf := make([]byte, ElementSize)
+	for _, input := range append([]*big.Int{g}, inputs...) {
+		hash.Write(input.FillBytes(buf))
+	}
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
+
+// exponent reports whether x is a valid exponent in [0, q)
+func exponent(x *big.Int) bool {
+	return x != nil && x.Sign() >= 0 && x.Cmp(q) < 0
+}
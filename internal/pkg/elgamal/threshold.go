@@ -0,0 +1,121 @@
+package elgamal
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Share is the share of the election key held by one trustee: the value at Index of a random polynomial of degree
+// threshold - 1 whose constant term is the election key (Shamir secret sharing)
+type Share struct {
+	Index int
+	Value *big.Int
+}
+
+// Deal creates an election key split into n shares, any threshold of which decrypt together. It returns the public
+// key, the shares and the verification key g^share of every share. The election key itself is never kept.
+func Deal(threshold int, n int) (*big.Int, []Share, []*big.Int, error) {
+	if threshold < 1 || threshold > n {
+		return nil, nil, nil, errors.New("threshold must be between 1 and the number of trustees")
+	}
+
+	coefficients := make([]*big.Int, threshold)
+	for i := range coefficients {
+		var err error
+		if coefficients[i], err = randomExponent(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	shares := make([]Share, n)
+	verificationKeys := make([]*big.Int, n)
+	for i := range shares {
+		x := big.NewInt(int64(i + 1))
+		value := new(big.Int)
+		for j := len(coefficients) - 1; j >= 0; j-- {
+			value.Mul(value, x).Add(value, coefficients[j]).Mod(value, q)
+		}
+		shares[i] = Share{Index: i + 1, Value: value}
+		verificationKeys[i] = exp(g, value)
+	}
+
+	return exp(g, coefficients[0]), shares, verificationKeys, nil
+}
+
+// DecryptionShare is the partial decryption A^share of a ciphertext by one trustee, with the proof that it was made
+// with the share of the verification key of the trustee
+type DecryptionShare struct {
+	Factor *big.Int
+	Proof  Proof
+}
+
+// PartialDecrypt computes the decryption share of a ciphertext with the share of a trustee
+func PartialDecrypt(share Share, c Ciphertext) (DecryptionShare, error) {
+	if err := c.Validate(); err != nil {
+		return DecryptionShare{}, err
+	}
+
+	factor := exp(c.A, share.Value)
+	proof, err := ProveEqual(share.Value, exp(g, share.Value), c.A, factor)
+	return DecryptionShare{Factor: factor, Proof: proof}, err
+}
+
+// Verify checks the decryption share of the ciphertext against the verification key of the trustee
+func (d DecryptionShare) Verify(verificationKey *big.Int, c Ciphertext) error {
+	if c.A == nil {
+		return ErrInvalidElement
+	}
+	return d.Proof.Verify(verificationKey, c.A, d.Factor)
+}
+
+// Combine decrypts the ciphertext with the factors of the decryption shares of at least threshold trustees, keyed by
+// the index of the trustee. The plaintext is searched in [0, max].
+func Combine(c Ciphertext, factors map[int]*big.Int, max int64) (int64, error) {
+	d := big.NewInt(1)
+	for i, factor := range factors {
+		d = mul(d, exp(factor, lagrange(i, factors)))
+	}
+	return discreteLog(divide(c.B, d, one), max)
+}
+
+// lagrange is the Lagrange coefficient at 0 of the trustee i among the trustees of the factors
+func lagrange(i int, factors map[int]*big.Int) *big.Int {
+	numerator, denominator := big.NewInt(1), big.NewInt(1)
+	for j := range factors {
+		if j == i {
+			continue
+		}
+		numerator.Mul(numerator, big.NewInt(int64(j))).Mod(numerator, q)
+		denominator.Mul(denominator, big.NewInt(int64(j-i))).Mod(denominator, q)
+	}
+	return numerator.Mul(numerator, denominator.ModInverse(denominator, q)).Mod(numerator, q)
+}
+
+// discreteLog finds m in [0, max] with g^m = y by baby-step giant-step
+func discreteLog(y *big.Int, max int64) (int64, error) {
+	if max < 0 {
+		return 0, ErrOutOfRange
+	}
+
+	step := int64(1)
+	for step*step <= max {
+		step++
+	}
+
+	baby := make(map[string]int64, step)
+	x := big.NewInt(1)
+	for j := int64(0); j < step; j++ {
+		baby[string(x.Bytes())] = j
+		x = mul(x, g)
+	}
+
+	giant := exp(g, new(big.Int).Sub(q, big.NewInt(step)))
+	gamma := new(big.Int).Set(y)
+	for i := int64(0); i*step <= max; i++ {
+		if j, ok := baby[string(gamma.Bytes())]; ok && i*step+j <= max {
+			return i*step + j, nil
+		}
+		gamma = mul(gamma, giant)
+	}
+	return 0, ErrOutOfRange
+}
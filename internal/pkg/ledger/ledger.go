@@ -0,0 +1,137 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// HashSize is the size of every hash of the ledger
+const HashSize = sha256.Size
+
+var (
+	// ErrConflict is returned when entries are appended after a height that is no longer the head
+	ErrConflict = errors.New("ledger head moved")
+	// ErrInvalidEntry is returned when an entry does not extend the ledger
+	ErrInvalidEntry = errors.New("entry does not extend the ledger")
+)
+
+// Hash is a SHA-256 hash, hex encoded in JSON
+type Hash []byte
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*h = decoded
+	return nil
+}
+
+// Entry is a record of the ledger. Hash chains the entry to the previous one and Root is the RFC 6962 Merkle tree hash
+// over the hashes of all entries up to and including this one, so a single root commits to the whole ledger.
+type Entry struct {
+	Height   int64           `json:"height"`
+	Payload  json.RawMessage `json:"payload"`
+	PrevHash Hash            `json:"prev_hash"`
+	Hash     Hash            `json:"hash"`
+	Root     Hash            `json:"root"`
+}
+
+// Head is the last entry of a ledger together with the compact Merkle tree needed to extend it.
+// Frontier holds the roots of the perfect subtrees making up the tree, largest first.
+type Head struct {
+	Height   int64  `json:"height"`
+	Hash     Hash   `json:"hash"`
+	Root     Hash   `json:"root"`
+	Frontier []Hash `json:"-"`
+}
+
+// Genesis is the head of an empty ledger
+func Genesis() Head {
+	empty := sha256.Sum256(nil)
+	return Head{Hash: make(Hash, HashSize), Root: empty[:]}
+}
+
+// EntryHash chains a payload to the previous entry: SHA-256 of the big endian height, the previous hash
+// and the SHA-256 of the payload
+func EntryHash(height int64, prevHash []byte, payload []byte) Hash {
+	payloadHash := sha256.Sum256(payload)
+	h := sha256.New()
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(height)))
+	h.Write(prevHash)
+	h.Write(payloadHash[:])
+	return h.Sum(nil)
+}
+
+// LeafHash is the RFC 6962 hash of a leaf, the leaves of the ledger tree are the entry hashes
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NodeHash is the RFC 6962 hash of an interior node
+func NodeHash(left []byte, right []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Next returns the entry appending the payload to the ledger and the new head
+func (h Head) Next(payload []byte) (Entry, Head) {
+	height := h.Height + 1
+	entry := Entry{
+		Height:   height,
+		Payload:  json.RawMessage(payload),
+		PrevHash: h.Hash,
+		Hash:     EntryHash(height, h.Hash, payload),
+	}
+
+	// adding a leaf merges the subtrees of equal size, like carrying a binary counter
+	frontier := append([]Hash{}, h.Frontier...)
+	node := LeafHash(entry.Hash)
+	for size := h.Height; size&1 == 1; size >>= 1 {
+		node = NodeHash(frontier[len(frontier)-1], node)
+		frontier = frontier[:len(frontier)-1]
+	}
+	frontier = append(frontier, node)
+
+	root := frontier[len(frontier)-1]
+	for i := len(frontier) - 2; i >= 0; i-- {
+		root = NodeHash(frontier[i], root)
+	}
+	entry.Root = root
+
+	return entry, Head{Height: height, Hash: entry.Hash, Root: root, Frontier: frontier}
+}
+
+// Extend checks that the entries follow the head one by one with the expected hashes and roots,
+// and returns the head after the last entry
+func (h Head) Extend(entries []Entry) (Head, error) {
+	for _, entry := range entries {
+		expected, next := h.Next(entry.Payload)
+		if entry.Height != expected.Height {
+			return h, fmt.Errorf("%w: height %d follows %d", ErrInvalidEntry, entry.Height, h.Height)
+		}
+		if !bytes.Equal(entry.PrevHash, expected.PrevHash) || !bytes.Equal(entry.Hash, expected.Hash) {
+			return h, fmt.Errorf("%w: hash of height %d does not chain", ErrInvalidEntry, entry.Height)
+		}
+		if !bytes.Equal(entry.Root, expected.Root) {
+			return h, fmt.Errorf("%w: root of height %d does not match", ErrInvalidEntry, entry.Height)
+		}
+		h = next
+	}
+	return h, nil
+}
@@ -0,0 +1,308 @@
+package ledger
+
+import (
+	"backend-election/internal/pkg/peer"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// merkleRoot is the recursive definition of RFC 6962, section 2.1
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	case 1:
+		return LeafHash(leaves[0])
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	return NodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+func payload(i int) []byte {
+	return []byte(fmt.Sprintf(`{"ballot_id":%d,"choices":[%d]}`, i, i%3+1))
+}
+
+func TestMerkleRoot(t *testing.T) {
+	head := Genesis()
+	if !bytes.Equal(head.Root, merkleRoot(nil)) {
+		t.Fatal("root of the empty ledger differs")
+	}
+
+	var leaves [][]byte
+	for i := 1; i <= 33; i++ {
+		var entry Entry
+		entry, head = head.Next(payload(i))
+		leaves = append(leaves, entry.Hash)
+		if !bytes.Equal(entry.Root, merkleRoot(leaves)) {
+			t.Fatalf("root at height %d differs from RFC 6962", i)
+		}
+	}
+}
+
+func TestExtend(t *testing.T) {
+	store := NewMemoryStore()
+	for i := 1; i <= 5; i++ {
+		store.Append(context.Background(), int64(i-1), payload(i))
+	}
+	entries, _ := store.Entries(context.Background(), 1, 10)
+	head, _ := store.Head(context.Background())
+
+	if extended, err := Genesis().Extend(entries); err != nil || !bytes.Equal(extended.Root, head.Root) {
+		t.Fatalf("valid entries do not extend the ledger: %v", err)
+	}
+
+	tampered := append([]Entry{}, entries...)
+	tampered[2].Payload = payload(99)
+	if _, err := Genesis().Extend(tampered); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("a changed payload was accepted: %v", err)
+	}
+
+	if _, err := Genesis().Extend(entries[1:]); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("a gap was accepted: %v", err)
+	}
+
+	if _, err := store.Append(context.Background(), 3, payload(6)); err != ErrConflict {
+		t.Errorf("append after an old head = %v, want ErrConflict", err)
+	}
+}
+
+// node stands in for a peer: it serves its ledger and the heads of its replicas to requests signed by the client key
+type node struct {
+	store    *MemoryStore
+	replicas []Replica
+	server   *httptest.Server
+}
+
+func newNode(t *testing.T, clientKey ed25519.PublicKey) *node {
+	n := &node{store: NewMemoryStore()}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := peer.Verify(r, body, clientKey, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var v interface{}
+		switch r.URL.Path {
+		case "/ledger/head":
+			v, _ = n.store.Head(r.Context())
+		case "/ledger/entries":
+			from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			v, _ = n.store.Entries(r.Context(), from, limit)
+		case "/ledger/replicas":
+			v = n.replicas
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(v)
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *node) append(count int) {
+	head, _ := n.store.Head(context.Background())
+	for i := 0; i < count; i++ {
+		n.store.Append(context.Background(), head.Height+int64(i), payload(int(head.Height)+i+1))
+	}
+}
+
+// rewrite replaces the ledger of the node with one that differs from height on
+func (n *node) rewrite(height int64) {
+	entries, _ := n.store.Entries(context.Background(), 1, 1000)
+	n.store = NewMemoryStore()
+	for i, entry := range entries {
+		data := []byte(entry.Payload)
+		if entry.Height >= height {
+			data = payload(1000 + i)
+		}
+		n.store.Append(context.Background(), int64(i), data)
+	}
+}
+
+// truncate drops the entries of the node after height
+func (n *node) truncate(height int64) {
+	entries, _ := n.store.Entries(context.Background(), 1, int(height))
+	n.store = NewMemoryStore()
+	for i, entry := range entries {
+		n.store.Append(context.Background(), int64(i), entry.Payload)
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{5}, 32)))
+	ctx := context.Background()
+
+	origin := newNode(t, publicKey)
+	remote := HTTPRemote{Endpoint: origin.server.URL, Client: peer.Client{ID: 1, Key: privateKey}}
+	replica := NewMemoryStore()
+
+	origin.append(23)
+	head, err := Replicate(ctx, replica, remote, 5)
+	if err != nil {
+		t.Fatalf("replicate: %v", err)
+	}
+	originHead, _ := origin.store.Head(ctx)
+	if head.Height != 23 || !bytes.Equal(head.Root, originHead.Root) {
+		t.Fatalf("replica is at %d, want 23 with the root of the origin", head.Height)
+	}
+
+	t.Run("Catch Up", func(t *testing.T) {
+		origin.append(4)
+		head, err := Replicate(ctx, replica, remote, 100)
+		if err != nil || head.Height != 27 {
+			t.Fatalf("catch up from 23 = %d, %v", head.Height, err)
+		}
+		if _, err := Replicate(ctx, replica, remote, 100); err != nil {
+			t.Errorf("replicating an unchanged ledger: %v", err)
+		}
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		_, otherKey, _ := ed25519.GenerateKey(nil)
+		other := HTTPRemote{Endpoint: origin.server.URL, Client: peer.Client{ID: 1, Key: otherKey}}
+		if _, err := Replicate(ctx, NewMemoryStore(), other, 100); err == nil {
+			t.Error("the origin accepted a request with another key")
+		}
+	})
+
+	scenarios := []struct {
+		Name   string
+		Change func(n *node)
+		Height int64
+	}{
+		{"Rewritten Tip", func(n *node) { n.rewrite(27); n.append(3) }, 27},
+		{"Rewritten History", func(n *node) { n.rewrite(10); n.append(3) }, 10},
+		{"Rewritten Same Height", func(n *node) { n.rewrite(20) }, 20},
+		{"Truncated", func(n *node) { n.truncate(12) }, 13},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.Name, func(t *testing.T) {
+			fork := newNode(t, publicKey)
+			entries, _ := origin.store.Entries(ctx, 1, 1000)
+			for _, entry := range entries {
+				fork.store.Append(ctx, entry.Height-1, entry.Payload)
+			}
+			s.Change(fork)
+
+			before, _ := replica.Head(ctx)
+			_, err := Replicate(ctx, replica, HTTPRemote{Endpoint: fork.server.URL, Client: peer.Client{ID: 1, Key: privateKey}}, 4)
+			var divergence *Divergence
+			if !errors.As(err, &divergence) {
+				t.Fatalf("expected a divergence, got %v", err)
+			}
+			if divergence.Height != s.Height {
+				t.Errorf("divergence detected at %d, the ledger forked at %d", divergence.Height, s.Height)
+			}
+			if after, _ := replica.Head(ctx); after.Height != before.Height {
+				t.Errorf("the replica grew from %d to %d with a forked ledger", before.Height, after.Height)
+			}
+		})
+	}
+
+	t.Run("Cross Verify", func(t *testing.T) {
+		witness := newNode(t, publicKey)
+		originHead, _ := origin.store.Head(ctx)
+		witness.replicas = []Replica{{PublicKey: []byte("origin"), Head: originHead}}
+
+		replicas, err := HTTPRemote{Endpoint: witness.server.URL, Client: peer.Client{ID: 1, Key: privateKey}}.Replicas(ctx)
+		if err != nil || len(replicas) != 1 {
+			t.Fatalf("replicas = %v, %v", replicas, err)
+		}
+		if err := Check(ctx, replica, replicas[0].Head); err != nil {
+			t.Errorf("matching replicas diverge: %v", err)
+		}
+
+		// the origin showed the witness another history at the same height
+		forked := NewMemoryStore()
+		for i := 0; i < int(originHead.Height); i++ {
+			forked.Append(ctx, int64(i), payload(2000+i))
+		}
+		forkedHead, _ := forked.Head(ctx)
+		var divergence *Divergence
+		if err := Check(ctx, replica, forkedHead); !errors.As(err, &divergence) {
+			t.Errorf("equivocation was not detected: %v", err)
+		}
+
+		ahead := forkedHead
+		ahead.Height = originHead.Height + 10
+		if err := Check(ctx, replica, ahead); err != nil {
+			t.Errorf("a head beyond the replica can not be checked yet: %v", err)
+		}
+	})
+}
+
+func TestProof(t *testing.T) {
+	receipt, err := NewReceipt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	for size := int64(1); size <= 21; size++ {
+		data := payload(int(size))
+		if size == 6 {
+			data = []byte(fmt.Sprintf(`{"election_id":1,"receipt":"%x"}`, []byte(ReceiptHash(receipt))))
+		}
+		store.Append(context.Background(), size-1, data)
+
+		entries, _ := store.Entries(context.Background(), 1, 100)
+		head, _ := store.Head(context.Background())
+		hashes := make([]Hash, len(entries))
+		for i, entry := range entries {
+			hashes[i] = entry.Hash
+		}
+		if !bytes.Equal(TreeHash(hashes), head.Root) {
+			t.Fatalf("tree hash of %d entries differs from the root", size)
+		}
+
+		for _, entry := range entries {
+			proof := Proof{Height: entry.Height, Payload: entry.Payload, PrevHash: entry.PrevHash, TreeSize: size, Root: head.Root, AuditPath: AuditPath(hashes, entry.Height-1)}
+			if err := proof.Verify(); err != nil {
+				t.Fatalf("proof of height %d in %d entries: %v", entry.Height, size, err)
+			}
+
+			tampered := proof
+			tampered.Payload = payload(99)
+			if tampered.Verify() == nil {
+				t.Fatalf("proof of height %d in %d entries verifies a changed payload", entry.Height, size)
+			}
+			if size > 1 {
+				moved := proof
+				moved.Height = size + 1 - proof.Height
+				if moved.Height != proof.Height && moved.Verify() == nil {
+					t.Fatalf("proof of height %d verifies at height %d", proof.Height, moved.Height)
+				}
+			}
+		}
+	}
+
+	entries, _ := store.Entries(context.Background(), 6, 1)
+	proof := Proof{Height: 6, Payload: entries[0].Payload}
+	if err := proof.VerifyReceipt(strings.ToLower(strings.ReplaceAll(receipt, "-", ""))); err != nil {
+		t.Errorf("receipt does not match its entry: %v", err)
+	}
+	other, _ := NewReceipt()
+	if err := proof.VerifyReceipt(other); err != ErrReceiptMismatch {
+		t.Errorf("another receipt matches the entry: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidProof is returned when an inclusion proof does not lead to the root
+var ErrInvalidProof = errors.New("inclusion proof is invalid")
+
+// Proof shows that an entry is included in the ledger of TreeSize entries with the Merkle root Root.
+// AuditPath holds the RFC 6962 sibling hashes from the leaf up to the root.
+type Proof struct {
+	Height    int64           `json:"height"`
+	Payload   json.RawMessage `json:"payload"`
+	PrevHash  Hash            `json:"prev_hash"`
+	TreeSize  int64           `json:"tree_size"`
+	Root      Hash            `json:"root"`
+	AuditPath []Hash          `json:"audit_path"`
+}
+
+// TreeHash is the RFC 6962 Merkle tree hash over the entry hashes
+func TreeHash(hashes []Hash) Hash {
+	switch len(hashes) {
+	case 0:
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	case 1:
+		return LeafHash(hashes[0])
+	}
+	k := splitPoint(len(hashes))
+	return NodeHash(TreeHash(hashes[:k]), TreeHash(hashes[k:]))
+}
+
+// AuditPath returns the RFC 6962 audit path of the entry at index (height - 1) in the tree over the entry hashes
+func AuditPath(hashes []Hash, index int64) []Hash {
+	if len(hashes) <= 1 {
+		return []Hash{}
+	}
+	k := int64(splitPoint(len(hashes)))
+	if index < k {
+		return append(AuditPath(hashes[:k], index), TreeHash(hashes[k:]))
+	}
+	return append(AuditPath(hashes[k:], index-k), TreeHash(hashes[:k]))
+}
+
+// splitPoint is the largest power of two smaller than n
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// Verify recomputes the entry from the payload and follows the audit path up to the root, as in RFC 9162 section 2.1.3.2
+func (p Proof) Verify() error {
+	if p.Height < 1 || p.Height > p.TreeSize {
+		return fmt.Errorf("%w: height %d is outside a tree of %d entries", ErrInvalidProof, p.Height, p.TreeSize)
+	}
+
+	node := LeafHash(EntryHash(p.Height, p.PrevHash, p.Payload))
+	index, last := p.Height-1, p.TreeSize-1
+	for _, sibling := range p.AuditPath {
+		if last == 0 {
+			return fmt.Errorf("%w: audit path is too long", ErrInvalidProof)
+		}
+		if index&1 == 1 || index == last {
+			node = NodeHash(sibling, node)
+			for index&1 == 0 && index != 0 {
+				index >>= 1
+				last >>= 1
+			}
+		} else {
+			node = NodeHash(node, sibling)
+		}
+		index >>= 1
+		last >>= 1
+	}
+
+	if last != 0 || !bytes.Equal(node, p.Root) {
+		return ErrInvalidProof
+	}
+	return nil
+}
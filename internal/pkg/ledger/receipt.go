@@ -0,0 +1,54 @@
+package ledger
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// receiptSize is the number of random bytes of a receipt code
+const receiptSize = 20
+
+// ErrReceiptMismatch is returned when the entry of a proof was not written for the receipt
+var ErrReceiptMismatch = errors.New("entry does not belong to the receipt")
+
+// NewReceipt returns a random receipt code, 32 base32 characters in groups of four separated by dashes
+func NewReceipt() (string, error) {
+	data := make([]byte, receiptSize)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	code := base32.StdEncoding.EncodeToString(data)
+	groups := make([]string, 0, len(code)/4)
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// ReceiptHash is the SHA-256 of the receipt code without separators and in upper case. Only the hash is stored,
+// so the code itself can not be read back from the database or the ledger.
+func ReceiptHash(code string) Hash {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hash[:]
+}
+
+// VerifyReceipt checks that the entry of the proof was written for the receipt code
+func (p Proof) VerifyReceipt(code string) error {
+	var payload struct {
+		Receipt string `json:"receipt"`
+	}
+	if err := json.Unmarshal(p.Payload, &payload); err != nil {
+		return ErrReceiptMismatch
+	}
+	if payload.Receipt != hex.EncodeToString(ReceiptHash(code)) {
+		return ErrReceiptMismatch
+	}
+	return nil
+}
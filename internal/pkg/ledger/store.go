@@ -0,0 +1,64 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+)
+
+// Store keeps a ledger, either the ledger of this node or the replica of the ledger of a peer
+type Store interface {
+	// Head returns the last entry of the ledger, or Genesis when it is empty
+	Head(ctx context.Context) (Head, error)
+	// Entries returns at most limit entries starting at height from
+	Entries(ctx context.Context, from int64, limit int) ([]Entry, error)
+	// Append adds the payloads after the entry at height, it fails with ErrConflict when that entry is no longer the head
+	Append(ctx context.Context, height int64, payloads ...[]byte) ([]Entry, error)
+}
+
+// MemoryStore is a Store kept in memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	head    Head
+	entries []Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{head: Genesis()}
+}
+
+func (s *MemoryStore) Head(ctx context.Context) (Head, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.head, nil
+}
+
+func (s *MemoryStore) Entries(ctx context.Context, from int64, limit int) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Entry, 0)
+	if from < 1 {
+		from = 1
+	}
+	for i := from - 1; i < int64(len(s.entries)) && len(list) < limit; i++ {
+		list = append(list, s.entries[i])
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) Append(ctx context.Context, height int64, payloads ...[]byte) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if height != s.head.Height {
+		return nil, ErrConflict
+	}
+	list := make([]Entry, 0, len(payloads))
+	for _, payload := range payloads {
+		var entry Entry
+		entry, s.head = s.head.Next(payload)
+		list = append(list, entry)
+	}
+	s.entries = append(s.entries, list...)
+	return list, nil
+}
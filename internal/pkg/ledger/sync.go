@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Divergence is returned when a ledger does not match its replica: the peer rewrote, truncated or forked its history
+type Divergence struct {
+	Height     int64
+	LocalHash  Hash
+	RemoteHash Hash
+	Reason     string
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("ledger diverges at height %d: %s (local %s, remote %s)",
+		d.Height, d.Reason, hex.EncodeToString(d.LocalHash), hex.EncodeToString(d.RemoteHash))
+}
+
+// Remote is the ledger of a peer
+type Remote interface {
+	Head(ctx context.Context) (Head, error)
+	Entries(ctx context.Context, from int64, limit int) ([]Entry, error)
+}
+
+// Replicate catches the replica up with the remote ledger from the height of the replica. Every entry is verified
+// against the chain and the Merkle root before it is stored, so the replica only ever holds a consistent prefix of
+// the remote ledger. A *Divergence is returned when the remote history no longer contains what was replicated before.
+func Replicate(ctx context.Context, replica Store, remote Remote, batch int) (Head, error) {
+	local, err := replica.Head(ctx)
+	if err != nil {
+		return local, err
+	}
+	head, err := remote.Head(ctx)
+	if err != nil {
+		return local, err
+	}
+
+	if head.Height < local.Height {
+		return local, &Divergence{Height: head.Height + 1, LocalHash: local.Hash, RemoteHash: head.Hash, Reason: "ledger is shorter than its replica"}
+	}
+	if head.Height == local.Height {
+		return local, locate(ctx, replica, remote, Check(ctx, replica, head))
+	}
+
+	// the first page starts at the head of the replica, so it also shows the remote still holds that entry
+	from := local.Height
+	if from < 1 {
+		from = 1
+	}
+	for local.Height < head.Height {
+		entries, err := remote.Entries(ctx, from, batch)
+		if err != nil {
+			return local, err
+		}
+		if len(entries) > 0 && local.Height > 0 && entries[0].Height == local.Height {
+			if !bytes.Equal(entries[0].Hash, local.Hash) {
+				return local, locate(ctx, replica, remote, &Divergence{Height: local.Height, LocalHash: local.Hash, RemoteHash: entries[0].Hash, Reason: "entry was rewritten"})
+			}
+			entries = entries[1:]
+		}
+		for i, entry := range entries {
+			if entry.Height > head.Height {
+				entries = entries[:i]
+				break
+			}
+		}
+		if len(entries) == 0 {
+			return local, fmt.Errorf("%w: remote returned no entry after height %d", ErrInvalidEntry, local.Height)
+		}
+
+		next, err := local.Extend(entries)
+		if err != nil {
+			return local, &Divergence{Height: next.Height + 1, LocalHash: next.Hash, RemoteHash: entries[next.Height-local.Height].PrevHash, Reason: err.Error()}
+		}
+
+		payloads := make([][]byte, len(entries))
+		for i, entry := range entries {
+			payloads[i] = entry.Payload
+		}
+		if _, err := replica.Append(ctx, local.Height, payloads...); err != nil {
+			return local, err
+		}
+		local = next
+		from = local.Height + 1
+	}
+
+	if !bytes.Equal(local.Hash, head.Hash) || !bytes.Equal(local.Root, head.Root) {
+		return local, &Divergence{Height: head.Height, LocalHash: local.Hash, RemoteHash: head.Hash, Reason: "head does not match its entries"}
+	}
+	return local, nil
+}
+
+// Check compares a head reported for a ledger, by the peer itself or by another replica of it, with the store.
+// A head beyond the store can not be checked yet and is accepted.
+func Check(ctx context.Context, store Store, head Head) error {
+	local, err := store.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if head.Height > local.Height {
+		return nil
+	}
+
+	expected := Genesis()
+	if head.Height == local.Height {
+		expected = local
+	} else if head.Height > 0 {
+		entries, err := store.Entries(ctx, head.Height, 1)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("%w: height %d is missing", ErrInvalidEntry, head.Height)
+		}
+		expected = Head{Height: entries[0].Height, Hash: entries[0].Hash, Root: entries[0].Root}
+	}
+
+	if !bytes.Equal(expected.Hash, head.Hash) || !bytes.Equal(expected.Root, head.Root) {
+		return &Divergence{Height: head.Height, LocalHash: expected.Hash, RemoteHash: head.Hash, Reason: "head differs"}
+	}
+	return nil
+}
+
+// locate moves a divergence back to the first height where the remote differs from the replica. As every hash
+// chains all entries before it, the entries differ from that height on and a binary search finds it.
+func locate(ctx context.Context, replica Store, remote Remote, err error) error {
+	divergence, ok := err.(*Divergence)
+	if !ok {
+		return err
+	}
+
+	low, high := int64(1), divergence.Height
+	for low < high {
+		middle := low + (high-low)/2
+		local, err := replica.Entries(ctx, middle, 1)
+		if err != nil || len(local) == 0 {
+			return divergence
+		}
+		remoteEntries, err := remote.Entries(ctx, middle, 1)
+		if err != nil || len(remoteEntries) == 0 {
+			return divergence
+		}
+		if bytes.Equal(local[0].Hash, remoteEntries[0].Hash) {
+			low = middle + 1
+		} else {
+			high = middle
+			divergence.Height, divergence.LocalHash, divergence.RemoteHash = middle, local[0].Hash, remoteEntries[0].Hash
+		}
+	}
+	return divergence
+}
+
+// Replica is the head of the replica a peer keeps of the ledger of the node identified by the public key
+type Replica struct {
+	PublicKey []byte `json:"public_key"`
+	Head
+}
+
+// Doer sends requests to a peer, see peer.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// HTTPRemote reads the ledger of a peer through its API
+type HTTPRemote struct {
+	Endpoint string
+	Client   Doer
+}
+
+func (r HTTPRemote) Head(ctx context.Context) (Head, error) {
+	var head Head
+	err := r.get(ctx, "/ledger/head", nil, &head)
+	return head, err
+}
+
+func (r HTTPRemote) Entries(ctx context.Context, from int64, limit int) ([]Entry, error) {
+	var list []Entry
+	query := url.Values{"from": {strconv.FormatInt(from, 10)}, "limit": {strconv.Itoa(limit)}}
+	err := r.get(ctx, "/ledger/entries", query, &list)
+	return list, err
+}
+
+// Replicas returns the heads of the replicas the peer keeps of other ledgers
+func (r HTTPRemote) Replicas(ctx context.Context) ([]Replica, error) {
+	var list []Replica
+	err := r.get(ctx, "/ledger/replicas", nil, &list)
+	return list, err
+}
+
+func (r HTTPRemote) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	target := strings.TrimRight(r.Endpoint, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", target, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Join(ErrInvalidEntry, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrNoKey is returned when PEER_PRIVATE_KEY is not set, the node then can not call its peers
+var ErrNoKey = errors.New("PEER_PRIVATE_KEY is not set")
+
+// PrivateKey reads the key of this node from PEER_PRIVATE_KEY, the hex seed printed by peer-keygen
+func PrivateKey() (ed25519.PrivateKey, error) {
+	value := os.Getenv("PEER_PRIVATE_KEY")
+	if value == "" {
+		return nil, ErrNoKey
+	}
+	seed, err := hex.DecodeString(value)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("PEER_PRIVATE_KEY must be a hex encoded ed25519 seed")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Client signs the requests this node sends to a peer. ID is the id this node is registered under at that peer.
+type Client struct {
+	ID   int64
+	Key  ed25519.PrivateKey
+	HTTP *http.Client
+}
+
+// Do reads the body of the request, signs it and sends it
+func (c Client) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	Sign(req, body, c.ID, c.Key, time.Now())
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return httpClient.Do(req)
+}
@@ -0,0 +1,80 @@
+package peer
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderID        = "X-Peer-ID"
+	HeaderTimestamp = "X-Peer-Timestamp"
+	HeaderSignature = "X-Peer-Signature"
+
+	// MaxSkew is how far the timestamp of a signed request may be from the clock of the receiving peer
+	MaxSkew = 5 * time.Minute
+)
+
+var (
+	// ErrMissingSignature is returned when a request lacks one of the peer headers
+	ErrMissingSignature = errors.New("peer signature headers are missing")
+	// ErrInvalidSignature is returned when the signature does not verify with the public key of the peer
+	ErrInvalidSignature = errors.New("peer signature is invalid")
+	// ErrStaleRequest is returned when the timestamp is further than MaxSkew from now
+	ErrStaleRequest = errors.New("peer request timestamp is out of range")
+)
+
+// Message is what a peer signs: the method, the request URI, the hex encoded SHA-256 checksum of the body
+// and the unix timestamp, separated by newlines
+func Message(method string, requestURI string, body []byte, timestamp string) []byte {
+	hash := sha256.Sum256(body)
+	return []byte(method + "\n" + requestURI + "\n" + hex.EncodeToString(hash[:]) + "\n" + timestamp)
+}
+
+// Sign adds the peer headers to the request. body must be the exact body the request sends.
+func Sign(req *http.Request, body []byte, peerID int64, key ed25519.PrivateKey, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := ed25519.Sign(key, Message(req.Method, req.URL.RequestURI(), body, timestamp))
+
+	req.Header.Set(HeaderID, strconv.FormatInt(peerID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(signature))
+}
+
+// ID returns the peer a request claims to come from
+func ID(req *http.Request) (int64, error) {
+	if req.Header.Get(HeaderID) == "" || req.Header.Get(HeaderTimestamp) == "" || req.Header.Get(HeaderSignature) == "" {
+		return 0, ErrMissingSignature
+	}
+	id, err := strconv.ParseInt(req.Header.Get(HeaderID), 10, 64)
+	if err != nil {
+		return 0, ErrMissingSignature
+	}
+	return id, nil
+}
+
+// Verify checks the timestamp and the signature of a request read with the body
+func Verify(req *http.Request, body []byte, publicKey ed25519.PublicKey, now time.Time) error {
+	timestamp := req.Header.Get(HeaderTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleRequest
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return ErrStaleRequest
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get(HeaderSignature))
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(publicKey, Message(req.Method, req.URL.RequestURI(), body, timestamp), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
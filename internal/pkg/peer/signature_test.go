@@ -0,0 +1,62 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{3}, 32)))
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"height":42}`)
+
+	signed := func() *http.Request {
+		req, _ := http.NewRequest("POST", "http://node-b.example/ledger/entries?from=3", bytes.NewReader(body))
+		Sign(req, body, 77, privateKey, now)
+		return req
+	}
+
+	req := signed()
+	if id, err := ID(req); err != nil || id != 77 {
+		t.Fatalf("ID = %d, %v", id, err)
+	}
+	if err := Verify(req, body, publicKey, now.Add(time.Minute)); err != nil {
+		t.Fatalf("a signed request did not verify: %v", err)
+	}
+
+	scenarios := []struct {
+		Name   string
+		Change func(req *http.Request) []byte
+		Now    time.Time
+		Err    error
+	}{
+		{"Changed Body", func(req *http.Request) []byte { return []byte(`{"height":43}`) }, now, ErrInvalidSignature},
+		{"Changed Path", func(req *http.Request) []byte { req.URL.RawQuery = "from=4"; return body }, now, ErrInvalidSignature},
+		{"Changed Method", func(req *http.Request) []byte { req.Method = "PUT"; return body }, now, ErrInvalidSignature},
+		{"Changed Timestamp", func(req *http.Request) []byte { req.Header.Set(HeaderTimestamp, "1760000001"); return body }, now, ErrInvalidSignature},
+		{"Too Old", func(req *http.Request) []byte { return body }, now.Add(MaxSkew + time.Second), ErrStaleRequest},
+		{"From The Future", func(req *http.Request) []byte { return body }, now.Add(-MaxSkew - time.Second), ErrStaleRequest},
+	}
+
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := signed()
+			if err := Verify(req, tt.Change(req), publicKey, tt.Now); err != tt.Err {
+				t.Errorf("err = %v, want %v", err, tt.Err)
+			}
+		})
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{4}, 32)))
+	if err := Verify(signed(), body, otherKey, now); err != ErrInvalidSignature {
+		t.Errorf("verified with the key of another peer: %v", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://node-b.example/peer", nil)
+	if _, err := ID(req); err != ErrMissingSignature {
+		t.Errorf("err = %v, want %v", err, ErrMissingSignature)
+	}
+}
@@ -0,0 +1,98 @@
+// Package policy decides whether the grants of a user allow a request. A grant gives an access path, either
+// everywhere or scoped to an election and to a region with the regions below it.
+package policy
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidGrant is returned when an encoded grant can not be parsed
+var ErrInvalidGrant = errors.New("invalid grant")
+
+// Grant is an access path granted to a user through one of their roles
+type Grant struct {
+	Path string
+	// ElectionID limits the grant to the routes of the election, 0 grants every election
+	ElectionID int64
+	// RegionID limits the grant to the region and the regions below it, 0 grants every region
+	RegionID int64
+}
+
+// Scoped reports whether the grant is limited to an election or a region
+func (g Grant) Scoped() bool {
+	return g.ElectionID != 0 || g.RegionID != 0
+}
+
+// Allows reports whether the grant allows the request. A scoped grant only allows the requests
+// that carry the attributes of its scope.
+func (g Grant) Allows(req Request) bool {
+	if g.Path != req.Path {
+		return false
+	}
+
+	if g.ElectionID != 0 && g.ElectionID != req.ElectionID {
+		return false
+	}
+
+	if g.RegionID != 0 {
+		for _, regionID := range req.Regions {
+			if regionID == g.RegionID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// String encodes the grant as its path, followed by the election and the region when it is scoped.
+// Paths hold exactly one space, so the encoding can be split on spaces.
+func (g Grant) String() string {
+	if !g.Scoped() {
+		return g.Path
+	}
+	return g.Path + " " + strconv.FormatInt(g.ElectionID, 10) + " " + strconv.FormatInt(g.RegionID, 10)
+}
+
+// Parse decodes a grant encoded by Grant.String
+func Parse(s string) (Grant, error) {
+	fields := strings.Split(s, " ")
+	switch len(fields) {
+	case 2:
+		return Grant{Path: s}, nil
+	case 4:
+		electionID, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return Grant{}, ErrInvalidGrant
+		}
+		regionID, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return Grant{}, ErrInvalidGrant
+		}
+		return Grant{Path: fields[0] + " " + fields[1], ElectionID: electionID, RegionID: regionID}, nil
+	default:
+		return Grant{}, ErrInvalidGrant
+	}
+}
+
+// Request is the access path of a request with the scope attributes read from its route params
+type Request struct {
+	Path string
+	// ElectionID is the election of an /elections/:id route, 0 for the other routes
+	ElectionID int64
+	// Regions holds the region the request targets followed by every region above it, nil when it targets no region
+	Regions []int64
+}
+
+// Allow reports whether one of the grants allows the request
+func Allow(grants []Grant, req Request) bool {
+	for _, grant := range grants {
+		if grant.Allows(req) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package policy
+
+import "testing"
+
+func TestAllow(t *testing.T) {
+	const tallyForm = "PUT /elections/:id/tally-forms/:polling_station_id"
+	// the village 5 lies in the subdistrict 4, the regency 3, the province 2 and the nation 1
+	village := []int64{5, 4, 3, 2, 1}
+	otherRegency := []int64{9, 8, 7, 2, 1}
+
+	officer := []Grant{{Path: tallyForm, ElectionID: 10, RegionID: 3}}
+	cases := []struct {
+		name   string
+		grants []Grant
+		req    Request
+		want   bool
+	}{
+		{"global grant", []Grant{{Path: tallyForm}}, Request{Path: tallyForm, ElectionID: 11, Regions: otherRegency}, true},
+		{"other path", []Grant{{Path: "GET /users"}}, Request{Path: tallyForm, ElectionID: 10, Regions: village}, false},
+		{"no grants", nil, Request{Path: tallyForm}, false},
+		{"officer in the regency", officer, Request{Path: tallyForm, ElectionID: 10, Regions: village}, true},
+		{"officer in the other regency", officer, Request{Path: tallyForm, ElectionID: 10, Regions: otherRegency}, false},
+		{"officer in the other election", officer, Request{Path: tallyForm, ElectionID: 11, Regions: village}, false},
+		{"officer without region", officer, Request{Path: tallyForm, ElectionID: 10}, false},
+		{"officer above the regency", officer, Request{Path: tallyForm, ElectionID: 10, Regions: []int64{2, 1}}, false},
+		{"election scope only", []Grant{{Path: tallyForm, ElectionID: 10}}, Request{Path: tallyForm, ElectionID: 10, Regions: otherRegency}, true},
+		{"region scope only", []Grant{{Path: tallyForm, RegionID: 3}}, Request{Path: tallyForm, ElectionID: 11, Regions: village}, true},
+		{"one of many grants", append([]Grant{{Path: tallyForm, RegionID: 7}}, officer...), Request{Path: tallyForm, ElectionID: 10, Regions: village}, true},
+	}
+	for _, c := range cases {
+		if got := Allow(c.grants, c.req); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, grant := range []Grant{
+		{Path: "GET /users/:id"},
+		{Path: "PUT /elections/:id/tally-forms/:polling_station_id", ElectionID: 10, RegionID: 3},
+		{Path: "PII /voters", RegionID: 3},
+	} {
+		parsed, err := Parse(grant.String())
+		if err != nil || parsed != grant {
+			t.Errorf("%q parsed to %+v, %v", grant.String(), parsed, err)
+		}
+	}
+
+	for _, s := range []string{"", "GET", "GET /users 1", "GET /users a 1"} {
+		if _, err := Parse(s); err != ErrInvalidGrant {
+			t.Errorf("%q: got %v, want ErrInvalidGrant", s, err)
+		}
+	}
+}
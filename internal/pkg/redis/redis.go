@@ -68,6 +68,133 @@ func (c *Cache) Get(ctx context.Context, key string) (interface{}, bool) {
 	return s, true
 }
 
+// SetNX stores the value with the ttl only when the key does not exist yet, and reports whether it was stored
+func (c *Cache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, apqPrefix+key, value, ttl).Result()
+}
+
+// setMembersIfScript replaces the set at KEYS[1] only when every counter in the other keys still holds the value
+// read before, ARGV[1] is the ttl in milliseconds, then come the values of the counters and the members
+var setMembersIfScript = redis.NewScript(`
+for i = 2, #KEYS do
+	if (redis.call("GET", KEYS[i]) or "") ~= ARGV[i] then
+		return 0
+	end
+end
+redis.call("DEL", KEYS[1])
+redis.call("SADD", KEYS[1], unpack(ARGV, #KEYS + 1))
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return 1`)
+
+// SetMembersIf replaces the set stored at key with the members when the counters still hold the values read by
+// Counters, and reports whether the set was stored. The set expires after ttl and always holds the empty member
+// too, so a cached empty set is told apart from a missing one.
+func (c *Cache) SetMembersIf(ctx context.Context, key string, members []string, ttl time.Duration, counters map[string]string) (bool, error) {
+	keys := []string{apqPrefix + key}
+	args := []interface{}{ttl.Milliseconds()}
+	for counter, value := range counters {
+		keys = append(keys, apqPrefix+counter)
+		args = append(args, value)
+	}
+	args = append(args, "")
+	for _, member := range members {
+		args = append(args, member)
+	}
+
+	stored, err := setMembersIfScript.Run(ctx, c.client, keys, args...).Int()
+	if err != nil {
+		return false, err
+	}
+	return stored == 1, nil
+}
+
+// Counters returns the values of the counters stored at keys, a missing counter reads as the empty string
+func (c *Cache) Counters(ctx context.Context, keys ...string) (map[string]string, error) {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, apqPrefix+key)
+	}
+
+	values, err := c.client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]string, len(keys))
+	for i, key := range keys {
+		value, _ := values[i].(string)
+		counters[key] = value
+	}
+	return counters, nil
+}
+
+// Bump increments the counters stored at keys, a missing counter starts from 0
+func (c *Cache) Bump(ctx context.Context, keys ...string) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Incr(ctx, apqPrefix+key)
+		}
+		return nil
+	})
+	return err
+}
+
+// IsMember reports whether the member is in the set stored at key, and whether the set exists at all
+func (c *Cache) IsMember(ctx context.Context, key string, member string) (bool, bool) {
+	var exists *redis.IntCmd
+	var isMember *redis.BoolCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(ctx, apqPrefix+key)
+		isMember = pipe.SIsMember(ctx, apqPrefix+key, member)
+		return nil
+	})
+	if err != nil {
+		return false, false
+	}
+	return isMember.Val(), exists.Val() == 1
+}
+
+// Members returns the members of the set stored at key, and whether the set exists at all
+func (c *Cache) Members(ctx context.Context, key string) ([]string, bool) {
+	members, err := c.client.SMembers(ctx, apqPrefix+key).Result()
+	if err != nil || len(members) == 0 {
+		return nil, false
+	}
+	return members, true
+}
+
+// lockScript takes the lock when it is free and extends it when the owner already holds it
+var lockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`)
+
+// unlockScript releases the lock only when the owner holds it, a lock that expired and was taken over is kept
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lock takes the lock for the owner or extends it when the owner holds it already, and reports whether the owner holds it.
+// The lock expires after ttl unless it is extended, so a crashed owner hands it over to the next one.
+func (c *Cache) Lock(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	held, err := lockScript.Run(ctx, c.client, []string{apqPrefix + key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// Unlock releases the lock when the owner holds it
+func (c *Cache) Unlock(ctx context.Context, key string, owner string) error {
+	return unlockScript.Run(ctx, c.client, []string{apqPrefix + key}, owner).Err()
+}
+
 // DeleteByPrefix cache
 func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) error {
 	var err error
@@ -86,3 +213,50 @@ func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) error {
 func (c *Cache) Del(ctx context.Context, keys ...string) error {
 	return c.client.Del(ctx, keys...).Err()
 }
+
+// Publish message to a pub/sub channel, every instance subscribed to the channel receives it
+func (c *Cache) Publish(ctx context.Context, channel string, message interface{}) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+// Message received from a pub/sub channel
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// Subscription to the pub/sub channels matching a pattern
+type Subscription struct {
+	pubsub   *redis.PubSub
+	messages chan Message
+}
+
+// PSubscribe subscribes to the pub/sub channels matching the pattern. The subscription is confirmed before it is returned,
+// so no message published afterwards is missed.
+func (c *Cache) PSubscribe(ctx context.Context, pattern string) (*Subscription, error) {
+	pubsub := c.client.PSubscribe(ctx, pattern)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("could not subscribe to %s: %w", pattern, err)
+	}
+
+	s := &Subscription{pubsub: pubsub, messages: make(chan Message)}
+	go func() {
+		defer close(s.messages)
+		for msg := range pubsub.Channel() {
+			s.messages <- Message{Channel: msg.Channel, Payload: msg.Payload}
+		}
+	}()
+
+	return s, nil
+}
+
+// Channel returns the received messages. The channel is closed after Close.
+func (s *Subscription) Channel() <-chan Message {
+	return s.messages
+}
+
+// Close the subscription
+func (s *Subscription) Close() error {
+	return s.pubsub.Close()
+}
@@ -0,0 +1,158 @@
+package rla
+
+import "math"
+
+// Contest is the reported outcome under audit. Votes are the reported totals of every candidate,
+// each winner must beat each loser.
+type Contest struct {
+	Votes   map[int64]int64
+	Winners []int64
+	Losers  []int64
+}
+
+// Pair is a reported winner and loser with the risk measure of the pair: the p-value of the hypothesis that the
+// loser actually beat the winner. The outcome is confirmed when the measure of every pair is at most the risk limit.
+type Pair struct {
+	Winner  int64
+	Loser   int64
+	Measure float64
+}
+
+// Measure is the risk measure of the contest, the largest measure of its pairs
+func Measure(pairs []Pair) float64 {
+	measure := 0.0
+	for _, pair := range pairs {
+		measure = math.Max(measure, pair.Measure)
+	}
+	if len(pairs) == 0 {
+		return 1
+	}
+	return measure
+}
+
+// Bravo is the ballot-polling audit of Lindeman, Stark and Yates. Every ballot holds the candidates marked on it,
+// in draw order with a ballot drawn twice appearing twice. For a pair with reported share s = Vw / (Vw + Vl),
+// a ballot for the winner and not the loser multiplies the statistic by 2s and a ballot for the loser and not the
+// winner by 2(1 - s). The measure is 1/T, capped at 1.
+func Bravo(contest Contest, ballots [][]int64) []Pair {
+	pairs, _ := BravoStop(contest, ballots, 0)
+	return pairs
+}
+
+// BravoStop is Bravo as a sequential test: the ballots are read in draw order and the audit stops at the first
+// ballot where the measure of the contest is at most the risk limit. It returns the pairs at that ballot and the
+// number of ballots read, all of them when the risk limit is not met.
+func BravoStop(contest Contest, ballots [][]int64, riskLimit float64) ([]Pair, int) {
+	pairs := make([]Pair, 0, len(contest.Winners)*len(contest.Losers))
+	shares := make([]float64, 0, cap(pairs))
+	for _, winner := range contest.Winners {
+		for _, loser := range contest.Losers {
+			vw, vl := contest.Votes[winner], contest.Votes[loser]
+			pairs = append(pairs, Pair{Winner: winner, Loser: loser, Measure: 1})
+			// a tie can not be confirmed by sampling, its share stays 0 and its measure 1
+			share := 0.0
+			if vw > vl {
+				share = float64(vw) / float64(vw+vl)
+			}
+			shares = append(shares, share)
+		}
+	}
+
+	logT := make([]float64, len(pairs))
+	for n, ballot := range ballots {
+		for i := range pairs {
+			if shares[i] == 0 {
+				continue
+			}
+			forWinner, forLoser := marked(ballot, pairs[i].Winner), marked(ballot, pairs[i].Loser)
+			switch {
+			case forWinner && !forLoser:
+				logT[i] += math.Log(2 * shares[i])
+			case forLoser && !forWinner:
+				logT[i] += math.Log(2 * (1 - shares[i]))
+			}
+			pairs[i].Measure = math.Min(1, math.Exp(-logT[i]))
+		}
+		if len(pairs) > 0 && Measure(pairs) <= riskLimit {
+			return pairs, n + 1
+		}
+	}
+	return pairs, len(ballots)
+}
+
+func marked(ballot []int64, candidate int64) bool {
+	for _, c := range ballot {
+		if c == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch is a TPS batch: the reported votes of its tally form and the number of ballots in it
+type Batch struct {
+	Votes   map[int64]int64
+	Ballots int64
+}
+
+// ErrorBound is the largest overstatement of any margin the batch can hold, as a fraction of the margin:
+// u = max over pairs (vw - vl + b) / (Vw - Vl). A tied pair gives +Inf, the outcome can not be confirmed then.
+func ErrorBound(contest Contest, batch Batch) float64 {
+	bound := 0.0
+	for _, winner := range contest.Winners {
+		for _, loser := range contest.Losers {
+			margin := contest.Votes[winner] - contest.Votes[loser]
+			if margin <= 0 {
+				return math.Inf(1)
+			}
+			bound = math.Max(bound, float64(batch.Votes[winner]-batch.Votes[loser]+batch.Ballots)/float64(margin))
+		}
+	}
+	return bound
+}
+
+// Overstatement is the largest overstatement of any margin found by the audit of the batch, as a fraction of the
+// margin: e = max over pairs ((vw - vl) - (aw - al)) / (Vw - Vl). Audited are the votes counted by hand.
+func Overstatement(contest Contest, batch Batch, audited map[int64]int64) float64 {
+	overstatement := math.Inf(-1)
+	for _, winner := range contest.Winners {
+		for _, loser := range contest.Losers {
+			margin := contest.Votes[winner] - contest.Votes[loser]
+			if margin <= 0 {
+				return math.Inf(1)
+			}
+			reported := batch.Votes[winner] - batch.Votes[loser]
+			actual := audited[winner] - audited[loser]
+			overstatement = math.Max(overstatement, float64(reported-actual)/float64(margin))
+		}
+	}
+	return overstatement
+}
+
+// KaplanMarkov is the risk measure of a batch-level comparison audit (MACRO) with batches drawn with probability
+// proportional to their error bound (PPEB). U is the total error bound, and for every draw in order taint is
+// e / u of the drawn batch: P = product of (1 - 1/U) / (1 - taint). The measure is capped at 1.
+func KaplanMarkov(totalBound float64, taints []float64) float64 {
+	measure, _ := KaplanMarkovStop(totalBound, taints, 0)
+	return measure
+}
+
+// KaplanMarkovStop is KaplanMarkov as a sequential test: it stops at the first draw where the measure is at most
+// the risk limit, and returns the measure there and the number of draws read.
+func KaplanMarkovStop(totalBound float64, taints []float64, riskLimit float64) (float64, int) {
+	if math.IsInf(totalBound, 1) || totalBound <= 1 {
+		return 1, len(taints)
+	}
+	logP := 0.0
+	for n, taint := range taints {
+		if taint >= 1 {
+			// the batch may hold the whole margin, no later draw can confirm the outcome
+			return 1, len(taints)
+		}
+		logP += math.Log1p(-1/totalBound) - math.Log1p(-taint)
+		if measure := math.Min(1, math.Exp(logP)); measure <= riskLimit {
+			return measure, n + 1
+		}
+	}
+	return math.Min(1, math.Exp(logP)), len(taints)
+}
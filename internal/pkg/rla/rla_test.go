@@ -0,0 +1,95 @@
+package rla
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	// values of int(sha256(seed + "," + str(k)).hexdigest(), 16) % 1000 in Python, as in Rivest's sampler.py
+	sampler := Sampler{Seed: "32705488315927640215"}
+	for k, want := range []int64{640, 289, 880, 162, 308} {
+		if got := sampler.Index(int64(k+1), 1000); got != want {
+			t.Errorf("draw %d: got %d, want %d", k+1, got, want)
+		}
+	}
+
+	weights := []float64{0, 3, 0, 1}
+	counts := make([]int, len(weights))
+	for k := int64(1); k <= 4000; k++ {
+		i, err := sampler.Weighted(k, weights)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[i]++
+	}
+	if counts[0] != 0 || counts[2] != 0 || counts[1] < 2800 || counts[1] > 3200 {
+		t.Errorf("weighted draws do not follow the weights: %v", counts)
+	}
+	if _, err := sampler.Weighted(1, []float64{0, 0}); err != ErrNoWeight {
+		t.Errorf("draw without weight: %v", err)
+	}
+}
+
+func TestBravo(t *testing.T) {
+	contest := Contest{Votes: map[int64]int64{1: 600, 2: 400}, Winners: []int64{1}, Losers: []int64{2}}
+
+	// every ballot for the winner multiplies T by 1.2, 20 is reached after 17 ballots
+	var ballots [][]int64
+	for i := 0; i < 16; i++ {
+		ballots = append(ballots, []int64{1})
+	}
+	if measure := Measure(Bravo(contest, ballots)); measure <= 0.05 {
+		t.Errorf("16 ballots give measure %f, want above 0.05", measure)
+	}
+	ballots = append(ballots, []int64{1})
+	if measure := Measure(Bravo(contest, ballots)); measure > 0.05 {
+		t.Errorf("17 ballots give measure %f, want at most 0.05", measure)
+	}
+
+	// ballots for the loser raise the measure, invalid ballots leave it
+	withLoser := append([][]int64{{2}, {2}, {}}, ballots...)
+	if Measure(Bravo(contest, withLoser)) <= Measure(Bravo(contest, ballots)) {
+		t.Error("ballots for the loser do not raise the measure")
+	}
+
+	// the audit stops at the 17th ballot, a loser ballot drawn later does not undo it
+	if _, n := BravoStop(contest, append(ballots, []int64{2}, []int64{2}), 0.05); n != 17 {
+		t.Errorf("audit stops after %d ballots, want 17", n)
+	}
+
+	tie := Contest{Votes: map[int64]int64{1: 500, 2: 500}, Winners: []int64{1}, Losers: []int64{2}}
+	if measure := Measure(Bravo(tie, ballots)); measure != 1 {
+		t.Errorf("tie gives measure %f, want 1", measure)
+	}
+}
+
+func TestKaplanMarkov(t *testing.T) {
+	contest := Contest{Votes: map[int64]int64{1: 600, 2: 400}, Winners: []int64{1}, Losers: []int64{2}}
+	batch := Batch{Votes: map[int64]int64{1: 60, 2: 30}, Ballots: 100}
+
+	// (60 - 30 + 100) / 200
+	if bound := ErrorBound(contest, batch); bound != 0.65 {
+		t.Errorf("error bound %f, want 0.65", bound)
+	}
+	// the hand count moved 10 votes from the winner to the loser: (30 - 10) / 200
+	if e := Overstatement(contest, batch, map[int64]int64{1: 50, 2: 40}); math.Abs(e-0.1) > 1e-12 {
+		t.Errorf("overstatement %f, want 0.1", e)
+	}
+
+	// without errors every draw multiplies P by 1 - 1/U = 0.9, 0.05 is reached after 29 draws
+	taints := make([]float64, 28)
+	if measure := KaplanMarkov(10, taints); measure <= 0.05 {
+		t.Errorf("28 clean draws give measure %f, want above 0.05", measure)
+	}
+	taints = append(taints, 0)
+	if measure := KaplanMarkov(10, taints); measure > 0.05 {
+		t.Errorf("29 clean draws give measure %f, want at most 0.05", measure)
+	}
+	if _, n := KaplanMarkovStop(10, append(taints, 0.5, 0.5), 0.05); n != 29 {
+		t.Errorf("audit stops after %d draws, want 29", n)
+	}
+	if measure := KaplanMarkov(10, append(taints, 1)); measure != 1 {
+		t.Errorf("a batch with the largest possible error gives measure %f, want 1", measure)
+	}
+}
@@ -0,0 +1,56 @@
+package rla
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+// ErrNoWeight is returned when a weighted draw is made from batches that can not hold any error
+var ErrNoWeight = errors.New("no batch can be drawn")
+
+// Sampler is the pseudo-random sampler of Rivest's sampler.py. Draw k takes the SHA-256 of the seed, a comma and k
+// in decimal, read as a big-endian number. Anyone with the public seed can repeat every draw of the audit.
+type Sampler struct {
+	Seed string
+}
+
+func (s Sampler) hash(k int64) *big.Int {
+	sum := sha256.Sum256([]byte(s.Seed + "," + strconv.FormatInt(k, 10)))
+	return new(big.Int).SetBytes(sum[:])
+}
+
+// Index returns the item of draw k among n items, from 0 to n - 1: hash mod n
+func (s Sampler) Index(k int64, n int64) int64 {
+	return new(big.Int).Mod(s.hash(k), big.NewInt(n)).Int64()
+}
+
+// Weighted returns the item of draw k, drawn with probability proportional to its weight.
+// The hash is read as a fraction of 2^256 and located in the cumulative weights.
+func (s Sampler) Weighted(k int64, weights []float64) (int, error) {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return 0, ErrNoWeight
+	}
+
+	u, _ := new(big.Float).Quo(new(big.Float).SetInt(s.hash(k)), new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 256))).Float64()
+	target := u * total
+	var cumulative float64
+	last := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		cumulative += w
+		last = i
+		if target < cumulative {
+			return i, nil
+		}
+	}
+	// rounding can leave the target just above the sum of the weights
+	return last, nil
+}
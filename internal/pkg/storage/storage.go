@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// DefaultMaxSize is the upload limit when UPLOAD_MAX_SIZE is not set
+const DefaultMaxSize = 10 << 20
+
+var (
+	// ErrNotFound is returned when no object is stored under the key
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned when a key is not a hex encoded SHA-256 checksum
+	ErrInvalidKey = errors.New("key must be a hex encoded sha256 checksum")
+	// ErrTooLarge is returned when an upload is bigger than the size limit
+	ErrTooLarge = errors.New("file is too large")
+)
+
+// Storage keeps objects addressed by the SHA-256 checksum of their content.
+// The same content is always stored under the same key, so storing it twice is a no-op.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.ReadSeeker, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// New creates the storage configured with STORAGE_DRIVER, local (the default) or s3
+func New() (Storage, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if len(dir) == 0 {
+			dir = "storage"
+		}
+		return &Local{Dir: dir}, nil
+	case "s3":
+		return &S3{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", os.Getenv("STORAGE_DRIVER"))
+	}
+}
+
+// MaxSize returns the upload limit in bytes from UPLOAD_MAX_SIZE
+func MaxSize() int64 {
+	size, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE"), 10, 64)
+	if err != nil || size <= 0 {
+		return DefaultMaxSize
+	}
+	return size
+}
+
+// Upload is a file spooled to a temporary file together with its checksum, size and sniffed content type
+type Upload struct {
+	File        *os.File
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// Spool copies r to a temporary file while the SHA-256 checksum is calculated, the same way migration files are checksummed.
+// The content type is sniffed from the content, the type claimed by the client is not trusted.
+// Close removes the temporary file.
+func Spool(r io.Reader, maxSize int64) (*Upload, error) {
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	upload := &Upload{File: file}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(r, maxSize+1))
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+	if size > maxSize {
+		upload.Close()
+		return nil, ErrTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		upload.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		upload.Close()
+		return nil, err
+	}
+
+	upload.Key = hex.EncodeToString(hash.Sum(nil))
+	upload.Size = size
+	upload.ContentType = http.DetectContentType(head[:n])
+	return upload, nil
+}
+
+// Close closes and removes the temporary file
+func (u *Upload) Close() error {
+	u.File.Close()
+	return os.Remove(u.File.Name())
+}
+
+// ValidKey reports whether key is a hex encoded SHA-256 checksum
+func ValidKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
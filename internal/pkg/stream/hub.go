@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"backend-election/internal/pkg/redis"
+	"context"
+	"errors"
+	"sync"
+)
+
+// listenerBuffer is the number of messages a listener may fall behind before it is dropped
+const listenerBuffer = 64
+
+// Hub fans the messages of one Redis pattern subscription out to the listeners of this instance.
+// Every instance runs its own hub, so a message published by any instance reaches the listeners of all of them.
+type Hub struct {
+	mu        sync.Mutex
+	sub       *redis.Subscription
+	listeners map[string]map[chan string]struct{}
+	closed    bool
+}
+
+// NewHub subscribes to the channels matching the pattern and starts the fan-out
+func NewHub(ctx context.Context, cache *redis.Cache, pattern string) (*Hub, error) {
+	if cache == nil {
+		return nil, errors.New("redis is not connected")
+	}
+
+	sub, err := cache.PSubscribe(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Hub{sub: sub, listeners: make(map[string]map[chan string]struct{})}
+	go func() {
+		for msg := range sub.Channel() {
+			h.dispatch(msg)
+		}
+	}()
+
+	return h, nil
+}
+
+// Listen returns the messages of the channel until stop is called. The returned channel is closed when the listener
+// falls too far behind or the hub is closed, the listener should then tell its client to reconnect.
+func (h *Hub) Listen(channel string) (<-chan string, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	messages := make(chan string, listenerBuffer)
+	if h.closed {
+		close(messages)
+		return messages, func() {}
+	}
+
+	if h.listeners[channel] == nil {
+		h.listeners[channel] = make(map[chan string]struct{})
+	}
+	h.listeners[channel][messages] = struct{}{}
+
+	stop := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(channel, messages)
+	}
+	return messages, stop
+}
+
+// Close ends every listener and the subscription. It is registered with http.Server.RegisterOnShutdown,
+// so open streams end cleanly instead of holding the shutdown until it times out.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	for channel, listeners := range h.listeners {
+		for messages := range listeners {
+			h.remove(channel, messages)
+		}
+	}
+	h.mu.Unlock()
+
+	h.sub.Close()
+}
+
+func (h *Hub) dispatch(msg redis.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for messages := range h.listeners[msg.Channel] {
+		select {
+		case messages <- msg.Payload:
+		default:
+			// a slow listener would hold up the others
+			h.remove(msg.Channel, messages)
+		}
+	}
+}
+
+// remove closes the listener, the caller holds the lock
+func (h *Hub) remove(channel string, messages chan string) {
+	if _, ok := h.listeners[channel][messages]; !ok {
+		return
+	}
+
+	delete(h.listeners[channel], messages)
+	if len(h.listeners[channel]) == 0 {
+		delete(h.listeners, channel)
+	}
+	close(messages)
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// ErrAccessTaken is returned when another access already uses the name or the path
+var ErrAccessTaken = errors.New("access name or path is already used")
+
+const accessColumns = `access.id, access.name, access.path`
+
+type AccessRepository struct {
+	Db           *sql.DB
+	Log          *logger.Logger
+	AccessEntity model.Access
+}
+
+func scanAccess(row interface{ Scan(...interface{}) error }, access *model.Access) error {
+	return row.Scan(&access.ID, &access.Name, &access.Path)
+}
+
+func (r *AccessRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + accessColumns + ` FROM access WHERE id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	if err := scanAccess(stmt.QueryRowContext(ctx, r.AccessEntity.ID), &r.AccessEntity); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// FindByPath finds the access of the path
+func (r *AccessRepository) FindByPath(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + accessColumns + ` FROM access WHERE path = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	if err := scanAccess(stmt.QueryRowContext(ctx, r.AccessEntity.Path), &r.AccessEntity); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// List returns every access ordered by path
+func (r *AccessRepository) List(ctx context.Context) ([]model.Access, error) {
+	var list []model.Access = make([]model.Access, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	return r.query(ctx, `SELECT `+accessColumns+` FROM access ORDER BY access.path`)
+}
+
+func (r *AccessRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `INSERT INTO access (name, path) VALUES ($1, $2) RETURNING ` + accessColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanAccess(stmt.QueryRowContext(ctx, r.AccessEntity.Name, r.AccessEntity.Path), &r.AccessEntity)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrAccessTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+func (r *AccessRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `UPDATE access SET name = $1, path = $2 WHERE id = $3 RETURNING ` + accessColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanAccess(stmt.QueryRowContext(ctx, r.AccessEntity.Name, r.AccessEntity.Path, r.AccessEntity.ID), &r.AccessEntity)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrAccessTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete removes the access and revokes it from every role
+func (r *AccessRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM access_roles WHERE access_id = $1`, r.AccessEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM access WHERE id = $1`, r.AccessEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Sync upserts the access declared by the routes by path and reports the access no route declares.
+// A new access is granted to every superuser role, as the seeds grant every route to the superuser role.
+func (r *AccessRepository) Sync(ctx context.Context, declared []model.Access) (model.AccessSync, error) {
+	var sync = model.AccessSync{Added: make([]model.Access, 0), Renamed: make([]model.Access, 0), Orphaned: make([]model.Access, 0)}
+	switch ctx.Err() {
+	case context.Canceled:
+		return sync, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return sync, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return sync, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	// xmax is 0 for a row the statement inserted, unchanged rows are not returned at all
+	const upsert = `
+		INSERT INTO access (name, path) VALUES ($1, $2)
+		ON CONFLICT (path) DO UPDATE SET name = EXCLUDED.name WHERE access.name <> EXCLUDED.name
+		RETURNING ` + accessColumns + `, xmax = 0`
+	paths := make([]string, 0, len(declared))
+	for _, access := range declared {
+		paths = append(paths, access.Path)
+
+		var inserted bool
+		err := tx.QueryRowContext(ctx, upsert, access.Name, access.Path).Scan(&access.ID, &access.Name, &access.Path, &inserted)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if isUniqueViolation(err) {
+			return sync, r.Log.Error(fmt.Errorf("%w: %s", ErrAccessTaken, access.Name))
+		}
+		if err != nil {
+			return sync, r.Log.Error(err)
+		}
+
+		if !inserted {
+			sync.Renamed = append(sync.Renamed, access)
+			continue
+		}
+		sync.Added = append(sync.Added, access)
+		_, err = tx.ExecContext(ctx, `INSERT INTO access_roles (access_id, role_id) SELECT $1::int8, id FROM roles WHERE is_superuser`, access.ID)
+		if err != nil {
+			return sync, r.Log.Error(err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+accessColumns+` FROM access WHERE path <> ALL($1) ORDER BY access.path`, pq.Array(paths))
+	if err != nil {
+		return sync, r.Log.Error(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var access model.Access
+		if err := scanAccess(rows, &access); err != nil {
+			return sync, r.Log.Error(err)
+		}
+		sync.Orphaned = append(sync.Orphaned, access)
+	}
+	if rows.Err() != nil {
+		return sync, r.Log.Error(rows.Err())
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return sync, r.Log.Error(err)
+	}
+
+	return sync, nil
+}
+
+func (r *AccessRepository) query(ctx context.Context, q string, args ...interface{}) ([]model.Access, error) {
+	var list []model.Access = make([]model.Access, 0)
+
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var access model.Access
+		if err = scanAccess(rows, &access); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, access)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
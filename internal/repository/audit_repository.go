@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/logger"
+)
+
+type AuditRepository struct {
+	Db  *sql.DB
+	Log *logger.Logger
+}
+
+// Append chains the record after the last record of the audit log. The table is locked for writes,
+// so two requests finishing at the same time are chained one after the other.
+func (r *AuditRepository) Append(ctx context.Context, record audit.Record) (audit.Entry, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return audit.Entry{}, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return audit.Entry{}, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE audit_logs IN EXCLUSIVE MODE`); err != nil {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+
+	prev := audit.Genesis()
+	err = tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_logs ORDER BY seq DESC LIMIT 1`).Scan(&record.Seq, &prev)
+	if err != nil && err != sql.ErrNoRows {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+
+	record.Seq++
+	createdAt := time.Now().UTC()
+	record.CreatedAt = createdAt.Format(time.RFC3339Nano)
+	entry, err := audit.Chain(prev, record)
+	if err != nil {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_logs (seq, actor_id, method, path, status_code, payload, prev_hash, hash, created_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9)`,
+		entry.Seq, record.ActorID, record.Method, record.Path, record.StatusCode, entry.Payload, entry.PrevHash, entry.Hash, createdAt,
+	)
+	if err != nil {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return audit.Entry{}, r.Log.Error(err)
+	}
+	return entry, nil
+}
+
+// Entries returns at most limit records of the audit log starting at seq from
+func (r *AuditRepository) Entries(ctx context.Context, from int64, limit int) ([]audit.Entry, error) {
+	var list []audit.Entry = make([]audit.Entry, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT seq, payload, prev_hash, hash FROM audit_logs WHERE seq >= $1 ORDER BY seq LIMIT $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, from, limit)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry audit.Entry
+		if err := rows.Scan(&entry.Seq, &entry.Payload, &entry.PrevHash, &entry.Hash); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, entry)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
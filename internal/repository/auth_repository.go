@@ -1,46 +1,168 @@
 package repository
 
 import (
+	"backend-election/internal/model"
 	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/policy"
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+// scopeRegions pairs every region a role is scoped to with itself and each region above it
+const scopeRegions = `
+	scope_regions (region_id, above_id) AS (
+		SELECT id, id FROM regions WHERE id IN (SELECT region_id FROM roles)
+		UNION
+		SELECT scope_regions.region_id, regions.parent_id
+		FROM scope_regions
+		JOIN regions ON scope_regions.above_id = regions.id
+		WHERE regions.parent_id IS NOT NULL
+	)`
+
+// inheritedScope walks from the roles in effective to their parents. A parent role gets the narrower of its own
+// scope and the scope walked so far, a parent scoped outside of it, to another election or another branch of the
+// regions, is not inherited.
+const inheritedScope = `
+	COALESCE(effective.election_id, parents.election_id),
+	CASE
+		WHEN effective.region_id IS NULL THEN parents.region_id
+		WHEN parents.region_id IS NULL THEN effective.region_id
+		WHEN (effective.region_id, parents.region_id) IN (SELECT region_id, above_id FROM scope_regions) THEN effective.region_id
+		ELSE parents.region_id
+	END
+	FROM roles_parents
+	JOIN effective ON roles_parents.role_id = effective.role_id
+	JOIN roles parents ON roles_parents.parent_id = parents.id
+	WHERE (effective.election_id IS NULL OR parents.election_id IS NULL OR effective.election_id = parents.election_id)
+	AND (effective.region_id IS NULL OR parents.region_id IS NULL
+		OR (effective.region_id, parents.region_id) IN (SELECT region_id, above_id FROM scope_regions)
+		OR (parents.region_id, effective.region_id) IN (SELECT region_id, above_id FROM scope_regions))`
+
+// effectiveRoles lists the roles of the user $1 with the roles they inherit, each with the scope of inheritedScope.
+// UNION drops the rows already seen, so the recursion ends even on a cycle.
+const effectiveRoles = `
+	WITH RECURSIVE` + scopeRegions + `,
+	effective (role_id, election_id, region_id) AS (
+		SELECT roles.id, roles.election_id, roles.region_id
+		FROM roles_users
+		JOIN roles ON roles_users.role_id = roles.id
+		WHERE roles_users.user_id = $1
+		UNION
+		SELECT roles_parents.parent_id,` + inheritedScope + `
+	)`
+
 type AuthRepository struct {
 	Db  *sql.DB
 	Log *logger.Logger
 }
 
-func (r *AuthRepository) HasAuth(ctx context.Context, path string) (bool, error) {
-	var hasAuth bool = false
+// Grants lists the access paths the roles of the user grant, directly or through the roles they inherit, with the scope they are inherited with
+func (r *AuthRepository) Grants(ctx context.Context, userID int64) ([]policy.Grant, error) {
+	var list []policy.Grant = make([]policy.Grant, 0)
 
 	switch ctx.Err() {
 	case context.Canceled:
-		return hasAuth, r.Log.Error(context.Canceled)
+		return list, r.Log.Error(context.Canceled)
 	case context.DeadlineExceeded:
-		return hasAuth, r.Log.Error(context.DeadlineExceeded)
+		return list, r.Log.Error(context.DeadlineExceeded)
 	default:
 	}
 
+	const q = effectiveRoles + `
+		SELECT DISTINCT access.path, COALESCE(effective.election_id, 0), COALESCE(effective.region_id, 0)
+		FROM effective
+		JOIN access_roles ON effective.role_id = access_roles.role_id
+		JOIN access ON access_roles.access_id = access.id
+		ORDER BY 1, 2, 3`
+
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grant policy.Grant
+		if err := rows.Scan(&grant.Path, &grant.ElectionID, &grant.RegionID); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, grant)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// Inheritance lists every way the roles of the user grant the access path. A grant is the chain of role ids from
+// the role the user holds to the role granted the access, with the scope of inheritedScope.
+func (r *AuthRepository) Inheritance(ctx context.Context, userID int64, path string) ([]model.AccessGrant, error) {
+	var list []model.AccessGrant = make([]model.AccessGrant, 0)
+
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	// chain keeps the roles of the walk, a role already in it is not walked twice
 	const q = `
-		SELECT 1 
-		FROM users 
-		JOIN roles_users ON users.id = roles_users.user_id
-		JOIN roles ON roles_users.role_id = roles.id
-		JOIN access_roles ON roles.id = access_roles.role_id
+		WITH RECURSIVE` + scopeRegions + `,
+		effective (role_id, chain, election_id, region_id) AS (
+			SELECT roles.id, ARRAY[roles.id], roles.election_id, roles.region_id
+			FROM roles_users
+			JOIN roles ON roles_users.role_id = roles.id
+			WHERE roles_users.user_id = $1
+			UNION ALL
+			SELECT roles_parents.parent_id, effective.chain || roles_parents.parent_id,` + inheritedScope + `
+			AND roles_parents.parent_id <> ALL(effective.chain)
+		)
+		SELECT effective.chain, COALESCE(effective.election_id, 0), COALESCE(effective.region_id, 0)
+		FROM effective
+		JOIN access_roles ON effective.role_id = access_roles.role_id
 		JOIN access ON access_roles.access_id = access.id
-		WHERE access.path = $1`
+		WHERE access.path = $2
+		ORDER BY array_length(effective.chain, 1), effective.chain`
 
 	stmt, err := r.Db.PrepareContext(ctx, q)
 	if err != nil {
-		return hasAuth, r.Log.Error(err)
+		return list, r.Log.Error(err)
 	}
 	defer stmt.Close()
 
-	err = stmt.QueryRowContext(ctx, path).Scan(&hasAuth)
+	rows, err := stmt.QueryContext(ctx, userID, path)
 	if err != nil {
-		return hasAuth, r.Log.Error(err)
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grant model.AccessGrant
+		var chain pq.Int64Array
+		if err := rows.Scan(&chain, &grant.ElectionID, &grant.RegionID); err != nil {
+			return list, r.Log.Error(err)
+		}
+		for _, roleID := range chain {
+			grant.Roles = append(grant.Roles, model.Role{ID: roleID})
+		}
+		list = append(list, grant)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
 	}
 
-	return hasAuth, nil
+	return list, nil
 }
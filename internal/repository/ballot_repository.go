@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrElectionNotOpen is returned when a ballot is cast or a credential is issued while the election is not open
+	ErrElectionNotOpen = errors.New("election is not open")
+	// ErrCredentialUsed is returned when a ballot is cast with a credential token that was already used
+	ErrCredentialUsed = errors.New("credential has already been used in the election")
+)
+
+type BallotRepository struct {
+	Db           *sql.DB
+	Log          *logger.Logger
+	BallotEntity model.Ballot
+}
+
+// Cast stores the ballot and spends the credential token of the ballot in one transaction.
+// The election row is share locked so the election can not be closed while the ballot is written,
+// and the primary key of spent_credentials makes a second ballot with the same token fail,
+// even when both requests run at the same time. The voter is not known here, only the hash of the token.
+// Every ledgerBatchSize ballots of the election are appended to the ledger in the same transaction.
+func (r *BallotRepository) Cast(ctx context.Context, tokenHash []byte) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT status FROM elections WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		r.BallotEntity.ElectionID,
+	).Scan(&status)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if status != model.ElectionStatusOpen {
+		return r.Log.Error(ErrElectionNotOpen)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO spent_credentials (election_id, token_hash) VALUES ($1, $2)`,
+		r.BallotEntity.ElectionID, tokenHash,
+	)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrCredentialUsed)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return r.Log.Error(err)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO ballots (election_id, choices, ciphertexts, receipt_hash, salt) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		r.BallotEntity.ElectionID, pq.Array(r.BallotEntity.Choices), pq.ByteaArray(r.BallotEntity.Ciphertexts), r.BallotEntity.ReceiptHash, salt,
+	).Scan(&r.BallotEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := appendBallots(ctx, tx, r.BallotEntity.ElectionID, ledgerBatchSize); err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// FindByReceipt finds the ballot of the receipt hash, without its choices
+func (r *BallotRepository) FindByReceipt(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, election_id, COALESCE(ledger_height, 0) FROM ballots WHERE receipt_hash = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.BallotEntity.ReceiptHash).Scan(&r.BallotEntity.ID, &r.BallotEntity.ElectionID, &r.BallotEntity.LedgerHeight)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// ListChoices returns the choices of every ballot cast in the election
+func (r *BallotRepository) ListChoices(ctx context.Context) ([][]int64, error) {
+	var list [][]int64 = make([][]int64, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT choices FROM ballots WHERE election_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.BallotEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var choices []int64
+		if err = rows.Scan(pq.Array(&choices)); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, choices)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// ListCiphertexts returns the ciphertexts of every encrypted ballot cast in the election
+func (r *BallotRepository) ListCiphertexts(ctx context.Context) ([][][]byte, error) {
+	var list [][][]byte = make([][][]byte, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ciphertexts FROM ballots WHERE election_id = $1 AND ciphertexts IS NOT NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.BallotEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ciphertexts pq.ByteaArray
+		if err = rows.Scan(&ciphertexts); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, ciphertexts)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
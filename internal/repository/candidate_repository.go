@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+// ErrBallotNumberTaken is returned when the ballot number is already used by another candidate of the election
+var ErrBallotNumberTaken = errors.New("ballot number is already used")
+
+type CandidateRepository struct {
+	Db              *sql.DB
+	Log             *logger.Logger
+	CandidateEntity model.Candidate
+}
+
+func (r *CandidateRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT id, election_id, ballot_number, name, COALESCE(running_mate_name, ''), COALESCE(party, '')
+		FROM candidates
+		WHERE id = $1 AND election_id = $2 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.CandidateEntity.ID, r.CandidateEntity.ElectionID).Scan(
+		&r.CandidateEntity.ID,
+		&r.CandidateEntity.ElectionID,
+		&r.CandidateEntity.BallotNumber,
+		&r.CandidateEntity.Name,
+		&r.CandidateEntity.RunningMateName,
+		&r.CandidateEntity.Party,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save registers the candidate. The insert only happens while the parent election is in draft state.
+func (r *CandidateRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO candidates (election_id, ballot_number, name, running_mate_name, party, created_by)
+		SELECT id, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6 FROM elections
+		WHERE id = $1 AND status = $7 AND deleted_at IS NULL
+		RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.CandidateEntity.ElectionID,
+		r.CandidateEntity.BallotNumber,
+		r.CandidateEntity.Name,
+		r.CandidateEntity.RunningMateName,
+		r.CandidateEntity.Party,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		model.ElectionStatusDraft,
+	).Scan(&r.CandidateEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrElectionLocked)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrBallotNumberTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the candidate. The update only happens while the parent election is in draft state.
+func (r *CandidateRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE candidates SET ballot_number = $1, name = $2, running_mate_name = NULLIF($3, ''), party = NULLIF($4, ''),
+			updated_at = timezone('utc', now()), updated_by = $5
+		FROM elections
+		WHERE candidates.id = $6 AND candidates.election_id = $7 AND candidates.deleted_at IS NULL
+			AND elections.id = candidates.election_id AND elections.status = $8 AND elections.deleted_at IS NULL
+		RETURNING candidates.id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.CandidateEntity.BallotNumber,
+		r.CandidateEntity.Name,
+		r.CandidateEntity.RunningMateName,
+		r.CandidateEntity.Party,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.CandidateEntity.ID,
+		r.CandidateEntity.ElectionID,
+		model.ElectionStatusDraft,
+	).Scan(&r.CandidateEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrElectionLocked)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrBallotNumberTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete soft deletes the candidate. The delete only happens while the parent election is in draft state.
+func (r *CandidateRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE candidates SET deleted_at = timezone('utc', now()), deleted_by = $1
+		FROM elections
+		WHERE candidates.id = $2 AND candidates.election_id = $3 AND candidates.deleted_at IS NULL
+			AND elections.id = candidates.election_id AND elections.status = $4 AND elections.deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.CandidateEntity.ID, r.CandidateEntity.ElectionID, model.ElectionStatusDraft)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(ErrElectionLocked)
+	}
+
+	return nil
+}
+
+// List returns the candidates of an election ordered by ballot number
+func (r *CandidateRepository) List(ctx context.Context) ([]model.Candidate, error) {
+	var list []model.Candidate = make([]model.Candidate, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT id, election_id, ballot_number, name, COALESCE(running_mate_name, ''), COALESCE(party, '')
+		FROM candidates
+		WHERE election_id = $1 AND deleted_at IS NULL
+		ORDER BY ballot_number`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.CandidateEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var candidate model.Candidate
+		err = rows.Scan(&candidate.ID, &candidate.ElectionID, &candidate.BallotNumber, &candidate.Name, &candidate.RunningMateName, &candidate.Party)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, candidate)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+)
+
+type CertificateRepository struct {
+	Db                *sql.DB
+	Log               *logger.Logger
+	CertificateEntity model.Certificate
+}
+
+// Find reads the certificate of the election with its signatures
+func (r *CertificateRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT election_id, document, sha256, certified_at, certified_by FROM certificates WHERE election_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	var document string
+	err = stmt.QueryRowContext(ctx, r.CertificateEntity.ElectionID).Scan(
+		&r.CertificateEntity.ElectionID,
+		&document,
+		&r.CertificateEntity.SHA256,
+		&r.CertificateEntity.CertifiedAt,
+		&r.CertificateEntity.CertifiedBy,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	r.CertificateEntity.Document = []byte(document)
+
+	const qSignatures = `SELECT public_key, signature FROM certificate_signatures WHERE election_id = $1 ORDER BY public_key`
+	rows, err := r.Db.QueryContext(ctx, qSignatures, r.CertificateEntity.ElectionID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	r.CertificateEntity.Signatures = make([]model.CertificateSignature, 0)
+	for rows.Next() {
+		var signature model.CertificateSignature
+		if err := rows.Scan(&signature.PublicKey, &signature.Signature); err != nil {
+			return r.Log.Error(err)
+		}
+		r.CertificateEntity.Signatures = append(r.CertificateEntity.Signatures, signature)
+	}
+	if rows.Err() != nil {
+		return r.Log.Error(rows.Err())
+	}
+
+	return nil
+}
+
+// insertCertificate stores the certificate and its signatures in the transaction of the certification
+func insertCertificate(ctx context.Context, tx *sql.Tx, certificate model.Certificate) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO certificates (election_id, document, sha256, certified_at, certified_by) VALUES ($1, $2, $3, $4, $5)`,
+		certificate.ElectionID, string(certificate.Document), certificate.SHA256, certificate.CertifiedAt, certificate.CertifiedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, signature := range certificate.Signatures {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO certificate_signatures (election_id, public_key, signature) VALUES ($1, $2, $3)`,
+			certificate.ElectionID, signature.PublicKey, signature.Signature,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
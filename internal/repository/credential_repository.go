@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+)
+
+var (
+	// ErrNotEligible is returned when the voter is not registered in the election
+	ErrNotEligible = errors.New("voter is not eligible in the election")
+	// ErrCredentialIssued is returned when a voter asks a second credential of the same election
+	ErrCredentialIssued = errors.New("voter already has a credential for the election")
+)
+
+type CredentialRepository struct {
+	Db               *sql.DB
+	Log              *logger.Logger
+	CredentialEntity model.Credential
+}
+
+// FindKey returns the signing key of the election
+func (r *CredentialRepository) FindKey(ctx context.Context) (model.CredentialKey, error) {
+	var key model.CredentialKey
+	switch ctx.Err() {
+	case context.Canceled:
+		return key, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return key, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT election_id, private_key, created_at FROM election_credential_keys WHERE election_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return key, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.CredentialEntity.ElectionID).Scan(&key.ElectionID, &key.PrivateKey, &key.CreatedAt)
+	if err != nil {
+		return key, r.Log.Error(err)
+	}
+	return key, nil
+}
+
+// SaveKey stores the signing key of the election unless it already has one.
+// When two requests create a key at the same time only the first is kept, both then read it with FindKey.
+func (r *CredentialRepository) SaveKey(ctx context.Context, privateKey []byte) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `INSERT INTO election_credential_keys (election_id, private_key) VALUES ($1, $2) ON CONFLICT (election_id) DO NOTHING`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, r.CredentialEntity.ElectionID, privateKey); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Issue records that the voter takes part in the election with the blinded message, before it is signed.
+// The election row is share locked so the election can not be closed meanwhile, and the primary key of
+// voter_participations gives a voter one credential per election, even when two requests run at the same time.
+// Asking again with the same blinded message is allowed, so a voter who lost the response can get it signed again.
+func (r *CredentialRepository) Issue(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT status FROM elections WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		r.CredentialEntity.ElectionID,
+	).Scan(&status)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if status != model.ElectionStatusOpen {
+		return r.Log.Error(ErrElectionNotOpen)
+	}
+
+	var eligible bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT true FROM election_voters WHERE election_id = $1 AND voter_id = $2`,
+		r.CredentialEntity.ElectionID, r.CredentialEntity.VoterID,
+	).Scan(&eligible)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrNotEligible)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO voter_participations (election_id, voter_id, blinded) VALUES ($1, $2, $3)
+		ON CONFLICT (election_id, voter_id) DO NOTHING`,
+		r.CredentialEntity.ElectionID, r.CredentialEntity.VoterID, r.CredentialEntity.Blinded,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return r.Log.Error(err)
+	} else if affected == 0 {
+		var blinded []byte
+		err = tx.QueryRowContext(ctx,
+			`SELECT blinded FROM voter_participations WHERE election_id = $1 AND voter_id = $2`,
+			r.CredentialEntity.ElectionID, r.CredentialEntity.VoterID,
+		).Scan(&blinded)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+		if !bytes.Equal(blinded, r.CredentialEntity.Blinded) {
+			return r.Log.Error(ErrCredentialIssued)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
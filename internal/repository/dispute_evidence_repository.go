@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+type DisputeEvidenceRepository struct {
+	Db                    *sql.DB
+	Log                   *logger.Logger
+	DisputeEvidenceEntity model.DisputeEvidence
+}
+
+const disputeEvidenceColumns = `id, dispute_id, sha256, content_type, "size", COALESCE(filename, ''), created_at, created_by`
+
+func scanDisputeEvidence(row interface{ Scan(...interface{}) error }, evidence *model.DisputeEvidence) error {
+	return row.Scan(
+		&evidence.ID,
+		&evidence.DisputeID,
+		&evidence.SHA256,
+		&evidence.ContentType,
+		&evidence.Size,
+		&evidence.Filename,
+		&evidence.CreatedAt,
+		&evidence.CreatedBy,
+	)
+}
+
+// Find finds the evidence of the dispute by its checksum
+func (r *DisputeEvidenceRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + disputeEvidenceColumns + ` FROM dispute_evidences WHERE dispute_id = $1 AND sha256 = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanDisputeEvidence(stmt.QueryRowContext(ctx, r.DisputeEvidenceEntity.DisputeID, r.DisputeEvidenceEntity.SHA256), &r.DisputeEvidenceEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save attaches the evidence to the dispute. When the same content is already attached the existing evidence is loaded
+// and created is false.
+func (r *DisputeEvidenceRepository) Save(ctx context.Context) (bool, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return false, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return false, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO dispute_evidences (dispute_id, sha256, content_type, "size", filename, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		ON CONFLICT (dispute_id, sha256) DO NOTHING
+		RETURNING ` + disputeEvidenceColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return false, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanDisputeEvidence(stmt.QueryRowContext(
+		ctx,
+		r.DisputeEvidenceEntity.DisputeID,
+		r.DisputeEvidenceEntity.SHA256,
+		r.DisputeEvidenceEntity.ContentType,
+		r.DisputeEvidenceEntity.Size,
+		r.DisputeEvidenceEntity.Filename,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	), &r.DisputeEvidenceEntity)
+	if err == sql.ErrNoRows {
+		return false, r.Find(ctx)
+	} else if err != nil {
+		return false, r.Log.Error(err)
+	}
+
+	return true, nil
+}
+
+// List returns the evidences of the dispute in upload order
+func (r *DisputeEvidenceRepository) List(ctx context.Context) ([]model.DisputeEvidence, error) {
+	var list []model.DisputeEvidence = make([]model.DisputeEvidence, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + disputeEvidenceColumns + ` FROM dispute_evidences WHERE dispute_id = $1 ORDER BY created_at, id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.DisputeEvidenceEntity.DisputeID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var evidence model.DisputeEvidence
+		if err = scanDisputeEvidence(rows, &evidence); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, evidence)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
@@ -0,0 +1,425 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrDisputesClosed is returned when a dispute is filed while the election is not closed or tallied
+	ErrDisputesClosed = errors.New("disputes are filed while the election is closed or tallied")
+	// ErrDisputeStatusChanged is returned when the dispute left the expected status before the transition
+	ErrDisputeStatusChanged = errors.New("dispute status changed, reload and try again")
+	// ErrNothingToRecount is returned when a recount is ordered for a polling station or region without a submission
+	ErrNothingToRecount = errors.New("there is no tally form or recapitulation to recount")
+	// ErrRecountPending is returned when a dispute is resolved before the recounted submission is approved again
+	ErrRecountPending = errors.New("the recounted tally form or recapitulation is not approved yet")
+)
+
+type DisputeRepository struct {
+	Db            *sql.DB
+	Log           *logger.Logger
+	DisputeEntity model.Dispute
+}
+
+const disputeColumns = `id, election_id, COALESCE(polling_station_id, 0), COALESCE(region_id, 0), filer, reason, status,
+	COALESCE(resolution, ''), filed_at, filed_by, COALESCE(resolved_at::text, ''), COALESCE(resolved_by, 0)`
+
+func scanDispute(row interface{ Scan(...interface{}) error }, dispute *model.Dispute) error {
+	return row.Scan(
+		&dispute.ID,
+		&dispute.ElectionID,
+		&dispute.PollingStationID,
+		&dispute.RegionID,
+		&dispute.Filer,
+		&dispute.Reason,
+		&dispute.Status,
+		&dispute.Resolution,
+		&dispute.FiledAt,
+		&dispute.FiledBy,
+		&dispute.ResolvedAt,
+		&dispute.ResolvedBy,
+	)
+}
+
+// Find finds the dispute in the election
+func (r *DisputeRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + disputeColumns + ` FROM disputes WHERE id = $1 AND election_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanDispute(stmt.QueryRowContext(ctx, r.DisputeEntity.ID, r.DisputeEntity.ElectionID), &r.DisputeEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Create files the dispute. Disputes are accepted while the election is closed or tallied, once it is certified
+// the results are final.
+func (r *DisputeRepository) Create(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT status FROM elections WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		r.DisputeEntity.ElectionID,
+	).Scan(&status)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if status != model.ElectionStatusClosed && status != model.ElectionStatusTallied {
+		return r.Log.Error(ErrDisputesClosed)
+	}
+
+	const q = `
+		INSERT INTO disputes (election_id, polling_station_id, region_id, filer, reason, status, filed_by)
+		VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, $7)
+		RETURNING ` + disputeColumns
+	err = scanDispute(tx.QueryRowContext(ctx, q,
+		r.DisputeEntity.ElectionID,
+		r.DisputeEntity.PollingStationID,
+		r.DisputeEntity.RegionID,
+		r.DisputeEntity.Filer,
+		r.DisputeEntity.Reason,
+		model.DisputeStatusFiled,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	), &r.DisputeEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// List returns the disputes of the election in filing order, filtered by status when given
+func (r *DisputeRepository) List(ctx context.Context, status string) ([]model.Dispute, error) {
+	var list []model.Dispute = make([]model.Dispute, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + disputeColumns + ` FROM disputes WHERE election_id = $1`)
+	var args = []interface{}{r.DisputeEntity.ElectionID}
+
+	if len(status) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND status = $%d`, len(args)+1))
+		args = append(args, status)
+	}
+	sb.WriteString(` ORDER BY filed_at`)
+
+	stmt, err := r.Db.PrepareContext(ctx, sb.String())
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dispute model.Dispute
+		if err = scanDispute(rows, &dispute); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, dispute)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// Transition moves the dispute from status `from` to status `to` and records the acting user with the note.
+// Ordering a recount reopens the tally form or recapitulation under dispute in the same transaction, and resolving
+// a dispute after a recount requires the reopened submission to be approved again. The note of the resolution is
+// kept as the resolution of the dispute. A recount of a tally form returns the update that takes the form back out of
+// the tally, it is empty for every other transition.
+func (r *DisputeRepository) Transition(ctx context.Context, from string, to string, note string) (model.DisputeTransition, model.TallyUpdate, error) {
+	var transition model.DisputeTransition
+	var update model.TallyUpdate
+	switch ctx.Err() {
+	case context.Canceled:
+		return transition, update, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return transition, update, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return transition, update, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	switch {
+	case to == model.DisputeStatusRecountOrdered:
+		update, err = r.reopen(ctx, tx, note)
+		if err != nil {
+			return transition, update, r.Log.Error(err)
+		}
+	case to == model.DisputeStatusResolved && from == model.DisputeStatusRecountOrdered:
+		status, err := r.submissionStatus(ctx, tx)
+		if err != nil {
+			return transition, update, r.Log.Error(err)
+		}
+		if status != model.ReviewStatusApproved {
+			return transition, update, r.Log.Error(ErrRecountPending)
+		}
+	}
+
+	var res sql.Result
+	if to == model.DisputeStatusResolved {
+		res, err = tx.ExecContext(ctx,
+			`UPDATE disputes SET status = $1, resolution = $2, resolved_at = timezone('utc', now()), resolved_by = $3
+			WHERE id = $4 AND election_id = $5 AND status = $6`,
+			to, note, userID, r.DisputeEntity.ID, r.DisputeEntity.ElectionID, from,
+		)
+	} else {
+		res, err = tx.ExecContext(ctx,
+			`UPDATE disputes SET status = $1 WHERE id = $2 AND election_id = $3 AND status = $4`,
+			to, r.DisputeEntity.ID, r.DisputeEntity.ElectionID, from,
+		)
+	}
+	if err != nil {
+		return transition, update, r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return transition, update, r.Log.Error(err)
+	}
+	if affected == 0 {
+		return transition, update, r.Log.Error(ErrDisputeStatusChanged)
+	}
+
+	transition = model.DisputeTransition{DisputeID: r.DisputeEntity.ID, FromStatus: from, ToStatus: to, Note: note, UserID: userID}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO dispute_transitions (dispute_id, from_status, to_status, note, user_id) VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id, created_at`,
+		transition.DisputeID, transition.FromStatus, transition.ToStatus, transition.Note, transition.UserID,
+	).Scan(&transition.ID, &transition.CreatedAt)
+	if err != nil {
+		return transition, update, r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return transition, update, r.Log.Error(err)
+	}
+
+	r.DisputeEntity.Status = to
+	if to == model.DisputeStatusResolved {
+		r.DisputeEntity.Resolution = note
+		r.DisputeEntity.ResolvedBy = userID
+	}
+	return transition, update, nil
+}
+
+// ListTransitions returns the status changes of the dispute in the order they were made
+func (r *DisputeRepository) ListTransitions(ctx context.Context) ([]model.DisputeTransition, error) {
+	var list []model.DisputeTransition = make([]model.DisputeTransition, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, dispute_id, from_status, to_status, COALESCE(note, ''), user_id, created_at FROM dispute_transitions
+		WHERE dispute_id = $1 ORDER BY created_at, id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.DisputeEntity.ID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transition model.DisputeTransition
+		err = rows.Scan(
+			&transition.ID,
+			&transition.DisputeID,
+			&transition.FromStatus,
+			&transition.ToStatus,
+			&transition.Note,
+			&transition.UserID,
+			&transition.CreatedAt,
+		)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, transition)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// reopen sets the tally form or recapitulation under dispute back to reopened, so it is submitted and reviewed again.
+// Reopening a tally form moves the tally version of the election up, since its votes may leave the approved sum.
+// The returned update carries that version, with the votes of a form that was approved negated to take them out of
+// the sum. Both require the election to be closed.
+func (r *DisputeRepository) reopen(ctx context.Context, tx *sql.Tx, note string) (model.TallyUpdate, error) {
+	var update model.TallyUpdate
+	var q string
+	var targetID int64
+	if r.DisputeEntity.PollingStationID > 0 {
+		// the election row is locked by the new version, so no review of the form falls between here and the update
+		version, err := nextTallyVersion(ctx, tx, r.DisputeEntity.ElectionID)
+		if err != nil {
+			return update, err
+		}
+		update, err = r.retraction(ctx, tx)
+		if err != nil {
+			return update, err
+		}
+		update.Version = version
+		q = `UPDATE tally_forms SET status = $1, note = $2, reviewed_at = NULL, reviewed_by = NULL WHERE election_id = $3 AND polling_station_id = $4`
+		targetID = r.DisputeEntity.PollingStationID
+	} else {
+		if err := lockClosedElection(ctx, tx, r.DisputeEntity.ElectionID); err != nil {
+			return update, err
+		}
+		q = `UPDATE recapitulations SET status = $1, note = $2, reviewed_at = NULL, reviewed_by = NULL WHERE election_id = $3 AND region_id = $4`
+		targetID = r.DisputeEntity.RegionID
+	}
+
+	res, err := tx.ExecContext(ctx, q,
+		model.ReviewStatusReopened, fmt.Sprintf("recount ordered for dispute %d: %s", r.DisputeEntity.ID, note),
+		r.DisputeEntity.ElectionID, targetID,
+	)
+	if err != nil {
+		return update, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return update, err
+	}
+	if affected == 0 {
+		return update, ErrNothingToRecount
+	}
+	return update, nil
+}
+
+// retraction reads the tally form under dispute as an update that takes it out of the approved sum. A form that was
+// not approved is not in the sum, so its update holds no votes.
+func (r *DisputeRepository) retraction(ctx context.Context, tx *sql.Tx) (model.TallyUpdate, error) {
+	update := model.TallyUpdate{
+		ElectionID:       r.DisputeEntity.ElectionID,
+		PollingStationID: r.DisputeEntity.PollingStationID,
+		Lines:            make([]model.TallyLine, 0),
+		Reopened:         true,
+	}
+
+	var formID int64
+	var status string
+	var invalidVotes int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, status, invalid_votes FROM tally_forms WHERE election_id = $1 AND polling_station_id = $2`,
+		r.DisputeEntity.ElectionID, r.DisputeEntity.PollingStationID,
+	).Scan(&formID, &status, &invalidVotes)
+	if err == sql.ErrNoRows {
+		return update, ErrNothingToRecount
+	} else if err != nil {
+		return update, err
+	}
+
+	if status != model.ReviewStatusApproved {
+		return update, nil
+	}
+	update.InvalidVotes = -invalidVotes
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT candidate_id, votes FROM tally_form_lines WHERE tally_form_id = $1 ORDER BY candidate_id`,
+		formID,
+	)
+	if err != nil {
+		return update, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line model.TallyLine
+		if err = rows.Scan(&line.CandidateID, &line.Votes); err != nil {
+			return update, err
+		}
+		line.Votes = -line.Votes
+		update.Lines = append(update.Lines, line)
+	}
+	return update, rows.Err()
+}
+
+// submissionStatus returns the review status of the tally form or recapitulation under dispute
+func (r *DisputeRepository) submissionStatus(ctx context.Context, tx *sql.Tx) (string, error) {
+	var status string
+	var err error
+	if r.DisputeEntity.PollingStationID > 0 {
+		err = tx.QueryRowContext(ctx,
+			`SELECT status FROM tally_forms WHERE election_id = $1 AND polling_station_id = $2`,
+			r.DisputeEntity.ElectionID, r.DisputeEntity.PollingStationID,
+		).Scan(&status)
+	} else {
+		err = tx.QueryRowContext(ctx,
+			`SELECT status FROM recapitulations WHERE election_id = $1 AND region_id = $2`,
+			r.DisputeEntity.ElectionID, r.DisputeEntity.RegionID,
+		).Scan(&status)
+	}
+	if err == sql.ErrNoRows {
+		return "", ErrNothingToRecount
+	}
+	return status, err
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrDistrictNameTaken is returned when another district of the election already uses the name
+	ErrDistrictNameTaken = errors.New("district name is already used")
+	// ErrVotesLocked is returned when party votes are entered while the election is not closed
+	ErrVotesLocked = errors.New("party votes can only be entered while the election is closed")
+)
+
+type DistrictRepository struct {
+	Db             *sql.DB
+	Log            *logger.Logger
+	DistrictEntity model.District
+}
+
+func (r *DistrictRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, election_id, name, seats FROM districts WHERE id = $1 AND election_id = $2 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.DistrictEntity.ID, r.DistrictEntity.ElectionID).Scan(
+		&r.DistrictEntity.ID,
+		&r.DistrictEntity.ElectionID,
+		&r.DistrictEntity.Name,
+		&r.DistrictEntity.Seats,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save adds the district. The insert only happens while the parent election is in draft state.
+func (r *DistrictRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO districts (election_id, name, seats, created_by)
+		SELECT id, $2, $3, $4 FROM elections
+		WHERE id = $1 AND status = $5 AND deleted_at IS NULL
+		RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.DistrictEntity.ElectionID,
+		r.DistrictEntity.Name,
+		r.DistrictEntity.Seats,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		model.ElectionStatusDraft,
+	).Scan(&r.DistrictEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrElectionLocked)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrDistrictNameTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the district. The update only happens while the parent election is in draft state.
+func (r *DistrictRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE districts SET name = $1, seats = $2, updated_at = timezone('utc', now()), updated_by = $3
+		FROM elections
+		WHERE districts.id = $4 AND districts.election_id = $5 AND districts.deleted_at IS NULL
+			AND elections.id = districts.election_id AND elections.status = $6 AND elections.deleted_at IS NULL
+		RETURNING districts.id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.DistrictEntity.Name,
+		r.DistrictEntity.Seats,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.DistrictEntity.ID,
+		r.DistrictEntity.ElectionID,
+		model.ElectionStatusDraft,
+	).Scan(&r.DistrictEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrElectionLocked)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrDistrictNameTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete soft deletes the district. The delete only happens while the parent election is in draft state.
+func (r *DistrictRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE districts SET deleted_at = timezone('utc', now()), deleted_by = $1
+		FROM elections
+		WHERE districts.id = $2 AND districts.election_id = $3 AND districts.deleted_at IS NULL
+			AND elections.id = districts.election_id AND elections.status = $4 AND elections.deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.DistrictEntity.ID, r.DistrictEntity.ElectionID, model.ElectionStatusDraft)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(ErrElectionLocked)
+	}
+
+	return nil
+}
+
+// List returns the districts of an election ordered by name
+func (r *DistrictRepository) List(ctx context.Context) ([]model.District, error) {
+	var list []model.District = make([]model.District, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, election_id, name, seats FROM districts WHERE election_id = $1 AND deleted_at IS NULL ORDER BY name`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.DistrictEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var district model.District
+		if err = rows.Scan(&district.ID, &district.ElectionID, &district.Name, &district.Seats); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, district)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// SaveVotes replaces the party votes of the district. Votes are only accepted while the election is closed,
+// the election row is share locked so the election can not move on while the votes are written.
+func (r *DistrictRepository) SaveVotes(ctx context.Context, votes []model.PartyVotes) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT elections.status FROM districts
+		JOIN elections ON elections.id = districts.election_id
+		WHERE districts.id = $1 AND districts.election_id = $2 AND districts.deleted_at IS NULL AND elections.deleted_at IS NULL
+		FOR SHARE OF elections`,
+		r.DistrictEntity.ID, r.DistrictEntity.ElectionID,
+	).Scan(&status)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if status != model.ElectionStatusClosed {
+		return r.Log.Error(ErrVotesLocked)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM district_votes WHERE district_id = $1`, r.DistrictEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+	for _, partyVotes := range votes {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO district_votes (district_id, party, votes, updated_by) VALUES ($1, $2, $3, $4)`,
+			r.DistrictEntity.ID, partyVotes.Party, partyVotes.Votes, userID,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// ListVotes returns the party votes of every district of the election
+func (r *DistrictRepository) ListVotes(ctx context.Context) ([]model.PartyVotes, error) {
+	var list []model.PartyVotes = make([]model.PartyVotes, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT district_votes.district_id, district_votes.party, district_votes.votes FROM district_votes
+		JOIN districts ON districts.id = district_votes.district_id
+		WHERE districts.election_id = $1 AND districts.deleted_at IS NULL
+		ORDER BY district_votes.district_id, district_votes.party`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.DistrictEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var partyVotes model.PartyVotes
+		if err = rows.Scan(&partyVotes.DistrictID, &partyVotes.Party, &partyVotes.Votes); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, partyVotes)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
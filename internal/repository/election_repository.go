@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+// ErrElectionStatusChanged is returned when the election status was changed by another request
+var ErrElectionStatusChanged = errors.New("election status has changed")
+
+// electionColumns read the voting window as RFC 3339 UTC
+const electionColumns = `id, name, COALESCE(description, ''), status, counting_method, seats, seat_method, threshold, COALESCE(public_key, ''), trustee_threshold,
+	COALESCE(to_char(opens_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''), COALESCE(to_char(closes_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''),
+	timezone, created_at, created_by`
+
+type ElectionRepository struct {
+	Db             *sql.DB
+	Log            *logger.Logger
+	ElectionEntity model.Election
+}
+
+func scanElection(row interface{ Scan(...interface{}) error }, election *model.Election) error {
+	return row.Scan(
+		&election.ID,
+		&election.Name,
+		&election.Description,
+		&election.Status,
+		&election.CountingMethod,
+		&election.Seats,
+		&election.SeatMethod,
+		&election.Threshold,
+		&election.PublicKey,
+		&election.TrusteeThreshold,
+		&election.OpensAt,
+		&election.ClosesAt,
+		&election.Timezone,
+		&election.CreatedAt,
+		&election.CreatedBy,
+	)
+}
+
+func (r *ElectionRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + electionColumns + ` FROM elections WHERE id=$1 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanElection(stmt.QueryRowContext(ctx, r.ElectionEntity.ID), &r.ElectionEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+func (r *ElectionRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO elections (name, description, status, counting_method, seats, seat_method, threshold, opens_at, closes_at, timezone, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::timestamptz, NULLIF($9, '')::timestamptz, $10, $11) RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	r.ElectionEntity.Status = model.ElectionStatusDraft
+	err = stmt.QueryRowContext(
+		ctx,
+		r.ElectionEntity.Name,
+		r.ElectionEntity.Description,
+		r.ElectionEntity.Status,
+		r.ElectionEntity.CountingMethod,
+		r.ElectionEntity.Seats,
+		r.ElectionEntity.SeatMethod,
+		r.ElectionEntity.Threshold,
+		r.ElectionEntity.OpensAt,
+		r.ElectionEntity.ClosesAt,
+		r.ElectionEntity.Timezone,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	).Scan(&r.ElectionEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the election detail. Only elections in draft state can be updated.
+func (r *ElectionRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE elections SET name = $1, description = $2, counting_method = $3, seats = $4, seat_method = $5, threshold = $6,
+			opens_at = NULLIF($7, '')::timestamptz, closes_at = NULLIF($8, '')::timestamptz, timezone = $9,
+			updated_at = timezone('utc', now()), updated_by = $10
+		WHERE id = $11 AND status = $12 AND deleted_at IS NULL
+		RETURNING status`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.ElectionEntity.Name,
+		r.ElectionEntity.Description,
+		r.ElectionEntity.CountingMethod,
+		r.ElectionEntity.Seats,
+		r.ElectionEntity.SeatMethod,
+		r.ElectionEntity.Threshold,
+		r.ElectionEntity.OpensAt,
+		r.ElectionEntity.ClosesAt,
+		r.ElectionEntity.Timezone,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.ElectionEntity.ID,
+		model.ElectionStatusDraft,
+	).Scan(&r.ElectionEntity.Status)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrElectionStatusChanged)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete soft deletes the election. Only elections in draft state can be deleted.
+func (r *ElectionRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `UPDATE elections SET deleted_at = timezone('utc', now()), deleted_by = $1 WHERE id = $2 AND status = $3 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.ElectionEntity.ID, model.ElectionStatusDraft)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(ErrElectionStatusChanged)
+	}
+
+	return nil
+}
+
+func (r *ElectionRepository) List(ctx context.Context, search string) ([]model.Election, error) {
+	var list []model.Election = make([]model.Election, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + electionColumns + ` FROM elections WHERE deleted_at IS NULL`)
+	var args []interface{}
+
+	if len(search) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND name ILIKE $%d`, len(args)+1))
+		args = append(args, `%`+search+`%`)
+	}
+	sb.WriteString(` ORDER BY created_at DESC`)
+
+	stmt, err := r.Db.PrepareContext(ctx, sb.String())
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var election model.Election
+		err = scanElection(rows, &election)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, election)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// ListDue lists the scheduled elections whose voting window opened and the open elections whose voting window closed
+func (r *ElectionRepository) ListDue(ctx context.Context) ([]model.Election, error) {
+	var list []model.Election = make([]model.Election, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + electionColumns + ` FROM elections
+		WHERE deleted_at IS NULL AND ((status = $1 AND opens_at <= now()) OR (status = $2 AND closes_at <= now()))
+		ORDER BY id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, model.ElectionStatusScheduled, model.ElectionStatusOpen)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var election model.Election
+		if err := scanElection(rows, &election); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, election)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// Transition moves the election from status `from` to status `to` and records the acting user.
+// The update only succeeds when the stored status still equals `from`, so concurrent transitions
+// of the same election can not both win.
+func (r *ElectionRepository) Transition(ctx context.Context, from string, to string) (model.ElectionTransition, error) {
+	var transition model.ElectionTransition
+	switch ctx.Err() {
+	case context.Canceled:
+		return transition, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return transition, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return transition, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	transition, err = transitionElection(ctx, tx, r.ElectionEntity.ID, from, to, userID)
+	if err != nil {
+		return transition, r.Log.Error(err)
+	}
+
+	// the ballots still waiting for a full batch are appended when the election closes
+	if to == model.ElectionStatusClosed {
+		if err := appendBallots(ctx, tx, r.ElectionEntity.ID, 1); err != nil {
+			return transition, r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return transition, r.Log.Error(err)
+	}
+
+	r.ElectionEntity.Status = to
+	return transition, nil
+}
+
+// Certify moves the election from tallied to certified and stores its signed certificate in the same transaction,
+// so a certified election always has its certificate
+func (r *ElectionRepository) Certify(ctx context.Context, certificate model.Certificate) (model.ElectionTransition, error) {
+	var transition model.ElectionTransition
+	switch ctx.Err() {
+	case context.Canceled:
+		return transition, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return transition, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return transition, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	transition, err = transitionElection(ctx, tx, r.ElectionEntity.ID, model.ElectionStatusTallied, model.ElectionStatusCertified, userID)
+	if err != nil {
+		return transition, r.Log.Error(err)
+	}
+
+	certificate.ElectionID = r.ElectionEntity.ID
+	certificate.CertifiedBy = userID
+	if err := insertCertificate(ctx, tx, certificate); err != nil {
+		return transition, r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return transition, r.Log.Error(err)
+	}
+
+	r.ElectionEntity.Status = model.ElectionStatusCertified
+	return transition, nil
+}
+
+// transitionElection updates the status of the election when it still equals `from` and records the transition.
+// userID is 0 for the scheduler.
+func transitionElection(ctx context.Context, tx *sql.Tx, electionID int64, from string, to string, userID int64) (model.ElectionTransition, error) {
+	var transition model.ElectionTransition
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE elections SET status = $1, updated_at = timezone('utc', now()), updated_by = NULLIF($2, 0) WHERE id = $3 AND status = $4 AND deleted_at IS NULL`,
+		to, userID, electionID, from,
+	)
+	if err != nil {
+		return transition, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return transition, err
+	}
+	if affected == 0 {
+		return transition, ErrElectionStatusChanged
+	}
+
+	transition = model.ElectionTransition{ElectionID: electionID, FromStatus: from, ToStatus: to, UserID: userID}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO election_transitions (election_id, from_status, to_status, user_id) VALUES ($1, $2, $3, NULLIF($4, 0)) RETURNING id, created_at`,
+		transition.ElectionID, transition.FromStatus, transition.ToStatus, transition.UserID,
+	).Scan(&transition.ID, &transition.CreatedAt)
+	if err != nil {
+		return transition, err
+	}
+
+	return transition, nil
+}
+
+func (r *ElectionRepository) ListTransitions(ctx context.Context) ([]model.ElectionTransition, error) {
+	var list []model.ElectionTransition = make([]model.ElectionTransition, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, election_id, from_status, to_status, COALESCE(user_id, 0), created_at FROM election_transitions WHERE election_id = $1 ORDER BY created_at`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.ElectionEntity.ID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transition model.ElectionTransition
+		err = rows.Scan(&transition.ID, &transition.ElectionID, &transition.FromStatus, &transition.ToStatus, &transition.UserID, &transition.CreatedAt)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, transition)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
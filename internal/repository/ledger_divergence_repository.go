@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+)
+
+const ledgerDivergenceColumns = `id, origin_peer_id, reported_by, height, COALESCE(local_hash, ''), COALESCE(remote_hash, ''), reason, created_at`
+
+type LedgerDivergenceRepository struct {
+	Db                     *sql.DB
+	Log                    *logger.Logger
+	LedgerDivergenceEntity model.LedgerDivergence
+}
+
+func scanLedgerDivergence(row interface{ Scan(...interface{}) error }, divergence *model.LedgerDivergence) error {
+	return row.Scan(
+		&divergence.ID,
+		&divergence.OriginPeerID,
+		&divergence.ReportedBy,
+		&divergence.Height,
+		&divergence.LocalHash,
+		&divergence.RemoteHash,
+		&divergence.Reason,
+		&divergence.CreatedAt,
+	)
+}
+
+// Save records the divergence. A divergence seen again on a later sync is recorded once.
+func (r *LedgerDivergenceRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO ledger_divergences (origin_peer_id, reported_by, height, local_hash, remote_hash, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		r.LedgerDivergenceEntity.OriginPeerID,
+		r.LedgerDivergenceEntity.ReportedBy,
+		r.LedgerDivergenceEntity.Height,
+		r.LedgerDivergenceEntity.LocalHash,
+		r.LedgerDivergenceEntity.RemoteHash,
+		r.LedgerDivergenceEntity.Reason,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// List returns the divergences of the ledger of a peer, or of all ledgers when originPeerID is 0, latest first
+func (r *LedgerDivergenceRepository) List(ctx context.Context, originPeerID int64) ([]model.LedgerDivergence, error) {
+	var list []model.LedgerDivergence = make([]model.LedgerDivergence, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + ledgerDivergenceColumns + ` FROM ledger_divergences WHERE $1 = 0 OR origin_peer_id = $1 ORDER BY created_at DESC, id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, originPeerID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var divergence model.LedgerDivergence
+		if err = scanLedgerDivergence(rows, &divergence); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, divergence)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
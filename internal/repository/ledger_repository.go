@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/ledger"
+	"backend-election/internal/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// ledgerBatchSize is how many ballots of an election wait before they are appended to the ledger together.
+// A ballot can only be told apart from the others of its batch, the rest is appended when the election closes.
+const ledgerBatchSize = 100
+
+// LedgerRepository stores the ledger of OriginPeerID, model.LedgerOriginLocal for the ledger of this node.
+// It implements ledger.Store.
+type LedgerRepository struct {
+	Db           *sql.DB
+	Log          *logger.Logger
+	OriginPeerID int64
+}
+
+func (r *LedgerRepository) Head(ctx context.Context) (ledger.Head, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return ledger.Head{}, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return ledger.Head{}, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT height, hash, root, frontier FROM ledger_heads WHERE origin_peer_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return ledger.Head{}, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	head, err := scanLedgerHead(stmt.QueryRowContext(ctx, r.OriginPeerID))
+	if err == sql.ErrNoRows {
+		return ledger.Genesis(), nil
+	} else if err != nil {
+		return head, r.Log.Error(err)
+	}
+	return head, nil
+}
+
+func (r *LedgerRepository) Entries(ctx context.Context, from int64, limit int) ([]ledger.Entry, error) {
+	var list []ledger.Entry = make([]ledger.Entry, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT height, payload, prev_hash, hash, root FROM ledger_entries
+		WHERE origin_peer_id = $1 AND height >= $2
+		ORDER BY height
+		LIMIT $3`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.OriginPeerID, from, limit)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry ledger.Entry
+		err = rows.Scan(&entry.Height, (*[]byte)(&entry.Payload), (*[]byte)(&entry.PrevHash), (*[]byte)(&entry.Hash), (*[]byte)(&entry.Root))
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, entry)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// Hashes returns the hashes of the entries up to height, the leaves of the Merkle tree at that height
+func (r *LedgerRepository) Hashes(ctx context.Context, height int64) ([]ledger.Hash, error) {
+	var list []ledger.Hash = make([]ledger.Hash, 0, height)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT hash FROM ledger_entries WHERE origin_peer_id = $1 AND height <= $2 ORDER BY height`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.OriginPeerID, height)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash []byte
+		if err = rows.Scan(&hash); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, hash)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// Append adds the payloads after the entry at height, it is how a replica is extended
+func (r *LedgerRepository) Append(ctx context.Context, height int64, payloads ...[]byte) ([]ledger.Entry, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	head, err := lockLedgerHead(ctx, tx, r.OriginPeerID)
+	if err != nil {
+		return nil, r.Log.Error(err)
+	}
+	if head.Height != height {
+		return nil, r.Log.Error(ledger.ErrConflict)
+	}
+
+	entries, err := writeLedgerEntries(ctx, tx, r.OriginPeerID, head, payloads)
+	if err != nil {
+		return nil, r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, r.Log.Error(err)
+	}
+
+	return entries, nil
+}
+
+// ListReplicas returns the heads of the replicas this node keeps of the ledgers of its peers
+func (r *LedgerRepository) ListReplicas(ctx context.Context) ([]model.LedgerReplica, error) {
+	var list []model.LedgerReplica = make([]model.LedgerReplica, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT p.id, p.public_key, h.height, h.hash, h.root FROM ledger_heads h
+		JOIN peers p ON p.id = h.origin_peer_id
+		ORDER BY p.id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var replica model.LedgerReplica
+		if err = rows.Scan(&replica.PeerID, &replica.PublicKey, &replica.Height, &replica.Hash, &replica.Root); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, replica)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+func scanLedgerHead(row interface{ Scan(...interface{}) error }) (ledger.Head, error) {
+	var head ledger.Head
+	var frontier pq.ByteaArray
+	if err := row.Scan(&head.Height, (*[]byte)(&head.Hash), (*[]byte)(&head.Root), &frontier); err != nil {
+		return head, err
+	}
+	head.Frontier = toHashes(frontier)
+	return head, nil
+}
+
+func toHashes(list pq.ByteaArray) []ledger.Hash {
+	hashes := make([]ledger.Hash, len(list))
+	for i, hash := range list {
+		hashes[i] = hash
+	}
+	return hashes
+}
+
+// lockLedgerHead returns the head of the ledger of the origin and locks it until the transaction ends,
+// creating the genesis head of a new ledger
+func lockLedgerHead(ctx context.Context, tx *sql.Tx, originPeerID int64) (ledger.Head, error) {
+	genesis := ledger.Genesis()
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO ledger_heads (origin_peer_id, height, hash, root, frontier) VALUES ($1, 0, $2, $3, '{}') ON CONFLICT DO NOTHING`,
+		originPeerID, []byte(genesis.Hash), []byte(genesis.Root),
+	)
+	if err != nil {
+		return genesis, err
+	}
+
+	return scanLedgerHead(tx.QueryRowContext(ctx,
+		`SELECT height, hash, root, frontier FROM ledger_heads WHERE origin_peer_id = $1 FOR UPDATE`,
+		originPeerID,
+	))
+}
+
+// writeLedgerEntries appends the payloads after the locked head and moves the head to the last of them
+func writeLedgerEntries(ctx context.Context, tx *sql.Tx, originPeerID int64, head ledger.Head, payloads [][]byte) ([]ledger.Entry, error) {
+	entries := make([]ledger.Entry, 0, len(payloads))
+	if len(payloads) == 0 {
+		return entries, nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO ledger_entries (origin_peer_id, height, payload, prev_hash, hash, root) VALUES ($1, $2, $3, $4, $5, $6)`,
+	)
+	if err != nil {
+		return entries, err
+	}
+	defer stmt.Close()
+
+	for _, payload := range payloads {
+		var entry ledger.Entry
+		entry, head = head.Next(payload)
+		_, err = stmt.ExecContext(ctx, originPeerID, entry.Height, payload, []byte(entry.PrevHash), []byte(entry.Hash), []byte(entry.Root))
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+
+	frontier := make(pq.ByteaArray, len(head.Frontier))
+	for i, hash := range head.Frontier {
+		frontier[i] = hash
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE ledger_heads SET height = $1, hash = $2, root = $3, frontier = $4, updated_at = timezone('utc', now()) WHERE origin_peer_id = $5`,
+		head.Height, []byte(head.Hash), []byte(head.Root), frontier, originPeerID,
+	)
+	if err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+// ballotPayload is the ledger entry of a ballot. It holds neither the voter nor the choices: Receipt is the receipt
+// hash the voter can look the entry up with, and Commitment binds the entry to the stored ballot without showing it.
+type ballotPayload struct {
+	ElectionID int64  `json:"election_id"`
+	Receipt    string `json:"receipt"`
+	Commitment string `json:"commitment"`
+}
+
+// ballotCommitment is the SHA-256 of the salt followed by the ballot id, the choices and the ciphertexts in JSON
+func ballotCommitment(salt []byte, ballotID int64, choices []int64, ciphertexts [][]byte) (string, error) {
+	data, err := json.Marshal(struct {
+		BallotID    int64    `json:"ballot_id"`
+		Choices     []int64  `json:"choices"`
+		Ciphertexts [][]byte `json:"ciphertexts,omitempty"`
+	}{ballotID, choices, ciphertexts})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(append(append([]byte{}, salt...), data...))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// appendBallots appends the ballots of the election that are not in the ledger yet, once at least minimum of them wait.
+// The ballots are appended in the order of their random id, so their heights do not follow the order they were cast in.
+func appendBallots(ctx context.Context, tx *sql.Tx, electionID int64, minimum int) error {
+	var pending int
+	err := tx.QueryRowContext(ctx,
+		`SELECT count(*) FROM ballots WHERE election_id = $1 AND ledger_height IS NULL`,
+		electionID,
+	).Scan(&pending)
+	if err != nil {
+		return err
+	}
+	if pending < minimum {
+		return nil
+	}
+
+	// the head is locked first, a concurrent batch that committed meanwhile is then no longer pending below
+	head, err := lockLedgerHead(ctx, tx, model.LedgerOriginLocal)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, choices, ciphertexts, COALESCE(receipt_hash, ''), COALESCE(salt, '') FROM ballots
+		WHERE election_id = $1 AND ledger_height IS NULL ORDER BY id FOR UPDATE`,
+		electionID,
+	)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	var payloads [][]byte
+	for rows.Next() {
+		var ballot model.Ballot
+		var salt []byte
+		if err = rows.Scan(&ballot.ID, pq.Array(&ballot.Choices), (*pq.ByteaArray)(&ballot.Ciphertexts), &ballot.ReceiptHash, &salt); err != nil {
+			rows.Close()
+			return err
+		}
+
+		payload := ballotPayload{ElectionID: electionID, Receipt: hex.EncodeToString(ballot.ReceiptHash)}
+		if payload.Commitment, err = ballotCommitment(salt, ballot.ID, ballot.Choices, ballot.Ciphertexts); err != nil {
+			rows.Close()
+			return err
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, ballot.ID)
+		payloads = append(payloads, data)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	if len(payloads) < minimum {
+		return nil
+	}
+
+	entries, err := writeLedgerEntries(ctx, tx, model.LedgerOriginLocal, head, payloads)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		_, err = tx.ExecContext(ctx, `UPDATE ballots SET ledger_height = $1 WHERE id = $2`, entry.Height, ids[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrPublicKeyTaken is returned when another peer is already registered with the public key
+	ErrPublicKeyTaken = errors.New("public key is already registered")
+	// ErrPeerStatusChanged is returned when the status of the peer changed since it was read
+	ErrPeerStatusChanged = errors.New("peer status changed concurrently")
+)
+
+const peerColumns = `id, name, endpoint, public_key, COALESCE(remote_id, 0), status, created_at, created_by, COALESCE(updated_at::text, ''), COALESCE(updated_by, 0)`
+
+type PeerRepository struct {
+	Db         *sql.DB
+	Log        *logger.Logger
+	PeerEntity model.Peer
+}
+
+func scanPeer(row interface{ Scan(...interface{}) error }, peer *model.Peer) error {
+	return row.Scan(
+		&peer.ID,
+		&peer.Name,
+		&peer.Endpoint,
+		&peer.PublicKey,
+		&peer.RemoteID,
+		&peer.Status,
+		&peer.CreatedAt,
+		&peer.CreatedBy,
+		&peer.UpdatedAt,
+		&peer.UpdatedBy,
+	)
+}
+
+func (r *PeerRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + peerColumns + ` FROM peers WHERE id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanPeer(stmt.QueryRowContext(ctx, r.PeerEntity.ID), &r.PeerEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save registers the peer, a new peer is pending until it is approved
+func (r *PeerRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO peers (name, endpoint, public_key, remote_id, status, created_by) VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+		RETURNING ` + peerColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanPeer(stmt.QueryRowContext(
+		ctx,
+		r.PeerEntity.Name,
+		r.PeerEntity.Endpoint,
+		r.PeerEntity.PublicKey,
+		r.PeerEntity.RemoteID,
+		model.PeerStatusPending,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	), &r.PeerEntity)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrPublicKeyTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the name, the endpoint and the remote id of the peer. The public key identifies the peer and can not be changed.
+func (r *PeerRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE peers SET name = $1, endpoint = $2, remote_id = NULLIF($3, 0), updated_at = timezone('utc', now()), updated_by = $4
+		WHERE id = $5
+		RETURNING ` + peerColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanPeer(stmt.QueryRowContext(
+		ctx,
+		r.PeerEntity.Name,
+		r.PeerEntity.Endpoint,
+		r.PeerEntity.RemoteID,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.PeerEntity.ID,
+	), &r.PeerEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// SetStatus moves the peer from status `from` to status `to`.
+// The update only succeeds when the stored status still equals `from`.
+func (r *PeerRepository) SetStatus(ctx context.Context, from string, to string) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE peers SET status = $1, updated_at = timezone('utc', now()), updated_by = $2
+		WHERE id = $3 AND status = $4
+		RETURNING ` + peerColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanPeer(stmt.QueryRowContext(ctx, to, ctx.Value(myctx.Key("user_id")).(int64), r.PeerEntity.ID, from), &r.PeerEntity)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrPeerStatusChanged)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// List returns the peers with the status, or all peers when status is empty
+func (r *PeerRepository) List(ctx context.Context, status string) ([]model.Peer, error) {
+	var list []model.Peer = make([]model.Peer, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + peerColumns + ` FROM peers WHERE $1 = '' OR status = $1 ORDER BY name, id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, status)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var peer model.Peer
+		if err = scanPeer(rows, &peer); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, peer)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+// ErrPollingStationNumberTaken is returned when another polling station of the village already uses the number
+var ErrPollingStationNumberTaken = errors.New("polling station number is already used in the village")
+
+type PollingStationRepository struct {
+	Db                   *sql.DB
+	Log                  *logger.Logger
+	PollingStationEntity model.PollingStation
+}
+
+func (r *PollingStationRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT id, village_id, number, COALESCE(address, '') FROM polling_stations WHERE id = $1 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.PollingStationEntity.ID).Scan(
+		&r.PollingStationEntity.ID,
+		&r.PollingStationEntity.VillageID,
+		&r.PollingStationEntity.Number,
+		&r.PollingStationEntity.Address,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+func (r *PollingStationRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `INSERT INTO polling_stations (village_id, number, address, created_by) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.PollingStationEntity.VillageID,
+		r.PollingStationEntity.Number,
+		r.PollingStationEntity.Address,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	).Scan(&r.PollingStationEntity.ID)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrPollingStationNumberTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the number and the address of the polling station. The village can not be changed.
+func (r *PollingStationRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE polling_stations SET number = $1, address = NULLIF($2, ''), updated_at = timezone('utc', now()), updated_by = $3
+		WHERE id = $4 AND deleted_at IS NULL
+		RETURNING village_id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.PollingStationEntity.Number,
+		r.PollingStationEntity.Address,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.PollingStationEntity.ID,
+	).Scan(&r.PollingStationEntity.VillageID)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrPollingStationNumberTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+func (r *PollingStationRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `UPDATE polling_stations SET deleted_at = timezone('utc', now()), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.PollingStationEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// List returns the polling stations, filtered by village when villageID is given, ordered by number
+func (r *PollingStationRepository) List(ctx context.Context, villageID int64) ([]model.PollingStation, error) {
+	var list []model.PollingStation = make([]model.PollingStation, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT id, village_id, number, COALESCE(address, '') FROM polling_stations WHERE deleted_at IS NULL`)
+	var args []interface{}
+
+	if villageID > 0 {
+		sb.WriteString(fmt.Sprintf(` AND village_id = $%d`, len(args)+1))
+		args = append(args, villageID)
+	}
+	sb.WriteString(` ORDER BY village_id, number`)
+
+	stmt, err := r.Db.PrepareContext(ctx, sb.String())
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollingStation model.PollingStation
+		err = rows.Scan(&pollingStation.ID, &pollingStation.VillageID, &pollingStation.Number, &pollingStation.Address)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, pollingStation)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
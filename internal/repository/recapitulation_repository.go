@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+type RecapitulationRepository struct {
+	Db                   *sql.DB
+	Log                  *logger.Logger
+	RecapitulationEntity model.Recapitulation
+}
+
+const recapitulationColumns = `id, election_id, region_id, status, revision, invalid_votes, COALESCE(note, ''),
+	submitted_at, submitted_by, COALESCE(reviewed_at::text, ''), COALESCE(reviewed_by, 0)`
+
+func scanRecapitulation(row interface{ Scan(...interface{}) error }, recapitulation *model.Recapitulation) error {
+	return row.Scan(
+		&recapitulation.ID,
+		&recapitulation.ElectionID,
+		&recapitulation.RegionID,
+		&recapitulation.Status,
+		&recapitulation.Revision,
+		&recapitulation.InvalidVotes,
+		&recapitulation.Note,
+		&recapitulation.SubmittedAt,
+		&recapitulation.SubmittedBy,
+		&recapitulation.ReviewedAt,
+		&recapitulation.ReviewedBy,
+	)
+}
+
+// Find finds the recapitulation of the region in the election, together with its lines
+func (r *RecapitulationRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + recapitulationColumns + ` FROM recapitulations WHERE election_id = $1 AND region_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanRecapitulation(stmt.QueryRowContext(ctx, r.RecapitulationEntity.ElectionID, r.RecapitulationEntity.RegionID), &r.RecapitulationEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.RecapitulationEntity.Lines, err = queryLines(ctx, r.Db,
+		`SELECT candidate_id, votes FROM recapitulation_lines WHERE recapitulation_id = $1 ORDER BY candidate_id`,
+		r.RecapitulationEntity.ID,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Submit stores the recapitulation of the region with a new revision, replacing a submitted or rejected one,
+// and records the discrepancies of the revision. An approved recapitulation can not be replaced.
+func (r *RecapitulationRepository) Submit(ctx context.Context, discrepancies []model.Discrepancy) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockClosedElection(ctx, tx, r.RecapitulationEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO recapitulations (election_id, region_id, status, invalid_votes, note, submitted_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		ON CONFLICT (election_id, region_id) DO UPDATE SET
+			status = EXCLUDED.status, revision = recapitulations.revision + 1, invalid_votes = EXCLUDED.invalid_votes, note = EXCLUDED.note,
+			submitted_at = timezone('utc', now()), submitted_by = EXCLUDED.submitted_by, reviewed_at = NULL, reviewed_by = NULL
+		WHERE recapitulations.status <> $7
+		RETURNING id, revision`,
+		r.RecapitulationEntity.ElectionID,
+		r.RecapitulationEntity.RegionID,
+		model.ReviewStatusSubmitted,
+		r.RecapitulationEntity.InvalidVotes,
+		r.RecapitulationEntity.Note,
+		userID,
+		model.ReviewStatusApproved,
+	).Scan(&r.RecapitulationEntity.ID, &r.RecapitulationEntity.Revision)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrAlreadyApproved)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recapitulation_lines WHERE recapitulation_id = $1`, r.RecapitulationEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	for _, line := range r.RecapitulationEntity.Lines {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO recapitulation_lines (recapitulation_id, candidate_id, votes) VALUES ($1, $2, $3)`,
+			r.RecapitulationEntity.ID, line.CandidateID, line.Votes,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	for _, discrepancy := range discrepancies {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO recapitulation_discrepancies (recapitulation_id, revision, candidate_id, submitted_votes, computed_votes)
+			VALUES ($1, $2, NULLIF($3, 0), $4, $5)`,
+			r.RecapitulationEntity.ID, r.RecapitulationEntity.Revision, discrepancy.CandidateID, discrepancy.SubmittedVotes, discrepancy.ComputedVotes,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.RecapitulationEntity.Status = model.ReviewStatusSubmitted
+	r.RecapitulationEntity.SubmittedBy = userID
+	return nil
+}
+
+// Review approves or rejects a submitted recapitulation
+func (r *RecapitulationRepository) Review(ctx context.Context, status string, note string) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockClosedElection(ctx, tx, r.RecapitulationEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE recapitulations SET status = $1, note = COALESCE(NULLIF($2, ''), note), reviewed_at = timezone('utc', now()), reviewed_by = $3
+		WHERE election_id = $4 AND region_id = $5 AND status = $6
+		RETURNING id`,
+		status, note, ctx.Value(myctx.Key("user_id")).(int64),
+		r.RecapitulationEntity.ElectionID, r.RecapitulationEntity.RegionID, model.ReviewStatusSubmitted,
+	).Scan(&r.RecapitulationEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrNotSubmitted)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.RecapitulationEntity.Status = status
+	return nil
+}
+
+// List returns the recapitulations of the election without their lines, filtered by the parent of their region
+func (r *RecapitulationRepository) List(ctx context.Context, parentID int64, status string) ([]model.Recapitulation, error) {
+	var list []model.Recapitulation = make([]model.Recapitulation, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + recapitulationColumns + ` FROM recapitulations WHERE election_id = $1`)
+	var args = []interface{}{r.RecapitulationEntity.ElectionID}
+
+	if parentID > 0 {
+		sb.WriteString(fmt.Sprintf(` AND region_id IN (SELECT id FROM regions WHERE parent_id = $%d)`, len(args)+1))
+		args = append(args, parentID)
+	}
+	if len(status) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND status = $%d`, len(args)+1))
+		args = append(args, status)
+	}
+	sb.WriteString(` ORDER BY submitted_at`)
+
+	stmt, err := r.Db.PrepareContext(ctx, sb.String())
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recapitulation model.Recapitulation
+		if err = scanRecapitulation(rows, &recapitulation); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, recapitulation)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// SumChildren adds up the approved recapitulations of the child regions
+func (r *RecapitulationRepository) SumChildren(ctx context.Context, regionID int64) (model.RecapitulationSum, error) {
+	var sum model.RecapitulationSum
+	switch ctx.Err() {
+	case context.Canceled:
+		return sum, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return sum, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT COUNT(regions.id), COUNT(recapitulations.id), COALESCE(SUM(recapitulations.invalid_votes), 0)
+		FROM regions
+		LEFT JOIN recapitulations ON recapitulations.region_id = regions.id AND recapitulations.election_id = $1 AND recapitulations.status = $3
+		WHERE regions.parent_id = $2 AND regions.deleted_at IS NULL`
+	err := r.Db.QueryRowContext(ctx, q, r.RecapitulationEntity.ElectionID, regionID, model.ReviewStatusApproved).Scan(
+		&sum.Children,
+		&sum.ApprovedChildren,
+		&sum.InvalidVotes,
+	)
+	if err != nil {
+		return sum, r.Log.Error(err)
+	}
+
+	sum.Lines, err = queryLines(ctx, r.Db, `
+		SELECT recapitulation_lines.candidate_id, SUM(recapitulation_lines.votes) FROM recapitulation_lines
+		JOIN recapitulations ON recapitulations.id = recapitulation_lines.recapitulation_id
+		JOIN regions ON regions.id = recapitulations.region_id
+		WHERE recapitulations.election_id = $1 AND regions.parent_id = $2 AND regions.deleted_at IS NULL AND recapitulations.status = $3
+		GROUP BY recapitulation_lines.candidate_id
+		ORDER BY recapitulation_lines.candidate_id`,
+		r.RecapitulationEntity.ElectionID, regionID, model.ReviewStatusApproved,
+	)
+	if err != nil {
+		return sum, r.Log.Error(err)
+	}
+
+	return sum, nil
+}
+
+// ListDiscrepancies returns the discrepancies recorded for every revision of the recapitulation, latest first
+func (r *RecapitulationRepository) ListDiscrepancies(ctx context.Context) ([]model.Discrepancy, error) {
+	var list []model.Discrepancy = make([]model.Discrepancy, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT id, recapitulation_id, revision, COALESCE(candidate_id, 0), submitted_votes, computed_votes, created_at
+		FROM recapitulation_discrepancies
+		WHERE recapitulation_id = $1
+		ORDER BY revision DESC, candidate_id NULLS LAST`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.RecapitulationEntity.ID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var discrepancy model.Discrepancy
+		err = rows.Scan(
+			&discrepancy.ID,
+			&discrepancy.RecapitulationID,
+			&discrepancy.Revision,
+			&discrepancy.CandidateID,
+			&discrepancy.SubmittedVotes,
+			&discrepancy.ComputedVotes,
+			&discrepancy.CreatedAt,
+		)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, discrepancy)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrRegionCodeTaken is returned when another region already uses the code
+	ErrRegionCodeTaken = errors.New("region code is already used")
+	// ErrRegionInUse is returned when a region with child regions or polling stations is deleted
+	ErrRegionInUse = errors.New("region still has child regions or polling stations")
+)
+
+const regionColumns = `id, COALESCE(parent_id, 0), level, code, name`
+
+type RegionRepository struct {
+	Db           *sql.DB
+	Log          *logger.Logger
+	RegionEntity model.Region
+}
+
+func (r *RegionRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + regionColumns + ` FROM regions WHERE id = $1 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.RegionEntity.ID).Scan(
+		&r.RegionEntity.ID,
+		&r.RegionEntity.ParentID,
+		&r.RegionEntity.Level,
+		&r.RegionEntity.Code,
+		&r.RegionEntity.Name,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+func (r *RegionRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `INSERT INTO regions (parent_id, level, code, name, created_by) VALUES (NULLIF($1, 0), $2, $3, $4, $5) RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.RegionEntity.ParentID,
+		r.RegionEntity.Level,
+		r.RegionEntity.Code,
+		r.RegionEntity.Name,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	).Scan(&r.RegionEntity.ID)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrRegionCodeTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Update changes the code and the name of the region. The place of a region in the hierarchy can not be changed.
+func (r *RegionRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE regions SET code = $1, name = $2, updated_at = timezone('utc', now()), updated_by = $3
+		WHERE id = $4 AND deleted_at IS NULL
+		RETURNING ` + regionColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.RegionEntity.Code,
+		r.RegionEntity.Name,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.RegionEntity.ID,
+	).Scan(
+		&r.RegionEntity.ID,
+		&r.RegionEntity.ParentID,
+		&r.RegionEntity.Level,
+		&r.RegionEntity.Code,
+		&r.RegionEntity.Name,
+	)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrRegionCodeTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete soft deletes the region. Regions with child regions or polling stations are kept.
+func (r *RegionRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE regions SET deleted_at = timezone('utc', now()), deleted_by = $1
+		WHERE id = $2 AND deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM regions children WHERE children.parent_id = regions.id AND children.deleted_at IS NULL)
+			AND NOT EXISTS (SELECT 1 FROM polling_stations WHERE polling_stations.village_id = regions.id AND polling_stations.deleted_at IS NULL)`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.RegionEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(ErrRegionInUse)
+	}
+
+	return nil
+}
+
+// List returns the regions filtered by parent and level, ordered by code
+func (r *RegionRepository) List(ctx context.Context, parentID int64, level string) ([]model.Region, error) {
+	var list []model.Region = make([]model.Region, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + regionColumns + ` FROM regions WHERE deleted_at IS NULL`)
+	var args []interface{}
+
+	if parentID > 0 {
+		sb.WriteString(fmt.Sprintf(` AND parent_id = $%d`, len(args)+1))
+		args = append(args, parentID)
+	}
+	if len(level) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND level = $%d`, len(args)+1))
+		args = append(args, level)
+	}
+	sb.WriteString(` ORDER BY code`)
+
+	return r.query(ctx, sb.String(), args...)
+}
+
+// Ancestors returns the region and every region above it, from the region itself up to the national level
+func (r *RegionRepository) Ancestors(ctx context.Context) ([]model.Region, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		WITH RECURSIVE ancestors AS (
+			SELECT regions.*, 0 AS depth FROM regions WHERE id = $1
+			UNION ALL
+			SELECT regions.*, ancestors.depth + 1 FROM regions JOIN ancestors ON regions.id = ancestors.parent_id
+		)
+		SELECT ` + regionColumns + ` FROM ancestors ORDER BY depth`
+
+	return r.query(ctx, q, r.RegionEntity.ID)
+}
+
+func (r *RegionRepository) query(ctx context.Context, q string, args ...interface{}) ([]model.Region, error) {
+	var list []model.Region = make([]model.Region, 0)
+
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var region model.Region
+		if err = rows.Scan(&region.ID, &region.ParentID, &region.Level, &region.Code, &region.Name); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, region)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
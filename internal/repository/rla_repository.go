@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrElectionNotTallied is returned when a risk-limiting audit changes while the election is not tallied
+	ErrElectionNotTallied = errors.New("election is not tallied")
+	// ErrRLAExists is returned when a second risk-limiting audit is started for the same election
+	ErrRLAExists = errors.New("risk-limiting audit is already started")
+	// ErrDrawsMoved is returned when draws are added after another request added draws since they were computed
+	ErrDrawsMoved = errors.New("draws were added meanwhile, try again")
+	// ErrNotDrawn is returned when a ballot or batch is interpreted before it is drawn
+	ErrNotDrawn = errors.New("ballot or batch is not drawn")
+	// ErrAlreadyInterpreted is returned when a drawn ballot or batch is interpreted a second time
+	ErrAlreadyInterpreted = errors.New("ballot or batch is already interpreted")
+)
+
+type RLARepository struct {
+	Db        *sql.DB
+	Log       *logger.Logger
+	RLAEntity model.RLA
+}
+
+// Find finds the risk-limiting audit of the election
+func (r *RLARepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT election_id, audit_type, risk_limit, max_draws, seed, created_at, created_by FROM rla_audits WHERE election_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.RLAEntity.ElectionID).Scan(
+		&r.RLAEntity.ElectionID,
+		&r.RLAEntity.Type,
+		&r.RLAEntity.RiskLimit,
+		&r.RLAEntity.MaxDraws,
+		&r.RLAEntity.Seed,
+		&r.RLAEntity.CreatedAt,
+		&r.RLAEntity.CreatedBy,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Create starts the risk-limiting audit of a tallied election. An election has one audit, its seed never changes.
+func (r *RLARepository) Create(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockTalliedElection(ctx, tx, r.RLAEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.RLAEntity.CreatedBy = ctx.Value(myctx.Key("user_id")).(int64)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO rla_audits (election_id, audit_type, risk_limit, max_draws, seed, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`,
+		r.RLAEntity.ElectionID,
+		r.RLAEntity.Type,
+		r.RLAEntity.RiskLimit,
+		r.RLAEntity.MaxDraws,
+		r.RLAEntity.Seed,
+		r.RLAEntity.CreatedBy,
+	).Scan(&r.RLAEntity.CreatedAt)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrRLAExists)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// ListDraws returns the draws of the audit in draw order
+func (r *RLARepository) ListDraws(ctx context.Context) ([]model.RLADraw, error) {
+	var list []model.RLADraw = make([]model.RLADraw, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT election_id, draw_index, polling_station_id, ballot_position, created_at FROM rla_draws
+		WHERE election_id = $1 ORDER BY draw_index`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.RLAEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var draw model.RLADraw
+		if err = rows.Scan(&draw.ElectionID, &draw.Index, &draw.PollingStationID, &draw.BallotPosition, &draw.CreatedAt); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, draw)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// AddDraws stores the next draws of the audit. The draws are computed from the number of draws already stored,
+// the audit row is locked so the first new draw must follow the last stored draw, else ErrDrawsMoved is returned.
+func (r *RLARepository) AddDraws(ctx context.Context, draws []model.RLADraw) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockTalliedElection(ctx, tx, r.RLAEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	var electionID int64
+	err = tx.QueryRowContext(ctx, `SELECT election_id FROM rla_audits WHERE election_id = $1 FOR UPDATE`, r.RLAEntity.ElectionID).Scan(&electionID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	var stored int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM rla_draws WHERE election_id = $1`, r.RLAEntity.ElectionID).Scan(&stored)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	for i, draw := range draws {
+		if draw.Index != stored+i+1 {
+			return r.Log.Error(ErrDrawsMoved)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO rla_draws (election_id, draw_index, polling_station_id, ballot_position) VALUES ($1, $2, $3, $4)`,
+			r.RLAEntity.ElectionID, draw.Index, draw.PollingStationID, draw.BallotPosition,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Interpret stores the interpretation of a drawn ballot or batch. An interpretation is recorded once.
+func (r *RLARepository) Interpret(ctx context.Context, interpretation model.RLAInterpretation) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockTalliedElection(ctx, tx, r.RLAEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	var drawn bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT true FROM rla_draws WHERE election_id = $1 AND polling_station_id = $2 AND ballot_position = $3 LIMIT 1`,
+		r.RLAEntity.ElectionID, interpretation.PollingStationID, interpretation.BallotPosition,
+	).Scan(&drawn)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrNotDrawn)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO rla_interpretations (election_id, polling_station_id, ballot_position, invalid_votes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (election_id, polling_station_id, ballot_position) DO NOTHING`,
+		r.RLAEntity.ElectionID, interpretation.PollingStationID, interpretation.BallotPosition,
+		interpretation.InvalidVotes, ctx.Value(myctx.Key("user_id")).(int64),
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return r.Log.Error(err)
+	} else if affected == 0 {
+		return r.Log.Error(ErrAlreadyInterpreted)
+	}
+
+	for _, line := range interpretation.Lines {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO rla_interpretation_lines (election_id, polling_station_id, ballot_position, candidate_id, votes)
+			VALUES ($1, $2, $3, $4, $5)`,
+			r.RLAEntity.ElectionID, interpretation.PollingStationID, interpretation.BallotPosition, line.CandidateID, line.Votes,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// ListInterpretations returns the interpretations of the audit with their lines
+func (r *RLARepository) ListInterpretations(ctx context.Context) ([]model.RLAInterpretation, error) {
+	var list []model.RLAInterpretation = make([]model.RLAInterpretation, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	rows, err := r.Db.QueryContext(ctx, `
+		SELECT election_id, polling_station_id, ballot_position, invalid_votes, created_at, created_by FROM rla_interpretations
+		WHERE election_id = $1 ORDER BY polling_station_id, ballot_position`,
+		r.RLAEntity.ElectionID,
+	)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		pollingStationID int64
+		ballotPosition   int
+	}
+	index := make(map[key]int)
+	for rows.Next() {
+		var interpretation model.RLAInterpretation
+		err = rows.Scan(
+			&interpretation.ElectionID,
+			&interpretation.PollingStationID,
+			&interpretation.BallotPosition,
+			&interpretation.InvalidVotes,
+			&interpretation.CreatedAt,
+			&interpretation.CreatedBy,
+		)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		interpretation.Lines = make([]model.TallyLine, 0)
+		index[key{interpretation.PollingStationID, interpretation.BallotPosition}] = len(list)
+		list = append(list, interpretation)
+	}
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	lineRows, err := r.Db.QueryContext(ctx, `
+		SELECT polling_station_id, ballot_position, candidate_id, votes FROM rla_interpretation_lines
+		WHERE election_id = $1 ORDER BY polling_station_id, ballot_position, candidate_id`,
+		r.RLAEntity.ElectionID,
+	)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var k key
+		var line model.TallyLine
+		if err = lineRows.Scan(&k.pollingStationID, &k.ballotPosition, &line.CandidateID, &line.Votes); err != nil {
+			return list, r.Log.Error(err)
+		}
+		if i, ok := index[k]; ok {
+			list[i].Lines = append(list[i].Lines, line)
+		}
+	}
+	if lineRows.Err() != nil {
+		return list, r.Log.Error(lineRows.Err())
+	}
+
+	return list, nil
+}
+
+// lockTalliedElection share locks the election row and checks that the election is tallied,
+// so the results under audit can not be certified while the audit changes
+func lockTalliedElection(ctx context.Context, tx *sql.Tx, electionID int64) error {
+	var status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT status FROM elections WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		electionID,
+	).Scan(&status)
+	if err != nil {
+		return err
+	}
+
+	if status != model.ElectionStatusTallied {
+		return ErrElectionNotTallied
+	}
+	return nil
+}
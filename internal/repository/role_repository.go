@@ -0,0 +1,645 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+)
+
+var (
+	// ErrRoleNameTaken is returned when another role already uses the name
+	ErrRoleNameTaken = errors.New("role name is already used")
+	// ErrLastSuperuser is returned when a change would leave no active user holding a superuser role
+	ErrLastSuperuser = errors.New("the last superuser role of the last superuser can not be removed")
+	// ErrSuperuserAccess is returned when access is revoked from a superuser role
+	ErrSuperuserAccess = errors.New("access can not be revoked from a superuser role")
+	// ErrAccessNotFound is returned when an unknown access is granted
+	ErrAccessNotFound = errors.New("access not found")
+	// ErrUserNotFound is returned when a role is assigned to an unknown or deleted user
+	ErrUserNotFound = errors.New("user not found")
+	// ErrScopeNotFound is returned when a role is scoped to an unknown election or region
+	ErrScopeNotFound = errors.New("election or region of the scope not found")
+	// ErrSuperuserScope is returned when a superuser role is scoped
+	ErrSuperuserScope = errors.New("a superuser role can not be scoped")
+	// ErrParentNotFound is returned when a role inherits an unknown role
+	ErrParentNotFound = errors.New("parent role not found")
+	// ErrRoleCycle is returned when a role would inherit itself, directly or through the parents of its parent
+	ErrRoleCycle = errors.New("the parent role already inherits the role")
+)
+
+const roleColumns = `roles.id, roles.name, roles.is_superuser, COALESCE(roles.election_id, 0), COALESCE(roles.region_id, 0)`
+
+type RoleRepository struct {
+	Db         *sql.DB
+	Log        *logger.Logger
+	RoleEntity model.Role
+}
+
+func scanRole(row interface{ Scan(...interface{}) error }, role *model.Role) error {
+	return row.Scan(&role.ID, &role.Name, &role.IsSuperuser, &role.ElectionID, &role.RegionID)
+}
+
+func (r *RoleRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + roleColumns + ` FROM roles WHERE id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	if err := scanRole(stmt.QueryRowContext(ctx, r.RoleEntity.ID), &r.RoleEntity); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// List returns every role ordered by name
+func (r *RoleRepository) List(ctx context.Context) ([]model.Role, error) {
+	var list []model.Role = make([]model.Role, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	return r.query(ctx, `SELECT `+roleColumns+` FROM roles ORDER BY roles.name`)
+}
+
+// ListByUser returns the roles held by the user ordered by name
+func (r *RoleRepository) ListByUser(ctx context.Context, userID int64) ([]model.Role, error) {
+	var list []model.Role = make([]model.Role, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + roleColumns + ` FROM roles
+		JOIN roles_users ON roles_users.role_id = roles.id
+		WHERE roles_users.user_id = $1
+		ORDER BY roles.name`
+
+	return r.query(ctx, q, userID)
+}
+
+func (r *RoleRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `INSERT INTO roles (name, election_id, region_id) VALUES ($1, NULLIF($2, 0), NULLIF($3, 0)) RETURNING ` + roleColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanRole(stmt.QueryRowContext(ctx, r.RoleEntity.Name, r.RoleEntity.ElectionID, r.RoleEntity.RegionID), &r.RoleEntity)
+	if err != nil {
+		return r.Log.Error(saveRoleError(err))
+	}
+
+	return nil
+}
+
+// Update renames the role and changes its scope. Whether a role is a superuser role can not be changed.
+func (r *RoleRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE roles SET name = $1, election_id = NULLIF($2, 0), region_id = NULLIF($3, 0)
+		WHERE id = $4
+		RETURNING ` + roleColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanRole(stmt.QueryRowContext(ctx, r.RoleEntity.Name, r.RoleEntity.ElectionID, r.RoleEntity.RegionID, r.RoleEntity.ID), &r.RoleEntity)
+	if err != nil {
+		return r.Log.Error(saveRoleError(err))
+	}
+
+	return nil
+}
+
+// saveRoleError maps the constraint violations of saving a role to their errors
+func saveRoleError(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return ErrRoleNameTaken
+	case isForeignKeyViolation(err):
+		return ErrScopeNotFound
+	case isCheckViolationOf(err, "roles_superuser_scope_check"):
+		return ErrSuperuserScope
+	default:
+		return err
+	}
+}
+
+// Delete removes the role with its access grants, its parents, the roles inheriting it and its users. A superuser role held by the last superuser is kept.
+func (r *RoleRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := r.keepSuperuser(ctx, tx, 0); err != nil {
+		return r.Log.Error(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM access_roles WHERE role_id = $1`, r.RoleEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM roles_parents WHERE role_id = $1 OR parent_id = $1`, r.RoleEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM roles_users WHERE role_id = $1`, r.RoleEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM roles WHERE id = $1`, r.RoleEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// ListAccess returns the access granted to the role ordered by path
+func (r *RoleRepository) ListAccess(ctx context.Context) ([]model.Access, error) {
+	var list []model.Access = make([]model.Access, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + accessColumns + ` FROM access
+		JOIN access_roles ON access_roles.access_id = access.id
+		WHERE access_roles.role_id = $1
+		ORDER BY access.path`
+
+	accessRepo := AccessRepository{Db: r.Db, Log: r.Log}
+	return accessRepo.query(ctx, q, r.RoleEntity.ID)
+}
+
+// Grant grants the access to the role, granting an access twice is a no-op
+func (r *RoleRepository) Grant(ctx context.Context, accessID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO access_roles (access_id, role_id)
+		SELECT access.id, $2::int8 FROM access WHERE access.id = $1
+		ON CONFLICT DO NOTHING`
+	return r.link(ctx, q, accessID, `SELECT EXISTS (SELECT 1 FROM access WHERE id = $1)`, ErrAccessNotFound)
+}
+
+// Revoke revokes the access from the role. Superuser roles keep all of their access.
+func (r *RoleRepository) Revoke(ctx context.Context, accessID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	if r.RoleEntity.IsSuperuser {
+		return r.Log.Error(ErrSuperuserAccess)
+	}
+
+	const q = `DELETE FROM access_roles WHERE access_id = $1 AND role_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, accessID, r.RoleEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// ListParents returns the roles the role inherits directly ordered by name
+func (r *RoleRepository) ListParents(ctx context.Context) ([]model.Role, error) {
+	var list []model.Role = make([]model.Role, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + roleColumns + ` FROM roles
+		JOIN roles_parents ON roles_parents.parent_id = roles.id
+		WHERE roles_parents.role_id = $1
+		ORDER BY roles.name`
+
+	return r.query(ctx, q, r.RoleEntity.ID)
+}
+
+// Inherit makes the role inherit the access of the parent, inheriting a parent twice is a no-op. A parent that
+// already inherits the role, directly or through its own parents, fails with ErrRoleCycle. The parents are locked
+// against other writers until the transaction ends, so two concurrent writes can not close a cycle together.
+func (r *RoleRepository) Inherit(ctx context.Context, parentID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE roles_parents IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return r.Log.Error(err)
+	}
+
+	var found bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE id = $1)`, parentID).Scan(&found); err != nil {
+		return r.Log.Error(err)
+	}
+	if !found {
+		return r.Log.Error(ErrParentNotFound)
+	}
+
+	const cycle = `
+		WITH RECURSIVE ancestors (id) AS (
+			SELECT $1::int8
+			UNION
+			SELECT roles_parents.parent_id FROM roles_parents JOIN ancestors ON roles_parents.role_id = ancestors.id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+	var isCycle bool
+	if err := tx.QueryRowContext(ctx, cycle, parentID, r.RoleEntity.ID).Scan(&isCycle); err != nil {
+		return r.Log.Error(err)
+	}
+	if isCycle {
+		return r.Log.Error(ErrRoleCycle)
+	}
+
+	const q = `INSERT INTO roles_parents (role_id, parent_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, q, r.RoleEntity.ID, parentID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Disinherit stops the role inheriting the access of the parent
+func (r *RoleRepository) Disinherit(ctx context.Context, parentID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `DELETE FROM roles_parents WHERE role_id = $1 AND parent_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, r.RoleEntity.ID, parentID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Assign gives the role to the user, assigning a role twice is a no-op
+func (r *RoleRepository) Assign(ctx context.Context, userID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO roles_users (user_id, role_id)
+		SELECT users.id, $2::int8 FROM users WHERE users.id = $1 AND users.deleted_at IS NULL
+		ON CONFLICT DO NOTHING`
+	return r.link(ctx, q, userID, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, ErrUserNotFound)
+}
+
+// Unassign takes the role from the user. The last superuser keeps their last superuser role.
+func (r *RoleRepository) Unassign(ctx context.Context, userID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := r.keepSuperuser(ctx, tx, userID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM roles_users WHERE user_id = $1 AND role_id = $2`, userID, r.RoleEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Holders returns the ids of the users holding the role or a role inheriting it, directly or through other roles
+func (r *RoleRepository) Holders(ctx context.Context) ([]int64, error) {
+	var list []int64 = make([]int64, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		WITH RECURSIVE heirs (id) AS (
+			SELECT $1::int8
+			UNION
+			SELECT roles_parents.role_id FROM roles_parents JOIN heirs ON roles_parents.parent_id = heirs.id
+		)
+		SELECT DISTINCT roles_users.user_id FROM roles_users JOIN heirs ON roles_users.role_id = heirs.id
+		ORDER BY roles_users.user_id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.RoleEntity.ID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, userID)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// keepSuperuser fails with ErrLastSuperuser when taking the role from the user, or from every user when userID is 0,
+// would leave no active user holding a superuser role. The superuser roles stay locked until the transaction ends,
+// so two concurrent removals can not both pass the check.
+func (r *RoleRepository) keepSuperuser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	superuserRoles, err := lockSuperuserRoles(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(superuserRoles, r.RoleEntity.ID) {
+		return nil
+	}
+
+	const q = `
+		SELECT EXISTS (
+			SELECT 1 FROM roles_users
+			JOIN roles ON roles.id = roles_users.role_id
+			JOIN users ON users.id = roles_users.user_id
+			WHERE roles.is_superuser AND users.deleted_at IS NULL
+				AND NOT (roles_users.role_id = $1 AND ($2::int8 = 0 OR roles_users.user_id = $2))
+		)`
+	var remains bool
+	if err := tx.QueryRowContext(ctx, q, r.RoleEntity.ID, userID).Scan(&remains); err != nil {
+		return err
+	}
+	if !remains {
+		return ErrLastSuperuser
+	}
+
+	return nil
+}
+
+// keepSuperuserHolder fails with ErrLastSuperuser when deleting the user would leave no active user holding
+// a superuser role. The superuser roles are locked as in keepSuperuser, so a concurrent removal waits for the delete.
+func keepSuperuserHolder(ctx context.Context, tx *sql.Tx, userID int64) error {
+	if _, err := lockSuperuserRoles(ctx, tx); err != nil {
+		return err
+	}
+
+	const q = `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM roles_users
+				JOIN roles ON roles.id = roles_users.role_id
+				WHERE roles.is_superuser AND roles_users.user_id = $1
+			),
+			EXISTS (
+				SELECT 1 FROM roles_users
+				JOIN roles ON roles.id = roles_users.role_id
+				JOIN users ON users.id = roles_users.user_id
+				WHERE roles.is_superuser AND users.deleted_at IS NULL AND roles_users.user_id <> $1
+			)`
+	var holds, remains bool
+	if err := tx.QueryRowContext(ctx, q, userID).Scan(&holds, &remains); err != nil {
+		return err
+	}
+	if holds && !remains {
+		return ErrLastSuperuser
+	}
+
+	return nil
+}
+
+// lockSuperuserRoles lists the superuser roles and locks them until the transaction ends, always in the same order
+// so two transactions locking them do not deadlock
+func lockSuperuserRoles(ctx context.Context, tx *sql.Tx) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM roles WHERE is_superuser ORDER BY id FOR UPDATE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		list = append(list, id)
+	}
+
+	return list, rows.Err()
+}
+
+// link runs the insert q linking the role to id. Nothing inserted means the link already exists or id is unknown,
+// which exists tells apart.
+func (r *RoleRepository) link(ctx context.Context, q string, id int64, exists string, notFound error) error {
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id, r.RoleEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var found bool
+	if err := r.Db.QueryRowContext(ctx, exists, id).Scan(&found); err != nil {
+		return r.Log.Error(err)
+	}
+	if !found {
+		return r.Log.Error(notFound)
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) query(ctx context.Context, q string, args ...interface{}) ([]model.Role, error) {
+	var list []model.Role = make([]model.Role, 0)
+
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role model.Role
+		if err = scanRole(rows, &role); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, role)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
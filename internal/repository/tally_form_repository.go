@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+var (
+	// ErrElectionNotClosed is returned when tally forms or recapitulations change while the election is not closed
+	ErrElectionNotClosed = errors.New("election is not closed")
+	// ErrAlreadyApproved is returned when an approved tally form or recapitulation is submitted again
+	ErrAlreadyApproved = errors.New("approved forms can not be changed")
+	// ErrNotSubmitted is returned when a tally form or recapitulation is reviewed while it is not waiting for review
+	ErrNotSubmitted = errors.New("only submitted forms can be reviewed")
+)
+
+type TallyFormRepository struct {
+	Db              *sql.DB
+	Log             *logger.Logger
+	TallyFormEntity model.TallyForm
+}
+
+const tallyFormColumns = `id, election_id, polling_station_id, status, invalid_votes, COALESCE(note, ''),
+	submitted_at, submitted_by, COALESCE(reviewed_at::text, ''), COALESCE(reviewed_by, 0)`
+
+func scanTallyForm(row interface{ Scan(...interface{}) error }, form *model.TallyForm) error {
+	return row.Scan(
+		&form.ID,
+		&form.ElectionID,
+		&form.PollingStationID,
+		&form.Status,
+		&form.InvalidVotes,
+		&form.Note,
+		&form.SubmittedAt,
+		&form.SubmittedBy,
+		&form.ReviewedAt,
+		&form.ReviewedBy,
+	)
+}
+
+// Find finds the tally form of the polling station in the election, together with its lines
+func (r *TallyFormRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + tallyFormColumns + ` FROM tally_forms WHERE election_id = $1 AND polling_station_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanTallyForm(stmt.QueryRowContext(ctx, r.TallyFormEntity.ElectionID, r.TallyFormEntity.PollingStationID), &r.TallyFormEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.TallyFormEntity.Lines, err = queryLines(ctx, r.Db,
+		`SELECT candidate_id, votes FROM tally_form_lines WHERE tally_form_id = $1 ORDER BY candidate_id`,
+		r.TallyFormEntity.ID,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Submit stores the tally form of the polling station, replacing a submitted or rejected form.
+// Forms are only accepted while the election is closed, and an approved form can not be replaced.
+func (r *TallyFormRepository) Submit(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	if err := lockClosedElection(ctx, tx, r.TallyFormEntity.ElectionID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO tally_forms (election_id, polling_station_id, status, invalid_votes, note, submitted_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		ON CONFLICT (election_id, polling_station_id) DO UPDATE SET
+			status = EXCLUDED.status, invalid_votes = EXCLUDED.invalid_votes, note = EXCLUDED.note,
+			submitted_at = timezone('utc', now()), submitted_by = EXCLUDED.submitted_by, reviewed_at = NULL, reviewed_by = NULL
+		WHERE tally_forms.status <> $7
+		RETURNING id`,
+		r.TallyFormEntity.ElectionID,
+		r.TallyFormEntity.PollingStationID,
+		model.ReviewStatusSubmitted,
+		r.TallyFormEntity.InvalidVotes,
+		r.TallyFormEntity.Note,
+		userID,
+		model.ReviewStatusApproved,
+	).Scan(&r.TallyFormEntity.ID)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrAlreadyApproved)
+	} else if err != nil {
+		return r.Log.Error(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM tally_form_lines WHERE tally_form_id = $1`, r.TallyFormEntity.ID); err != nil {
+		return r.Log.Error(err)
+	}
+
+	for _, line := range r.TallyFormEntity.Lines {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO tally_form_lines (tally_form_id, candidate_id, votes) VALUES ($1, $2, $3)`,
+			r.TallyFormEntity.ID, line.CandidateID, line.Votes,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+
+	r.TallyFormEntity.Status = model.ReviewStatusSubmitted
+	r.TallyFormEntity.SubmittedBy = userID
+	return nil
+}
+
+// Review approves or rejects a submitted tally form. Every approval moves the tally version of the election up by one,
+// the version after the review is returned.
+func (r *TallyFormRepository) Review(ctx context.Context, status string, note string) (int64, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return 0, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return 0, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var version int64
+	if status == model.ReviewStatusApproved {
+		version, err = nextTallyVersion(ctx, tx, r.TallyFormEntity.ElectionID)
+	} else {
+		err = lockClosedElection(ctx, tx, r.TallyFormEntity.ElectionID)
+	}
+	if err != nil {
+		return 0, r.Log.Error(err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE tally_forms SET status = $1, note = COALESCE(NULLIF($2, ''), note), reviewed_at = timezone('utc', now()), reviewed_by = $3
+		WHERE election_id = $4 AND polling_station_id = $5 AND status = $6
+		RETURNING id`,
+		status, note, ctx.Value(myctx.Key("user_id")).(int64),
+		r.TallyFormEntity.ElectionID, r.TallyFormEntity.PollingStationID, model.ReviewStatusSubmitted,
+	).Scan(&r.TallyFormEntity.ID)
+	if err == sql.ErrNoRows {
+		return 0, r.Log.Error(ErrNotSubmitted)
+	} else if err != nil {
+		return 0, r.Log.Error(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, r.Log.Error(err)
+	}
+
+	r.TallyFormEntity.Status = status
+	return version, nil
+}
+
+// List returns the tally forms of the election without their lines, filtered by village when villageID is given
+func (r *TallyFormRepository) List(ctx context.Context, villageID int64, status string) ([]model.TallyForm, error) {
+	var list []model.TallyForm = make([]model.TallyForm, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + tallyFormColumns + ` FROM tally_forms WHERE election_id = $1`)
+	var args = []interface{}{r.TallyFormEntity.ElectionID}
+
+	if villageID > 0 {
+		sb.WriteString(fmt.Sprintf(` AND polling_station_id IN (SELECT id FROM polling_stations WHERE village_id = $%d)`, len(args)+1))
+		args = append(args, villageID)
+	}
+	if len(status) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND status = $%d`, len(args)+1))
+		args = append(args, status)
+	}
+	sb.WriteString(` ORDER BY submitted_at`)
+
+	stmt, err := r.Db.PrepareContext(ctx, sb.String())
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var form model.TallyForm
+		if err = scanTallyForm(rows, &form); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, form)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// SumVillage adds up the approved tally forms of the polling stations in the village
+func (r *TallyFormRepository) SumVillage(ctx context.Context, villageID int64) (model.RecapitulationSum, error) {
+	var sum model.RecapitulationSum
+	switch ctx.Err() {
+	case context.Canceled:
+		return sum, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return sum, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT COUNT(polling_stations.id), COUNT(tally_forms.id), COALESCE(SUM(tally_forms.invalid_votes), 0)
+		FROM polling_stations
+		LEFT JOIN tally_forms ON tally_forms.polling_station_id = polling_stations.id AND tally_forms.election_id = $1 AND tally_forms.status = $3
+		WHERE polling_stations.village_id = $2 AND polling_stations.deleted_at IS NULL`
+	err := r.Db.QueryRowContext(ctx, q, r.TallyFormEntity.ElectionID, villageID, model.ReviewStatusApproved).Scan(
+		&sum.Children,
+		&sum.ApprovedChildren,
+		&sum.InvalidVotes,
+	)
+	if err != nil {
+		return sum, r.Log.Error(err)
+	}
+
+	sum.Lines, err = queryLines(ctx, r.Db, `
+		SELECT tally_form_lines.candidate_id, SUM(tally_form_lines.votes) FROM tally_form_lines
+		JOIN tally_forms ON tally_forms.id = tally_form_lines.tally_form_id
+		JOIN polling_stations ON polling_stations.id = tally_forms.polling_station_id
+		WHERE tally_forms.election_id = $1 AND polling_stations.village_id = $2 AND polling_stations.deleted_at IS NULL AND tally_forms.status = $3
+		GROUP BY tally_form_lines.candidate_id
+		ORDER BY tally_form_lines.candidate_id`,
+		r.TallyFormEntity.ElectionID, villageID, model.ReviewStatusApproved,
+	)
+	if err != nil {
+		return sum, r.Log.Error(err)
+	}
+
+	return sum, nil
+}
+
+// SumElection adds up the approved tally forms of the election together with the tally version they belong to
+func (r *TallyFormRepository) SumElection(ctx context.Context) (model.TallySnapshot, error) {
+	var snapshot = model.TallySnapshot{ElectionID: r.TallyFormEntity.ElectionID}
+	switch ctx.Err() {
+	case context.Canceled:
+		return snapshot, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return snapshot, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	// every query sees the same snapshot, so the sums match the version
+	tx, err := r.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return snapshot, r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT tally_version FROM elections WHERE id = $1 AND deleted_at IS NULL`,
+		r.TallyFormEntity.ElectionID,
+	).Scan(&snapshot.Version)
+	if err != nil {
+		return snapshot, r.Log.Error(err)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(id), COALESCE(SUM(invalid_votes), 0) FROM tally_forms WHERE election_id = $1 AND status = $2`,
+		r.TallyFormEntity.ElectionID, model.ReviewStatusApproved,
+	).Scan(&snapshot.ApprovedForms, &snapshot.InvalidVotes)
+	if err != nil {
+		return snapshot, r.Log.Error(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT tally_form_lines.candidate_id, SUM(tally_form_lines.votes) FROM tally_form_lines
+		JOIN tally_forms ON tally_forms.id = tally_form_lines.tally_form_id
+		WHERE tally_forms.election_id = $1 AND tally_forms.status = $2
+		GROUP BY tally_form_lines.candidate_id
+		ORDER BY tally_form_lines.candidate_id`,
+		r.TallyFormEntity.ElectionID, model.ReviewStatusApproved,
+	)
+	if err != nil {
+		return snapshot, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	snapshot.Lines = make([]model.TallyLine, 0)
+	for rows.Next() {
+		var line model.TallyLine
+		if err = rows.Scan(&line.CandidateID, &line.Votes); err != nil {
+			return snapshot, r.Log.Error(err)
+		}
+		snapshot.Lines = append(snapshot.Lines, line)
+	}
+
+	if rows.Err() != nil {
+		return snapshot, r.Log.Error(rows.Err())
+	}
+
+	return snapshot, nil
+}
+
+// ListApproved returns the approved tally forms of the election with their lines, ordered by polling station.
+// The order is the ballot manifest of a risk-limiting audit, so it must not change once the election is tallied.
+func (r *TallyFormRepository) ListApproved(ctx context.Context) ([]model.TallyForm, error) {
+	var list []model.TallyForm = make([]model.TallyForm, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + tallyFormColumns + ` FROM tally_forms WHERE election_id = $1 AND status = $2 ORDER BY polling_station_id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.TallyFormEntity.ElectionID, model.ReviewStatusApproved)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	index := make(map[int64]int)
+	for rows.Next() {
+		var form model.TallyForm
+		if err = scanTallyForm(rows, &form); err != nil {
+			return list, r.Log.Error(err)
+		}
+		form.Lines = make([]model.TallyLine, 0)
+		index[form.ID] = len(list)
+		list = append(list, form)
+	}
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	lineRows, err := r.Db.QueryContext(ctx, `
+		SELECT tally_form_lines.tally_form_id, tally_form_lines.candidate_id, tally_form_lines.votes FROM tally_form_lines
+		JOIN tally_forms ON tally_forms.id = tally_form_lines.tally_form_id
+		WHERE tally_forms.election_id = $1 AND tally_forms.status = $2
+		ORDER BY tally_form_lines.tally_form_id, tally_form_lines.candidate_id`,
+		r.TallyFormEntity.ElectionID, model.ReviewStatusApproved,
+	)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var formID int64
+		var line model.TallyLine
+		if err = lineRows.Scan(&formID, &line.CandidateID, &line.Votes); err != nil {
+			return list, r.Log.Error(err)
+		}
+		if i, ok := index[formID]; ok {
+			list[i].Lines = append(list[i].Lines, line)
+		}
+	}
+	if lineRows.Err() != nil {
+		return list, r.Log.Error(lineRows.Err())
+	}
+
+	return list, nil
+}
+
+// nextTallyVersion moves the tally version of a closed election up by one. The election row stays locked until the
+// transaction ends, so approvals of the same election are numbered in commit order.
+func nextTallyVersion(ctx context.Context, tx *sql.Tx, electionID int64) (int64, error) {
+	var status string
+	var version int64
+	err := tx.QueryRowContext(ctx,
+		`UPDATE elections SET tally_version = tally_version + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING status, tally_version`,
+		electionID,
+	).Scan(&status, &version)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != model.ElectionStatusClosed {
+		return 0, ErrElectionNotClosed
+	}
+	return version, nil
+}
+
+// lockClosedElection share locks the election row and checks that the election is closed,
+// so the election can not move on while tally forms and recapitulations are written
+func lockClosedElection(ctx context.Context, tx *sql.Tx, electionID int64) error {
+	var status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT status FROM elections WHERE id = $1 AND deleted_at IS NULL FOR SHARE`,
+		electionID,
+	).Scan(&status)
+	if err != nil {
+		return err
+	}
+
+	if status != model.ElectionStatusClosed {
+		return ErrElectionNotClosed
+	}
+	return nil
+}
+
+// queryLines reads candidate_id and votes pairs
+func queryLines(ctx context.Context, db *sql.DB, q string, args ...interface{}) ([]model.TallyLine, error) {
+	var list []model.TallyLine = make([]model.TallyLine, 0)
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line model.TallyLine
+		if err = rows.Scan(&line.CandidateID, &line.Votes); err != nil {
+			return list, err
+		}
+		list = append(list, line)
+	}
+
+	return list, rows.Err()
+}
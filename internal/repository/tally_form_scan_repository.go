@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+type TallyFormScanRepository struct {
+	Db                  *sql.DB
+	Log                 *logger.Logger
+	TallyFormScanEntity model.TallyFormScan
+}
+
+const tallyFormScanColumns = `id, tally_form_id, sha256, content_type, "size", COALESCE(filename, ''), created_at, created_by`
+
+func scanTallyFormScan(row interface{ Scan(...interface{}) error }, scan *model.TallyFormScan) error {
+	return row.Scan(
+		&scan.ID,
+		&scan.TallyFormID,
+		&scan.SHA256,
+		&scan.ContentType,
+		&scan.Size,
+		&scan.Filename,
+		&scan.CreatedAt,
+		&scan.CreatedBy,
+	)
+}
+
+// Find finds the scan of the tally form by its checksum
+func (r *TallyFormScanRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + tallyFormScanColumns + ` FROM tally_form_scans WHERE tally_form_id = $1 AND sha256 = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanTallyFormScan(stmt.QueryRowContext(ctx, r.TallyFormScanEntity.TallyFormID, r.TallyFormScanEntity.SHA256), &r.TallyFormScanEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save attaches the scan to the tally form. When the same content is already attached the existing scan is loaded
+// and created is false.
+func (r *TallyFormScanRepository) Save(ctx context.Context) (bool, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return false, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return false, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO tally_form_scans (tally_form_id, sha256, content_type, "size", filename, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		ON CONFLICT (tally_form_id, sha256) DO NOTHING
+		RETURNING ` + tallyFormScanColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return false, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanTallyFormScan(stmt.QueryRowContext(
+		ctx,
+		r.TallyFormScanEntity.TallyFormID,
+		r.TallyFormScanEntity.SHA256,
+		r.TallyFormScanEntity.ContentType,
+		r.TallyFormScanEntity.Size,
+		r.TallyFormScanEntity.Filename,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	), &r.TallyFormScanEntity)
+	if err == sql.ErrNoRows {
+		return false, r.Find(ctx)
+	} else if err != nil {
+		return false, r.Log.Error(err)
+	}
+
+	return true, nil
+}
+
+// List returns the scans of the tally form in upload order
+func (r *TallyFormScanRepository) List(ctx context.Context) ([]model.TallyFormScan, error) {
+	var list []model.TallyFormScan = make([]model.TallyFormScan, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + tallyFormScanColumns + ` FROM tally_form_scans WHERE tally_form_id = $1 ORDER BY created_at, id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.TallyFormScanEntity.TallyFormID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scan model.TallyFormScan
+		if err = scanTallyFormScan(rows, &scan); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, scan)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
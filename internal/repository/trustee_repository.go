@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTrusteesAssigned is returned when the trustees of an election are set up a second time
+	ErrTrusteesAssigned = errors.New("election already has trustees")
+	// ErrTrusteeUserNotFound is returned when a trustee is not an existing user
+	ErrTrusteeUserNotFound = errors.New("trustee user does not exist")
+	// ErrAlreadyDecrypted is returned when a trustee submits a second decryption of the tally
+	ErrAlreadyDecrypted = errors.New("trustee already decrypted the tally")
+	// ErrShareFetched is returned when a trustee fetches their key share a second time
+	ErrShareFetched = errors.New("trustee already fetched the key share")
+)
+
+type TrusteeRepository struct {
+	Db            *sql.DB
+	Log           *logger.Logger
+	TrusteeEntity model.Trustee
+}
+
+const trusteeColumns = `election_trustees.election_id, election_trustees.trustee_index, election_trustees.user_id,
+	election_trustees.verification_key, election_decryptions.election_id IS NOT NULL, election_trustees.created_at,
+	election_trustees.created_by`
+
+const trusteeTables = `election_trustees LEFT JOIN election_decryptions
+	ON election_decryptions.election_id = election_trustees.election_id
+	AND election_decryptions.trustee_index = election_trustees.trustee_index`
+
+func scanTrustee(row interface{ Scan(...interface{}) error }, trustee *model.Trustee) error {
+	return row.Scan(
+		&trustee.ElectionID,
+		&trustee.Index,
+		&trustee.UserID,
+		&trustee.VerificationKey,
+		&trustee.Decrypted,
+		&trustee.CreatedAt,
+		&trustee.CreatedBy,
+	)
+}
+
+// Setup stores the public key of the election and its trustees with their key shares, until every trustee fetches
+// their own. It only happens once, while the election is in draft state.
+func (r *TrusteeRepository) Setup(ctx context.Context, electionID int64, publicKey []byte, threshold int, trustees []model.Trustee) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var assigned bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT status, public_key IS NOT NULL FROM elections WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		electionID,
+	).Scan(&status, &assigned)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if status != model.ElectionStatusDraft {
+		return r.Log.Error(ErrElectionLocked)
+	}
+	if assigned {
+		return r.Log.Error(ErrTrusteesAssigned)
+	}
+
+	userID := ctx.Value(myctx.Key("user_id")).(int64)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE elections SET public_key = $1, trustee_threshold = $2, updated_at = timezone('utc', now()), updated_by = $3 WHERE id = $4`,
+		publicKey, threshold, userID, electionID,
+	)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	for _, trustee := range trustees {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO election_trustees (election_id, trustee_index, user_id, verification_key, share, created_by)
+			SELECT $1, $2, id, $4, $5, $6 FROM users WHERE id = $3 AND deleted_at IS NULL`,
+			electionID, trustee.Index, trustee.UserID, trustee.VerificationKey, trustee.Share, userID,
+		)
+		if err != nil {
+			return r.Log.Error(err)
+		}
+		if affected, err := res.RowsAffected(); err != nil {
+			return r.Log.Error(err)
+		} else if affected == 0 {
+			return r.Log.Error(ErrTrusteeUserNotFound)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// List returns the trustees of the election in index order
+func (r *TrusteeRepository) List(ctx context.Context) ([]model.Trustee, error) {
+	var list []model.Trustee = make([]model.Trustee, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + trusteeColumns + ` FROM ` + trusteeTables + `
+		WHERE election_trustees.election_id = $1 ORDER BY election_trustees.trustee_index`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.TrusteeEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trustee model.Trustee
+		if err = scanTrustee(rows, &trustee); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, trustee)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
+
+// FindByUser finds the trustee of the election held by the user
+func (r *TrusteeRepository) FindByUser(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `SELECT ` + trusteeColumns + ` FROM ` + trusteeTables + `
+		WHERE election_trustees.election_id = $1 AND election_trustees.user_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanTrustee(stmt.QueryRowContext(ctx, r.TrusteeEntity.ElectionID, r.TrusteeEntity.UserID), &r.TrusteeEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// TakeShare reads the key share of the trustee and erases it, so the share is handed out once.
+// It fails with ErrShareFetched when the share was fetched already.
+func (r *TrusteeRepository) TakeShare(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		WITH taken AS (
+			SELECT trustee_index, share FROM election_trustees
+			WHERE election_id = $1 AND user_id = $2 AND share IS NOT NULL
+			FOR UPDATE
+		)
+		UPDATE election_trustees SET share = NULL, share_fetched_at = timezone('utc', now())
+		FROM taken
+		WHERE election_trustees.election_id = $1 AND election_trustees.trustee_index = taken.trustee_index
+		RETURNING taken.trustee_index, taken.share, election_trustees.verification_key`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx, r.TrusteeEntity.ElectionID, r.TrusteeEntity.UserID).Scan(
+		&r.TrusteeEntity.Index,
+		&r.TrusteeEntity.Share,
+		&r.TrusteeEntity.VerificationKey,
+	)
+	if err == sql.ErrNoRows {
+		return r.Log.Error(ErrShareFetched)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// SaveDecryption stores the decryption share of the trustee, a trustee decrypts the tally only once
+func (r *TrusteeRepository) SaveDecryption(ctx context.Context, decryption model.TallyDecryption) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO election_decryptions (election_id, trustee_index, factors, challenges, responses)
+		VALUES ($1, $2, $3, $4, $5)`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		decryption.ElectionID,
+		decryption.TrusteeIndex,
+		pq.ByteaArray(decryption.Factors),
+		pq.ByteaArray(decryption.Challenges),
+		pq.ByteaArray(decryption.Responses),
+	)
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrAlreadyDecrypted)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// ListDecryptions returns the decryption shares submitted for the tally of the election
+func (r *TrusteeRepository) ListDecryptions(ctx context.Context) ([]model.TallyDecryption, error) {
+	var list []model.TallyDecryption = make([]model.TallyDecryption, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT election_id, trustee_index, factors, challenges, responses, created_at FROM election_decryptions
+		WHERE election_id = $1 ORDER BY trustee_index`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.TrusteeEntity.ElectionID)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var decryption model.TallyDecryption
+		err = rows.Scan(
+			&decryption.ElectionID,
+			&decryption.TrusteeIndex,
+			(*pq.ByteaArray)(&decryption.Factors),
+			(*pq.ByteaArray)(&decryption.Challenges),
+			(*pq.ByteaArray)(&decryption.Responses),
+			&decryption.CreatedAt,
+		)
+		if err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, decryption)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
@@ -94,6 +94,7 @@ func (u *UserRepository) Update(ctx context.Context) error {
 	return nil
 }
 
+// Delete soft deletes the user. The last active user holding a superuser role can not be deleted.
 func (u *UserRepository) Delete(ctx context.Context) error {
 	switch ctx.Err() {
 	case context.Canceled:
@@ -103,18 +104,28 @@ func (u *UserRepository) Delete(ctx context.Context) error {
 	default:
 	}
 
-	const q = `UPDATE users SET deleted_at = timezone('utc', now()), deleted_by = $1 WHERE id = $2`
-	stmt, err := u.Db.PrepareContext(ctx, q)
+	tx, err := u.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return u.Log.Error(err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
+
+	if err := keepSuperuserHolder(ctx, tx, u.UserEntity.ID); err != nil {
+		return u.Log.Error(err)
+	}
 
-	_, err = stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), u.UserEntity.ID)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET deleted_at = timezone('utc', now()), deleted_by = $1 WHERE id = $2`,
+		ctx.Value(myctx.Key("user_id")).(int64), u.UserEntity.ID,
+	)
 	if err != nil {
 		return u.Log.Error(err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return u.Log.Error(err)
+	}
+
 	return nil
 }
 
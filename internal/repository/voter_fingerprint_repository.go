@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+)
+
+type VoterFingerprintRepository struct {
+	Db                     *sql.DB
+	Log                    *logger.Logger
+	VoterFingerprintEntity model.VoterFingerprint
+}
+
+const voterFingerprintColumns = `voter_fingerprints.voter_id, voter_fingerprints.format, voter_fingerprints.finger_position,
+	voter_fingerprints.minutiae_count, voter_fingerprints.quality, voter_fingerprints."template",
+	voter_fingerprints.created_at, voter_fingerprints.created_by,
+	COALESCE(voter_fingerprints.updated_at::text, ''), COALESCE(voter_fingerprints.updated_by, 0)`
+
+func scanVoterFingerprint(row interface{ Scan(...interface{}) error }, fingerprint *model.VoterFingerprint) error {
+	return row.Scan(
+		&fingerprint.VoterID,
+		&fingerprint.Format,
+		&fingerprint.FingerPosition,
+		&fingerprint.MinutiaeCount,
+		&fingerprint.Quality,
+		&fingerprint.Template,
+		&fingerprint.CreatedAt,
+		&fingerprint.CreatedBy,
+		&fingerprint.UpdatedAt,
+		&fingerprint.UpdatedBy,
+	)
+}
+
+// Find finds the fingerprint of a voter that is not deleted
+func (r *VoterFingerprintRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + voterFingerprintColumns + ` FROM voter_fingerprints
+		JOIN voters ON voters.id = voter_fingerprints.voter_id
+		WHERE voter_fingerprints.voter_id = $1 AND voters.deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanVoterFingerprint(stmt.QueryRowContext(ctx, r.VoterFingerprintEntity.VoterID), &r.VoterFingerprintEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// Save enrolls the fingerprint of the voter, replacing the fingerprint enrolled before
+func (r *VoterFingerprintRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO voter_fingerprints (voter_id, format, finger_position, minutiae_count, quality, "template", created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (voter_id) DO UPDATE SET
+			format = EXCLUDED.format,
+			finger_position = EXCLUDED.finger_position,
+			minutiae_count = EXCLUDED.minutiae_count,
+			quality = EXCLUDED.quality,
+			"template" = EXCLUDED."template",
+			updated_at = timezone('utc', now()),
+			updated_by = EXCLUDED.created_by
+		RETURNING ` + voterFingerprintColumns
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanVoterFingerprint(stmt.QueryRowContext(
+		ctx,
+		r.VoterFingerprintEntity.VoterID,
+		r.VoterFingerprintEntity.Format,
+		r.VoterFingerprintEntity.FingerPosition,
+		r.VoterFingerprintEntity.MinutiaeCount,
+		r.VoterFingerprintEntity.Quality,
+		r.VoterFingerprintEntity.Template,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	), &r.VoterFingerprintEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+// Delete erases the fingerprint of the voter
+func (r *VoterFingerprintRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `DELETE FROM voter_fingerprints WHERE voter_id = $1`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, r.VoterFingerprintEntity.VoterID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Each calls fn with the fingerprint of every voter that is not deleted, except the voter of the entity.
+// The fingerprints are streamed instead of listed because a 1:N search goes through the whole registry.
+func (r *VoterFingerprintRepository) Each(ctx context.Context, fn func(model.VoterFingerprint) error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		SELECT ` + voterFingerprintColumns + ` FROM voter_fingerprints
+		JOIN voters ON voters.id = voter_fingerprints.voter_id
+		WHERE voters.deleted_at IS NULL AND voter_fingerprints.voter_id <> $1
+		ORDER BY voter_fingerprints.voter_id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, r.VoterFingerprintEntity.VoterID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint model.VoterFingerprint
+		if err = scanVoterFingerprint(rows, &fingerprint); err != nil {
+			return r.Log.Error(err)
+		}
+		if err = fn(fingerprint); err != nil {
+			return err
+		}
+	}
+
+	if rows.Err() != nil {
+		return r.Log.Error(rows.Err())
+	}
+
+	return nil
+}
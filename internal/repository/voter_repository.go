@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNIKTaken is returned when another voter is already registered with the NIK
+	ErrNIKTaken = errors.New("nik is already registered")
+	// ErrUserTaken is returned when the user account is already linked to another voter
+	ErrUserTaken = errors.New("user is already linked to another voter")
+)
+
+const voterColumns = `id, nik, name, normalized_name, COALESCE(birth_place, ''), to_char(birth_date, 'YYYY-MM-DD'), gender, COALESCE(address, ''), COALESCE(user_id, 0)`
+
+type VoterRepository struct {
+	Db          *sql.DB
+	Log         *logger.Logger
+	VoterEntity model.Voter
+}
+
+func scanVoter(row interface{ Scan(...interface{}) error }, voter *model.Voter) error {
+	return row.Scan(
+		&voter.ID,
+		&voter.NIK,
+		&voter.Name,
+		&voter.NormalizedName,
+		&voter.BirthPlace,
+		&voter.BirthDate,
+		&voter.Gender,
+		&voter.Address,
+		&voter.UserID,
+	)
+}
+
+func (r *VoterRepository) Find(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	q := `SELECT ` + voterColumns + ` FROM voters WHERE id = $1 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanVoter(stmt.QueryRowContext(ctx, r.VoterEntity.ID), &r.VoterEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+// FindByUserID finds the voter linked to the user account
+func (r *VoterRepository) FindByUserID(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	q := `SELECT ` + voterColumns + ` FROM voters WHERE user_id = $1 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = scanVoter(stmt.QueryRowContext(ctx, r.VoterEntity.UserID), &r.VoterEntity)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	return nil
+}
+
+func (r *VoterRepository) Save(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO voters (nik, name, normalized_name, birth_place, birth_date, gender, address, user_id, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), NULLIF($8, 0), $9)
+		RETURNING id`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		r.VoterEntity.NIK,
+		r.VoterEntity.Name,
+		r.VoterEntity.NormalizedName,
+		r.VoterEntity.BirthPlace,
+		r.VoterEntity.BirthDate,
+		r.VoterEntity.Gender,
+		r.VoterEntity.Address,
+		r.VoterEntity.UserID,
+		ctx.Value(myctx.Key("user_id")).(int64),
+	).Scan(&r.VoterEntity.ID)
+	if isUniqueViolationOf(err, "voters_user_id_unique") {
+		return r.Log.Error(ErrUserTaken)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrNIKTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+func (r *VoterRepository) Update(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		UPDATE voters SET nik = $1, name = $2, normalized_name = $3, birth_place = NULLIF($4, ''), birth_date = $5,
+			gender = $6, address = NULLIF($7, ''), user_id = NULLIF($8, 0), updated_at = timezone('utc', now()), updated_by = $9
+		WHERE id = $10 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(
+		ctx,
+		r.VoterEntity.NIK,
+		r.VoterEntity.Name,
+		r.VoterEntity.NormalizedName,
+		r.VoterEntity.BirthPlace,
+		r.VoterEntity.BirthDate,
+		r.VoterEntity.Gender,
+		r.VoterEntity.Address,
+		r.VoterEntity.UserID,
+		ctx.Value(myctx.Key("user_id")).(int64),
+		r.VoterEntity.ID,
+	)
+	if isUniqueViolationOf(err, "voters_user_id_unique") {
+		return r.Log.Error(ErrUserTaken)
+	}
+	if isUniqueViolation(err) {
+		return r.Log.Error(ErrNIKTaken)
+	}
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	if affected == 0 {
+		return r.Log.Error(sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *VoterRepository) Delete(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `UPDATE voters SET deleted_at = timezone('utc', now()), deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, ctx.Value(myctx.Key("user_id")).(int64), r.VoterEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+func (r *VoterRepository) List(ctx context.Context, search string) ([]model.Voter, error) {
+	var list []model.Voter = make([]model.Voter, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(`SELECT ` + voterColumns + ` FROM voters WHERE deleted_at IS NULL`)
+	var args []interface{}
+
+	if len(search) > 0 {
+		sb.WriteString(fmt.Sprintf(` AND normalized_name LIKE $%d`, len(args)+1))
+		args = append(args, `%`+search+`%`)
+	}
+	sb.WriteString(` ORDER BY normalized_name`)
+
+	return r.query(ctx, sb.String(), args...)
+}
+
+// PossibleDuplicates returns other voters sharing the NIK, or sharing both the normalized name and the birth date
+func (r *VoterRepository) PossibleDuplicates(ctx context.Context) ([]model.Voter, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	q := `
+		SELECT ` + voterColumns + ` FROM voters
+		WHERE deleted_at IS NULL AND id <> $1
+			AND (nik = $2 OR (normalized_name = $3 AND birth_date = $4))
+		ORDER BY id`
+
+	return r.query(ctx, q, r.VoterEntity.ID, r.VoterEntity.NIK, r.VoterEntity.NormalizedName, r.VoterEntity.BirthDate)
+}
+
+// RegisterEligible makes the voters eligible in the election. Voters already eligible are skipped.
+func (r *VoterRepository) RegisterEligible(ctx context.Context, electionID int64, voterIDs []int64) (int64, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return 0, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return 0, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `
+		INSERT INTO election_voters (election_id, voter_id, created_by)
+		SELECT $1, id, $3 FROM voters WHERE id = ANY($2) AND deleted_at IS NULL
+		ON CONFLICT (election_id, voter_id) DO NOTHING`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return 0, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, electionID, pq.Array(voterIDs), ctx.Value(myctx.Key("user_id")).(int64))
+	if err != nil {
+		return 0, r.Log.Error(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, r.Log.Error(err)
+	}
+
+	return affected, nil
+}
+
+func (r *VoterRepository) UnregisterEligible(ctx context.Context, electionID int64) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	const q = `DELETE FROM election_voters WHERE election_id = $1 AND voter_id = $2`
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, electionID, r.VoterEntity.ID)
+	if err != nil {
+		return r.Log.Error(err)
+	}
+
+	return nil
+}
+
+func (r *VoterRepository) ListEligible(ctx context.Context, electionID int64) ([]model.Voter, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, r.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, r.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	q := `
+		SELECT ` + voterColumns + ` FROM voters
+		JOIN election_voters ON voters.id = election_voters.voter_id
+		WHERE election_voters.election_id = $1 AND voters.deleted_at IS NULL
+		ORDER BY normalized_name`
+
+	return r.query(ctx, q, electionID)
+}
+
+func (r *VoterRepository) query(ctx context.Context, q string, args ...interface{}) ([]model.Voter, error) {
+	var list []model.Voter = make([]model.Voter, 0)
+
+	stmt, err := r.Db.PrepareContext(ctx, q)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return list, r.Log.Error(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var voter model.Voter
+		if err = scanVoter(rows, &voter); err != nil {
+			return list, r.Log.Error(err)
+		}
+		list = append(list, voter)
+	}
+
+	if rows.Err() != nil {
+		return list, r.Log.Error(rows.Err())
+	}
+
+	return list, nil
+}
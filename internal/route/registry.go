@@ -0,0 +1,53 @@
+package route
+
+import (
+	"backend-election/internal/middleware"
+	"backend-election/internal/model"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Registry registers the private routes of the router together with the access that guards them,
+// so every private route has a matching entry in the access table.
+type Registry struct {
+	router      *httprouter.Router
+	mid         *middleware.Middleware
+	middlewares []func(httprouter.Handle) httprouter.Handle
+	access      []model.Access
+}
+
+// GET registers a private GET route, name is the name of its access
+func (reg *Registry) GET(path string, name string, handle httprouter.Handle) {
+	reg.Handle("GET", path, name, handle)
+}
+
+// POST registers a private POST route, name is the name of its access
+func (reg *Registry) POST(path string, name string, handle httprouter.Handle) {
+	reg.Handle("POST", path, name, handle)
+}
+
+// PUT registers a private PUT route, name is the name of its access
+func (reg *Registry) PUT(path string, name string, handle httprouter.Handle) {
+	reg.Handle("PUT", path, name, handle)
+}
+
+// DELETE registers a private DELETE route, name is the name of its access
+func (reg *Registry) DELETE(path string, name string, handle httprouter.Handle) {
+	reg.Handle("DELETE", path, name, handle)
+}
+
+// Handle registers a private route behind the private middlewares and declares its access
+func (reg *Registry) Handle(method string, path string, name string, handle httprouter.Handle) {
+	reg.router.Handle(method, path, reg.mid.WrapMiddleware(reg.middlewares, handle))
+	reg.Permission(method+" "+path, name)
+}
+
+// Permission declares an access checked by a handler instead of a route, like the access to voter personal data
+func (reg *Registry) Permission(path string, name string) {
+	reg.access = append(reg.access, model.Access{Name: name, Path: path})
+}
+
+// Access lists the access declared by the routes, in the order they are registered
+func (reg *Registry) Access() []model.Access {
+	return reg.access
+}
@@ -4,9 +4,15 @@ import (
 	_ "backend-election/docs"
 	"backend-election/internal/handler"
 	"backend-election/internal/middleware"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/biometric"
 	"backend-election/internal/pkg/database"
 	"backend-election/internal/pkg/logger"
 	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/pkg/stream"
+	"crypto/ed25519"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,7 +21,16 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func ApiRoute(log *logger.Logger, db *database.Database, cache *redis.Cache) *httprouter.Router {
+func ApiRoute(log *logger.Logger, db *database.Database, cache *redis.Cache, store storage.Storage, hub *stream.Hub, engine *biometric.Engine, peerKey ed25519.PrivateKey, certifierKeys []ed25519.PrivateKey, spool *audit.Spool) *httprouter.Router {
+	return register(log, db, cache, store, hub, engine, peerKey, certifierKeys, spool).router
+}
+
+// Access lists the access declared by the private routes of ApiRoute, to sync the access table with
+func Access() []model.Access {
+	return register(logger.New(), &database.Database{}, nil, nil, nil, nil, nil, nil, nil).Access()
+}
+
+func register(log *logger.Logger, db *database.Database, cache *redis.Cache, store storage.Storage, hub *stream.Hub, engine *biometric.Engine, peerKey ed25519.PrivateKey, certifierKeys []ed25519.PrivateKey, spool *audit.Spool) *Registry {
 	router := httprouter.New()
 	router.ServeFiles("/docs/*filepath", http.Dir("./docs"))
 
@@ -24,7 +39,7 @@ func ApiRoute(log *logger.Logger, db *database.Database, cache *redis.Cache) *ht
 	)
 	router.Handler("GET", "/swagger/*filepath", swaggerHandler)
 
-	var mid middleware.Middleware = middleware.Middleware{Log: log, DB: db.Conn, Cache: cache}
+	var mid middleware.Middleware = middleware.Middleware{Log: log, DB: db.Conn, Cache: cache, Spool: spool}
 	publicMiddlewares := []func(httprouter.Handle) httprouter.Handle{
 		mid.CORS,
 		mid.PanicRecovery,
@@ -32,17 +47,162 @@ func ApiRoute(log *logger.Logger, db *database.Database, cache *redis.Cache) *ht
 		mid.RateLimit,
 		mid.Idempotency,
 	}
-	privateMiddlewares := append(publicMiddlewares, mid.Authentication, mid.Authorization)
+	privateMiddlewares := append(publicMiddlewares, mid.Authentication, mid.Authorization, mid.Audit)
+	peerMiddlewares := append(publicMiddlewares, mid.PeerAuthentication)
+	routes := &Registry{router: router, mid: &mid, middlewares: privateMiddlewares}
+	routes.Permission(handler.VoterPIIAccess, "view voter personal data")
 
 	userHandler := handler.Users{Log: log, DB: db.Conn, Cache: cache}
 	authHandler := handler.Auths{Log: log, DB: db.Conn}
+	roleHandler := handler.Roles{Log: log, DB: db.Conn, Cache: cache}
+	accessHandler := handler.Accesses{Log: log, DB: db.Conn, Cache: cache}
+	electionHandler := handler.Elections{Log: log, DB: db.Conn, Cache: cache, CertifierKeys: certifierKeys}
+	candidateHandler := handler.Candidates{Log: log, DB: db.Conn, Cache: cache}
+	voterHandler := handler.Voters{Log: log, DB: db.Conn, Cache: cache}
+	fingerprintHandler := handler.Fingerprints{Log: log, DB: db.Conn, Cache: cache, Biometric: engine}
+	ballotHandler := handler.Ballots{Log: log, DB: db.Conn, Cache: cache}
+	credentialHandler := handler.Credentials{Log: log, DB: db.Conn, Cache: cache}
+	trusteeHandler := handler.Trustees{Log: log, DB: db.Conn, Cache: cache}
+	certificateHandler := handler.Certificates{Log: log, DB: db.Conn, Cache: cache}
+	resultHandler := handler.Results{Log: log, DB: db.Conn, Cache: cache, Hub: hub}
+	districtHandler := handler.Districts{Log: log, DB: db.Conn, Cache: cache}
+	regionHandler := handler.Regions{Log: log, DB: db.Conn, Cache: cache}
+	pollingStationHandler := handler.PollingStations{Log: log, DB: db.Conn, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{Log: log, DB: db.Conn, Cache: cache}
+	rlaHandler := handler.RLAs{Log: log, DB: db.Conn, Cache: cache}
+	scanHandler := handler.Scans{Log: log, DB: db.Conn, Cache: cache, Storage: store}
+	disputeHandler := handler.Disputes{Log: log, DB: db.Conn, Cache: cache, Storage: store}
+	peerHandler := handler.Peers{Log: log, DB: db.Conn, Cache: cache}
+	ledgerHandler := handler.Ledgers{Log: log, DB: db.Conn, Cache: cache, Key: peerKey}
 
 	router.POST("/login", mid.WrapMiddleware(publicMiddlewares, authHandler.Login))
-	router.GET("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.List))
-	router.GET("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.GetById))
-	router.POST("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.Create))
-	router.PUT("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.Update))
-	router.DELETE("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.Delete))
+	routes.GET("/users", "list user", userHandler.List)
+	routes.GET("/users/:id", "view user", userHandler.GetById)
+	routes.POST("/users", "create user", userHandler.Create)
+	routes.PUT("/users/:id", "update user", userHandler.Update)
+	routes.DELETE("/users/:id", "delete user", userHandler.Delete)
+	routes.GET("/users/:id/roles", "list user roles", roleHandler.ListUserRoles)
+	routes.POST("/users/:id/roles", "assign user role", roleHandler.Assign)
+	routes.DELETE("/users/:id/roles/:role_id", "unassign user role", roleHandler.Unassign)
+	routes.GET("/users/:id/access/explain", "explain user access", roleHandler.ExplainAccess)
+
+	routes.GET("/roles", "list roles", roleHandler.List)
+	routes.GET("/roles/:id", "view role", roleHandler.GetById)
+	routes.POST("/roles", "create role", roleHandler.Create)
+	routes.PUT("/roles/:id", "update role", roleHandler.Update)
+	routes.DELETE("/roles/:id", "delete role", roleHandler.Delete)
+	routes.GET("/roles/:id/access", "list role access", roleHandler.ListAccess)
+	routes.POST("/roles/:id/access", "grant role access", roleHandler.Grant)
+	routes.DELETE("/roles/:id/access/:access_id", "revoke role access", roleHandler.Revoke)
+	routes.GET("/roles/:id/parents", "list role parents", roleHandler.ListParents)
+	routes.POST("/roles/:id/parents", "add role parent", roleHandler.AddParent)
+	routes.DELETE("/roles/:id/parents/:parent_id", "remove role parent", roleHandler.RemoveParent)
+
+	routes.GET("/access", "list access", accessHandler.List)
+	routes.GET("/access/:id", "view access", accessHandler.GetById)
+	routes.POST("/access", "create access", accessHandler.Create)
+	routes.PUT("/access/:id", "update access", accessHandler.Update)
+	routes.DELETE("/access/:id", "delete access", accessHandler.Delete)
+
+	routes.GET("/elections", "list election", electionHandler.List)
+	routes.GET("/elections/:id", "view election", electionHandler.GetById)
+	routes.POST("/elections", "create election", electionHandler.Create)
+	routes.PUT("/elections/:id", "update election", electionHandler.Update)
+	routes.DELETE("/elections/:id", "delete election", electionHandler.Delete)
+	routes.GET("/elections/:id/transitions", "list election transitions", electionHandler.ListTransitions)
+	routes.POST("/elections/:id/transitions", "transition election", electionHandler.Transition)
+
+	routes.GET("/elections/:id/candidates", "list candidate", candidateHandler.List)
+	routes.GET("/elections/:id/candidates/:candidate_id", "view candidate", candidateHandler.GetById)
+	routes.POST("/elections/:id/candidates", "create candidate", candidateHandler.Create)
+	routes.PUT("/elections/:id/candidates/:candidate_id", "update candidate", candidateHandler.Update)
+	routes.DELETE("/elections/:id/candidates/:candidate_id", "delete candidate", candidateHandler.Delete)
+
+	routes.GET("/voters", "list voter", voterHandler.List)
+	routes.GET("/voters/:id", "view voter", voterHandler.GetById)
+	routes.POST("/voters", "create voter", voterHandler.Create)
+	routes.PUT("/voters/:id", "update voter", voterHandler.Update)
+	routes.DELETE("/voters/:id", "delete voter", voterHandler.Delete)
+	routes.GET("/voters/:id/duplicates", "list voter possible duplicates", voterHandler.Duplicates)
+	routes.GET("/voters/:id/fingerprint", "get voter fingerprint", fingerprintHandler.Get)
+	routes.PUT("/voters/:id/fingerprint", "enroll voter fingerprint", fingerprintHandler.Enroll)
+	routes.DELETE("/voters/:id/fingerprint", "delete voter fingerprint", fingerprintHandler.Delete)
+	routes.POST("/voters/:id/fingerprint/verify", "verify voter fingerprint", fingerprintHandler.Verify)
+	routes.POST("/fingerprints/identify", "identify fingerprint", fingerprintHandler.Identify)
+	routes.GET("/elections/:id/voters", "list election voter", voterHandler.ListEligible)
+	routes.POST("/elections/:id/voters", "register election voter", voterHandler.RegisterEligible)
+	routes.DELETE("/elections/:id/voters/:voter_id", "unregister election voter", voterHandler.UnregisterEligible)
+
+	router.GET("/elections/:id/credential-key", mid.WrapMiddleware(publicMiddlewares, credentialHandler.Key))
+	routes.POST("/elections/:id/credentials", "issue voting credential", credentialHandler.Issue)
+	router.POST("/elections/:id/ballots", mid.WrapMiddleware(publicMiddlewares, ballotHandler.Cast))
+	router.GET("/verify/:receipt", mid.WrapMiddleware(publicMiddlewares, ballotHandler.Verify))
+
+	routes.GET("/elections/:id/districts", "list districts", districtHandler.List)
+	routes.POST("/elections/:id/districts", "add district", districtHandler.Create)
+	routes.PUT("/elections/:id/districts/:district_id", "update district", districtHandler.Update)
+	routes.DELETE("/elections/:id/districts/:district_id", "delete district", districtHandler.Delete)
+	routes.PUT("/elections/:id/districts/:district_id/votes", "enter district party votes", districtHandler.SaveVotes)
+
+	routes.GET("/elections/:id/trustees", "list election trustees", trusteeHandler.List)
+	routes.POST("/elections/:id/trustees", "setup election trustees", trusteeHandler.Setup)
+	routes.POST("/elections/:id/trustees/share", "fetch trustee share", trusteeHandler.Share)
+	routes.GET("/elections/:id/encrypted-tally", "get encrypted tally", trusteeHandler.EncryptedTally)
+	routes.POST("/elections/:id/decryptions", "submit tally decryption", trusteeHandler.Decrypt)
+
+	routes.GET("/elections/:id/results", "view election results", resultHandler.Get)
+	routes.GET("/elections/:id/results/stream", "stream election results", resultHandler.Stream)
+	routes.GET("/elections/:id/seats", "view seat allocation", resultHandler.Seats)
+	router.GET("/elections/:id/certificate", mid.WrapMiddleware(publicMiddlewares, certificateHandler.Get))
+
+	routes.GET("/regions", "list regions", regionHandler.List)
+	routes.GET("/regions/:id", "view region", regionHandler.GetById)
+	routes.POST("/regions", "create region", regionHandler.Create)
+	routes.PUT("/regions/:id", "update region", regionHandler.Update)
+	routes.DELETE("/regions/:id", "delete region", regionHandler.Delete)
+
+	routes.GET("/polling-stations", "list polling stations", pollingStationHandler.List)
+	routes.GET("/polling-stations/:id", "view polling station", pollingStationHandler.GetById)
+	routes.POST("/polling-stations", "create polling station", pollingStationHandler.Create)
+	routes.PUT("/polling-stations/:id", "update polling station", pollingStationHandler.Update)
+	routes.DELETE("/polling-stations/:id", "delete polling station", pollingStationHandler.Delete)
+
+	routes.GET("/elections/:id/tally-forms", "list tally forms", recapitulationHandler.ListTallyForms)
+	routes.GET("/elections/:id/tally-forms/:polling_station_id", "view tally form", recapitulationHandler.GetTallyForm)
+	routes.PUT("/elections/:id/tally-forms/:polling_station_id", "submit tally form", recapitulationHandler.SubmitTallyForm)
+	routes.PUT("/elections/:id/tally-forms/:polling_station_id/status", "review tally form", recapitulationHandler.ReviewTallyForm)
+	routes.GET("/elections/:id/tally-forms/:polling_station_id/scans", "list tally form scans", scanHandler.List)
+	routes.POST("/elections/:id/tally-forms/:polling_station_id/scans", "upload tally form scan", scanHandler.Upload)
+	routes.GET("/elections/:id/tally-forms/:polling_station_id/scans/:sha256", "download tally form scan", scanHandler.Download)
+	routes.GET("/elections/:id/recapitulations", "list recapitulations", recapitulationHandler.List)
+	routes.GET("/elections/:id/recapitulations/:region_id", "view recapitulation", recapitulationHandler.Get)
+	routes.PUT("/elections/:id/recapitulations/:region_id", "submit recapitulation", recapitulationHandler.Submit)
+	routes.PUT("/elections/:id/recapitulations/:region_id/status", "review recapitulation", recapitulationHandler.Review)
+	router.GET("/elections/:id/rla", mid.WrapMiddleware(publicMiddlewares, rlaHandler.Get))
+	routes.POST("/elections/:id/rla", "start risk-limiting audit", rlaHandler.Create)
+	router.GET("/elections/:id/rla/draws", mid.WrapMiddleware(publicMiddlewares, rlaHandler.ListDraws))
+	routes.POST("/elections/:id/rla/draws", "draw risk-limiting audit sample", rlaHandler.Draw)
+	routes.POST("/elections/:id/rla/interpretations", "record risk-limiting audit interpretation", rlaHandler.Interpret)
+
+	routes.GET("/elections/:id/disputes", "list disputes", disputeHandler.List)
+	routes.POST("/elections/:id/disputes", "file dispute", disputeHandler.Create)
+	routes.GET("/elections/:id/disputes/:dispute_id", "get dispute", disputeHandler.Get)
+	routes.POST("/elections/:id/disputes/:dispute_id/transitions", "move dispute", disputeHandler.Transition)
+	routes.POST("/elections/:id/disputes/:dispute_id/evidences", "upload dispute evidence", disputeHandler.UploadEvidence)
+	routes.GET("/elections/:id/disputes/:dispute_id/evidences/:sha256", "download dispute evidence", disputeHandler.DownloadEvidence)
+
+	routes.GET("/peers", "list peers", peerHandler.List)
+	routes.GET("/peers/:id", "get peer", peerHandler.GetById)
+	routes.POST("/peers", "register peer", peerHandler.Create)
+	routes.PUT("/peers/:id", "update peer", peerHandler.Update)
+	routes.PUT("/peers/:id/status", "change peer status", peerHandler.SetStatus)
+	router.GET("/peer", mid.WrapMiddleware(peerMiddlewares, peerHandler.Handshake))
+	router.GET("/ledger/head", mid.WrapMiddleware(peerMiddlewares, ledgerHandler.Head))
+	router.GET("/ledger/root", mid.WrapMiddleware(publicMiddlewares, ledgerHandler.Root))
+	router.GET("/ledger/entries", mid.WrapMiddleware(peerMiddlewares, ledgerHandler.Entries))
+	router.GET("/ledger/replicas", mid.WrapMiddleware(peerMiddlewares, ledgerHandler.Replicas))
+	routes.POST("/ledger/sync", "sync ledgers", ledgerHandler.Sync)
+	routes.GET("/ledger/divergences", "list ledger divergences", ledgerHandler.Divergences)
 
-	return router
+	return routes
 }
@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"net/http"
+)
+
+type AccessUC struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// Sync makes the access table match the access declared by the routes. New access is granted to the superuser
+// roles, so the cached permissions are dropped when access is added. Orphaned access is only reported.
+func (uc AccessUC) Sync(ctx context.Context, declared []model.Access) (model.AccessSync, int, error) {
+	accessRepo := repository.AccessRepository{Log: uc.Log, Db: uc.DB}
+	sync, err := accessRepo.Sync(ctx, declared)
+	if err != nil {
+		return sync, http.StatusInternalServerError, err
+	}
+
+	if len(sync.Added) > 0 {
+		authUC := AuthUC{Log: uc.Log, DB: uc.DB, Cache: uc.Cache}
+		if err := authUC.InvalidatePermissions(ctx); err != nil {
+			uc.Log.Error(err)
+		}
+	}
+
+	return sync, http.StatusOK, nil
+}
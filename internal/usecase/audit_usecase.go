@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type AuditUC struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Spool *audit.Spool
+}
+
+// Flush appends the records spooled while the audit log could not be written to, in the order they were spooled.
+// The records keep their place in the spool until they are appended, so a failed flush is retried by the next one.
+func (uc AuditUC) Flush(ctx context.Context) (int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return 0, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return 0, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	auditRepo := repository.AuditRepository{Db: uc.DB, Log: uc.Log}
+	added, err := uc.Spool.Drain(func(record audit.Record) error {
+		_, err := auditRepo.Append(ctx, record)
+		return err
+	})
+	if added > 0 {
+		uc.Log.Info(fmt.Sprintf("%d spooled audit records are appended", added))
+	}
+	if err != nil {
+		return added, uc.Log.Error(fmt.Errorf("audit records are left in the spool %s: %w", uc.Spool.Path, err))
+	}
+
+	return added, nil
+}
+
+// Run flushes the spool every interval until ctx is done
+func (uc AuditUC) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.Flush(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uc.Flush(ctx)
+		}
+	}
+}
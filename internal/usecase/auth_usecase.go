@@ -5,18 +5,38 @@ import (
 	"backend-election/internal/model"
 	"backend-election/internal/pkg/jwttoken"
 	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/policy"
+	"backend-election/internal/pkg/redis"
 	"backend-election/internal/repository"
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PermissionsPrefix prefixes the cached grants of every user
+const PermissionsPrefix = "permissions."
+
+// generationPrefix prefixes the counter of every user that InvalidatePermissions bumps, outside of PermissionsPrefix
+// so dropping every cached set keeps the counters
+const generationPrefix = "permissions-generation."
+
+// allGenerations is the counter bumped when the permissions of every user are invalidated
+const allGenerations = "permissions-generation"
+
+// permissionsTTL bounds how long a role change made outside the API takes to reach the cached permissions
+const permissionsTTL = 5 * time.Minute
+
 type AuthUC struct {
-	Log *logger.Logger
-	DB  *sql.DB
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
 }
 
 func (uc AuthUC) Login(ctx context.Context, loginRequest dto.LoginRequest) (string, int, error) {
@@ -44,3 +64,329 @@ func (uc AuthUC) Login(ctx context.Context, loginRequest dto.LoginRequest) (stri
 
 	return token, http.StatusOK, nil
 }
+
+// Authorize reports whether the grants of the user allow the access path. Scoped grants are evaluated against
+// the election and the region the route params point to, which are only looked up when a scoped grant needs them.
+// The grants of the user are cached as a Redis set, the database is asked when redis is not available.
+func (uc AuthUC) Authorize(ctx context.Context, userID int64, path string, params map[string]string) (bool, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return false, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return false, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	grants, err := uc.grants(ctx, userID, path)
+	if err != nil {
+		return false, err
+	}
+
+	var regionScoped bool
+	for _, grant := range grants {
+		if !grant.Scoped() {
+			return true, nil
+		}
+		regionScoped = regionScoped || grant.RegionID != 0
+	}
+	if len(grants) == 0 {
+		return false, nil
+	}
+
+	req, err := uc.request(ctx, path, params, regionScoped)
+	if err != nil {
+		return false, err
+	}
+
+	return policy.Allow(grants, req), nil
+}
+
+// HasAuth reports whether the grants of the user allow an access path that is not tied to an election or a region
+func (uc AuthUC) HasAuth(ctx context.Context, userID int64, path string) (bool, error) {
+	return uc.Authorize(ctx, userID, path, nil)
+}
+
+// grants returns the grants of the user for the path. An unscoped grant of the path is found in the cache
+// with a single lookup, the scoped grants need the whole set. The grants read from the database are only cached
+// when no invalidation ran since the read started, so stale grants are not written back over an invalidation.
+func (uc AuthUC) grants(ctx context.Context, userID int64, path string) ([]policy.Grant, error) {
+	key := permissionsKey(userID)
+	var generations map[string]string
+	if uc.Cache != nil {
+		isMember, exists := uc.Cache.IsMember(ctx, key, path)
+		if isMember {
+			return []policy.Grant{{Path: path}}, nil
+		}
+		if exists {
+			if members, ok := uc.Cache.Members(ctx, key); ok {
+				return grantsOf(members, path), nil
+			}
+		}
+
+		var err error
+		if generations, err = uc.Cache.Counters(ctx, generationKey(userID), allGenerations); err != nil {
+			uc.Log.Error(err)
+		}
+	}
+
+	authRepo := repository.AuthRepository{Log: uc.Log, Db: uc.DB}
+	grants, err := authRepo.Grants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		members = append(members, grant.String())
+	}
+	if generations != nil {
+		if _, err := uc.Cache.SetMembersIf(ctx, key, members, permissionsTTL, generations); err != nil {
+			uc.Log.Error(err)
+		}
+	}
+
+	return grantsOf(members, path), nil
+}
+
+// grantsOf parses the encoded grants of the path, skipping the empty member that marks a cached set
+func grantsOf(members []string, path string) []policy.Grant {
+	var list []policy.Grant
+	for _, member := range members {
+		if !strings.HasPrefix(member, path) {
+			continue
+		}
+		grant, err := policy.Parse(member)
+		if err == nil && grant.Path == path {
+			list = append(list, grant)
+		}
+	}
+	return list
+}
+
+// request reads the scope attributes of the request from the route params. The election is the :id of the
+// /elections/:id routes. The region is the one the route points to, directly or through a polling station or a dispute.
+func (uc AuthUC) request(ctx context.Context, path string, params map[string]string, withRegions bool) (policy.Request, error) {
+	req := policy.Request{Path: path}
+	_, route, _ := strings.Cut(path, " ")
+	if strings.HasPrefix(route, "/elections/:id") {
+		req.ElectionID, _ = strconv.ParseInt(params["id"], 10, 64)
+	}
+	if !withRegions {
+		return req, nil
+	}
+
+	regionID, err := uc.regionOf(ctx, route, req.ElectionID, params)
+	if err != nil || regionID == 0 {
+		return req, err
+	}
+
+	regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: regionID}}
+	regions, err := regionRepo.Ancestors(ctx)
+	if err != nil {
+		return req, err
+	}
+	for _, region := range regions {
+		req.Regions = append(req.Regions, region.ID)
+	}
+
+	return req, nil
+}
+
+// regionOf returns the region the route params point to, 0 when they point to no region or to an unknown one
+func (uc AuthUC) regionOf(ctx context.Context, route string, electionID int64, params map[string]string) (int64, error) {
+	param := func(key string) int64 {
+		value, _ := strconv.ParseInt(params[key], 10, 64)
+		return value
+	}
+
+	switch {
+	case param("region_id") > 0:
+		return param("region_id"), nil
+	case strings.HasPrefix(route, "/regions/:id"):
+		return param("id"), nil
+	case param("polling_station_id") > 0:
+		return uc.villageOf(ctx, param("polling_station_id"))
+	case strings.HasPrefix(route, "/polling-stations/:id"):
+		return uc.villageOf(ctx, param("id"))
+	case param("dispute_id") > 0:
+		disputeRepo := repository.DisputeRepository{Log: uc.Log, Db: uc.DB, DisputeEntity: model.Dispute{ID: param("dispute_id"), ElectionID: electionID}}
+		err := disputeRepo.Find(ctx)
+		if err == sql.ErrNoRows {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if disputeRepo.DisputeEntity.PollingStationID > 0 {
+			return uc.villageOf(ctx, disputeRepo.DisputeEntity.PollingStationID)
+		}
+		return disputeRepo.DisputeEntity.RegionID, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (uc AuthUC) villageOf(ctx context.Context, pollingStationID int64) (int64, error) {
+	pollingStationRepo := repository.PollingStationRepository{Log: uc.Log, Db: uc.DB, PollingStationEntity: model.PollingStation{ID: pollingStationID}}
+	err := pollingStationRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return pollingStationRepo.PollingStationEntity.VillageID, nil
+}
+
+// Explain tells why the user has or lacks the access path, for support to debug the permissions of a user.
+// It reads the roles from the database rather than the cached permissions, which may lag behind for 5 minutes.
+// When the user only holds scoped grants of the path, the election or the region to explain it in must be given.
+func (uc AuthUC) Explain(ctx context.Context, userID int64, path string, electionID int64, regionID int64) (model.AccessExplanation, int, error) {
+	explanation := model.AccessExplanation{UserID: userID, Path: path, Grants: make([]model.AccessGrant, 0)}
+	switch ctx.Err() {
+	case context.Canceled:
+		return explanation, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return explanation, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	accessRepo := repository.AccessRepository{Log: uc.Log, Db: uc.DB, AccessEntity: model.Access{Path: path}}
+	err := accessRepo.FindByPath(ctx)
+	if err == sql.ErrNoRows {
+		explanation.Reason = "no access is registered for the path, so no role can grant it"
+		return explanation, http.StatusOK, nil
+	} else if err != nil {
+		return explanation, http.StatusInternalServerError, err
+	}
+
+	roleRepo := repository.RoleRepository{Log: uc.Log, Db: uc.DB}
+	held, err := roleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return explanation, http.StatusInternalServerError, err
+	}
+	if len(held) == 0 {
+		explanation.Reason = "the user holds no role"
+		return explanation, http.StatusOK, nil
+	}
+
+	authRepo := repository.AuthRepository{Log: uc.Log, Db: uc.DB}
+	explanation.Grants, err = authRepo.Inheritance(ctx, userID, path)
+	if err != nil {
+		return explanation, http.StatusInternalServerError, err
+	}
+	if len(explanation.Grants) == 0 {
+		explanation.Reason = "none of the roles of the user grants the access, directly or through the roles they inherit"
+		return explanation, http.StatusOK, nil
+	}
+
+	roles, err := roleRepo.List(ctx)
+	if err != nil {
+		return explanation, http.StatusInternalServerError, err
+	}
+	byID := make(map[int64]model.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+	for _, grant := range explanation.Grants {
+		for i, role := range grant.Roles {
+			grant.Roles[i] = byID[role.ID]
+		}
+	}
+
+	for _, grant := range explanation.Grants {
+		if grant.ElectionID == 0 && grant.RegionID == 0 {
+			explanation.Allowed = true
+			explanation.Reason = grantedBy(grant)
+			return explanation, http.StatusOK, nil
+		}
+	}
+
+	scopes := make([]string, 0, len(explanation.Grants))
+	for _, grant := range explanation.Grants {
+		if scope := scopeOf(grant); !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if electionID == 0 && regionID == 0 {
+		return explanation, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("the access is only granted within %s, supply election_id or region_id", strings.Join(scopes, ", ")))
+	}
+
+	req := policy.Request{Path: path, ElectionID: electionID}
+	if regionID != 0 {
+		regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: regionID}}
+		regions, err := regionRepo.Ancestors(ctx)
+		if err != nil {
+			return explanation, http.StatusInternalServerError, err
+		}
+		for _, region := range regions {
+			req.Regions = append(req.Regions, region.ID)
+		}
+	}
+
+	for _, grant := range explanation.Grants {
+		if (policy.Grant{Path: path, ElectionID: grant.ElectionID, RegionID: grant.RegionID}).Allows(req) {
+			explanation.Allowed = true
+			explanation.Reason = grantedBy(grant) + ", within " + scopeOf(grant)
+			return explanation, http.StatusOK, nil
+		}
+	}
+	explanation.Reason = "the access is only granted within " + strings.Join(scopes, ", ") + ", which does not cover the election or the region asked"
+
+	return explanation, http.StatusOK, nil
+}
+
+// scopeOf describes the election and the region a scoped grant is limited to
+func scopeOf(grant model.AccessGrant) string {
+	switch {
+	case grant.ElectionID != 0 && grant.RegionID != 0:
+		return fmt.Sprintf("election %d in region %d", grant.ElectionID, grant.RegionID)
+	case grant.ElectionID != 0:
+		return fmt.Sprintf("election %d", grant.ElectionID)
+	default:
+		return fmt.Sprintf("region %d", grant.RegionID)
+	}
+}
+
+// grantedBy tells which role is granted the access and, when it is inherited, which role the user holds
+func grantedBy(grant model.AccessGrant) string {
+	held, granted := grant.Roles[0], grant.Roles[len(grant.Roles)-1]
+	if len(grant.Roles) == 1 {
+		return fmt.Sprintf("granted to the role %s held by the user", held.Name)
+	}
+	return fmt.Sprintf("granted to the role %s, inherited by the role %s held by the user", granted.Name, held.Name)
+}
+
+// InvalidatePermissions drops the cached permissions of the users, or of every user when none is given.
+// It must be called whenever the roles of a user or the access of a role change. The counters are bumped
+// before the sets are dropped, so a read of the grants started before can not cache them again.
+func (uc AuthUC) InvalidatePermissions(ctx context.Context, userIDs ...int64) error {
+	if uc.Cache == nil {
+		return nil
+	}
+
+	if len(userIDs) == 0 {
+		if err := uc.Cache.Bump(ctx, allGenerations); err != nil {
+			return err
+		}
+		return uc.Cache.DeleteByPrefix(ctx, PermissionsPrefix)
+	}
+
+	keys := make([]string, 0, len(userIDs))
+	generations := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, permissionsKey(userID))
+		generations = append(generations, generationKey(userID))
+	}
+
+	if err := uc.Cache.Bump(ctx, generations...); err != nil {
+		return err
+	}
+	return uc.Cache.Del(ctx, keys...)
+}
+
+func permissionsKey(userID int64) string {
+	return fmt.Sprintf("%s%d", PermissionsPrefix, userID)
+}
+
+func generationKey(userID int64) string {
+	return fmt.Sprintf("%s%d", generationPrefix, userID)
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/blindsig"
+	"backend-election/internal/pkg/ledger"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotVoter is returned when the authenticated user is not linked to a voter
+var ErrNotVoter = errors.New("user is not registered as a voter")
+
+type BallotUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// Cast stores the ballot of an anonymous voter and returns the receipt code of the ballot.
+// The ballot is accepted for a token signed by the credential key of the election, each token casts one ballot.
+// Only the hash of the receipt is stored, the code is shown to the voter once.
+func (uc BallotUC) Cast(ctx context.Context, electionID int64, ballotRequest dto.CastBallotRequest) (string, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return "", http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return "", http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return "", http.StatusNotFound, err
+	} else if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+
+	key, err := signingKey(ctx, uc.Log, uc.DB, electionID, false)
+	if err == sql.ErrNoRows {
+		return "", http.StatusForbidden, uc.Log.Error(ErrInvalidCredential)
+	} else if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	token, signature := ballotRequest.Credential.ToEntity()
+	if err := blindsig.Verify(&key.PublicKey, token, signature); err != nil {
+		return "", http.StatusForbidden, uc.Log.Error(ErrInvalidCredential)
+	}
+
+	candidateRepo := repository.CandidateRepository{Log: uc.Log, Db: uc.DB, CandidateEntity: model.Candidate{ElectionID: electionID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+
+	ballot := ballotRequest.ToEntity(electionID)
+	if len(electionRepo.ElectionEntity.PublicKey) > 0 {
+		if ballot.Ciphertexts, err = encryptedBallot(electionRepo.ElectionEntity, len(candidates), ballotRequest.Encrypted); err != nil {
+			return "", http.StatusBadRequest, uc.Log.Error(err)
+		}
+		ballot.Choices = []int64{}
+	} else if ballotRequest.Encrypted != nil {
+		return "", http.StatusBadRequest, uc.Log.Error(ErrNotEncrypted)
+	} else {
+		tally, err := NewTally(electionRepo.ElectionEntity.CountingMethod)
+		if err != nil {
+			return "", http.StatusInternalServerError, uc.Log.Error(err)
+		}
+		if err := tally.Validate(ballotRequest.Choices); err != nil {
+			return "", http.StatusBadRequest, uc.Log.Error(err)
+		}
+
+		isCandidate := make(map[int64]bool, len(candidates))
+		for _, candidate := range candidates {
+			isCandidate[candidate.ID] = true
+		}
+		for _, choice := range ballotRequest.Choices {
+			if !isCandidate[choice] {
+				return "", http.StatusBadRequest, uc.Log.Error(fmt.Errorf("choice %d is not a candidate of the election", choice))
+			}
+		}
+	}
+
+	receipt, err := ledger.NewReceipt()
+	if err != nil {
+		return "", http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	ballotRepo := repository.BallotRepository{Log: uc.Log, Db: uc.DB, BallotEntity: ballot}
+	ballotRepo.BallotEntity.ReceiptHash = ledger.ReceiptHash(receipt)
+	tokenHash := sha256.Sum256(token)
+	err = ballotRepo.Cast(ctx, tokenHash[:])
+	switch err {
+	case nil:
+		return receipt, http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return "", http.StatusNotFound, err
+	case repository.ErrElectionNotOpen, repository.ErrCredentialUsed:
+		return "", http.StatusConflict, err
+	default:
+		return "", http.StatusInternalServerError, err
+	}
+}
+
+// VerifyReceipt returns the inclusion proof of the ballot of the receipt against the current root of the ledger.
+// The ballot is found but not included yet while it waits for its batch, the returned proof is then empty.
+func (uc BallotUC) VerifyReceipt(ctx context.Context, receipt string) (model.Ballot, ledger.Proof, int, error) {
+	var proof ledger.Proof
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.Ballot{}, proof, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.Ballot{}, proof, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	ballotRepo := repository.BallotRepository{Log: uc.Log, Db: uc.DB, BallotEntity: model.Ballot{ReceiptHash: ledger.ReceiptHash(receipt)}}
+	if err := ballotRepo.FindByReceipt(ctx); err == sql.ErrNoRows {
+		return ballotRepo.BallotEntity, proof, http.StatusNotFound, err
+	} else if err != nil {
+		return ballotRepo.BallotEntity, proof, http.StatusInternalServerError, err
+	}
+	if ballotRepo.BallotEntity.LedgerHeight == 0 {
+		return ballotRepo.BallotEntity, proof, http.StatusOK, nil
+	}
+
+	ledgerRepo := repository.LedgerRepository{Log: uc.Log, Db: uc.DB, OriginPeerID: model.LedgerOriginLocal}
+	head, err := ledgerRepo.Head(ctx)
+	if err != nil {
+		return ballotRepo.BallotEntity, proof, http.StatusInternalServerError, err
+	}
+	entries, err := ledgerRepo.Entries(ctx, ballotRepo.BallotEntity.LedgerHeight, 1)
+	if err != nil {
+		return ballotRepo.BallotEntity, proof, http.StatusInternalServerError, err
+	}
+	hashes, err := ledgerRepo.Hashes(ctx, head.Height)
+	if err != nil {
+		return ballotRepo.BallotEntity, proof, http.StatusInternalServerError, err
+	}
+	if len(entries) == 0 || int64(len(hashes)) != head.Height {
+		return ballotRepo.BallotEntity, proof, http.StatusInternalServerError, uc.Log.Error(ledger.ErrInvalidEntry)
+	}
+
+	proof = ledger.Proof{
+		Height:    entries[0].Height,
+		Payload:   entries[0].Payload,
+		PrevHash:  entries[0].PrevHash,
+		TreeSize:  head.Height,
+		Root:      head.Root,
+		AuditPath: ledger.AuditPath(hashes, entries[0].Height-1),
+	}
+	return ballotRepo.BallotEntity, proof, http.StatusOK, nil
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/certificate"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type CertificateUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// Get returns the signed certificate of a certified election
+func (uc CertificateUC) Get(ctx context.Context, electionID int64) (model.Certificate, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.Certificate{}, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.Certificate{}, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	certificateRepo := repository.CertificateRepository{Log: uc.Log, Db: uc.DB, CertificateEntity: model.Certificate{ElectionID: electionID}}
+	if err := certificateRepo.Find(ctx); err == sql.ErrNoRows {
+		return certificateRepo.CertificateEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return certificateRepo.CertificateEntity, http.StatusInternalServerError, err
+	}
+
+	return certificateRepo.CertificateEntity, http.StatusOK, nil
+}
+
+// certify builds the results document of the tallied election, signs it with the certifier keys and certifies
+// the election together with its certificate. The results are frozen once the election is tallied.
+func (uc ElectionUC) certify(ctx context.Context, electionRepo *repository.ElectionRepository) (model.ElectionTransition, int, error) {
+	var transition model.ElectionTransition
+	if len(uc.CertifierKeys) == 0 {
+		return transition, http.StatusServiceUnavailable, uc.Log.Error(certificate.ErrNoKeys)
+	}
+
+	election := electionRepo.ElectionEntity
+	document := dto.CertificateDocument{Version: dto.CertificateVersion, CertifiedAt: time.Now().UTC().Format(time.RFC3339)}
+	document.Election.FromEntity(election)
+
+	resultUC := ResultUC{Log: uc.Log, DB: uc.DB}
+	result, candidates, statusCode, err := resultUC.Count(ctx, election.ID)
+	if err != nil {
+		return transition, statusCode, err
+	}
+	document.Results.FromEntity(election.ID, result, candidates)
+
+	snapshot, statusCode, err := resultUC.Snapshot(ctx, election.ID)
+	if err != nil {
+		return transition, statusCode, err
+	}
+	document.Tally.FromEntity(snapshot)
+
+	allocation, statusCode, err := resultUC.Seats(ctx, election.ID)
+	if err != nil {
+		return transition, statusCode, err
+	}
+	if len(allocation.Districts) > 0 {
+		document.Seats = &dto.SeatAllocationResponse{}
+		document.Seats.FromEntity(election.ID, allocation)
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return transition, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+	canonical, err := certificate.Canonical(data)
+	if err != nil {
+		return transition, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	cert := model.Certificate{Document: canonical, SHA256: certificate.Checksum(canonical), CertifiedAt: document.CertifiedAt}
+	for _, signature := range certificate.Sign(canonical, uc.CertifierKeys) {
+		cert.Signatures = append(cert.Signatures, model.CertificateSignature{PublicKey: signature.PublicKey, Signature: signature.Signature})
+	}
+
+	transition, err = electionRepo.Certify(ctx, cert)
+	if err == repository.ErrElectionStatusChanged {
+		return transition, http.StatusConflict, err
+	} else if err != nil {
+		return transition, http.StatusInternalServerError, err
+	}
+
+	return transition, http.StatusOK, nil
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/blindsig"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/repository"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrInvalidCredential is returned when a ballot is cast with a token that is not signed by the credential key
+	ErrInvalidCredential = errors.New("credential is invalid")
+	// ErrNoCredentialKey is returned when the credential key of an election that was opened is missing
+	ErrNoCredentialKey = errors.New("credential key not found")
+)
+
+type CredentialUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// Key returns the public key that signs the voting credentials of the election. The key is made when the
+// election opens, so an election that was never opened answers ErrElectionNotOpen. Key never creates a key.
+func (uc CredentialUC) Key(ctx context.Context, electionID int64) (*rsa.PublicKey, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	key, err := signingKey(ctx, uc.Log, uc.DB, electionID, false)
+	if err == sql.ErrNoRows {
+		switch electionRepo.ElectionEntity.Status {
+		case model.ElectionStatusDraft, model.ElectionStatusScheduled:
+			return nil, http.StatusConflict, repository.ErrElectionNotOpen
+		default:
+			return nil, http.StatusNotFound, ErrNoCredentialKey
+		}
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return &key.PublicKey, http.StatusOK, nil
+}
+
+// Issue signs the blinded token of the authenticated voter. Every eligible voter gets one credential per election,
+// the blinded token hides the credential so the signature can not be linked to the ballot later.
+func (uc CredentialUC) Issue(ctx context.Context, credential model.Credential) ([]byte, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	voterRepo := repository.VoterRepository{Log: uc.Log, Db: uc.DB, VoterEntity: model.Voter{UserID: ctx.Value(myctx.Key("user_id")).(int64)}}
+	if err := voterRepo.FindByUserID(ctx); err == sql.ErrNoRows {
+		return nil, http.StatusForbidden, uc.Log.Error(ErrNotVoter)
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: credential.ElectionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// the key of an election opened before it was made on opening is made here, never before the election opens
+	if electionRepo.ElectionEntity.Status != model.ElectionStatusOpen {
+		return nil, http.StatusConflict, uc.Log.Error(repository.ErrElectionNotOpen)
+	}
+	key, err := signingKey(ctx, uc.Log, uc.DB, credential.ElectionID, true)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// the message is signed before the voter is recorded, so an invalid message does not use up the credential
+	blindSignature, err := blindsig.Sign(key, credential.Blinded)
+	if errors.Is(err, blindsig.ErrInvalidMessage) {
+		return nil, http.StatusBadRequest, uc.Log.Error(err)
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	credentialRepo := repository.CredentialRepository{Log: uc.Log, Db: uc.DB, CredentialEntity: credential}
+	credentialRepo.CredentialEntity.VoterID = voterRepo.VoterEntity.ID
+	err = credentialRepo.Issue(ctx)
+	switch err {
+	case nil:
+		return blindSignature, http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return nil, http.StatusNotFound, err
+	case repository.ErrNotEligible:
+		return nil, http.StatusForbidden, err
+	case repository.ErrElectionNotOpen, repository.ErrCredentialIssued:
+		return nil, http.StatusConflict, err
+	default:
+		return nil, http.StatusInternalServerError, err
+	}
+}
+
+// CreateKey makes the credential key of the election unless it has one, when the election opens
+func (uc CredentialUC) CreateKey(ctx context.Context, electionID int64) error {
+	_, err := signingKey(ctx, uc.Log, uc.DB, electionID, true)
+	return err
+}
+
+// signingKey returns the credential key of the election. With create, a key is made when the election has none yet.
+// Without it sql.ErrNoRows is returned, no credential of the election was issued then.
+func signingKey(ctx context.Context, log *logger.Logger, db *sql.DB, electionID int64, create bool) (*rsa.PrivateKey, error) {
+	credentialRepo := repository.CredentialRepository{Log: log, Db: db, CredentialEntity: model.Credential{ElectionID: electionID}}
+	key, err := credentialRepo.FindKey(ctx)
+	if err == sql.ErrNoRows && create {
+		privateKey, err := blindsig.GenerateKey()
+		if err != nil {
+			return nil, log.Error(err)
+		}
+		if err := credentialRepo.SaveKey(ctx, x509.MarshalPKCS1PrivateKey(privateKey)); err != nil {
+			return nil, err
+		}
+		key, err = credentialRepo.FindKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, log.Error(err)
+	}
+	return privateKey, nil
+}
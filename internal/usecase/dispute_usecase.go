@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrDisputeResolved is returned when evidence is attached to a resolved dispute
+	ErrDisputeResolved = errors.New("dispute is resolved")
+	// ErrUnsupportedEvidence is returned when an uploaded evidence is not a JPEG, PNG, WebP or PDF file
+	ErrUnsupportedEvidence = errors.New("evidence must be a jpeg, png, webp or pdf file")
+)
+
+// disputeTransitions lists the statuses a dispute may move to from each status.
+// A dispute can be dismissed as resolved at any stage, a resolved dispute is final.
+var disputeTransitions = map[string][]string{
+	model.DisputeStatusFiled:          {model.DisputeStatusUnderReview, model.DisputeStatusResolved},
+	model.DisputeStatusUnderReview:    {model.DisputeStatusRecountOrdered, model.DisputeStatusResolved},
+	model.DisputeStatusRecountOrdered: {model.DisputeStatusResolved},
+}
+
+// CanDisputeTransition reports whether a dispute may move from status `from` to status `to`
+func CanDisputeTransition(from string, to string) bool {
+	for _, status := range disputeTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type DisputeUC struct {
+	Log     *logger.Logger
+	DB      *sql.DB
+	Cache   *redis.Cache
+	Storage storage.Storage
+}
+
+// File files a dispute against the tally form of a polling station or the recapitulation of a region
+func (uc DisputeUC) File(ctx context.Context, dispute model.Dispute) (model.Dispute, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return dispute, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return dispute, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	if dispute.PollingStationID > 0 {
+		pollingStationRepo := repository.PollingStationRepository{Log: uc.Log, Db: uc.DB, PollingStationEntity: model.PollingStation{ID: dispute.PollingStationID}}
+		if err := pollingStationRepo.Find(ctx); err == sql.ErrNoRows {
+			return dispute, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("polling station %d does not exist", dispute.PollingStationID))
+		} else if err != nil {
+			return dispute, http.StatusInternalServerError, err
+		}
+	} else {
+		regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: dispute.RegionID}}
+		if err := regionRepo.Find(ctx); err == sql.ErrNoRows {
+			return dispute, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("region %d does not exist", dispute.RegionID))
+		} else if err != nil {
+			return dispute, http.StatusInternalServerError, err
+		}
+	}
+
+	disputeRepo := repository.DisputeRepository{Log: uc.Log, Db: uc.DB, DisputeEntity: dispute}
+	switch err := disputeRepo.Create(ctx); err {
+	case nil:
+		return disputeRepo.DisputeEntity, http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return dispute, http.StatusNotFound, err
+	case repository.ErrDisputesClosed:
+		return dispute, http.StatusConflict, err
+	default:
+		return dispute, http.StatusInternalServerError, err
+	}
+}
+
+// Get returns the dispute with its status changes and evidences
+func (uc DisputeUC) Get(ctx context.Context, electionID int64, disputeID int64) (model.Dispute, []model.DisputeTransition, []model.DisputeEvidence, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.Dispute{}, nil, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.Dispute{}, nil, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	dispute, statusCode, err := uc.find(ctx, electionID, disputeID)
+	if err != nil {
+		return dispute, nil, nil, statusCode, err
+	}
+
+	disputeRepo := repository.DisputeRepository{Log: uc.Log, Db: uc.DB, DisputeEntity: dispute}
+	transitions, err := disputeRepo.ListTransitions(ctx)
+	if err != nil {
+		return dispute, nil, nil, http.StatusInternalServerError, err
+	}
+
+	evidenceRepo := repository.DisputeEvidenceRepository{Log: uc.Log, Db: uc.DB, DisputeEvidenceEntity: model.DisputeEvidence{DisputeID: dispute.ID}}
+	evidences, err := evidenceRepo.List(ctx)
+	if err != nil {
+		return dispute, nil, nil, http.StatusInternalServerError, err
+	}
+
+	return dispute, transitions, evidences, http.StatusOK, nil
+}
+
+// Transition moves the dispute to the requested status. Ordering a recount reopens the tally form or recapitulation
+// under dispute, and the cached recapitulations of its region and every region above it are dropped.
+// A reopened tally form is published to the result streams, taking its votes back out of the sum.
+func (uc DisputeUC) Transition(ctx context.Context, electionID int64, disputeID int64, to string, note string) (model.Dispute, model.DisputeTransition, int, error) {
+	var transition model.DisputeTransition
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.Dispute{}, transition, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.Dispute{}, transition, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	dispute, statusCode, err := uc.find(ctx, electionID, disputeID)
+	if err != nil {
+		return dispute, transition, statusCode, err
+	}
+
+	if !CanDisputeTransition(dispute.Status, to) {
+		return dispute, transition, http.StatusConflict, uc.Log.Error(fmt.Errorf("dispute can not move from %s to %s", dispute.Status, to))
+	}
+
+	// the region is read before the recount, a failed lookup leaves nothing to undo
+	regionID := dispute.RegionID
+	if to == model.DisputeStatusRecountOrdered && dispute.PollingStationID > 0 {
+		pollingStationRepo := repository.PollingStationRepository{Log: uc.Log, Db: uc.DB, PollingStationEntity: model.PollingStation{ID: dispute.PollingStationID}}
+		if err := pollingStationRepo.Find(ctx); err != nil {
+			return dispute, transition, http.StatusInternalServerError, err
+		}
+		regionID = pollingStationRepo.PollingStationEntity.VillageID
+	}
+
+	disputeRepo := repository.DisputeRepository{Log: uc.Log, Db: uc.DB, DisputeEntity: dispute}
+	transition, update, err := disputeRepo.Transition(ctx, dispute.Status, to, note)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return dispute, transition, http.StatusNotFound, err
+	case repository.ErrDisputeStatusChanged, repository.ErrNothingToRecount, repository.ErrRecountPending, repository.ErrElectionNotClosed:
+		return dispute, transition, http.StatusConflict, err
+	default:
+		return dispute, transition, http.StatusInternalServerError, err
+	}
+
+	if to == model.DisputeStatusRecountOrdered {
+		recapitulationUC := RecapitulationUC{Log: uc.Log, DB: uc.DB, Cache: uc.Cache}
+		recapitulationUC.invalidate(ctx, electionID, regionID)
+		// the recount moved the tally version, the streams learn of it even when the form was not approved yet
+		if update.Version > 0 {
+			update.VillageID = regionID
+			recapitulationUC.publish(ctx, update)
+		}
+	}
+	return disputeRepo.DisputeEntity, transition, http.StatusOK, nil
+}
+
+// UploadEvidence stores the file and attaches it to the dispute. The file is stored once per checksum,
+// uploading the same file again returns the existing evidence with created false.
+func (uc DisputeUC) UploadEvidence(ctx context.Context, electionID int64, disputeID int64, filename string, r io.Reader) (model.DisputeEvidence, bool, int, error) {
+	var evidence model.DisputeEvidence
+	switch ctx.Err() {
+	case context.Canceled:
+		return evidence, false, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return evidence, false, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	dispute, statusCode, err := uc.find(ctx, electionID, disputeID)
+	if err != nil {
+		return evidence, false, statusCode, err
+	}
+	if dispute.Status == model.DisputeStatusResolved {
+		return evidence, false, http.StatusConflict, uc.Log.Error(ErrDisputeResolved)
+	}
+
+	upload, err := storage.Spool(r, storage.MaxSize())
+	if err == storage.ErrTooLarge {
+		return evidence, false, http.StatusRequestEntityTooLarge, uc.Log.Error(err)
+	} else if err != nil {
+		return evidence, false, http.StatusBadRequest, uc.Log.Error(err)
+	}
+	defer upload.Close()
+
+	if !scanContentTypes[upload.ContentType] {
+		return evidence, false, http.StatusUnsupportedMediaType, uc.Log.Error(ErrUnsupportedEvidence)
+	}
+
+	if err := uc.Storage.Put(ctx, upload.Key, upload.File, upload.Size, upload.ContentType); err != nil {
+		return evidence, false, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	evidenceRepo := repository.DisputeEvidenceRepository{Log: uc.Log, Db: uc.DB, DisputeEvidenceEntity: model.DisputeEvidence{
+		DisputeID:   dispute.ID,
+		SHA256:      upload.Key,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Filename:    filename,
+	}}
+	created, err := evidenceRepo.Save(ctx)
+	if err != nil {
+		return evidence, false, http.StatusInternalServerError, err
+	}
+
+	return evidenceRepo.DisputeEvidenceEntity, created, http.StatusOK, nil
+}
+
+// OpenEvidence returns an evidence of the dispute with a reader of its content. The caller closes the reader.
+func (uc DisputeUC) OpenEvidence(ctx context.Context, electionID int64, disputeID int64, sha256 string) (model.DisputeEvidence, io.ReadCloser, int, error) {
+	var evidence model.DisputeEvidence
+	switch ctx.Err() {
+	case context.Canceled:
+		return evidence, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return evidence, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	if !storage.ValidKey(sha256) {
+		return evidence, nil, http.StatusBadRequest, uc.Log.Error(storage.ErrInvalidKey)
+	}
+
+	dispute, statusCode, err := uc.find(ctx, electionID, disputeID)
+	if err != nil {
+		return evidence, nil, statusCode, err
+	}
+
+	evidenceRepo := repository.DisputeEvidenceRepository{Log: uc.Log, Db: uc.DB, DisputeEvidenceEntity: model.DisputeEvidence{DisputeID: dispute.ID, SHA256: sha256}}
+	if err := evidenceRepo.Find(ctx); err == sql.ErrNoRows {
+		return evidence, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return evidence, nil, http.StatusInternalServerError, err
+	}
+
+	content, err := uc.Storage.Get(ctx, sha256)
+	if err != nil {
+		return evidence, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	return evidenceRepo.DisputeEvidenceEntity, content, http.StatusOK, nil
+}
+
+func (uc DisputeUC) find(ctx context.Context, electionID int64, disputeID int64) (model.Dispute, int, error) {
+	disputeRepo := repository.DisputeRepository{Log: uc.Log, Db: uc.DB, DisputeEntity: model.Dispute{ID: disputeID, ElectionID: electionID}}
+	if err := disputeRepo.Find(ctx); err == sql.ErrNoRows {
+		return disputeRepo.DisputeEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return disputeRepo.DisputeEntity, http.StatusInternalServerError, err
+	}
+
+	return disputeRepo.DisputeEntity, http.StatusOK, nil
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// electionTransitions lists the allowed target statuses for every election status
+var electionTransitions = map[string][]string{
+	model.ElectionStatusDraft:     {model.ElectionStatusScheduled},
+	model.ElectionStatusScheduled: {model.ElectionStatusDraft, model.ElectionStatusOpen},
+	model.ElectionStatusOpen:      {model.ElectionStatusClosed},
+	model.ElectionStatusClosed:    {model.ElectionStatusTallied},
+	model.ElectionStatusTallied:   {model.ElectionStatusCertified},
+}
+
+// CanTransition reports whether an election may move from status `from` to status `to`
+func CanTransition(from string, to string) bool {
+	for _, status := range electionTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type ElectionUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+	// CertifierKeys sign the certificate of an election when it is certified
+	CertifierKeys []ed25519.PrivateKey
+}
+
+func (uc ElectionUC) Transition(ctx context.Context, electionID int64, to string) (model.ElectionTransition, int, error) {
+	var transition model.ElectionTransition
+	switch ctx.Err() {
+	case context.Canceled:
+		return transition, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return transition, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return transition, http.StatusNotFound, err
+	} else if err != nil {
+		return transition, http.StatusInternalServerError, err
+	}
+
+	from := electionRepo.ElectionEntity.Status
+	if !CanTransition(from, to) {
+		return transition, http.StatusConflict, uc.Log.Error(fmt.Errorf("illegal election transition from %s to %s", from, to))
+	}
+
+	if to == model.ElectionStatusCertified {
+		return uc.certify(ctx, &electionRepo)
+	}
+
+	transition, err := electionRepo.Transition(ctx, from, to)
+	if err == repository.ErrElectionStatusChanged {
+		return transition, http.StatusConflict, err
+	} else if err != nil {
+		return transition, http.StatusInternalServerError, err
+	}
+
+	// the voters blind their credentials with the key as soon as the election is open. Issuing a credential
+	// makes the key as well, so a failure here does not undo the transition.
+	if to == model.ElectionStatusOpen {
+		if err := (CredentialUC{Log: uc.Log, DB: uc.DB}).CreateKey(ctx, electionID); err != nil {
+			uc.Log.Error(err)
+		}
+	}
+
+	return transition, http.StatusOK, nil
+}
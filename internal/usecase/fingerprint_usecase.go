@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/biometric"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+var (
+	// ErrTooFewMinutiae is returned when a fingerprint to enroll has too few minutiae to be matched reliably
+	ErrTooFewMinutiae = fmt.Errorf("fingerprint must have at least %d minutiae", biometric.MinMinutiae)
+	// ErrFingerprintDuplicate is returned when the fingerprint to enroll matches the fingerprint of another voter
+	ErrFingerprintDuplicate = errors.New("fingerprint matches the fingerprint of another voter")
+)
+
+type FingerprintUC struct {
+	Log       *logger.Logger
+	DB        *sql.DB
+	Biometric *biometric.Engine
+}
+
+// Enroll seals and stores the fingerprint of the voter, replacing the one enrolled before.
+// The registry is searched first, when the fingerprint matches other voters they are returned with ErrFingerprintDuplicate
+// unless the request allows duplicates.
+func (uc FingerprintUC) Enroll(ctx context.Context, voterID int64, request dto.FingerprintEnrollRequest) (model.VoterFingerprint, []model.FingerprintMatch, int, error) {
+	var fingerprint model.VoterFingerprint
+	switch ctx.Err() {
+	case context.Canceled:
+		return fingerprint, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return fingerprint, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	voterRepo := repository.VoterRepository{Log: uc.Log, Db: uc.DB, VoterEntity: model.Voter{ID: voterID}}
+	if err := voterRepo.Find(ctx); err == sql.ErrNoRows {
+		return fingerprint, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return fingerprint, nil, http.StatusInternalServerError, err
+	}
+
+	data, err := request.Decode()
+	if err != nil {
+		return fingerprint, nil, http.StatusBadRequest, uc.Log.Error(err)
+	}
+	template, err := request.ToTemplate()
+	if err != nil {
+		return fingerprint, nil, http.StatusBadRequest, uc.Log.Error(err)
+	}
+	if len(template.Minutiae) < biometric.MinMinutiae {
+		return fingerprint, nil, http.StatusBadRequest, uc.Log.Error(ErrTooFewMinutiae)
+	}
+
+	matches, statusCode, err := uc.identify(ctx, template, voterID)
+	if err != nil {
+		return fingerprint, nil, statusCode, err
+	}
+	if len(matches) > 0 && !request.AllowDuplicate {
+		return fingerprint, matches, http.StatusConflict, uc.Log.Error(ErrFingerprintDuplicate)
+	}
+
+	sealed, err := uc.Biometric.Seal(data, voterID)
+	if err != nil {
+		return fingerprint, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	fingerprintRepo := repository.VoterFingerprintRepository{Log: uc.Log, Db: uc.DB, VoterFingerprintEntity: model.VoterFingerprint{
+		VoterID:        voterID,
+		Format:         string(template.Format),
+		FingerPosition: template.FingerPosition,
+		MinutiaeCount:  len(template.Minutiae),
+		Quality:        template.Quality,
+		Template:       sealed,
+	}}
+	if err := fingerprintRepo.Save(ctx); err != nil {
+		return fingerprint, nil, http.StatusInternalServerError, err
+	}
+
+	return fingerprintRepo.VoterFingerprintEntity, matches, http.StatusOK, nil
+}
+
+// Verify compares the fingerprint read at check-in with the fingerprint enrolled for the voter (1:1)
+func (uc FingerprintUC) Verify(ctx context.Context, voterID int64, request dto.FingerprintRequest) (model.FingerprintMatch, int, error) {
+	var match = model.FingerprintMatch{VoterID: voterID}
+	switch ctx.Err() {
+	case context.Canceled:
+		return match, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return match, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	probe, err := request.ToTemplate()
+	if err != nil {
+		return match, http.StatusBadRequest, uc.Log.Error(err)
+	}
+
+	fingerprintRepo := repository.VoterFingerprintRepository{Log: uc.Log, Db: uc.DB, VoterFingerprintEntity: model.VoterFingerprint{VoterID: voterID}}
+	if err := fingerprintRepo.Find(ctx); err == sql.ErrNoRows {
+		return match, http.StatusNotFound, err
+	} else if err != nil {
+		return match, http.StatusInternalServerError, err
+	}
+
+	enrolled, err := uc.open(fingerprintRepo.VoterFingerprintEntity)
+	if err != nil {
+		return match, http.StatusInternalServerError, err
+	}
+
+	match.Score = uc.Biometric.Matcher.Score(probe, enrolled)
+	return match, http.StatusOK, nil
+}
+
+// Identify searches the fingerprint among the fingerprints of all voters (1:N).
+// The voters scoring at least the threshold are returned, best match first.
+func (uc FingerprintUC) Identify(ctx context.Context, request dto.FingerprintRequest) ([]model.FingerprintMatch, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	probe, err := request.ToTemplate()
+	if err != nil {
+		return nil, http.StatusBadRequest, uc.Log.Error(err)
+	}
+
+	return uc.identify(ctx, probe, 0)
+}
+
+func (uc FingerprintUC) identify(ctx context.Context, probe biometric.Template, exceptVoterID int64) ([]model.FingerprintMatch, int, error) {
+	var matches []model.FingerprintMatch = make([]model.FingerprintMatch, 0)
+
+	fingerprintRepo := repository.VoterFingerprintRepository{Log: uc.Log, Db: uc.DB, VoterFingerprintEntity: model.VoterFingerprint{VoterID: exceptVoterID}}
+	err := fingerprintRepo.Each(ctx, func(fingerprint model.VoterFingerprint) error {
+		enrolled, err := uc.open(fingerprint)
+		if err != nil {
+			return err
+		}
+		if score := uc.Biometric.Matcher.Score(probe, enrolled); uc.Biometric.Matcher.Match(score) {
+			matches = append(matches, model.FingerprintMatch{VoterID: fingerprint.VoterID, Score: score})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, http.StatusOK, nil
+}
+
+// open decrypts and parses an enrolled fingerprint
+func (uc FingerprintUC) open(fingerprint model.VoterFingerprint) (biometric.Template, error) {
+	data, err := uc.Biometric.Open(fingerprint.Template, fingerprint.VoterID)
+	if err != nil {
+		return biometric.Template{}, uc.Log.Error(fmt.Errorf("fingerprint of voter %d: %w", fingerprint.VoterID, err))
+	}
+
+	template, err := biometric.Parse(biometric.Format(fingerprint.Format), data)
+	if err != nil {
+		return biometric.Template{}, uc.Log.Error(fmt.Errorf("fingerprint of voter %d: %w", fingerprint.VoterID, err))
+	}
+	return template, nil
+}
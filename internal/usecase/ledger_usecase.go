@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/ledger"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/peer"
+	"backend-election/internal/repository"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ledgerSyncBatch is how many entries are read from a peer per request
+const ledgerSyncBatch = 500
+
+// ErrNoRemoteID is returned for a peer whose remote_id is not set, this node can not sign its calls to it
+var ErrNoRemoteID = errors.New("remote_id of the peer is not set")
+
+type LedgerUC struct {
+	Log  *logger.Logger
+	DB   *sql.DB
+	Key  ed25519.PrivateKey
+	HTTP *http.Client
+}
+
+// Sync replicates the ledgers of all active peers and cross verifies the replicas they keep.
+// A peer that can not be reached or whose ledger diverged is reported in its result, the other peers are still synced.
+func (uc LedgerUC) Sync(ctx context.Context) ([]model.LedgerSync, int, error) {
+	var list []model.LedgerSync = make([]model.LedgerSync, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	if uc.Key == nil {
+		return list, http.StatusServiceUnavailable, uc.Log.Error(peer.ErrNoKey)
+	}
+
+	peerRepo := repository.PeerRepository{Log: uc.Log, Db: uc.DB}
+	peers, err := peerRepo.List(ctx, model.PeerStatusActive)
+	if err != nil {
+		return list, http.StatusInternalServerError, err
+	}
+
+	for _, p := range peers {
+		list = append(list, uc.syncPeer(ctx, p, peers))
+	}
+
+	return list, http.StatusOK, nil
+}
+
+// Run syncs the ledgers every interval until ctx is done
+func (uc LedgerUC) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uc.Sync(ctx)
+		}
+	}
+}
+
+func (uc LedgerUC) syncPeer(ctx context.Context, p model.Peer, peers []model.Peer) model.LedgerSync {
+	result := model.LedgerSync{PeerID: p.ID}
+	if p.RemoteID == 0 {
+		result.Error = ErrNoRemoteID.Error()
+		return result
+	}
+
+	remote := ledger.HTTPRemote{Endpoint: p.Endpoint, Client: peer.Client{ID: p.RemoteID, Key: uc.Key, HTTP: uc.HTTP}}
+	replica := &repository.LedgerRepository{Log: uc.Log, Db: uc.DB, OriginPeerID: p.ID}
+	head, err := ledger.Replicate(ctx, replica, remote, ledgerSyncBatch)
+	result.Height, result.Hash = head.Height, head.Hash
+	if err != nil {
+		uc.record(ctx, p.ID, p.ID, err)
+		result.Error = err.Error()
+		return result
+	}
+
+	// the peer also shows what it replicated of the other ledgers, including the ledger of this node.
+	// A ledger that shows different histories to different peers is caught here.
+	replicas, err := remote.Replicas(ctx)
+	if err != nil {
+		uc.Log.Error(err)
+		result.Error = err.Error()
+		return result
+	}
+	self := uc.Key.Public().(ed25519.PublicKey)
+	for _, r := range replicas {
+		origin := int64(-1)
+		if bytes.Equal(r.PublicKey, self) {
+			origin = model.LedgerOriginLocal
+		}
+		for _, other := range peers {
+			if other.ID != p.ID && bytes.Equal(r.PublicKey, other.PublicKey) {
+				origin = other.ID
+			}
+		}
+		if origin < 0 {
+			continue
+		}
+
+		store := &repository.LedgerRepository{Log: uc.Log, Db: uc.DB, OriginPeerID: origin}
+		if err := ledger.Check(ctx, store, r.Head); err != nil {
+			uc.record(ctx, origin, p.ID, err)
+			result.Error = err.Error()
+		}
+	}
+
+	return result
+}
+
+// record stores a divergence, other errors are only logged
+func (uc LedgerUC) record(ctx context.Context, origin int64, reportedBy int64, err error) {
+	var divergence *ledger.Divergence
+	if !errors.As(err, &divergence) {
+		uc.Log.Error(err)
+		return
+	}
+
+	uc.Log.Error(divergence)
+	divergenceRepo := repository.LedgerDivergenceRepository{Log: uc.Log, Db: uc.DB}
+	divergenceRepo.LedgerDivergenceEntity = model.LedgerDivergence{
+		OriginPeerID: origin,
+		ReportedBy:   reportedBy,
+		Height:       divergence.Height,
+		LocalHash:    divergence.LocalHash,
+		RemoteHash:   divergence.RemoteHash,
+		Reason:       divergence.Reason,
+	}
+	divergenceRepo.Save(ctx)
+}
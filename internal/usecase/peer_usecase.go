@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// peerTransitions lists the allowed target statuses for every peer status. Revoked is final.
+var peerTransitions = map[string][]string{
+	model.PeerStatusPending:   {model.PeerStatusActive, model.PeerStatusRevoked},
+	model.PeerStatusActive:    {model.PeerStatusSuspended, model.PeerStatusRevoked},
+	model.PeerStatusSuspended: {model.PeerStatusActive, model.PeerStatusRevoked},
+}
+
+// CanPeerTransition reports whether a peer may move from status `from` to status `to`
+func CanPeerTransition(from string, to string) bool {
+	for _, status := range peerTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type PeerUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// SetStatus approves, suspends, reinstates or revokes the peer
+func (uc PeerUC) SetStatus(ctx context.Context, peerID int64, to string) (model.Peer, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.Peer{}, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.Peer{}, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	peerRepo := repository.PeerRepository{Log: uc.Log, Db: uc.DB, PeerEntity: model.Peer{ID: peerID}}
+	if err := peerRepo.Find(ctx); err == sql.ErrNoRows {
+		return peerRepo.PeerEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return peerRepo.PeerEntity, http.StatusInternalServerError, err
+	}
+
+	from := peerRepo.PeerEntity.Status
+	if !CanPeerTransition(from, to) {
+		return peerRepo.PeerEntity, http.StatusConflict, uc.Log.Error(fmt.Errorf("illegal peer transition from %s to %s", from, to))
+	}
+
+	err := peerRepo.SetStatus(ctx, from, to)
+	if err == repository.ErrPeerStatusChanged {
+		return peerRepo.PeerEntity, http.StatusConflict, err
+	} else if err != nil {
+		return peerRepo.PeerEntity, http.StatusInternalServerError, err
+	}
+
+	return peerRepo.PeerEntity, http.StatusOK, nil
+}
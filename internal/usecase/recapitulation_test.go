@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestFindDiscrepancies(t *testing.T) {
+	computed := model.RecapitulationSum{
+		Lines:        []model.TallyLine{{CandidateID: 1, Votes: 120}, {CandidateID: 2, Votes: 80}},
+		InvalidVotes: 5,
+	}
+
+	scenarios := []struct {
+		Name          string
+		Submitted     model.Recapitulation
+		Discrepancies []model.Discrepancy
+	}{
+		{
+			Name: "Matching Totals",
+			Submitted: model.Recapitulation{
+				Lines:        []model.TallyLine{{CandidateID: 2, Votes: 80}, {CandidateID: 1, Votes: 120}},
+				InvalidVotes: 5,
+			},
+			Discrepancies: []model.Discrepancy{},
+		},
+		{
+			Name: "Candidate And Invalid Votes Differ",
+			Submitted: model.Recapitulation{
+				Lines:        []model.TallyLine{{CandidateID: 2, Votes: 85}, {CandidateID: 1, Votes: 120}},
+				InvalidVotes: 3,
+			},
+			Discrepancies: []model.Discrepancy{
+				{CandidateID: 2, SubmittedVotes: 85, ComputedVotes: 80},
+				{CandidateID: 0, SubmittedVotes: 3, ComputedVotes: 5},
+			},
+		},
+		{
+			Name: "Missing Candidate",
+			Submitted: model.Recapitulation{
+				Lines:        []model.TallyLine{{CandidateID: 1, Votes: 120}},
+				InvalidVotes: 5,
+			},
+			Discrepancies: []model.Discrepancy{{CandidateID: 2, SubmittedVotes: 0, ComputedVotes: 80}},
+		},
+	}
+
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			discrepancies := FindDiscrepancies(tt.Submitted, computed)
+			if !reflect.DeepEqual(discrepancies, tt.Discrepancies) {
+				t.Errorf("discrepancies = %+v, want %+v", discrepancies, tt.Discrepancies)
+			}
+		})
+	}
+}
+
+func TestIsChildLevel(t *testing.T) {
+	if !IsChildLevel(model.RegionLevelNational, model.RegionLevelProvince) {
+		t.Error("province should be below national")
+	}
+	if !IsChildLevel(model.RegionLevelSubdistrict, model.RegionLevelVillage) {
+		t.Error("village should be below subdistrict")
+	}
+	if IsChildLevel(model.RegionLevelNational, model.RegionLevelRegency) {
+		t.Error("regency can not skip the province level")
+	}
+	if IsChildLevel(model.RegionLevelVillage, model.RegionLevelSubdistrict) {
+		t.Error("subdistrict can not be below village")
+	}
+}
@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/bytedance/sonic"
+)
+
+// ResultsChannelPattern matches the pub/sub channels of the result streams of every election
+const ResultsChannelPattern = "stream.results.*"
+
+// ResultsChannel is the pub/sub channel of the result stream of the election
+func ResultsChannel(electionID int64) string {
+	return fmt.Sprintf("stream.results.%d", electionID)
+}
+
+var (
+	// ErrChildrenPending is returned when a recapitulation is submitted while a polling station or child region is not approved yet
+	ErrChildrenPending = errors.New("every child must be approved before the recapitulation is submitted")
+	// ErrNoChildren is returned when a recapitulation is submitted for a region without polling stations or child regions
+	ErrNoChildren = errors.New("region has no polling stations or child regions to recapitulate")
+)
+
+// IsChildLevel reports whether a region of level `child` may be placed directly below a region of level `parent`
+func IsChildLevel(parent string, child string) bool {
+	for i := 1; i < len(model.RegionLevels); i++ {
+		if model.RegionLevels[i-1] == parent && model.RegionLevels[i] == child {
+			return true
+		}
+	}
+	return false
+}
+
+// FindDiscrepancies compares the submitted totals with the computed sum of the children.
+// Candidates are compared in ID order, a difference in the invalid votes is returned last with CandidateID 0.
+func FindDiscrepancies(submitted model.Recapitulation, computed model.RecapitulationSum) []model.Discrepancy {
+	votes := make(map[int64][2]int64)
+	for _, line := range submitted.Lines {
+		pair := votes[line.CandidateID]
+		pair[0] += line.Votes
+		votes[line.CandidateID] = pair
+	}
+	for _, line := range computed.Lines {
+		pair := votes[line.CandidateID]
+		pair[1] += line.Votes
+		votes[line.CandidateID] = pair
+	}
+
+	candidateIDs := make([]int64, 0, len(votes))
+	for candidateID := range votes {
+		candidateIDs = append(candidateIDs, candidateID)
+	}
+	sort.Slice(candidateIDs, func(i, j int) bool { return candidateIDs[i] < candidateIDs[j] })
+
+	discrepancies := make([]model.Discrepancy, 0)
+	for _, candidateID := range candidateIDs {
+		if pair := votes[candidateID]; pair[0] != pair[1] {
+			discrepancies = append(discrepancies, model.Discrepancy{CandidateID: candidateID, SubmittedVotes: pair[0], ComputedVotes: pair[1]})
+		}
+	}
+	if submitted.InvalidVotes != computed.InvalidVotes {
+		discrepancies = append(discrepancies, model.Discrepancy{SubmittedVotes: submitted.InvalidVotes, ComputedVotes: computed.InvalidVotes})
+	}
+
+	return discrepancies
+}
+
+type RecapitulationUC struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+}
+
+// SubmitTallyForm stores the tally form of a polling station after checking the candidates of its lines
+func (uc RecapitulationUC) SubmitTallyForm(ctx context.Context, form model.TallyForm) (model.TallyForm, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return form, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return form, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	pollingStationRepo := repository.PollingStationRepository{Log: uc.Log, Db: uc.DB, PollingStationEntity: model.PollingStation{ID: form.PollingStationID}}
+	if err := pollingStationRepo.Find(ctx); err == sql.ErrNoRows {
+		return form, http.StatusNotFound, err
+	} else if err != nil {
+		return form, http.StatusInternalServerError, err
+	}
+
+	if statusCode, err := uc.checkCandidates(ctx, form.ElectionID, form.Lines); err != nil {
+		return form, statusCode, err
+	}
+
+	tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: form}
+	if err := tallyFormRepo.Submit(ctx); err != nil {
+		return form, reviewStatusCode(err), err
+	}
+
+	return tallyFormRepo.TallyFormEntity, http.StatusOK, nil
+}
+
+// ReviewTallyForm approves or rejects the tally form of a polling station.
+// The cached recapitulations of the village and every region above it are dropped.
+func (uc RecapitulationUC) ReviewTallyForm(ctx context.Context, electionID int64, pollingStationID int64, status string, note string) (model.TallyForm, int, error) {
+	form := model.TallyForm{ElectionID: electionID, PollingStationID: pollingStationID}
+	switch ctx.Err() {
+	case context.Canceled:
+		return form, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return form, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	pollingStationRepo := repository.PollingStationRepository{Log: uc.Log, Db: uc.DB, PollingStationEntity: model.PollingStation{ID: pollingStationID}}
+	if err := pollingStationRepo.Find(ctx); err == sql.ErrNoRows {
+		return form, http.StatusNotFound, err
+	} else if err != nil {
+		return form, http.StatusInternalServerError, err
+	}
+
+	tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: form}
+	if err := tallyFormRepo.Find(ctx); err == sql.ErrNoRows {
+		return form, http.StatusNotFound, err
+	} else if err != nil {
+		return form, http.StatusInternalServerError, err
+	}
+
+	version, err := tallyFormRepo.Review(ctx, status, note)
+	if err != nil {
+		return form, reviewStatusCode(err), err
+	}
+
+	uc.invalidate(ctx, electionID, pollingStationRepo.PollingStationEntity.VillageID)
+	if status == model.ReviewStatusApproved {
+		uc.publish(ctx, model.TallyUpdate{
+			ElectionID:       electionID,
+			Version:          version,
+			PollingStationID: pollingStationID,
+			VillageID:        pollingStationRepo.PollingStationEntity.VillageID,
+			Lines:            tallyFormRepo.TallyFormEntity.Lines,
+			InvalidVotes:     tallyFormRepo.TallyFormEntity.InvalidVotes,
+		})
+	}
+
+	// the form is read again for the note, the reviewer and the time of the review. The review is done already,
+	// so a failed read still answers with the form as it was reviewed.
+	reviewedRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: form}
+	if err := reviewedRepo.Find(ctx); err == nil {
+		return reviewedRepo.TallyFormEntity, http.StatusOK, nil
+	}
+	return tallyFormRepo.TallyFormEntity, http.StatusOK, nil
+}
+
+// Submit stores the totals declared for a region. The lower level has to be final, so every polling station
+// (for a village) or every child region has to be approved. Totals that differ from the computed sum are recorded as discrepancies.
+func (uc RecapitulationUC) Submit(ctx context.Context, recapitulation model.Recapitulation) (model.Recapitulation, []model.Discrepancy, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return recapitulation, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return recapitulation, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: recapitulation.RegionID}}
+	if err := regionRepo.Find(ctx); err == sql.ErrNoRows {
+		return recapitulation, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return recapitulation, nil, http.StatusInternalServerError, err
+	}
+
+	if statusCode, err := uc.checkCandidates(ctx, recapitulation.ElectionID, recapitulation.Lines); err != nil {
+		return recapitulation, nil, statusCode, err
+	}
+
+	sum, err := uc.Sum(ctx, recapitulation.ElectionID, regionRepo.RegionEntity)
+	if err != nil {
+		return recapitulation, nil, http.StatusInternalServerError, err
+	}
+
+	if sum.Children == 0 {
+		return recapitulation, nil, http.StatusConflict, uc.Log.Error(ErrNoChildren)
+	}
+	if sum.ApprovedChildren < sum.Children {
+		return recapitulation, nil, http.StatusConflict, uc.Log.Error(ErrChildrenPending)
+	}
+
+	discrepancies := FindDiscrepancies(recapitulation, sum)
+	recapitulationRepo := repository.RecapitulationRepository{Log: uc.Log, Db: uc.DB, RecapitulationEntity: recapitulation}
+	if err := recapitulationRepo.Submit(ctx, discrepancies); err != nil {
+		return recapitulation, nil, reviewStatusCode(err), err
+	}
+
+	for i := range discrepancies {
+		discrepancies[i].RecapitulationID = recapitulationRepo.RecapitulationEntity.ID
+		discrepancies[i].Revision = recapitulationRepo.RecapitulationEntity.Revision
+	}
+
+	uc.invalidate(ctx, recapitulation.ElectionID, recapitulation.RegionID)
+	return recapitulationRepo.RecapitulationEntity, discrepancies, http.StatusOK, nil
+}
+
+// Review approves or rejects the recapitulation of a region.
+// The cached recapitulations of the region and every region above it are dropped.
+func (uc RecapitulationUC) Review(ctx context.Context, electionID int64, regionID int64, status string, note string) (model.Recapitulation, int, error) {
+	recapitulation := model.Recapitulation{ElectionID: electionID, RegionID: regionID}
+	switch ctx.Err() {
+	case context.Canceled:
+		return recapitulation, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return recapitulation, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	recapitulationRepo := repository.RecapitulationRepository{Log: uc.Log, Db: uc.DB, RecapitulationEntity: recapitulation}
+	if err := recapitulationRepo.Find(ctx); err == sql.ErrNoRows {
+		return recapitulation, http.StatusNotFound, err
+	} else if err != nil {
+		return recapitulation, http.StatusInternalServerError, err
+	}
+
+	if err := recapitulationRepo.Review(ctx, status, note); err != nil {
+		return recapitulation, reviewStatusCode(err), err
+	}
+
+	uc.invalidate(ctx, electionID, regionID)
+	return recapitulationRepo.RecapitulationEntity, http.StatusOK, nil
+}
+
+// Report returns the recapitulation of a region with the live sum of its approved children
+func (uc RecapitulationUC) Report(ctx context.Context, electionID int64, regionID int64) (model.RecapitulationReport, int, error) {
+	var report model.RecapitulationReport
+	switch ctx.Err() {
+	case context.Canceled:
+		return report, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return report, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: regionID}}
+	if err := regionRepo.Find(ctx); err == sql.ErrNoRows {
+		return report, http.StatusNotFound, err
+	} else if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+	report.Region = regionRepo.RegionEntity
+
+	sum, err := uc.Sum(ctx, electionID, report.Region)
+	if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+	report.Sum = sum
+
+	recapitulationRepo := repository.RecapitulationRepository{Log: uc.Log, Db: uc.DB, RecapitulationEntity: model.Recapitulation{ElectionID: electionID, RegionID: regionID}}
+	err = recapitulationRepo.Find(ctx)
+	if err == sql.ErrNoRows {
+		report.Recapitulation = model.Recapitulation{ElectionID: electionID, RegionID: regionID}
+		report.Discrepancies = make([]model.Discrepancy, 0)
+		return report, http.StatusOK, nil
+	} else if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+	report.Recapitulation = recapitulationRepo.RecapitulationEntity
+
+	report.Discrepancies, err = recapitulationRepo.ListDiscrepancies(ctx)
+	if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+
+	return report, http.StatusOK, nil
+}
+
+// Sum adds up the approved children of the region: tally forms for a village, recapitulations of child regions above it
+func (uc RecapitulationUC) Sum(ctx context.Context, electionID int64, region model.Region) (model.RecapitulationSum, error) {
+	if region.Level == model.RegionLevelVillage {
+		tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: model.TallyForm{ElectionID: electionID}}
+		return tallyFormRepo.SumVillage(ctx, region.ID)
+	}
+
+	recapitulationRepo := repository.RecapitulationRepository{Log: uc.Log, Db: uc.DB, RecapitulationEntity: model.Recapitulation{ElectionID: electionID}}
+	return recapitulationRepo.SumChildren(ctx, region.ID)
+}
+
+// checkCandidates returns 400 when a line refers to a candidate outside the election
+func (uc RecapitulationUC) checkCandidates(ctx context.Context, electionID int64, lines []model.TallyLine) (int, error) {
+	candidateRepo := repository.CandidateRepository{Log: uc.Log, Db: uc.DB, CandidateEntity: model.Candidate{ElectionID: electionID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	isCandidate := make(map[int64]bool, len(candidates))
+	for _, candidate := range candidates {
+		isCandidate[candidate.ID] = true
+	}
+	for _, line := range lines {
+		if !isCandidate[line.CandidateID] {
+			return http.StatusBadRequest, uc.Log.Error(fmt.Errorf("candidate %d is not a candidate of the election", line.CandidateID))
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// invalidate drops the cached recapitulations of the region and every region above it
+func (uc RecapitulationUC) invalidate(ctx context.Context, electionID int64, regionID int64) {
+	regionRepo := repository.RegionRepository{Log: uc.Log, Db: uc.DB, RegionEntity: model.Region{ID: regionID}}
+	ancestors, err := regionRepo.Ancestors(ctx)
+	if err != nil {
+		return
+	}
+
+	keys := make([]string, 0, len(ancestors))
+	for _, region := range ancestors {
+		keys = append(keys, fmt.Sprintf("recapitulations.%d.%d", electionID, region.ID))
+	}
+	if len(keys) > 0 {
+		uc.Cache.Del(ctx, keys...)
+	}
+}
+
+// publish sends the approved tally form to the result streams of every instance. A lost update is not fatal,
+// the version tells the clients to reload the snapshot. Without redis there are no streams to publish to.
+func (uc RecapitulationUC) publish(ctx context.Context, update model.TallyUpdate) {
+	if uc.Cache == nil {
+		return
+	}
+
+	var response dto.TallyUpdateResponse
+	response.FromEntity(update)
+	data, err := sonic.Marshal(response)
+	if err != nil {
+		uc.Log.Error(err)
+		return
+	}
+
+	if err := uc.Cache.Publish(ctx, ResultsChannel(update.ElectionID), data); err != nil {
+		uc.Log.Error(err)
+	}
+}
+
+// reviewStatusCode maps the errors of submitting and reviewing forms to a status code
+func reviewStatusCode(err error) int {
+	switch err {
+	case sql.ErrNoRows:
+		return http.StatusNotFound
+	case repository.ErrElectionNotClosed, repository.ErrAlreadyApproved, repository.ErrNotSubmitted:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
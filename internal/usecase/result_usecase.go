@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/elgamal"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// ErrResultsNotAvailable is returned when the results are requested before the election is closed
+var ErrResultsNotAvailable = errors.New("results are available once the election is closed")
+
+type ResultUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// Count computes the results of the election from the stored ballots with the counting method of the election
+func (uc ResultUC) Count(ctx context.Context, electionID int64) (model.TallyResult, []model.Candidate, int, error) {
+	var result model.TallyResult
+	switch ctx.Err() {
+	case context.Canceled:
+		return result, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return result, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	election, statusCode, err := uc.closedElection(ctx, electionID)
+	if err != nil {
+		return result, nil, statusCode, err
+	}
+
+	if len(election.PublicKey) > 0 {
+		return uc.countEncrypted(ctx, election)
+	}
+
+	tally, err := NewTally(election.CountingMethod)
+	if err != nil {
+		return result, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	candidateRepo := repository.CandidateRepository{Log: uc.Log, Db: uc.DB, CandidateEntity: model.Candidate{ElectionID: electionID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return result, nil, http.StatusInternalServerError, err
+	}
+
+	ballotRepo := repository.BallotRepository{Log: uc.Log, Db: uc.DB, BallotEntity: model.Ballot{ElectionID: electionID}}
+	ballots, err := ballotRepo.ListChoices(ctx)
+	if err != nil {
+		return result, nil, http.StatusInternalServerError, err
+	}
+
+	candidateIDs := make([]int64, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidateIDs = append(candidateIDs, candidate.ID)
+	}
+
+	return tally.Count(candidateIDs, ballots, election.Seats), candidates, http.StatusOK, nil
+}
+
+// countEncrypted decrypts the vote totals of an encrypted election with the decryption shares of the trustees.
+// Until threshold trustees decrypted the tally, the results stay hidden.
+func (uc ResultUC) countEncrypted(ctx context.Context, election model.Election) (model.TallyResult, []model.Candidate, int, error) {
+	var result model.TallyResult
+	if _, err := encryptedTotals(election.CountingMethod, 0); err != nil {
+		return result, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	trusteeRepo := repository.TrusteeRepository{Log: uc.Log, Db: uc.DB, TrusteeEntity: model.Trustee{ElectionID: election.ID}}
+	decryptions, err := trusteeRepo.ListDecryptions(ctx)
+	if err != nil {
+		return result, nil, http.StatusInternalServerError, err
+	}
+	if len(decryptions) < election.TrusteeThreshold {
+		return result, nil, http.StatusConflict, uc.Log.Error(ErrTallyNotDecrypted)
+	}
+
+	candidates, totals, ballots, err := encryptedTally(ctx, uc.Log, uc.DB, election.ID)
+	if err != nil {
+		return result, nil, http.StatusInternalServerError, err
+	}
+
+	candidateIDs := make([]int64, 0, len(candidates))
+	votes := make([]int64, 0, len(candidates))
+	for i, candidate := range candidates {
+		factors := make(map[int]*big.Int, len(decryptions))
+		for _, decryption := range decryptions {
+			if len(decryption.Factors) != len(candidates) {
+				return result, nil, http.StatusInternalServerError, uc.Log.Error(fmt.Errorf("decryption of trustee %d has %d shares for %d candidates", decryption.TrusteeIndex, len(decryption.Factors), len(candidates)))
+			}
+			factors[decryption.TrusteeIndex] = new(big.Int).SetBytes(decryption.Factors[i])
+		}
+
+		total, err := elgamal.Combine(totals[i], factors, int64(ballots))
+		if err != nil {
+			return result, nil, http.StatusInternalServerError, uc.Log.Error(fmt.Errorf("votes of candidate %d: %w", candidate.ID, err))
+		}
+		candidateIDs = append(candidateIDs, candidate.ID)
+		votes = append(votes, total)
+	}
+
+	return countTotals(election.CountingMethod, candidateIDs, votes, ballots, election.Seats), candidates, http.StatusOK, nil
+}
+
+// Seats allocates the seats of the party-list election from the party votes entered per district
+func (uc ResultUC) Seats(ctx context.Context, electionID int64) (model.SeatAllocation, int, error) {
+	var allocation model.SeatAllocation
+	switch ctx.Err() {
+	case context.Canceled:
+		return allocation, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return allocation, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	election, statusCode, err := uc.closedElection(ctx, electionID)
+	if err != nil {
+		return allocation, statusCode, err
+	}
+
+	districtRepo := repository.DistrictRepository{Log: uc.Log, Db: uc.DB, DistrictEntity: model.District{ElectionID: electionID}}
+	districts, err := districtRepo.List(ctx)
+	if err != nil {
+		return allocation, http.StatusInternalServerError, err
+	}
+
+	votes, err := districtRepo.ListVotes(ctx)
+	if err != nil {
+		return allocation, http.StatusInternalServerError, err
+	}
+
+	allocation, err = AllocateSeats(election.SeatMethod, election.Threshold, districts, votes)
+	if err != nil {
+		return allocation, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	return allocation, http.StatusOK, nil
+}
+
+// closedElection finds the election and checks that its results are available
+// Snapshot returns the sum of the approved tally forms of the election. The snapshot is available in every state,
+// so a results stream can be opened before the counting starts.
+func (uc ResultUC) Snapshot(ctx context.Context, electionID int64) (model.TallySnapshot, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return model.TallySnapshot{}, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return model.TallySnapshot{}, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: model.TallyForm{ElectionID: electionID}}
+	snapshot, err := tallyFormRepo.SumElection(ctx)
+	if err == sql.ErrNoRows {
+		return snapshot, http.StatusNotFound, err
+	} else if err != nil {
+		return snapshot, http.StatusInternalServerError, err
+	}
+
+	return snapshot, http.StatusOK, nil
+}
+
+func (uc ResultUC) closedElection(ctx context.Context, electionID int64) (model.Election, int, error) {
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return electionRepo.ElectionEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return electionRepo.ElectionEntity, http.StatusInternalServerError, err
+	}
+
+	switch electionRepo.ElectionEntity.Status {
+	case model.ElectionStatusClosed, model.ElectionStatusTallied, model.ElectionStatusCertified:
+	default:
+		return electionRepo.ElectionEntity, http.StatusConflict, uc.Log.Error(ErrResultsNotAvailable)
+	}
+
+	return electionRepo.ElectionEntity, http.StatusOK, nil
+}
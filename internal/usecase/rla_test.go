@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestRiskLimitingAudit(t *testing.T) {
+	election := model.Election{ID: 1, CountingMethod: model.CountingMethodPlurality, Seats: 1}
+	var forms []model.TallyForm
+	for i := int64(1); i <= 10; i++ {
+		forms = append(forms, model.TallyForm{
+			PollingStationID: 100 + i,
+			Lines:            []model.TallyLine{{CandidateID: 1, Votes: 60}, {CandidateID: 2, Votes: 40}},
+			InvalidVotes:     2,
+		})
+	}
+	manifest := newManifest(election, []int64{1, 2}, forms)
+	if manifest.ballots != 1020 || !reflect.DeepEqual(manifest.contest.Winners, []int64{1}) || !reflect.DeepEqual(manifest.contest.Losers, []int64{2}) {
+		t.Fatalf("manifest: %d ballots, winners %v, losers %v", manifest.ballots, manifest.contest.Winners, manifest.contest.Losers)
+	}
+
+	// the ballots of a batch are stacked in tally form order: winner, loser, invalid
+	interpret := func(draw model.RLADraw) model.RLAInterpretation {
+		interpretation := model.RLAInterpretation{PollingStationID: draw.PollingStationID, BallotPosition: draw.BallotPosition}
+		switch {
+		case draw.BallotPosition <= 60:
+			interpretation.Lines = []model.TallyLine{{CandidateID: 1, Votes: 1}}
+		case draw.BallotPosition <= 100:
+			interpretation.Lines = []model.TallyLine{{CandidateID: 2, Votes: 1}}
+		default:
+			interpretation.InvalidVotes = 1
+		}
+		return interpretation
+	}
+
+	t.Run("Polling", func(t *testing.T) {
+		audit := model.RLA{ElectionID: 1, Type: model.RLATypePolling, RiskLimit: 0.05, MaxDraws: 400, Seed: "32705488315927640215"}
+		draws, err := drawRLA(audit, manifest, 0, 400)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := drawRLA(audit, manifest, 200, 200)
+		if !reflect.DeepEqual(draws[200:], again) {
+			t.Error("draws are not reproducible from the seed")
+		}
+		for _, draw := range draws {
+			if draw.PollingStationID < 101 || draw.PollingStationID > 110 || draw.BallotPosition < 1 || draw.BallotPosition > 102 {
+				t.Fatalf("draw %d is outside the manifest: %+v", draw.Index, draw)
+			}
+		}
+
+		var interpretations []model.RLAInterpretation
+		for _, draw := range draws {
+			interpretations = append(interpretations, interpret(draw))
+		}
+		report, err := measureRLA(audit, manifest, draws, interpretations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != model.RLAStatusPassed || report.RiskMeasure > 0.05 || report.Interpreted == 0 || report.Interpreted == len(draws) {
+			t.Errorf("audit of a correct outcome: status %s, measure %f after %d draws", report.Status, report.RiskMeasure, report.Interpreted)
+		}
+
+		// the measure only counts the run of interpreted draws from the first one
+		var gap []model.RLAInterpretation
+		for _, interpretation := range interpretations {
+			if interpretation.PollingStationID != draws[0].PollingStationID || interpretation.BallotPosition != draws[0].BallotPosition {
+				gap = append(gap, interpretation)
+			}
+		}
+		report, _ = measureRLA(audit, manifest, draws, gap)
+		if report.Interpreted != 0 || report.Status != model.RLAStatusInProgress {
+			t.Errorf("first draw is not interpreted, yet %d draws are counted", report.Interpreted)
+		}
+
+		// every drawn ballot is for the loser, the audit escalates to a full hand count
+		for i := range interpretations {
+			interpretations[i].Lines = []model.TallyLine{{CandidateID: 2, Votes: 1}}
+			interpretations[i].InvalidVotes = 0
+		}
+		report, _ = measureRLA(audit, manifest, draws, interpretations)
+		if report.Status != model.RLAStatusEscalated || report.RiskMeasure != 1 {
+			t.Errorf("audit of a wrong outcome: status %s, measure %f", report.Status, report.RiskMeasure)
+		}
+	})
+
+	t.Run("Comparison", func(t *testing.T) {
+		audit := model.RLA{ElectionID: 1, Type: model.RLATypeComparison, RiskLimit: 0.05, MaxDraws: 50, Seed: "32705488315927640215"}
+		draws, err := drawRLA(audit, manifest, 0, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the hand count of every batch matches its tally form. Every batch has error bound (60 - 40 + 102) / 200,
+		// so U = 6.1 and 0.05 is reached after 17 draws
+		var interpretations []model.RLAInterpretation
+		for _, draw := range draws {
+			if draw.BallotPosition != 0 {
+				t.Fatalf("comparison draw %d draws a ballot", draw.Index)
+			}
+			interpretations = append(interpretations, model.RLAInterpretation{
+				PollingStationID: draw.PollingStationID,
+				Lines:            []model.TallyLine{{CandidateID: 1, Votes: 60}, {CandidateID: 2, Votes: 40}},
+				InvalidVotes:     2,
+			})
+		}
+		report, err := measureRLA(audit, manifest, draws, interpretations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != model.RLAStatusPassed || report.Interpreted != 17 {
+			t.Errorf("audit of clean batches: status %s after %d draws", report.Status, report.Interpreted)
+		}
+	})
+}
@@ -0,0 +1,465 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/rla"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrRLAMethod is returned when a risk-limiting audit is started for an election not counted by plurality.
+	// The ballots of a TPS batch are only known from its tally form when every ballot holds one vote.
+	ErrRLAMethod = errors.New("risk-limiting audits need a plurality election")
+	// ErrRLAUncontested is returned when every candidate is elected and there is no outcome to audit
+	ErrRLAUncontested = errors.New("every candidate is elected, there is no outcome to audit")
+	// ErrRLANoBallots is returned when the approved tally forms hold no ballot to draw
+	ErrRLANoBallots = errors.New("approved tally forms hold no ballots to audit")
+	// ErrRLAFinished is returned when draws are asked after the audit passed or escalated
+	ErrRLAFinished = errors.New("risk-limiting audit is finished")
+	// ErrRLAMaxDraws is returned when draws are asked after the audit made its max draws
+	ErrRLAMaxDraws = errors.New("risk-limiting audit made its max draws, interpret the drawn ballots")
+	// ErrInvalidInterpretation is returned when the interpretation of a drawn ballot is not one ballot
+	ErrInvalidInterpretation = errors.New("a ballot holds one vote for a candidate or is invalid")
+)
+
+type RLAUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// rlaManifest is the ballot manifest of the audit: the approved tally forms in polling station order,
+// the TPS batches read from them and the reported outcome
+type rlaManifest struct {
+	forms   []model.TallyForm
+	batches []rla.Batch
+	ballots int64
+	contest rla.Contest
+}
+
+// Create starts the risk-limiting audit of a tallied election with the seed of the seed ceremony
+func (uc RLAUC) Create(ctx context.Context, audit model.RLA) (model.RLA, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return audit, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return audit, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	election, statusCode, err := uc.election(ctx, audit.ElectionID)
+	if err != nil {
+		return audit, statusCode, err
+	}
+	if election.CountingMethod != model.CountingMethodPlurality {
+		return audit, http.StatusConflict, uc.Log.Error(ErrRLAMethod)
+	}
+
+	manifest, err := uc.manifest(ctx, election)
+	if err != nil {
+		return audit, http.StatusInternalServerError, err
+	}
+	if len(manifest.contest.Losers) == 0 {
+		return audit, http.StatusConflict, uc.Log.Error(ErrRLAUncontested)
+	}
+	if manifest.ballots == 0 {
+		return audit, http.StatusConflict, uc.Log.Error(ErrRLANoBallots)
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: audit}
+	switch err := rlaRepo.Create(ctx); err {
+	case nil:
+		return rlaRepo.RLAEntity, http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return audit, http.StatusNotFound, err
+	case repository.ErrElectionNotTallied, repository.ErrRLAExists:
+		return audit, http.StatusConflict, err
+	default:
+		return audit, http.StatusInternalServerError, err
+	}
+}
+
+// Report returns the state of the audit: the risk measure over the interpreted draws and whether the audit passed,
+// needs more draws or escalates to a full hand count
+func (uc RLAUC) Report(ctx context.Context, electionID int64) (model.RLAReport, int, error) {
+	var report model.RLAReport
+	switch ctx.Err() {
+	case context.Canceled:
+		return report, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return report, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	audit, manifest, draws, statusCode, err := uc.load(ctx, electionID)
+	if err != nil {
+		return report, statusCode, err
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: audit}
+	interpretations, err := rlaRepo.ListInterpretations(ctx)
+	if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+
+	report, err = measureRLA(audit, manifest, draws, interpretations)
+	if err != nil {
+		return report, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+	return report, http.StatusOK, nil
+}
+
+// Draws returns the draws of the audit in draw order
+func (uc RLAUC) Draws(ctx context.Context, electionID int64) ([]model.RLADraw, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: model.RLA{ElectionID: electionID}}
+	if err := rlaRepo.Find(ctx); err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	draws, err := rlaRepo.ListDraws(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return draws, http.StatusOK, nil
+}
+
+// Draw adds count draws to the audit and returns them, never more than MaxDraws in total. Draw k is computed from
+// the seed and k alone, so anyone can repeat the sample. No draw is added once the audit passed or escalated.
+func (uc RLAUC) Draw(ctx context.Context, electionID int64, count int) ([]model.RLADraw, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	audit, manifest, draws, statusCode, err := uc.load(ctx, electionID)
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: audit}
+	interpretations, err := rlaRepo.ListInterpretations(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	report, err := measureRLA(audit, manifest, draws, interpretations)
+	if err != nil {
+		return nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+	if report.Status != model.RLAStatusInProgress {
+		return nil, http.StatusConflict, uc.Log.Error(ErrRLAFinished)
+	}
+
+	if count > audit.MaxDraws-len(draws) {
+		count = audit.MaxDraws - len(draws)
+	}
+	if count <= 0 {
+		return nil, http.StatusConflict, uc.Log.Error(ErrRLAMaxDraws)
+	}
+
+	added, err := drawRLA(audit, manifest, len(draws), count)
+	if err != nil {
+		return nil, http.StatusConflict, uc.Log.Error(err)
+	}
+
+	switch err := rlaRepo.AddDraws(ctx, added); err {
+	case nil:
+		return added, http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return nil, http.StatusNotFound, err
+	case repository.ErrElectionNotTallied, repository.ErrDrawsMoved:
+		return nil, http.StatusConflict, err
+	default:
+		return nil, http.StatusInternalServerError, err
+	}
+}
+
+// Interpret records what the auditors read from a drawn ballot, or counted by hand in a drawn batch
+func (uc RLAUC) Interpret(ctx context.Context, interpretation model.RLAInterpretation) (int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: model.RLA{ElectionID: interpretation.ElectionID}}
+	if err := rlaRepo.Find(ctx); err == sql.ErrNoRows {
+		return http.StatusNotFound, err
+	} else if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	candidateRepo := repository.CandidateRepository{Log: uc.Log, Db: uc.DB, CandidateEntity: model.Candidate{ElectionID: interpretation.ElectionID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	isCandidate := make(map[int64]bool, len(candidates))
+	for _, candidate := range candidates {
+		isCandidate[candidate.ID] = true
+	}
+
+	var votes int64
+	for _, line := range interpretation.Lines {
+		if !isCandidate[line.CandidateID] {
+			return http.StatusBadRequest, uc.Log.Error(fmt.Errorf("candidate %d is not a candidate of the election", line.CandidateID))
+		}
+		votes += line.Votes
+	}
+	if rlaRepo.RLAEntity.Type == model.RLATypePolling {
+		if interpretation.BallotPosition == 0 || votes+interpretation.InvalidVotes != 1 {
+			return http.StatusBadRequest, uc.Log.Error(ErrInvalidInterpretation)
+		}
+	} else if interpretation.BallotPosition != 0 {
+		return http.StatusBadRequest, uc.Log.Error(errors.New("a comparison audit interprets whole batches, ballot_position must be 0"))
+	}
+
+	switch err := rlaRepo.Interpret(ctx, interpretation); err {
+	case nil:
+		return http.StatusCreated, nil
+	case sql.ErrNoRows:
+		return http.StatusNotFound, err
+	case repository.ErrElectionNotTallied, repository.ErrNotDrawn, repository.ErrAlreadyInterpreted:
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, err
+	}
+}
+
+// load reads the audit of the election with its ballot manifest and draws
+func (uc RLAUC) load(ctx context.Context, electionID int64) (model.RLA, rlaManifest, []model.RLADraw, int, error) {
+	var manifest rlaManifest
+	election, statusCode, err := uc.election(ctx, electionID)
+	if err != nil {
+		return model.RLA{}, manifest, nil, statusCode, err
+	}
+
+	rlaRepo := repository.RLARepository{Log: uc.Log, Db: uc.DB, RLAEntity: model.RLA{ElectionID: electionID}}
+	if err := rlaRepo.Find(ctx); err == sql.ErrNoRows {
+		return rlaRepo.RLAEntity, manifest, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return rlaRepo.RLAEntity, manifest, nil, http.StatusInternalServerError, err
+	}
+
+	manifest, err = uc.manifest(ctx, election)
+	if err != nil {
+		return rlaRepo.RLAEntity, manifest, nil, http.StatusInternalServerError, err
+	}
+
+	draws, err := rlaRepo.ListDraws(ctx)
+	if err != nil {
+		return rlaRepo.RLAEntity, manifest, nil, http.StatusInternalServerError, err
+	}
+	return rlaRepo.RLAEntity, manifest, draws, http.StatusOK, nil
+}
+
+func (uc RLAUC) election(ctx context.Context, electionID int64) (model.Election, int, error) {
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return electionRepo.ElectionEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return electionRepo.ElectionEntity, http.StatusInternalServerError, err
+	}
+	return electionRepo.ElectionEntity, http.StatusOK, nil
+}
+
+// manifest reads the approved tally forms of the election and the outcome they report
+func (uc RLAUC) manifest(ctx context.Context, election model.Election) (rlaManifest, error) {
+	var manifest rlaManifest
+	candidateRepo := repository.CandidateRepository{Log: uc.Log, Db: uc.DB, CandidateEntity: model.Candidate{ElectionID: election.ID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return manifest, err
+	}
+
+	tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: model.TallyForm{ElectionID: election.ID}}
+	manifest.forms, err = tallyFormRepo.ListApproved(ctx)
+	if err != nil {
+		return manifest, err
+	}
+
+	candidateIDs := make([]int64, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidateIDs = append(candidateIDs, candidate.ID)
+	}
+	return newManifest(election, candidateIDs, manifest.forms), nil
+}
+
+// newManifest reads a TPS batch from every tally form, a batch holds one ballot per vote and per invalid vote
+func newManifest(election model.Election, candidateIDs []int64, forms []model.TallyForm) rlaManifest {
+	manifest := rlaManifest{forms: forms, batches: make([]rla.Batch, 0, len(forms))}
+	votes := make(map[int64]int64, len(candidateIDs))
+	for _, form := range forms {
+		batch := rla.Batch{Votes: make(map[int64]int64, len(form.Lines)), Ballots: form.InvalidVotes}
+		for _, line := range form.Lines {
+			batch.Votes[line.CandidateID] += line.Votes
+			batch.Ballots += line.Votes
+			votes[line.CandidateID] += line.Votes
+		}
+		manifest.batches = append(manifest.batches, batch)
+		manifest.ballots += batch.Ballots
+	}
+
+	totals := make([]int64, 0, len(candidateIDs))
+	for _, candidateID := range candidateIDs {
+		totals = append(totals, votes[candidateID])
+	}
+	result := countTotals(election.CountingMethod, candidateIDs, totals, int(manifest.ballots), election.Seats)
+
+	elected := make(map[int64]bool, len(result.Elected))
+	for _, candidateID := range result.Elected {
+		elected[candidateID] = true
+	}
+	manifest.contest = rla.Contest{Votes: votes, Winners: result.Elected}
+	for _, candidateID := range candidateIDs {
+		if !elected[candidateID] {
+			manifest.contest.Losers = append(manifest.contest.Losers, candidateID)
+		}
+	}
+	return manifest
+}
+
+// drawRLA computes count draws after the stored draws. A polling audit draws ballots uniformly from the manifest,
+// a comparison audit draws batches with probability proportional to their error bound. Both draw with replacement.
+func drawRLA(audit model.RLA, manifest rlaManifest, stored int, count int) ([]model.RLADraw, error) {
+	sampler := rla.Sampler{Seed: audit.Seed}
+	weights := make([]float64, 0, len(manifest.batches))
+	for _, batch := range manifest.batches {
+		weights = append(weights, rla.ErrorBound(manifest.contest, batch))
+	}
+
+	draws := make([]model.RLADraw, 0, count)
+	for k := stored + 1; k <= stored+count; k++ {
+		draw := model.RLADraw{ElectionID: audit.ElectionID, Index: k}
+		if audit.Type == model.RLATypePolling {
+			if manifest.ballots == 0 {
+				return nil, ErrRLANoBallots
+			}
+			// the ballot index is located in the batches, ballot positions in a batch start from 1
+			index := sampler.Index(int64(k), manifest.ballots)
+			for i, batch := range manifest.batches {
+				if index < batch.Ballots {
+					draw.PollingStationID = manifest.forms[i].PollingStationID
+					draw.BallotPosition = int(index) + 1
+					break
+				}
+				index -= batch.Ballots
+			}
+		} else {
+			i, err := sampler.Weighted(int64(k), weights)
+			if err != nil {
+				return nil, err
+			}
+			draw.PollingStationID = manifest.forms[i].PollingStationID
+		}
+		draws = append(draws, draw)
+	}
+	return draws, nil
+}
+
+// measureRLA computes the risk measure over the longest run of interpreted draws from the first draw.
+// The audit passes at the first draw where the measure is at most the risk limit. It escalates to a full hand count
+// when every draw is interpreted, at least MaxDraws were made and the risk limit is still not met.
+func measureRLA(audit model.RLA, manifest rlaManifest, draws []model.RLADraw, interpretations []model.RLAInterpretation) (model.RLAReport, error) {
+	report := model.RLAReport{
+		RLA:     audit,
+		Status:  model.RLAStatusInProgress,
+		Ballots: manifest.ballots,
+		Batches: len(manifest.batches),
+		Winners: manifest.contest.Winners,
+		Draws:   len(draws),
+		Pairs:   make([]model.RLAPair, 0),
+	}
+
+	type key struct {
+		pollingStationID int64
+		ballotPosition   int
+	}
+	interpreted := make(map[key]model.RLAInterpretation, len(interpretations))
+	for _, interpretation := range interpretations {
+		interpreted[key{interpretation.PollingStationID, interpretation.BallotPosition}] = interpretation
+	}
+	batchOf := make(map[int64]int, len(manifest.forms))
+	for i, form := range manifest.forms {
+		batchOf[form.PollingStationID] = i
+	}
+
+	var sample []model.RLAInterpretation
+	for _, draw := range draws {
+		interpretation, ok := interpreted[key{draw.PollingStationID, draw.BallotPosition}]
+		if !ok {
+			break
+		}
+		sample = append(sample, interpretation)
+	}
+
+	if audit.Type == model.RLATypePolling {
+		ballots := make([][]int64, 0, len(sample))
+		for _, interpretation := range sample {
+			ballot := make([]int64, 0, 1)
+			for _, line := range interpretation.Lines {
+				if line.Votes > 0 {
+					ballot = append(ballot, line.CandidateID)
+				}
+			}
+			ballots = append(ballots, ballot)
+		}
+
+		pairs, used := rla.BravoStop(manifest.contest, ballots, audit.RiskLimit)
+		report.Interpreted = used
+		report.RiskMeasure = rla.Measure(pairs)
+		for _, pair := range pairs {
+			report.Pairs = append(report.Pairs, model.RLAPair{Winner: pair.Winner, Loser: pair.Loser, Measure: pair.Measure})
+		}
+	} else {
+		var totalBound float64
+		bounds := make([]float64, 0, len(manifest.batches))
+		for _, batch := range manifest.batches {
+			bound := rla.ErrorBound(manifest.contest, batch)
+			bounds = append(bounds, bound)
+			totalBound += bound
+		}
+
+		taints := make([]float64, 0, len(sample))
+		for _, interpretation := range sample {
+			i, ok := batchOf[interpretation.PollingStationID]
+			if !ok {
+				return report, fmt.Errorf("drawn polling station %d has no approved tally form", interpretation.PollingStationID)
+			}
+			audited := make(map[int64]int64, len(interpretation.Lines))
+			for _, line := range interpretation.Lines {
+				audited[line.CandidateID] += line.Votes
+			}
+			taints = append(taints, rla.Overstatement(manifest.contest, manifest.batches[i], audited)/bounds[i])
+		}
+
+		report.RiskMeasure, report.Interpreted = rla.KaplanMarkovStop(totalBound, taints, audit.RiskLimit)
+	}
+
+	switch {
+	case len(manifest.contest.Losers) > 0 && report.RiskMeasure <= audit.RiskLimit:
+		report.Status = model.RLAStatusPassed
+	case len(sample) == len(draws) && len(draws) >= audit.MaxDraws:
+		report.Status = model.RLAStatusEscalated
+	}
+	return report, nil
+}
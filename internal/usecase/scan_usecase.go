@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrUnsupportedScan is returned when an uploaded scan is not a JPEG, PNG, WebP or PDF file
+var ErrUnsupportedScan = errors.New("scan must be a jpeg, png, webp or pdf file")
+
+// scanContentTypes are the sniffed content types accepted for scans
+var scanContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type ScanUC struct {
+	Log     *logger.Logger
+	DB      *sql.DB
+	Storage storage.Storage
+}
+
+// Upload stores the file and attaches it to the tally form of the polling station. The file is stored once per checksum,
+// uploading the same file again returns the existing scan with created false.
+func (uc ScanUC) Upload(ctx context.Context, electionID int64, pollingStationID int64, filename string, r io.Reader) (model.TallyFormScan, bool, int, error) {
+	var scan model.TallyFormScan
+	switch ctx.Err() {
+	case context.Canceled:
+		return scan, false, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return scan, false, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	form, statusCode, err := uc.findTallyForm(ctx, electionID, pollingStationID)
+	if err != nil {
+		return scan, false, statusCode, err
+	}
+
+	// an approved tally form is final, including the evidence attached to it
+	if form.Status == model.ReviewStatusApproved {
+		return scan, false, http.StatusConflict, uc.Log.Error(repository.ErrAlreadyApproved)
+	}
+
+	upload, err := storage.Spool(r, storage.MaxSize())
+	if err == storage.ErrTooLarge {
+		return scan, false, http.StatusRequestEntityTooLarge, uc.Log.Error(err)
+	} else if err != nil {
+		return scan, false, http.StatusBadRequest, uc.Log.Error(err)
+	}
+	defer upload.Close()
+
+	if !scanContentTypes[upload.ContentType] {
+		return scan, false, http.StatusUnsupportedMediaType, uc.Log.Error(ErrUnsupportedScan)
+	}
+
+	if err := uc.Storage.Put(ctx, upload.Key, upload.File, upload.Size, upload.ContentType); err != nil {
+		return scan, false, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	scanRepo := repository.TallyFormScanRepository{Log: uc.Log, Db: uc.DB, TallyFormScanEntity: model.TallyFormScan{
+		TallyFormID: form.ID,
+		SHA256:      upload.Key,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Filename:    filename,
+	}}
+	created, err := scanRepo.Save(ctx)
+	if err != nil {
+		return scan, false, http.StatusInternalServerError, err
+	}
+
+	return scanRepo.TallyFormScanEntity, created, http.StatusOK, nil
+}
+
+// List returns the scans attached to the tally form of the polling station
+func (uc ScanUC) List(ctx context.Context, electionID int64, pollingStationID int64) ([]model.TallyFormScan, int, error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	form, statusCode, err := uc.findTallyForm(ctx, electionID, pollingStationID)
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	scanRepo := repository.TallyFormScanRepository{Log: uc.Log, Db: uc.DB, TallyFormScanEntity: model.TallyFormScan{TallyFormID: form.ID}}
+	scans, err := scanRepo.List(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return scans, http.StatusOK, nil
+}
+
+// Open returns a scan of the tally form of the polling station with a reader of its content. The caller closes the reader.
+func (uc ScanUC) Open(ctx context.Context, electionID int64, pollingStationID int64, sha256 string) (model.TallyFormScan, io.ReadCloser, int, error) {
+	var scan model.TallyFormScan
+	switch ctx.Err() {
+	case context.Canceled:
+		return scan, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return scan, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	if !storage.ValidKey(sha256) {
+		return scan, nil, http.StatusBadRequest, uc.Log.Error(storage.ErrInvalidKey)
+	}
+
+	form, statusCode, err := uc.findTallyForm(ctx, electionID, pollingStationID)
+	if err != nil {
+		return scan, nil, statusCode, err
+	}
+
+	scanRepo := repository.TallyFormScanRepository{Log: uc.Log, Db: uc.DB, TallyFormScanEntity: model.TallyFormScan{TallyFormID: form.ID, SHA256: sha256}}
+	if err := scanRepo.Find(ctx); err == sql.ErrNoRows {
+		return scan, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return scan, nil, http.StatusInternalServerError, err
+	}
+
+	content, err := uc.Storage.Get(ctx, sha256)
+	if err != nil {
+		return scan, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	return scanRepo.TallyFormScanEntity, content, http.StatusOK, nil
+}
+
+func (uc ScanUC) findTallyForm(ctx context.Context, electionID int64, pollingStationID int64) (model.TallyForm, int, error) {
+	tallyFormRepo := repository.TallyFormRepository{Log: uc.Log, Db: uc.DB, TallyFormEntity: model.TallyForm{ElectionID: electionID, PollingStationID: pollingStationID}}
+	if err := tallyFormRepo.Find(ctx); err == sql.ErrNoRows {
+		return tallyFormRepo.TallyFormEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return tallyFormRepo.TallyFormEntity, http.StatusInternalServerError, err
+	}
+
+	return tallyFormRepo.TallyFormEntity, http.StatusOK, nil
+}
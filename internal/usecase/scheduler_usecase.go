@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/pkg/redis"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SchedulerLockKey is the Redis lock of the replica that performs the scheduled transitions
+const SchedulerLockKey = "scheduler.leader"
+
+// SchedulerUC opens and closes the elections at their voting window. Every API replica runs it,
+// only the replica holding the Redis lock transitions the elections.
+type SchedulerUC struct {
+	Log   *logger.Logger
+	DB    *sql.DB
+	Cache *redis.Cache
+	// Owner identifies this replica in the lock
+	Owner string
+}
+
+// Tick transitions the due elections when this replica holds the lock, or takes the lock when it is free.
+// The lock is held for ttl, Tick must be called again before it expires to keep it.
+// An election moved by a user in the meantime is skipped.
+func (uc SchedulerUC) Tick(ctx context.Context, ttl time.Duration) ([]model.ElectionTransition, bool, error) {
+	var list []model.ElectionTransition = make([]model.ElectionTransition, 0)
+	switch ctx.Err() {
+	case context.Canceled:
+		return list, false, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return list, false, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	leader, err := uc.Cache.Lock(ctx, SchedulerLockKey, uc.Owner, ttl)
+	if err != nil {
+		return list, false, uc.Log.Error(err)
+	}
+	if !leader {
+		return list, false, nil
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB}
+	elections, err := electionRepo.ListDue(ctx)
+	if err != nil {
+		return list, true, err
+	}
+
+	// the transitions of the scheduler are recorded without a user
+	ctx = context.WithValue(ctx, myctx.Key("user_id"), int64(0))
+	electionUC := ElectionUC{Log: uc.Log, DB: uc.DB}
+	for _, election := range elections {
+		to := model.ElectionStatusOpen
+		if election.Status == model.ElectionStatusOpen {
+			to = model.ElectionStatusClosed
+		}
+
+		transition, statusCode, err := electionUC.Transition(ctx, election.ID, to)
+		if statusCode == http.StatusConflict {
+			continue
+		} else if err != nil {
+			uc.Log.Error(fmt.Errorf("scheduled transition of election %d to %s: %w", election.ID, to, err))
+			continue
+		}
+
+		uc.Cache.Del(ctx, fmt.Sprintf("elections.%d", election.ID))
+		uc.Log.Info(fmt.Sprintf("election %d is %s at its scheduled time", election.ID, to))
+		list = append(list, transition)
+	}
+
+	return list, true, nil
+}
+
+// Run ticks every interval until ctx is done, then releases the lock so another replica takes over at once
+func (uc SchedulerUC) Run(ctx context.Context, interval time.Duration) {
+	// the lock outlives a missed tick, a replica that stopped ticking loses it after three intervals
+	ttl := 3 * interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.Tick(ctx, ttl)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := uc.Cache.Unlock(context.Background(), SchedulerLockKey, uc.Owner); err != nil {
+				uc.Log.Error(err)
+			}
+			return
+		case <-ticker.C:
+			uc.Tick(ctx, ttl)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// AllocateSeats divides the seats of every district among the parties with a highest averages method.
+// Parties whose national share of the votes is below the threshold (in percent) get no seats in any district.
+//
+// A seat that two parties claim with the same average goes to the party with more votes in the district,
+// and when the votes are equal too, to the party whose name sorts first. DistrictSeats.TieBreak reports
+// whether such a tie decided the last seat of the district, so it can be settled by the election authority.
+func AllocateSeats(method string, threshold float64, districts []model.District, votes []model.PartyVotes) (model.SeatAllocation, error) {
+	allocation := model.SeatAllocation{Method: method, Threshold: threshold}
+
+	var divisor func(seats int) int64
+	switch method {
+	case model.SeatMethodSainteLague:
+		divisor = func(seats int) int64 { return int64(2*seats + 1) }
+	case model.SeatMethodDHondt:
+		divisor = func(seats int) int64 { return int64(seats + 1) }
+	default:
+		return allocation, fmt.Errorf("unknown seat method %s", method)
+	}
+
+	national := make(map[string]int64)
+	byDistrict := make(map[int64][]model.PartyVotes, len(districts))
+	for _, partyVotes := range votes {
+		national[partyVotes.Party] += partyVotes.Votes
+		allocation.Votes += partyVotes.Votes
+		byDistrict[partyVotes.DistrictID] = append(byDistrict[partyVotes.DistrictID], partyVotes)
+	}
+
+	// threshold is a percentage with two decimals, compare in basis points to stay in integers
+	basisPoints := int64(math.Round(threshold * 100))
+	eligible := make(map[string]bool, len(national))
+	nationalSeats := make(map[string]int, len(national))
+	for party, partyVotes := range national {
+		eligible[party] = partyVotes*10000 >= basisPoints*allocation.Votes
+	}
+
+	for _, district := range districts {
+		districtSeats := model.DistrictSeats{DistrictID: district.ID, Name: district.Name, Seats: district.Seats}
+
+		var contenders []model.PartySeats
+		for _, partyVotes := range byDistrict[district.ID] {
+			party := model.PartySeats{Party: partyVotes.Party, Votes: partyVotes.Votes, Eligible: eligible[partyVotes.Party]}
+			districtSeats.Parties = append(districtSeats.Parties, party)
+			if party.Eligible && party.Votes > 0 {
+				contenders = append(contenders, party)
+			}
+		}
+
+		seats, tieBreak := highestAverages(district.Seats, contenders, divisor)
+		districtSeats.TieBreak = tieBreak
+		districtSeats.Unallocated = district.Seats
+		for i := range districtSeats.Parties {
+			party := &districtSeats.Parties[i]
+			party.Seats = seats[party.Party]
+			districtSeats.Unallocated -= party.Seats
+			nationalSeats[party.Party] += party.Seats
+		}
+		sortParties(districtSeats.Parties)
+
+		allocation.Districts = append(allocation.Districts, districtSeats)
+	}
+
+	for party, partyVotes := range national {
+		allocation.Parties = append(allocation.Parties, model.PartySeats{
+			Party:    party,
+			Votes:    partyVotes,
+			Seats:    nationalSeats[party],
+			Eligible: eligible[party],
+		})
+	}
+	sortParties(allocation.Parties)
+
+	return allocation, nil
+}
+
+// highestAverages hands out the seats one by one to the party with the highest votes / divisor(seats won).
+// It reports whether the last seat was won on a tie with the party that would get the next seat.
+func highestAverages(seats int, parties []model.PartySeats, divisor func(seats int) int64) (map[string]int, bool) {
+	won := make(map[string]int, len(parties))
+	if len(parties) == 0 {
+		return won, false
+	}
+
+	// claims reports whether party a claims the next seat before party b, averages are compared cross multiplied
+	claims := func(a, b model.PartySeats) bool {
+		left := a.Votes * divisor(won[b.Party])
+		right := b.Votes * divisor(won[a.Party])
+		if left != right {
+			return left > right
+		}
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		return a.Party < b.Party
+	}
+
+	for i := 0; i < seats; i++ {
+		best := parties[0]
+		for _, party := range parties[1:] {
+			if claims(party, best) {
+				best = party
+			}
+		}
+		won[best.Party]++
+	}
+
+	// the last seat was decided by a tie when the lowest average that won a seat equals the highest average that did not
+	var lowestVotes, lowestDivisor, highestVotes, highestDivisor int64 = 0, 1, 0, 1
+	first := true
+	for _, party := range parties {
+		if won[party.Party] > 0 {
+			d := divisor(won[party.Party] - 1)
+			if first || party.Votes*lowestDivisor < lowestVotes*d {
+				lowestVotes, lowestDivisor, first = party.Votes, d, false
+			}
+		}
+		if d := divisor(won[party.Party]); party.Votes*highestDivisor > highestVotes*d {
+			highestVotes, highestDivisor = party.Votes, d
+		}
+	}
+
+	return won, seats > 0 && highestVotes > 0 && lowestVotes*highestDivisor == highestVotes*lowestDivisor
+}
+
+// sortParties orders the parties by seats, then votes, then name
+func sortParties(parties []model.PartySeats) {
+	sort.Slice(parties, func(i, j int) bool {
+		if parties[i].Seats != parties[j].Seats {
+			return parties[i].Seats > parties[j].Seats
+		}
+		if parties[i].Votes != parties[j].Votes {
+			return parties[i].Votes > parties[j].Votes
+		}
+		return parties[i].Party < parties[j].Party
+	})
+}
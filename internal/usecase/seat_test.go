@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"testing"
+)
+
+func TestAllocateSeats(t *testing.T) {
+	districts := []model.District{{ID: 1, Name: "Dapil 1", Seats: 8}}
+	votes := []model.PartyVotes{
+		{DistrictID: 1, Party: "A", Votes: 100000},
+		{DistrictID: 1, Party: "B", Votes: 80000},
+		{DistrictID: 1, Party: "C", Votes: 30000},
+		{DistrictID: 1, Party: "D", Votes: 20000},
+	}
+
+	scenarios := []struct {
+		Name      string
+		Method    string
+		Threshold float64
+		Districts []model.District
+		Votes     []model.PartyVotes
+		Seats     map[string]int
+		TieBreak  bool
+	}{
+		{
+			Name:      "Sainte-Lague",
+			Method:    model.SeatMethodSainteLague,
+			Districts: districts,
+			Votes:     votes,
+			Seats:     map[string]int{"A": 3, "B": 3, "C": 1, "D": 1},
+		},
+		{
+			Name:      "D'Hondt",
+			Method:    model.SeatMethodDHondt,
+			Districts: districts,
+			Votes:     votes,
+			Seats:     map[string]int{"A": 4, "B": 3, "C": 1, "D": 0},
+		},
+		{
+			// D holds 8.7 percent of the votes
+			Name:      "Threshold Excludes Party",
+			Method:    model.SeatMethodSainteLague,
+			Threshold: 10,
+			Districts: districts,
+			Votes:     votes,
+			Seats:     map[string]int{"A": 4, "B": 3, "C": 1, "D": 0},
+		},
+		{
+			Name:      "Tie Goes To Name Order",
+			Method:    model.SeatMethodSainteLague,
+			Districts: []model.District{{ID: 1, Name: "Dapil 1", Seats: 1}},
+			Votes:     []model.PartyVotes{{DistrictID: 1, Party: "Beta", Votes: 500}, {DistrictID: 1, Party: "Alpha", Votes: 500}},
+			Seats:     map[string]int{"Alpha": 1, "Beta": 0},
+			TieBreak:  true,
+		},
+	}
+
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			allocation, err := AllocateSeats(tt.Method, tt.Threshold, tt.Districts, tt.Votes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			district := allocation.Districts[0]
+			for _, party := range district.Parties {
+				if party.Seats != tt.Seats[party.Party] {
+					t.Errorf("party %s got wrong seats: got %d want %d", party.Party, party.Seats, tt.Seats[party.Party])
+				}
+			}
+			if district.TieBreak != tt.TieBreak {
+				t.Errorf("wrong tie break: got %v want %v", district.TieBreak, tt.TieBreak)
+			}
+			if district.Unallocated != 0 {
+				t.Errorf("wrong unallocated seats: got %d want 0", district.Unallocated)
+			}
+		})
+	}
+}
+
+func TestAllocateSeatsThresholdIsNational(t *testing.T) {
+	districts := []model.District{{ID: 1, Name: "Dapil 1", Seats: 2}, {ID: 2, Name: "Dapil 2", Seats: 2}}
+	votes := []model.PartyVotes{
+		{DistrictID: 1, Party: "A", Votes: 900},
+		{DistrictID: 1, Party: "B", Votes: 100},
+		{DistrictID: 2, Party: "A", Votes: 900},
+		{DistrictID: 2, Party: "C", Votes: 600},
+	}
+
+	// C holds 24 percent of the votes nationally, B only 4 percent although it would win a seat in Dapil 1
+	allocation, err := AllocateSeats(model.SeatMethodSainteLague, 5, districts, votes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"A": 3, "B": 0, "C": 1}
+	for _, party := range allocation.Parties {
+		if party.Seats != want[party.Party] {
+			t.Errorf("party %s got wrong seats: got %d want %d", party.Party, party.Seats, want[party.Party])
+		}
+		if party.Eligible != (party.Party != "B") {
+			t.Errorf("party %s got wrong eligibility: got %v", party.Party, party.Eligible)
+		}
+	}
+
+	if _, err := AllocateSeats("hare", 0, districts, votes); err == nil {
+		t.Error("unknown seat method should be rejected")
+	}
+}
+
+func TestAllocateSeatsWithoutVotes(t *testing.T) {
+	allocation, err := AllocateSeats(model.SeatMethodDHondt, 0, []model.District{{ID: 1, Name: "Dapil 1", Seats: 3}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allocation.Districts[0].Unallocated != 3 {
+		t.Errorf("wrong unallocated seats: got %d want 3", allocation.Districts[0].Unallocated)
+	}
+}
@@ -0,0 +1,366 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// voteScale is the fixed point precision of a vote. STV transfers fractions of a ballot,
+// integer arithmetic keeps the count exactly reproducible on every machine.
+const voteScale int64 = 100000
+
+// Tally counts the ballots of an election with one counting method.
+// Implementations only work on candidate IDs, so they can be tested without a database.
+type Tally interface {
+	// Validate checks that the choices of one ballot are valid for the counting method
+	Validate(choices []int64) error
+	// Count computes the result. Candidates are given in ballot order, which is also the last tie breaker.
+	Count(candidates []int64, ballots [][]int64, seats int) model.TallyResult
+}
+
+// NewTally returns the Tally of the counting method
+func NewTally(method string) (Tally, error) {
+	switch method {
+	case model.CountingMethodPlurality:
+		return Plurality{}, nil
+	case model.CountingMethodApproval:
+		return Approval{}, nil
+	case model.CountingMethodIRV:
+		return InstantRunoff{}, nil
+	case model.CountingMethodSTV:
+		return SingleTransferableVote{}, nil
+	default:
+		return nil, fmt.Errorf("unknown counting method %s", method)
+	}
+}
+
+// Plurality elects the candidates with the most first choices. Every ballot holds exactly one choice.
+type Plurality struct{}
+
+func (Plurality) Validate(choices []int64) error {
+	if len(choices) != 1 {
+		return errors.New("plurality ballot must have exactly one choice")
+	}
+	return nil
+}
+
+func (t Plurality) Count(candidates []int64, ballots [][]int64, seats int) model.TallyResult {
+	return countSingleRound(model.CountingMethodPlurality, t, candidates, ballots, seats)
+}
+
+// Approval elects the candidates approved by the most ballots. Every choice on a ballot counts as one vote.
+type Approval struct{}
+
+func (Approval) Validate(choices []int64) error {
+	return validateChoices(choices)
+}
+
+func (t Approval) Count(candidates []int64, ballots [][]int64, seats int) model.TallyResult {
+	return countSingleRound(model.CountingMethodApproval, t, candidates, ballots, seats)
+}
+
+// InstantRunoff elects one candidate with ranked ballots. While no candidate holds a majority of the
+// ballots still in the count, the last candidate is eliminated and their ballots move to the next choice.
+type InstantRunoff struct{}
+
+func (InstantRunoff) Validate(choices []int64) error {
+	return validateChoices(choices)
+}
+
+func (t InstantRunoff) Count(candidates []int64, ballots [][]int64, seats int) model.TallyResult {
+	result := model.TallyResult{Method: model.CountingMethodIRV, Seats: 1}
+	valid, invalid := filterBallots(t, candidates, ballots)
+	result.Ballots, result.Invalid = len(valid), invalid
+
+	order := candidateOrder(candidates)
+	continuing := make(map[int64]bool, len(candidates))
+	for _, candidate := range candidates {
+		continuing[candidate] = true
+	}
+
+	var history []map[int64]int64
+	for round := 1; len(continuing) > 0; round++ {
+		votes := make(map[int64]int64, len(continuing))
+		var exhausted int64
+		for _, ballot := range valid {
+			if choice, ok := firstContinuing(ballot, continuing); ok {
+				votes[choice] += voteScale
+			} else {
+				exhausted += voteScale
+			}
+		}
+
+		ranked := rankCandidates(keys(continuing, order), votes, history, order)
+		tallyRound := newRound(round, candidates, continuing, votes, exhausted)
+
+		active := int64(len(valid))*voteScale - exhausted
+		if len(ranked) == 1 || votes[ranked[0]]*2 > active {
+			tallyRound.Elected = []int64{ranked[0]}
+			result.Rounds = append(result.Rounds, tallyRound)
+			result.Elected = tallyRound.Elected
+			break
+		}
+
+		loser := ranked[len(ranked)-1]
+		tallyRound.Eliminated = []int64{loser}
+		tallyRound.TieBreak = votes[loser] == votes[ranked[len(ranked)-2]]
+		result.Rounds = append(result.Rounds, tallyRound)
+
+		delete(continuing, loser)
+		history = append(history, votes)
+	}
+
+	return result
+}
+
+// SingleTransferableVote elects several candidates with ranked ballots using the Droop quota.
+// Surpluses are transferred with the weighted inclusive Gregory method: every ballot of an elected
+// candidate moves on to the next choice with its weight multiplied by surplus / total votes.
+type SingleTransferableVote struct{}
+
+func (SingleTransferableVote) Validate(choices []int64) error {
+	return validateChoices(choices)
+}
+
+type stvBallot struct {
+	choices []int64
+	weight  int64
+	at      int
+}
+
+func (t SingleTransferableVote) Count(candidates []int64, ballots [][]int64, seats int) model.TallyResult {
+	result := model.TallyResult{Method: model.CountingMethodSTV, Seats: seats}
+	valid, invalid := filterBallots(t, candidates, ballots)
+	result.Ballots, result.Invalid = len(valid), invalid
+
+	quota := (int64(len(valid))/int64(seats+1) + 1) * voteScale
+	result.Quota = float64(quota) / float64(voteScale)
+
+	order := candidateOrder(candidates)
+	continuing := make(map[int64]bool, len(candidates))
+	for _, candidate := range candidates {
+		continuing[candidate] = true
+	}
+
+	piles := make(map[int64][]*stvBallot, len(candidates))
+	var exhausted int64
+	// move hands the ballot to its next continuing choice, or counts it as exhausted
+	move := func(ballot *stvBallot) {
+		for ; ballot.at < len(ballot.choices); ballot.at++ {
+			if choice := ballot.choices[ballot.at]; continuing[choice] {
+				piles[choice] = append(piles[choice], ballot)
+				return
+			}
+		}
+		exhausted += ballot.weight
+	}
+	for _, choices := range valid {
+		move(&stvBallot{choices: choices, weight: voteScale})
+	}
+
+	var history []map[int64]int64
+	for round := 1; len(continuing) > 0 && len(result.Elected) < seats; round++ {
+		votes := make(map[int64]int64, len(continuing))
+		for candidate := range continuing {
+			for _, ballot := range piles[candidate] {
+				votes[candidate] += ballot.weight
+			}
+		}
+
+		ranked := rankCandidates(keys(continuing, order), votes, history, order)
+		tallyRound := newRound(round, candidates, continuing, votes, exhausted)
+
+		for _, candidate := range ranked {
+			if votes[candidate] >= quota && len(result.Elected)+len(tallyRound.Elected) < seats {
+				tallyRound.Elected = append(tallyRound.Elected, candidate)
+			}
+		}
+
+		switch {
+		case len(tallyRound.Elected) > 0:
+			for _, candidate := range tallyRound.Elected {
+				delete(continuing, candidate)
+			}
+			for _, candidate := range tallyRound.Elected {
+				surplus := votes[candidate] - quota
+				for _, ballot := range piles[candidate] {
+					ballot.weight = ballot.weight * surplus / votes[candidate]
+					ballot.at++
+					move(ballot)
+				}
+				delete(piles, candidate)
+			}
+		case len(result.Elected)+len(ranked) <= seats:
+			// the remaining candidates fill the remaining seats
+			tallyRound.Elected = ranked
+			for _, candidate := range ranked {
+				delete(continuing, candidate)
+			}
+		default:
+			loser := ranked[len(ranked)-1]
+			tallyRound.Eliminated = []int64{loser}
+			tallyRound.TieBreak = votes[loser] == votes[ranked[len(ranked)-2]]
+			delete(continuing, loser)
+			for _, ballot := range piles[loser] {
+				ballot.at++
+				move(ballot)
+			}
+			delete(piles, loser)
+		}
+
+		result.Elected = append(result.Elected, tallyRound.Elected...)
+		result.Rounds = append(result.Rounds, tallyRound)
+		history = append(history, votes)
+	}
+
+	return result
+}
+
+// countSingleRound counts every choice of every ballot once and elects the candidates with the most votes
+func countSingleRound(method string, t Tally, candidates []int64, ballots [][]int64, seats int) model.TallyResult {
+	result := model.TallyResult{Method: method, Seats: seats}
+	valid, invalid := filterBallots(t, candidates, ballots)
+	result.Ballots, result.Invalid = len(valid), invalid
+
+	votes := make(map[int64]int64, len(candidates))
+	for _, ballot := range valid {
+		for _, choice := range ballot {
+			votes[choice] += voteScale
+		}
+	}
+
+	return electByVotes(result, candidates, votes)
+}
+
+// countTotals elects the candidates with the most votes from the vote total of every candidate in ballot order.
+// The ballots of an encrypted election are counted this way, the totals are all that is decrypted.
+func countTotals(method string, candidates []int64, totals []int64, ballots int, seats int) model.TallyResult {
+	result := model.TallyResult{Method: method, Seats: seats, Ballots: ballots}
+	votes := make(map[int64]int64, len(candidates))
+	for i, candidate := range candidates {
+		votes[candidate] = totals[i] * voteScale
+	}
+	return electByVotes(result, candidates, votes)
+}
+
+// electByVotes fills the single round of the result and elects the Seats candidates with the most votes
+func electByVotes(result model.TallyResult, candidates []int64, votes map[int64]int64) model.TallyResult {
+	seats := result.Seats
+	order := candidateOrder(candidates)
+	continuing := make(map[int64]bool, len(candidates))
+	for _, candidate := range candidates {
+		continuing[candidate] = true
+	}
+
+	ranked := rankCandidates(candidates, votes, nil, order)
+	if seats > len(ranked) {
+		seats = len(ranked)
+	}
+
+	tallyRound := newRound(1, candidates, continuing, votes, 0)
+	tallyRound.Elected = ranked[:seats]
+	tallyRound.TieBreak = seats > 0 && seats < len(ranked) && votes[ranked[seats-1]] == votes[ranked[seats]]
+
+	result.Rounds = []model.TallyRound{tallyRound}
+	result.Elected = tallyRound.Elected
+	return result
+}
+
+// validateChoices checks that the ballot is not empty and does not choose the same candidate twice
+func validateChoices(choices []int64) error {
+	if len(choices) == 0 {
+		return errors.New("ballot must have at least one choice")
+	}
+
+	seen := make(map[int64]bool, len(choices))
+	for _, choice := range choices {
+		if seen[choice] {
+			return errors.New("ballot can not choose the same candidate twice")
+		}
+		seen[choice] = true
+	}
+	return nil
+}
+
+// filterBallots returns the ballots valid for the counting method and the number of rejected ballots
+func filterBallots(t Tally, candidates []int64, ballots [][]int64) ([][]int64, int) {
+	isCandidate := candidateOrder(candidates)
+	valid := make([][]int64, 0, len(ballots))
+
+	for _, ballot := range ballots {
+		ok := t.Validate(ballot) == nil
+		for _, choice := range ballot {
+			if _, found := isCandidate[choice]; !found {
+				ok = false
+			}
+		}
+		if ok {
+			valid = append(valid, ballot)
+		}
+	}
+
+	return valid, len(ballots) - len(valid)
+}
+
+func candidateOrder(candidates []int64) map[int64]int {
+	order := make(map[int64]int, len(candidates))
+	for i, candidate := range candidates {
+		order[candidate] = i
+	}
+	return order
+}
+
+// keys returns the candidates of the set in ballot order
+func keys(set map[int64]bool, order map[int64]int) []int64 {
+	list := make([]int64, 0, len(set))
+	for candidate := range set {
+		list = append(list, candidate)
+	}
+	sort.Slice(list, func(i, j int) bool { return order[list[i]] < order[list[j]] })
+	return list
+}
+
+func firstContinuing(choices []int64, continuing map[int64]bool) (int64, bool) {
+	for _, choice := range choices {
+		if continuing[choice] {
+			return choice, true
+		}
+	}
+	return 0, false
+}
+
+// rankCandidates orders the candidates from the most to the fewest votes. Ties are broken by looking back
+// through the earlier rounds, from the latest to the first, for a round where the candidates differed.
+// Candidates that tied in every round keep their ballot order.
+func rankCandidates(candidates []int64, votes map[int64]int64, history []map[int64]int64, order map[int64]int) []int64 {
+	ranked := append([]int64(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if votes[a] != votes[b] {
+			return votes[a] > votes[b]
+		}
+		for r := len(history) - 1; r >= 0; r-- {
+			if history[r][a] != history[r][b] {
+				return history[r][a] > history[r][b]
+			}
+		}
+		return order[a] < order[b]
+	})
+	return ranked
+}
+
+// newRound records the votes of the continuing candidates in ballot order
+func newRound(round int, candidates []int64, continuing map[int64]bool, votes map[int64]int64, exhausted int64) model.TallyRound {
+	tallyRound := model.TallyRound{Round: round, Exhausted: float64(exhausted) / float64(voteScale)}
+	for _, candidate := range candidates {
+		if continuing[candidate] {
+			tallyRound.Votes = append(tallyRound.Votes, model.CandidateVotes{
+				CandidateID: candidate,
+				Votes:       float64(votes[candidate]) / float64(voteScale),
+			})
+		}
+	}
+	return tallyRound
+}
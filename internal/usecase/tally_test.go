@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"backend-election/internal/model"
+	"reflect"
+	"testing"
+)
+
+// repeat returns n copies of the ballot
+func repeat(n int, choices ...int64) [][]int64 {
+	ballots := make([][]int64, n)
+	for i := range ballots {
+		ballots[i] = choices
+	}
+	return ballots
+}
+
+func join(groups ...[][]int64) [][]int64 {
+	var ballots [][]int64
+	for _, group := range groups {
+		ballots = append(ballots, group...)
+	}
+	return ballots
+}
+
+func TestTally(t *testing.T) {
+	scenarios := []struct {
+		Name       string
+		Method     string
+		Candidates []int64
+		Ballots    [][]int64
+		Seats      int
+		Elected    []int64
+		Rounds     int
+		Invalid    int
+		Quota      float64
+	}{
+		{
+			Name:       "Plurality",
+			Method:     model.CountingMethodPlurality,
+			Candidates: []int64{1, 2, 3},
+			Ballots:    join(repeat(3, 1), repeat(2, 2), repeat(1, 3), repeat(1, 1, 2), repeat(1, 9)),
+			Seats:      1,
+			Elected:    []int64{1},
+			Rounds:     1,
+			Invalid:    2,
+		},
+		{
+			Name:       "Plurality Tie Keeps Ballot Order",
+			Method:     model.CountingMethodPlurality,
+			Candidates: []int64{1, 2},
+			Ballots:    join(repeat(2, 2), repeat(2, 1)),
+			Seats:      1,
+			Elected:    []int64{1},
+			Rounds:     1,
+		},
+		{
+			Name:       "Approval",
+			Method:     model.CountingMethodApproval,
+			Candidates: []int64{1, 2, 3},
+			Ballots:    join(repeat(1, 1, 2), repeat(1, 2, 3), repeat(1, 2), repeat(1, 3), repeat(1, 3, 3)),
+			Seats:      2,
+			Elected:    []int64{2, 3},
+			Rounds:     1,
+			Invalid:    1,
+		},
+		{
+			Name:       "Instant Runoff",
+			Method:     model.CountingMethodIRV,
+			Candidates: []int64{1, 2, 3},
+			Ballots:    join(repeat(4, 1), repeat(3, 2, 3), repeat(2, 3, 2)),
+			Seats:      1,
+			Elected:    []int64{2},
+			Rounds:     2,
+		},
+		{
+			Name:       "Instant Runoff Tie Broken By Earlier Round",
+			Method:     model.CountingMethodIRV,
+			Candidates: []int64{1, 2, 3, 4},
+			Ballots:    join(repeat(6, 1), repeat(3, 2), repeat(4, 3), repeat(1, 4, 2)),
+			Seats:      1,
+			Elected:    []int64{1},
+			Rounds:     3,
+		},
+		{
+			// oranges 1, pears 2, chocolate 3, strawberries 4, sweets 5, hamburgers 6
+			Name:       "Single Transferable Vote",
+			Method:     model.CountingMethodSTV,
+			Candidates: []int64{1, 2, 3, 4, 5, 6},
+			Ballots:    join(repeat(4, 1), repeat(2, 2, 1), repeat(8, 3, 4), repeat(4, 3, 5), repeat(1, 4), repeat(1, 6)),
+			Seats:      3,
+			Elected:    []int64{3, 1, 4},
+			Rounds:     6,
+			Quota:      6,
+		},
+	}
+
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			tally, err := NewTally(tt.Method)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := tally.Count(tt.Candidates, tt.Ballots, tt.Seats)
+			if !reflect.DeepEqual(result.Elected, tt.Elected) {
+				t.Errorf("wrong elected candidates: got %v want %v", result.Elected, tt.Elected)
+			}
+			if len(result.Rounds) != tt.Rounds {
+				t.Errorf("wrong number of rounds: got %d want %d", len(result.Rounds), tt.Rounds)
+			}
+			if result.Invalid != tt.Invalid {
+				t.Errorf("wrong number of invalid ballots: got %d want %d", result.Invalid, tt.Invalid)
+			}
+			if result.Quota != tt.Quota {
+				t.Errorf("wrong quota: got %v want %v", result.Quota, tt.Quota)
+			}
+
+			// the result does not depend on the order the ballots are read from storage
+			reversed := make([][]int64, len(tt.Ballots))
+			for i, ballot := range tt.Ballots {
+				reversed[len(reversed)-1-i] = ballot
+			}
+			if again := tally.Count(tt.Candidates, reversed, tt.Seats); !reflect.DeepEqual(result, again) {
+				t.Errorf("count is not deterministic: got %+v want %+v", again, result)
+			}
+		})
+	}
+}
+
+func TestInstantRunoffRounds(t *testing.T) {
+	ballots := join(repeat(6, 1), repeat(3, 2), repeat(4, 3), repeat(1, 4, 2))
+	result := InstantRunoff{}.Count([]int64{1, 2, 3, 4}, ballots, 1)
+
+	second := result.Rounds[1]
+	if !second.TieBreak || !reflect.DeepEqual(second.Eliminated, []int64{2}) {
+		t.Errorf("round 2 should break the tie of candidate 2 and 3 by round 1: got %+v", second)
+	}
+
+	last := result.Rounds[2]
+	if last.Exhausted != 4 {
+		t.Errorf("wrong exhausted ballots: got %v want 4", last.Exhausted)
+	}
+}
+
+func TestSingleTransferableVoteSurplus(t *testing.T) {
+	ballots := join(repeat(4, 1), repeat(2, 2, 1), repeat(8, 3, 4), repeat(4, 3, 5), repeat(1, 4), repeat(1, 6))
+	result := SingleTransferableVote{}.Count([]int64{1, 2, 3, 4, 5, 6}, ballots, 3)
+
+	// chocolate holds 12 votes against a quota of 6, so its ballots move on at half weight
+	want := []model.CandidateVotes{
+		{CandidateID: 1, Votes: 4},
+		{CandidateID: 2, Votes: 2},
+		{CandidateID: 4, Votes: 5},
+		{CandidateID: 5, Votes: 2},
+		{CandidateID: 6, Votes: 1},
+	}
+	if !reflect.DeepEqual(result.Rounds[1].Votes, want) {
+		t.Errorf("wrong votes after the surplus transfer: got %v want %v", result.Rounds[1].Votes, want)
+	}
+}
+
+func TestPluralityValidate(t *testing.T) {
+	if err := (Plurality{}).Validate([]int64{1, 2}); err == nil {
+		t.Error("plurality ballot with two choices should be rejected")
+	}
+
+	if _, err := NewTally("borda"); err == nil {
+		t.Error("unknown counting method should be rejected")
+	}
+}
+
+func TestCountTotals(t *testing.T) {
+	// an encrypted election only reveals the totals, they elect the same candidates as the ballots would
+	ballots := join(repeat(3, 1, 3), repeat(2, 2), repeat(1, 3), repeat(1, 2, 3))
+	want := Approval{}.Count([]int64{1, 2, 3}, ballots, 2)
+	got := countTotals(model.CountingMethodApproval, []int64{1, 2, 3}, []int64{3, 3, 5}, len(ballots), 2)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("totals count differently: got %+v want %+v", got, want)
+	}
+}
@@ -0,0 +1,318 @@
+package usecase
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/elgamal"
+	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+var (
+	// ErrNotHomomorphic is returned for an encrypted election whose counting method needs every ballot decrypted
+	ErrNotHomomorphic = errors.New("only plurality and approval elections can be encrypted")
+	// ErrNotEncrypted is returned when encryption is used with an election whose ballots are stored in plaintext
+	ErrNotEncrypted = errors.New("election is not encrypted")
+	// ErrEncryptionRequired is returned for a plaintext ballot in an encrypted election
+	ErrEncryptionRequired = errors.New("ballot of an encrypted election must be encrypted")
+	// ErrNotTrustee is returned when a user who is not a trustee of the election submits a decryption
+	ErrNotTrustee = errors.New("user is not a trustee of the election")
+	// ErrTallyNotDecrypted is returned for the results of an encrypted election before enough trustees decrypted them
+	ErrTallyNotDecrypted = errors.New("results are available once the threshold of trustees decrypted the tally")
+)
+
+type TrusteeUC struct {
+	Log *logger.Logger
+	DB  *sql.DB
+}
+
+// Setup creates the key of the election and deals its shares to the trustees, threshold of whom decrypt the tally
+// together. Every share is kept for its own trustee to fetch once, the user setting up the trustees never sees them.
+func (uc TrusteeUC) Setup(ctx context.Context, electionID int64, trusteeRequest dto.TrusteeSetupRequest) (model.Election, []model.Trustee, int, error) {
+	var election model.Election
+	switch ctx.Err() {
+	case context.Canceled:
+		return election, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return election, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return election, nil, http.StatusNotFound, err
+	} else if err != nil {
+		return election, nil, http.StatusInternalServerError, err
+	}
+	election = electionRepo.ElectionEntity
+
+	if _, err := encryptedTotals(election.CountingMethod, 0); err != nil {
+		return election, nil, http.StatusBadRequest, uc.Log.Error(err)
+	}
+
+	publicKey, shares, verificationKeys, err := elgamal.Deal(trusteeRequest.Threshold, len(trusteeRequest.UserIDs))
+	if err != nil {
+		return election, nil, http.StatusInternalServerError, uc.Log.Error(err)
+	}
+
+	trustees := make([]model.Trustee, 0, len(shares))
+	for i, share := range shares {
+		trustees = append(trustees, model.Trustee{
+			ElectionID:      electionID,
+			Index:           share.Index,
+			UserID:          trusteeRequest.UserIDs[i],
+			VerificationKey: verificationKeys[i].Bytes(),
+			Share:           share.Value.Bytes(),
+		})
+	}
+
+	trusteeRepo := repository.TrusteeRepository{Log: uc.Log, Db: uc.DB}
+	err = trusteeRepo.Setup(ctx, electionID, publicKey.Bytes(), trusteeRequest.Threshold, trustees)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return election, nil, http.StatusNotFound, err
+	case repository.ErrTrusteeUserNotFound:
+		return election, nil, http.StatusBadRequest, err
+	case repository.ErrElectionLocked, repository.ErrTrusteesAssigned:
+		return election, nil, http.StatusConflict, err
+	default:
+		return election, nil, http.StatusInternalServerError, err
+	}
+
+	election.PublicKey, election.TrusteeThreshold = publicKey.Bytes(), trusteeRequest.Threshold
+	return election, trustees, http.StatusCreated, nil
+}
+
+// Share hands the authenticated trustee their key share of the election. The share is erased once fetched,
+// so it can not be fetched again.
+func (uc TrusteeUC) Share(ctx context.Context, electionID int64) (model.Trustee, int, error) {
+	trustee := model.Trustee{ElectionID: electionID, UserID: ctx.Value(myctx.Key("user_id")).(int64)}
+	switch ctx.Err() {
+	case context.Canceled:
+		return trustee, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return trustee, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	trusteeRepo := repository.TrusteeRepository{Log: uc.Log, Db: uc.DB, TrusteeEntity: trustee}
+	if err := trusteeRepo.FindByUser(ctx); err == sql.ErrNoRows {
+		return trustee, http.StatusForbidden, uc.Log.Error(ErrNotTrustee)
+	} else if err != nil {
+		return trustee, http.StatusInternalServerError, err
+	}
+
+	if err := trusteeRepo.TakeShare(ctx); err == repository.ErrShareFetched {
+		return trustee, http.StatusConflict, err
+	} else if err != nil {
+		return trustee, http.StatusInternalServerError, err
+	}
+
+	return trusteeRepo.TrusteeEntity, http.StatusOK, nil
+}
+
+// List returns the trustees of the election and whether each of them decrypted the tally
+func (uc TrusteeUC) List(ctx context.Context, electionID int64) (model.Election, []model.Trustee, int, error) {
+	var election model.Election
+	switch ctx.Err() {
+	case context.Canceled:
+		return election, nil, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return election, nil, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	election, statusCode, err := uc.encryptedElection(ctx, electionID)
+	if err != nil {
+		return election, nil, statusCode, err
+	}
+
+	trusteeRepo := repository.TrusteeRepository{Log: uc.Log, Db: uc.DB, TrusteeEntity: model.Trustee{ElectionID: electionID}}
+	trustees, err := trusteeRepo.List(ctx)
+	if err != nil {
+		return election, nil, http.StatusInternalServerError, err
+	}
+
+	return election, trustees, http.StatusOK, nil
+}
+
+// EncryptedTally returns the encrypted votes of every candidate of the closed election, which the trustees decrypt
+func (uc TrusteeUC) EncryptedTally(ctx context.Context, electionID int64) (model.Election, []model.Candidate, []elgamal.Ciphertext, int, int, error) {
+	var election model.Election
+	switch ctx.Err() {
+	case context.Canceled:
+		return election, nil, nil, 0, http.StatusInternalServerError, uc.Log.Error(context.Canceled)
+	case context.DeadlineExceeded:
+		return election, nil, nil, 0, http.StatusInternalServerError, uc.Log.Error(context.DeadlineExceeded)
+	default:
+	}
+
+	election, statusCode, err := ResultUC{Log: uc.Log, DB: uc.DB}.closedElection(ctx, electionID)
+	if err != nil {
+		return election, nil, nil, 0, statusCode, err
+	}
+	if len(election.PublicKey) == 0 {
+		return election, nil, nil, 0, http.StatusConflict, uc.Log.Error(ErrNotEncrypted)
+	}
+
+	candidates, totals, ballots, err := encryptedTally(ctx, uc.Log, uc.DB, electionID)
+	if err != nil {
+		return election, nil, nil, 0, http.StatusInternalServerError, err
+	}
+
+	return election, candidates, totals, ballots, http.StatusOK, nil
+}
+
+// Decrypt stores the decryption share of the authenticated trustee for the encrypted tally, once the proof of every
+// share holds. It returns the trustee and how many trustees decrypted the tally so far.
+func (uc TrusteeUC) Decrypt(ctx context.Context, electionID int64, decryptionRequest dto.DecryptionRequest) (model.Election, model.Trustee, int, int, error) {
+	var trustee model.Trustee
+	election, candidates, totals, _, statusCode, err := uc.EncryptedTally(ctx, electionID)
+	if err != nil {
+		return election, trustee, 0, statusCode, err
+	}
+
+	trusteeRepo := repository.TrusteeRepository{Log: uc.Log, Db: uc.DB, TrusteeEntity: model.Trustee{ElectionID: electionID, UserID: ctx.Value(myctx.Key("user_id")).(int64)}}
+	if err := trusteeRepo.FindByUser(ctx); err == sql.ErrNoRows {
+		return election, trustee, 0, http.StatusForbidden, uc.Log.Error(ErrNotTrustee)
+	} else if err != nil {
+		return election, trustee, 0, http.StatusInternalServerError, err
+	}
+	trustee = trusteeRepo.TrusteeEntity
+	if trustee.Decrypted {
+		return election, trustee, 0, http.StatusConflict, uc.Log.Error(repository.ErrAlreadyDecrypted)
+	}
+
+	shares, err := decryptionRequest.ToEntity()
+	if err != nil {
+		return election, trustee, 0, http.StatusBadRequest, uc.Log.Error(err)
+	}
+	if len(shares) != len(candidates) {
+		return election, trustee, 0, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("shares must decrypt all %d candidates", len(candidates)))
+	}
+
+	decryption := model.TallyDecryption{ElectionID: electionID, TrusteeIndex: trustee.Index}
+	verificationKey := new(big.Int).SetBytes(trustee.VerificationKey)
+	for i, candidate := range candidates {
+		share, ok := shares[candidate.ID]
+		if !ok {
+			return election, trustee, 0, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("share of candidate %d is missing", candidate.ID))
+		}
+		if err := share.Verify(verificationKey, totals[i]); err != nil {
+			return election, trustee, 0, http.StatusBadRequest, uc.Log.Error(fmt.Errorf("share of candidate %d: %w", candidate.ID, err))
+		}
+		decryption.Factors = append(decryption.Factors, share.Factor.Bytes())
+		decryption.Challenges = append(decryption.Challenges, share.Proof.Challenge.Bytes())
+		decryption.Responses = append(decryption.Responses, share.Proof.Response.Bytes())
+	}
+
+	if err := trusteeRepo.SaveDecryption(ctx, decryption); err == repository.ErrAlreadyDecrypted {
+		return election, trustee, 0, http.StatusConflict, err
+	} else if err != nil {
+		return election, trustee, 0, http.StatusInternalServerError, err
+	}
+
+	decryptions, err := trusteeRepo.ListDecryptions(ctx)
+	if err != nil {
+		return election, trustee, 0, http.StatusInternalServerError, err
+	}
+
+	return election, trustee, len(decryptions), http.StatusCreated, nil
+}
+
+// encryptedElection finds the election and checks that its ballots are encrypted
+func (uc TrusteeUC) encryptedElection(ctx context.Context, electionID int64) (model.Election, int, error) {
+	electionRepo := repository.ElectionRepository{Log: uc.Log, Db: uc.DB, ElectionEntity: model.Election{ID: electionID}}
+	if err := electionRepo.Find(ctx); err == sql.ErrNoRows {
+		return electionRepo.ElectionEntity, http.StatusNotFound, err
+	} else if err != nil {
+		return electionRepo.ElectionEntity, http.StatusInternalServerError, err
+	}
+
+	if len(electionRepo.ElectionEntity.PublicKey) == 0 {
+		return electionRepo.ElectionEntity, http.StatusConflict, uc.Log.Error(ErrNotEncrypted)
+	}
+
+	return electionRepo.ElectionEntity, http.StatusOK, nil
+}
+
+// encryptedTotals returns the numbers of candidates an encrypted ballot of the counting method may choose.
+// Ranked ballots can not be added up without decrypting them one by one, so IRV and STV are not encrypted.
+func encryptedTotals(method string, candidates int) ([]int64, error) {
+	switch method {
+	case model.CountingMethodPlurality:
+		return []int64{1}, nil
+	case model.CountingMethodApproval:
+		totals := make([]int64, 0, candidates)
+		for total := 1; total <= candidates; total++ {
+			totals = append(totals, int64(total))
+		}
+		return totals, nil
+	default:
+		return nil, ErrNotHomomorphic
+	}
+}
+
+// encryptedBallot verifies the encrypted ballot against the key of the election and returns its ciphertexts as stored
+func encryptedBallot(election model.Election, candidates int, request *dto.EncryptedBallot) ([][]byte, error) {
+	if request == nil {
+		return nil, ErrEncryptionRequired
+	}
+
+	totals, err := encryptedTotals(election.CountingMethod, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	ballot, err := request.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+	if err := ballot.Verify(new(big.Int).SetBytes(election.PublicKey), candidates, totals); err != nil {
+		return nil, err
+	}
+
+	ciphertexts := make([][]byte, 0, 2*len(ballot.Ciphertexts))
+	for _, c := range ballot.Ciphertexts {
+		ciphertexts = append(ciphertexts, c.A.Bytes(), c.B.Bytes())
+	}
+	return ciphertexts, nil
+}
+
+// encryptedTally multiplies the ciphertexts of all ballots of the election per candidate, in ballot order
+func encryptedTally(ctx context.Context, log *logger.Logger, db *sql.DB, electionID int64) ([]model.Candidate, []elgamal.Ciphertext, int, error) {
+	candidateRepo := repository.CandidateRepository{Log: log, Db: db, CandidateEntity: model.Candidate{ElectionID: electionID}}
+	candidates, err := candidateRepo.List(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	ballotRepo := repository.BallotRepository{Log: log, Db: db, BallotEntity: model.Ballot{ElectionID: electionID}}
+	ballots, err := ballotRepo.ListCiphertexts(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	totals := make([]elgamal.Ciphertext, len(candidates))
+	for i := range totals {
+		totals[i] = elgamal.Zero()
+	}
+	for _, ciphertexts := range ballots {
+		if len(ciphertexts) != 2*len(candidates) {
+			return nil, nil, 0, log.Error(fmt.Errorf("ballot has %d ciphertexts for %d candidates", len(ciphertexts)/2, len(candidates)))
+		}
+		for i := range totals {
+			totals[i] = totals[i].Add(elgamal.Ciphertext{A: new(big.Int).SetBytes(ciphertexts[2*i]), B: new(big.Int).SetBytes(ciphertexts[2*i+1])})
+		}
+	}
+
+	return candidates, totals, len(ballots), nil
+}
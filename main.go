@@ -6,15 +6,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/pkg/biometric"
+	"backend-election/internal/pkg/certificate"
 	"backend-election/internal/pkg/config"
 	"backend-election/internal/pkg/database"
 	"backend-election/internal/pkg/logger"
+	"backend-election/internal/pkg/peer"
 	"backend-election/internal/pkg/redis"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/pkg/stream"
 	"backend-election/internal/route"
+	"backend-election/internal/usecase"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
@@ -52,12 +61,121 @@ func main() {
 	}
 	defer redisClient.Close()
 
+	// the access table follows the private routes, orphaned access is left for an admin to delete through the API
+	accessSync, _, err := usecase.AccessUC{Log: log, DB: db.Conn, Cache: redisClient}.Sync(context.Background(), route.Access())
+	if err != nil {
+		fmt.Printf("Could not sync the access of the routes: %v", err)
+	} else {
+		for _, access := range accessSync.Added {
+			log.Info(fmt.Sprintf("access %s (%s) is added", access.Path, access.Name))
+		}
+		for _, access := range accessSync.Orphaned {
+			log.Info(fmt.Sprintf("access %s (%s) matches no route", access.Path, access.Name))
+		}
+	}
+
+	store, err := storage.New()
+	if err != nil {
+		fmt.Printf("Could not setup storage: %v", err)
+		os.Exit(1)
+	}
+
+	engine, err := biometric.New()
+	if err != nil {
+		fmt.Printf("Could not setup biometric matching: %v", err)
+		os.Exit(1)
+	}
+
+	peerKey, err := peer.PrivateKey()
+	if err == peer.ErrNoKey {
+		fmt.Println("PEER_PRIVATE_KEY is not set, the ledgers of the peers are not replicated")
+	} else if err != nil {
+		fmt.Printf("Could not read the peer key: %v", err)
+		os.Exit(1)
+	}
+
+	certifierKeys, err := certificate.PrivateKeys()
+	if err == certificate.ErrNoKeys {
+		fmt.Println("CERTIFIER_PRIVATE_KEYS is not set, elections can not be certified")
+	} else if err != nil {
+		fmt.Printf("Could not read the certifier keys: %v", err)
+		os.Exit(1)
+	}
+
+	syncInterval := time.Minute
+	if value := os.Getenv("LEDGER_SYNC_INTERVAL"); value != "" {
+		if syncInterval, err = time.ParseDuration(value); err != nil {
+			fmt.Printf("Invalid LEDGER_SYNC_INTERVAL: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	auditFlushInterval := 30 * time.Second
+	if value := os.Getenv("AUDIT_FLUSH_INTERVAL"); value != "" {
+		if auditFlushInterval, err = time.ParseDuration(value); err != nil {
+			fmt.Printf("Invalid AUDIT_FLUSH_INTERVAL: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// the audit records the audit log refuses wait on the disk until they are appended
+	spool := &audit.Spool{Path: os.Getenv("AUDIT_SPOOL")}
+	if spool.Path == "" {
+		spool.Path = filepath.Join("storage", "audit-spool.jsonl")
+	}
+
+	schedulerInterval := 30 * time.Second
+	if value := os.Getenv("SCHEDULER_INTERVAL"); value != "" {
+		if schedulerInterval, err = time.ParseDuration(value); err != nil {
+			fmt.Printf("Invalid SCHEDULER_INTERVAL: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// the result streams need redis pub/sub, without it the stream routes answer 503 and the rest keeps serving
+	hub, err := stream.NewHub(context.Background(), redisClient, usecase.ResultsChannelPattern)
+	if err != nil {
+		fmt.Printf("Could not subscribe to the result streams, streaming results is disabled: %v", err)
+	}
+
 	srv := &http.Server{
 		Addr:         ":" + os.Getenv("APP_PORT"),
 		WriteTimeout: time.Second * 5,
 		ReadTimeout:  time.Second * 5,
 		IdleTimeout:  time.Second * 30,
-		Handler:      route.ApiRoute(log, db, redisClient),
+		Handler:      route.ApiRoute(log, db, redisClient, store, hub, engine, peerKey, certifierKeys, spool),
+	}
+
+	// streams outlive the WriteTimeout, end them when the shutdown starts so it does not wait for them
+	if hub != nil {
+		srv.RegisterOnShutdown(hub.Close)
+	}
+
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	if peerKey != nil && syncInterval > 0 {
+		go usecase.LedgerUC{Log: log, DB: db.Conn, Key: peerKey}.Run(syncCtx, syncInterval)
+	}
+
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	defer stopFlush()
+	if auditFlushInterval > 0 {
+		go usecase.AuditUC{Log: log, DB: db.Conn, Spool: spool}.Run(flushCtx, auditFlushInterval)
+	}
+
+	// every replica runs the scheduler, the one holding the redis lock opens and closes the elections
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	schedulerDone := make(chan struct{})
+	if redisClient != nil && schedulerInterval > 0 {
+		hostname, _ := os.Hostname()
+		scheduler := usecase.SchedulerUC{Log: log, DB: db.Conn, Cache: redisClient, Owner: hostname + "-" + uuid.NewString()}
+		go func() {
+			scheduler.Run(schedulerCtx, schedulerInterval)
+			close(schedulerDone)
+		}()
+	} else {
+		close(schedulerDone)
 	}
 
 	go func() {
@@ -70,6 +188,10 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	fmt.Println("Shutdown Server ...", "")
+	stopSync()
+	stopFlush()
+	stopScheduler()
+	<-schedulerDone
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
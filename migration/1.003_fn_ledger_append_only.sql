@@ -0,0 +1,11 @@
+-- ledger_append_only refuses to change or remove ledger rows, a replica or an audit of the ledger must be able to rely
+-- on a height never being written twice
+CREATE OR REPLACE FUNCTION public.ledger_append_only()
+ RETURNS trigger
+ LANGUAGE plpgsql
+AS $function$
+BEGIN
+  RAISE EXCEPTION 'table % is append-only', TG_TABLE_NAME;
+END;
+$function$
+;
@@ -0,0 +1,14 @@
+CREATE TABLE public.elections (
+	id int8 DEFAULT int64_id('elections'::text, 'id'::text) NOT NULL,
+	"name" varchar(128) NOT NULL,
+	description text NULL,
+	status varchar(16) DEFAULT 'draft'::character varying NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT elections_pk PRIMARY KEY (id),
+	CONSTRAINT elections_status_check CHECK (status IN ('draft', 'scheduled', 'open', 'closed', 'tallied', 'certified'))
+);
@@ -0,0 +1,12 @@
+CREATE TABLE public.election_transitions (
+	id int8 DEFAULT int64_id('election_transitions'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	from_status varchar(16) NOT NULL,
+	to_status varchar(16) NOT NULL,
+	user_id int8 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NOT NULL,
+	CONSTRAINT election_transitions_pk PRIMARY KEY (id),
+	CONSTRAINT election_transitions_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id)
+);
+
+CREATE INDEX election_transitions_election_idx ON public.election_transitions (election_id, created_at);
@@ -0,0 +1,19 @@
+CREATE TABLE public.candidates (
+	id int8 DEFAULT int64_id('candidates'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	ballot_number int4 NOT NULL,
+	"name" varchar(128) NOT NULL,
+	running_mate_name varchar(128) NULL,
+	party varchar(128) NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT candidates_pk PRIMARY KEY (id),
+	CONSTRAINT candidates_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT candidates_ballot_number_check CHECK (ballot_number > 0)
+);
+
+CREATE UNIQUE INDEX candidates_ballot_number_unique ON public.candidates (election_id, ballot_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,22 @@
+CREATE TABLE public.voters (
+	id int8 DEFAULT int64_id('voters'::text, 'id'::text) NOT NULL,
+	nik bpchar(16) NOT NULL,
+	"name" varchar(128) NOT NULL,
+	normalized_name varchar(128) NOT NULL,
+	birth_place varchar(64) NULL,
+	birth_date date NOT NULL,
+	gender bpchar(1) NOT NULL,
+	address text NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT voters_pk PRIMARY KEY (id),
+	CONSTRAINT voters_nik_check CHECK (nik ~ '^[0-9]{16}$'),
+	CONSTRAINT voters_gender_check CHECK (gender IN ('L', 'P'))
+);
+
+CREATE UNIQUE INDEX voters_nik_unique ON public.voters (nik) WHERE deleted_at IS NULL;
+CREATE INDEX voters_normalized_name_birth_date_idx ON public.voters (normalized_name, birth_date) WHERE deleted_at IS NULL;
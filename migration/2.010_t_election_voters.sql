@@ -0,0 +1,9 @@
+CREATE TABLE public.election_voters (
+	election_id int8 NOT NULL,
+	voter_id int8 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT election_voters_pk PRIMARY KEY (election_id, voter_id),
+	CONSTRAINT election_voters_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT election_voters_voter_fk FOREIGN KEY (voter_id) REFERENCES public.voters(id)
+);
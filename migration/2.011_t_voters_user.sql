@@ -0,0 +1,3 @@
+ALTER TABLE public.voters ADD user_id int8 NULL;
+
+CREATE UNIQUE INDEX voters_user_id_unique ON public.voters (user_id) WHERE deleted_at IS NULL AND user_id IS NOT NULL;
@@ -0,0 +1,9 @@
+-- voter_participations records that a voter has voted. It is kept apart from the ballots table
+-- and no column links the two, so a ballot can not be traced back to its voter.
+CREATE TABLE public.voter_participations (
+	election_id int8 NOT NULL,
+	voter_id int8 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NOT NULL,
+	CONSTRAINT voter_participations_pk PRIMARY KEY (election_id, voter_id),
+	CONSTRAINT voter_participations_election_voter_fk FOREIGN KEY (election_id, voter_id) REFERENCES public.election_voters(election_id, voter_id)
+);
@@ -0,0 +1,12 @@
+-- ballots hold the anonymous ballot content. The id is random and there is no timestamp,
+-- so neither the insert order nor the cast time can be matched with voter_participations.
+CREATE TABLE public.ballots (
+	id int8 DEFAULT int64_id('ballots'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	choices int8[] NOT NULL,
+	CONSTRAINT ballots_pk PRIMARY KEY (id),
+	CONSTRAINT ballots_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT ballots_choices_check CHECK (cardinality(choices) > 0)
+);
+
+CREATE INDEX ballots_election_idx ON public.ballots (election_id);
@@ -0,0 +1,5 @@
+ALTER TABLE public.elections ADD counting_method varchar(16) DEFAULT 'plurality'::character varying NOT NULL;
+ALTER TABLE public.elections ADD seats int4 DEFAULT 1 NOT NULL;
+
+ALTER TABLE public.elections ADD CONSTRAINT elections_counting_method_check CHECK (counting_method IN ('plurality', 'approval', 'irv', 'stv'));
+ALTER TABLE public.elections ADD CONSTRAINT elections_seats_check CHECK (seats > 0);
@@ -0,0 +1,5 @@
+ALTER TABLE public.elections ADD seat_method varchar(16) DEFAULT 'sainte_lague'::character varying NOT NULL;
+ALTER TABLE public.elections ADD threshold numeric(5,2) DEFAULT 0 NOT NULL;
+
+ALTER TABLE public.elections ADD CONSTRAINT elections_seat_method_check CHECK (seat_method IN ('sainte_lague', 'dhondt'));
+ALTER TABLE public.elections ADD CONSTRAINT elections_threshold_check CHECK (threshold >= 0 AND threshold <= 100);
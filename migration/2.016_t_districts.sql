@@ -0,0 +1,17 @@
+CREATE TABLE public.districts (
+	id int8 DEFAULT int64_id('districts'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	"name" varchar(128) NOT NULL,
+	seats int4 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT districts_pk PRIMARY KEY (id),
+	CONSTRAINT districts_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT districts_seats_check CHECK (seats > 0)
+);
+
+CREATE UNIQUE INDEX districts_name_unique ON public.districts (election_id, "name") WHERE deleted_at IS NULL;
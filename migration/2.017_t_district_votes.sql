@@ -0,0 +1,10 @@
+CREATE TABLE public.district_votes (
+	district_id int8 NOT NULL,
+	party varchar(128) NOT NULL,
+	votes int8 NOT NULL,
+	updated_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	updated_by int8 NOT NULL,
+	CONSTRAINT district_votes_pk PRIMARY KEY (district_id, party),
+	CONSTRAINT district_votes_district_fk FOREIGN KEY (district_id) REFERENCES public.districts(id),
+	CONSTRAINT district_votes_votes_check CHECK (votes >= 0)
+);
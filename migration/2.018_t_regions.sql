@@ -0,0 +1,20 @@
+CREATE TABLE public.regions (
+	id int8 DEFAULT int64_id('regions'::text, 'id'::text) NOT NULL,
+	parent_id int8 NULL,
+	"level" varchar(16) NOT NULL,
+	code varchar(16) NOT NULL,
+	"name" varchar(128) NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT regions_pk PRIMARY KEY (id),
+	CONSTRAINT regions_parent_fk FOREIGN KEY (parent_id) REFERENCES public.regions(id),
+	CONSTRAINT regions_level_check CHECK ("level" IN ('national', 'province', 'regency', 'subdistrict', 'village')),
+	CONSTRAINT regions_parent_check CHECK (("level" = 'national') = (parent_id IS NULL))
+);
+
+CREATE UNIQUE INDEX regions_code_unique ON public.regions (code) WHERE deleted_at IS NULL;
+CREATE INDEX regions_parent_id_idx ON public.regions (parent_id);
@@ -0,0 +1,16 @@
+CREATE TABLE public.polling_stations (
+	id int8 DEFAULT int64_id('polling_stations'::text, 'id'::text) NOT NULL,
+	village_id int8 NOT NULL,
+	"number" varchar(8) NOT NULL,
+	address text NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	deleted_at timestamptz NULL,
+	deleted_by int8 NULL,
+	CONSTRAINT polling_stations_pk PRIMARY KEY (id),
+	CONSTRAINT polling_stations_village_fk FOREIGN KEY (village_id) REFERENCES public.regions(id)
+);
+
+CREATE UNIQUE INDEX polling_stations_number_unique ON public.polling_stations (village_id, "number") WHERE deleted_at IS NULL;
@@ -0,0 +1,20 @@
+-- tally_forms hold the C1 form of a polling station: the votes counted at the polling station for every candidate
+CREATE TABLE public.tally_forms (
+	id int8 DEFAULT int64_id('tally_forms'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	polling_station_id int8 NOT NULL,
+	status varchar(16) DEFAULT 'submitted'::character varying NOT NULL,
+	invalid_votes int4 DEFAULT 0 NOT NULL,
+	note text NULL,
+	submitted_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	submitted_by int8 NOT NULL,
+	reviewed_at timestamptz NULL,
+	reviewed_by int8 NULL,
+	CONSTRAINT tally_forms_pk PRIMARY KEY (id),
+	CONSTRAINT tally_forms_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT tally_forms_polling_station_fk FOREIGN KEY (polling_station_id) REFERENCES public.polling_stations(id),
+	CONSTRAINT tally_forms_status_check CHECK (status IN ('submitted', 'approved', 'rejected')),
+	CONSTRAINT tally_forms_invalid_votes_check CHECK (invalid_votes >= 0)
+);
+
+CREATE UNIQUE INDEX tally_forms_polling_station_unique ON public.tally_forms (election_id, polling_station_id);
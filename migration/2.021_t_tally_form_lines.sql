@@ -0,0 +1,9 @@
+CREATE TABLE public.tally_form_lines (
+	tally_form_id int8 NOT NULL,
+	candidate_id int8 NOT NULL,
+	votes int4 NOT NULL,
+	CONSTRAINT tally_form_lines_pk PRIMARY KEY (tally_form_id, candidate_id),
+	CONSTRAINT tally_form_lines_tally_form_fk FOREIGN KEY (tally_form_id) REFERENCES public.tally_forms(id),
+	CONSTRAINT tally_form_lines_candidate_fk FOREIGN KEY (candidate_id) REFERENCES public.candidates(id),
+	CONSTRAINT tally_form_lines_votes_check CHECK (votes >= 0)
+);
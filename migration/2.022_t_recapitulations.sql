@@ -0,0 +1,22 @@
+-- recapitulations hold the totals declared for a region. The totals are checked against the sum of the
+-- approved tally forms (village) or the approved recapitulations of the child regions (higher levels).
+CREATE TABLE public.recapitulations (
+	id int8 DEFAULT int64_id('recapitulations'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	region_id int8 NOT NULL,
+	status varchar(16) DEFAULT 'submitted'::character varying NOT NULL,
+	revision int4 DEFAULT 1 NOT NULL,
+	invalid_votes int8 DEFAULT 0 NOT NULL,
+	note text NULL,
+	submitted_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	submitted_by int8 NOT NULL,
+	reviewed_at timestamptz NULL,
+	reviewed_by int8 NULL,
+	CONSTRAINT recapitulations_pk PRIMARY KEY (id),
+	CONSTRAINT recapitulations_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT recapitulations_region_fk FOREIGN KEY (region_id) REFERENCES public.regions(id),
+	CONSTRAINT recapitulations_status_check CHECK (status IN ('submitted', 'approved', 'rejected')),
+	CONSTRAINT recapitulations_invalid_votes_check CHECK (invalid_votes >= 0)
+);
+
+CREATE UNIQUE INDEX recapitulations_region_unique ON public.recapitulations (election_id, region_id);
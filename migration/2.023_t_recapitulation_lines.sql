@@ -0,0 +1,9 @@
+CREATE TABLE public.recapitulation_lines (
+	recapitulation_id int8 NOT NULL,
+	candidate_id int8 NOT NULL,
+	votes int8 NOT NULL,
+	CONSTRAINT recapitulation_lines_pk PRIMARY KEY (recapitulation_id, candidate_id),
+	CONSTRAINT recapitulation_lines_recapitulation_fk FOREIGN KEY (recapitulation_id) REFERENCES public.recapitulations(id),
+	CONSTRAINT recapitulation_lines_candidate_fk FOREIGN KEY (candidate_id) REFERENCES public.candidates(id),
+	CONSTRAINT recapitulation_lines_votes_check CHECK (votes >= 0)
+);
@@ -0,0 +1,15 @@
+-- every submission of a recapitulation keeps its discrepancies, a null candidate_id is the invalid votes total
+CREATE TABLE public.recapitulation_discrepancies (
+	id int8 DEFAULT int64_id('recapitulation_discrepancies'::text, 'id'::text) NOT NULL,
+	recapitulation_id int8 NOT NULL,
+	revision int4 NOT NULL,
+	candidate_id int8 NULL,
+	submitted_votes int8 NOT NULL,
+	computed_votes int8 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT recapitulation_discrepancies_pk PRIMARY KEY (id),
+	CONSTRAINT recapitulation_discrepancies_recapitulation_fk FOREIGN KEY (recapitulation_id) REFERENCES public.recapitulations(id),
+	CONSTRAINT recapitulation_discrepancies_candidate_fk FOREIGN KEY (candidate_id) REFERENCES public.candidates(id)
+);
+
+CREATE INDEX recapitulation_discrepancies_recapitulation_id_idx ON public.recapitulation_discrepancies (recapitulation_id);
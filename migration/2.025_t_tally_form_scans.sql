@@ -0,0 +1,16 @@
+-- tally_form_scans hold the photos and scans of the signed tally sheet of a tally form, the file itself is kept in the storage under its sha256 checksum
+CREATE TABLE public.tally_form_scans (
+	id int8 DEFAULT int64_id('tally_form_scans'::text, 'id'::text) NOT NULL,
+	tally_form_id int8 NOT NULL,
+	sha256 char(64) NOT NULL,
+	content_type varchar(64) NOT NULL,
+	"size" int8 NOT NULL,
+	filename varchar(255) NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT tally_form_scans_pk PRIMARY KEY (id),
+	CONSTRAINT tally_form_scans_tally_form_fk FOREIGN KEY (tally_form_id) REFERENCES public.tally_forms(id),
+	CONSTRAINT tally_form_scans_size_check CHECK ("size" > 0)
+);
+
+CREATE UNIQUE INDEX tally_form_scans_unique ON public.tally_form_scans (tally_form_id, sha256);
@@ -0,0 +1,2 @@
+-- tally_version counts the approved tally forms of an election, so a results snapshot and the stream of updates after it can be lined up
+ALTER TABLE public.elections ADD tally_version int8 DEFAULT 0 NOT NULL;
@@ -0,0 +1,17 @@
+-- voter_fingerprints hold the enrolled fingerprint minutiae template of a voter, sealed with AES-256-GCM under BIOMETRIC_KEY.
+-- Rows are deleted, not soft deleted, so the biometric data is gone once a voter withdraws it.
+CREATE TABLE public.voter_fingerprints (
+	voter_id int8 NOT NULL,
+	format varchar(16) NOT NULL,
+	finger_position int2 NOT NULL,
+	minutiae_count int2 NOT NULL,
+	quality int2 NOT NULL,
+	"template" bytea NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	CONSTRAINT voter_fingerprints_pk PRIMARY KEY (voter_id),
+	CONSTRAINT voter_fingerprints_voter_fk FOREIGN KEY (voter_id) REFERENCES public.voters(id),
+	CONSTRAINT voter_fingerprints_format_check CHECK (format IN ('iso19794-2', 'ansi378'))
+);
@@ -0,0 +1,18 @@
+-- peers are the other nodes of the election network. A peer signs its calls with the Ed25519 key registered here,
+-- only active peers are accepted. Revoking a peer is final, a new key is registered as a new peer.
+CREATE TABLE public.peers (
+	id int8 DEFAULT int64_id('peers'::text, 'id'::text) NOT NULL,
+	"name" varchar(128) NOT NULL,
+	endpoint varchar(255) NOT NULL,
+	public_key bytea NOT NULL,
+	status varchar(16) DEFAULT 'pending' NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by int8 NULL,
+	CONSTRAINT peers_pk PRIMARY KEY (id),
+	CONSTRAINT peers_public_key_check CHECK (length(public_key) = 32),
+	CONSTRAINT peers_status_check CHECK (status IN ('pending', 'active', 'suspended', 'revoked'))
+);
+
+CREATE UNIQUE INDEX peers_public_key_unique ON public.peers (public_key);
@@ -0,0 +1,19 @@
+-- ledger_entries is the hash chained ballot ledger. origin_peer_id is 0 for the ledger of this node, any other value
+-- is the replica of the ledger of that peer. hash chains the entry to the previous one and root is the RFC 6962
+-- Merkle tree hash over the hashes of all entries of the origin up to this height.
+CREATE TABLE public.ledger_entries (
+	origin_peer_id int8 NOT NULL,
+	height int8 NOT NULL,
+	payload bytea NOT NULL,
+	prev_hash bytea NOT NULL,
+	hash bytea NOT NULL,
+	root bytea NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT ledger_entries_pk PRIMARY KEY (origin_peer_id, height),
+	CONSTRAINT ledger_entries_height_check CHECK (height > 0)
+);
+
+CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON public.ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+CREATE TRIGGER ledger_entries_no_truncate BEFORE TRUNCATE ON public.ledger_entries
+	FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
@@ -0,0 +1,11 @@
+-- ledger_heads holds the last entry of every ledger with the compact Merkle tree needed to extend it.
+-- Appending locks the row, so the entries of one origin are written one writer at a time.
+CREATE TABLE public.ledger_heads (
+	origin_peer_id int8 NOT NULL,
+	height int8 NOT NULL,
+	hash bytea NOT NULL,
+	root bytea NOT NULL,
+	frontier bytea[] NOT NULL,
+	updated_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT ledger_heads_pk PRIMARY KEY (origin_peer_id)
+);
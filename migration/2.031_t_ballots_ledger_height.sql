@@ -0,0 +1,5 @@
+-- ledger_height is the entry of the ballot in the ledger. Ballots are appended in batches ordered by their random id,
+-- so the ledger does not give away the order the ballots were cast in.
+ALTER TABLE public.ballots ADD ledger_height int8 NULL;
+
+CREATE INDEX ballots_ledger_pending_idx ON public.ballots (election_id) WHERE ledger_height IS NULL;
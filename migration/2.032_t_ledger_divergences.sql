@@ -0,0 +1,15 @@
+-- ledger_divergences records every time the ledger of a peer did not match what this node replicated or what
+-- another peer reported of it. origin_peer_id is the ledger that diverged, reported_by the peer it was seen at.
+CREATE TABLE public.ledger_divergences (
+	id int8 DEFAULT int64_id('ledger_divergences'::text, 'id'::text) NOT NULL,
+	origin_peer_id int8 NOT NULL,
+	reported_by int8 NOT NULL,
+	height int8 NOT NULL,
+	local_hash bytea NULL,
+	remote_hash bytea NULL,
+	reason varchar(255) NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT ledger_divergences_pk PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX ledger_divergences_unique ON public.ledger_divergences (origin_peer_id, reported_by, height, remote_hash);
@@ -0,0 +1,2 @@
+-- remote_id is the id this node is registered under at the peer, the node signs its calls to the peer with it
+ALTER TABLE public.peers ADD remote_id int8 NULL;
@@ -0,0 +1,6 @@
+-- receipt_hash is the SHA-256 of the receipt code handed to the voter, the code itself is not stored.
+-- salt keeps the commitment to the ballot in the ledger from being guessed from the few possible choices.
+ALTER TABLE public.ballots ADD receipt_hash bytea NULL;
+ALTER TABLE public.ballots ADD salt bytea NULL;
+
+CREATE UNIQUE INDEX ballots_receipt_hash_unique ON public.ballots (receipt_hash);
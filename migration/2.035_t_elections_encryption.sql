@@ -0,0 +1,4 @@
+-- public_key is the ElGamal key the ballots of the election are encrypted under, NULL for a plaintext election.
+-- Its private key only exists as the shares of the trustees, trustee_threshold of them decrypt the tally together.
+ALTER TABLE public.elections ADD public_key bytea NULL;
+ALTER TABLE public.elections ADD trustee_threshold int4 DEFAULT 0 NOT NULL;
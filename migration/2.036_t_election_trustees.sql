@@ -0,0 +1,16 @@
+-- election_trustees hold a share of the key of an encrypted election. The share itself is handed out once,
+-- only its verification key g^share is stored to check the decryption shares of the trustee.
+CREATE TABLE public.election_trustees (
+	election_id int8 NOT NULL,
+	trustee_index int4 NOT NULL,
+	user_id int8 NOT NULL,
+	verification_key bytea NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT election_trustees_pk PRIMARY KEY (election_id, trustee_index),
+	CONSTRAINT election_trustees_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT election_trustees_user_fk FOREIGN KEY (user_id) REFERENCES public.users(id),
+	CONSTRAINT election_trustees_index_check CHECK (trustee_index > 0)
+);
+
+CREATE UNIQUE INDEX election_trustees_user_unique ON public.election_trustees (election_id, user_id);
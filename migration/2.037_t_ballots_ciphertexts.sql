@@ -0,0 +1,6 @@
+-- ciphertexts hold the encrypted ballot of an encrypted election, the A and B of the ciphertext of every candidate
+-- in ballot order. The choices of such a ballot stay empty.
+ALTER TABLE public.ballots ADD ciphertexts bytea[] NULL;
+
+ALTER TABLE public.ballots DROP CONSTRAINT ballots_choices_check;
+ALTER TABLE public.ballots ADD CONSTRAINT ballots_choices_check CHECK (cardinality(choices) > 0 OR COALESCE(cardinality(ciphertexts), 0) > 0);
@@ -0,0 +1,12 @@
+-- election_decryptions hold the decryption share of a trustee for the encrypted tally of every candidate in ballot
+-- order, with the Chaum-Pedersen proof of each. A trustee decrypts once, after the election is closed.
+CREATE TABLE public.election_decryptions (
+	election_id int8 NOT NULL,
+	trustee_index int4 NOT NULL,
+	factors bytea[] NOT NULL,
+	challenges bytea[] NOT NULL,
+	responses bytea[] NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT election_decryptions_pk PRIMARY KEY (election_id, trustee_index),
+	CONSTRAINT election_decryptions_trustee_fk FOREIGN KEY (election_id, trustee_index) REFERENCES public.election_trustees(election_id, trustee_index)
+);
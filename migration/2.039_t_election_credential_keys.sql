@@ -0,0 +1,9 @@
+-- election_credential_keys hold the RSA key that blindly signs the voting credentials of an election.
+-- The key is created on the first request for it and is only used for the credentials of that election.
+CREATE TABLE public.election_credential_keys (
+	election_id int8 NOT NULL,
+	private_key bytea NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT election_credential_keys_pk PRIMARY KEY (election_id),
+	CONSTRAINT election_credential_keys_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id)
+);
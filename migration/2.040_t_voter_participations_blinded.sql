@@ -0,0 +1,3 @@
+-- A voter now takes part by getting the voting credential signed. blinded is the message the voter had signed,
+-- so a lost response can be requested again with the same message but no second credential can be issued.
+ALTER TABLE public.voter_participations ADD blinded bytea NULL;
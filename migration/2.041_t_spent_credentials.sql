@@ -0,0 +1,8 @@
+-- spent_credentials hold the SHA-256 of the tokens used to cast a ballot. The primary key makes a second ballot
+-- with the same token fail. Like ballots, it has no time and no link to the ballot or to the voter.
+CREATE TABLE public.spent_credentials (
+	election_id int8 NOT NULL,
+	token_hash bytea NOT NULL,
+	CONSTRAINT spent_credentials_pk PRIMARY KEY (election_id, token_hash),
+	CONSTRAINT spent_credentials_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id)
+);
@@ -0,0 +1,22 @@
+-- audit_logs is the hash chained log of every mutating request. payload is the JSON of the record exactly as it was
+-- hashed, hash = SHA-256(prev_hash || payload). The other columns are copies of the payload to search the log.
+CREATE TABLE public.audit_logs (
+	seq int8 NOT NULL,
+	actor_id int8 NULL,
+	"method" varchar(10) NOT NULL,
+	"path" varchar(255) NOT NULL,
+	status_code int4 NOT NULL,
+	payload bytea NOT NULL,
+	prev_hash bytea NOT NULL,
+	hash bytea NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT audit_logs_pk PRIMARY KEY (seq),
+	CONSTRAINT audit_logs_seq_check CHECK (seq > 0)
+);
+
+CREATE INDEX audit_logs_actor_idx ON public.audit_logs (actor_id);
+
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON public.audit_logs
+	FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON public.audit_logs
+	FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
@@ -0,0 +1,16 @@
+-- rla_audits hold the risk-limiting audit of the paper-backed results of an election, one per election.
+-- seed is the public random seed of the seed ceremony, every draw of the audit can be repeated from it.
+CREATE TABLE public.rla_audits (
+	election_id int8 NOT NULL,
+	audit_type varchar(16) NOT NULL,
+	risk_limit float8 NOT NULL,
+	max_draws int4 NOT NULL,
+	seed varchar(100) NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT rla_audits_pk PRIMARY KEY (election_id),
+	CONSTRAINT rla_audits_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT rla_audits_type_check CHECK (audit_type IN ('polling', 'comparison')),
+	CONSTRAINT rla_audits_risk_limit_check CHECK (risk_limit > 0 AND risk_limit < 1),
+	CONSTRAINT rla_audits_max_draws_check CHECK (max_draws > 0)
+);
@@ -0,0 +1,14 @@
+-- rla_draws hold every draw of a risk-limiting audit in draw order. A polling audit draws a ballot, the ballot at
+-- ballot_position of the TPS batch. A comparison audit draws the whole batch, ballot_position is 0 then.
+-- Draws are with replacement, the same ballot or batch can be drawn again.
+CREATE TABLE public.rla_draws (
+	election_id int8 NOT NULL,
+	draw_index int4 NOT NULL,
+	polling_station_id int8 NOT NULL,
+	ballot_position int4 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	CONSTRAINT rla_draws_pk PRIMARY KEY (election_id, draw_index),
+	CONSTRAINT rla_draws_audit_fk FOREIGN KEY (election_id) REFERENCES public.rla_audits(election_id),
+	CONSTRAINT rla_draws_polling_station_fk FOREIGN KEY (polling_station_id) REFERENCES public.polling_stations(id),
+	CONSTRAINT rla_draws_index_check CHECK (draw_index > 0)
+);
@@ -0,0 +1,26 @@
+-- rla_interpretations hold what the auditors read from a drawn ballot, or counted by hand in a drawn batch.
+-- An interpretation is recorded once and counts for every draw of the same ballot or batch.
+CREATE TABLE public.rla_interpretations (
+	election_id int8 NOT NULL,
+	polling_station_id int8 NOT NULL,
+	ballot_position int4 NOT NULL,
+	invalid_votes int4 DEFAULT 0 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT rla_interpretations_pk PRIMARY KEY (election_id, polling_station_id, ballot_position),
+	CONSTRAINT rla_interpretations_audit_fk FOREIGN KEY (election_id) REFERENCES public.rla_audits(election_id),
+	CONSTRAINT rla_interpretations_invalid_votes_check CHECK (invalid_votes >= 0)
+);
+
+CREATE TABLE public.rla_interpretation_lines (
+	election_id int8 NOT NULL,
+	polling_station_id int8 NOT NULL,
+	ballot_position int4 NOT NULL,
+	candidate_id int8 NOT NULL,
+	votes int4 NOT NULL,
+	CONSTRAINT rla_interpretation_lines_pk PRIMARY KEY (election_id, polling_station_id, ballot_position, candidate_id),
+	CONSTRAINT rla_interpretation_lines_interpretation_fk FOREIGN KEY (election_id, polling_station_id, ballot_position)
+		REFERENCES public.rla_interpretations(election_id, polling_station_id, ballot_position),
+	CONSTRAINT rla_interpretation_lines_candidate_fk FOREIGN KEY (candidate_id) REFERENCES public.candidates(id),
+	CONSTRAINT rla_interpretation_lines_votes_check CHECK (votes >= 0)
+);
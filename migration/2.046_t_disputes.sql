@@ -0,0 +1,24 @@
+-- disputes hold the objections filed by a party against the tally form of a polling station or the recapitulation of a region.
+-- Exactly one of polling_station_id and region_id is set.
+CREATE TABLE public.disputes (
+	id int8 DEFAULT int64_id('disputes'::text, 'id'::text) NOT NULL,
+	election_id int8 NOT NULL,
+	polling_station_id int8 NULL,
+	region_id int8 NULL,
+	filer varchar(255) NOT NULL,
+	reason text NOT NULL,
+	status varchar(16) DEFAULT 'filed'::character varying NOT NULL,
+	resolution text NULL,
+	filed_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	filed_by int8 NOT NULL,
+	resolved_at timestamptz NULL,
+	resolved_by int8 NULL,
+	CONSTRAINT disputes_pk PRIMARY KEY (id),
+	CONSTRAINT disputes_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id),
+	CONSTRAINT disputes_polling_station_fk FOREIGN KEY (polling_station_id) REFERENCES public.polling_stations(id),
+	CONSTRAINT disputes_region_fk FOREIGN KEY (region_id) REFERENCES public.regions(id),
+	CONSTRAINT disputes_target_check CHECK ((polling_station_id IS NULL) <> (region_id IS NULL)),
+	CONSTRAINT disputes_status_check CHECK (status IN ('filed', 'under_review', 'recount_ordered', 'resolved'))
+);
+
+CREATE INDEX disputes_election_idx ON public.disputes (election_id, filed_at);
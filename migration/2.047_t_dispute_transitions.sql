@@ -0,0 +1,14 @@
+-- dispute_transitions record every status change of a dispute with the acting user
+CREATE TABLE public.dispute_transitions (
+	id int8 DEFAULT int64_id('dispute_transitions'::text, 'id'::text) NOT NULL,
+	dispute_id int8 NOT NULL,
+	from_status varchar(16) NOT NULL,
+	to_status varchar(16) NOT NULL,
+	note text NULL,
+	user_id int8 NOT NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NOT NULL,
+	CONSTRAINT dispute_transitions_pk PRIMARY KEY (id),
+	CONSTRAINT dispute_transitions_dispute_fk FOREIGN KEY (dispute_id) REFERENCES public.disputes(id)
+);
+
+CREATE INDEX dispute_transitions_dispute_idx ON public.dispute_transitions (dispute_id, created_at);
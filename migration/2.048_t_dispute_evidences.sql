@@ -0,0 +1,16 @@
+-- dispute_evidences hold the files attached to a dispute, the file itself is kept in the storage under its sha256 checksum
+CREATE TABLE public.dispute_evidences (
+	id int8 DEFAULT int64_id('dispute_evidences'::text, 'id'::text) NOT NULL,
+	dispute_id int8 NOT NULL,
+	sha256 char(64) NOT NULL,
+	content_type varchar(64) NOT NULL,
+	"size" int8 NOT NULL,
+	filename varchar(255) NULL,
+	created_at timestamptz DEFAULT timezone('utc'::text, now()) NULL,
+	created_by int8 NOT NULL,
+	CONSTRAINT dispute_evidences_pk PRIMARY KEY (id),
+	CONSTRAINT dispute_evidences_dispute_fk FOREIGN KEY (dispute_id) REFERENCES public.disputes(id),
+	CONSTRAINT dispute_evidences_size_check CHECK ("size" > 0)
+);
+
+CREATE UNIQUE INDEX dispute_evidences_unique ON public.dispute_evidences (dispute_id, sha256);
@@ -0,0 +1,6 @@
+-- a recount ordered for a dispute reopens the tally form or recapitulation, it is then submitted and reviewed again
+ALTER TABLE public.tally_forms DROP CONSTRAINT tally_forms_status_check;
+ALTER TABLE public.tally_forms ADD CONSTRAINT tally_forms_status_check CHECK (status IN ('submitted', 'approved', 'rejected', 'reopened'));
+
+ALTER TABLE public.recapitulations DROP CONSTRAINT recapitulations_status_check;
+ALTER TABLE public.recapitulations ADD CONSTRAINT recapitulations_status_check CHECK (status IN ('submitted', 'approved', 'rejected', 'reopened'));
@@ -0,0 +1,11 @@
+-- certificates hold the signed results document of a certified election, one per election. document is the
+-- canonical JSON the certifiers signed, it is kept byte for byte so the signatures keep verifying.
+CREATE TABLE public.certificates (
+	election_id int8 NOT NULL,
+	"document" text NOT NULL,
+	sha256 char(64) NOT NULL,
+	certified_at timestamptz NOT NULL,
+	certified_by int8 NOT NULL,
+	CONSTRAINT certificates_pk PRIMARY KEY (election_id),
+	CONSTRAINT certificates_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id)
+);
@@ -0,0 +1,10 @@
+-- certificate_signatures hold the detached Ed25519 signature of every certifier key over the certificate document.
+CREATE TABLE public.certificate_signatures (
+	election_id int8 NOT NULL,
+	public_key bytea NOT NULL,
+	signature bytea NOT NULL,
+	CONSTRAINT certificate_signatures_pk PRIMARY KEY (election_id, public_key),
+	CONSTRAINT certificate_signatures_certificate_fk FOREIGN KEY (election_id) REFERENCES public.certificates(election_id),
+	CONSTRAINT certificate_signatures_public_key_check CHECK (length(public_key) = 32),
+	CONSTRAINT certificate_signatures_signature_check CHECK (length(signature) = 64)
+);
@@ -0,0 +1,12 @@
+-- opens_at and closes_at are the voting window of the election. The scheduler opens a scheduled election at opens_at
+-- and closes an open election at closes_at, NULL leaves the transition to a user. timezone is the Indonesian time zone
+-- the window was set in (WIB, WITA or WIT) and is shown in.
+ALTER TABLE public.elections ADD opens_at timestamptz NULL;
+ALTER TABLE public.elections ADD closes_at timestamptz NULL;
+ALTER TABLE public.elections ADD timezone varchar(4) DEFAULT 'WIB'::character varying NOT NULL;
+
+ALTER TABLE public.elections ADD CONSTRAINT elections_timezone_check CHECK (timezone IN ('WIB', 'WITA', 'WIT'));
+ALTER TABLE public.elections ADD CONSTRAINT elections_window_check CHECK (opens_at IS NULL OR closes_at IS NULL OR closes_at > opens_at);
+
+CREATE INDEX elections_opens_at_idx ON public.elections (opens_at) WHERE status = 'scheduled' AND deleted_at IS NULL;
+CREATE INDEX elections_closes_at_idx ON public.elections (closes_at) WHERE status = 'open' AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- user_id is NULL for the transitions made by the scheduler
+ALTER TABLE public.election_transitions ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,3 @@
+-- is_superuser marks the roles that can not lose their last active user, so the role and access management
+-- can not lock every user out.
+ALTER TABLE public.roles ADD is_superuser bool DEFAULT false NOT NULL;
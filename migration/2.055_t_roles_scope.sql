@@ -0,0 +1,8 @@
+-- election_id and region_id scope the access of a role to the routes of one election and to a region with the regions
+-- below it, like a regency officer managing the tally forms of their regency. NULL grants every election or region.
+ALTER TABLE public.roles ADD election_id int8 NULL;
+ALTER TABLE public.roles ADD region_id int8 NULL;
+
+ALTER TABLE public.roles ADD CONSTRAINT roles_election_fk FOREIGN KEY (election_id) REFERENCES public.elections(id);
+ALTER TABLE public.roles ADD CONSTRAINT roles_region_fk FOREIGN KEY (region_id) REFERENCES public.regions(id);
+ALTER TABLE public.roles ADD CONSTRAINT roles_superuser_scope_check CHECK (NOT is_superuser OR (election_id IS NULL AND region_id IS NULL));
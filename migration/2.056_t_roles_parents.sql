@@ -0,0 +1,11 @@
+-- roles_parents lets a role inherit the access of its parent roles, like a provincial admin inheriting the access of
+-- a regency admin. The inherited access keeps the scope of the role the user holds. Cycles are refused on write.
+CREATE TABLE public.roles_parents (
+	role_id int8 NOT NULL,
+	parent_id int8 NOT NULL,
+	CONSTRAINT roles_parents_pk PRIMARY KEY (role_id, parent_id),
+	CONSTRAINT roles_parents_role_fk FOREIGN KEY (role_id) REFERENCES public.roles(id),
+	CONSTRAINT roles_parents_parent_fk FOREIGN KEY (parent_id) REFERENCES public.roles(id),
+	CONSTRAINT roles_parents_self_check CHECK (role_id <> parent_id)
+);
+CREATE INDEX roles_parents_parent_idx ON public.roles_parents (parent_id);
@@ -0,0 +1,4 @@
+-- The key share of a trustee waits here until the trustee fetches it, then it is erased. Every trustee only
+-- receives their own share, so no single user ever holds enough shares to decrypt the tally alone.
+ALTER TABLE public.election_trustees ADD share bytea NULL;
+ALTER TABLE public.election_trustees ADD share_fetched_at timestamptz NULL;
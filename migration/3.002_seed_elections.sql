@@ -0,0 +1,17 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (708825631291534,'list election','GET /elections'),
+	 (134748087117233,'create election','POST /elections'),
+	 (792136840703408,'view election','GET /elections/:id'),
+	 (793747167321819,'update election','PUT /elections/:id'),
+	 (459204311087122,'delete election','DELETE /elections/:id'),
+	 (972015985893631,'transition election','POST /elections/:id/transitions'),
+	 (846935894554229,'list election transitions','GET /elections/:id/transitions');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (708825631291534,156677038157782),
+	 (134748087117233,156677038157782),
+	 (792136840703408,156677038157782),
+	 (793747167321819,156677038157782),
+	 (459204311087122,156677038157782),
+	 (972015985893631,156677038157782),
+	 (846935894554229,156677038157782);
@@ -0,0 +1,13 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (622278702689472,'list candidate','GET /elections/:id/candidates'),
+	 (647705268906660,'create candidate','POST /elections/:id/candidates'),
+	 (763964387452715,'view candidate','GET /elections/:id/candidates/:candidate_id'),
+	 (401068865354488,'update candidate','PUT /elections/:id/candidates/:candidate_id'),
+	 (688320844528353,'delete candidate','DELETE /elections/:id/candidates/:candidate_id');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (622278702689472,156677038157782),
+	 (647705268906660,156677038157782),
+	 (763964387452715,156677038157782),
+	 (401068865354488,156677038157782),
+	 (688320844528353,156677038157782);
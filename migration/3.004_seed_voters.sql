@@ -0,0 +1,23 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (783084759735348,'list voter','GET /voters'),
+	 (168164933108073,'create voter','POST /voters'),
+	 (950647696551828,'view voter','GET /voters/:id'),
+	 (342975489218409,'update voter','PUT /voters/:id'),
+	 (291691084925902,'delete voter','DELETE /voters/:id'),
+	 (733936569166159,'list voter possible duplicates','GET /voters/:id/duplicates'),
+	 (785302052307665,'list election voter','GET /elections/:id/voters'),
+	 (743203778388412,'register election voter','POST /elections/:id/voters'),
+	 (401761911736568,'unregister election voter','DELETE /elections/:id/voters/:voter_id'),
+	 (355467837752631,'view voter personal data','PII /voters');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (783084759735348,156677038157782),
+	 (168164933108073,156677038157782),
+	 (950647696551828,156677038157782),
+	 (342975489218409,156677038157782),
+	 (291691084925902,156677038157782),
+	 (733936569166159,156677038157782),
+	 (785302052307665,156677038157782),
+	 (743203778388412,156677038157782),
+	 (401761911736568,156677038157782),
+	 (355467837752631,156677038157782);
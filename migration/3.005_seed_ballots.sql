@@ -0,0 +1,8 @@
+INSERT INTO public.roles (id,"name") VALUES(202158176166790,'Voter');
+
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (111545283869178,'cast ballot','POST /elections/:id/ballots');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (111545283869178,156677038157782),
+	 (111545283869178,202158176166790);
@@ -0,0 +1,5 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (423537112406448,'view election results','GET /elections/:id/results');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (423537112406448,156677038157782);
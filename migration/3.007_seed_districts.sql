@@ -0,0 +1,15 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (930432005224422,'list districts','GET /elections/:id/districts'),
+	 (980547642271413,'add district','POST /elections/:id/districts'),
+	 (789160131767609,'update district','PUT /elections/:id/districts/:district_id'),
+	 (119401318608300,'delete district','DELETE /elections/:id/districts/:district_id'),
+	 (693618249434122,'enter district party votes','PUT /elections/:id/districts/:district_id/votes'),
+	 (613026143840767,'view seat allocation','GET /elections/:id/seats');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (930432005224422,156677038157782),
+	 (980547642271413,156677038157782),
+	 (789160131767609,156677038157782),
+	 (119401318608300,156677038157782),
+	 (693618249434122,156677038157782),
+	 (613026143840767,156677038157782);
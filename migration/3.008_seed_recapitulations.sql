@@ -0,0 +1,39 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (477029239385992,'list regions','GET /regions'),
+	 (294742546385427,'view region','GET /regions/:id'),
+	 (287073536848679,'create region','POST /regions'),
+	 (701293191435917,'update region','PUT /regions/:id'),
+	 (467658837715190,'delete region','DELETE /regions/:id'),
+	 (120725313626333,'list polling stations','GET /polling-stations'),
+	 (407523597226119,'view polling station','GET /polling-stations/:id'),
+	 (720801805406441,'create polling station','POST /polling-stations'),
+	 (311937822106912,'update polling station','PUT /polling-stations/:id'),
+	 (577991165453542,'delete polling station','DELETE /polling-stations/:id'),
+	 (423528228964576,'list tally forms','GET /elections/:id/tally-forms'),
+	 (502930017222813,'view tally form','GET /elections/:id/tally-forms/:polling_station_id'),
+	 (869792532506072,'submit tally form','PUT /elections/:id/tally-forms/:polling_station_id'),
+	 (504481087456244,'review tally form','PUT /elections/:id/tally-forms/:polling_station_id/status'),
+	 (117433066166444,'list recapitulations','GET /elections/:id/recapitulations'),
+	 (941052637174052,'view recapitulation','GET /elections/:id/recapitulations/:region_id'),
+	 (206378424694095,'submit recapitulation','PUT /elections/:id/recapitulations/:region_id'),
+	 (855755112709475,'review recapitulation','PUT /elections/:id/recapitulations/:region_id/status');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (477029239385992,156677038157782),
+	 (294742546385427,156677038157782),
+	 (287073536848679,156677038157782),
+	 (701293191435917,156677038157782),
+	 (467658837715190,156677038157782),
+	 (120725313626333,156677038157782),
+	 (407523597226119,156677038157782),
+	 (720801805406441,156677038157782),
+	 (311937822106912,156677038157782),
+	 (577991165453542,156677038157782),
+	 (423528228964576,156677038157782),
+	 (502930017222813,156677038157782),
+	 (869792532506072,156677038157782),
+	 (504481087456244,156677038157782),
+	 (117433066166444,156677038157782),
+	 (941052637174052,156677038157782),
+	 (206378424694095,156677038157782),
+	 (855755112709475,156677038157782);
@@ -0,0 +1,9 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (230090632097981,'list tally form scans','GET /elections/:id/tally-forms/:polling_station_id/scans'),
+	 (842053923874193,'upload tally form scan','POST /elections/:id/tally-forms/:polling_station_id/scans'),
+	 (485510253227763,'download tally form scan','GET /elections/:id/tally-forms/:polling_station_id/scans/:sha256');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (230090632097981,156677038157782),
+	 (842053923874193,156677038157782),
+	 (485510253227763,156677038157782);
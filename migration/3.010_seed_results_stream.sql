@@ -0,0 +1,5 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (483335829225794,'stream election results','GET /elections/:id/results/stream');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (483335829225794,156677038157782);
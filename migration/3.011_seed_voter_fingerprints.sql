@@ -0,0 +1,13 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (411523541578852,'get voter fingerprint','GET /voters/:id/fingerprint'),
+	 (958957455510852,'enroll voter fingerprint','PUT /voters/:id/fingerprint'),
+	 (214322714443509,'delete voter fingerprint','DELETE /voters/:id/fingerprint'),
+	 (553073835255223,'verify voter fingerprint','POST /voters/:id/fingerprint/verify'),
+	 (494877491125375,'identify fingerprint','POST /fingerprints/identify');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (411523541578852,156677038157782),
+	 (958957455510852,156677038157782),
+	 (214322714443509,156677038157782),
+	 (553073835255223,156677038157782),
+	 (494877491125375,156677038157782);
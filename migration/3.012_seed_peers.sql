@@ -0,0 +1,13 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (684497155850548,'list peers','GET /peers'),
+	 (968240795767519,'get peer','GET /peers/:id'),
+	 (680689935391816,'register peer','POST /peers'),
+	 (676050678206345,'update peer','PUT /peers/:id'),
+	 (271564237439836,'change peer status','PUT /peers/:id/status');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (684497155850548,156677038157782),
+	 (968240795767519,156677038157782),
+	 (680689935391816,156677038157782),
+	 (676050678206345,156677038157782),
+	 (271564237439836,156677038157782);
@@ -0,0 +1,7 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (928819607195713,'sync ledgers','POST /ledger/sync'),
+	 (726382791333170,'list ledger divergences','GET /ledger/divergences');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (928819607195713,156677038157782),
+	 (726382791333170,156677038157782);
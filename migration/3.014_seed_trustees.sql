@@ -0,0 +1,11 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (706178407715449,'setup election trustees','POST /elections/:id/trustees'),
+	 (778042350753823,'list election trustees','GET /elections/:id/trustees'),
+	 (917872624060265,'get encrypted tally','GET /elections/:id/encrypted-tally'),
+	 (391614419604320,'submit tally decryption','POST /elections/:id/decryptions');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (706178407715449,156677038157782),
+	 (778042350753823,156677038157782),
+	 (917872624060265,156677038157782),
+	 (391614419604320,156677038157782);
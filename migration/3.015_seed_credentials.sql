@@ -0,0 +1,5 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (583027164930851,'issue voting credential','POST /elections/:id/credentials');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (583027164930851,156677038157782);
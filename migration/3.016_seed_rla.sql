@@ -0,0 +1,9 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (264915830772046,'start risk-limiting audit','POST /elections/:id/rla'),
+	 (839206471158523,'draw risk-limiting audit sample','POST /elections/:id/rla/draws'),
+	 (517384029663181,'record risk-limiting audit interpretation','POST /elections/:id/rla/interpretations');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (264915830772046,156677038157782),
+	 (839206471158523,156677038157782),
+	 (517384029663181,156677038157782);
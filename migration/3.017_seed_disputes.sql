@@ -0,0 +1,15 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (381640927515302,'file dispute','POST /elections/:id/disputes'),
+	 (706218453390874,'list disputes','GET /elections/:id/disputes'),
+	 (459372810664127,'get dispute','GET /elections/:id/disputes/:dispute_id'),
+	 (912845306718259,'move dispute','POST /elections/:id/disputes/:dispute_id/transitions'),
+	 (237561094882413,'upload dispute evidence','POST /elections/:id/disputes/:dispute_id/evidences'),
+	 (648093275120956,'download dispute evidence','GET /elections/:id/disputes/:dispute_id/evidences/:sha256');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (381640927515302,156677038157782),
+	 (706218453390874,156677038157782),
+	 (459372810664127,156677038157782),
+	 (912845306718259,156677038157782),
+	 (237561094882413,156677038157782),
+	 (648093275120956,156677038157782);
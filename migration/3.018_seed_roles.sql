@@ -0,0 +1,37 @@
+UPDATE public.roles SET is_superuser = true WHERE id = 156677038157782;
+
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (573920184627310,'list roles','GET /roles'),
+	 (829104736251847,'create role','POST /roles'),
+	 (164839275019362,'view role','GET /roles/:id'),
+	 (947265103826475,'update role','PUT /roles/:id'),
+	 (302817465930128,'delete role','DELETE /roles/:id'),
+	 (618394027561839,'list role access','GET /roles/:id/access'),
+	 (485029371640582,'grant role access','POST /roles/:id/access'),
+	 (790162538407291,'revoke role access','DELETE /roles/:id/access/:access_id'),
+	 (236801947352619,'list access','GET /access'),
+	 (914375028163740,'create access','POST /access'),
+	 (357920816430271,'view access','GET /access/:id'),
+	 (681043927516308,'update access','PUT /access/:id'),
+	 (129573604819237,'delete access','DELETE /access/:id'),
+	 (846210395731652,'list user roles','GET /users/:id/roles'),
+	 (503782916045187,'assign user role','POST /users/:id/roles'),
+	 (271649038527914,'unassign user role','DELETE /users/:id/roles/:role_id');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (573920184627310,156677038157782),
+	 (829104736251847,156677038157782),
+	 (164839275019362,156677038157782),
+	 (947265103826475,156677038157782),
+	 (302817465930128,156677038157782),
+	 (618394027561839,156677038157782),
+	 (485029371640582,156677038157782),
+	 (790162538407291,156677038157782),
+	 (236801947352619,156677038157782),
+	 (914375028163740,156677038157782),
+	 (357920816430271,156677038157782),
+	 (681043927516308,156677038157782),
+	 (129573604819237,156677038157782),
+	 (846210395731652,156677038157782),
+	 (503782916045187,156677038157782),
+	 (271649038527914,156677038157782);
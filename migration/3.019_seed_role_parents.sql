@@ -0,0 +1,11 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (417305928164273,'list role parents','GET /roles/:id/parents'),
+	 (862941037518426,'add role parent','POST /roles/:id/parents'),
+	 (395068172430915,'remove role parent','DELETE /roles/:id/parents/:parent_id'),
+	 (728416530937102,'explain user access','GET /users/:id/access/explain');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (417305928164273,156677038157782),
+	 (862941037518426,156677038157782),
+	 (395068172430915,156677038157782),
+	 (728416530937102,156677038157782);
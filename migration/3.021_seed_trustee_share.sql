@@ -0,0 +1,5 @@
+INSERT INTO public."access" (id,"name","path") VALUES
+	 (166024574085984,'fetch trustee share','POST /elections/:id/trustees/share');
+
+INSERT INTO public.access_roles (access_id,role_id) VALUES
+	 (166024574085984,156677038157782);
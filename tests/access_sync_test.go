@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"backend-election/internal/model"
+	"backend-election/internal/repository"
+	"backend-election/internal/route"
+	"backend-election/internal/usecase"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAccessSync(t *testing.T) {
+	ctx := context.Background()
+	accessUC := usecase.AccessUC{Log: log, DB: db, Cache: cache}
+	contains := func(list []model.Access, path string) bool {
+		for _, access := range list {
+			if access.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the seeded access already matches the routes, the access of casting a ballot is dropped since it is public
+	sync, _, err := accessUC.Sync(ctx, route.Access())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sync.Added) != 0 || len(sync.Renamed) != 0 {
+		t.Errorf("seeded access does not match the routes: %+v", sync)
+	}
+	if contains(sync.Orphaned, "POST /elections/:id/ballots") || contains(sync.Orphaned, "GET /users") {
+		t.Errorf("unexpected orphaned access %+v", sync.Orphaned)
+	}
+
+	// a new route is added and granted to the superuser role, a removed route is reported
+	path := fmt.Sprintf("GET /sync/%d", time.Now().UnixNano())
+	declared := append(route.Access(), model.Access{Name: "sync " + path, Path: path})
+	sync, _, err = accessUC.Sync(ctx, declared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sync.Added) != 1 || sync.Added[0].Path != path {
+		t.Fatalf("unexpected added access %+v", sync.Added)
+	}
+	defer func() {
+		accessRepo := repository.AccessRepository{Log: log, Db: db, AccessEntity: model.Access{ID: sync.Added[0].ID}}
+		accessRepo.Delete(ctx)
+	}()
+
+	authUC := usecase.AuthUC{Log: log, DB: db, Cache: cache}
+	if hasAuth, err := authUC.HasAuth(ctx, 425071490427828, path); err != nil || !hasAuth {
+		t.Errorf("the superuser is not granted the added access: %v", err)
+	}
+
+	declared[len(declared)-1].Name = "renamed " + path
+	if sync, _, err = accessUC.Sync(ctx, declared); err != nil || len(sync.Renamed) != 1 {
+		t.Errorf("renamed access is not synced: %+v %v", sync.Renamed, err)
+	}
+
+	if sync, _, err = accessUC.Sync(ctx, route.Access()); err != nil || !contains(sync.Orphaned, path) {
+		t.Errorf("removed route is not reported: %+v %v", sync.Orphaned, err)
+	}
+}
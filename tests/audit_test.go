@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/audit"
+	"backend-election/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAuditLog(t *testing.T) {
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.Create))
+	router.PUT("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.Update))
+	router.GET("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.GetById))
+
+	// the requests come from a fixed address, so the audit record can be checked for it
+	call := newCaller(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = "10.1.2.3:51234"
+		router.ServeHTTP(w, r)
+	}))
+
+	auditRepo := repository.AuditRepository{Db: db, Log: log}
+	records := func(from int64) []audit.Record {
+		entries, err := auditRepo.Entries(context.Background(), from, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list []audit.Record
+		for _, entry := range entries {
+			var record audit.Record
+			if err := json.Unmarshal(entry.Payload, &record); err != nil {
+				t.Fatal(err)
+			}
+			list = append(list, record)
+		}
+		return list
+	}
+	var head int64
+	for _, record := range records(1) {
+		head = record.Seq
+	}
+
+	var user dto.UserResponse
+	email := fmt.Sprintf("audit.%d@sukamaju.example", time.Now().UnixNano())
+	call("POST", "/users", dto.UserCreateRequest{Name: "Asep", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &user)
+	call("PUT", fmt.Sprintf("/users/%d", user.ID), dto.UserUpdateRequest{ID: user.ID, Name: "Asep Sunandar"}, http.StatusOK, nil)
+	// reads are not audited
+	call("GET", fmt.Sprintf("/users/%d", user.ID), nil, http.StatusOK, nil)
+
+	list := records(head + 1)
+	if len(list) != 2 {
+		t.Fatalf("got %d audit records, want 2", len(list))
+	}
+	created, updated := list[0], list[1]
+	if created.ActorID != 425071490427828 || created.Method != "POST" || created.Path != "/users" || created.StatusCode != http.StatusCreated || created.ClientIP != "10.1.2.3" {
+		t.Errorf("unexpected record of the created user %+v", created)
+	}
+	if created.Before != nil || !strings.Contains(string(created.After), email) || strings.Contains(string(created.After), "assword") {
+		t.Errorf("unexpected snapshots of the created user %s -> %s", created.Before, created.After)
+	}
+	if updated.Path != "/users/:id" || updated.URL != fmt.Sprintf("/users/%d", user.ID) ||
+		!strings.Contains(string(updated.Before), `"Asep"`) || !strings.Contains(string(updated.After), `"Asep Sunandar"`) {
+		t.Errorf("unexpected record of the updated user %+v", updated)
+	}
+
+	// the whole chain verifies and can not be rewritten
+	verifier := audit.NewVerifier()
+	entries, err := auditRepo.Entries(context.Background(), 1, int(head)+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := verifier.Check(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE audit_logs SET payload = $1 WHERE seq = $2`, []byte(`{}`), updated.Seq); err == nil {
+		t.Error("an audit record was rewritten")
+	}
+}
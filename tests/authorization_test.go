@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/jwttoken"
+	"backend-election/internal/usecase"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAuthorization(t *testing.T) {
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.Create))
+	router.GET("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.GetById))
+
+	var user dto.UserResponse
+	email := fmt.Sprintf("authorization.%d@sukamaju.example", time.Now().UnixNano())
+	req, err := newAuthenticatedRequest("POST", "/users", dto.UserCreateRequest{Name: "Ujang", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create user returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &user); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+
+	userToken, err := jwttoken.ClaimToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(bearer string, statusCode int) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/users/%d", user.ID), nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("GET /users/%d returned wrong status code: got %v want %v: %s", user.ID, rr.Code, statusCode, rr.Body.String())
+		}
+	}
+
+	// the seeded user holds the Superman role, the new user holds no role yet
+	get(token, http.StatusOK)
+	get("", http.StatusUnauthorized)
+	get(userToken, http.StatusForbidden)
+
+	// the denial is cached until the permissions of the user are invalidated
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `INSERT INTO roles_users (user_id, role_id) VALUES ($1, 156677038157782)`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	defer db.ExecContext(ctx, `DELETE FROM roles_users WHERE user_id = $1`, user.ID)
+	get(userToken, http.StatusForbidden)
+
+	authUC := usecase.AuthUC{Log: log, DB: db, Cache: cache}
+	if err := authUC.InvalidatePermissions(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	get(userToken, http.StatusOK)
+
+	// grants read before an invalidation are not cached over it
+	key := fmt.Sprintf("%s%d", usecase.PermissionsPrefix, user.ID)
+	generations, err := cache.Counters(ctx, fmt.Sprintf("permissions-generation.%d", user.ID), "permissions-generation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authUC.InvalidatePermissions(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := cache.SetMembersIf(ctx, key, []string{"GET /users/:id"}, time.Minute, generations); err != nil || stored {
+		t.Fatalf("cached stale grants: stored %v, err %v", stored, err)
+	}
+	if cache.Exists(ctx, key) {
+		t.Fatal("stale grants are cached")
+	}
+}
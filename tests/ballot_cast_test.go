@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/blindsig"
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// newAnonymousRequest creates a request without an authenticated user, as a ballot is cast
+func newAnonymousRequest(method string, url string, data interface{}) (*http.Request, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal data: %v", err)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(dataJSON))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", uuid.NewString())
+	return req, nil
+}
+
+// credentialKey reads the credential key of the election
+func credentialKey(t *testing.T, router http.Handler, electionID int64) *rsa.PublicKey {
+	req, err := newAnonymousRequest("GET", fmt.Sprintf("/elections/%d/credential-key", electionID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("credential key returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var key dto.CredentialKeyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &key); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	der, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey.(*rsa.PublicKey)
+}
+
+// newCredential gets a credential of the election signed for the authenticated seed user, as the app of a voter does
+func newCredential(t *testing.T, router http.Handler, electionID int64) dto.BallotCredential {
+	publicKey := credentialKey(t, router, electionID)
+	token, err := blindsig.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blinded, unblinder, err := blindsig.Blind(publicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blindSignature, statusCode := issueCredential(t, router, electionID, blinded)
+	if statusCode != http.StatusCreated {
+		t.Fatalf("credential returned wrong status code: got %v want %v", statusCode, http.StatusCreated)
+	}
+	signature, err := blindsig.Unblind(publicKey, token, blindSignature, unblinder)
+	if err != nil {
+		t.Fatalf("signature of the credential does not verify: %v", err)
+	}
+
+	var credential dto.BallotCredential
+	credential.FromEntity(token, signature)
+	return credential
+}
+
+func issueCredential(t *testing.T, router http.Handler, electionID int64, blinded []byte) ([]byte, int) {
+	req, err := newAuthenticatedRequest("POST", fmt.Sprintf("/elections/%d/credentials", electionID), dto.CredentialRequest{Blinded: hex.EncodeToString(blinded)})
+	if err != nil {
+		t.Error(err)
+		return nil, 0
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		return nil, rr.Code
+	}
+
+	var issued dto.CredentialResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &issued); err != nil {
+		t.Errorf("could not unmarshal response: %v", err)
+	}
+	blindSignature, _ := hex.DecodeString(issued.BlindSignature)
+	return blindSignature, rr.Code
+}
+
+func TestCastBallotOnce(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	voterHandler := handler.Voters{DB: db, Log: log, Cache: cache}
+	credentialHandler := handler.Credentials{DB: db, Log: log, Cache: cache}
+	ballotHandler := handler.Ballots{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/elections/:id/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.RegisterEligible))
+	router.POST("/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.Create))
+	router.GET("/elections/:id/credential-key", mid.WrapMiddleware(publicMiddlewares, credentialHandler.Key))
+	router.POST("/elections/:id/credentials", mid.WrapMiddleware(publicMiddlewares, credentialHandler.Issue))
+	router.POST("/elections/:id/ballots", mid.WrapMiddleware(publicMiddlewares, ballotHandler.Cast))
+
+	call := newCaller(t, router)
+	// cast sends the ballot without the authenticated user
+	cast := func(url string, data interface{}) int {
+		req, err := newAnonymousRequest("POST", url, data)
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Ketua RW 05"}, http.StatusCreated, &election)
+
+	var candidate dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Ahmad"}, http.StatusCreated, &candidate)
+
+	// the voter is linked to the authenticated seed user
+	var voter dto.VoterResponse
+	call("POST", "/voters", dto.VoterCreateRequest{NIK: "3174015203850003", Name: "Dewi Lestari", BirthDate: "1985-03-12", UserID: 425071490427828}, http.StatusCreated, &voter)
+	call("POST", fmt.Sprintf("/elections/%d/voters", election.ID), dto.ElectionVoterRequest{VoterIDs: []int64{voter.ID}}, http.StatusOK, nil)
+
+	// the credential key is made when the election opens, reading it does not create one
+	call("GET", fmt.Sprintf("/elections/%d/credential-key", election.ID), nil, http.StatusConflict, nil)
+	call("GET", fmt.Sprintf("/elections/%d/credential-key", election.ID+1000000), nil, http.StatusNotFound, nil)
+	if _, statusCode := issueCredential(t, router, election.ID, []byte("not open yet")); statusCode != http.StatusConflict {
+		t.Errorf("credential of an election that is not open returned %v, want %v", statusCode, http.StatusConflict)
+	}
+
+	call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "scheduled"}, http.StatusOK, nil)
+	call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "open"}, http.StatusOK, nil)
+
+	publicKey := credentialKey(t, router, election.ID)
+	token, _ := blindsig.NewToken()
+	blinded, unblinder, err := blindsig.Blind(publicKey, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, statusCode := issueCredential(t, router, election.ID, publicKey.N.Bytes()); statusCode != http.StatusBadRequest {
+		t.Errorf("credential of a message out of range returned %v, want %v", statusCode, http.StatusBadRequest)
+	}
+
+	// the voter asks a credential for several blinded tokens at the same time, only one is signed
+	const attempts = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	statusCodes := map[int]int{}
+	messages := [][]byte{blinded}
+	unblinders := []*big.Int{unblinder}
+	tokens := [][]byte{token}
+	for i := 1; i < attempts; i++ {
+		token, _ := blindsig.NewToken()
+		blinded, unblinder, err := blindsig.Blind(publicKey, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, blinded)
+		unblinders = append(unblinders, unblinder)
+		tokens = append(tokens, token)
+	}
+	signed := -1
+	var blindSignature []byte
+	for i := range messages {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			signature, statusCode := issueCredential(t, router, election.ID, messages[i])
+
+			mu.Lock()
+			statusCodes[statusCode]++
+			if statusCode == http.StatusCreated {
+				signed, blindSignature = i, signature
+			}
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if statusCodes[http.StatusCreated] != 1 || statusCodes[http.StatusConflict] != attempts-1 {
+		t.Fatalf("concurrent credentials returned wrong status codes: got %v want 1 created and %d conflicts", statusCodes, attempts-1)
+	}
+
+	// a lost response is requested again with the same blinded token
+	again, statusCode := issueCredential(t, router, election.ID, messages[signed])
+	if statusCode != http.StatusCreated || !bytes.Equal(again, blindSignature) {
+		t.Errorf("credential requested again returned %v", statusCode)
+	}
+
+	signature, err := blindsig.Unblind(publicKey, tokens[signed], blindSignature, unblinders[signed])
+	if err != nil {
+		t.Fatalf("signature of the credential does not verify: %v", err)
+	}
+	var credential dto.BallotCredential
+	credential.FromEntity(tokens[signed], signature)
+
+	ballotURL := fmt.Sprintf("/elections/%d/ballots", election.ID)
+	var forged dto.BallotCredential
+	forged.FromEntity(tokens[(signed+1)%attempts], signature)
+	if statusCode := cast(ballotURL, dto.CastBallotRequest{Credential: forged, Choices: []int64{candidate.ID}}); statusCode != http.StatusForbidden {
+		t.Errorf("ballot with a forged credential returned %v, want %v", statusCode, http.StatusForbidden)
+	}
+	if statusCode := cast(ballotURL, dto.CastBallotRequest{Credential: credential, Choices: []int64{candidate.ID + 1}}); statusCode != http.StatusBadRequest {
+		t.Errorf("ballot for a candidate of another election returned %v, want %v", statusCode, http.StatusBadRequest)
+	}
+
+	// the credential casts one ballot, even when it is sent several times at once
+	statusCodes = map[int]int{}
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statusCode := cast(ballotURL, dto.CastBallotRequest{Credential: credential, Choices: []int64{candidate.ID}})
+
+			mu.Lock()
+			statusCodes[statusCode]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if statusCodes[http.StatusCreated] != 1 || statusCodes[http.StatusConflict] != attempts-1 {
+		t.Errorf("concurrent ballots returned wrong status codes: got %v want 1 created and %d conflicts", statusCodes, attempts-1)
+	}
+}
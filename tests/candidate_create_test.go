@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCreateCandidate(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+
+	req, err := newAuthenticatedRequest("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Kepala Desa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create election returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	var election dto.ElectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &election); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+
+	url := fmt.Sprintf("/elections/%d/candidates", election.ID)
+	scenarios := []struct {
+		Name        string
+		Data        dto.AddCandidateRequest
+		Transition  string
+		ExpectedErr string
+		StatusCode  int
+	}{
+		{
+			Name:        "Invalid Ballot Number",
+			Data:        dto.AddCandidateRequest{Name: "Budi"},
+			ExpectedErr: "Invalid input: ballot_number must be greater than 0",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:       "Valid Candidate With Running Mate",
+			Data:       dto.AddCandidateRequest{BallotNumber: 1, Name: "Budi", RunningMateName: "Siti", Party: "Partai A"},
+			StatusCode: http.StatusCreated,
+		},
+		{
+			Name:        "Duplicate Ballot Number",
+			Data:        dto.AddCandidateRequest{BallotNumber: 1, Name: "Joko"},
+			ExpectedErr: "Invalid input: ballot number is already used",
+			StatusCode:  http.StatusConflict,
+		},
+		{
+			Name:        "Locked After Draft",
+			Data:        dto.AddCandidateRequest{BallotNumber: 2, Name: "Joko"},
+			Transition:  "scheduled",
+			ExpectedErr: "Candidates are locked: election is not in draft state",
+			StatusCode:  http.StatusConflict,
+		},
+	}
+
+	// scenarios share one election and run in order
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			if len(tt.Transition) > 0 {
+				req, err := newAuthenticatedRequest("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: tt.Transition})
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+				if rr.Code != http.StatusOK {
+					t.Fatalf("transition returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+				}
+			}
+
+			req, err := newAuthenticatedRequest("POST", url, tt.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.StatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.StatusCode)
+			}
+			if rr.Code != http.StatusCreated {
+				if strings.TrimSpace(rr.Body.String()) != tt.ExpectedErr {
+					t.Errorf("handler returned wrong error message: got %v want %v", rr.Body.String(), tt.ExpectedErr)
+				}
+				return
+			}
+
+			var response dto.CandidateResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Errorf("could not unmarshal response: %v", err)
+			}
+			if response.Name != tt.Data.Name || response.RunningMateName != tt.Data.RunningMateName || response.BallotNumber != tt.Data.BallotNumber {
+				t.Errorf("handler returned wrong candidate: got %v want %v", response, tt.Data)
+			}
+		})
+	}
+}
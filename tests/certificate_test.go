@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/certificate"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCertificate(t *testing.T) {
+	var certifierKeys []ed25519.PrivateKey
+	var publicKeys []ed25519.PublicKey
+	for i := 0; i < 2; i++ {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certifierKeys = append(certifierKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache, CertifierKeys: certifierKeys}
+	unconfiguredHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	certificateHandler := handler.Certificates{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/unconfigured/:id/transitions", mid.WrapMiddleware(publicMiddlewares, unconfiguredHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.GET("/elections/:id/certificate", mid.WrapMiddleware(publicMiddlewares, certificateHandler.Get))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Kepala Desa"}, http.StatusCreated, &election)
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, nil)
+
+	transitionsURL := fmt.Sprintf("/elections/%d/transitions", election.ID)
+	certificateURL := fmt.Sprintf("/elections/%d/certificate", election.ID)
+	for _, status := range []string{"scheduled", "open", "closed", "tallied"} {
+		call("POST", transitionsURL, dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+
+	// the certificate exists once the election is certified, which needs the certifier keys
+	call("GET", certificateURL, nil, http.StatusNotFound, nil)
+	call("POST", fmt.Sprintf("/unconfigured/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "certified"}, http.StatusServiceUnavailable, nil)
+	call("POST", transitionsURL, dto.ElectionTransitionRequest{Status: "certified"}, http.StatusOK, nil)
+
+	var bundle dto.CertificateResponse
+	call("GET", certificateURL, nil, http.StatusOK, &bundle)
+	signatures, err := bundle.ToSignatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signatures) != 2 {
+		t.Fatalf("expected 2 signatures, got %d", len(signatures))
+	}
+	if err := certificate.Verify(bundle.Document, bundle.SHA256, signatures, publicKeys); err != nil {
+		t.Fatalf("certificate does not verify: %v", err)
+	}
+
+	var document dto.CertificateDocument
+	if err := json.Unmarshal(bundle.Document, &document); err != nil {
+		t.Fatal(err)
+	}
+	if document.Election.ID != election.ID || document.Results.CountingMethod != "plurality" || len(document.Results.Rounds) != 1 || document.CertifiedAt == "" {
+		t.Fatalf("unexpected certificate document: %+v", document)
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/storage"
+	"backend-election/internal/pkg/stream"
+	"backend-election/internal/usecase"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestDispute(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+	disputeHandler := handler.Disputes{DB: db, Log: log, Cache: cache, Storage: &storage.Local{Dir: t.TempDir()}}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.GET("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.GetTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.SubmitTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.ReviewTallyForm))
+	router.POST("/elections/:id/disputes", mid.WrapMiddleware(publicMiddlewares, disputeHandler.Create))
+	router.GET("/elections/:id/disputes", mid.WrapMiddleware(publicMiddlewares, disputeHandler.List))
+	router.GET("/elections/:id/disputes/:dispute_id", mid.WrapMiddleware(publicMiddlewares, disputeHandler.Get))
+	router.POST("/elections/:id/disputes/:dispute_id/transitions", mid.WrapMiddleware(publicMiddlewares, disputeHandler.Transition))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Bupati"}, http.StatusCreated, &election)
+	var candidate dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, &candidate)
+
+	var parentID int64
+	for _, level := range []string{"national", "province", "regency", "subdistrict", "village"} {
+		var region dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.d%s", election.ID%1000000, level[:3]), Name: level}
+		call("POST", "/regions", request, http.StatusCreated, &region)
+		parentID = region.ID
+	}
+	var tps dto.PollingStationResponse
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: parentID, Number: "001"}, http.StatusCreated, &tps)
+
+	disputesURL := fmt.Sprintf("/elections/%d/disputes", election.ID)
+	formURL := fmt.Sprintf("/elections/%d/tally-forms/%d", election.ID, tps.ID)
+	form := func(votes int64) dto.TallyFormRequest {
+		return dto.TallyFormRequest{Lines: []dto.TallyLineRequest{{CandidateID: candidate.ID, Votes: votes}}}
+	}
+	request := dto.DisputeRequest{PollingStationID: tps.ID, Filer: "Saksi Paslon 2", Reason: "the C1 does not match the count witnessed at the polling station"}
+
+	// disputes are filed once the votes are counted
+	call("POST", disputesURL, request, http.StatusConflict, nil)
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+	call("PUT", formURL, form(120), http.StatusOK, nil)
+	call("PUT", formURL+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+
+	call("POST", disputesURL, dto.DisputeRequest{PollingStationID: tps.ID, RegionID: parentID, Filer: "Saksi", Reason: "both"}, http.StatusBadRequest, nil)
+	var dispute dto.DisputeResponse
+	call("POST", disputesURL, request, http.StatusCreated, &dispute)
+	if dispute.Status != "filed" || dispute.PollingStationID != tps.ID {
+		t.Fatalf("unexpected dispute: %+v", dispute)
+	}
+
+	transitionsURL := fmt.Sprintf("%s/%d/transitions", disputesURL, dispute.ID)
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "recount_ordered", Note: "recount"}, http.StatusConflict, nil)
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "under_review"}, http.StatusOK, nil)
+
+	hub, err := stream.NewHub(context.Background(), cache, usecase.ResultsChannelPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	updates, stop := hub.Listen(usecase.ResultsChannel(election.ID))
+	defer stop()
+
+	// ordering a recount reopens the approved tally form and takes its votes back out of the streamed tally
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "recount_ordered", Note: "recount the ballots of TPS 001"}, http.StatusOK, nil)
+	select {
+	case payload := <-updates:
+		var update dto.TallyUpdateResponse
+		json.Unmarshal([]byte(payload), &update)
+		if !update.Reopened || update.PollingStationID != tps.ID || update.VillageID != parentID || update.Lines[0].Votes != -120 {
+			t.Fatalf("unexpected update: %s", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the recount to be published")
+	}
+	var reopened dto.TallyFormResponse
+	call("GET", formURL, nil, http.StatusOK, &reopened)
+	if reopened.Status != "reopened" {
+		t.Fatalf("expected the tally form to be reopened, got %s", reopened.Status)
+	}
+
+	// the dispute is resolved once the recounted form is approved
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "resolved", Note: "recounted"}, http.StatusConflict, nil)
+	call("PUT", formURL, form(118), http.StatusOK, nil)
+	call("PUT", formURL+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "resolved", Note: "recount corrected two votes"}, http.StatusOK, nil)
+	call("POST", transitionsURL, dto.DisputeTransitionRequest{Status: "under_review"}, http.StatusConflict, nil)
+
+	var detail dto.DisputeDetailResponse
+	call("GET", fmt.Sprintf("%s/%d", disputesURL, dispute.ID), nil, http.StatusOK, &detail)
+	if detail.Status != "resolved" || detail.Resolution != "recount corrected two votes" || len(detail.Transitions) != 3 {
+		t.Fatalf("unexpected dispute detail: %+v", detail)
+	}
+
+	var disputes []dto.DisputeResponse
+	call("GET", disputesURL+"?status=resolved", nil, http.StatusOK, &disputes)
+	if len(disputes) != 1 || disputes[0].ID != dispute.ID {
+		t.Fatalf("expected the resolved dispute, got %+v", disputes)
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestElectionResult(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	resultHandler := handler.Results{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.GET("/elections/:id/results", mid.WrapMiddleware(publicMiddlewares, resultHandler.Get))
+
+	call := newCaller(t, router)
+
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Ketua OSIS", CountingMethod: "irv", Seats: 2}, http.StatusBadRequest, nil)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Ketua OSIS", CountingMethod: "irv"}, http.StatusCreated, &election)
+	if election.CountingMethod != "irv" || election.Seats != 1 {
+		t.Fatalf("election returned wrong counting method: got %s with %d seats want irv with 1 seat", election.CountingMethod, election.Seats)
+	}
+
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, nil)
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 2, Name: "Bayu"}, http.StatusCreated, nil)
+
+	resultURL := fmt.Sprintf("/elections/%d/results", election.ID)
+	call("GET", resultURL, nil, http.StatusConflict, nil)
+
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+
+	var result dto.ElectionResultResponse
+	call("GET", resultURL, nil, http.StatusOK, &result)
+	// without ballots both candidates tie and the tie is broken by ballot number
+	if result.CountingMethod != "irv" || result.Ballots != 0 || len(result.Rounds) != 2 || len(result.Elected) != 1 || result.Elected[0].Name != "Rina" {
+		t.Errorf("handler returned wrong result: got %+v", result)
+	}
+}
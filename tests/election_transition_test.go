@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/myctx"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+type ElectionTransitionScenario struct {
+	Name        string
+	Status      string
+	ExpectedErr string
+	StatusCode  int
+}
+
+func getElectionTransitionScenarios() []ElectionTransitionScenario {
+	return []ElectionTransitionScenario{
+		{
+			Name:        "Unknown Status",
+			Status:      "finished",
+			ExpectedErr: "Invalid input: status is unknown",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:        "Draft To Open",
+			Status:      "open",
+			ExpectedErr: "Invalid transition: illegal election transition from draft to open",
+			StatusCode:  http.StatusConflict,
+		},
+		{
+			Name:       "Draft To Scheduled",
+			Status:     "scheduled",
+			StatusCode: http.StatusOK,
+		},
+		{
+			Name:       "Scheduled To Open",
+			Status:     "open",
+			StatusCode: http.StatusOK,
+		},
+		{
+			Name:        "Open To Certified",
+			Status:      "certified",
+			ExpectedErr: "Invalid transition: illegal election transition from open to certified",
+			StatusCode:  http.StatusConflict,
+		},
+		{
+			Name:       "Open To Closed",
+			Status:     "closed",
+			StatusCode: http.StatusOK,
+		},
+		{
+			Name:       "Closed To Tallied",
+			Status:     "tallied",
+			StatusCode: http.StatusOK,
+		},
+		{
+			Name:       "Tallied To Certified",
+			Status:     "certified",
+			StatusCode: http.StatusOK,
+		},
+		{
+			Name:        "Certified To Draft",
+			Status:      "draft",
+			ExpectedErr: "Invalid transition: illegal election transition from certified to draft",
+			StatusCode:  http.StatusConflict,
+		},
+	}
+}
+
+func newAuthenticatedRequest(method string, url string, data interface{}) (*http.Request, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal data: %v", err)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(dataJSON))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %v", err)
+	}
+	ctx := context.WithValue(req.Context(), myctx.Key("user_id"), int64(425071490427828))
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", uuid.NewString())
+	return req, nil
+}
+
+// newCaller returns call, which sends an authenticated request to the handler and fails the test on another status
+// code than statusCode. The body of the response is decoded into response unless it is nil.
+func newCaller(t *testing.T, handler http.Handler) func(method string, url string, data interface{}, statusCode int, response interface{}) {
+	return func(method string, url string, data interface{}, statusCode int, response interface{}) {
+		t.Helper()
+		req, err := newAuthenticatedRequest(method, url, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("%s %s returned wrong status code: got %v want %v: %s", method, url, rr.Code, statusCode, rr.Body.String())
+		}
+		if response != nil {
+			if err := json.Unmarshal(rr.Body.Bytes(), response); err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+		}
+	}
+}
+
+func TestElectionTransition(t *testing.T) {
+	_, certifierKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache, CertifierKeys: []ed25519.PrivateKey{certifierKey}}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.GET("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.ListTransitions))
+
+	req, err := newAuthenticatedRequest("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Ketua RT 01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create election returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	var election dto.ElectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &election); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	if election.Status != "draft" {
+		t.Fatalf("new election has wrong status: got %v want draft", election.Status)
+	}
+
+	// transitions depend on the previous state, so the scenarios run in order
+	url := fmt.Sprintf("/elections/%d/transitions", election.ID)
+	succeeded := 0
+	for _, tt := range getElectionTransitionScenarios() {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := newAuthenticatedRequest("POST", url, dto.ElectionTransitionRequest{Status: tt.Status})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.StatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.StatusCode)
+			}
+			if rr.Code != http.StatusOK {
+				if strings.TrimSpace(rr.Body.String()) != tt.ExpectedErr {
+					t.Errorf("handler returned wrong error message: got %v want %v", rr.Body.String(), tt.ExpectedErr)
+				}
+				return
+			}
+
+			succeeded++
+			var response dto.ElectionTransitionResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Errorf("could not unmarshal response: %v", err)
+			}
+			if response.ToStatus != tt.Status || response.UserID != 425071490427828 {
+				t.Errorf("handler returned wrong transition: got %v", response)
+			}
+		})
+	}
+
+	req, err = newAuthenticatedRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var transitions []dto.ElectionTransitionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &transitions); err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	if len(transitions) != succeeded {
+		t.Errorf("wrong number of recorded transitions: got %v want %v", len(transitions), succeeded)
+	}
+}
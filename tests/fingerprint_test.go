@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/biometric"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestFingerprint(t *testing.T) {
+	engine, err := biometric.NewEngine(bytes.Repeat([]byte{1}, 32), biometric.DefaultThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	voterHandler := handler.Voters{DB: db, Log: log, Cache: cache}
+	fingerprintHandler := handler.Fingerprints{DB: db, Log: log, Cache: cache, Biometric: engine}
+
+	router := httprouter.New()
+	router.POST("/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.Create))
+	router.GET("/voters/:id/fingerprint", mid.WrapMiddleware(publicMiddlewares, fingerprintHandler.Get))
+	router.PUT("/voters/:id/fingerprint", mid.WrapMiddleware(publicMiddlewares, fingerprintHandler.Enroll))
+	router.DELETE("/voters/:id/fingerprint", mid.WrapMiddleware(publicMiddlewares, fingerprintHandler.Delete))
+	router.POST("/voters/:id/fingerprint/verify", mid.WrapMiddleware(publicMiddlewares, fingerprintHandler.Verify))
+	router.POST("/fingerprints/identify", mid.WrapMiddleware(publicMiddlewares, fingerprintHandler.Identify))
+
+	call := newCaller(t, router)
+
+	rng := rand.New(rand.NewSource(11))
+	finger := func(count int) biometric.Template {
+		template := biometric.Template{FingerPosition: 7, Quality: 70}
+		for i := 0; i < count; i++ {
+			template.Minutiae = append(template.Minutiae, biometric.Minutia{
+				X:     2 + rng.Float64()*15,
+				Y:     -2 - rng.Float64()*20,
+				Angle: rng.Float64() * 2 * math.Pi,
+				Type:  biometric.MinutiaRidgeEnding,
+			})
+		}
+		return template
+	}
+	// another reading of the same finger, moved and missing a few minutiae
+	impression := func(template biometric.Template) biometric.Template {
+		result := biometric.Template{FingerPosition: template.FingerPosition, Quality: template.Quality}
+		for i, m := range template.Minutiae {
+			if i%6 != 0 {
+				m.X, m.Y = m.X+0.7+rng.NormFloat64()*0.05, m.Y-0.4+rng.NormFloat64()*0.05
+				result.Minutiae = append(result.Minutiae, m)
+			}
+		}
+		return result
+	}
+	request := func(format biometric.Format, template biometric.Template) dto.FingerprintRequest {
+		data, err := biometric.Encode(format, template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dto.FingerprintRequest{Format: string(format), Template: base64.StdEncoding.EncodeToString(data)}
+	}
+
+	var dewi, budi dto.VoterResponse
+	call("POST", "/voters", dto.VoterCreateRequest{NIK: "3273016708920009", Name: "Dewi Sartika", BirthDate: "1992-08-27"}, http.StatusCreated, &dewi)
+	call("POST", "/voters", dto.VoterCreateRequest{NIK: "3273011505880007", Name: "Budi Hartono", BirthDate: "1988-05-15"}, http.StatusCreated, &budi)
+	dewiURL := fmt.Sprintf("/voters/%d/fingerprint", dewi.ID)
+	budiURL := fmt.Sprintf("/voters/%d/fingerprint", budi.ID)
+
+	dewiFinger, budiFinger := finger(36), finger(36)
+
+	t.Run("Enroll", func(t *testing.T) {
+		var fingerprint dto.FingerprintResponse
+		call("PUT", dewiURL, dto.FingerprintEnrollRequest{FingerprintRequest: request(biometric.FormatISO19794, dewiFinger)}, http.StatusOK, &fingerprint)
+		if fingerprint.VoterID != dewi.ID || fingerprint.Format != "iso19794-2" || fingerprint.FingerPosition != 7 || fingerprint.MinutiaeCount != 36 {
+			t.Fatalf("unexpected fingerprint %+v", fingerprint)
+		}
+		call("PUT", budiURL, dto.FingerprintEnrollRequest{FingerprintRequest: request(biometric.FormatANSI378, budiFinger)}, http.StatusOK, nil)
+
+		call("GET", dewiURL, nil, http.StatusOK, &fingerprint)
+		if fingerprint.Quality != 70 {
+			t.Errorf("unexpected fingerprint %+v", fingerprint)
+		}
+
+		call("PUT", budiURL, dto.FingerprintEnrollRequest{FingerprintRequest: request(biometric.FormatISO19794, finger(5))}, http.StatusBadRequest, nil)
+		call("PUT", budiURL, dto.FingerprintEnrollRequest{FingerprintRequest: dto.FingerprintRequest{Format: "ansi378", Template: "bm90IGEgcmVjb3Jk"}}, http.StatusBadRequest, nil)
+	})
+
+	t.Run("Duplicate Enrollment", func(t *testing.T) {
+		var duplicate dto.FingerprintDuplicateResponse
+		call("PUT", budiURL, dto.FingerprintEnrollRequest{FingerprintRequest: request(biometric.FormatANSI378, impression(dewiFinger))}, http.StatusConflict, &duplicate)
+		if len(duplicate.Matches) != 1 || duplicate.Matches[0].VoterID != dewi.ID {
+			t.Fatalf("unexpected duplicates %+v", duplicate)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		var verify dto.FingerprintVerifyResponse
+		call("POST", dewiURL+"/verify", request(biometric.FormatANSI378, impression(dewiFinger)), http.StatusOK, &verify)
+		if !verify.Matched || verify.Threshold != biometric.DefaultThreshold {
+			t.Errorf("the fingerprint of dewi was not verified: %+v", verify)
+		}
+
+		call("POST", budiURL+"/verify", request(biometric.FormatISO19794, impression(dewiFinger)), http.StatusOK, &verify)
+		if verify.Matched {
+			t.Errorf("the fingerprint of dewi was verified as budi: %+v", verify)
+		}
+	})
+
+	t.Run("Identify", func(t *testing.T) {
+		var matches []dto.FingerprintMatchResponse
+		call("POST", "/fingerprints/identify", request(biometric.FormatISO19794, impression(budiFinger)), http.StatusOK, &matches)
+		if len(matches) != 1 || matches[0].VoterID != budi.ID {
+			t.Errorf("unexpected matches %+v", matches)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		call("DELETE", budiURL, nil, http.StatusNoContent, nil)
+		call("GET", budiURL, nil, http.StatusNotFound, nil)
+		call("POST", budiURL+"/verify", request(biometric.FormatISO19794, budiFinger), http.StatusNotFound, nil)
+	})
+}
@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/ledger"
+	"backend-election/internal/pkg/peer"
+	"backend-election/internal/repository"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// standIn is a peer node served in process: its ledger is kept in memory and it only answers requests signed by the
+// node under test
+type standIn struct {
+	publicKey ed25519.PublicKey
+	store     *ledger.MemoryStore
+	replicas  []ledger.Replica
+	server    *httptest.Server
+}
+
+func newStandIn(t *testing.T, nodeKey ed25519.PublicKey) *standIn {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	s := &standIn{publicKey: publicKey, store: ledger.NewMemoryStore()}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := peer.Verify(r, body, nodeKey, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var v interface{}
+		switch r.URL.Path {
+		case "/ledger/head":
+			v, _ = s.store.Head(r.Context())
+		case "/ledger/entries":
+			from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			v, _ = s.store.Entries(r.Context(), from, limit)
+		case "/ledger/replicas":
+			v = s.replicas
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(v)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *standIn) append(count int) {
+	head, _ := s.store.Head(context.Background())
+	for i := 0; i < count; i++ {
+		s.store.Append(context.Background(), head.Height+int64(i), []byte(fmt.Sprintf(`{"ballot_id":%d}`, head.Height+int64(i)+1)))
+	}
+}
+
+func TestLedger(t *testing.T) {
+	nodePublicKey, nodeKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	voterHandler := handler.Voters{DB: db, Log: log, Cache: cache}
+	peerHandler := handler.Peers{DB: db, Log: log, Cache: cache}
+	ledgerHandler := handler.Ledgers{DB: db, Log: log, Cache: cache, Key: nodeKey}
+	peerMiddlewares := append(publicMiddlewares, mid.PeerAuthentication)
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/elections/:id/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.RegisterEligible))
+	router.POST("/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.Create))
+	router.POST("/peers", mid.WrapMiddleware(publicMiddlewares, peerHandler.Create))
+	router.PUT("/peers/:id/status", mid.WrapMiddleware(publicMiddlewares, peerHandler.SetStatus))
+	router.GET("/ledger/head", mid.WrapMiddleware(peerMiddlewares, ledgerHandler.Head))
+	router.GET("/ledger/entries", mid.WrapMiddleware(peerMiddlewares, ledgerHandler.Entries))
+	router.POST("/ledger/sync", mid.WrapMiddleware(publicMiddlewares, ledgerHandler.Sync))
+	router.GET("/ledger/divergences", mid.WrapMiddleware(publicMiddlewares, ledgerHandler.Divergences))
+
+	call := newCaller(t, router)
+
+	register := func(name string, publicKey ed25519.PublicKey, endpoint string, remoteID int64) dto.PeerResponse {
+		var p dto.PeerResponse
+		call("POST", "/peers", dto.AddPeerRequest{Name: name, Endpoint: endpoint, PublicKey: base64.StdEncoding.EncodeToString(publicKey), RemoteID: remoteID}, http.StatusCreated, &p)
+		call("PUT", fmt.Sprintf("/peers/%d/status", p.ID), dto.PeerStatusRequest{Status: "active"}, http.StatusOK, nil)
+		return p
+	}
+
+	t.Run("Ballots", func(t *testing.T) {
+		var election dto.ElectionResponse
+		call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Kepala Desa Sukamaju"}, http.StatusCreated, &election)
+		var candidate dto.CandidateResponse
+		call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Budi"}, http.StatusCreated, &candidate)
+
+		var voterIDs []int64
+		for i := 0; i < 3; i++ {
+			var voter dto.VoterResponse
+			call("POST", "/voters", dto.VoterCreateRequest{NIK: fmt.Sprintf("320201410195000%d", i+1), Name: fmt.Sprintf("Warga Sukamaju %d", i+1), BirthDate: "1995-01-01"}, http.StatusCreated, &voter)
+			voterIDs = append(voterIDs, voter.ID)
+		}
+		call("POST", fmt.Sprintf("/elections/%d/voters", election.ID), dto.ElectionVoterRequest{VoterIDs: voterIDs}, http.StatusOK, nil)
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "scheduled"}, http.StatusOK, nil)
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "open"}, http.StatusOK, nil)
+
+		ledgerRepo := repository.LedgerRepository{Db: db, Log: log, OriginPeerID: model.LedgerOriginLocal}
+		before, err := ledgerRepo.Head(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var receipts []string
+		for _, voterID := range voterIDs {
+			receipt, _ := ledger.NewReceipt()
+			receipts = append(receipts, receipt)
+			ballotRepo := repository.BallotRepository{Db: db, Log: log, BallotEntity: model.Ballot{ElectionID: election.ID, Choices: []int64{candidate.ID}, ReceiptHash: ledger.ReceiptHash(receipt)}}
+			tokenHash := sha256.Sum256([]byte(fmt.Sprintf("token of voter %d", voterID)))
+			if err := ballotRepo.Cast(context.Background(), tokenHash[:]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var pending dto.ReceiptVerificationResponse
+		call("GET", "/verify/"+receipts[0], nil, http.StatusOK, &pending)
+		if pending.Status != "pending" || pending.ElectionID != election.ID {
+			t.Errorf("receipt of a ballot waiting for its batch: %+v", pending)
+		}
+
+		// the ballots wait for a full batch, closing the election appends them
+		if head, _ := ledgerRepo.Head(context.Background()); head.Height != before.Height {
+			t.Errorf("ballots were appended before a batch was full: height %d, was %d", head.Height, before.Height)
+		}
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "closed"}, http.StatusOK, nil)
+
+		// an active peer reads the ledger of this node
+		_, readerKey, _ := ed25519.GenerateKey(rand.Reader)
+		reader := register("KPU Kabupaten Bogor", readerKey.Public().(ed25519.PublicKey), "https://bogor.example", 0)
+		remote := ledger.HTTPRemote{Endpoint: "http://node.test", Client: routerClient{router: router, id: reader.ID, key: readerKey}}
+
+		replica := ledger.NewMemoryStore()
+		head, err := ledger.Replicate(context.Background(), replica, remote, 2)
+		if err != nil {
+			t.Fatalf("the ledger of this node does not replicate: %v", err)
+		}
+		if head.Height != before.Height+3 {
+			t.Fatalf("ledger height %d, want %d", head.Height, before.Height+3)
+		}
+
+		entries, _ := replica.Entries(context.Background(), before.Height+1, 10)
+		for _, entry := range entries {
+			var payload struct {
+				ElectionID int64   `json:"election_id"`
+				Receipt    string  `json:"receipt"`
+				Commitment string  `json:"commitment"`
+				Choices    []int64 `json:"choices"`
+				VoterID    int64   `json:"voter_id"`
+			}
+			json.Unmarshal(entry.Payload, &payload)
+			if payload.ElectionID != election.ID || payload.Receipt == "" || payload.Commitment == "" || payload.Choices != nil || payload.VoterID != 0 {
+				t.Errorf("unexpected ballot entry %s", entry.Payload)
+			}
+		}
+
+		// every receipt proves its ballot is in the published root, without the choices
+		var root dto.LedgerHeadResponse
+		call("GET", "/ledger/root", nil, http.StatusOK, &root)
+		for _, receipt := range receipts {
+			var proof ledger.Proof
+			call("GET", "/verify/"+strings.ToLower(receipt), nil, http.StatusOK, &proof)
+			if err := proof.Verify(); err != nil {
+				t.Errorf("proof of receipt %s: %v", receipt, err)
+			}
+			if err := proof.VerifyReceipt(receipt); err != nil {
+				t.Errorf("proof of receipt %s: %v", receipt, err)
+			}
+			if proof.TreeSize != root.Height || hex.EncodeToString(proof.Root) != root.Root {
+				t.Errorf("proof of receipt %s is not against the published root", receipt)
+			}
+		}
+		if err := (ledger.Proof{}).VerifyReceipt(receipts[0]); err == nil {
+			t.Error("an empty proof matches a receipt")
+		}
+		call("GET", "/verify/AAAA-BBBB", nil, http.StatusNotFound, nil)
+
+		_, err = db.Exec(`UPDATE ledger_entries SET payload = 'x' WHERE origin_peer_id = 0 AND height = $1`, head.Height)
+		if err == nil {
+			t.Error("a ledger entry was updated")
+		}
+	})
+
+	t.Run("Replication", func(t *testing.T) {
+		origin := newStandIn(t, nodePublicKey)
+		origin.append(7)
+		originPeer := register("KPU Jawa Tengah", origin.publicKey, origin.server.URL, 9001)
+
+		witness := newStandIn(t, nodePublicKey)
+		register("KPU Jawa Timur", witness.publicKey, witness.server.URL, 9002)
+
+		sync := func() map[int64]dto.LedgerSyncResponse {
+			var list []dto.LedgerSyncResponse
+			call("POST", "/ledger/sync", nil, http.StatusOK, &list)
+			results := map[int64]dto.LedgerSyncResponse{}
+			for _, result := range list {
+				results[result.PeerID] = result
+			}
+			return results
+		}
+
+		if result := sync()[originPeer.ID]; result.Height != 7 || result.Error != "" {
+			t.Fatalf("replicating the origin: %+v", result)
+		}
+
+		origin.append(5)
+		if result := sync()[originPeer.ID]; result.Height != 12 || result.Error != "" {
+			t.Fatalf("catching up with the origin: %+v", result)
+		}
+
+		// the origin shows the witness another history of the same height
+		forked := ledger.NewMemoryStore()
+		for i := int64(0); i < 12; i++ {
+			forked.Append(context.Background(), i, []byte(fmt.Sprintf(`{"ballot_id":%d}`, 100+i)))
+		}
+		forkedHead, _ := forked.Head(context.Background())
+		witness.replicas = []ledger.Replica{{PublicKey: origin.publicKey, Head: forkedHead}}
+		sync()
+
+		// the origin rewrites its history
+		entries, _ := origin.store.Entries(context.Background(), 1, 100)
+		origin.store = ledger.NewMemoryStore()
+		for i, entry := range entries {
+			data := []byte(entry.Payload)
+			if entry.Height >= 4 {
+				data = []byte(fmt.Sprintf(`{"ballot_id":%d}`, 200+i))
+			}
+			origin.store.Append(context.Background(), int64(i), data)
+		}
+		origin.append(2)
+		if result := sync()[originPeer.ID]; result.Error == "" || result.Height != 12 {
+			t.Errorf("a rewritten ledger was replicated: %+v", result)
+		}
+
+		var divergences []dto.LedgerDivergenceResponse
+		call("GET", fmt.Sprintf("/ledger/divergences?peer_id=%d", originPeer.ID), nil, http.StatusOK, &divergences)
+		var rewritten, equivocated bool
+		for _, divergence := range divergences {
+			rewritten = rewritten || (divergence.ReportedBy == originPeer.ID && divergence.Height == 4)
+			equivocated = equivocated || (divergence.ReportedBy != originPeer.ID && divergence.Height == 12)
+		}
+		if !rewritten || !equivocated {
+			t.Errorf("divergences not recorded, rewritten %v, equivocated %v: %+v", rewritten, equivocated, divergences)
+		}
+	})
+}
+
+// routerClient sends signed requests to the router of the node under test
+type routerClient struct {
+	router http.Handler
+	id     int64
+	key    ed25519.PrivateKey
+}
+
+func (c routerClient) Do(req *http.Request) (*http.Response, error) {
+	peer.Sign(req, nil, c.id, c.key, time.Now())
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	rr := httptest.NewRecorder()
+	c.router.ServeHTTP(rr, req)
+	return rr.Result(), nil
+}
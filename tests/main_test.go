@@ -72,7 +72,7 @@ func TestMain(m *testing.M) {
 		mid.RateLimit,
 		mid.Idempotency,
 	}
-	privateMiddlewares = append(publicMiddlewares, mid.Authentication, mid.Authorization)
+	privateMiddlewares = append(publicMiddlewares, mid.Authentication, mid.Authorization, mid.Audit)
 
 	err = login(log, db)
 	if err != nil {
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/peer"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestPeer(t *testing.T) {
+	peerHandler := handler.Peers{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.GET("/peers", mid.WrapMiddleware(publicMiddlewares, peerHandler.List))
+	router.POST("/peers", mid.WrapMiddleware(publicMiddlewares, peerHandler.Create))
+	router.PUT("/peers/:id", mid.WrapMiddleware(publicMiddlewares, peerHandler.Update))
+	router.PUT("/peers/:id/status", mid.WrapMiddleware(publicMiddlewares, peerHandler.SetStatus))
+	router.GET("/peer", mid.WrapMiddleware(append(publicMiddlewares, mid.PeerAuthentication), peerHandler.Handshake))
+
+	call := newCaller(t, router)
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	var node dto.PeerResponse
+	call("POST", "/peers", dto.AddPeerRequest{Name: "KPU Jawa Barat", Endpoint: "https://jabar.example", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}, http.StatusCreated, &node)
+	if node.Status != "pending" {
+		t.Fatalf("a new peer should be pending, got %s", node.Status)
+	}
+	call("POST", "/peers", dto.AddPeerRequest{Name: "Copy", Endpoint: "https://copy.example", PublicKey: base64.StdEncoding.EncodeToString(publicKey)}, http.StatusConflict, nil)
+	call("POST", "/peers", dto.AddPeerRequest{Name: "Short Key", Endpoint: "https://short.example", PublicKey: "c2hvcnQ="}, http.StatusBadRequest, nil)
+
+	statusURL := fmt.Sprintf("/peers/%d/status", node.ID)
+	signed := func(key ed25519.PrivateKey, now time.Time) *http.Request {
+		req := httptest.NewRequest("GET", "/peer", nil)
+		peer.Sign(req, nil, node.ID, key, now)
+		return req
+	}
+	handshake := func(req *http.Request, statusCode int) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("handshake returned wrong status code: got %v want %v: %s", rr.Code, statusCode, rr.Body.String())
+		}
+	}
+
+	t.Run("Pending Peer", func(t *testing.T) {
+		handshake(signed(privateKey, time.Now()), http.StatusForbidden)
+	})
+
+	t.Run("Active Peer", func(t *testing.T) {
+		call("PUT", statusURL, dto.PeerStatusRequest{Status: "active"}, http.StatusOK, nil)
+
+		req := signed(privateKey, time.Now())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var self dto.PeerResponse
+		json.Unmarshal(rr.Body.Bytes(), &self)
+		if rr.Code != http.StatusOK || self.ID != node.ID {
+			t.Fatalf("handshake failed: %d %s", rr.Code, rr.Body.String())
+		}
+
+		replay := httptest.NewRequest("GET", "/peer", nil)
+		replay.Header = req.Header.Clone()
+		handshake(replay, http.StatusUnauthorized)
+
+		handshake(httptest.NewRequest("GET", "/peer", nil), http.StatusUnauthorized)
+		handshake(signed(privateKey, time.Now().Add(-time.Hour)), http.StatusUnauthorized)
+
+		_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+		handshake(signed(otherKey, time.Now()), http.StatusUnauthorized)
+	})
+
+	t.Run("Signed Body", func(t *testing.T) {
+		body := []byte(`{"ping":true}`)
+		req := httptest.NewRequest("GET", "/peer", bytes.NewReader(body))
+		peer.Sign(req, body, node.ID, privateKey, time.Now())
+		handshake(req, http.StatusOK)
+
+		req = httptest.NewRequest("GET", "/peer", bytes.NewReader([]byte(`{"ping":false}`)))
+		peer.Sign(req, body, node.ID, privateKey, time.Now())
+		handshake(req, http.StatusUnauthorized)
+	})
+
+	t.Run("Suspend And Revoke", func(t *testing.T) {
+		call("PUT", statusURL, dto.PeerStatusRequest{Status: "suspended"}, http.StatusOK, nil)
+		handshake(signed(privateKey, time.Now()), http.StatusForbidden)
+
+		call("PUT", statusURL, dto.PeerStatusRequest{Status: "revoked"}, http.StatusOK, nil)
+		call("PUT", statusURL, dto.PeerStatusRequest{Status: "active"}, http.StatusConflict, nil)
+		call("PUT", statusURL, dto.PeerStatusRequest{Status: "pending"}, http.StatusBadRequest, nil)
+
+		var revoked []dto.PeerResponse
+		call("GET", "/peers?status=revoked", nil, http.StatusOK, &revoked)
+		found := false
+		for _, p := range revoked {
+			found = found || p.ID == node.ID
+		}
+		if !found {
+			t.Errorf("revoked peer %d is not listed", node.ID)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		var updated dto.PeerResponse
+		call("PUT", fmt.Sprintf("/peers/%d", node.ID), dto.UpdatePeerRequest{ID: node.ID, Name: "KPU Jabar", Endpoint: "https://kpu-jabar.example"}, http.StatusOK, &updated)
+		if updated.Endpoint != "https://kpu-jabar.example" || updated.PublicKey != node.PublicKey {
+			t.Errorf("unexpected peer %+v", updated)
+		}
+		call("PUT", fmt.Sprintf("/peers/%d", node.ID), dto.UpdatePeerRequest{ID: node.ID, Name: "KPU Jabar", Endpoint: "ftp://kpu-jabar.example"}, http.StatusBadRequest, nil)
+	})
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRecapitulation(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.SubmitTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.ReviewTallyForm))
+	router.GET("/elections/:id/recapitulations/:region_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.Get))
+	router.PUT("/elections/:id/recapitulations/:region_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.Submit))
+	router.PUT("/elections/:id/recapitulations/:region_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.Review))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Presiden"}, http.StatusCreated, &election)
+
+	var rina, bayu dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, &rina)
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 2, Name: "Bayu"}, http.StatusCreated, &bayu)
+
+	// national > province > regency > subdistrict > village, codes are unique per election run
+	var parentID int64
+	var regions []dto.RegionResponse
+	for _, level := range []string{"national", "province", "regency", "subdistrict", "village"} {
+		var region dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.%s", election.ID%1000000, level[:3]), Name: level}
+		call("POST", "/regions", request, http.StatusCreated, &region)
+		regions = append(regions, region)
+		parentID = region.ID
+	}
+	subdistrict, village := regions[3], regions[4]
+	call("POST", "/regions", dto.AddRegionRequest{ParentID: regions[1].ID, Level: "village", Code: "skip", Name: "skip"}, http.StatusBadRequest, nil)
+
+	var tps1, tps2 dto.PollingStationResponse
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: village.ID, Number: "001"}, http.StatusCreated, &tps1)
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: village.ID, Number: "002"}, http.StatusCreated, &tps2)
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: village.ID, Number: "002"}, http.StatusConflict, nil)
+
+	form := func(rinaVotes int64, bayuVotes int64, invalid int64) dto.TallyFormRequest {
+		return dto.TallyFormRequest{
+			Lines:        []dto.TallyLineRequest{{CandidateID: rina.ID, Votes: rinaVotes}, {CandidateID: bayu.ID, Votes: bayuVotes}},
+			InvalidVotes: invalid,
+		}
+	}
+	formURL := func(tps dto.PollingStationResponse) string {
+		return fmt.Sprintf("/elections/%d/tally-forms/%d", election.ID, tps.ID)
+	}
+	recapitulationURL := func(region dto.RegionResponse) string {
+		return fmt.Sprintf("/elections/%d/recapitulations/%d", election.ID, region.ID)
+	}
+
+	// tally forms are only accepted once the election is closed
+	call("PUT", formURL(tps1), form(100, 50, 2), http.StatusConflict, nil)
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+
+	call("PUT", formURL(tps1), form(100, 50, 2), http.StatusOK, nil)
+	call("PUT", formURL(tps1)+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+	call("PUT", formURL(tps1), form(1, 1, 0), http.StatusConflict, nil)
+
+	// the village can not be recapitulated while a polling station is pending
+	call("PUT", formURL(tps2), form(70, 80, 1), http.StatusOK, nil)
+	call("PUT", recapitulationURL(village), form(170, 130, 3), http.StatusConflict, nil)
+	call("PUT", formURL(tps2)+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+
+	var submitted dto.RecapitulationSubmitResponse
+	call("PUT", recapitulationURL(village), form(170, 135, 3), http.StatusOK, &submitted)
+	if len(submitted.Discrepancies) != 1 || submitted.Discrepancies[0].CandidateID != bayu.ID || submitted.Discrepancies[0].ComputedVotes != 130 {
+		t.Fatalf("expected one discrepancy for Bayu, got %+v", submitted.Discrepancies)
+	}
+
+	call("PUT", recapitulationURL(village), form(170, 130, 3), http.StatusOK, &submitted)
+	if submitted.Recapitulation.Revision != 2 || len(submitted.Discrepancies) != 0 {
+		t.Fatalf("expected revision 2 without discrepancies, got %+v", submitted)
+	}
+	call("PUT", recapitulationURL(village)+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+
+	var report dto.RecapitulationReportResponse
+	call("GET", recapitulationURL(subdistrict), nil, http.StatusOK, &report)
+	if report.Recapitulation != nil || report.Computed.Children != 1 || report.Computed.ApprovedChildren != 1 || report.Computed.InvalidVotes != 3 {
+		t.Fatalf("unexpected subdistrict report: %+v", report)
+	}
+	for _, line := range report.Computed.Lines {
+		if (line.CandidateID == rina.ID && line.Votes != 170) || (line.CandidateID == bayu.ID && line.Votes != 130) {
+			t.Errorf("candidate %d got wrong computed votes: %d", line.CandidateID, line.Votes)
+		}
+	}
+
+	call("GET", recapitulationURL(village), nil, http.StatusOK, &report)
+	if report.Recapitulation == nil || report.Recapitulation.Status != "approved" || len(report.Discrepancies) != 1 {
+		t.Fatalf("unexpected village report: %+v", report)
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/stream"
+	"backend-election/internal/usecase"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/websocket"
+)
+
+func TestResultsStream(t *testing.T) {
+	hub, err := stream.NewHub(context.Background(), cache, usecase.ResultsChannelPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+	resultHandler := handler.Results{DB: db, Log: log, Cache: cache, Hub: hub}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.SubmitTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.ReviewTallyForm))
+	router.GET("/elections/:id/results/stream", mid.WrapMiddleware(publicMiddlewares, resultHandler.Stream))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Gubernur"}, http.StatusCreated, &election)
+	var candidate dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, &candidate)
+
+	var parentID int64
+	for _, level := range []string{"national", "province", "regency", "subdistrict", "village"} {
+		var region dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.r%s", election.ID%1000000, level[:3]), Name: level}
+		call("POST", "/regions", request, http.StatusCreated, &region)
+		parentID = region.ID
+	}
+	var tps1, tps2 dto.PollingStationResponse
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: parentID, Number: "001"}, http.StatusCreated, &tps1)
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: parentID, Number: "002"}, http.StatusCreated, &tps2)
+
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+
+	approve := func(tps dto.PollingStationResponse, votes int64) {
+		formURL := fmt.Sprintf("/elections/%d/tally-forms/%d", election.ID, tps.ID)
+		call("PUT", formURL, dto.TallyFormRequest{Lines: []dto.TallyLineRequest{{CandidateID: candidate.ID, Votes: votes}}}, http.StatusOK, nil)
+		call("PUT", formURL+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+	}
+	approve(tps1, 40)
+
+	// a real server, the stream must outlive the handler of a single recorder
+	server := httptest.NewServer(router)
+	defer server.Close()
+	streamURL := fmt.Sprintf("%s/elections/%d/results/stream", server.URL, election.ID)
+
+	t.Run("Server-Sent Events", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", streamURL, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("unexpected response: %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+		}
+
+		reader := bufio.NewReader(res.Body)
+		next := func() (string, string) {
+			var event, data string
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatal(err)
+				}
+				line = strings.TrimRight(line, "\n")
+				switch {
+				case line == "" && event != "":
+					return event, data
+				case strings.HasPrefix(line, "event: "):
+					event = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					data = strings.TrimPrefix(line, "data: ")
+				}
+			}
+		}
+
+		event, data := next()
+		var snapshot dto.TallySnapshotResponse
+		json.Unmarshal([]byte(data), &snapshot)
+		if event != "snapshot" || snapshot.ApprovedForms != 1 || snapshot.Lines[0].Votes != 40 {
+			t.Fatalf("unexpected snapshot %s: %s", event, data)
+		}
+
+		approve(tps2, 25)
+
+		event, data = next()
+		var update dto.TallyUpdateResponse
+		json.Unmarshal([]byte(data), &update)
+		if event != "tally" || update.Version != snapshot.Version+1 || update.PollingStationID != tps2.ID || update.Lines[0].Votes != 25 {
+			t.Fatalf("unexpected update %s: %s", event, data)
+		}
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		config, _ := websocket.NewConfig("ws"+strings.TrimPrefix(streamURL, "http"), server.URL)
+		config.Header.Set("Authorization", "Bearer "+token)
+		ws, err := websocket.DialConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+
+		var message struct {
+			Event string                    `json:"event"`
+			Data  dto.TallySnapshotResponse `json:"data"`
+		}
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Event != "snapshot" || message.Data.ApprovedForms != 2 {
+			t.Fatalf("unexpected snapshot: %+v", message)
+		}
+
+		// closing the hub, as the server shutdown does, asks the client to reconnect
+		hub.Close()
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Event != "reconnect" {
+			t.Fatalf("expected a reconnect event, got %s", message.Event)
+		}
+	})
+	t.Run("without redis", func(t *testing.T) {
+		withoutHub := handler.Results{DB: db, Log: log}
+		req := httptest.NewRequest("GET", "/elections/1/results/stream", nil)
+		rr := httptest.NewRecorder()
+		withoutHub.Stream(rr, req, httprouter.Params{{Key: "id", Value: "1"}})
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("got %v want %v", rr.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
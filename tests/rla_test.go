@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRiskLimitingAudit(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+	rlaHandler := handler.RLAs{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.SubmitTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.ReviewTallyForm))
+	router.GET("/elections/:id/rla", mid.WrapMiddleware(publicMiddlewares, rlaHandler.Get))
+	router.POST("/elections/:id/rla", mid.WrapMiddleware(publicMiddlewares, rlaHandler.Create))
+	router.GET("/elections/:id/rla/draws", mid.WrapMiddleware(publicMiddlewares, rlaHandler.ListDraws))
+	router.POST("/elections/:id/rla/draws", mid.WrapMiddleware(publicMiddlewares, rlaHandler.Draw))
+	router.POST("/elections/:id/rla/interpretations", mid.WrapMiddleware(publicMiddlewares, rlaHandler.Interpret))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Bupati"}, http.StatusCreated, &election)
+	rlaURL := fmt.Sprintf("/elections/%d/rla", election.ID)
+
+	var rina, bayu dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, &rina)
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 2, Name: "Bayu"}, http.StatusCreated, &bayu)
+
+	var parentID int64
+	for _, level := range []string{"national", "province", "regency", "subdistrict", "village"} {
+		var region dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.rla.%s", election.ID%1000000, level[:3]), Name: level}
+		call("POST", "/regions", request, http.StatusCreated, &region)
+		parentID = region.ID
+	}
+
+	// TPS 001 reports 90 for Rina and 10 for Bayu, TPS 002 reports 80 and 20
+	reported := map[int64][2]int64{}
+	for i, votes := range [][2]int64{{90, 10}, {80, 20}} {
+		var tps dto.PollingStationResponse
+		call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: parentID, Number: fmt.Sprintf("%03d", i+1)}, http.StatusCreated, &tps)
+		reported[tps.ID] = votes
+	}
+
+	request := dto.RLARequest{AuditType: "polling", RiskLimit: 0.05, MaxDraws: 100, Seed: "32705488315927640215"}
+	call("POST", rlaURL, request, http.StatusConflict, nil)
+
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+	for tpsID, votes := range reported {
+		formURL := fmt.Sprintf("/elections/%d/tally-forms/%d", election.ID, tpsID)
+		form := dto.TallyFormRequest{Lines: []dto.TallyLineRequest{{CandidateID: rina.ID, Votes: votes[0]}, {CandidateID: bayu.ID, Votes: votes[1]}}}
+		call("PUT", formURL, form, http.StatusOK, nil)
+		call("PUT", formURL+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+	}
+
+	// the audit starts once the count is tallied
+	call("POST", rlaURL, request, http.StatusConflict, nil)
+	call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "tallied"}, http.StatusOK, nil)
+	call("POST", rlaURL, dto.RLARequest{AuditType: "polling", RiskLimit: 0.05, MaxDraws: 100, Seed: "1234"}, http.StatusBadRequest, nil)
+	call("POST", rlaURL, request, http.StatusCreated, nil)
+	call("POST", rlaURL, request, http.StatusConflict, nil)
+
+	// the ballots of a TPS batch are stacked in candidate id order, as the manifest reads them
+	interpret := func(draw dto.RLADrawResponse) dto.RLAInterpretationRequest {
+		votes := reported[draw.PollingStationID]
+		first, second := rina.ID, bayu.ID
+		firstVotes := votes[0]
+		if bayu.ID < rina.ID {
+			first, second, firstVotes = bayu.ID, rina.ID, votes[1]
+		}
+		candidateID := second
+		if int64(draw.BallotPosition) <= firstVotes {
+			candidateID = first
+		}
+		return dto.RLAInterpretationRequest{
+			PollingStationID: draw.PollingStationID,
+			BallotPosition:   draw.BallotPosition,
+			Lines:            []dto.TallyLineRequest{{CandidateID: candidateID, Votes: 1}},
+		}
+	}
+
+	var report dto.RLAReportResponse
+	call("GET", rlaURL, nil, http.StatusOK, &report)
+	if report.Status != "in_progress" || report.Ballots != 200 || report.Batches != 2 || report.RiskMeasure != 1 || len(report.Winners) != 1 || report.Winners[0] != rina.ID {
+		t.Fatalf("unexpected report before any draw: %+v", report)
+	}
+
+	interpreted := map[string]bool{}
+	for report.Status == "in_progress" {
+		var draws []dto.RLADrawResponse
+		call("POST", rlaURL+"/draws", dto.RLADrawRequest{Count: 5}, http.StatusCreated, &draws)
+		for _, draw := range draws {
+			if _, ok := reported[draw.PollingStationID]; !ok || draw.BallotPosition < 1 || draw.BallotPosition > 100 {
+				t.Fatalf("draw %d is outside the manifest: %+v", draw.Index, draw)
+			}
+			key := fmt.Sprintf("%d/%d", draw.PollingStationID, draw.BallotPosition)
+			if interpreted[key] {
+				continue
+			}
+			interpreted[key] = true
+
+			interpretation := interpret(draw)
+			twoVotes := interpretation
+			twoVotes.InvalidVotes = 1
+			call("POST", rlaURL+"/interpretations", twoVotes, http.StatusBadRequest, nil)
+			call("POST", rlaURL+"/interpretations", interpretation, http.StatusCreated, &report)
+			call("POST", rlaURL+"/interpretations", interpretation, http.StatusConflict, nil)
+		}
+	}
+	if report.Status != "passed" || report.RiskMeasure > 0.05 || report.Interpreted == 0 {
+		t.Fatalf("audit of a correct count did not pass: %+v", report)
+	}
+
+	// the draws are public and no draw is made after the audit stopped
+	var draws []dto.RLADrawResponse
+	call("GET", rlaURL+"/draws", nil, http.StatusOK, &draws)
+	if len(draws) != report.Draws || draws[0].Index != 1 {
+		t.Fatalf("expected %d draws from 1, got %+v", report.Draws, draws)
+	}
+	call("POST", rlaURL+"/draws", dto.RLADrawRequest{Count: 5}, http.StatusConflict, nil)
+	var tpsID int64
+	for id := range reported {
+		tpsID = id
+	}
+	call("POST", rlaURL+"/interpretations", dto.RLAInterpretationRequest{PollingStationID: tpsID, BallotPosition: 0, InvalidVotes: 1}, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/jwttoken"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRoleHierarchy(t *testing.T) {
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+	roleHandler := handler.Roles{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.Create))
+	router.GET("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.GetById))
+	router.POST("/users/:id/roles", mid.WrapMiddleware(privateMiddlewares, roleHandler.Assign))
+	router.GET("/users/:id/access/explain", mid.WrapMiddleware(privateMiddlewares, roleHandler.ExplainAccess))
+	router.POST("/roles", mid.WrapMiddleware(privateMiddlewares, roleHandler.Create))
+	router.DELETE("/roles/:id", mid.WrapMiddleware(privateMiddlewares, roleHandler.Delete))
+	router.POST("/roles/:id/access", mid.WrapMiddleware(privateMiddlewares, roleHandler.Grant))
+	router.GET("/roles/:id/parents", mid.WrapMiddleware(privateMiddlewares, roleHandler.ListParents))
+	router.POST("/roles/:id/parents", mid.WrapMiddleware(privateMiddlewares, roleHandler.AddParent))
+	router.DELETE("/roles/:id/parents/:parent_id", mid.WrapMiddleware(privateMiddlewares, roleHandler.RemoveParent))
+
+	call := newCaller(t, router)
+
+	const viewUser int64 = 852228553691053
+
+	var user dto.UserResponse
+	email := fmt.Sprintf("hierarchy.%d@sukamaju.example", time.Now().UnixNano())
+	call("POST", "/users", dto.UserCreateRequest{Name: "Asep", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &user)
+	userToken, err := jwttoken.ClaimToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewSelf := func(statusCode int) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/users/%d", user.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("GET /users/%d returned wrong status code: got %v want %v: %s", user.ID, rr.Code, statusCode, rr.Body.String())
+		}
+	}
+	explain := func(path string) dto.AccessExplanationResponse {
+		var response dto.AccessExplanationResponse
+		call("GET", fmt.Sprintf("/users/%d/access/explain?path=%s", user.ID, url.QueryEscape(path)), nil, http.StatusOK, &response)
+		return response
+	}
+
+	suffix := time.Now().UnixNano() % 1000000
+	role := func(name string) dto.RoleResponse {
+		var response dto.RoleResponse
+		call("POST", "/roles", dto.AddRoleRequest{Name: fmt.Sprintf("%s %d", name, suffix)}, http.StatusCreated, &response)
+		return response
+	}
+	regency, province, national := role("Regency Admin"), role("Provincial Admin"), role("National Admin")
+	defer func() {
+		for _, role := range []dto.RoleResponse{national, province, regency} {
+			call("DELETE", fmt.Sprintf("/roles/%d", role.ID), nil, http.StatusNoContent, nil)
+		}
+	}()
+
+	call("POST", fmt.Sprintf("/roles/%d/access", regency.ID), dto.GrantAccessRequest{AccessID: viewUser}, http.StatusOK, nil)
+	call("POST", fmt.Sprintf("/users/%d/roles", user.ID), dto.AssignRoleRequest{RoleID: province.ID}, http.StatusOK, nil)
+	viewSelf(http.StatusForbidden)
+	if explanation := explain("GET /users/:id"); explanation.Allowed || len(explanation.Grants) != 0 {
+		t.Fatalf("explained a missing grant as %+v", explanation)
+	}
+
+	// the provincial admin inherits the access of the regency admin, reaching its holders right away
+	var parents []dto.RoleResponse
+	call("POST", fmt.Sprintf("/roles/%d/parents", province.ID), dto.AddParentRequest{ParentID: regency.ID}, http.StatusOK, &parents)
+	if len(parents) != 1 || parents[0].ID != regency.ID {
+		t.Fatalf("got parents %+v", parents)
+	}
+	viewSelf(http.StatusOK)
+
+	explanation := explain("GET /users/:id")
+	if !explanation.Allowed || len(explanation.Grants) != 1 {
+		t.Fatalf("explained an inherited grant as %+v", explanation)
+	}
+	if chain := explanation.Grants[0].Roles; len(chain) != 2 || chain[0].ID != province.ID || chain[1].ID != regency.ID {
+		t.Fatalf("got chain %+v", chain)
+	}
+	if explanation := explain("GET /nowhere"); explanation.Allowed {
+		t.Fatalf("explained an unregistered path as %+v", explanation)
+	}
+	call("GET", fmt.Sprintf("/users/%d/access/explain?path=nowhere", user.ID), nil, http.StatusBadRequest, nil)
+
+	// cycles are refused, directly or through the parents of the parent
+	call("POST", fmt.Sprintf("/roles/%d/parents", national.ID), dto.AddParentRequest{ParentID: province.ID}, http.StatusOK, nil)
+	call("POST", fmt.Sprintf("/roles/%d/parents", regency.ID), dto.AddParentRequest{ParentID: province.ID}, http.StatusConflict, nil)
+	call("POST", fmt.Sprintf("/roles/%d/parents", regency.ID), dto.AddParentRequest{ParentID: national.ID}, http.StatusConflict, nil)
+	call("POST", fmt.Sprintf("/roles/%d/parents", regency.ID), dto.AddParentRequest{ParentID: regency.ID}, http.StatusBadRequest, nil)
+	call("POST", fmt.Sprintf("/roles/%d/parents", regency.ID), dto.AddParentRequest{ParentID: 1}, http.StatusBadRequest, nil)
+
+	call("DELETE", fmt.Sprintf("/roles/%d/parents/%d", province.ID, regency.ID), nil, http.StatusOK, &parents)
+	if len(parents) != 0 {
+		t.Fatalf("got parents %+v", parents)
+	}
+	viewSelf(http.StatusForbidden)
+	call("DELETE", fmt.Sprintf("/roles/%d/parents/%d", province.ID, regency.ID), nil, http.StatusNotFound, nil)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/jwttoken"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRoleManagement(t *testing.T) {
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+	roleHandler := handler.Roles{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/users", mid.WrapMiddleware(privateMiddlewares, userHandler.Create))
+	router.GET("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.GetById))
+	router.DELETE("/users/:id", mid.WrapMiddleware(privateMiddlewares, userHandler.Delete))
+	router.POST("/users/:id/roles", mid.WrapMiddleware(privateMiddlewares, roleHandler.Assign))
+	router.DELETE("/users/:id/roles/:role_id", mid.WrapMiddleware(privateMiddlewares, roleHandler.Unassign))
+	router.POST("/roles", mid.WrapMiddleware(privateMiddlewares, roleHandler.Create))
+	router.DELETE("/roles/:id", mid.WrapMiddleware(privateMiddlewares, roleHandler.Delete))
+	router.POST("/roles/:id/access", mid.WrapMiddleware(privateMiddlewares, roleHandler.Grant))
+	router.DELETE("/roles/:id/access/:access_id", mid.WrapMiddleware(privateMiddlewares, roleHandler.Revoke))
+
+	call := newCaller(t, router)
+
+	const superman int64 = 156677038157782
+	const viewUser int64 = 852228553691053
+	const seededUser int64 = 425071490427828
+
+	var user dto.UserResponse
+	email := fmt.Sprintf("role.%d@sukamaju.example", time.Now().UnixNano())
+	call("POST", "/users", dto.UserCreateRequest{Name: "Euis", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &user)
+	userToken, err := jwttoken.ClaimToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewSelf := func(statusCode int) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/users/%d", user.ID), nil)
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("GET /users/%d returned wrong status code: got %v want %v: %s", user.ID, rr.Code, statusCode, rr.Body.String())
+		}
+	}
+
+	var role dto.RoleResponse
+	name := fmt.Sprintf("Operator %d", time.Now().UnixNano()%1000000)
+	call("POST", "/roles", dto.AddRoleRequest{Name: name}, http.StatusCreated, &role)
+	call("POST", "/roles", dto.AddRoleRequest{Name: name}, http.StatusConflict, nil)
+	if role.IsSuperuser {
+		t.Fatal("a new role is a superuser role")
+	}
+
+	// granting and revoking reach the holders of the role right away
+	viewSelf(http.StatusForbidden)
+	var roles []dto.RoleResponse
+	call("POST", fmt.Sprintf("/users/%d/roles", user.ID), dto.AssignRoleRequest{RoleID: role.ID}, http.StatusOK, &roles)
+	if len(roles) != 1 || roles[0].ID != role.ID {
+		t.Fatalf("unexpected roles of the user %+v", roles)
+	}
+	viewSelf(http.StatusForbidden)
+	var access []dto.AccessResponse
+	call("POST", fmt.Sprintf("/roles/%d/access", role.ID), dto.GrantAccessRequest{AccessID: viewUser}, http.StatusOK, &access)
+	if len(access) != 1 || access[0].Path != "GET /users/:id" {
+		t.Fatalf("unexpected access of the role %+v", access)
+	}
+	viewSelf(http.StatusOK)
+	call("DELETE", fmt.Sprintf("/roles/%d/access/%d", role.ID, viewUser), nil, http.StatusOK, nil)
+	viewSelf(http.StatusForbidden)
+	call("POST", fmt.Sprintf("/roles/%d/access", role.ID), dto.GrantAccessRequest{AccessID: 1}, http.StatusBadRequest, nil)
+
+	// the superuser role keeps its access and at least one active user
+	call("DELETE", fmt.Sprintf("/roles/%d/access/%d", superman, viewUser), nil, http.StatusConflict, nil)
+	call("DELETE", fmt.Sprintf("/roles/%d", superman), nil, http.StatusConflict, nil)
+	call("POST", fmt.Sprintf("/users/%d/roles", user.ID), dto.AssignRoleRequest{RoleID: superman}, http.StatusOK, nil)
+	viewSelf(http.StatusOK)
+	call("DELETE", fmt.Sprintf("/users/%d/roles/%d", user.ID, superman), nil, http.StatusOK, nil)
+	viewSelf(http.StatusForbidden)
+	call("DELETE", fmt.Sprintf("/users/%d/roles/%d", user.ID, superman), nil, http.StatusNotFound, nil)
+	call("DELETE", fmt.Sprintf("/users/%d", seededUser), nil, http.StatusConflict, nil)
+
+	call("DELETE", fmt.Sprintf("/roles/%d", role.ID), nil, http.StatusNoContent, nil)
+	call("DELETE", fmt.Sprintf("/roles/%d", role.ID), nil, http.StatusNotFound, nil)
+	call("DELETE", fmt.Sprintf("/users/%d", user.ID), nil, http.StatusNoContent, nil)
+}
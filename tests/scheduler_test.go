@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/usecase"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestScheduler(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.GET("/elections/:id", mid.WrapMiddleware(publicMiddlewares, electionHandler.GetById))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.GET("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.ListTransitions))
+
+	call := newCaller(t, router)
+
+	// the window is set in WITA, UTC+8
+	wita := time.FixedZone("WITA", 8*60*60)
+	opensAt := time.Now().In(wita).Add(-time.Hour).Truncate(time.Minute)
+	closesAt := opensAt.Add(2 * time.Hour)
+	request := dto.ElectionCreateRequest{
+		Name:     "Pemilihan Gubernur",
+		OpensAt:  opensAt.Format("2006-01-02T15:04"),
+		ClosesAt: closesAt.Format("2006-01-02T15:04"),
+		Timezone: "WITA",
+	}
+
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Gubernur", OpensAt: request.ClosesAt, ClosesAt: request.OpensAt}, http.StatusBadRequest, nil)
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Gubernur", OpensAt: request.OpensAt, Timezone: "UTC"}, http.StatusBadRequest, nil)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", request, http.StatusCreated, &election)
+	call("GET", fmt.Sprintf("/elections/%d", election.ID), nil, http.StatusOK, &election)
+	if election.Timezone != "WITA" || election.OpensAt != opensAt.Format(time.RFC3339) || !strings.HasSuffix(election.ClosesAt, "+08:00") {
+		t.Fatalf("unexpected voting window: %s - %s %s", election.OpensAt, election.ClosesAt, election.Timezone)
+	}
+	call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: "scheduled"}, http.StatusOK, nil)
+
+	ctx := context.Background()
+	scheduler := usecase.SchedulerUC{Log: log, DB: db, Cache: cache, Owner: "scheduler-test"}
+	defer cache.Unlock(ctx, usecase.SchedulerLockKey, scheduler.Owner)
+
+	// another replica holds the lock, this one does nothing
+	if held, err := cache.Lock(ctx, usecase.SchedulerLockKey, "other-replica", time.Minute); err != nil || !held {
+		t.Fatalf("could not take the lock for the other replica: %v", err)
+	}
+	if _, leader, err := scheduler.Tick(ctx, time.Minute); err != nil || leader {
+		t.Fatalf("scheduler ran without the lock: %v", err)
+	}
+	call("GET", fmt.Sprintf("/elections/%d", election.ID), nil, http.StatusOK, &election)
+	if election.Status != "scheduled" {
+		t.Fatalf("election was transitioned without the lock: %s", election.Status)
+	}
+
+	// once the lock is released the scheduler takes it and opens the election
+	if err := cache.Unlock(ctx, usecase.SchedulerLockKey, "other-replica"); err != nil {
+		t.Fatal(err)
+	}
+	transitions, leader, err := scheduler.Tick(ctx, time.Minute)
+	if err != nil || !leader {
+		t.Fatalf("scheduler did not take the lock: %v", err)
+	}
+	opened := false
+	for _, transition := range transitions {
+		opened = opened || (transition.ElectionID == election.ID && transition.ToStatus == "open")
+	}
+	if !opened {
+		t.Fatalf("election %d was not opened: %+v", election.ID, transitions)
+	}
+
+	var history []dto.ElectionTransitionResponse
+	call("GET", fmt.Sprintf("/elections/%d/transitions", election.ID), nil, http.StatusOK, &history)
+	if last := history[len(history)-1]; last.ToStatus != "open" || last.UserID != 0 {
+		t.Fatalf("unexpected scheduled transition: %+v", last)
+	}
+
+	// the voting window is still open, the next tick leaves the election open
+	transitions, _, _ = scheduler.Tick(ctx, time.Minute)
+	for _, transition := range transitions {
+		if transition.ElectionID == election.ID {
+			t.Fatalf("election was closed before its closing time")
+		}
+	}
+}
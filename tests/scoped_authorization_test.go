@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/jwttoken"
+	"backend-election/internal/repository"
+	"backend-election/internal/usecase"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestScopedAuthorization(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+	roleHandler := handler.Roles{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/users", mid.WrapMiddleware(publicMiddlewares, userHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.GET("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(privateMiddlewares, recapitulationHandler.GetTallyForm))
+	router.GET("/users/:id/access/explain", mid.WrapMiddleware(publicMiddlewares, roleHandler.ExplainAccess))
+
+	call := newCaller(t, router)
+
+	var election, otherElection dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Bupati"}, http.StatusCreated, &election)
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Gubernur"}, http.StatusCreated, &otherElection)
+
+	// national > province > two regencies, each with a subdistrict, a village and a polling station
+	region := func(parentID int64, level string, code string) dto.RegionResponse {
+		var response dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.%s", election.ID%1000000, code), Name: code}
+		call("POST", "/regions", request, http.StatusCreated, &response)
+		return response
+	}
+	province := region(region(0, "national", "nat").ID, "province", "pro")
+	pollingStation := func(code string) (dto.RegionResponse, dto.PollingStationResponse) {
+		regency := region(province.ID, "regency", code)
+		village := region(region(regency.ID, "subdistrict", code+".sub").ID, "village", code+".vil")
+		var tps dto.PollingStationResponse
+		call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: village.ID, Number: "001"}, http.StatusCreated, &tps)
+		return regency, tps
+	}
+	regency, tps := pollingStation("bdg")
+	_, otherTps := pollingStation("grt")
+
+	// the officer manages the tally forms of their regency in their election only
+	var officer dto.UserResponse
+	email := fmt.Sprintf("officer.%d@sukamaju.example", time.Now().UnixNano())
+	call("POST", "/users", dto.UserCreateRequest{Name: "Dadang", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &officer)
+	officerToken, err := jwttoken.ClaimToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	roleRepo := repository.RoleRepository{Log: log, Db: db}
+	roleRepo.RoleEntity = model.Role{Name: fmt.Sprintf("Officer %d", regency.ID%1000000), ElectionID: election.ID, RegionID: regency.ID}
+	if err := roleRepo.Save(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer roleRepo.Delete(ctx)
+	if err := roleRepo.Grant(ctx, 502930017222813); err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.Assign(ctx, officer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	viewAs := func(token string, electionID int64, pollingStationID int64, statusCode int) {
+		url := fmt.Sprintf("/elections/%d/tally-forms/%d", electionID, pollingStationID)
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("GET %s returned wrong status code: got %v want %v: %s", url, rr.Code, statusCode, rr.Body.String())
+		}
+	}
+	view := func(electionID int64, pollingStationID int64, statusCode int) {
+		viewAs(officerToken, electionID, pollingStationID, statusCode)
+	}
+
+	// no tally form is submitted yet, passing the authorization ends in 404
+	view(election.ID, tps.ID, http.StatusNotFound)
+	view(election.ID, otherTps.ID, http.StatusForbidden)
+	view(otherElection.ID, tps.ID, http.StatusForbidden)
+	view(election.ID, 1, http.StatusForbidden)
+
+	// scoped grants are explained within the election or the region asked
+	explainURL := fmt.Sprintf("/users/%d/access/explain?path=%s", officer.ID, url.QueryEscape("GET /elections/:id/tally-forms/:polling_station_id"))
+	call("GET", explainURL, nil, http.StatusBadRequest, nil)
+	var explanation dto.AccessExplanationResponse
+	call("GET", fmt.Sprintf("%s&election_id=%d&region_id=%d", explainURL, election.ID, tps.VillageID), nil, http.StatusOK, &explanation)
+	if !explanation.Allowed || len(explanation.Grants) != 1 || explanation.Grants[0].RegionID != regency.ID {
+		t.Fatalf("explained a grant within its scope as %+v", explanation)
+	}
+	call("GET", fmt.Sprintf("%s&election_id=%d&region_id=%d", explainURL, otherElection.ID, tps.VillageID), nil, http.StatusOK, &explanation)
+	if explanation.Allowed {
+		t.Fatalf("explained a grant outside of its scope as %+v", explanation)
+	}
+
+	// an unscoped supervisor inheriting the officer role only reaches the scope of the officer
+	var supervisor dto.UserResponse
+	email = fmt.Sprintf("supervisor.%d@sukamaju.example", time.Now().UnixNano())
+	call("POST", "/users", dto.UserCreateRequest{Name: "Ujang", Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &supervisor)
+	supervisorToken, err := jwttoken.ClaimToken(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisorRepo := repository.RoleRepository{Log: log, Db: db}
+	supervisorRepo.RoleEntity = model.Role{Name: fmt.Sprintf("Supervisor %d", regency.ID%1000000)}
+	if err := supervisorRepo.Save(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer supervisorRepo.Delete(ctx)
+	if err := supervisorRepo.Inherit(ctx, roleRepo.RoleEntity.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := supervisorRepo.Assign(ctx, supervisor.ID); err != nil {
+		t.Fatal(err)
+	}
+	viewAs(supervisorToken, election.ID, tps.ID, http.StatusNotFound)
+	viewAs(supervisorToken, election.ID, otherTps.ID, http.StatusForbidden)
+	viewAs(supervisorToken, otherElection.ID, tps.ID, http.StatusForbidden)
+	supervisorURL := fmt.Sprintf("/users/%d/access/explain?path=%s", supervisor.ID, url.QueryEscape("GET /elections/:id/tally-forms/:polling_station_id"))
+	call("GET", fmt.Sprintf("%s&election_id=%d&region_id=%d", supervisorURL, election.ID, tps.VillageID), nil, http.StatusOK, &explanation)
+	if !explanation.Allowed || len(explanation.Grants) != 1 || explanation.Grants[0].ElectionID != election.ID || explanation.Grants[0].RegionID != regency.ID {
+		t.Fatalf("explained an inherited scoped grant as %+v", explanation)
+	}
+
+	// scoped to the other election, the supervisor role inherits nothing from the officer role
+	supervisorRepo.RoleEntity.ElectionID = otherElection.ID
+	if err := supervisorRepo.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	authUC := usecase.AuthUC{Log: log, DB: db, Cache: cache}
+	if err := authUC.InvalidatePermissions(ctx, supervisor.ID); err != nil {
+		t.Fatal(err)
+	}
+	viewAs(supervisorToken, election.ID, tps.ID, http.StatusForbidden)
+	viewAs(supervisorToken, otherElection.ID, tps.ID, http.StatusForbidden)
+
+	// the seeded superuser holds the access everywhere
+	req, err := newAuthenticatedRequest("GET", fmt.Sprintf("/elections/%d/tally-forms/%d", otherElection.ID, otherTps.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("superuser got %v: %s", rr.Code, rr.Body.String())
+	}
+}
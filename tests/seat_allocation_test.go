@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestSeatAllocation(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	districtHandler := handler.Districts{DB: db, Log: log, Cache: cache}
+	resultHandler := handler.Results{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/districts", mid.WrapMiddleware(publicMiddlewares, districtHandler.Create))
+	router.PUT("/elections/:id/districts/:district_id/votes", mid.WrapMiddleware(publicMiddlewares, districtHandler.SaveVotes))
+	router.GET("/elections/:id/seats", mid.WrapMiddleware(publicMiddlewares, resultHandler.Seats))
+
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan DPRD", SeatMethod: "sainte_lague", Threshold: 4}, http.StatusCreated, &election)
+
+	var district dto.DistrictResponse
+	call("POST", fmt.Sprintf("/elections/%d/districts", election.ID), dto.AddDistrictRequest{Name: "Dapil 1", Seats: 8}, http.StatusCreated, &district)
+	call("POST", fmt.Sprintf("/elections/%d/districts", election.ID), dto.AddDistrictRequest{Name: "Dapil 1", Seats: 3}, http.StatusConflict, nil)
+
+	votesURL := fmt.Sprintf("/elections/%d/districts/%d/votes", election.ID, district.ID)
+	votes := dto.DistrictVotesRequest{Votes: []dto.PartyVotesRequest{
+		{Party: "A", Votes: 100000},
+		{Party: "B", Votes: 80000},
+		{Party: "C", Votes: 30000},
+		{Party: "D", Votes: 20000},
+	}}
+	call("PUT", votesURL, votes, http.StatusConflict, nil)
+
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+	call("PUT", votesURL, votes, http.StatusNoContent, nil)
+
+	var allocation dto.SeatAllocationResponse
+	call("GET", fmt.Sprintf("/elections/%d/seats", election.ID), nil, http.StatusOK, &allocation)
+
+	want := map[string]int{"A": 3, "B": 3, "C": 1, "D": 1}
+	for _, party := range allocation.Parties {
+		if party.Seats != want[party.Party] {
+			t.Errorf("party %s got wrong seats: got %d want %d", party.Party, party.Seats, want[party.Party])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/pkg/storage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestTallyFormScan(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	regionHandler := handler.Regions{DB: db, Log: log, Cache: cache}
+	pollingStationHandler := handler.PollingStations{DB: db, Log: log, Cache: cache}
+	recapitulationHandler := handler.Recapitulations{DB: db, Log: log, Cache: cache}
+	scanHandler := handler.Scans{DB: db, Log: log, Cache: cache, Storage: &storage.Local{Dir: t.TempDir()}}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/regions", mid.WrapMiddleware(publicMiddlewares, regionHandler.Create))
+	router.POST("/polling-stations", mid.WrapMiddleware(publicMiddlewares, pollingStationHandler.Create))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.SubmitTallyForm))
+	router.PUT("/elections/:id/tally-forms/:polling_station_id/status", mid.WrapMiddleware(publicMiddlewares, recapitulationHandler.ReviewTallyForm))
+	router.GET("/elections/:id/tally-forms/:polling_station_id/scans", mid.WrapMiddleware(publicMiddlewares, scanHandler.List))
+	router.POST("/elections/:id/tally-forms/:polling_station_id/scans", mid.WrapMiddleware(publicMiddlewares, scanHandler.Upload))
+	router.GET("/elections/:id/tally-forms/:polling_station_id/scans/:sha256", mid.WrapMiddleware(publicMiddlewares, scanHandler.Download))
+
+	serve := func(req *http.Request, statusCode int) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("%s %s returned wrong status code: got %v want %v: %s", req.Method, req.URL, rr.Code, statusCode, rr.Body.String())
+		}
+		return rr
+	}
+	call := newCaller(t, router)
+	upload := func(url string, filename string, content []byte, statusCode int, response interface{}) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write(content)
+		writer.Close()
+
+		req, err := newAuthenticatedRequest("POST", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Body = io.NopCloser(body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rr := serve(req, statusCode)
+		if response != nil {
+			if err := json.Unmarshal(rr.Body.Bytes(), response); err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+		}
+	}
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Walikota"}, http.StatusCreated, &election)
+	var candidate dto.CandidateResponse
+	call("POST", fmt.Sprintf("/elections/%d/candidates", election.ID), dto.AddCandidateRequest{BallotNumber: 1, Name: "Rina"}, http.StatusCreated, &candidate)
+
+	var parentID int64
+	for _, level := range []string{"national", "province", "regency", "subdistrict", "village"} {
+		var region dto.RegionResponse
+		request := dto.AddRegionRequest{ParentID: parentID, Level: level, Code: fmt.Sprintf("%d.s%s", election.ID%1000000, level[:3]), Name: level}
+		call("POST", "/regions", request, http.StatusCreated, &region)
+		parentID = region.ID
+	}
+	var tps dto.PollingStationResponse
+	call("POST", "/polling-stations", dto.AddPollingStationRequest{VillageID: parentID, Number: "001"}, http.StatusCreated, &tps)
+
+	for _, status := range []string{"scheduled", "open", "closed"} {
+		call("POST", fmt.Sprintf("/elections/%d/transitions", election.ID), dto.ElectionTransitionRequest{Status: status}, http.StatusOK, nil)
+	}
+
+	formURL := fmt.Sprintf("/elections/%d/tally-forms/%d", election.ID, tps.ID)
+	scansURL := formURL + "/scans"
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+	// scans are attached to a submitted tally form
+	upload(scansURL, "c1.png", png, http.StatusNotFound, nil)
+	call("PUT", formURL, dto.TallyFormRequest{Lines: []dto.TallyLineRequest{{CandidateID: candidate.ID, Votes: 10}}}, http.StatusOK, nil)
+
+	var scan dto.TallyFormScanResponse
+	upload(scansURL, "c1.png", png, http.StatusCreated, &scan)
+	sum := sha256.Sum256(png)
+	if scan.SHA256 != hex.EncodeToString(sum[:]) || scan.ContentType != "image/png" || scan.Size != int64(len(png)) {
+		t.Fatalf("unexpected scan: %+v", scan)
+	}
+
+	// the same content is not stored twice, the claimed file name and extension do not matter
+	var duplicate dto.TallyFormScanResponse
+	upload(scansURL, "c1-copy.jpg", png, http.StatusOK, &duplicate)
+	if duplicate.ID != scan.ID {
+		t.Fatalf("expected the existing scan %d, got %d", scan.ID, duplicate.ID)
+	}
+
+	upload(scansURL, "c1.png", []byte("plain text is not a scan"), http.StatusUnsupportedMediaType, nil)
+
+	var scans []dto.TallyFormScanResponse
+	call("GET", scansURL, nil, http.StatusOK, &scans)
+	if len(scans) != 1 {
+		t.Fatalf("expected 1 scan, got %d", len(scans))
+	}
+
+	req, _ := newAuthenticatedRequest("GET", scansURL+"/"+scan.SHA256, nil)
+	rr := serve(req, http.StatusOK)
+	if !bytes.Equal(rr.Body.Bytes(), png) || rr.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("downloaded scan does not match the upload")
+	}
+
+	// an approved tally form is final
+	call("PUT", formURL+"/status", dto.ReviewRequest{Status: "approved"}, http.StatusOK, nil)
+	upload(scansURL, "c1-page2.pdf", []byte("%PDF-1.4\n"), http.StatusConflict, nil)
+}
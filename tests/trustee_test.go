@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"backend-election/internal/model"
+	"backend-election/internal/pkg/elgamal"
+	"backend-election/internal/pkg/myctx"
+	"backend-election/internal/repository"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestEncryptedElection(t *testing.T) {
+	electionHandler := handler.Elections{DB: db, Log: log, Cache: cache}
+	candidateHandler := handler.Candidates{DB: db, Log: log, Cache: cache}
+	voterHandler := handler.Voters{DB: db, Log: log, Cache: cache}
+	userHandler := handler.Users{DB: db, Log: log, Cache: cache}
+	credentialHandler := handler.Credentials{DB: db, Log: log, Cache: cache}
+	ballotHandler := handler.Ballots{DB: db, Log: log, Cache: cache}
+	trusteeHandler := handler.Trustees{DB: db, Log: log, Cache: cache}
+	resultHandler := handler.Results{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/elections", mid.WrapMiddleware(publicMiddlewares, electionHandler.Create))
+	router.POST("/elections/:id/transitions", mid.WrapMiddleware(publicMiddlewares, electionHandler.Transition))
+	router.POST("/elections/:id/candidates", mid.WrapMiddleware(publicMiddlewares, candidateHandler.Create))
+	router.POST("/elections/:id/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.RegisterEligible))
+	router.POST("/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.Create))
+	router.POST("/users", mid.WrapMiddleware(publicMiddlewares, userHandler.Create))
+	router.GET("/elections/:id/credential-key", mid.WrapMiddleware(publicMiddlewares, credentialHandler.Key))
+	router.POST("/elections/:id/credentials", mid.WrapMiddleware(publicMiddlewares, credentialHandler.Issue))
+	router.POST("/elections/:id/ballots", mid.WrapMiddleware(publicMiddlewares, ballotHandler.Cast))
+	router.GET("/elections/:id/trustees", mid.WrapMiddleware(publicMiddlewares, trusteeHandler.List))
+	router.POST("/elections/:id/trustees", mid.WrapMiddleware(publicMiddlewares, trusteeHandler.Setup))
+	router.POST("/elections/:id/trustees/share", mid.WrapMiddleware(publicMiddlewares, trusteeHandler.Share))
+	router.GET("/elections/:id/encrypted-tally", mid.WrapMiddleware(publicMiddlewares, trusteeHandler.EncryptedTally))
+	router.POST("/elections/:id/decryptions", mid.WrapMiddleware(publicMiddlewares, trusteeHandler.Decrypt))
+	router.GET("/elections/:id/results", mid.WrapMiddleware(publicMiddlewares, resultHandler.Get))
+
+	// callAs sends the request as another user than the authenticated seed user
+	callAs := func(userID int64, method string, url string, data interface{}, statusCode int, response interface{}) {
+		asUser := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), myctx.Key("user_id"), userID)))
+		})
+		newCaller(t, asUser)(method, url, data, statusCode, response)
+	}
+	const seedUserID int64 = 425071490427828
+	call := newCaller(t, router)
+
+	var election dto.ElectionResponse
+	call("POST", "/elections", dto.ElectionCreateRequest{Name: "Pemilihan Kepala Desa Sukamakmur"}, http.StatusCreated, &election)
+	electionURL := fmt.Sprintf("/elections/%d", election.ID)
+
+	var candidates []dto.CandidateResponse
+	for i, name := range []string{"Asep", "Cecep", "Dadang"} {
+		var candidate dto.CandidateResponse
+		call("POST", electionURL+"/candidates", dto.AddCandidateRequest{BallotNumber: i + 1, Name: name}, http.StatusCreated, &candidate)
+		candidates = append(candidates, candidate)
+	}
+
+	// the seed user and two new users hold the shares, any two of them decrypt
+	userIDs := []int64{seedUserID}
+	for i := 0; i < 2; i++ {
+		var user dto.UserResponse
+		email := fmt.Sprintf("trustee%d.%d@sukamakmur.example", i, time.Now().UnixNano())
+		call("POST", "/users", dto.UserCreateRequest{Name: fmt.Sprintf("Trustee %d", i+1), Email: email, Password: "Rahasia#2024", RePassword: "Rahasia#2024"}, http.StatusCreated, &user)
+		userIDs = append(userIDs, user.ID)
+	}
+
+	call("POST", electionURL+"/trustees", dto.TrusteeSetupRequest{Threshold: 4, UserIDs: userIDs}, http.StatusBadRequest, nil)
+	call("POST", electionURL+"/trustees", dto.TrusteeSetupRequest{Threshold: 2, UserIDs: []int64{seedUserID, 1}}, http.StatusBadRequest, nil)
+	var setup dto.TrusteeListResponse
+	call("POST", electionURL+"/trustees", dto.TrusteeSetupRequest{Threshold: 2, UserIDs: userIDs}, http.StatusCreated, &setup)
+	call("POST", electionURL+"/trustees", dto.TrusteeSetupRequest{Threshold: 2, UserIDs: userIDs}, http.StatusConflict, nil)
+	if len(setup.Trustees) != 3 || setup.Threshold != 2 {
+		t.Fatalf("unexpected trustees %+v", setup)
+	}
+	publicKey, _ := new(big.Int).SetString(setup.PublicKey, 16)
+
+	// every trustee fetches their own share, once. Replaying the Idempotency-Key of a fetch gets nothing back,
+	// the share is not kept by the idempotency cache.
+	shares := make([]dto.TrusteeShareResponse, len(setup.Trustees))
+	idempotencyKey := uuid.NewString()
+	for _, statusCode := range []int{http.StatusOK, http.StatusConflict} {
+		req, err := newAuthenticatedRequest("POST", electionURL+"/trustees/share", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != statusCode {
+			t.Fatalf("POST %s/trustees/share returned wrong status code: got %v want %v: %s", electionURL, rr.Code, statusCode, rr.Body.String())
+		}
+		if statusCode == http.StatusOK {
+			json.Unmarshal(rr.Body.Bytes(), &shares[0])
+		}
+	}
+	for i, trustee := range setup.Trustees[1:] {
+		callAs(trustee.UserID, "POST", electionURL+"/trustees/share", nil, http.StatusOK, &shares[i+1])
+	}
+	for i, trustee := range setup.Trustees {
+		if shares[i].Index != trustee.Index || shares[i].VerificationKey != trustee.VerificationKey {
+			t.Fatalf("unexpected share of trustee %d: %+v", trustee.Index, shares[i])
+		}
+	}
+	callAs(1, "POST", electionURL+"/trustees/share", nil, http.StatusForbidden, nil)
+
+	// the seed user votes through the API, the other voters are stored directly
+	voterRepo := repository.VoterRepository{Db: db, Log: log, VoterEntity: model.Voter{UserID: seedUserID}}
+	if err := voterRepo.FindByUserID(context.Background()); err == sql.ErrNoRows {
+		var voter dto.VoterResponse
+		call("POST", "/voters", dto.VoterCreateRequest{NIK: "3174015203850003", Name: "Dewi Lestari", BirthDate: "1985-03-12", UserID: seedUserID}, http.StatusCreated, &voter)
+		voterRepo.VoterEntity.ID = voter.ID
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	voterIDs := []int64{voterRepo.VoterEntity.ID}
+	for i := 0; i < 3; i++ {
+		var voter dto.VoterResponse
+		call("POST", "/voters", dto.VoterCreateRequest{NIK: fmt.Sprintf("320501550590000%d", i+1), Name: fmt.Sprintf("Warga Sukamakmur %d", i+1), BirthDate: "1990-05-15"}, http.StatusCreated, &voter)
+		voterIDs = append(voterIDs, voter.ID)
+	}
+	call("POST", electionURL+"/voters", dto.ElectionVoterRequest{VoterIDs: voterIDs}, http.StatusOK, nil)
+	call("POST", electionURL+"/transitions", dto.ElectionTransitionRequest{Status: "scheduled"}, http.StatusOK, nil)
+	call("POST", electionURL+"/transitions", dto.ElectionTransitionRequest{Status: "open"}, http.StatusOK, nil)
+
+	encrypt := func(choice int, totals []int64) dto.EncryptedBallot {
+		selections := make([]bool, len(candidates))
+		selections[choice] = true
+		ballot, err := elgamal.EncryptBallot(publicKey, selections, totals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var request dto.EncryptedBallot
+		request.FromEntity(ballot)
+		return request
+	}
+
+	credential := newCredential(t, router, election.ID)
+	call("POST", electionURL+"/ballots", dto.CastBallotRequest{Credential: credential, Choices: []int64{candidates[1].ID}}, http.StatusBadRequest, nil)
+	double, err := elgamal.EncryptBallot(publicKey, []bool{true, true, false}, []int64{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doubleRequest dto.EncryptedBallot
+	doubleRequest.FromEntity(double)
+	call("POST", electionURL+"/ballots", dto.CastBallotRequest{Credential: credential, Encrypted: &doubleRequest}, http.StatusBadRequest, nil)
+	seedBallot := encrypt(1, []int64{1})
+	call("POST", electionURL+"/ballots", dto.CastBallotRequest{Credential: credential, Encrypted: &seedBallot}, http.StatusCreated, nil)
+
+	for i, choice := range []int{1, 2, 1} {
+		request := encrypt(choice, []int64{1})
+		ballot, _ := request.ToEntity()
+		var ciphertexts [][]byte
+		for _, c := range ballot.Ciphertexts {
+			ciphertexts = append(ciphertexts, c.A.Bytes(), c.B.Bytes())
+		}
+		ballotRepo := repository.BallotRepository{Db: db, Log: log, BallotEntity: model.Ballot{ElectionID: election.ID, Choices: []int64{}, Ciphertexts: ciphertexts}}
+		tokenHash := sha256.Sum256([]byte(fmt.Sprintf("token of voter %d", voterIDs[i+1])))
+		if err := ballotRepo.Cast(context.Background(), tokenHash[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call("GET", electionURL+"/encrypted-tally", nil, http.StatusConflict, nil)
+	call("POST", electionURL+"/transitions", dto.ElectionTransitionRequest{Status: "closed"}, http.StatusOK, nil)
+
+	var tally dto.EncryptedTallyResponse
+	call("GET", electionURL+"/encrypted-tally", nil, http.StatusOK, &tally)
+	if tally.Ballots != 4 || len(tally.Candidates) != len(candidates) {
+		t.Fatalf("unexpected encrypted tally of %d ballots and %d candidates", tally.Ballots, len(tally.Candidates))
+	}
+
+	decrypt := func(trustee dto.TrusteeShareResponse) dto.DecryptionRequest {
+		value, _ := new(big.Int).SetString(trustee.Share, 16)
+		var candidateIDs []int64
+		var shares []elgamal.DecryptionShare
+		for _, candidate := range tally.Candidates {
+			c, err := candidate.Ciphertext.ToEntity()
+			if err != nil {
+				t.Fatal(err)
+			}
+			share, err := elgamal.PartialDecrypt(elgamal.Share{Index: trustee.Index, Value: value}, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidateIDs = append(candidateIDs, candidate.CandidateID)
+			shares = append(shares, share)
+		}
+		var request dto.DecryptionRequest
+		request.FromEntity(candidateIDs, shares)
+		return request
+	}
+
+	// the tally stays hidden until two trustees decrypted it
+	var decryption dto.DecryptionResponse
+	call("GET", electionURL+"/results", nil, http.StatusConflict, nil)
+	call("POST", electionURL+"/decryptions", decrypt(shares[0]), http.StatusCreated, &decryption)
+	if decryption.Decrypted != 1 || decryption.TrusteeIndex != setup.Trustees[0].Index {
+		t.Errorf("unexpected decryption %+v", decryption)
+	}
+	call("POST", electionURL+"/decryptions", decrypt(shares[0]), http.StatusConflict, nil)
+	callAs(setup.Trustees[1].UserID, "POST", electionURL+"/decryptions", decrypt(shares[0]), http.StatusBadRequest, nil)
+	callAs(1, "POST", electionURL+"/decryptions", decrypt(shares[1]), http.StatusForbidden, nil)
+	call("GET", electionURL+"/results", nil, http.StatusConflict, nil)
+
+	callAs(setup.Trustees[2].UserID, "POST", electionURL+"/decryptions", decrypt(shares[2]), http.StatusCreated, &decryption)
+	if decryption.Decrypted != 2 {
+		t.Errorf("unexpected decryption %+v", decryption)
+	}
+
+	var trustees dto.TrusteeListResponse
+	call("GET", electionURL+"/trustees", nil, http.StatusOK, &trustees)
+	if trustees.Decrypted != 2 || trustees.Trustees[1].Decrypted {
+		t.Errorf("unexpected trustees %+v", trustees)
+	}
+
+	var result dto.ElectionResultResponse
+	call("GET", electionURL+"/results", nil, http.StatusOK, &result)
+	if result.Ballots != 4 || len(result.Elected) != 1 || result.Elected[0].ID != candidates[1].ID {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	for i, votes := range result.Rounds[0].Votes {
+		if want := []float64{0, 3, 1}[i]; votes.Votes != want {
+			t.Errorf("candidate %d has %v votes, want %v", votes.CandidateID, votes.Votes, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"backend-election/internal/dto"
+	"backend-election/internal/handler"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestCreateVoter(t *testing.T) {
+	voterHandler := handler.Voters{DB: db, Log: log, Cache: cache}
+
+	router := httprouter.New()
+	router.POST("/voters", mid.WrapMiddleware(publicMiddlewares, voterHandler.Create))
+
+	scenarios := []struct {
+		Name               string
+		Data               dto.VoterCreateRequest
+		ExpectedErr        string
+		PossibleDuplicates int
+		StatusCode         int
+	}{
+		{
+			Name:        "Invalid NIK Length",
+			Data:        dto.VoterCreateRequest{NIK: "320101410190", Name: "Siti Aminah", BirthDate: "1990-01-01"},
+			ExpectedErr: "Invalid input: nik must be 16 digits",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:        "Invalid NIK Province",
+			Data:        dto.VoterCreateRequest{NIK: "9901014101900001", Name: "Siti Aminah", BirthDate: "1990-01-01"},
+			ExpectedErr: "Invalid input: nik has an unknown province code",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:        "Birth Date Not Match NIK",
+			Data:        dto.VoterCreateRequest{NIK: "3201014101900001", Name: "Siti Aminah", BirthDate: "1991-01-01"},
+			ExpectedErr: "Invalid input: birth_date does not match nik",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:        "Gender Not Match NIK",
+			Data:        dto.VoterCreateRequest{NIK: "3201014101900001", Name: "Siti Aminah", BirthDate: "1990-01-01", Gender: "L"},
+			ExpectedErr: "Invalid input: gender does not match nik",
+			StatusCode:  http.StatusBadRequest,
+		},
+		{
+			Name:       "Valid Voter",
+			Data:       dto.VoterCreateRequest{NIK: "3201014101900001", Name: "Siti Aminah", BirthDate: "1990-01-01", BirthPlace: "Bogor"},
+			StatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "Same NIK",
+			Data:               dto.VoterCreateRequest{NIK: "3201014101900001", Name: "Siti Aminah", BirthDate: "1990-01-01"},
+			ExpectedErr:        "nik is already registered",
+			PossibleDuplicates: 1,
+			StatusCode:         http.StatusConflict,
+		},
+		{
+			Name:               "Same Normalized Name And Birth Date",
+			Data:               dto.VoterCreateRequest{NIK: "3271024101900002", Name: "  SITI   aminah. ", BirthDate: "1990-01-01"},
+			ExpectedErr:        "possible duplicate voters found, resend with allow_duplicate to register anyway",
+			PossibleDuplicates: 1,
+			StatusCode:         http.StatusConflict,
+		},
+		{
+			Name:       "Allowed Duplicate",
+			Data:       dto.VoterCreateRequest{NIK: "3271024101900002", Name: "Siti Aminah", BirthDate: "1990-01-01", AllowDuplicate: true},
+			StatusCode: http.StatusCreated,
+		},
+	}
+
+	// later scenarios depend on the voters registered by the earlier ones
+	for _, tt := range scenarios {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := newAuthenticatedRequest("POST", "/voters", tt.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.StatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.StatusCode)
+			}
+
+			switch rr.Code {
+			case http.StatusCreated:
+				var response dto.VoterResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Errorf("could not unmarshal response: %v", err)
+				}
+				if response.Gender != "P" {
+					t.Errorf("handler returned wrong gender: got %v want P", response.Gender)
+				}
+			case http.StatusConflict:
+				var response dto.VoterDuplicateResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Errorf("could not unmarshal response: %v", err)
+				}
+				if response.Message != tt.ExpectedErr || len(response.PossibleDuplicates) != tt.PossibleDuplicates {
+					t.Errorf("handler returned wrong duplicates: got %v want %v with %d duplicates", response, tt.ExpectedErr, tt.PossibleDuplicates)
+				}
+			default:
+				if strings.TrimSpace(rr.Body.String()) != tt.ExpectedErr {
+					t.Errorf("handler returned wrong error message: got %v want %v", rr.Body.String(), tt.ExpectedErr)
+				}
+			}
+		})
+	}
+}